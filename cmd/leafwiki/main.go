@@ -28,15 +28,20 @@ import (
 	"github.com/perber/wiki/internal/core/email"
 	"github.com/perber/wiki/internal/core/ignore"
 	"github.com/perber/wiki/internal/core/tools"
+	"github.com/perber/wiki/internal/export"
+	"github.com/perber/wiki/internal/fswatch"
 	httpinternal "github.com/perber/wiki/internal/http"
 	httpmetrics "github.com/perber/wiki/internal/http/metrics"
 	authmw "github.com/perber/wiki/internal/http/middleware/auth"
+	"github.com/perber/wiki/internal/publish"
 	"github.com/perber/wiki/internal/restore"
+	"github.com/perber/wiki/internal/search"
 	"github.com/perber/wiki/internal/snapshot"
 	"github.com/perber/wiki/internal/wiki"
 	wikibackup "github.com/perber/wiki/internal/wiki/backup"
 	wikirestore "github.com/perber/wiki/internal/wiki/restore"
 	wikisnapshot "github.com/perber/wiki/internal/wiki/snapshot"
+	"github.com/perber/wiki/pkg/client"
 )
 
 // Version is the LeafWiki build version. It defaults to "dev" for
@@ -59,6 +64,8 @@ func writeUsage(w io.Writer) {
 	leafwiki --disable-auth [--host <HOST>] [--port <PORT>] [--unix-socket <PATH>] [--data-dir <DIR>]
 	leafwiki reset-admin-password
 	leafwiki [--data-dir <DIR>] restore-snapshot <path-to-zip>
+	leafwiki [--data-dir <DIR>] export-site [--path <PAGE-PATH>] <output-dir>
+	leafwiki publish --url <SERVER-URL> --api-key <KEY> --target <PAGE-PATH> [--dry-run] <dir>
 	leafwiki --help
 
 	Options:
@@ -94,6 +101,15 @@ func writeUsage(w io.Writer) {
 	--metrics-port                Port for the metrics listener (default: 9091)
 	--max-revision-history        Maximum revisions kept per page; 0 = unlimited (default: 100)
 	--revision-coalesce-window    Window for coalescing rapid successive saves by the same author (e.g. 5m, 0 = disabled) (default: 5m)
+	--watch-filesystem            Apply edits made outside LeafWiki below root/ and assets/ automatically (default: false)
+	--watch-polling               Detect those edits by scanning instead of native file notifications (default: false)
+	--watch-poll-interval         Scan interval for --watch-polling and where native notifications are unavailable (default: 2s)
+	--rebuild-index               Rebuild the page tree and all indexes from scratch on startup (default: false)
+	--search-language             Stemming language of pages without a lang frontmatter field: en, de or none (default: none)
+	--search-stopwords            Leave out the stopwords of a page's language when indexing and searching (default: false)
+	--search-attachment-max-size  Bytes of an attachment whose text is indexed for search (default: 1MiB)
+	--search-pdf-attachments      Also index the text of PDF attachments for search (default: false)
+	--search-history              Make the text of all stored revisions searchable; requires revisions (default: false)
 	--enable-http-remote-user               Enable reverse-proxy authentication via HTTP header (default: false)
 	--http-remote-user-header-name          HTTP header carrying the username or email from a trusted proxy (default: Remote-User)
 	--enable-http-remote-user-auto-create   Auto-provision users asserted by the trusted proxy but unknown to LeafWiki (default: false)
@@ -162,6 +178,10 @@ func writeUsage(w io.Writer) {
 	LEAFWIKI_METRICS_PORT
 	LEAFWIKI_MAX_REVISION_HISTORY
 	LEAFWIKI_REVISION_COALESCE_WINDOW
+	LEAFWIKI_WATCH_FILESYSTEM
+	LEAFWIKI_WATCH_POLLING
+	LEAFWIKI_WATCH_POLL_INTERVAL
+	LEAFWIKI_REBUILD_INDEX
 	LEAFWIKI_ENABLE_HTTP_REMOTE_USER
 	LEAFWIKI_HTTP_REMOTE_USER_HEADER_NAME
 	LEAFWIKI_ENABLE_HTTP_REMOTE_USER_AUTO_CREATE
@@ -189,6 +209,8 @@ func writeUsage(w io.Writer) {
 	LEAFWIKI_SNAPSHOT_RETENTION
 	LEAFWIKI_SNAPSHOT_DIR
 	LEAFWIKI_RESTORE_UPLOAD_MAX_SIZE
+	LEAFWIKI_PUBLISH_URL  (publish: server URL)
+	LEAFWIKI_API_KEY      (publish: API key)
 	`); err != nil {
 		panic(err)
 	}
@@ -241,6 +263,59 @@ func runRestoreSnapshotCommand(dataDir string, args []string) error {
 	return restore.RestoreOffline(dataDir, args[1])
 }
 
+var errExportSiteUsage = errors.New("export-site requires an output directory: leafwiki [--data-dir <DIR>] export-site [--path <PAGE-PATH>] <output-dir>")
+
+// runExportSiteCommand implements the `export-site` subcommand: args is
+// flag.Args() (args[0] == "export-site"), followed by an optional --path
+// (the subtree to export, default: whole wiki) and the output directory.
+func runExportSiteCommand(dataDir string, args []string) (*export.SiteResult, error) {
+	fs := flag.NewFlagSet("export-site", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	pagePath := fs.String("path", "", "route path of the subtree to export")
+	if len(args) < 1 || fs.Parse(args[1:]) != nil || fs.NArg() != 1 {
+		return nil, errExportSiteUsage
+	}
+	return export.ExportSiteOffline(dataDir, *pagePath, fs.Arg(0))
+}
+
+var errPublishUsage = errors.New("publish requires a server URL, an API key, a target path and a directory: leafwiki publish --url <SERVER-URL> --api-key <KEY> --target <PAGE-PATH> [--dry-run] <dir>")
+
+// runPublishCommand implements the `publish` subcommand: args is flag.Args()
+// (args[0] == "publish"), followed by the server URL, API key and target path
+// (falling back to LEAFWIKI_PUBLISH_URL and LEAFWIKI_API_KEY), an optional
+// --dry-run and the source directory. The plan is written to out; a dry run
+// returns a nil result.
+func runPublishCommand(args []string, out io.Writer) (*publish.Result, error) {
+	fs := flag.NewFlagSet("publish", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	serverURL := fs.String("url", os.Getenv("LEAFWIKI_PUBLISH_URL"), "URL of the LeafWiki server, including its base path")
+	apiKey := fs.String("api-key", os.Getenv("LEAFWIKI_API_KEY"), "API key with editor access")
+	target := fs.String("target", "", "page path of the section to publish into")
+	dryRun := fs.Bool("dry-run", false, "print the plan without changing the wiki")
+	if len(args) < 1 || fs.Parse(args[1:]) != nil || fs.NArg() != 1 {
+		return nil, errPublishUsage
+	}
+	if strings.TrimSpace(*serverURL) == "" || strings.TrimSpace(*apiKey) == "" || strings.Trim(strings.TrimSpace(*target), "/") == "" {
+		return nil, errPublishUsage
+	}
+
+	wikiClient, err := client.New(*serverURL, client.Options{APIKey: *apiKey})
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	publisher := publish.NewPublisher(wikiClient)
+	plan, err := publisher.Plan(ctx, publish.Options{SourceDir: fs.Arg(0), TargetPath: *target})
+	if err != nil {
+		return nil, err
+	}
+	publish.WritePlan(out, plan)
+	if *dryRun {
+		return nil, nil
+	}
+	return publisher.Execute(ctx, plan)
+}
+
 var gracefulShutdownTimeout = 10 * time.Second
 
 type cliFlags struct {
@@ -294,6 +369,15 @@ type cliFlags struct {
 	gitBackupHTTPPassword          *string
 	gitBackupInterval              *time.Duration
 	revisionCoalesceWindow         *time.Duration
+	watchFilesystem                *bool
+	watchPolling                   *bool
+	watchPollInterval              *time.Duration
+	rebuildIndex                   *bool
+	searchLanguage                 *string
+	searchStopwords                *bool
+	searchAttachmentMaxSize        *string
+	searchPDFAttachments           *bool
+	searchHistory                  *bool
 	snapshotEnabled                *bool
 	snapshotInterval               *time.Duration
 	snapshotRetention              *int
@@ -363,6 +447,15 @@ func registerFlags(fs *flag.FlagSet) *cliFlags {
 		gitBackupHTTPPassword:          fs.String(gitBackupHTTPPasswordFlagName, "", "password or access token for HTTP(S) basic auth (env var preferred)"),
 		gitBackupInterval:              fs.Duration("git-backup-interval", 60*time.Minute, "git backup interval (e.g. 60m, 2h); 0 = manual-only, no automatic scheduling (default: 60m)"),
 		revisionCoalesceWindow:         fs.Duration("revision-coalesce-window", 5*time.Minute, "window for coalescing rapid successive saves by the same author; 0 = disabled (default: 5m)"),
+		watchFilesystem:                fs.Bool("watch-filesystem", false, "apply edits made outside LeafWiki below root/ and assets/ automatically (default: false)"),
+		watchPolling:                   fs.Bool("watch-polling", false, "detect external edits by scanning instead of native file notifications (default: false)"),
+		watchPollInterval:              fs.Duration("watch-poll-interval", fswatch.DefaultPollInterval, "scan interval when polling for external edits (default: 2s)"),
+		rebuildIndex:                   fs.Bool("rebuild-index", false, "rebuild the page tree and all indexes from scratch on startup (default: false)"),
+		searchLanguage:                 fs.String("search-language", "", "stemming language of pages without a lang frontmatter field: en, de or none (default: none)"),
+		searchStopwords:                fs.Bool("search-stopwords", false, "leave out the stopwords of a page's language when indexing and searching (default: false)"),
+		searchAttachmentMaxSize:        fs.String("search-attachment-max-size", "", "bytes of an attachment whose text is indexed for search (for example 1MiB) (default: 1MiB)"),
+		searchPDFAttachments:           fs.Bool("search-pdf-attachments", false, "also index the text of PDF attachments for search (default: false)"),
+		searchHistory:                  fs.Bool("search-history", false, "make the text of all stored revisions searchable; requires revisions (default: false)"),
 		snapshotEnabled:                fs.Bool("snapshot", true, "enable full backup snapshots (ZIP incl. the SQLite database) (default: true)"),
 		snapshotInterval:               fs.Duration("snapshot-interval", 24*time.Hour, "snapshot interval (e.g. 24h, 6h); 0 = manual-only, no automatic scheduling (default: 24h)"),
 		snapshotRetention:              fs.Int("snapshot-retention", 10, "number of most recent snapshots to keep; <= 0 = keep all (default: 10)"),
@@ -440,6 +533,18 @@ func main() {
 	metricsPort := resolveString("metrics-port", *flags.metricsPort, visited, "LEAFWIKI_METRICS_PORT", "9091")
 	maxRevisionHistory := resolveInt("max-revision-history", *flags.maxRevisionHistory, visited, "LEAFWIKI_MAX_REVISION_HISTORY", 100)
 	revisionCoalesceWindow := resolveDuration("revision-coalesce-window", *flags.revisionCoalesceWindow, visited, "LEAFWIKI_REVISION_COALESCE_WINDOW")
+	watchFilesystem := resolveBool("watch-filesystem", *flags.watchFilesystem, visited, "LEAFWIKI_WATCH_FILESYSTEM")
+	watchPolling := resolveBool("watch-polling", *flags.watchPolling, visited, "LEAFWIKI_WATCH_POLLING")
+	watchPollInterval := resolveDuration("watch-poll-interval", *flags.watchPollInterval, visited, "LEAFWIKI_WATCH_POLL_INTERVAL")
+	rebuildIndex := resolveBool("rebuild-index", *flags.rebuildIndex, visited, "LEAFWIKI_REBUILD_INDEX")
+	searchLanguageRaw := resolveString("search-language", *flags.searchLanguage, visited, "LEAFWIKI_SEARCH_LANGUAGE", "none")
+	searchStopwords := resolveBool("search-stopwords", *flags.searchStopwords, visited, "LEAFWIKI_SEARCH_STOPWORDS")
+	searchAttachmentMaxSize := parseByteSize(
+		resolveString("search-attachment-max-size", *flags.searchAttachmentMaxSize, visited, "LEAFWIKI_SEARCH_ATTACHMENT_MAX_SIZE", "1MiB"),
+		"search attachment max size",
+	)
+	searchPDFAttachments := resolveBool("search-pdf-attachments", *flags.searchPDFAttachments, visited, "LEAFWIKI_SEARCH_PDF_ATTACHMENTS")
+	searchHistory := resolveBool("search-history", *flags.searchHistory, visited, "LEAFWIKI_SEARCH_HISTORY")
 	enableHTTPRemoteUser := resolveBool("enable-http-remote-user", *flags.enableHTTPRemoteUser, visited, "LEAFWIKI_ENABLE_HTTP_REMOTE_USER")
 	httpRemoteUserHeader := resolveString("http-remote-user-header-name", *flags.httpRemoteUserHeader, visited, "LEAFWIKI_HTTP_REMOTE_USER_HEADER_NAME", "Remote-User")
 	enableHTTPRemoteUserAutoCreate := resolveBool("enable-http-remote-user-auto-create", *flags.enableHTTPRemoteUserAutoCreate, visited, "LEAFWIKI_ENABLE_HTTP_REMOTE_USER_AUTO_CREATE")
@@ -484,6 +589,10 @@ func main() {
 	if err != nil {
 		fail("invalid --trusted-proxy-ips value", "error", err)
 	}
+	searchLanguage, err := search.ParseLanguage(searchLanguageRaw)
+	if err != nil {
+		fail("invalid --search-language value", "error", err)
+	}
 	if err := validateListenConfig(unixSocket, visited); err != nil {
 		fail("Invalid listen configuration", "error", err)
 	}
@@ -561,6 +670,36 @@ func main() {
 			}
 			fmt.Println("Snapshot restored successfully. Start the server normally to pick up the restored data.")
 			return
+		case "export-site":
+			result, err := runExportSiteCommand(dataDir, args)
+			if err != nil {
+				if errors.Is(err, errExportSiteUsage) {
+					fail(err.Error())
+				} else {
+					fail("Export failed", "error", err)
+				}
+			}
+			fmt.Printf("Exported %d pages and %d assets (%d private pages skipped).\n", result.Pages, result.Assets, result.Excluded)
+			return
+		case "publish":
+			result, err := runPublishCommand(args, os.Stdout)
+			if err != nil {
+				if errors.Is(err, errPublishUsage) {
+					fail(err.Error())
+				}
+				if result != nil {
+					for _, msg := range result.Errors {
+						fmt.Println("failed  " + msg)
+					}
+				}
+				fail("Publish failed", "error", err)
+			}
+			if result == nil {
+				fmt.Println("Dry run: the wiki was not changed.")
+				return
+			}
+			fmt.Printf("Published: %d created, %d updated, %d moved, %d unchanged.\n", result.Created, result.Updated, result.Moved, result.Unchanged)
+			return
 		case "--help", "-h", "help":
 			printUsage()
 			return
@@ -621,6 +760,13 @@ func main() {
 		EnableAPIKeyManagement: enableAPIKeyManagement,
 		MaxRevisionHistory:     maxRevisionHistory,
 		RevisionCoalesceWindow: revisionCoalesceWindow,
+		WatchFilesystem:        watchFilesystem,
+		WatchPolling:           watchPolling,
+		WatchPollInterval:      watchPollInterval,
+		RebuildIndex:           rebuildIndex,
+		SearchAnalyzer:         search.Analyzer{Language: searchLanguage, Stopwords: searchStopwords},
+		SearchAttachments:      search.AttachmentOptions{MaxBytes: searchAttachmentMaxSize, PDF: searchPDFAttachments},
+		SearchHistory:          searchHistory,
 		SMTP: email.Config{
 			Host:               smtpHost,
 			Port:               smtpPort,
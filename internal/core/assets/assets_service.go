@@ -204,6 +204,37 @@ func (s *AssetService) ListAssetsForPage(page *tree.PageNode) ([]string, error)
 	return result, nil
 }
 
+// ReadAssetForPage returns up to maxBytes bytes of an asset of a page, and
+// whether the file is longer than that.
+func (s *AssetService) ReadAssetForPage(page *tree.PageNode, filename string, maxBytes int64) ([]byte, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := validateFilename(filename); err != nil {
+		return nil, false, sharederrors.NewLocalizedError("asset_invalid_name", errInvalidAssetName, errInvalidAssetNameFmt, nil, filename)
+	}
+
+	f, err := os.Open(assetFileDiskPath(assetPageDiskPath(s.assetsDir, page.ID), filename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, sharederrors.NewLocalizedError("asset_not_found", errAssetNotFound, errAssetNotFoundFmt, nil, filename)
+		}
+		return nil, false, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxBytes+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(data)) > maxBytes {
+		return data[:maxBytes], true, nil
+	}
+	return data, false, nil
+}
+
 // DeleteAsset removes an asset file from disk
 func (s *AssetService) DeleteAsset(page *tree.PageNode, filename string) error {
 	s.mu.Lock()
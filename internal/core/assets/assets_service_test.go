@@ -353,3 +353,35 @@ func TestSaveAssetForPage_TooLarge_DoesNotLeavePartialFile(t *testing.T) {
 		t.Fatalf("expected no files after failed upload, got %d", len(entries))
 	}
 }
+
+func TestReadAssetForPage_LimitsSize(t *testing.T) {
+	tmp := t.TempDir()
+	page := &tree.PageNode{Slug: "logs", ID: "read-limit"}
+	service := NewAssetService(tmp, tree.NewSlugService())
+
+	pageAssetDir := filepath.Join(service.GetAssetsDir(), page.ID)
+	if err := os.MkdirAll(pageAssetDir, 0755); err != nil {
+		t.Fatalf("failed to create asset directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(pageAssetDir, "app.log"), []byte("0123456789"), 0644); err != nil {
+		t.Fatalf("failed to write asset: %v", err)
+	}
+
+	data, truncated, err := service.ReadAssetForPage(page, "app.log", 4)
+	if err != nil || string(data) != "0123" || !truncated {
+		t.Fatalf("expected the first 4 bytes, got %q, %v, %v", data, truncated, err)
+	}
+	data, truncated, err = service.ReadAssetForPage(page, "app.log", 10)
+	if err != nil || string(data) != "0123456789" || truncated {
+		t.Fatalf("expected the whole file, got %q, %v, %v", data, truncated, err)
+	}
+
+	_, _, err = service.ReadAssetForPage(page, "../../users.db", 10)
+	if localized, ok := sharederrors.AsLocalizedError(err); !ok || localized.Code != "asset_invalid_name" {
+		t.Fatalf("expected asset_invalid_name for a path traversal, got %v", err)
+	}
+	_, _, err = service.ReadAssetForPage(page, "missing.log", 10)
+	if localized, ok := sharederrors.AsLocalizedError(err); !ok || localized.Code != "asset_not_found" {
+		t.Fatalf("expected asset_not_found, got %v", err)
+	}
+}
@@ -114,6 +114,13 @@ func (c *Cache) Get(dir string) *IgnoreFile {
 	return compiled
 }
 
+// Clear drops all cached rules so the next Get reads the ignore files again.
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data = make(map[string]*IgnoreFile)
+}
+
 func CompileLines(lines []string) *IgnoreFile {
 	return &IgnoreFile{
 		matcher:      gitignore.CompileIgnoreLines(lines...),
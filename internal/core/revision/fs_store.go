@@ -339,22 +339,22 @@ func (s *FSStore) UpdateRevision(rev *Revision) error {
 // PruneRevisions removes the oldest revision files beyond keepCount for the given page.
 // Files are sorted newest-first, so names[keepCount:] are the oldest ones.
 // Content blobs and asset manifests are NOT deleted — they are content-addressed and
-// may be shared across multiple revisions.
-func (s *FSStore) PruneRevisions(pageID string, keepCount int) error {
+// may be shared across multiple revisions. It returns the IDs of the deleted revisions.
+func (s *FSStore) PruneRevisions(pageID string, keepCount int) ([]string, error) {
 	if keepCount <= 0 {
-		return nil
+		return nil, nil
 	}
 	if err := validateStorageID(pageID); err != nil {
-		return fmt.Errorf(errInvalidPageID, err)
+		return nil, fmt.Errorf(errInvalidPageID, err)
 	}
 	names, err := s.revisionFileNames(pageID)
 	if err != nil || len(names) <= keepCount {
-		return err
+		return nil, err
 	}
 
 	index, err := s.loadRevisionIndex(pageID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Build reverse map: filename → revisionID for index cleanup
@@ -366,21 +366,43 @@ func (s *FSStore) PruneRevisions(pageID string, keepCount int) error {
 	toDelete := names[keepCount:]
 	dir := s.revisionsPageDir(pageID)
 	indexChanged := false
+	deleted := make([]string, 0, len(toDelete))
 
 	for _, name := range toDelete {
 		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("delete revision file %s: %w", name, err)
+			return deleted, fmt.Errorf("delete revision file %s: %w", name, err)
 		}
 		if id, ok := filenameToID[name]; ok {
 			delete(index, id)
 			indexChanged = true
+			deleted = append(deleted, id)
+		} else if _, id, ok := strings.Cut(strings.TrimSuffix(name, ".json"), "_"); ok {
+			deleted = append(deleted, id)
 		}
 	}
 
 	if indexChanged {
-		return s.saveRevisionIndex(pageID, index)
+		return deleted, s.saveRevisionIndex(pageID, index)
 	}
-	return nil
+	return deleted, nil
+}
+
+// ListPageIDs returns the IDs of the pages that have revisions.
+func (s *FSStore) ListPageIDs() ([]string, error) {
+	entries, err := os.ReadDir(s.revisionsDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("read revisions dir: %w", err)
+	}
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() && validateStorageID(entry.Name()) == nil {
+			ids = append(ids, entry.Name())
+		}
+	}
+	return ids, nil
 }
 
 func (s *FSStore) revisionFileNames(pageID string) ([]string, error) {
@@ -467,7 +467,7 @@ func TestFSStoreRejectsPathTraversalPageID(t *testing.T) {
 			if _, err := store.GetRevision(id, "rev1"); err == nil {
 				t.Errorf("GetRevision(%q, rev1) should have failed", id)
 			}
-			if err := store.PruneRevisions(id, 5); err == nil {
+			if _, err := store.PruneRevisions(id, 5); err == nil {
 				t.Errorf("PruneRevisions(%q) should have failed", id)
 			}
 		})
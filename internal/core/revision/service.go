@@ -44,6 +44,20 @@ type Service struct {
 	log                *slog.Logger
 	assetManifestCache sync.Map // pageID → assetManifestEntry
 	pageLocks          sync.Map // pageID → *sync.Mutex
+	observer           Observer
+}
+
+// Observer is told about the revisions the service stores and removes, for
+// example to keep a search index of the history up to date. Its methods are
+// called synchronously after the change was written; they must not call
+// back into the service.
+type Observer interface {
+	// RevisionSaved is called for new revisions and for revisions that
+	// were updated in place, with the page content of the revision.
+	RevisionSaved(rev *Revision, content string)
+	// RevisionsRemoved is called when revisions of a page were pruned, and
+	// with nil revisionIDs when all revisions of the page were deleted.
+	RevisionsRemoved(pageID string, revisionIDs []string)
 }
 
 type ServiceOptions struct {
@@ -74,15 +88,36 @@ func NewService(storageDir string, pages *tree.TreeService, logger *slog.Logger,
 	}
 }
 
+// SetObserver registers o to be told about stored and removed revisions.
+// Call it before the service is used.
+func (s *Service) SetObserver(o Observer) {
+	s.observer = o
+}
+
 // pruneAfterSave removes the oldest revisions beyond the configured limit.
 // Errors are non-fatal and only logged — a prune failure must not fail the save.
 func (s *Service) pruneAfterSave(pageID string) {
 	if s.maxRevisions <= 0 {
 		return
 	}
-	if err := s.store.PruneRevisions(pageID, s.maxRevisions); err != nil {
+	pruned, err := s.store.PruneRevisions(pageID, s.maxRevisions)
+	if err != nil {
 		s.log.Warn("failed to prune old revisions", "pageID", pageID, "maxRevisions", s.maxRevisions, "error", err)
 	}
+	if len(pruned) > 0 && s.observer != nil {
+		s.observer.RevisionsRemoved(pageID, pruned)
+	}
+}
+
+// saveRevision stores rev and tells the observer about it.
+func (s *Service) saveRevision(rev *Revision, content string) error {
+	if err := s.store.SaveRevision(rev); err != nil {
+		return err
+	}
+	if s.observer != nil {
+		s.observer.RevisionSaved(rev, content)
+	}
+	return nil
 }
 
 // CapturePageState returns a full detached snapshot including current assets.
@@ -205,7 +240,7 @@ func (s *Service) RecordAssetChange(pageID, authorID, summary string) (*Revision
 	if err != nil {
 		return nil, false, err
 	}
-	if err := s.store.SaveRevision(rev); err != nil {
+	if err := s.saveRevision(rev, state.Content); err != nil {
 		return nil, false, err
 	}
 	s.assetManifestCache.Store(rev.PageID, assetManifestEntry{hash: savedManifestHash})
@@ -246,7 +281,7 @@ func (s *Service) RecordStructureChange(pageID, authorID, summary string) (*Revi
 	if err != nil {
 		return nil, false, err
 	}
-	if err := s.store.SaveRevision(rev); err != nil {
+	if err := s.saveRevision(rev, state.Content); err != nil {
 		return nil, false, err
 	}
 	s.pruneAfterSave(rev.PageID)
@@ -254,6 +289,59 @@ func (s *Service) RecordStructureChange(pageID, authorID, summary string) (*Revi
 	return rev, true, nil
 }
 
+// RecordImportedRevisions adds the history of an imported page, oldest
+// first, as content revisions with their original authors and timestamps.
+// Title, path, frontmatter and assets are those of the current page.
+func (s *Service) RecordImportedRevisions(pageID string, revisions []ImportedRevision) error {
+	mu := s.pageWriteLock(pageID)
+	mu.Lock()
+	defer mu.Unlock()
+
+	prev, err := s.store.GetLatestRevision(pageID)
+	if err != nil {
+		return err
+	}
+
+	state, err := s.capturePageState(pageID, false)
+	if err != nil {
+		return err
+	}
+
+	assetManifestHash, err := s.resolveAssetManifestHash(pageID, prev)
+	if err != nil {
+		return err
+	}
+
+	for _, imported := range revisions {
+		if imported.CreatedAt.IsZero() {
+			return fmt.Errorf("created_at is required")
+		}
+		contentHash, err := s.store.SaveContentBlob(pageID, []byte(imported.Content))
+		if err != nil {
+			return err
+		}
+
+		revState := *state
+		revState.Content = imported.Content
+		revState.ContentHash = contentHash
+		revState.PageUpdatedAt = imported.CreatedAt
+		revState.LastAuthorID = imported.AuthorID
+
+		rev, err := s.newRevision(RevisionTypeContentUpdate, &revState, imported.AuthorID, imported.Summary, assetManifestHash)
+		if err != nil {
+			return err
+		}
+		rev.CreatedAt = imported.CreatedAt.UTC()
+		rev.AuthorName = strings.TrimSpace(imported.AuthorName)
+		if err := s.saveRevision(rev, imported.Content); err != nil {
+			return err
+		}
+	}
+	s.pruneAfterSave(pageID)
+
+	return nil
+}
+
 func (s *Service) resolveAssetManifestHash(pageID string, prev *Revision) (string, error) {
 	// Check in-memory cache first — avoids a full asset scan on every content save.
 	// Use a stat to verify the file still exists without parsing its JSON content.
@@ -460,10 +548,40 @@ func (s *Service) DeletePageData(pageID string) error {
 		return err
 	}
 	s.assetManifestCache.Delete(pageID)
+	if s.observer != nil {
+		s.observer.RevisionsRemoved(pageID, nil)
+	}
 
 	return nil
 }
 
+// WalkRevisions calls fn with every stored revision and its page content,
+// page by page. It stops at the first error fn returns.
+func (s *Service) WalkRevisions(fn func(rev *Revision, content string) error) error {
+	pageIDs, err := s.store.ListPageIDs()
+	if err != nil {
+		return err
+	}
+	for _, pageID := range pageIDs {
+		revisions, err := s.store.ListRevisions(pageID)
+		if err != nil {
+			s.log.Warn("skipping unreadable revisions", "pageID", pageID, "error", err)
+			continue
+		}
+		for _, rev := range revisions {
+			content, err := s.store.ReadContentBlob(pageID, rev.ContentHash)
+			if err != nil {
+				s.log.Warn("skipping revision without content", "pageID", pageID, "revisionID", rev.ID, "error", err)
+				continue
+			}
+			if err := fn(rev, string(content)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (s *Service) CheckRevisionIntegrity(pageID string) ([]RevisionIntegrityIssue, error) {
 	revisions, err := s.store.ListRevisions(pageID)
 	if err != nil {
@@ -796,6 +914,9 @@ func (s *Service) recordContentUpdateForPage(page *tree.Page, authorID, summary
 		if err := s.store.UpdateRevision(prev); err != nil {
 			return nil, false, err
 		}
+		if s.observer != nil {
+			s.observer.RevisionSaved(prev, state.Content)
+		}
 		if oldHash != contentHash {
 			if err := s.store.DeleteContentBlobIfUnreferenced(page.ID, oldHash); err != nil {
 				s.log.Warn("failed to gc orphaned content blob", "pageID", page.ID, "hash", oldHash, "error", err)
@@ -821,7 +942,7 @@ func (s *Service) recordContentUpdateForPage(page *tree.Page, authorID, summary
 	if err != nil {
 		return nil, false, err
 	}
-	if err := s.store.SaveRevision(rev); err != nil {
+	if err := s.saveRevision(rev, state.Content); err != nil {
 		return nil, false, err
 	}
 	s.pruneAfterSave(rev.PageID)
@@ -1075,5 +1196,5 @@ func (s *Service) recordRestoreRevision(pageID, authorID string) error {
 	if err != nil {
 		return err
 	}
-	return s.store.SaveRevision(rev)
+	return s.saveRevision(rev, state.Content)
 }
@@ -1390,3 +1390,113 @@ func TestRecordContentUpdate_CoalescingGCsOldScopedBlob(t *testing.T) {
 		t.Fatalf("expected new scoped blob to exist at %s", newBlobPath)
 	}
 }
+
+func TestRecordImportedRevisions_StoresBackdatedHistory(t *testing.T) {
+	service, treeService, _ := newRevisionTestService(t)
+	pageID := createRevisionTestPage(t, treeService, "Page", "page", "current")
+
+	current, _, err := service.RecordContentUpdate(pageID, "tester", "import")
+	if err != nil {
+		t.Fatalf("RecordContentUpdate failed: %v", err)
+	}
+
+	first := time.Date(2012, 3, 4, 5, 6, 7, 0, time.UTC)
+	err = service.RecordImportedRevisions(pageID, []ImportedRevision{
+		{AuthorName: "Alice", CreatedAt: first, Content: "first", Summary: "created"},
+		{AuthorID: "bob-id", AuthorName: "Bob", CreatedAt: first.Add(time.Hour), Content: "second"},
+	})
+	if err != nil {
+		t.Fatalf("RecordImportedRevisions failed: %v", err)
+	}
+
+	revisions, err := service.ListRevisions(pageID)
+	if err != nil {
+		t.Fatalf("ListRevisions failed: %v", err)
+	}
+	if len(revisions) != 3 || revisions[0].ID != current.ID {
+		t.Fatalf("expected the import revision to stay the latest, got %#v", revisions)
+	}
+	second, oldest := revisions[1], revisions[2]
+	if !oldest.CreatedAt.Equal(first) || oldest.AuthorName != "Alice" || oldest.AuthorID != "" || oldest.Summary != "created" {
+		t.Fatalf("unexpected oldest revision: %#v", oldest)
+	}
+	if second.AuthorID != "bob-id" || second.Type != RevisionTypeContentUpdate || second.AssetManifestHash != current.AssetManifestHash {
+		t.Fatalf("unexpected second revision: %#v", second)
+	}
+
+	snapshot, err := service.GetRevisionSnapshot(pageID, oldest.ID)
+	if err != nil {
+		t.Fatalf("GetRevisionSnapshot failed: %v", err)
+	}
+	if snapshot.Content != "first" {
+		t.Fatalf("snapshot content = %q", snapshot.Content)
+	}
+}
+
+type recordingObserver struct {
+	saved   map[string]string // revision ID → content
+	removed map[string][]string
+	deleted []string
+}
+
+func (o *recordingObserver) RevisionSaved(rev *Revision, content string) {
+	o.saved[rev.ID] = content
+}
+
+func (o *recordingObserver) RevisionsRemoved(pageID string, revisionIDs []string) {
+	if revisionIDs == nil {
+		o.deleted = append(o.deleted, pageID)
+		return
+	}
+	o.removed[pageID] = append(o.removed[pageID], revisionIDs...)
+}
+
+func TestObserver_IsToldAboutSavedPrunedAndDeletedRevisions(t *testing.T) {
+	storageDir := t.TempDir()
+	treeService := tree.NewTreeService(storageDir)
+	if err := treeService.LoadTree(); err != nil {
+		t.Fatalf("LoadTree failed: %v", err)
+	}
+	service := NewService(storageDir, treeService, slog.New(slog.NewTextHandler(io.Discard, nil)), ServiceOptions{MaxRevisions: 2})
+	observer := &recordingObserver{saved: map[string]string{}, removed: map[string][]string{}}
+	service.SetObserver(observer)
+
+	pageID := createRevisionTestPage(t, treeService, "Page", "page", "v1")
+	var ids []string
+	for i, content := range []string{"v1", "v2", "v3"} {
+		if i > 0 {
+			if err := treeService.UpdateNode("tester", pageID, "Page", "page", &content, tree.VersionUnchecked, nil, nil, false); err != nil {
+				t.Fatalf("UpdateNode failed: %v", err)
+			}
+		}
+		rev, created, err := service.RecordContentUpdate(pageID, "tester", "edit")
+		if err != nil || !created {
+			t.Fatalf("RecordContentUpdate(%s) = %v, %v", content, created, err)
+		}
+		ids = append(ids, rev.ID)
+		if observer.saved[rev.ID] != content {
+			t.Fatalf("expected the observer to get %q for %s, got %q", content, rev.ID, observer.saved[rev.ID])
+		}
+	}
+	if got := observer.removed[pageID]; len(got) != 1 || got[0] != ids[0] {
+		t.Fatalf("expected the oldest revision to be reported as pruned, got %v", got)
+	}
+
+	walked := map[string]string{}
+	if err := service.WalkRevisions(func(rev *Revision, content string) error {
+		walked[rev.ID] = content
+		return nil
+	}); err != nil {
+		t.Fatalf("WalkRevisions failed: %v", err)
+	}
+	if len(walked) != 2 || walked[ids[1]] != "v2" || walked[ids[2]] != "v3" {
+		t.Fatalf("expected the two kept revisions, got %v", walked)
+	}
+
+	if err := service.DeletePageData(pageID); err != nil {
+		t.Fatalf("DeletePageData failed: %v", err)
+	}
+	if len(observer.deleted) != 1 || observer.deleted[0] != pageID {
+		t.Fatalf("expected the page's revisions to be reported as deleted, got %v", observer.deleted)
+	}
+}
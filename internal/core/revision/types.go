@@ -44,6 +44,7 @@ type Revision struct {
 	ParentID             string                 `json:"parent_id,omitempty"`
 	Type                 RevisionType           `json:"type"`
 	AuthorID             string                 `json:"author_id"`
+	AuthorName           string                 `json:"author_name,omitempty"` // author of imported revisions without a user
 	CreatedAt            time.Time              `json:"created_at"`
 	Title                string                 `json:"title"`
 	Slug                 string                 `json:"slug"`
@@ -60,6 +61,15 @@ type Revision struct {
 	Summary              string                 `json:"summary,omitempty"`
 }
 
+// ImportedRevision is a revision of a page taken over from another wiki.
+type ImportedRevision struct {
+	AuthorID   string
+	AuthorName string
+	CreatedAt  time.Time
+	Content    string
+	Summary    string
+}
+
 type assetManifest struct {
 	Items []AssetRef `json:"items"`
 }
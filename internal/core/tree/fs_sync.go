@@ -0,0 +1,392 @@
+package tree
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/perber/wiki/internal/core/ignore"
+	"github.com/perber/wiki/internal/core/markdown"
+)
+
+var errChangeTrackingDisabled = errors.New("change tracking is not enabled")
+
+// FSChange describes how a node differs after SyncFromFS.
+type FSChange struct {
+	ID string
+	// Node is the synced node, or the detached previous node when Removed.
+	Node    *PageNode
+	Added   bool
+	Removed bool
+	// Moved reports a changed route path; OldPath holds the previous one.
+	Moved    bool
+	OldPath  string
+	OldTitle string
+	// ContentChanged reports that the content file was edited outside LeafWiki.
+	ContentChanged bool
+}
+
+// contentFingerprints remembers the SHA-256 of each content file as LeafWiki
+// last wrote or synced it, keyed by node ID. It tells edits made outside the
+// app apart from the app's own writes.
+type contentFingerprints struct {
+	mu   sync.Mutex
+	byID map[string]string
+}
+
+func (c *contentFingerprints) get(id string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sum, ok := c.byID[id]
+	return sum, ok
+}
+
+func (c *contentFingerprints) set(id, sum string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.byID[id] = sum
+}
+
+func (c *contentFingerprints) delete(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.byID, id)
+}
+
+func (c *contentFingerprints) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.byID = map[string]string{}
+}
+
+func (c *contentFingerprints) snapshot() map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]string, len(c.byID))
+	for id, sum := range c.byID {
+		out[id] = sum
+	}
+	return out
+}
+
+// fileSHA256 hashes the file at p. A missing file hashes to "".
+func fileSHA256(p string) (string, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// writeMarkdownFile writes mdFile and remembers the written content as
+// LeafWiki's own.
+func (f *NodeStore) writeMarkdownFile(mdFile *markdown.MarkdownFile, entry *PageNode) error {
+	if err := mdFile.WriteToFile(); err != nil {
+		return err
+	}
+	f.rememberContent(entry)
+	return nil
+}
+
+func (f *NodeStore) rememberContent(entry *PageNode) {
+	if entry == nil {
+		return
+	}
+	fp := f.fingerprints.Load()
+	sum, err := f.contentFingerprint(entry)
+	if err != nil {
+		f.log.Warn("could not fingerprint content file", "nodeID", entry.ID, "error", err)
+		f.written.delete(entry.ID)
+		if fp != nil {
+			fp.delete(entry.ID)
+		}
+		return
+	}
+	f.written.set(entry.ID, sum)
+	if fp != nil {
+		fp.set(entry.ID, sum)
+	}
+}
+
+func (f *NodeStore) contentFingerprint(entry *PageNode) (string, error) {
+	filePath, err := f.contentPathForNodeRead(entry)
+	if err != nil {
+		return "", err
+	}
+	return fileSHA256(filePath)
+}
+
+// EnableChangeTracking fingerprints the content file of every node so that
+// SyncFromFS can tell edits made outside LeafWiki from the app's own writes.
+// From then on, versioned updates of a node whose file changed on disk fail
+// with ErrVersionConflict until SyncFromFS has picked the change up.
+func (t *TreeService) EnableChangeTracking() error {
+	return t.withTreeClaim(false, func() error {
+		t.mu.RLock()
+		defer t.mu.RUnlock()
+		return t.enableChangeTrackingLocked()
+	})
+}
+
+func (t *TreeService) enableChangeTrackingLocked() error {
+	if t.tree == nil {
+		return ErrTreeNotLoaded
+	}
+
+	fp := &contentFingerprints{byID: make(map[string]string, len(t.nodesByID))}
+	for id, node := range t.nodesByID {
+		sum, err := t.store.contentFingerprint(node)
+		if err != nil {
+			return fmt.Errorf("fingerprint node %s: %w", id, err)
+		}
+		fp.set(id, sum)
+	}
+	t.store.fingerprints.Store(fp)
+	return nil
+}
+
+// checkDiskVersion rejects a versioned write to a node whose content file
+// was changed outside LeafWiki since the tree last saw it. The caller's
+// version predates that edit even though SyncFromFS has not bumped it yet.
+// The caller must hold a claim on the node.
+func (t *TreeService) checkDiskVersion(node *PageNode, expectedVersion string) error {
+	if expectedVersion == VersionUnchecked {
+		return nil
+	}
+	fp := t.store.fingerprints.Load()
+	if fp == nil {
+		return nil
+	}
+	known, ok := fp.get(node.ID)
+	if !ok {
+		return nil
+	}
+	sum, err := t.store.contentFingerprint(node)
+	if err != nil {
+		return fmt.Errorf("fingerprint node %s: %w", node.ID, err)
+	}
+	if sum != known {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+type fsNodeState struct {
+	node  *PageNode
+	path  string
+	title string
+}
+
+// SyncFromFS applies changes made on disk below root/ to the tree. paths are
+// slash-separated and relative to root/; only the directories containing
+// them are scanned again, everything else keeps its in-memory nodes. A node
+// whose file was edited outside LeafWiki gets a new version, so API updates
+// based on the previous one fail with ErrVersionConflict instead of
+// overwriting the edit. EnableChangeTracking must have been called first.
+func (t *TreeService) SyncFromFS(ctx context.Context, paths []string) ([]FSChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var changes []FSChange
+	err := t.withTreeClaim(true, func() (err error) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		changes, err = t.syncFromFSLocked(ctx, paths)
+		return err
+	})
+	return changes, err
+}
+
+func (t *TreeService) syncFromFSLocked(ctx context.Context, paths []string) ([]FSChange, error) {
+	if t.tree == nil {
+		return nil, ErrTreeNotLoaded
+	}
+	fp := t.store.fingerprints.Load()
+	if fp == nil {
+		return nil, errChangeTrackingDisabled
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	dirty, deep := dirtyDirsForPaths(paths)
+	isDirty := func(rel string) bool {
+		if dirty[rel] {
+			return true
+		}
+		for prefix := range deep {
+			if prefix == "" || rel == prefix || strings.HasPrefix(rel, prefix+"/") {
+				return true
+			}
+		}
+		return false
+	}
+	if len(deep) > 0 && t.store.ignoreCache != nil {
+		t.store.ignoreCache.Clear()
+	}
+
+	rootDir := filepath.Join(t.storageDir, "root")
+
+	// Remember the current state and pick the sections that are not scanned
+	// again; the IDs below them are taken before the scan runs.
+	before := make(map[string]fsNodeState, len(t.nodesByID))
+	var beforeOrder []string
+	reuse := map[string]*PageNode{}
+	seenIDs := map[string]string{"root": rootDir}
+	var walkOld func(node *PageNode, rel string, kept bool)
+	walkOld = func(node *PageNode, rel string, kept bool) {
+		for _, child := range node.Children {
+			childRel := path.Join(rel, child.Slug)
+			before[child.ID] = fsNodeState{node: child, path: child.CalculatePath(), title: child.Title}
+			beforeOrder = append(beforeOrder, child.ID)
+			if kept {
+				seenIDs[child.ID] = filepath.Join(rootDir, filepath.FromSlash(childRel))
+			}
+			childKept := kept
+			if child.Kind == NodeKindSection && !kept && !isDirty(childRel) {
+				reuse[filepath.Join(rootDir, filepath.FromSlash(childRel))] = child
+				childKept = true
+			}
+			walkOld(child, childRel, childKept)
+		}
+	}
+	walkOld(t.tree, "", false)
+	known := fp.snapshot()
+
+	now := time.Now().UTC()
+	newRoot := &PageNode{
+		ID:       t.tree.ID,
+		Slug:     t.tree.Slug,
+		Title:    t.tree.Title,
+		Children: []*PageNode{},
+		Kind:     NodeKindSection,
+		Metadata: t.tree.Metadata,
+	}
+	if info, err := os.Stat(rootDir); err == nil && info.IsDir() {
+		if err := t.store.reconstructTreeRecursive(ctx, rootDir, newRoot, now, seenIDs, &reconstructOptions{reuse: reuse}); err != nil {
+			return nil, fmt.Errorf("sync tree from fs: %w", err)
+		}
+	} else if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("stat root dir %s: %w", rootDir, err)
+	}
+
+	t.tree = newRoot
+	t.rebuildIndexesLocked()
+
+	var changes []FSChange
+	var walkNew func(node *PageNode, rel string, scanned bool) error
+	walkNew = func(node *PageNode, rel string, scanned bool) error {
+		for _, child := range node.Children {
+			childRel := path.Join(rel, child.Slug)
+			if err := t.diffSyncedNodeLocked(child, before, known, scanned, now, &changes); err != nil {
+				return err
+			}
+			childScanned := scanned
+			if child.Kind == NodeKindSection && scanned {
+				_, adopted := reuse[filepath.Join(rootDir, filepath.FromSlash(childRel))]
+				childScanned = !adopted
+			}
+			if err := walkNew(child, childRel, childScanned); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walkNew(t.tree, "", true); err != nil {
+		return changes, err
+	}
+
+	for _, id := range beforeOrder {
+		if _, ok := t.nodesByID[id]; ok {
+			continue
+		}
+		old := before[id]
+		fp.delete(id)
+		changes = append(changes, FSChange{ID: id, Node: old.node, Removed: true, OldPath: old.path, OldTitle: old.title})
+	}
+
+	return changes, nil
+}
+
+// diffSyncedNodeLocked compares a node of the synced tree with its previous
+// state. Nodes that were read from disk again are fingerprinted; an edit made
+// outside LeafWiki that left the version untouched gets a new one.
+func (t *TreeService) diffSyncedNodeLocked(node *PageNode, before map[string]fsNodeState, known map[string]string, scanned bool, now time.Time, changes *[]FSChange) error {
+	fp := t.store.fingerprints.Load()
+	old, existed := before[node.ID]
+	if !existed {
+		t.store.rememberContent(node)
+		*changes = append(*changes, FSChange{ID: node.ID, Node: node, Added: true})
+		return nil
+	}
+
+	change := FSChange{ID: node.ID, Node: node, OldPath: old.path, OldTitle: old.title}
+	change.Moved = node.CalculatePath() != old.path
+
+	if scanned {
+		sum, err := t.store.contentFingerprint(node)
+		if err != nil {
+			return fmt.Errorf("fingerprint node %s: %w", node.ID, err)
+		}
+		if prev, ok := known[node.ID]; !ok || prev != sum {
+			change.ContentChanged = true
+			if node.Version() == old.node.Version() {
+				node.Metadata.UpdatedAt = now
+				node.Metadata.LastAuthorID = reconstructSystemUserID
+				if err := t.store.SyncFrontmatterIfExists(node); err != nil {
+					return fmt.Errorf("could not sync frontmatter: %w", err)
+				}
+			} else {
+				fp.set(node.ID, sum)
+			}
+		}
+	}
+
+	if change.Moved || change.ContentChanged {
+		*changes = append(*changes, change)
+	}
+	return nil
+}
+
+// dirtyDirsForPaths returns the directories (relative to root/, "" for root
+// itself) that have to be scanned again for the changed paths. Directories
+// holding a changed ignore file are returned in deep: everything below them
+// is scanned again, too. So is everything when root itself changed.
+func dirtyDirsForPaths(paths []string) (dirty map[string]bool, deep map[string]bool) {
+	dirty = map[string]bool{"": true}
+	deep = map[string]bool{}
+	for _, p := range paths {
+		p = path.Clean("/" + filepath.ToSlash(p))[1:]
+		if p == "" {
+			deep[""] = true
+		}
+		if path.Base(p) == ignore.IgnoreFilename {
+			deep[parentDir(p)] = true
+		}
+		for d := p; d != ""; d = parentDir(d) {
+			dirty[d] = true
+		}
+	}
+	return dirty, deep
+}
+
+func parentDir(p string) string {
+	d := path.Dir(p)
+	if d == "." || d == "/" {
+		return ""
+	}
+	return d
+}
@@ -0,0 +1,226 @@
+package tree
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTrackedService(t *testing.T) (*TreeService, string) {
+	t.Helper()
+	svc, tmpDir := newLoadedService(t)
+	if err := svc.EnableChangeTracking(); err != nil {
+		t.Fatalf("EnableChangeTracking failed: %v", err)
+	}
+	return svc, tmpDir
+}
+
+func appendExternally(t *testing.T, path string, text string) {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, append(raw, []byte(text)...), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func findChange(changes []FSChange, id string) *FSChange {
+	for i := range changes {
+		if changes[i].ID == id {
+			return &changes[i]
+		}
+	}
+	return nil
+}
+
+func TestTreeService_SyncFromFS_ExternalEditBumpsVersion(t *testing.T) {
+	svc, tmpDir := newTrackedService(t)
+	id, err := svc.CreateNode("alice", nil, "Page", "page", ptrKind(NodeKindPage))
+	if err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+	node, _ := svc.FindPageByID(*id)
+	oldVersion := node.Version()
+
+	appendExternally(t, filepath.Join(tmpDir, "root", "page.md"), "\nEdited in an editor.\n")
+
+	changes, err := svc.SyncFromFS(context.Background(), []string{"page.md"})
+	if err != nil {
+		t.Fatalf("SyncFromFS failed: %v", err)
+	}
+	change := findChange(changes, *id)
+	if change == nil || !change.ContentChanged || change.Added || change.Moved {
+		t.Fatalf("expected a content change for %s, got %+v", *id, changes)
+	}
+
+	node, _ = svc.FindPageByID(*id)
+	if node.Version() == oldVersion {
+		t.Fatalf("expected a new version after the external edit")
+	}
+	if node.Metadata.LastAuthorID != reconstructSystemUserID {
+		t.Fatalf("expected last author %q, got %q", reconstructSystemUserID, node.Metadata.LastAuthorID)
+	}
+
+	content := "Overwrite"
+	err = svc.UpdateNode("bob", *id, "Page", "page", &content, oldVersion, nil, nil, false)
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict for the stale version, got %v", err)
+	}
+	if err := svc.UpdateNode("bob", *id, "Page", "page", &content, node.Version(), nil, nil, false); err != nil {
+		t.Fatalf("UpdateNode with the new version failed: %v", err)
+	}
+}
+
+func TestTreeService_SyncFromFS_IgnoresOwnWrites(t *testing.T) {
+	svc, _ := newTrackedService(t)
+	id, err := svc.CreateNode("alice", nil, "Page", "page", ptrKind(NodeKindPage))
+	if err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+	node, _ := svc.FindPageByID(*id)
+	content := "Saved through the API"
+	if err := svc.UpdateNode("alice", *id, "Page", "page", &content, node.Version(), nil, nil, false); err != nil {
+		t.Fatalf("UpdateNode failed: %v", err)
+	}
+	version := node.Version()
+
+	changes, err := svc.SyncFromFS(context.Background(), []string{"page.md"})
+	if err != nil {
+		t.Fatalf("SyncFromFS failed: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("expected no changes for the app's own write, got %+v", changes)
+	}
+	node, _ = svc.FindPageByID(*id)
+	if node.Version() != version {
+		t.Fatalf("expected version %q to be kept, got %q", version, node.Version())
+	}
+}
+
+func TestTreeService_UpdateNode_RejectsEditNotYetSynced(t *testing.T) {
+	svc, tmpDir := newTrackedService(t)
+	id, err := svc.CreateNode("alice", nil, "Page", "page", ptrKind(NodeKindPage))
+	if err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+	node, _ := svc.FindPageByID(*id)
+
+	appendExternally(t, filepath.Join(tmpDir, "root", "page.md"), "\nEdited in an editor.\n")
+
+	content := "Overwrite"
+	err = svc.UpdateNode("bob", *id, "Page", "page", &content, node.Version(), nil, nil, false)
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+	raw, _ := os.ReadFile(filepath.Join(tmpDir, "root", "page.md"))
+	if !strings.Contains(string(raw), "Edited in an editor.") {
+		t.Fatalf("expected the external edit to be kept, got %q", raw)
+	}
+	if err := svc.UpdateNode("bob", *id, "Page", "page", &content, VersionUnchecked, nil, nil, false); err != nil {
+		t.Fatalf("expected VersionUnchecked to bypass the check, got %v", err)
+	}
+}
+
+func TestTreeService_SyncFromFS_AddsRemovesAndMovesNodes(t *testing.T) {
+	svc, tmpDir := newTrackedService(t)
+	docsID, err := svc.CreateNode("alice", nil, "Docs", "docs", ptrKind(NodeKindSection))
+	if err != nil {
+		t.Fatalf("CreateNode docs failed: %v", err)
+	}
+	guideID, err := svc.CreateNode("alice", docsID, "Guide", "guide", ptrKind(NodeKindPage))
+	if err != nil {
+		t.Fatalf("CreateNode guide failed: %v", err)
+	}
+	oldID, err := svc.CreateNode("alice", docsID, "Old", "old", ptrKind(NodeKindPage))
+	if err != nil {
+		t.Fatalf("CreateNode old failed: %v", err)
+	}
+	keepID, err := svc.CreateNode("alice", nil, "Keep", "keep", ptrKind(NodeKindSection))
+	if err != nil {
+		t.Fatalf("CreateNode keep failed: %v", err)
+	}
+	untouchedID, err := svc.CreateNode("alice", keepID, "Untouched", "untouched", ptrKind(NodeKindPage))
+	if err != nil {
+		t.Fatalf("CreateNode untouched failed: %v", err)
+	}
+	untouched, _ := svc.FindPageByID(*untouchedID)
+
+	docsDir := filepath.Join(tmpDir, "root", "docs")
+	if err := os.Rename(filepath.Join(docsDir, "guide.md"), filepath.Join(docsDir, "manual.md")); err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+	if err := os.Remove(filepath.Join(docsDir, "old.md")); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(docsDir, "new.md"), []byte("# New\n\nWritten elsewhere.\n"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	changes, err := svc.SyncFromFS(context.Background(), []string{"docs/guide.md", "docs/manual.md", "docs/old.md", "docs/new.md"})
+	if err != nil {
+		t.Fatalf("SyncFromFS failed: %v", err)
+	}
+
+	moved := findChange(changes, *guideID)
+	if moved == nil || !moved.Moved || moved.ContentChanged || moved.OldPath != "/docs/guide" {
+		t.Fatalf("expected guide to be moved from /docs/guide without content change, got %+v", moved)
+	}
+	if got := moved.Node.CalculatePath(); got != "/docs/manual" {
+		t.Fatalf("expected new path /docs/manual, got %q", got)
+	}
+	removed := findChange(changes, *oldID)
+	if removed == nil || !removed.Removed || removed.OldPath != "/docs/old" {
+		t.Fatalf("expected old to be removed, got %+v", removed)
+	}
+	if _, err := svc.FindPageByID(*oldID); err == nil {
+		t.Fatalf("expected removed node to be gone from the tree")
+	}
+
+	var added *FSChange
+	for i := range changes {
+		if changes[i].Added {
+			added = &changes[i]
+		}
+	}
+	if added == nil || added.Node.Title != "New" || added.Node.CalculatePath() != "/docs/new" {
+		t.Fatalf("expected new page to be added, got %+v", changes)
+	}
+	if findChange(changes, *untouchedID) != nil || findChange(changes, *keepID) != nil {
+		t.Fatalf("expected untouched section to be left alone, got %+v", changes)
+	}
+	if got, _ := svc.FindPageByID(*untouchedID); got != untouched {
+		t.Fatalf("expected node of an unchanged directory to be kept as is")
+	}
+	if got, _ := svc.FindPageByID(*untouchedID); got.Parent == nil || got.Parent.ID != *keepID {
+		t.Fatalf("expected kept node to point to its synced parent")
+	}
+}
+
+func TestTreeService_SyncFromFS_RequiresChangeTracking(t *testing.T) {
+	svc, _ := newLoadedService(t)
+	if _, err := svc.SyncFromFS(context.Background(), []string{"page.md"}); !errors.Is(err, errChangeTrackingDisabled) {
+		t.Fatalf("expected errChangeTrackingDisabled, got %v", err)
+	}
+}
+
+func TestDirtyDirsForPaths(t *testing.T) {
+	dirty, deep := dirtyDirsForPaths([]string{"a/b/c.md", "x/.leafwikiignore"})
+	for _, dir := range []string{"", "a", "a/b", "a/b/c.md", "x"} {
+		if !dirty[dir] {
+			t.Errorf("expected %q to be dirty", dir)
+		}
+	}
+	if !deep["x"] || len(deep) != 1 {
+		t.Errorf("expected only x to be scanned deeply, got %v", deep)
+	}
+
+	_, deep = dirtyDirsForPaths([]string{""})
+	if !deep[""] {
+		t.Errorf("expected a change of root itself to rescan everything")
+	}
+}
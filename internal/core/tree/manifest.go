@@ -0,0 +1,439 @@
+package tree
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	manifestFilename = "index-manifest.json"
+	manifestVersion  = 1
+	// manifestRacyWindow covers coarse file timestamps: a file modified this
+	// close to a manifest check may keep its size and mtime across an edit,
+	// so it is hashed again instead of being trusted.
+	manifestRacyWindow = 2 * time.Second
+)
+
+// manifest records the content files of the tree as of the last clean
+// shutdown. It only exists on disk while LeafWiki is stopped: LoadTree and
+// LoadTreeFromManifest remove it, so a crash leads to a full rebuild.
+type manifest struct {
+	Version int `json:"version"`
+	// CheckedAt is when the files were last compared with the manifest.
+	CheckedAt time.Time `json:"checked_at"`
+	// Files is keyed by the content file path relative to root/, slash-separated.
+	Files map[string]manifestEntry `json:"files"`
+}
+
+type manifestEntry struct {
+	ID       string       `json:"id"`
+	Kind     NodeKind     `json:"kind"`
+	Title    string       `json:"title"`
+	Pinned   bool         `json:"pinned,omitempty"`
+	Metadata PageMetadata `json:"metadata"`
+	Size     int64        `json:"size"`
+	ModTime  int64        `json:"mtime"`
+	SHA256   string       `json:"sha256"`
+}
+
+func newManifest(checkedAt time.Time) *manifest {
+	return &manifest{Version: manifestVersion, CheckedAt: checkedAt, Files: map[string]manifestEntry{}}
+}
+
+func manifestPath(storageDir string) string {
+	return filepath.Join(storageDir, manifestFilename)
+}
+
+// loadManifest reads the manifest of the last clean shutdown. It returns nil
+// without an error when there is none or when it cannot be used.
+func loadManifest(storageDir string) (*manifest, error) {
+	data, err := os.ReadFile(manifestPath(storageDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", manifestFilename, err)
+	}
+	if m.Version != manifestVersion || m.Files == nil {
+		return nil, nil
+	}
+	return &m, nil
+}
+
+func removeManifest(storageDir string) error {
+	if err := os.Remove(manifestPath(storageDir)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// stat fills in size and mtime of the file at filePath.
+func (e *manifestEntry) stat(filePath string) (os.FileInfo, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	e.Size = info.Size()
+	e.ModTime = info.ModTime().UnixNano()
+	return info, nil
+}
+
+// unchanged reports whether the file at filePath still has the content
+// recorded in entry. Size and mtime decide unless the file was modified
+// close to checkedAt; then the content is hashed. The returned entry carries
+// the current size, mtime and hash.
+func (e manifestEntry) unchanged(filePath string, checkedAt time.Time) (manifestEntry, bool, error) {
+	current := e
+	info, err := current.stat(filePath)
+	if err != nil {
+		return e, false, err
+	}
+	if current.Size == e.Size && current.ModTime == e.ModTime && info.ModTime().Before(checkedAt.Add(-manifestRacyWindow)) {
+		return current, true, nil
+	}
+	sum, err := fileSHA256(filePath)
+	if err != nil {
+		return e, false, err
+	}
+	current.SHA256 = sum
+	return current, sum == e.SHA256, nil
+}
+
+// reconstructOptions lets a reconstruction skip work that is already done.
+// A nil *reconstructOptions scans and parses everything.
+type reconstructOptions struct {
+	// reuse maps section directories that are not scanned again to the
+	// existing node whose children they adopt.
+	reuse map[string]*PageNode
+	// prev holds the files of the last clean shutdown; files that did not
+	// change since are taken from it instead of being parsed again.
+	prev *manifest
+	// next collects the content files of the reconstructed tree.
+	next    *manifest
+	rootDir string
+}
+
+func (o *reconstructOptions) reused(dirPath string) (*PageNode, bool) {
+	if o == nil {
+		return nil, false
+	}
+	node, ok := o.reuse[dirPath]
+	return node, ok
+}
+
+func (o *reconstructOptions) key(filePath string) string {
+	rel, err := filepath.Rel(o.rootDir, filePath)
+	if err != nil {
+		return filepath.ToSlash(filePath)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// cached returns the manifest entry of filePath if the file did not change
+// since the last clean shutdown.
+func (o *reconstructOptions) cached(filePath string) (*manifestEntry, bool) {
+	if o == nil || o.prev == nil {
+		return nil, false
+	}
+	entry, ok := o.prev.Files[o.key(filePath)]
+	if !ok {
+		return nil, false
+	}
+	current, same, err := entry.unchanged(filePath, o.prev.CheckedAt)
+	if err != nil || !same {
+		return nil, false
+	}
+	return &current, true
+}
+
+// record adds the content file of node to the manifest being built. entry is
+// the cached entry the node was taken from, if any.
+func (o *reconstructOptions) record(filePath string, node *PageNode, entry *manifestEntry) error {
+	if o == nil || o.next == nil {
+		return nil
+	}
+	if entry == nil {
+		current, err := currentManifestEntry(filePath)
+		if err != nil || current == nil {
+			return err
+		}
+		entry = current
+	}
+	e := *entry
+	e.ID = node.ID
+	e.Kind = node.Kind
+	e.Title = node.Title
+	e.Pinned = node.Pinned
+	e.Metadata = node.Metadata
+	o.next.Files[o.key(filePath)] = e
+	return nil
+}
+
+// nodeFromManifestEntry is the node a cached content file stands for.
+func nodeFromManifestEntry(entry *manifestEntry, slug string, kind NodeKind, parent *PageNode) *PageNode {
+	node := &PageNode{
+		ID:       entry.ID,
+		Slug:     slug,
+		Title:    entry.Title,
+		Parent:   parent,
+		Position: len(parent.Children),
+		Kind:     kind,
+		Metadata: entry.Metadata,
+		Pinned:   entry.Pinned,
+	}
+	if kind == NodeKindSection {
+		node.Children = []*PageNode{}
+	}
+	return node
+}
+
+// routePathForContentFile turns a manifest key into the route path of its
+// node: "docs/index.md" is "/docs", "docs/guide.md" is "/docs/guide".
+func routePathForContentFile(key string) string {
+	p := strings.TrimSuffix(key, path.Ext(key))
+	if path.Base(p) == "index" {
+		p = parentDir(p)
+	}
+	return "/" + p
+}
+
+// reconstructWithManifestLocked reconstructs the tree from the filesystem and
+// keeps its content files as the manifest SaveManifest starts from.
+func (t *TreeService) reconstructWithManifestLocked() (*PageNode, error) {
+	opts := &reconstructOptions{
+		next:    newManifest(time.Now().UTC()),
+		rootDir: filepath.Join(t.storageDir, "root"),
+	}
+	reconstructed, err := t.store.reconstructTree(context.Background(), opts)
+	if err != nil {
+		return nil, err
+	}
+	if reconstructed == nil {
+		return nil, errors.New(errNilTreeReconstructed)
+	}
+	t.manifest = opts.next
+	return reconstructed, nil
+}
+
+// LoadTreeFromManifest is LoadTree for a data directory that was shut down
+// cleanly: only content files that changed since then are parsed again. It
+// returns how the tree differs from the one that was shut down. full is true
+// when there was no usable manifest; the tree is then loaded like LoadTree
+// and every index has to be rebuilt.
+func (t *TreeService) LoadTreeFromManifest() (changes []FSChange, full bool, err error) {
+	err = t.withTreeClaim(true, func() error {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		changes, full, err = t.loadTreeFromManifestLocked()
+		return err
+	})
+	return changes, full, err
+}
+
+func (t *TreeService) loadTreeFromManifestLocked() (changes []FSChange, full bool, err error) {
+	prev, err := loadManifest(t.storageDir)
+	if err != nil {
+		t.log.Warn("ignoring unreadable manifest", "error", err)
+	}
+	schema, err := loadSchema(t.storageDir)
+	if err != nil {
+		return nil, true, err
+	}
+	if prev == nil || schema.Version != CurrentSchemaVersion {
+		return nil, true, t.loadTreeLocked()
+	}
+	if err := removeManifest(t.storageDir); err != nil {
+		return nil, true, fmt.Errorf("remove manifest: %w", err)
+	}
+	t.store.written.reset()
+
+	opts := &reconstructOptions{
+		prev:    prev,
+		next:    newManifest(time.Now().UTC()),
+		rootDir: filepath.Join(t.storageDir, "root"),
+	}
+	reconstructed, err := t.store.reconstructTree(context.Background(), opts)
+	if err != nil {
+		return nil, false, err
+	}
+	t.tree = reconstructed
+	t.rebuildIndexesLocked()
+	t.manifest = opts.next
+
+	return t.diffManifestsLocked(prev, opts.next), false, nil
+}
+
+// diffManifestsLocked lists the nodes whose content file, path or kind
+// differs between two manifests. Nodes are reported in tree order, removed
+// ones last.
+func (t *TreeService) diffManifestsLocked(prev, next *manifest) []FSChange {
+	type located struct {
+		key   string
+		entry manifestEntry
+	}
+	before := make(map[string]located, len(prev.Files))
+	for key, entry := range prev.Files {
+		before[entry.ID] = located{key: key, entry: entry}
+	}
+	after := make(map[string]manifestEntry, len(next.Files))
+	for _, entry := range next.Files {
+		after[entry.ID] = entry
+	}
+
+	var changes []FSChange
+	var walk func(node *PageNode)
+	walk = func(node *PageNode) {
+		for _, child := range node.Children {
+			old, existed := before[child.ID]
+			if !existed {
+				changes = append(changes, FSChange{ID: child.ID, Node: child, Added: true})
+			} else {
+				change := FSChange{ID: child.ID, Node: child, OldPath: routePathForContentFile(old.key), OldTitle: old.entry.Title}
+				change.Moved = child.CalculatePath() != change.OldPath
+				if current, ok := after[child.ID]; !ok || current.SHA256 != old.entry.SHA256 || current.Kind != old.entry.Kind {
+					change.ContentChanged = true
+				}
+				if change.Moved || change.ContentChanged {
+					changes = append(changes, change)
+				}
+			}
+			walk(child)
+		}
+	}
+	walk(t.tree)
+
+	var removed []string
+	for id := range before {
+		if _, ok := t.nodesByID[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(removed)
+	for _, id := range removed {
+		old := before[id]
+		node := &PageNode{ID: id, Title: old.entry.Title, Kind: old.entry.Kind, Metadata: old.entry.Metadata}
+		changes = append(changes, FSChange{ID: id, Node: node, Removed: true, OldPath: routePathForContentFile(old.key), OldTitle: old.entry.Title})
+	}
+	return changes
+}
+
+// SaveManifest records the content files of the current tree so the next
+// LoadTreeFromManifest only has to parse the files changed in between. It
+// must only be called when every index is in sync with the tree, i.e. on a
+// clean shutdown. A file edited on disk since the tree read it, without
+// LeafWiki picking the edit up, is recorded with the content LeafWiki knows
+// so that the next start parses it again.
+func (t *TreeService) SaveManifest() error {
+	return t.withTreeClaim(false, func() error {
+		t.mu.RLock()
+		defer t.mu.RUnlock()
+		return t.saveManifestLocked()
+	})
+}
+
+func (t *TreeService) saveManifestLocked() error {
+	if t.tree == nil {
+		return ErrTreeNotLoaded
+	}
+
+	known := t.knownContentLocked()
+
+	m := newManifest(time.Now().UTC())
+	opts := &reconstructOptions{prev: t.manifest, next: m, rootDir: filepath.Join(t.storageDir, "root")}
+	for _, node := range t.nodesByID {
+		if node.Parent == nil {
+			continue
+		}
+		filePath, err := t.store.contentPathForNodeRead(node)
+		if err != nil {
+			return err
+		}
+		current, ok := opts.cached(filePath)
+		if !ok {
+			if current, err = currentManifestEntry(filePath); err != nil {
+				return fmt.Errorf("record %s: %w", filePath, err)
+			}
+			if current == nil {
+				continue
+			}
+		}
+		sum, ok := known[node.ID]
+		if !ok {
+			continue
+		}
+		if current.SHA256 != sum {
+			// The node and the indexes still hold the content LeafWiki
+			// knows; recording its hash without size and mtime makes the
+			// next start parse the file again.
+			current = &manifestEntry{SHA256: sum}
+		}
+		if err := opts.record(filePath, node, current); err != nil {
+			return fmt.Errorf("record %s: %w", filePath, err)
+		}
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	tmp := manifestPath(t.storageDir) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, manifestPath(t.storageDir)); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// knownContentLocked returns the hash of the content each node was last read
+// from or written with, keyed by node ID.
+func (t *TreeService) knownContentLocked() map[string]string {
+	known := map[string]string{}
+	if t.manifest != nil {
+		for _, entry := range t.manifest.Files {
+			known[entry.ID] = entry.SHA256
+		}
+	}
+	for id, sum := range t.store.written.snapshot() {
+		known[id] = sum
+	}
+	// Change tracking also knows the edits SyncFromFS picked up.
+	if fp := t.store.fingerprints.Load(); fp != nil {
+		for id, sum := range fp.snapshot() {
+			known[id] = sum
+		}
+	}
+	return known
+}
+
+// currentManifestEntry returns size, mtime and hash of the file at filePath,
+// or nil if there is no such file.
+func currentManifestEntry(filePath string) (*manifestEntry, error) {
+	var e manifestEntry
+	if _, err := e.stat(filePath); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	sum, err := fileSHA256(filePath)
+	if err != nil {
+		return nil, err
+	}
+	e.SHA256 = sum
+	return &e, nil
+}
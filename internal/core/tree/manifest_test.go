@@ -0,0 +1,264 @@
+package tree
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ageFile moves the mtime of path out of the racy window so that the
+// manifest trusts size and mtime.
+func ageFile(t *testing.T, path string) {
+	t.Helper()
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("chtimes %s: %v", path, err)
+	}
+}
+
+func restartWithManifest(t *testing.T, svc *TreeService, tmpDir string) (*TreeService, []FSChange, bool) {
+	t.Helper()
+	if err := svc.SaveManifest(); err != nil {
+		t.Fatalf("SaveManifest failed: %v", err)
+	}
+	restarted := NewTreeService(tmpDir)
+	changes, full, err := restarted.LoadTreeFromManifest()
+	if err != nil {
+		t.Fatalf("LoadTreeFromManifest failed: %v", err)
+	}
+	return restarted, changes, full
+}
+
+func TestTreeService_LoadTreeFromManifest_WithoutManifestLoadsFully(t *testing.T) {
+	svc, tmpDir := newLoadedService(t)
+	if _, err := svc.CreateNode("alice", nil, "Page", "page", ptrKind(NodeKindPage)); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+
+	restarted := NewTreeService(tmpDir)
+	changes, full, err := restarted.LoadTreeFromManifest()
+	if err != nil {
+		t.Fatalf("LoadTreeFromManifest failed: %v", err)
+	}
+	if !full || changes != nil {
+		t.Fatalf("expected a full load without changes, got full=%v changes=%+v", full, changes)
+	}
+	if len(restarted.GetTree().Children) != 1 {
+		t.Fatalf("expected the page to be loaded")
+	}
+}
+
+func TestTreeService_LoadTreeFromManifest_UnchangedFilesAreNotParsed(t *testing.T) {
+	svc, tmpDir := newLoadedService(t)
+	docsID, err := svc.CreateNode("alice", nil, "Docs", "docs", ptrKind(NodeKindSection))
+	if err != nil {
+		t.Fatalf("CreateNode docs failed: %v", err)
+	}
+	guideID, err := svc.CreateNode("alice", docsID, "Guide", "guide", ptrKind(NodeKindPage))
+	if err != nil {
+		t.Fatalf("CreateNode guide failed: %v", err)
+	}
+	guidePath := filepath.Join(tmpDir, "root", "docs", "guide.md")
+	ageFile(t, guidePath)
+	ageFile(t, filepath.Join(tmpDir, "root", "docs", "index.md"))
+
+	if err := svc.SaveManifest(); err != nil {
+		t.Fatalf("SaveManifest failed: %v", err)
+	}
+	// Same size and mtime, different title: only a parse would notice.
+	raw, err := os.ReadFile(guidePath)
+	if err != nil {
+		t.Fatalf("read guide: %v", err)
+	}
+	info, _ := os.Stat(guidePath)
+	if err := os.WriteFile(guidePath, []byte(strings.Replace(string(raw), "Guide", "Gxxde", 1)), 0o644); err != nil {
+		t.Fatalf("write guide: %v", err)
+	}
+	if err := os.Chtimes(guidePath, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatalf("chtimes guide: %v", err)
+	}
+
+	restarted := NewTreeService(tmpDir)
+	changes, full, err := restarted.LoadTreeFromManifest()
+	if err != nil {
+		t.Fatalf("LoadTreeFromManifest failed: %v", err)
+	}
+	if full || len(changes) != 0 {
+		t.Fatalf("expected an incremental load without changes, got full=%v changes=%+v", full, changes)
+	}
+	guide, err := restarted.FindPageByID(*guideID)
+	if err != nil {
+		t.Fatalf("FindPageByID failed: %v", err)
+	}
+	if guide.Title != "Guide" || guide.CalculatePath() != "/docs/guide" || guide.Parent.ID != *docsID {
+		t.Fatalf("expected guide to be taken from the manifest, got %q at %q", guide.Title, guide.CalculatePath())
+	}
+	if _, err := os.Stat(manifestPath(tmpDir)); !os.IsNotExist(err) {
+		t.Fatalf("expected the manifest to be removed after loading, got %v", err)
+	}
+}
+
+func TestTreeService_LoadTreeFromManifest_ReportsChangesSinceShutdown(t *testing.T) {
+	svc, tmpDir := newLoadedService(t)
+	docsID, err := svc.CreateNode("alice", nil, "Docs", "docs", ptrKind(NodeKindSection))
+	if err != nil {
+		t.Fatalf("CreateNode docs failed: %v", err)
+	}
+	editedID, err := svc.CreateNode("alice", docsID, "Edited", "edited", ptrKind(NodeKindPage))
+	if err != nil {
+		t.Fatalf("CreateNode edited failed: %v", err)
+	}
+	movedID, err := svc.CreateNode("alice", docsID, "Moved", "moved", ptrKind(NodeKindPage))
+	if err != nil {
+		t.Fatalf("CreateNode moved failed: %v", err)
+	}
+	goneID, err := svc.CreateNode("alice", docsID, "Gone", "gone", ptrKind(NodeKindPage))
+	if err != nil {
+		t.Fatalf("CreateNode gone failed: %v", err)
+	}
+	keptID, err := svc.CreateNode("alice", nil, "Kept", "kept", ptrKind(NodeKindPage))
+	if err != nil {
+		t.Fatalf("CreateNode kept failed: %v", err)
+	}
+	if err := svc.SaveManifest(); err != nil {
+		t.Fatalf("SaveManifest failed: %v", err)
+	}
+
+	docsDir := filepath.Join(tmpDir, "root", "docs")
+	appendExternally(t, filepath.Join(docsDir, "edited.md"), "\nEdited while stopped.\n")
+	if err := os.Rename(filepath.Join(docsDir, "moved.md"), filepath.Join(docsDir, "renamed.md")); err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+	if err := os.Remove(filepath.Join(docsDir, "gone.md")); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(docsDir, "new.md"), []byte("# New\n"), 0o644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	restarted := NewTreeService(tmpDir)
+	changes, full, err := restarted.LoadTreeFromManifest()
+	if err != nil {
+		t.Fatalf("LoadTreeFromManifest failed: %v", err)
+	}
+	if full {
+		t.Fatalf("expected an incremental load")
+	}
+
+	if c := findChange(changes, *editedID); c == nil || !c.ContentChanged || c.Moved {
+		t.Fatalf("expected edited to have changed content, got %+v", c)
+	}
+	if c := findChange(changes, *movedID); c == nil || !c.Moved || c.ContentChanged || c.OldPath != "/docs/moved" || c.Node.CalculatePath() != "/docs/renamed" {
+		t.Fatalf("expected moved to be reported as moved from /docs/moved, got %+v", c)
+	}
+	if c := findChange(changes, *goneID); c == nil || !c.Removed || c.OldPath != "/docs/gone" || c.OldTitle != "Gone" {
+		t.Fatalf("expected gone to be removed, got %+v", c)
+	}
+	if findChange(changes, *keptID) != nil || findChange(changes, *docsID) != nil {
+		t.Fatalf("expected unchanged nodes not to be reported, got %+v", changes)
+	}
+	var added int
+	for _, c := range changes {
+		if c.Added {
+			added++
+			if c.Node.CalculatePath() != "/docs/new" {
+				t.Fatalf("expected only /docs/new to be added, got %q", c.Node.CalculatePath())
+			}
+		}
+	}
+	if added != 1 {
+		t.Fatalf("expected one added node, got %+v", changes)
+	}
+
+	// The next clean shutdown starts from the synced state.
+	_, changes, full = restartWithManifest(t, restarted, tmpDir)
+	if full || len(changes) != 0 {
+		t.Fatalf("expected no changes after a second restart, got full=%v changes=%+v", full, changes)
+	}
+}
+
+func TestTreeService_SaveManifest_KeepsEditsTheRunningServerMissed(t *testing.T) {
+	svc, tmpDir := newLoadedService(t)
+	pageID, err := svc.CreateNode("alice", nil, "Old Title", "page", ptrKind(NodeKindPage))
+	if err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+	pagePath := filepath.Join(tmpDir, "root", "page.md")
+
+	editOnDisk := func(from, to string) {
+		t.Helper()
+		raw, err := os.ReadFile(pagePath)
+		if err != nil {
+			t.Fatalf("read page: %v", err)
+		}
+		edited := strings.Replace(string(raw), "title: "+from, "title: "+to, 1) + "\nEdited while " + to + " ran.\n"
+		if err := os.WriteFile(pagePath, []byte(edited), 0o644); err != nil {
+			t.Fatalf("write page: %v", err)
+		}
+	}
+	assertPickedUp := func(restarted *TreeService, changes []FSChange, title string) {
+		t.Helper()
+		if c := findChange(changes, *pageID); c == nil || !c.ContentChanged {
+			t.Fatalf("expected the edit made on disk to be reported, got %+v", changes)
+		}
+		page, err := restarted.FindPageByID(*pageID)
+		if err != nil {
+			t.Fatalf("FindPageByID failed: %v", err)
+		}
+		if page.Title != title {
+			t.Fatalf("expected title %q after the restart, got %q", title, page.Title)
+		}
+	}
+
+	// Edited while a fully loaded server runs without watching the files.
+	editOnDisk("Old Title", "Full Load")
+	restarted, changes, full := restartWithManifest(t, svc, tmpDir)
+	if full {
+		t.Fatalf("expected an incremental load")
+	}
+	assertPickedUp(restarted, changes, "Full Load")
+
+	// Edited while a server started from the manifest runs.
+	editOnDisk("Full Load", "Manifest Load")
+	restarted, changes, _ = restartWithManifest(t, restarted, tmpDir)
+	assertPickedUp(restarted, changes, "Manifest Load")
+
+	// Writes of the server itself are recorded as they are.
+	content := "Saved in the app."
+	if err := restarted.UpdateNode("alice", *pageID, "Saved Title", "page", &content, VersionUnchecked, nil, nil, false); err != nil {
+		t.Fatalf("UpdateNode failed: %v", err)
+	}
+	_, changes, _ = restartWithManifest(t, restarted, tmpDir)
+	if len(changes) != 0 {
+		t.Fatalf("expected no changes after saving in the app, got %+v", changes)
+	}
+}
+
+func TestTreeService_LoadTree_DiscardsManifest(t *testing.T) {
+	svc, tmpDir := newLoadedService(t)
+	if err := svc.SaveManifest(); err != nil {
+		t.Fatalf("SaveManifest failed: %v", err)
+	}
+	if err := NewTreeService(tmpDir).LoadTree(); err != nil {
+		t.Fatalf("LoadTree failed: %v", err)
+	}
+	if _, err := os.Stat(manifestPath(tmpDir)); !os.IsNotExist(err) {
+		t.Fatalf("expected LoadTree to remove the manifest, got %v", err)
+	}
+}
+
+func TestRoutePathForContentFile(t *testing.T) {
+	cases := map[string]string{
+		"docs/index.md": "/docs",
+		"docs/guide.md": "/docs/guide",
+		"page.md":       "/page",
+		"a/b/index.md":  "/a/b",
+	}
+	for key, want := range cases {
+		if got := routePathForContentFile(key); got != want {
+			t.Errorf("routePathForContentFile(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
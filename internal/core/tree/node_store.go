@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/perber/wiki/internal/core/ignore"
@@ -65,10 +66,15 @@ type ResolvedNode struct {
 }
 
 type NodeStore struct {
-	storageDir  string
-	log         *slog.Logger
-	slugger     *SlugService
-	ignoreCache *ignore.Cache
+	storageDir   string
+	log          *slog.Logger
+	slugger      *SlugService
+	ignoreCache  *ignore.Cache
+	fingerprints atomic.Pointer[contentFingerprints]
+	// written holds the content files LeafWiki wrote since the tree was
+	// loaded. Unlike fingerprints it is kept whether or not change tracking
+	// is enabled.
+	written *contentFingerprints
 }
 
 // SetIgnoreCache sets the ignore cache to use for multi-level ignore resolution.
@@ -102,6 +108,7 @@ func NewNodeStore(storageDir string) *NodeStore {
 		storageDir: storageDir,
 		log:        slog.Default().With("component", "NodeStore"),
 		slugger:    NewSlugService(),
+		written:    &contentFingerprints{byID: map[string]string{}},
 	}
 }
 
@@ -115,7 +122,7 @@ func (f *NodeStore) writeReconstructedFrontmatter(mdFile *markdown.MarkdownFile,
 	}
 
 	f.syncManagedFrontmatter(mdFile, entry)
-	if err := mdFile.WriteToFile(); err != nil {
+	if err := f.writeMarkdownFile(mdFile, entry); err != nil {
 		f.log.Error("could not write metadata back to file during reconstruct", "path", mdFile.GetPath(), "error", err)
 		return
 	}
@@ -166,7 +173,7 @@ func (f *NodeStore) ensureSectionIndex(entry *PageNode) (string, error) {
 	}
 
 	f.syncManagedFrontmatter(mdFile, entry)
-	if err := mdFile.WriteToFile(); err != nil {
+	if err := f.writeMarkdownFile(mdFile, entry); err != nil {
 		return "", fmt.Errorf(errWriteMarkdownFailed, err)
 	}
 
@@ -268,6 +275,10 @@ func (f *NodeStore) ReconstructTreeFromFS() (*PageNode, error) {
 }
 
 func (f *NodeStore) ReconstructTreeFromFSContext(ctx context.Context) (*PageNode, error) {
+	return f.reconstructTree(ctx, nil)
+}
+
+func (f *NodeStore) reconstructTree(ctx context.Context, opts *reconstructOptions) (*PageNode, error) {
 	reconstructNow := time.Now().UTC()
 	rootDir := filepath.Join(f.storageDir, "root")
 	root := &PageNode{
@@ -299,13 +310,15 @@ func (f *NodeStore) ReconstructTreeFromFSContext(ctx context.Context) (*PageNode
 		return nil, err
 	}
 
-	if err := f.reconstructTreeRecursive(ctx, rootDir, root, reconstructNow, seenIDs); err != nil {
+	if err := f.reconstructTreeRecursive(ctx, rootDir, root, reconstructNow, seenIDs, opts); err != nil {
 		return nil, fmt.Errorf("reconstruct tree from fs: %w", err)
 	}
 
 	return root, nil
 }
-func (f *NodeStore) reconstructTreeRecursive(ctx context.Context, currentPath string, parent *PageNode, reconstructNow time.Time, seenIDs map[string]string) error {
+
+// reconstructTreeRecursive rebuilds the children of parent from currentPath.
+func (f *NodeStore) reconstructTreeRecursive(ctx context.Context, currentPath string, parent *PageNode, reconstructNow time.Time, seenIDs map[string]string, opts *reconstructOptions) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
@@ -364,7 +377,13 @@ func (f *NodeStore) reconstructTreeRecursive(ctx context.Context, currentPath st
 			var sectionMdFile *markdown.MarkdownFile
 			needsWriteback := false
 			sectionPinned := false
-			if fileExists(indexPath) {
+			cached, isCached := opts.cached(indexPath)
+			if isCached {
+				metadata = cached.Metadata
+				title = cached.Title
+				id = cached.ID
+				sectionPinned = cached.Pinned
+			} else if fileExists(indexPath) {
 				mdFile, err := markdown.LoadMarkdownFile(indexPath)
 				if err != nil {
 					f.log.Error("could not load index.md", "path", indexPath, "error", err)
@@ -425,8 +444,17 @@ func (f *NodeStore) reconstructTreeRecursive(ctx context.Context, currentPath st
 					return fmt.Errorf("materialize missing section index for %s: %w", indexPath, err)
 				}
 			}
+			if err := opts.record(indexPath, child, cached); err != nil {
+				return fmt.Errorf("record %s: %w", indexPath, err)
+			}
 
-			if err := f.reconstructTreeRecursive(ctx, filepath.Join(currentPath, name), child, reconstructNow, seenIDs); err != nil {
+			if existing, ok := opts.reused(filepath.Join(currentPath, name)); ok {
+				child.Children = existing.Children
+				f.assignParentToChildren(child)
+				continue
+			}
+
+			if err := f.reconstructTreeRecursive(ctx, filepath.Join(currentPath, name), child, reconstructNow, seenIDs, opts); err != nil {
 				return err
 			}
 			continue
@@ -450,6 +478,27 @@ func (f *NodeStore) reconstructTreeRecursive(ctx context.Context, currentPath st
 
 		filePath := filepath.Join(currentPath, name)
 
+		if cached, ok := opts.cached(filePath); ok {
+			child := nodeFromManifestEntry(cached, baseFilename, NodeKindPage, parent)
+			if err := ensureUniqueReconstructedSlug(seenSlugs, child.Slug, filePath); err != nil {
+				return err
+			}
+			conflictPath, err := ensureUniqueReconstructedID(seenIDs, child.ID, filePath)
+			if err != nil {
+				return err
+			}
+			if conflictPath != "" {
+				f.log.Warn("skipping page: duplicate leafwiki_id, keeping first occurrence",
+					"leafwikiID", child.ID, "path", filePath, "conflictingPath", conflictPath)
+				continue
+			}
+			parent.Children = append(parent.Children, child)
+			if err := opts.record(filePath, child, cached); err != nil {
+				return fmt.Errorf("record %s: %w", filePath, err)
+			}
+			continue
+		}
+
 		mdFile, err := markdown.LoadMarkdownFile(filePath)
 		if err != nil {
 			f.log.Error("could not load markdown file", "path", filePath, "error", err)
@@ -497,6 +546,9 @@ func (f *NodeStore) reconstructTreeRecursive(ctx context.Context, currentPath st
 			f.writeReconstructedFrontmatter(mdFile, child)
 		}
 		parent.Children = append(parent.Children, child)
+		if err := opts.record(filePath, child, nil); err != nil {
+			return fmt.Errorf("record %s: %w", filePath, err)
+		}
 	}
 
 	f.applyChildOrder(parent, currentPath)
@@ -656,7 +708,7 @@ func (f *NodeStore) CreatePage(parentEntry *PageNode, newEntry *PageNode) error
 
 	mdFile := markdown.NewMarkdownFile(destFile, "# "+newEntry.Title+"\n", markdown.Frontmatter{})
 	f.syncManagedFrontmatter(mdFile, newEntry)
-	if err := mdFile.WriteToFile(); err != nil {
+	if err := f.writeMarkdownFile(mdFile, newEntry); err != nil {
 		return fmt.Errorf("could not create file: %w", err)
 	}
 
@@ -748,7 +800,7 @@ func (f *NodeStore) UpsertContent(entry *PageNode, content string) error {
 
 	mdFile.SetContent(content)
 	f.syncManagedFrontmatter(mdFile, entry)
-	if err := mdFile.WriteToFile(); err != nil {
+	if err := f.writeMarkdownFile(mdFile, entry); err != nil {
 		return fmt.Errorf(errWriteMarkdownFailed, err)
 	}
 
@@ -780,7 +832,7 @@ func (f *NodeStore) UpsertContentPreservingFrontmatter(entry *PageNode, content
 		return fmt.Errorf("could not parse markdown content: %w", err)
 	}
 	f.syncManagedFrontmatter(mdFile, entry)
-	if err := mdFile.WriteToFile(); err != nil {
+	if err := f.writeMarkdownFile(mdFile, entry); err != nil {
 		return fmt.Errorf(errWriteMarkdownFailed, err)
 	}
 
@@ -865,7 +917,7 @@ func (f *NodeStore) UpsertContentAndMetadata(
 	mdFile.SetExtraFields(extra)
 
 	f.syncManagedFrontmatter(mdFile, entry)
-	if err := mdFile.WriteToFile(); err != nil {
+	if err := f.writeMarkdownFile(mdFile, entry); err != nil {
 		return fmt.Errorf(errWriteMarkdownFailed, err)
 	}
 
@@ -1228,7 +1280,7 @@ func (f *NodeStore) SyncFrontmatterIfExists(entry *PageNode) error {
 	}
 
 	f.syncManagedFrontmatter(mdFile, entry)
-	if err := mdFile.WriteToFile(); err != nil {
+	if err := f.writeMarkdownFile(mdFile, entry); err != nil {
 		return fmt.Errorf("write markdown file: %w", err)
 	}
 	return nil
@@ -1447,7 +1499,7 @@ func (f *NodeStore) ConvertNode(entry *PageNode, target NodeKind) error {
 		} else {
 			mdFile := markdown.NewMarkdownFile(filePath, "", markdown.Frontmatter{})
 			f.syncManagedFrontmatter(mdFile, entry)
-			if err := mdFile.WriteToFile(); err != nil {
+			if err := f.writeMarkdownFile(mdFile, entry); err != nil {
 				return fmt.Errorf("could not write page file: %w", err)
 			}
 		}
@@ -1493,7 +1545,7 @@ func (f *NodeStore) SetPinnedFrontmatter(entry *PageNode, pinned bool) (string,
 
 	mdFile.SetLeafWikiPinned(pinned)
 
-	if err := mdFile.WriteToFile(); err != nil {
+	if err := f.writeMarkdownFile(mdFile, entry); err != nil {
 		return "", fmt.Errorf("write markdown file: %w", err)
 	}
 
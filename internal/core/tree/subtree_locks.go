@@ -0,0 +1,186 @@
+package tree
+
+import (
+	"slices"
+	"sync"
+)
+
+// Locking in TreeService
+//
+// TreeService uses two levels of locking so that slow disk work in one part
+// of the tree does not hold up reads and writes everywhere else:
+//
+//   - Claims are taken first and held for a whole operation, including its
+//     disk I/O. A claim covers a single node or a node with all of its
+//     descendants, for reading or for writing. Two claims conflict when one
+//     covers the node of the other and at least one of them writes.
+//   - mu guards the in-memory tree and its indexes. It is only taken while
+//     the claims are held, and only for in-memory work. Writers stage their
+//     changes on disk using detached copies of the nodes involved and publish
+//     the result under mu.Lock in one step, so readers never see a half
+//     applied change and a failed write only has to roll back the disk.
+//
+// All claims of an operation are acquired at once and before mu; claims are
+// never acquired while mu is held. Waiting operations are granted in arrival
+// order, so operations can neither deadlock nor starve each other.
+//
+// Which node a claim covers is decided by IDs: a claim records the IDs from
+// the root down to its node, which is what it is compared against. Node
+// fields are only written under mu.Lock by the holder of a claim covering
+// the node; code that only holds claims may read the fields of the nodes
+// those claims cover and of their ancestors.
+
+// claim is one node an operation reads or writes.
+type claim struct {
+	id string
+	// path holds the IDs from the root down to id.
+	path []string
+	// subtree extends the claim to all descendants of the node.
+	subtree bool
+	write   bool
+}
+
+// rootID is the ID of the root node of every tree.
+const rootID = "root"
+
+// treeClaim covers the whole tree.
+func treeClaim(write bool) claim {
+	return claim{id: rootID, path: []string{rootID}, subtree: true, write: write}
+}
+
+// nodeClaimLocked returns a claim on node. The read lock must be held.
+func nodeClaimLocked(node *PageNode, subtree, write bool) claim {
+	var path []string
+	for n := node; n != nil; n = n.Parent {
+		path = append(path, n.ID)
+	}
+	slices.Reverse(path)
+	return claim{id: node.ID, path: path, subtree: subtree, write: write}
+}
+
+func (c claim) covers(other claim) bool {
+	if c.id == other.id {
+		return true
+	}
+	return c.subtree && slices.Contains(other.path, c.id)
+}
+
+func (c claim) conflicts(other claim) bool {
+	return (c.write || other.write) && (c.covers(other) || other.covers(c))
+}
+
+func sameClaims(a, b []claim) bool {
+	return slices.EqualFunc(a, b, func(x, y claim) bool {
+		return x.id == y.id && x.subtree == y.subtree && x.write == y.write && slices.Equal(x.path, y.path)
+	})
+}
+
+// claimSet holds the claims of one operation.
+type claimSet struct {
+	claims  []claim
+	granted bool
+}
+
+func (s *claimSet) conflicts(other *claimSet) bool {
+	for _, a := range s.claims {
+		for _, b := range other.claims {
+			if a.conflicts(b) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// subtreeLocks hands out claims.
+type subtreeLocks struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	// queue holds the granted and the waiting claim sets in arrival order.
+	queue []*claimSet
+}
+
+func newSubtreeLocks() *subtreeLocks {
+	l := &subtreeLocks{}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire blocks until claims can be granted together. A set is granted
+// when it conflicts neither with a granted set nor with a set that has been
+// waiting longer.
+func (l *subtreeLocks) acquire(claims []claim) *claimSet {
+	set := &claimSet{claims: claims}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.queue = append(l.queue, set)
+	for !l.grantableLocked(set) {
+		l.cond.Wait()
+	}
+	set.granted = true
+	return set
+}
+
+func (l *subtreeLocks) grantableLocked(set *claimSet) bool {
+	earlier := true
+	for _, other := range l.queue {
+		if other == set {
+			earlier = false
+			continue
+		}
+		if (other.granted || earlier) && set.conflicts(other) {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *subtreeLocks) release(set *claimSet) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if i := slices.Index(l.queue, set); i >= 0 {
+		l.queue = slices.Delete(l.queue, i, i+1)
+	}
+	l.cond.Broadcast()
+}
+
+// withClaims runs fn while holding the claims returned by resolve. resolve
+// runs under the read lock; it is called again once the claims are held,
+// and the claims are taken anew if the tree changed in between. fn is
+// called without mu held.
+func (t *TreeService) withClaims(resolve func() ([]claim, error), fn func() error) error {
+	for {
+		var claims []claim
+		err := t.withRLockedTree(func() (err error) {
+			claims, err = resolve()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		set := t.locks.acquire(claims)
+		var current []claim
+		err = t.withRLockedTree(func() (err error) {
+			current, err = resolve()
+			return err
+		})
+		if err == nil && sameClaims(claims, current) {
+			defer t.locks.release(set)
+			return fn()
+		}
+		t.locks.release(set)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// withTreeClaim runs fn while holding a claim on the whole tree.
+func (t *TreeService) withTreeClaim(write bool, fn func() error) error {
+	set := t.locks.acquire([]claim{treeClaim(write)})
+	defer t.locks.release(set)
+	return fn()
+}
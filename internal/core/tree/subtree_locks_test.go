@@ -0,0 +1,241 @@
+package tree
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClaim_Conflicts(t *testing.T) {
+	docs := claim{id: "docs", path: []string{"root", "docs"}}
+	guide := claim{id: "guide", path: []string{"root", "docs", "guide"}}
+	blog := claim{id: "blog", path: []string{"root", "blog"}}
+
+	with := func(c claim, subtree, write bool) claim {
+		c.subtree = subtree
+		c.write = write
+		return c
+	}
+	cases := []struct {
+		name string
+		a, b claim
+		want bool
+	}{
+		{"readers share a node", with(guide, false, false), with(guide, false, false), false},
+		{"writer excludes reader of the node", with(guide, false, true), with(guide, false, false), true},
+		{"subtree writer excludes reader below", with(docs, true, true), with(guide, false, false), true},
+		{"node writer leaves nodes below alone", with(docs, false, true), with(guide, false, true), false},
+		{"subtree reader excludes writer below", with(docs, true, false), with(guide, false, true), true},
+		{"independent subtrees", with(docs, true, true), with(blog, true, true), false},
+		{"tree claim covers everything", treeClaim(true), with(guide, false, false), true},
+		{"tree readers share", treeClaim(false), with(guide, false, false), false},
+	}
+	for _, tc := range cases {
+		if got := tc.a.conflicts(tc.b); got != tc.want {
+			t.Errorf("%s: a.conflicts(b) = %v, want %v", tc.name, got, tc.want)
+		}
+		if got := tc.b.conflicts(tc.a); got != tc.want {
+			t.Errorf("%s: b.conflicts(a) = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSubtreeLocks_GrantsWaitingSetsInArrivalOrder(t *testing.T) {
+	l := newSubtreeLocks()
+	docs := claim{id: "docs", path: []string{"root", "docs"}, subtree: true, write: true}
+	guide := claim{id: "guide", path: []string{"root", "docs", "guide"}}
+
+	held := l.acquire([]claim{docs})
+
+	order := make(chan string, 2)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		set := l.acquire([]claim{docs})
+		order <- "writer"
+		time.Sleep(20 * time.Millisecond)
+		l.release(set)
+	}()
+	// Let the writer queue up first; the reader arriving later must not
+	// overtake it even though readers could share the node.
+	time.Sleep(20 * time.Millisecond)
+	go func() {
+		defer wg.Done()
+		set := l.acquire([]claim{guide})
+		order <- "reader"
+		l.release(set)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	l.release(held)
+	wg.Wait()
+	close(order)
+	var got []string
+	for name := range order {
+		got = append(got, name)
+	}
+	if fmt.Sprint(got) != "[writer reader]" {
+		t.Fatalf("expected the waiting writer to be granted first, got %v", got)
+	}
+}
+
+func TestTreeService_ClaimedSubtreeOnlyBlocksItsOwnPages(t *testing.T) {
+	svc, _ := newLoadedService(t)
+	docsID, err := svc.CreateNode("alice", nil, "Docs", "docs", ptrKind(NodeKindSection))
+	if err != nil {
+		t.Fatalf("CreateNode docs failed: %v", err)
+	}
+	guideID, err := svc.CreateNode("alice", docsID, "Guide", "guide", ptrKind(NodeKindPage))
+	if err != nil {
+		t.Fatalf("CreateNode guide failed: %v", err)
+	}
+	blogID, err := svc.CreateNode("alice", nil, "Blog", "blog", ptrKind(NodeKindPage))
+	if err != nil {
+		t.Fatalf("CreateNode blog failed: %v", err)
+	}
+
+	// Hold docs the way a slow recursive write would.
+	var docsClaim claim
+	_ = svc.withRLockedTree(func() error {
+		docsClaim = nodeClaimLocked(svc.getNodeByIDLocked(*docsID), true, true)
+		return nil
+	})
+	held := svc.locks.acquire([]claim{docsClaim})
+
+	if _, err := svc.GetPage(*blogID); err != nil {
+		t.Fatalf("GetPage blog failed: %v", err)
+	}
+	content := "Written next to the busy section."
+	if err := svc.UpdateNode("alice", *blogID, "Blog", "blog", &content, VersionUnchecked, nil, nil, false); err != nil {
+		t.Fatalf("UpdateNode blog failed: %v", err)
+	}
+	if _, err := svc.FindPageByID(*guideID); err != nil {
+		t.Fatalf("FindPageByID guide failed: %v", err)
+	}
+
+	read := make(chan error, 1)
+	go func() {
+		_, err := svc.GetPage(*guideID)
+		read <- err
+	}()
+	select {
+	case err := <-read:
+		t.Fatalf("expected reading a page of the claimed section to wait, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	svc.locks.release(held)
+	select {
+	case err := <-read:
+		if err != nil {
+			t.Fatalf("GetPage guide failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the read after releasing the claim")
+	}
+}
+
+func TestTreeService_ConcurrentWritesKeepTreeAndDiskInSync(t *testing.T) {
+	svc, tmpDir := newLoadedService(t)
+	sections := make([]string, 4)
+	for i := range sections {
+		id, err := svc.CreateNode("alice", nil, fmt.Sprintf("Section %d", i), fmt.Sprintf("section-%d", i), ptrKind(NodeKindSection))
+		if err != nil {
+			t.Fatalf("CreateNode section failed: %v", err)
+		}
+		sections[i] = *id
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i, sectionID := range sections {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var pages []string
+			for j := range 8 {
+				id, err := svc.CreateNode("alice", &sectionID, fmt.Sprintf("Page %d", j), fmt.Sprintf("page-%d-%d", i, j), ptrKind(NodeKindPage))
+				if err != nil {
+					errs <- err
+					return
+				}
+				pages = append(pages, *id)
+			}
+			content := fmt.Sprintf("Content of section %d", i)
+			updates := make([]BulkContentUpdate, len(pages))
+			for j, id := range pages {
+				updates[j] = BulkContentUpdate{ID: id, Content: content}
+			}
+			for _, err := range svc.BulkUpdateContent("alice", updates) {
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+			// Move a page into the next section, whose writer runs concurrently.
+			if err := svc.MoveNode("alice", pages[0], sections[(i+1)%len(sections)], VersionUnchecked); err != nil {
+				errs <- err
+				return
+			}
+			if err := svc.UpdateNode("alice", pages[1], "Renamed", fmt.Sprintf("renamed-%d", i), nil, VersionUnchecked, nil, nil, false); err != nil {
+				errs <- err
+				return
+			}
+			if err := svc.DeleteNode("alice", pages[2], false, VersionUnchecked); err != nil {
+				errs <- err
+				return
+			}
+		}()
+	}
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				if err := svc.WalkNodes(func(id string) error {
+					if _, err := svc.GetPage(id); err != nil && !errors.Is(err, ErrPageNotFound) {
+						return err
+					}
+					return nil
+				}); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("concurrent operation failed: %v", err)
+	}
+
+	reloaded := NewTreeService(tmpDir)
+	if err := reloaded.LoadTree(); err != nil {
+		t.Fatalf("LoadTree failed: %v", err)
+	}
+	var describe func(node *PageNode) string
+	describe = func(node *PageNode) string {
+		s := node.ID + ":" + node.Slug + "["
+		for _, child := range node.Children {
+			s += describe(child) + " "
+		}
+		return s + "]"
+	}
+	if got, want := describe(reloaded.GetTree()), describe(svc.GetTree()); got != want {
+		t.Fatalf("tree on disk differs from the tree in memory:\ndisk:   %s\nmemory: %s", got, want)
+	}
+	for _, sectionID := range sections {
+		section, err := svc.FindPageByID(sectionID)
+		if err != nil {
+			t.Fatalf("FindPageByID failed: %v", err)
+		}
+		// 8 created, one moved out, one moved in, one deleted.
+		if len(section.Children) != 7 {
+			t.Fatalf("expected 7 pages in %s, got %d", section.Slug, len(section.Children))
+		}
+	}
+}
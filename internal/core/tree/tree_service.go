@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -27,8 +28,14 @@ type TreeService struct {
 	nodesByID    map[string]*PageNode
 	nodesByTitle map[string][]*PageNode
 	childSlugs   map[string]map[string]*PageNode
-
-	mu sync.RWMutex
+	// manifest describes the content files as of the last load; SaveManifest
+	// reuses its hashes for files that did not change.
+	manifest *manifest
+
+	// locks hands out the claims that writers hold during disk I/O; mu
+	// only guards the in-memory tree. See subtree_locks.go.
+	locks *subtreeLocks
+	mu    sync.RWMutex
 }
 
 const (
@@ -50,6 +57,7 @@ func NewTreeService(storageDir string) *TreeService {
 		nodesByID:    make(map[string]*PageNode),
 		nodesByTitle: make(map[string][]*PageNode),
 		childSlugs:   make(map[string]map[string]*PageNode),
+		locks:        newSubtreeLocks(),
 	}
 }
 
@@ -60,9 +68,21 @@ func (t *TreeService) SetIgnoreCache(ignoreCache *ignore.Cache) {
 
 // LoadTree reconstructs the in-memory tree from the filesystem.
 // Legacy tree.json data is only used as a migration source for older schema versions.
+// A manifest left by SaveManifest is discarded; LoadTreeFromManifest makes use of it.
 func (t *TreeService) LoadTree() error {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+	return t.withTreeClaim(true, func() error {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return t.loadTreeLocked()
+	})
+}
+
+func (t *TreeService) loadTreeLocked() error {
+	if err := removeManifest(t.storageDir); err != nil {
+		return fmt.Errorf("remove manifest: %w", err)
+	}
+	t.manifest = nil
+	t.store.written.reset()
 
 	t.log.Info("Checking schema version...")
 	schema, err := loadSchema(t.storageDir)
@@ -72,13 +92,10 @@ func (t *TreeService) LoadTree() error {
 	}
 
 	if schema.Version == CurrentSchemaVersion {
-		reconstructed, err := t.store.ReconstructTreeFromFS()
+		reconstructed, err := t.reconstructWithManifestLocked()
 		if err != nil {
 			return err
 		}
-		if reconstructed == nil {
-			return errors.New(errNilTreeReconstructed)
-		}
 		t.tree = reconstructed
 		t.rebuildIndexesLocked()
 		return nil
@@ -113,13 +130,10 @@ func (t *TreeService) LoadTree() error {
 		return err
 	}
 
-	reconstructed, err := t.store.ReconstructTreeFromFS()
+	reconstructed, err := t.reconstructWithManifestLocked()
 	if err != nil {
 		return err
 	}
-	if reconstructed == nil {
-		return errors.New(errNilTreeReconstructed)
-	}
 
 	t.tree = reconstructed
 	t.rebuildIndexesLocked()
@@ -157,13 +171,19 @@ func (t *TreeService) TreeHash() string {
 
 // ReconstructTreeFromFS reconstructs the tree from the filesystem.
 // The slow FS scan runs without holding the write lock so readers can
-// continue serving the current tree concurrently. The lock is acquired
-// only for the fast in-memory swap and index rebuild.
+// continue serving the current tree concurrently; writers wait for it. The
+// lock is acquired only for the fast in-memory swap and index rebuild.
 func (t *TreeService) ReconstructTreeFromFS() error {
 	return t.ReconstructTreeFromFSContext(context.Background())
 }
 
 func (t *TreeService) ReconstructTreeFromFSContext(ctx context.Context) error {
+	return t.withTreeClaim(false, func() error {
+		return t.reconstructTreeFromFS(ctx)
+	})
+}
+
+func (t *TreeService) reconstructTreeFromFS(ctx context.Context) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
@@ -204,13 +224,37 @@ type createNodeResult struct {
 
 type createNodeOptions struct {
 	existingID string
+	// restore, when set, is written to the new node before it is published.
+	restore *restoredContent
+}
+
+type restoredContent struct {
+	content  string
+	metadata PageMetadata
+}
+
+// parentClaims claims the children of the parent a node is created under.
+func (t *TreeService) parentClaims(parentID *string) func() ([]claim, error) {
+	return func() ([]claim, error) {
+		if t.tree == nil {
+			return nil, ErrTreeNotLoaded
+		}
+		parent := t.tree
+		if parentID != nil && *parentID != "" && *parentID != "root" {
+			parent = t.getNodeByIDLocked(*parentID)
+			if parent == nil {
+				return nil, ErrParentNotFound
+			}
+		}
+		return []claim{nodeClaimLocked(parent, false, true)}, nil
+	}
 }
 
 // Create Node adds a new node to the tree
 func (t *TreeService) CreateNode(userID string, parentID *string, title string, slug string, nodeKind *NodeKind) (*string, error) {
 	var result *string
-	err := t.withLockedTree(func() error {
-		created, err := t.createNodeLocked(userID, parentID, title, slug, nodeKind, createNodeOptions{})
+	err := t.withClaims(t.parentClaims(parentID), func() error {
+		created, err := t.createNodeClaimed(userID, parentID, title, slug, nodeKind, createNodeOptions{})
 		if err != nil {
 			return err
 		}
@@ -224,85 +268,88 @@ func (t *TreeService) CreateNode(userID string, parentID *string, title string,
 
 func (t *TreeService) RestoreNode(userID, id string, parentID *string, title, slug string, nodeKind NodeKind, content string, metadata PageMetadata) (*Page, error) {
 	var restored *Page
-	err := t.withLockedTree(func() error {
+	err := t.withClaims(t.parentClaims(parentID), func() error {
 		kind := nodeKind
-		created, err := t.createNodeLocked(userID, parentID, title, slug, &kind, createNodeOptions{existingID: id})
+		created, err := t.createNodeClaimed(userID, parentID, title, slug, &kind, createNodeOptions{
+			existingID: id,
+			restore:    &restoredContent{content: content, metadata: metadata},
+		})
 		if err != nil {
 			return err
 		}
 
-		if err := t.store.UpsertContent(created.entry, content); err != nil {
-			return fmt.Errorf("could not restore content: %w", err)
-		}
-
-		created.entry.Metadata = metadata
-		created.entry.Metadata.UpdatedAt = metadata.UpdatedAt.UTC()
-		created.entry.Metadata.CreatedAt = metadata.CreatedAt.UTC()
-		created.entry.Metadata.CreatorID = strings.TrimSpace(metadata.CreatorID)
-		created.entry.Metadata.LastAuthorID = strings.TrimSpace(metadata.LastAuthorID)
-		if err := t.store.SyncFrontmatterIfExists(created.entry); err != nil {
-			return fmt.Errorf("could not sync restored frontmatter: %w", err)
-		}
-
 		restored = &Page{PageNode: created.entry, Content: content}
 		return nil
 	})
 	return restored, err
 }
 
-// createNodeLocked creates a new node under the given parent.
-// Lock must be held by the caller.
-func (t *TreeService) createNodeLocked(userID string, parentID *string, title string, slug string, kind *NodeKind, opts createNodeOptions) (*createNodeResult, error) {
-	if t.tree == nil {
-		return nil, ErrTreeNotLoaded
-	}
-
+// createNodeClaimed creates a new node under the given parent. The caller
+// must hold a write claim on the parent and must not hold mu. The node is
+// published to the tree only after it is complete on disk; until then the
+// parent is staged as a detached copy.
+func (t *TreeService) createNodeClaimed(userID string, parentID *string, title string, slug string, kind *NodeKind, opts createNodeOptions) (*createNodeResult, error) {
 	// Decide which kind we create
 	k := NodeKindPage
 	if kind != nil {
 		k = *kind
 	}
 
-	// Resolve the parent
-	parent := t.tree
-	if parentID != nil && *parentID != "" && *parentID != "root" {
-		parent = t.getNodeByIDLocked(*parentID)
-		if parent == nil {
-			return nil, ErrParentNotFound
+	id := strings.TrimSpace(opts.existingID)
+	var parent *PageNode
+	var staged PageNode
+	err := t.withRLockedTree(func() error {
+		if t.tree == nil {
+			return ErrTreeNotLoaded
+		}
+
+		// Resolve the parent
+		parent = t.tree
+		if parentID != nil && *parentID != "" && *parentID != "root" {
+			parent = t.getNodeByIDLocked(*parentID)
+			if parent == nil {
+				return ErrParentNotFound
+			}
+		}
+
+		// Check if a child with the same slug already exists
+		if t.findChildBySlugInParentLocked(parent, slug) != nil {
+			return ErrPageAlreadyExists
+		}
+		if id != "" && t.getNodeByIDLocked(id) != nil {
+			return fmt.Errorf("page id already exists: %s", id)
 		}
-	}
 
-	// Check if a child with the same slug already exists
-	if t.findChildBySlugInParentLocked(parent, slug) != nil {
-		return nil, ErrPageAlreadyExists
+		staged = *parent
+		staged.Children = slices.Clone(parent.Children)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	parentWasConverted := false
 
 	// Check if the current parent is a section
 	// if not, we need to convert it to a section
-	if parent.Kind != NodeKindSection && parent.ID != "root" {
-		t.log.Info("converting parent to section", "parentID", parent.ID, "oldKind", parent.Kind, "newKind", NodeKindSection)
-		if err := t.store.ConvertNode(parent, NodeKindSection); err != nil {
+	if staged.Kind != NodeKindSection && staged.ID != "root" {
+		t.log.Info("converting parent to section", "parentID", staged.ID, "oldKind", staged.Kind, "newKind", NodeKindSection)
+		if err := t.store.ConvertNode(&staged, NodeKindSection); err != nil {
 			return nil, fmt.Errorf("could not convert parent node: %w", err)
 		}
-		parent.Kind = NodeKindSection
+		staged.Kind = NodeKindSection
 		parentWasConverted = true
 	}
 
-	if parent.Kind != NodeKindSection {
-		return nil, fmt.Errorf("cannot add child to non-section parent, got %q", parent.Kind)
+	if staged.Kind != NodeKindSection {
+		return nil, fmt.Errorf("cannot add child to non-section parent, got %q", staged.Kind)
 	}
 
-	id := strings.TrimSpace(opts.existingID)
 	if id == "" {
-		var err error
 		id, err = shared.GenerateUniqueID()
 		if err != nil {
-			return nil, fmt.Errorf("could not generate unique ID: %w", err)
+			return nil, errors.Join(fmt.Errorf("could not generate unique ID: %w", err), t.rollbackCreatedNode(&staged, nil, parentWasConverted))
 		}
-	} else if existing := t.getNodeByIDLocked(id); existing != nil {
-		return nil, fmt.Errorf("page id already exists: %s", id)
 	}
 
 	now := time.Now().UTC()
@@ -313,7 +360,7 @@ func (t *TreeService) createNodeLocked(userID string, parentID *string, title st
 		Parent:   parent,
 		Slug:     slug,
 		Kind:     k,
-		Position: len(parent.Children), // Set the position to the end of the list
+		Position: len(staged.Children), // Set the position to the end of the list
 		Children: []*PageNode{},
 		Metadata: PageMetadata{
 			CreatedAt:    now,
@@ -322,29 +369,64 @@ func (t *TreeService) createNodeLocked(userID string, parentID *string, title st
 			LastAuthorID: userID,
 		},
 	}
+	if opts.restore != nil {
+		metadata := opts.restore.metadata
+		entry.Metadata = PageMetadata{
+			CreatedAt:    metadata.CreatedAt.UTC(),
+			UpdatedAt:    metadata.UpdatedAt.UTC(),
+			CreatorID:    strings.TrimSpace(metadata.CreatorID),
+			LastAuthorID: strings.TrimSpace(metadata.LastAuthorID),
+		}
+	}
 
 	// Create on disk depending on kind
+	var createErr error
 	switch k {
 	case NodeKindPage:
-		if err := t.store.CreatePage(parent, entry); err != nil {
-			return nil, fmt.Errorf("could not create page entry: %w", err)
+		if err := t.store.CreatePage(&staged, entry); err != nil {
+			createErr = fmt.Errorf("could not create page entry: %w", err)
 		}
 	case NodeKindSection:
-		if err := t.store.CreateSection(parent, entry); err != nil {
-			return nil, fmt.Errorf("could not create section entry: %w", err)
+		if err := t.store.CreateSection(&staged, entry); err != nil {
+			createErr = fmt.Errorf("could not create section entry: %w", err)
+		}
+	}
+	if createErr != nil {
+		if rollbackErr := t.rollbackCreatedNode(&staged, nil, parentWasConverted); rollbackErr != nil {
+			return nil, errors.Join(createErr, fmt.Errorf("rollback created node: %w", rollbackErr))
+		}
+		return nil, createErr
+	}
+
+	if opts.restore != nil {
+		if err := t.store.UpsertContent(entry, opts.restore.content); err != nil {
+			restoreErr := fmt.Errorf("could not restore content: %w", err)
+			if rollbackErr := t.rollbackCreatedNode(&staged, entry, parentWasConverted); rollbackErr != nil {
+				return nil, errors.Join(restoreErr, fmt.Errorf("rollback created node: %w", rollbackErr))
+			}
+			return nil, restoreErr
 		}
 	}
 
 	// Add the new page to the parent
-	parent.Children = append(parent.Children, entry)
-	t.indexNodeLocked(entry)
-	if err := t.store.SaveChildOrder(parent); err != nil {
-		rollbackErr := t.rollbackCreatedNodeLocked(parent, entry, parentWasConverted)
+	staged.Children = append(staged.Children, entry)
+	if err := t.store.SaveChildOrder(&staged); err != nil {
+		rollbackErr := t.rollbackCreatedNode(&staged, entry, parentWasConverted)
 		if rollbackErr != nil {
 			return nil, errors.Join(fmt.Errorf(errPersistChildOrderFailed, err), fmt.Errorf("rollback created node: %w", rollbackErr))
 		}
 		return nil, fmt.Errorf(errPersistChildOrderFailed, err)
 	}
+
+	_ = t.withLockedTree(func() error {
+		if parentWasConverted {
+			parent.Kind = NodeKindSection
+		}
+		parent.Children = append(parent.Children, entry)
+		t.indexNodeLocked(entry)
+		return nil
+	})
+
 	return &createNodeResult{
 		id:                 entry.ID,
 		entry:              entry,
@@ -353,45 +435,40 @@ func (t *TreeService) createNodeLocked(userID string, parentID *string, title st
 	}, nil
 }
 
-func (t *TreeService) rollbackCreatedNodeLocked(parent *PageNode, entry *PageNode, parentWasConverted bool) error {
-	if parent == nil || entry == nil {
-		return nil
-	}
-
-	for i, child := range parent.Children {
-		if child == entry {
-			parent.Children = append(parent.Children[:i], parent.Children[i+1:]...)
-			break
+// rollbackCreatedNode removes a node staged by createNodeClaimed from disk
+// and folds a parent that was converted for it back into a page. Neither
+// change has been published to the tree, so only the disk is restored.
+func (t *TreeService) rollbackCreatedNode(staged *PageNode, entry *PageNode, parentWasConverted bool) error {
+	if entry != nil {
+		switch entry.Kind {
+		case NodeKindSection:
+			if err := t.store.DeleteSection(entry); err != nil {
+				return err
+			}
+		case NodeKindPage:
+			if err := t.store.DeletePage(entry); err != nil {
+				return err
+			}
 		}
 	}
-	t.removeNodeIndexLocked(entry)
 
-	switch entry.Kind {
-	case NodeKindSection:
-		if err := t.store.DeleteSection(entry); err != nil {
-			return err
-		}
-	case NodeKindPage:
-		if err := t.store.DeletePage(entry); err != nil {
-			return err
-		}
+	if parentWasConverted {
+		return t.foldBackToPage(staged)
 	}
+	return nil
+}
 
-	if parentWasConverted && len(parent.Children) == 0 {
-		orderPath, err := t.store.dirPathForNode(parent)
-		if err != nil {
-			return err
-		}
-		if err := os.Remove(filepath.Join(orderPath, orderFilename)); err != nil && !errors.Is(err, os.ErrNotExist) {
-			return fmt.Errorf("remove parent order file before fold-back: %w", err)
-		}
-		if err := t.store.ConvertNode(parent, NodeKindPage); err != nil {
-			return err
-		}
-		parent.Kind = NodeKindPage
+// foldBackToPage turns a section that was converted from a page for a new
+// child back into a page on disk. staged is a detached copy of the section.
+func (t *TreeService) foldBackToPage(staged *PageNode) error {
+	orderPath, err := t.store.dirPathForNode(staged)
+	if err != nil {
+		return err
 	}
-
-	return nil
+	if err := os.Remove(filepath.Join(orderPath, orderFilename)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove parent order file before fold-back: %w", err)
+	}
+	return t.store.ConvertNode(staged, NodeKindPage)
 }
 
 // FindPagesByTitle returns all nodes whose title matches the given string
@@ -418,6 +495,55 @@ func (t *TreeService) FindPagesByTitle(title string) []*PageNode {
 	return nodes
 }
 
+// SuggestPagesByTitle returns up to limit nodes whose title starts with the
+// prefix, or has a word that does (case-insensitive). Titles starting with
+// the prefix come first, then shorter titles, then alphabetically. Returns
+// nil when the tree is not loaded or the prefix is empty.
+func (t *TreeService) SuggestPagesByTitle(prefix string, limit int) []*PageNode {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if t.tree == nil || prefix == "" || limit <= 0 {
+		return nil
+	}
+
+	type suggestion struct {
+		title string
+		rank  int
+	}
+	var matches []suggestion
+	for title := range t.nodesByTitle {
+		switch {
+		case strings.HasPrefix(title, prefix):
+			matches = append(matches, suggestion{title: title, rank: 0})
+		case slices.ContainsFunc(strings.Fields(title), func(word string) bool { return strings.HasPrefix(word, prefix) }):
+			matches = append(matches, suggestion{title: title, rank: 1})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if len(a.title) != len(b.title) {
+			return len(a.title) < len(b.title)
+		}
+		return a.title < b.title
+	})
+
+	var nodes []*PageNode
+	for _, match := range matches {
+		for _, node := range t.nodesByTitle[match.title] {
+			if len(nodes) == limit {
+				return nodes
+			}
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
 // FindPageByID finds a page in the tree by its ID.
 func (t *TreeService) FindPageByID(id string) (*PageNode, error) {
 	var result *PageNode
@@ -592,76 +718,109 @@ func (t *TreeService) findChildBySlugExactInParentLocked(parent *PageNode, slug
 	return nil
 }
 
-// DeleteNode deletes a node from the tree
-func (t *TreeService) DeleteNode(userID string, id string, recursive bool, expectedVersion string) error {
-	err := t.withLockedTree(func() error {
+// nodeClaims claims the node with the given ID, including its descendants
+// when subtree is true, and the children of its parent when withParent is
+// true.
+func (t *TreeService) nodeClaims(id string, subtree, withParent bool) func() ([]claim, error) {
+	return func() ([]claim, error) {
 		if t.tree == nil {
-			return ErrTreeNotLoaded
+			return nil, ErrTreeNotLoaded
 		}
-
-		// Find the node to delete
 		node := t.getNodeByIDLocked(id)
 		if node == nil {
-			return ErrPageNotFound
+			return nil, ErrPageNotFound
 		}
-
-		if err := checkNodeVersion(node, expectedVersion); err != nil {
-			return err
+		claims := []claim{nodeClaimLocked(node, subtree, true)}
+		if withParent && node.Parent != nil {
+			claims = append(claims, nodeClaimLocked(node.Parent, false, true))
 		}
+		return claims, nil
+	}
+}
 
-		// Check if node has children
-		if node.HasChildren() && !recursive {
-			return ErrPageHasChildren
-		}
+// DeleteNode deletes a node from the tree
+func (t *TreeService) DeleteNode(userID string, id string, recursive bool, expectedVersion string) error {
+	return t.withClaims(t.nodeClaims(id, true, true), func() error {
+		var node, parent *PageNode
+		var doomed PageNode
+		err := t.withRLockedTree(func() error {
+			if t.tree == nil {
+				return ErrTreeNotLoaded
+			}
+
+			// Find the node to delete
+			node = t.getNodeByIDLocked(id)
+			if node == nil {
+				return ErrPageNotFound
+			}
 
-		// Delete the node from the parent
-		parent := node.Parent
-		if parent == nil {
-			return ErrParentNotFound
+			if err := checkNodeVersion(node, expectedVersion); err != nil {
+				return err
+			}
+
+			// Check if node has children
+			if node.HasChildren() && !recursive {
+				return ErrPageHasChildren
+			}
+
+			// Delete the node from the parent
+			parent = node.Parent
+			if parent == nil {
+				return ErrParentNotFound
+			}
+			doomed = *node
+			return nil
+		})
+		if err != nil {
+			return err
 		}
 
-		switch node.Kind {
+		switch doomed.Kind {
 		case NodeKindSection:
-			if err := t.store.DeleteSection(node); err != nil {
+			if err := t.store.DeleteSection(&doomed); err != nil {
 				return fmt.Errorf("could not delete section entry: %w", err)
 			}
 		case NodeKindPage:
-			if node.HasChildren() {
+			if doomed.HasChildren() {
 				// This should not happen due to earlier check, but just in case
 				// Convert to section and delete recursively
-				t.log.Info("converting page to section for recursive delete", "pageID", node.ID)
-				if err := t.store.ConvertNode(node, NodeKindSection); err != nil {
+				t.log.Info("converting page to section for recursive delete", "pageID", doomed.ID)
+				if err := t.store.ConvertNode(&doomed, NodeKindSection); err != nil {
 					return fmt.Errorf("could not convert page to section: %w", err)
 				}
-				node.Kind = NodeKindSection
-				if err := t.store.DeleteSection(node); err != nil {
+				doomed.Kind = NodeKindSection
+				if err := t.store.DeleteSection(&doomed); err != nil {
 					return fmt.Errorf("could not delete section entry: %w", err)
 				}
 			} else {
-				if err := t.store.DeletePage(node); err != nil {
+				if err := t.store.DeletePage(&doomed); err != nil {
 					return fmt.Errorf("could not delete page entry: %w", err)
 				}
 			}
 		default:
-			return fmt.Errorf("unknown node kind: %v", node.Kind)
+			return fmt.Errorf("unknown node kind: %v", doomed.Kind)
 		}
 
 		// Remove the page from the parent
-		for i, e := range parent.Children {
-			if e.ID == id {
-				parent.Children = append(parent.Children[:i], parent.Children[i+1:]...)
-				break
+		_ = t.withLockedTree(func() error {
+			for i, e := range parent.Children {
+				if e.ID == id {
+					parent.Children = append(parent.Children[:i], parent.Children[i+1:]...)
+					break
+				}
 			}
-		}
-		t.removeNodeIndexLocked(node)
+			t.removeNodeIndexLocked(node)
 
-		t.reindexPositions(parent)
+			t.reindexPositions(parent)
+			return nil
+		})
+
+		// The claim on the parent keeps its children stable without mu.
 		if err := t.store.SaveChildOrder(parent); err != nil {
 			return fmt.Errorf(errPersistChildOrderFailed, err)
 		}
 		return nil
 	})
-	return err
 }
 
 // UpdateNode updates a node (page/section) in the tree and syncs disk state via NodeStore.
@@ -672,41 +831,61 @@ func (t *TreeService) DeleteNode(userID string, id string, recursive bool, expec
 // embedded frontmatter (UpsertContentPreservingFrontmatter).
 // When both are absent, content is a plain body update (UpsertContent).
 func (t *TreeService) UpdateNode(userID string, id string, title string, slug string, content *string, expectedVersion string, tags []string, properties map[string]string, preserveFrontmatter bool) error {
-	return t.withLockedTree(func() error {
-		if t.tree == nil {
-			return ErrTreeNotLoaded
-		}
-
-		// Find node
+	// A new slug moves the node and everything below it on disk.
+	resolve := func() ([]claim, error) {
 		node := t.getNodeByIDLocked(id)
-		if node == nil {
-			return ErrPageNotFound
-		}
+		renamed := node != nil && node.Slug != slug
+		return t.nodeClaims(id, renamed, renamed)()
+	}
+	return t.withClaims(resolve, func() error {
+		var node *PageNode
+		var next PageNode
+		slugTaken := false
+		err := t.withRLockedTree(func() error {
+			if t.tree == nil {
+				return ErrTreeNotLoaded
+			}
 
-		if err := checkNodeVersion(node, expectedVersion); err != nil {
-			return err
-		}
+			// Find node
+			node = t.getNodeByIDLocked(id)
+			if node == nil {
+				return ErrPageNotFound
+			}
+
+			if err := checkNodeVersion(node, expectedVersion); err != nil {
+				return err
+			}
 
-		// Slug must be unique under same parent (when changed)
-		if slug != node.Slug && node.Parent != nil {
-			existing := t.findChildBySlugInParentLocked(node.Parent, slug)
-			if existing != nil && existing.ID != node.ID {
-				return ErrPageAlreadyExists
+			// Slug must be unique under same parent (when changed)
+			if slug != node.Slug && node.Parent != nil {
+				existing := t.findChildBySlugInParentLocked(node.Parent, slug)
+				slugTaken = existing != nil && existing.ID != node.ID
 			}
+			next = *node
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if err := t.checkDiskVersion(&next, expectedVersion); err != nil {
+			return err
+		}
+		if slugTaken {
+			return ErrPageAlreadyExists
 		}
 
 		// Content update?
 		if content != nil {
-			t.log.Info("updating node content", "nodeID", node.ID)
+			t.log.Info("updating node content", "nodeID", next.ID)
 			var upsertErr error
 			// Priority: preserveFrontmatter wins over tags/properties; callers must not set both.
 			switch {
 			case preserveFrontmatter:
-				upsertErr = t.store.UpsertContentPreservingFrontmatter(node, *content)
+				upsertErr = t.store.UpsertContentPreservingFrontmatter(&next, *content)
 			case tags != nil || properties != nil:
-				upsertErr = t.store.UpsertContentAndMetadata(node, *content, tags, properties)
+				upsertErr = t.store.UpsertContentAndMetadata(&next, *content, tags, properties)
 			default:
-				upsertErr = t.store.UpsertContent(node, *content)
+				upsertErr = t.store.UpsertContent(&next, *content)
 			}
 			if upsertErr != nil {
 				return fmt.Errorf("could not upsert content: %w", upsertErr)
@@ -714,29 +893,42 @@ func (t *TreeService) UpdateNode(userID string, id string, title string, slug st
 		}
 
 		// Rename slug on disk (must happen while node still has old slug)
-		if slug != node.Slug {
-			t.log.Info("renaming node slug", "nodeID", node.ID, "oldSlug", node.Slug, "newSlug", slug)
-			if err := t.store.RenameNode(node, slug); err != nil {
+		if slug != next.Slug {
+			t.log.Info("renaming node slug", "nodeID", next.ID, "oldSlug", next.Slug, "newSlug", slug)
+			if err := t.store.RenameNode(&next, slug); err != nil {
 				return fmt.Errorf("could not rename node: %w", err)
 			}
-			node.Slug = slug
-			if node.Parent != nil {
-				t.rebuildChildSlugIndexForParentLocked(node.Parent)
-			}
+			next.Slug = slug
 		}
 
-		// Update title in tree
-		t.removeTitleIndexForNodeLocked(node)
-		node.Title = title
-		t.addTitleIndexForNodeLocked(node)
+		next.Title = title
 
 		// Update metadata
-		node.Metadata.UpdatedAt = time.Now().UTC()
-		node.Metadata.LastAuthorID = userID
+		next.Metadata.UpdatedAt = time.Now().UTC()
+		next.Metadata.LastAuthorID = userID
 
 		// Keep frontmatter in sync *if file exists* (important when title changed but content == nil)
-		if err := t.store.SyncFrontmatterIfExists(node); err != nil {
-			return fmt.Errorf("could not sync frontmatter: %w", err)
+		syncErr := t.store.SyncFrontmatterIfExists(&next)
+
+		// The rename already happened on disk, so the tree follows it even
+		// when the frontmatter could not be synced.
+		_ = t.withLockedTree(func() error {
+			if node.Slug != next.Slug {
+				node.Slug = next.Slug
+				if node.Parent != nil {
+					t.rebuildChildSlugIndexForParentLocked(node.Parent)
+				}
+			}
+
+			// Update title in tree
+			t.removeTitleIndexForNodeLocked(node)
+			node.Title = next.Title
+			t.addTitleIndexForNodeLocked(node)
+			node.Metadata = next.Metadata
+			return nil
+		})
+		if syncErr != nil {
+			return fmt.Errorf("could not sync frontmatter: %w", syncErr)
 		}
 
 		// Save tree
@@ -746,45 +938,65 @@ func (t *TreeService) UpdateNode(userID string, id string, title string, slug st
 }
 
 func (t *TreeService) ConvertNode(userID string, id string, kind NodeKind, expectedVersion string) error {
-	return t.withLockedTree(func() error {
-		if t.tree == nil {
-			return ErrTreeNotLoaded
-		}
+	// Only childless nodes change their kind, so claiming the node is enough.
+	return t.withClaims(t.nodeClaims(id, false, false), func() error {
+		var node *PageNode
+		var next PageNode
+		err := t.withRLockedTree(func() error {
+			if t.tree == nil {
+				return ErrTreeNotLoaded
+			}
 
-		// Find node
-		node := t.getNodeByIDLocked(id)
-		if node == nil {
-			return ErrPageNotFound
-		}
+			// Find node
+			node = t.getNodeByIDLocked(id)
+			if node == nil {
+				return ErrPageNotFound
+			}
 
-		if err := checkNodeVersion(node, expectedVersion); err != nil {
+			if err := checkNodeVersion(node, expectedVersion); err != nil {
+				return err
+			}
+			next = *node
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if err := t.checkDiskVersion(&next, expectedVersion); err != nil {
 			return err
 		}
 
-		if node.Kind == kind {
+		if next.Kind == kind {
 			// No change
 			return nil
 		}
 
 		// Section -> Page only allowed if no children
-		if node.Kind == NodeKindSection && kind == NodeKindPage && node.HasChildren() {
+		if next.Kind == NodeKindSection && kind == NodeKindPage && next.HasChildren() {
 			return ErrPageHasChildren
 		}
 
-		t.log.Info("changing node kind", "nodeID", node.ID, "oldKind", node.Kind, "newKind", kind)
+		t.log.Info("changing node kind", "nodeID", next.ID, "oldKind", next.Kind, "newKind", kind)
 
-		if err := t.store.ConvertNode(node, kind); err != nil {
+		if err := t.store.ConvertNode(&next, kind); err != nil {
 			return fmt.Errorf("could not convert node: %w", err)
 		}
-		node.Kind = kind
+		next.Kind = kind
 
 		// Update metadata
-		node.Metadata.UpdatedAt = time.Now().UTC()
-		node.Metadata.LastAuthorID = userID
+		next.Metadata.UpdatedAt = time.Now().UTC()
+		next.Metadata.LastAuthorID = userID
 
 		// Keep frontmatter in sync *if file exists* (important when kind changed but content == nil)
-		if err := t.store.SyncFrontmatterIfExists(node); err != nil {
-			return fmt.Errorf("could not sync frontmatter: %w", err)
+		syncErr := t.store.SyncFrontmatterIfExists(&next)
+
+		_ = t.withLockedTree(func() error {
+			node.Kind = next.Kind
+			node.Metadata = next.Metadata
+			return nil
+		})
+		if syncErr != nil {
+			return fmt.Errorf("could not sync frontmatter: %w", syncErr)
 		}
 
 		// Save tree
@@ -838,6 +1050,28 @@ func (t *TreeService) collectIDsDFS() []string {
 		return nil
 	}
 
+	return collectSubtreeIDs(t.tree)
+}
+
+// SubtreeIDs returns the ID of the node and those of all its descendants in
+// depth-first order. For "root" these are the IDs of all non-root nodes.
+func (t *TreeService) SubtreeIDs(id string) ([]string, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.tree == nil {
+		return nil, ErrTreeNotLoaded
+	}
+	node := t.getNodeByIDLocked(id)
+	if node == nil {
+		return nil, ErrPageNotFound
+	}
+	return collectSubtreeIDs(node), nil
+}
+
+// collectSubtreeIDs returns the IDs of node and its descendants, leaving out
+// the root. The caller holds the read lock.
+func collectSubtreeIDs(node *PageNode) []string {
 	var ids []string
 	var collect func(*PageNode)
 	collect = func(node *PageNode) {
@@ -848,7 +1082,7 @@ func (t *TreeService) collectIDsDFS() []string {
 			collect(child)
 		}
 	}
-	collect(t.tree)
+	collect(node)
 	return ids
 }
 
@@ -858,8 +1092,9 @@ type BulkContentUpdate struct {
 	Content string
 }
 
-// BulkUpdateContent updates content for multiple pages under a single write lock,
-// running disk writes in parallel. Returns per-item errors; nil means success.
+// BulkUpdateContent updates content for multiple pages, running disk writes
+// in parallel. It only claims the pages it writes, so the rest of the tree
+// stays available meanwhile. Returns per-item errors; nil means success.
 // Only content and metadata timestamps are updated; slug and title are unchanged.
 func (t *TreeService) BulkUpdateContent(userID string, updates []BulkContentUpdate) []error {
 	errs := make([]error, len(updates))
@@ -868,68 +1103,100 @@ func (t *TreeService) BulkUpdateContent(userID string, updates []BulkContentUpda
 	}
 
 	type task struct {
-		index       int
-		node        *PageNode
-		content     string
-		oldMetadata PageMetadata
+		index int
+		node  *PageNode
+		// next is the node as it is written to disk.
+		next PageNode
 	}
 
-	t.mu.Lock()
-	defer t.mu.Unlock()
-
-	if t.tree == nil {
-		for i := range errs {
-			errs[i] = ErrTreeNotLoaded
+	resolve := func() ([]claim, error) {
+		if t.tree == nil {
+			return nil, ErrTreeNotLoaded
 		}
-		return errs
-	}
+		var claims []claim
+		for _, u := range updates {
+			if node := t.getNodeByIDLocked(u.ID); node != nil {
+				claims = append(claims, nodeClaimLocked(node, false, true))
+			}
+		}
+		return claims, nil
+	}
+	err := t.withClaims(resolve, func() error {
+		now := time.Now().UTC()
+		tasks := make([]task, 0, len(updates))
+		_ = t.withRLockedTree(func() error {
+			for i, u := range updates {
+				node := t.getNodeByIDLocked(u.ID)
+				if node == nil {
+					errs[i] = ErrPageNotFound
+					continue
+				}
+				tk := task{index: i, node: node, next: *node}
+				// Stamp the new metadata before the disk write so UpsertContent writes the correct timestamps.
+				tk.next.Metadata.UpdatedAt = now
+				tk.next.Metadata.LastAuthorID = userID
+				tasks = append(tasks, tk)
+			}
+			return nil
+		})
 
-	now := time.Now().UTC()
-	tasks := make([]task, 0, len(updates))
-	for i, u := range updates {
-		node := t.getNodeByIDLocked(u.ID)
-		if node == nil {
-			errs[i] = ErrPageNotFound
-			continue
+		if len(tasks) == 0 {
+			return nil
 		}
-		oldMetadata := node.Metadata
-		// Update in-memory metadata before disk write so UpsertContent writes the correct timestamps.
-		node.Metadata.UpdatedAt = now
-		node.Metadata.LastAuthorID = userID
-		tasks = append(tasks, task{index: i, node: node, content: u.Content, oldMetadata: oldMetadata})
-	}
 
-	if len(tasks) == 0 {
-		return errs
-	}
+		// Each page lives in its own file — writes are independent and safe to parallelise.
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		wg.Add(len(tasks))
+		for i := range tasks {
+			go func(tk *task) {
+				defer wg.Done()
+				if err := t.store.UpsertContent(&tk.next, updates[tk.index].Content); err != nil {
+					mu.Lock()
+					errs[tk.index] = err
+					mu.Unlock()
+				}
+			}(&tasks[i])
+		}
+		wg.Wait()
 
-	// Each page lives in its own file — writes are independent and safe to parallelise.
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-	wg.Add(len(tasks))
-	for _, tk := range tasks {
-		go func(tk task) {
-			defer wg.Done()
-			if err := t.store.UpsertContent(tk.node, tk.content); err != nil {
-				mu.Lock()
-				errs[tk.index] = err
-				mu.Unlock()
+		return t.withLockedTree(func() error {
+			for _, tk := range tasks {
+				if errs[tk.index] == nil {
+					tk.node.Metadata = tk.next.Metadata
+				}
 			}
-		}(tk)
-	}
-	wg.Wait()
-
-	for _, tk := range tasks {
-		if errs[tk.index] != nil {
-			tk.node.Metadata = tk.oldMetadata
+			return nil
+		})
+	})
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
 		}
 	}
 
 	return errs
 }
 
-// GetPages returns pages for the given IDs under a single read lock,
-// reading files in parallel. Each entry is nil when the corresponding error is non-nil.
+// readClaims claims the nodes with the given IDs for reading. Unknown IDs
+// are skipped.
+func (t *TreeService) readClaims(ids ...string) func() ([]claim, error) {
+	return func() ([]claim, error) {
+		if t.tree == nil {
+			return nil, ErrTreeNotLoaded
+		}
+		var claims []claim
+		for _, id := range ids {
+			if node := t.getNodeByIDLocked(id); node != nil {
+				claims = append(claims, nodeClaimLocked(node, false, false))
+			}
+		}
+		return claims, nil
+	}
+}
+
+// GetPages returns pages for the given IDs, reading files in parallel.
+// Each entry is nil when the corresponding error is non-nil.
 func (t *TreeService) GetPages(ids []string) ([]*Page, []error) {
 	pages := make([]*Page, len(ids))
 	errs := make([]error, len(ids))
@@ -942,98 +1209,102 @@ func (t *TreeService) GetPages(ids []string) ([]*Page, []error) {
 		node  *PageNode
 	}
 
-	t.mu.RLock()
-	defer t.mu.RUnlock()
+	err := t.withClaims(t.readClaims(ids...), func() error {
+		tasks := make([]task, 0, len(ids))
+		_ = t.withRLockedTree(func() error {
+			for i, id := range ids {
+				node := t.getNodeByIDLocked(id)
+				if node == nil {
+					errs[i] = ErrPageNotFound
+					continue
+				}
+				tasks = append(tasks, task{index: i, node: node})
+			}
+			return nil
+		})
 
-	if t.tree == nil {
-		for i := range errs {
-			errs[i] = ErrTreeNotLoaded
+		if len(tasks) == 0 {
+			return nil
 		}
-		return pages, errs
-	}
 
-	tasks := make([]task, 0, len(ids))
-	for i, id := range ids {
-		node := t.getNodeByIDLocked(id)
-		if node == nil {
-			errs[i] = ErrPageNotFound
-			continue
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		wg.Add(len(tasks))
+		for _, tk := range tasks {
+			go func(tk task) {
+				defer wg.Done()
+				content, raw, err := t.store.ReadPageAndRaw(tk.node)
+				mu.Lock()
+				if err != nil {
+					errs[tk.index] = fmt.Errorf(errGetPageContentFailed, err)
+				} else {
+					pages[tk.index] = &Page{PageNode: tk.node, Content: content, RawContent: raw}
+				}
+				mu.Unlock()
+			}(tk)
+		}
+		wg.Wait()
+		return nil
+	})
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
 		}
-		tasks = append(tasks, task{index: i, node: node})
-	}
-
-	if len(tasks) == 0 {
-		return pages, errs
-	}
-
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-	wg.Add(len(tasks))
-	for _, tk := range tasks {
-		go func(tk task) {
-			defer wg.Done()
-			content, raw, err := t.store.ReadPageAndRaw(tk.node)
-			mu.Lock()
-			if err != nil {
-				errs[tk.index] = fmt.Errorf(errGetPageContentFailed, err)
-			} else {
-				pages[tk.index] = &Page{PageNode: tk.node, Content: content, RawContent: raw}
-			}
-			mu.Unlock()
-		}(tk)
 	}
-	wg.Wait()
 
 	return pages, errs
 }
 
-// GetPage returns a page by its ID
-func (t *TreeService) GetPage(id string) (*Page, error) {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
-
+// findNodeForReadLocked finds the node with the given ID and returns a read
+// claim on it. The read lock must be held.
+func (t *TreeService) findNodeForReadLocked(id string, node **PageNode) ([]claim, error) {
 	if t.tree == nil {
 		return nil, ErrTreeNotLoaded
 	}
-
-	// Find the page
-	page := t.getNodeByIDLocked(id)
-	if page == nil {
+	*node = t.getNodeByIDLocked(id)
+	if *node == nil {
 		return nil, ErrPageNotFound
 	}
+	return []claim{nodeClaimLocked(*node, false, false)}, nil
+}
 
-	content, raw, err := t.store.ReadPageAndRaw(page)
-	if err != nil {
-		return nil, fmt.Errorf(errGetPageContentFailed, err)
-	}
+// GetPage returns a page by its ID
+func (t *TreeService) GetPage(id string) (*Page, error) {
+	var page *Page
+	var node *PageNode
+	err := t.withClaims(func() ([]claim, error) {
+		return t.findNodeForReadLocked(id, &node)
+	}, func() error {
+		content, raw, err := t.store.ReadPageAndRaw(node)
+		if err != nil {
+			return fmt.Errorf(errGetPageContentFailed, err)
+		}
 
-	return &Page{
-		PageNode:   page,
-		Content:    content,
-		RawContent: raw,
-	}, nil
+		page = &Page{
+			PageNode:   node,
+			Content:    content,
+			RawContent: raw,
+		}
+		return nil
+	})
+	return page, err
 }
 
 // ReadPageRaw returns the raw markdown of a page including frontmatter.
 func (t *TreeService) ReadPageRaw(id string) (string, error) {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
-
-	if t.tree == nil {
-		return "", ErrTreeNotLoaded
-	}
-
-	page := t.getNodeByIDLocked(id)
-	if page == nil {
-		return "", ErrPageNotFound
-	}
-
-	raw, err := t.store.ReadPageRaw(page)
-	if err != nil {
-		return "", fmt.Errorf("could not get page raw content: %w", err)
-	}
-
-	return raw, nil
+	var raw string
+	var node *PageNode
+	err := t.withClaims(func() ([]claim, error) {
+		return t.findNodeForReadLocked(id, &node)
+	}, func() error {
+		var err error
+		raw, err = t.store.ReadPageRaw(node)
+		if err != nil {
+			return fmt.Errorf("could not get page raw content: %w", err)
+		}
+		return nil
+	})
+	return raw, err
 }
 
 // ResolvePermalinkTarget resolves a stable page ID to the current route path.
@@ -1059,43 +1330,48 @@ func (t *TreeService) ResolvePermalinkTarget(id string) (*PermalinkTarget, error
 
 // FindPageByRoutePath finds a page in the tree by its path.
 func (t *TreeService) FindPageByRoutePath(routePath string) (*Page, error) {
-	t.mu.RLock()
-	defer t.mu.RUnlock()
-
-	if t.tree == nil {
-		return nil, ErrTreeNotLoaded
-	}
-
-	// Split the routePath into parts
-	routePart := strings.Split(routePath, "/")
-	if len(routePart) == 0 {
-		return nil, ErrPageNotFound
-	}
-
-	parent := t.tree
+	var page *Page
 	var node *PageNode
-	for _, part := range routePart {
-		if part == "" {
-			return nil, ErrPageNotFound
+	resolve := func() ([]claim, error) {
+		if t.tree == nil {
+			return nil, ErrTreeNotLoaded
 		}
 
-		node = t.findChildBySlugExactInParentLocked(parent, part)
-		if node == nil {
+		// Split the routePath into parts
+		routePart := strings.Split(routePath, "/")
+		if len(routePart) == 0 {
 			return nil, ErrPageNotFound
 		}
 
-		parent = node
-	}
+		parent := t.tree
+		node = nil
+		for _, part := range routePart {
+			if part == "" {
+				return nil, ErrPageNotFound
+			}
 
-	content, err := t.store.ReadPageContent(node)
-	if err != nil {
-		return nil, fmt.Errorf(errGetPageContentFailed, err)
+			node = t.findChildBySlugExactInParentLocked(parent, part)
+			if node == nil {
+				return nil, ErrPageNotFound
+			}
+
+			parent = node
+		}
+		return []claim{nodeClaimLocked(node, false, false)}, nil
 	}
+	err := t.withClaims(resolve, func() error {
+		content, err := t.store.ReadPageContent(node)
+		if err != nil {
+			return fmt.Errorf(errGetPageContentFailed, err)
+		}
 
-	return &Page{
-		PageNode: node,
-		Content:  content,
-	}, nil
+		page = &Page{
+			PageNode: node,
+			Content:  content,
+		}
+		return nil
+	})
+	return page, err
 }
 
 // LookupPagePath looks up a path in the tree and returns a PathLookup struct
@@ -1209,79 +1485,116 @@ func (t *TreeService) lookupPagePathLocked(p string) (*PathLookup, error) {
 // It creates any missing segments as needed
 // Returns the final page node and a list of created nodes
 func (t *TreeService) EnsurePagePath(userID string, p string, targetTitle string, kind *NodeKind) (*EnsurePathResult, error) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-
-	if t.tree == nil {
-		return nil, ErrTreeNotLoaded
+	// Missing segments are created below the deepest existing one. A single
+	// new node only needs the children of its parent; a chain of them claims
+	// the whole subtree so nobody adds to the new sections halfway through.
+	resolve := func() ([]claim, error) {
+		if t.tree == nil {
+			return nil, ErrTreeNotLoaded
+		}
+		lookup, err := t.lookupPagePathLocked(p)
+		if err != nil {
+			return nil, fmt.Errorf("could not lookup page path: %w", err)
+		}
+		parent := t.tree
+		missing := 0
+		for _, segment := range lookup.Segments {
+			if !segment.Exists {
+				missing++
+			} else if node := t.getNodeByIDLocked(*segment.ID); node != nil {
+				parent = node
+			}
+		}
+		if lookup.Exists {
+			return []claim{nodeClaimLocked(parent, false, false)}, nil
+		}
+		return []claim{nodeClaimLocked(parent, missing > 1, true)}, nil
 	}
 
-	created := []*PageNode{}
+	var result *EnsurePathResult
+	err := t.withClaims(resolve, func() error {
+		var lookup *PathLookup
+		var page *PageNode
+		err := t.withRLockedTree(func() (err error) {
+			lookup, err = t.lookupPagePathLocked(p)
+			if err != nil {
+				return fmt.Errorf("could not lookup page path: %w", err)
+			}
+			// Path exists -> return existing
+			if lookup.Exists {
+				last := lookup.Segments[len(lookup.Segments)-1]
+				page = t.getNodeByIDLocked(*last.ID)
+				if page == nil {
+					return fmt.Errorf("could not find existing page by ID: %w", ErrPageNotFound)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if page != nil {
+			result = &EnsurePathResult{Exists: true, Page: page}
+			return nil
+		}
 
-	lookup, err := t.lookupPagePathLocked(p)
-	if err != nil {
-		return nil, fmt.Errorf("could not lookup page path: %w", err)
-	}
+		created := []*PageNode{}
 
-	// Path exists -> return existing
-	if lookup.Exists {
-		last := lookup.Segments[len(lookup.Segments)-1]
-		page := t.getNodeByIDLocked(*last.ID)
-		if page == nil {
-			return nil, fmt.Errorf("could not find existing page by ID: %w", ErrPageNotFound)
-		}
-		return &EnsurePathResult{Exists: true, Page: page}, nil
-	}
+		// Create missing segments
+		var currentID *string // nil means root
+		for i, segment := range lookup.Segments {
+			if segment.Exists {
+				currentID = segment.ID
+				continue
+			}
 
-	// Create missing segments
-	var currentID *string // nil means root
-	for i, segment := range lookup.Segments {
-		if segment.Exists {
-			currentID = segment.ID
-			continue
-		}
+			// Title
+			segTitle := segment.Slug
+			if i == len(lookup.Segments)-1 {
+				segTitle = targetTitle
+			}
 
-		// Title
-		segTitle := segment.Slug
-		if i == len(lookup.Segments)-1 {
-			segTitle = targetTitle
-		}
+			// Kind: intermediate segments are sections, last segment uses provided kind (or page/section default)
+			kindToUse := NodeKindSection
+			if i == len(lookup.Segments)-1 && kind != nil {
+				kindToUse = *kind
+			}
 
-		// Kind: intermediate segments are sections, last segment uses provided kind (or page/section default)
-		kindToUse := NodeKindSection
-		if i == len(lookup.Segments)-1 && kind != nil {
-			kindToUse = *kind
-		}
+			createdNode, err := t.createNodeClaimed(userID, currentID, segTitle, segment.Slug, &kindToUse, createNodeOptions{})
+			if err != nil {
+				return fmt.Errorf("could not create segment %q: %w", segment.Slug, err)
+			}
+			currentID = &createdNode.id
 
-		createdNode, err := t.createNodeLocked(userID, currentID, segTitle, segment.Slug, &kindToUse, createNodeOptions{})
-		if err != nil {
-			return nil, fmt.Errorf("could not create segment %q: %w", segment.Slug, err)
+			created = append(created, &PageNode{
+				ID:    createdNode.id,
+				Slug:  segment.Slug,
+				Title: segTitle,
+				Kind:  kindToUse,
+			})
 		}
-		currentID = &createdNode.id
 
-		created = append(created, &PageNode{
-			ID:    createdNode.id,
-			Slug:  segment.Slug,
-			Title: segTitle,
-			Kind:  kindToUse,
+		// Resolve final page
+		if currentID == nil {
+			return fmt.Errorf("could not ensure page path")
+		}
+		_ = t.withRLockedTree(func() error {
+			page = t.getNodeByIDLocked(*currentID)
+			return nil
 		})
-	}
-
-	// Resolve final page
-	if currentID == nil {
-		return nil, fmt.Errorf("could not ensure page path")
-	}
-	page := t.getNodeByIDLocked(*currentID)
-	if page == nil {
-		return nil, fmt.Errorf("could not find created page by ID: %w", ErrPageNotFound)
-	}
+		if page == nil {
+			return fmt.Errorf("could not find created page by ID: %w", ErrPageNotFound)
+		}
 
-	// Save once
-	return &EnsurePathResult{
-		Exists:  true,
-		Page:    page,
-		Created: created,
-	}, nil
+		// Save once
+		result = &EnsurePathResult{
+			Exists:  true,
+			Page:    page,
+			Created: created,
+		}
+		return nil
+	})
+	return result, err
 }
 
 // MoveNode moves a node to another parent (root if parentID is empty/"root"),
@@ -1294,195 +1607,177 @@ func (t *TreeService) MoveNode(userID string, id string, parentID string, expect
 // and inserts it at the given index among the new parent's children.
 // A negative or out-of-range position appends at the end.
 func (t *TreeService) MoveNodeToPosition(userID string, id string, parentID string, expectedVersion string, position int) error {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-
-	if t.tree == nil {
-		return ErrTreeNotLoaded
-	}
-
-	// Find node to move
-	node := t.getNodeByIDLocked(id)
-	if node == nil {
-		return ErrPageNotFound
-	}
-
-	if err := checkNodeVersion(node, expectedVersion); err != nil {
-		return err
-	}
-
-	oldParent := node.Parent
-	if oldParent == nil {
-		return fmt.Errorf("old parent not found: %w", ErrParentNotFound)
-	}
-
-	// Resolve destination parent (default root)
-	newParent := t.tree
-	if parentID != "" && parentID != "root" {
-		newParent = t.getNodeByIDLocked(parentID)
-		if newParent == nil {
-			return fmt.Errorf("new parent not found: %w", ErrParentNotFound)
+	// A move changes the path of the node and everything below it, and the
+	// children of both parents.
+	resolve := func() ([]claim, error) {
+		claims, err := t.nodeClaims(id, true, true)()
+		if err != nil {
+			return nil, err
+		}
+		newParent := t.tree
+		if parentID != "" && parentID != "root" {
+			newParent = t.getNodeByIDLocked(parentID)
+		}
+		if newParent != nil {
+			claims = append(claims, nodeClaimLocked(newParent, false, true))
 		}
+		return claims, nil
 	}
+	return t.withClaims(resolve, func() error {
+		var node, oldParent, newParent *PageNode
+		var moved, stagedOld, stagedNew PageNode
+		err := t.withRLockedTree(func() error {
+			if t.tree == nil {
+				return ErrTreeNotLoaded
+			}
 
-	// Same slug collision under new parent
-	if existing := t.findChildBySlugInParentLocked(newParent, node.Slug); existing != nil && existing.ID != node.ID {
-		return fmt.Errorf("child with the same slug already exists: %w", ErrPageAlreadyExists)
-	}
+			// Find node to move
+			node = t.getNodeByIDLocked(id)
+			if node == nil {
+				return ErrPageNotFound
+			}
 
-	// Can't move into itself
-	if node.ID == newParent.ID {
-		return fmt.Errorf("page cannot be moved to itself: %w", ErrPageCannotBeMovedToItself)
-	}
+			if err := checkNodeVersion(node, expectedVersion); err != nil {
+				return err
+			}
 
-	// Circular reference guard: node cannot be moved under its own descendants
-	if node.IsChildOf(newParent.ID, true) {
-		return fmt.Errorf("circular reference detected: %w", ErrMovePageCircularReference)
-	}
+			oldParent = node.Parent
+			if oldParent == nil {
+				return fmt.Errorf("old parent not found: %w", ErrParentNotFound)
+			}
 
-	newParentWasConverted := false
-	if newParent.ID != "root" && newParent.Kind == NodeKindPage {
-		if err := t.store.ConvertNode(newParent, NodeKindSection); err != nil {
-			return fmt.Errorf("could not auto-convert new parent page to section: %w", err)
-		}
-		newParent.Kind = NodeKindSection
-		newParentWasConverted = true
-	}
+			// Resolve destination parent (default root)
+			newParent = t.tree
+			if parentID != "" && parentID != "root" {
+				newParent = t.getNodeByIDLocked(parentID)
+				if newParent == nil {
+					return fmt.Errorf("new parent not found: %w", ErrParentNotFound)
+				}
+			}
 
-	if newParent.Kind != NodeKindSection {
-		return fmt.Errorf("destination parent must be a section, got %q", newParent.Kind)
-	}
+			// Same slug collision under new parent
+			if existing := t.findChildBySlugInParentLocked(newParent, node.Slug); existing != nil && existing.ID != node.ID {
+				return fmt.Errorf("child with the same slug already exists: %w", ErrPageAlreadyExists)
+			}
 
-	previousOldChildren := append([]*PageNode(nil), oldParent.Children...)
-	previousOldPositions := snapshotChildPositions(oldParent.Children)
-	previousNewChildren := append([]*PageNode(nil), newParent.Children...)
-	previousNewPositions := snapshotChildPositions(newParent.Children)
-	previousPosition := node.Position
-	previousMetadata := node.Metadata
+			// Can't move into itself
+			if node.ID == newParent.ID {
+				return fmt.Errorf("page cannot be moved to itself: %w", ErrPageCannotBeMovedToItself)
+			}
 
-	if err := t.store.MoveNode(node, newParent); err != nil {
-		return fmt.Errorf("could not move node on disk: %w", err)
-	}
+			// Circular reference guard: node cannot be moved under its own descendants
+			if node.IsChildOf(newParent.ID, true) {
+				return fmt.Errorf("circular reference detected: %w", ErrMovePageCircularReference)
+			}
 
-	for i, e := range oldParent.Children {
-		if e.ID == id {
-			oldParent.Children = append(oldParent.Children[:i], oldParent.Children[i+1:]...)
-			break
+			moved = *node
+			stagedOld = *oldParent
+			stagedOld.Children = slices.Clone(oldParent.Children)
+			stagedNew = *newParent
+			stagedNew.Children = slices.Clone(newParent.Children)
+			return nil
+		})
+		if err != nil {
+			return err
 		}
-	}
-
-	insertAt := len(newParent.Children)
-	if position >= 0 && position < len(newParent.Children) {
-		insertAt = position
-	}
-	newParent.Children = append(newParent.Children, nil)
-	copy(newParent.Children[insertAt+1:], newParent.Children[insertAt:])
-	newParent.Children[insertAt] = node
-	node.Position = insertAt
-	node.Parent = newParent
-	t.rebuildChildSlugIndexForParentLocked(oldParent)
-	t.rebuildChildSlugIndexForParentLocked(newParent)
-	node.Metadata.UpdatedAt = time.Now().UTC()
-	node.Metadata.LastAuthorID = userID
-
-	t.reindexPositions(newParent)
-	t.reindexPositions(oldParent)
 
-	if err := t.store.SaveChildOrder(oldParent); err != nil {
-		rollbackErr := t.rollbackMovedNodeLocked(node, oldParent, newParent, previousOldChildren, previousOldPositions, previousNewChildren, previousNewPositions, previousPosition, previousMetadata, newParentWasConverted)
-		if rollbackErr != nil {
-			return errors.Join(fmt.Errorf("could not persist source child order: %w", err), fmt.Errorf(errRollbackMovedNodeFailed, rollbackErr))
+		// dest is the new parent as staged on disk.
+		dest := &stagedNew
+		if newParent == oldParent {
+			dest = &stagedOld
 		}
-		return fmt.Errorf("could not persist source child order: %w", err)
-	}
-	if newParent != oldParent {
-		if err := t.store.SaveChildOrder(newParent); err != nil {
-			rollbackErr := t.rollbackMovedNodeLocked(node, oldParent, newParent, previousOldChildren, previousOldPositions, previousNewChildren, previousNewPositions, previousPosition, previousMetadata, newParentWasConverted)
-			if rollbackErr != nil {
-				return errors.Join(fmt.Errorf("could not persist destination child order: %w", err), fmt.Errorf(errRollbackMovedNodeFailed, rollbackErr))
+
+		newParentWasConverted := false
+		if dest.ID != "root" && dest.Kind == NodeKindPage {
+			if err := t.store.ConvertNode(dest, NodeKindSection); err != nil {
+				return fmt.Errorf("could not auto-convert new parent page to section: %w", err)
 			}
-			return fmt.Errorf("could not persist destination child order: %w", err)
+			dest.Kind = NodeKindSection
+			newParentWasConverted = true
 		}
-	}
 
-	if err := t.store.SyncFrontmatterIfExists(node); err != nil {
-		rollbackErr := t.rollbackMovedNodeLocked(node, oldParent, newParent, previousOldChildren, previousOldPositions, previousNewChildren, previousNewPositions, previousPosition, previousMetadata, newParentWasConverted)
-		if rollbackErr != nil {
-			return errors.Join(fmt.Errorf("could not sync moved node frontmatter: %w", err), fmt.Errorf(errRollbackMovedNodeFailed, rollbackErr))
+		if dest.Kind != NodeKindSection {
+			return fmt.Errorf("destination parent must be a section, got %q", dest.Kind)
 		}
-		return fmt.Errorf("could not sync moved node frontmatter: %w", err)
-	}
 
-	return nil
-}
-
-func snapshotChildPositions(children []*PageNode) map[string]int {
-	positions := make(map[string]int, len(children))
-	for _, child := range children {
-		if child == nil {
-			continue
+		if err := t.store.MoveNode(node, dest); err != nil {
+			moveErr := fmt.Errorf("could not move node on disk: %w", err)
+			if newParentWasConverted {
+				if rollbackErr := t.foldBackToPage(dest); rollbackErr != nil {
+					return errors.Join(moveErr, fmt.Errorf(errRollbackMovedNodeFailed, rollbackErr))
+				}
+			}
+			return moveErr
 		}
-		positions[child.ID] = child.Position
-	}
-	return positions
-}
 
-func restoreChildSnapshot(parent *PageNode, children []*PageNode, positions map[string]int) {
-	if parent == nil {
-		return
-	}
+		stagedOld.Children = slices.DeleteFunc(stagedOld.Children, func(e *PageNode) bool { return e.ID == id })
+		insertAt := len(dest.Children)
+		if position >= 0 && position < len(dest.Children) {
+			insertAt = position
+		}
+		dest.Children = slices.Insert(dest.Children, insertAt, node)
+		moved.Parent = newParent
+		moved.Metadata.UpdatedAt = time.Now().UTC()
+		moved.Metadata.LastAuthorID = userID
 
-	parent.Children = append([]*PageNode(nil), children...)
-	for _, child := range parent.Children {
-		if child == nil {
-			continue
+		rollback := func(cause error) error {
+			if rollbackErr := t.rollbackMovedNode(&moved, oldParent, newParent, dest, newParentWasConverted); rollbackErr != nil {
+				return errors.Join(cause, fmt.Errorf(errRollbackMovedNodeFailed, rollbackErr))
+			}
+			return cause
 		}
-		child.Parent = parent
-		if pos, ok := positions[child.ID]; ok {
-			child.Position = pos
+		if err := t.store.SaveChildOrder(&stagedOld); err != nil {
+			return rollback(fmt.Errorf("could not persist source child order: %w", err))
 		}
-	}
+		if newParent != oldParent {
+			if err := t.store.SaveChildOrder(dest); err != nil {
+				return rollback(fmt.Errorf("could not persist destination child order: %w", err))
+			}
+		}
+		if err := t.store.SyncFrontmatterIfExists(&moved); err != nil {
+			return rollback(fmt.Errorf("could not sync moved node frontmatter: %w", err))
+		}
+
+		return t.withLockedTree(func() error {
+			if newParentWasConverted {
+				newParent.Kind = NodeKindSection
+			}
+			oldParent.Children = stagedOld.Children
+			newParent.Children = dest.Children
+			node.Parent = newParent
+			node.Position = insertAt
+			node.Metadata = moved.Metadata
+			t.rebuildChildSlugIndexForParentLocked(oldParent)
+			t.rebuildChildSlugIndexForParentLocked(newParent)
+
+			t.reindexPositions(newParent)
+			t.reindexPositions(oldParent)
+			return nil
+		})
+	})
 }
 
-func (t *TreeService) rollbackMovedNodeLocked(node *PageNode, oldParent *PageNode, newParent *PageNode, previousOldChildren []*PageNode, previousOldPositions map[string]int, previousNewChildren []*PageNode, previousNewPositions map[string]int, previousPosition int, previousMetadata PageMetadata, newParentWasConverted bool) error {
+// rollbackMovedNode undoes a move staged by MoveNodeToPosition on disk:
+// moved is the node at its new location, dest the staged new parent. The
+// move has not been published to the tree, so oldParent and newParent still
+// hold their previous children.
+func (t *TreeService) rollbackMovedNode(moved *PageNode, oldParent *PageNode, newParent *PageNode, dest *PageNode, newParentWasConverted bool) error {
 	var rollbackErr error
 
-	if moveErr := t.store.MoveNode(node, oldParent); moveErr != nil {
+	if moveErr := t.store.MoveNode(moved, oldParent); moveErr != nil {
 		rollbackErr = errors.Join(rollbackErr, fmt.Errorf("move node back on disk: %w", moveErr))
 	}
 
-	restoreChildSnapshot(oldParent, previousOldChildren, previousOldPositions)
-	if newParent != oldParent {
-		restoreChildSnapshot(newParent, previousNewChildren, previousNewPositions)
-	}
-	t.rebuildChildSlugIndexForParentLocked(oldParent)
-	if newParent != oldParent {
-		t.rebuildChildSlugIndexForParentLocked(newParent)
-	}
-	node.Parent = oldParent
-	node.Position = previousPosition
-	node.Metadata = previousMetadata
-
-	if newParentWasConverted && newParent != nil && newParent.ID != "root" && len(newParent.Children) == 0 {
-		newParentDir, dirErr := t.store.dirPathForNode(newParent)
-		if dirErr != nil {
-			rollbackErr = errors.Join(rollbackErr, fmt.Errorf("resolve converted parent dir: %w", dirErr))
-		} else {
-			if removeErr := os.RemoveAll(filepath.Join(newParentDir, orderFilename)); removeErr != nil {
-				rollbackErr = errors.Join(rollbackErr, fmt.Errorf("remove child order before parent rollback: %w", removeErr))
-			}
-		}
-		if convertErr := t.store.ConvertNode(newParent, NodeKindPage); convertErr != nil {
+	if newParentWasConverted {
+		if convertErr := t.foldBackToPage(dest); convertErr != nil {
 			rollbackErr = errors.Join(rollbackErr, fmt.Errorf("convert destination parent back to page: %w", convertErr))
-		} else {
-			newParent.Kind = NodeKindPage
 		}
 	}
 
 	if err := t.store.SaveChildOrder(oldParent); err != nil {
 		rollbackErr = errors.Join(rollbackErr, fmt.Errorf("restore source child order: %w", err))
 	}
-	if newParent != oldParent && newParent.Kind == NodeKindSection {
+	if newParent != oldParent && !newParentWasConverted {
 		if err := t.store.SaveChildOrder(newParent); err != nil {
 			rollbackErr = errors.Join(rollbackErr, fmt.Errorf("restore destination child order: %w", err))
 		}
@@ -1493,108 +1788,118 @@ func (t *TreeService) rollbackMovedNodeLocked(node *PageNode, oldParent *PageNod
 
 // SetPinned updates the leafwiki_pinned frontmatter field and in-memory Pinned flag.
 func (t *TreeService) SetPinned(id string, version string, pinned bool) (*Page, error) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-
-	if t.tree == nil {
-		return nil, ErrTreeNotLoaded
-	}
+	var page *Page
+	err := t.withClaims(t.nodeClaims(id, false, false), func() error {
+		var node *PageNode
+		err := t.withRLockedTree(func() error {
+			if t.tree == nil {
+				return ErrTreeNotLoaded
+			}
 
-	node := t.getNodeByIDLocked(id)
-	if node == nil {
-		return nil, ErrPageNotFound
-	}
+			node = t.getNodeByIDLocked(id)
+			if node == nil {
+				return ErrPageNotFound
+			}
 
-	if err := checkNodeVersion(node, version); err != nil {
-		return nil, err
-	}
+			return checkNodeVersion(node, version)
+		})
+		if err != nil {
+			return err
+		}
+		if err := t.checkDiskVersion(node, version); err != nil {
+			return err
+		}
 
-	content, err := t.store.SetPinnedFrontmatter(node, pinned)
-	if err != nil {
-		return nil, fmt.Errorf("set pinned: %w", err)
-	}
+		content, err := t.store.SetPinnedFrontmatter(node, pinned)
+		if err != nil {
+			return fmt.Errorf("set pinned: %w", err)
+		}
 
-	node.Pinned = pinned
-	return &Page{PageNode: node, Content: content}, nil
+		_ = t.withLockedTree(func() error {
+			node.Pinned = pinned
+			return nil
+		})
+		page = &Page{PageNode: node, Content: content}
+		return nil
+	})
+	return page, err
 }
 
 func (t *TreeService) SortPages(parentID string, orderedIDs []string) error {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+	return t.withClaims(t.parentClaims(&parentID), func() error {
+		var parent *PageNode
+		var staged PageNode
+		err := t.withRLockedTree(func() error {
+			if t.tree == nil {
+				return ErrTreeNotLoaded
+			}
 
-	if t.tree == nil {
-		return ErrTreeNotLoaded
-	}
+			parent = t.tree
 
-	parent := t.tree
+			if parentID != "" && parentID != "root" {
+				parent = t.getNodeByIDLocked(parentID)
+				if parent == nil {
+					return ErrParentNotFound
+				}
+			}
 
-	if parentID != "" && parentID != "root" {
-		parent = t.getNodeByIDLocked(parentID)
-		if parent == nil {
-			return ErrParentNotFound
-		}
-	}
+			// Check if the number of orderedIDs is the same as the number of children
+			if len(orderedIDs) != len(parent.Children) {
+				return fmt.Errorf("number of ordered IDs does not match the number of children: %w", ErrInvalidSortOrder)
+			}
 
-	// Check if the number of orderedIDs is the same as the number of children
-	if len(orderedIDs) != len(parent.Children) {
-		return fmt.Errorf("number of ordered IDs does not match the number of children: %w", ErrInvalidSortOrder)
-	}
+			// Check if all IDs in the sort order are valid
+			existingIDs := make(map[string]bool)
+			for _, child := range parent.Children {
+				existingIDs[child.ID] = true
+			}
+			for _, id := range orderedIDs {
+				if !existingIDs[id] {
+					return fmt.Errorf("invalid ID in sort order, ID: %s - %w", id, ErrInvalidSortOrder)
+				}
+			}
 
-	// Check if all IDs in the sort order are valid
-	existingIDs := make(map[string]bool)
-	for _, child := range parent.Children {
-		existingIDs[child.ID] = true
-	}
-	for _, id := range orderedIDs {
-		if !existingIDs[id] {
-			return fmt.Errorf("invalid ID in sort order, ID: %s - %w", id, ErrInvalidSortOrder)
-		}
-	}
+			seen := make(map[string]bool)
+			for _, id := range orderedIDs {
+				if seen[id] {
+					return fmt.Errorf("duplicate ID in sort order: %s", id)
+				}
+				seen[id] = true
+			}
 
-	seen := make(map[string]bool)
-	for _, id := range orderedIDs {
-		if seen[id] {
-			return fmt.Errorf("duplicate ID in sort order: %s", id)
+			staged = *parent
+			staged.Children = slices.Clone(parent.Children)
+			return nil
+		})
+		if err != nil {
+			return err
 		}
-		seen[id] = true
-	}
-
-	previousChildren := append([]*PageNode(nil), parent.Children...)
-	previousPositions := make(map[string]int, len(parent.Children))
-	for _, child := range parent.Children {
-		previousPositions[child.ID] = child.Position
-	}
 
-	// Create a map to store the position of each page
-	positions := make(map[string]int)
-	for i, id := range orderedIDs {
-		positions[id] = i
-	}
+		// Create a map to store the position of each page
+		positions := make(map[string]int)
+		for i, id := range orderedIDs {
+			positions[id] = i
+		}
 
-	// Sort the children of the parent
-	sort.SliceStable(parent.Children, func(i, j int) bool {
-		return positions[parent.Children[i].ID] < positions[parent.Children[j].ID]
-	})
+		// Sort the children of the parent
+		sort.SliceStable(staged.Children, func(i, j int) bool {
+			return positions[staged.Children[i].ID] < positions[staged.Children[j].ID]
+		})
 
-	// write postion index to children
-	for i, child := range parent.Children {
-		child.Position = i
-	}
+		if err := t.store.SaveChildOrder(&staged); err != nil {
+			return fmt.Errorf(errPersistChildOrderFailed, err)
+		}
 
-	// Reindex the positions
-	t.reindexPositions(parent)
+		return t.withLockedTree(func() error {
+			parent.Children = staged.Children
 
-	if err := t.store.SaveChildOrder(parent); err != nil {
-		parent.Children = previousChildren
-		for _, child := range parent.Children {
-			if pos, ok := previousPositions[child.ID]; ok {
-				child.Position = pos
+			// write postion index to children
+			for i, child := range parent.Children {
+				child.Position = i
 			}
-		}
-		return fmt.Errorf(errPersistChildOrderFailed, err)
-	}
-
-	return nil
+			return nil
+		})
+	})
 }
 
 func (t *TreeService) reindexPositions(parent *PageNode) {
@@ -0,0 +1,187 @@
+package tree
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// benchWiki builds a tree with a "reads" section that the benchmarks read
+// from and a "writes" section with writeCount pages that background writers
+// keep busy.
+func benchWiki(b *testing.B, readCount, writeCount int) (svc *TreeService, readIDs, writeIDs []string, archiveID string) {
+	b.Helper()
+	logger := slog.Default()
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	b.Cleanup(func() { slog.SetDefault(logger) })
+
+	tmpDir := b.TempDir()
+	if err := saveSchema(tmpDir, CurrentSchemaVersion); err != nil {
+		b.Fatalf("saveSchema failed: %v", err)
+	}
+	svc = NewTreeService(tmpDir)
+	if err := svc.LoadTree(); err != nil {
+		b.Fatalf("LoadTree failed: %v", err)
+	}
+
+	create := func(parentID *string, slug string, kind NodeKind) string {
+		id, err := svc.CreateNode("bench", parentID, slug, slug, &kind)
+		if err != nil {
+			b.Fatalf("CreateNode %s failed: %v", slug, err)
+		}
+		return *id
+	}
+	readsID := create(nil, "reads", NodeKindSection)
+	for i := range readCount {
+		readIDs = append(readIDs, create(&readsID, fmt.Sprintf("read-%d", i), NodeKindPage))
+	}
+	writesID := create(nil, "writes", NodeKindSection)
+	for i := range writeCount {
+		writeIDs = append(writeIDs, create(&writesID, fmt.Sprintf("write-%d", i), NodeKindPage))
+	}
+	archiveID = create(nil, "archive", NodeKindSection)
+	return svc, readIDs, writeIDs, archiveID
+}
+
+// startWriters keeps updating and moving the pages in writeIDs until stop is
+// closed: one goroutine rewrites all of them with BulkUpdateContent, another
+// moves them to the archive section and back one by one.
+func startWriters(b *testing.B, svc *TreeService, writeIDs []string, archiveID string, stop <-chan struct{}) *sync.WaitGroup {
+	b.Helper()
+	writesID := svc.GetTree().Children[1].ID
+	content := make([]BulkContentUpdate, len(writeIDs))
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for round := 0; ; round++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			for i, id := range writeIDs {
+				content[i] = BulkContentUpdate{ID: id, Content: fmt.Sprintf("# Round %d\n\nSome text.\n", round)}
+			}
+			for _, err := range svc.BulkUpdateContent("bench", content) {
+				if err != nil {
+					b.Errorf("BulkUpdateContent failed: %v", err)
+					return
+				}
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for {
+			for _, id := range writeIDs[:len(writeIDs)/4] {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if err := svc.MoveNode("bench", id, archiveID, VersionUnchecked); err != nil {
+					b.Errorf("MoveNode failed: %v", err)
+					return
+				}
+				if err := svc.MoveNode("bench", id, writesID, VersionUnchecked); err != nil {
+					b.Errorf("MoveNode back failed: %v", err)
+					return
+				}
+			}
+		}
+	}()
+	return &wg
+}
+
+// reportLatencies adds the median and 99th percentile of the measured read
+// latencies to the benchmark result.
+func reportLatencies(b *testing.B, latencies []time.Duration) {
+	if len(latencies) == 0 {
+		return
+	}
+	slices.Sort(latencies)
+	b.ReportMetric(float64(latencies[len(latencies)/2].Nanoseconds()), "p50-ns")
+	b.ReportMetric(float64(latencies[len(latencies)*99/100].Nanoseconds()), "p99-ns")
+}
+
+// readInterval spaces out the reads of BenchmarkTreeService_ReadsUnderConcurrentWrites.
+const readInterval = time.Millisecond
+
+// BenchmarkTreeService_ReadsUnderConcurrentWrites measures how long reads of
+// one section take while other sections are being rewritten and moved. Reads
+// are spaced out so they sample the whole write cycle; run it with
+// -benchtime=1000x to compare latencies. "idle" is the same read without
+// writers.
+func BenchmarkTreeService_ReadsUnderConcurrentWrites(b *testing.B) {
+	reads := map[string]func(svc *TreeService, id string) error{
+		"GetPage": func(svc *TreeService, id string) error {
+			_, err := svc.GetPage(id)
+			return err
+		},
+		"FindPageByID": func(svc *TreeService, id string) error {
+			_, err := svc.FindPageByID(id)
+			return err
+		},
+	}
+	for _, name := range []string{"GetPage", "FindPageByID"} {
+		read := reads[name]
+		for _, writers := range []bool{false, true} {
+			mode := "idle"
+			if writers {
+				mode = "writers"
+			}
+			b.Run(name+"/"+mode, func(b *testing.B) {
+				svc, readIDs, writeIDs, archiveID := benchWiki(b, 20, 200)
+				stop := make(chan struct{})
+				if writers {
+					wg := startWriters(b, svc, writeIDs, archiveID, stop)
+					defer wg.Wait()
+				}
+				defer close(stop)
+
+				latencies := make([]time.Duration, 0, b.N)
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+					time.Sleep(readInterval)
+					b.StartTimer()
+					start := time.Now()
+					if err := read(svc, readIDs[i%len(readIDs)]); err != nil {
+						b.Fatalf("%s failed: %v", name, err)
+					}
+					latencies = append(latencies, time.Since(start))
+				}
+				b.StopTimer()
+				reportLatencies(b, latencies)
+			})
+		}
+	}
+}
+
+// BenchmarkTreeService_WritesToIndependentSections measures content updates
+// of one section while another section is rewritten in bulk.
+func BenchmarkTreeService_WritesToIndependentSections(b *testing.B) {
+	svc, readIDs, writeIDs, archiveID := benchWiki(b, 20, 200)
+	stop := make(chan struct{})
+	wg := startWriters(b, svc, writeIDs, archiveID, stop)
+	defer wg.Wait()
+	defer close(stop)
+
+	content := "# Independent\n\nUpdated while the writes section is busy.\n"
+	latencies := make([]time.Duration, 0, b.N)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		id := readIDs[i%len(readIDs)]
+		start := time.Now()
+		if err := svc.UpdateNode("bench", id, "Independent", fmt.Sprintf("read-%d", i%len(readIDs)), &content, VersionUnchecked, nil, nil, false); err != nil {
+			b.Fatalf("UpdateNode failed: %v", err)
+		}
+		latencies = append(latencies, time.Since(start))
+	}
+	b.StopTimer()
+	reportLatencies(b, latencies)
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -3356,6 +3357,58 @@ func TestFindPagesByTitle_MultipleMatches(t *testing.T) {
 	}
 }
 
+func TestSubtreeIDs_ReturnsNodeAndDescendants(t *testing.T) {
+	svc, _ := newLoadedService(t)
+
+	parentID, err := svc.CreateNode("system", nil, "Runbooks", "runbooks", ptrKind(NodeKindSection))
+	if err != nil {
+		t.Fatalf("CreateNode parent: %v", err)
+	}
+	childID, err := svc.CreateNode("system", parentID, "Restart", "restart", ptrKind(NodeKindPage))
+	if err != nil {
+		t.Fatalf("CreateNode child: %v", err)
+	}
+	otherID, err := svc.CreateNode("system", nil, "Other", "other", ptrKind(NodeKindPage))
+	if err != nil {
+		t.Fatalf("CreateNode other: %v", err)
+	}
+
+	ids, err := svc.SubtreeIDs(*parentID)
+	if err != nil || !slices.Equal(ids, []string{*parentID, *childID}) {
+		t.Fatalf("SubtreeIDs = %v, %v", ids, err)
+	}
+	if ids, err = svc.SubtreeIDs("root"); err != nil || !slices.Equal(ids, []string{*parentID, *childID, *otherID}) {
+		t.Fatalf("SubtreeIDs(root) = %v, %v", ids, err)
+	}
+	if _, err := svc.SubtreeIDs("missing"); !errors.Is(err, ErrPageNotFound) {
+		t.Fatalf("expected ErrPageNotFound, got %v", err)
+	}
+}
+
+func TestSuggestPagesByTitle_PrefixMatchesFirst(t *testing.T) {
+	svc, _ := newLoadedService(t)
+
+	for _, title := range []string{"Deploy Guide", "Deployment", "How to deploy", "Release", "Deploy"} {
+		if _, err := svc.CreateNode("system", nil, title, strings.ToLower(strings.ReplaceAll(title, " ", "-")), ptrKind(NodeKindPage)); err != nil {
+			t.Fatalf("CreateNode %q: %v", title, err)
+		}
+	}
+
+	var titles []string
+	for _, node := range svc.SuggestPagesByTitle(" DEPLOY", 10) {
+		titles = append(titles, node.Title)
+	}
+	if want := []string{"Deploy", "Deployment", "Deploy Guide", "How to deploy"}; !slices.Equal(titles, want) {
+		t.Fatalf("got %v, want %v", titles, want)
+	}
+	if got := svc.SuggestPagesByTitle("deploy", 2); len(got) != 2 {
+		t.Errorf("expected the limit to apply, got %d results", len(got))
+	}
+	if got := svc.SuggestPagesByTitle("  ", 10); got != nil {
+		t.Errorf("expected no suggestions for an empty prefix, got %d", len(got))
+	}
+}
+
 func TestFindPagesByTitle_NoMatch(t *testing.T) {
 	svc, _ := newLoadedService(t)
 
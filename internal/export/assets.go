@@ -0,0 +1,37 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// pageAssetFiles lists the attachments stored for a page in the asset
+// storage (<assetsDir>/<pageID>/<file>), sorted by name.
+func pageAssetFiles(assetsDir, pageID string) ([]string, error) {
+	if assetsDir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(filepath.Join(assetsDir, pageID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() || !entry.Type().IsRegular() {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// assetDiskPath returns the on-disk path of an attachment.
+func assetDiskPath(assetsDir, pageID, name string) string {
+	return filepath.Join(assetsDir, pageID, name)
+}
@@ -0,0 +1,489 @@
+package export
+
+import (
+	_ "embed"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/perber/wiki/internal/core/shared/htmlutil"
+)
+
+//go:embed static/book.css
+var bookCSS string
+
+// bookImageTypes lists the image formats that are embedded into books. They
+// are the EPUB 3 core media types, so every embedded image is readable by
+// any EPUB reader; other attachments are not embedded.
+var bookImageTypes = map[string]string{
+	".gif":  "image/gif",
+	".jpeg": "image/jpeg",
+	".jpg":  "image/jpeg",
+	".png":  "image/png",
+	".svg":  "image/svg+xml",
+	".webp": "image/webp",
+}
+
+// xhtmlVoidElementPattern matches void elements that the sanitizer writes in
+// HTML form (<br>) so they can be closed for XHTML (<br/>).
+var xhtmlVoidElementPattern = regexp.MustCompile(`<(area|br|col|hr|img|input|source|wbr)(\s[^>]*?)?\s*/?>`)
+
+// bookImagePlaceholder prefixes the temporary image sources of the HTML book.
+const bookImagePlaceholder = "leafwiki-book-image-"
+
+var bookHTMLTemplate = template.Must(template.New("book").Parse(`<!DOCTYPE html>
+<html lang="{{.Language}}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="generator" content="LeafWiki">
+<title>{{.Title}}</title>
+<style>{{.CSS}}</style>
+</head>
+<body>
+<header class="book-cover">
+<h1 class="book-title">{{.Title}}</h1>
+</header>
+<nav class="book-toc" aria-label="Contents">
+<h2>Contents</h2>
+{{.TOC}}
+</nav>
+{{range .Chapters}}{{.}}
+{{end}}</body>
+</html>
+`))
+
+// BookOptions configures a book export.
+type BookOptions struct {
+	// Title is the book title; it defaults to the title of the exported page.
+	Title string
+	// Language is the BCP 47 language tag of the book (default "en").
+	Language string
+	// AssetsDir is the asset storage directory (<data>/assets).
+	AssetsDir string
+}
+
+// BookResult summarizes a book export.
+type BookResult struct {
+	Chapters int
+	Images   int
+	Excluded int
+}
+
+// bookChapter is one page of the book. The first chapter is always the
+// export root: it has no number and its content introduces the book.
+type bookChapter struct {
+	node *Node
+	// number is the outline number ("2.1"); empty for the export root.
+	number string
+	// level is the heading level of the chapter title.
+	level  int
+	anchor string
+	// file is the EPUB content document holding the chapter.
+	file string
+}
+
+// bookImage is an attachment embedded into the book.
+type bookImage struct {
+	pageID    string
+	name      string
+	mediaType string
+	file      string
+	itemID    string
+}
+
+// bookBuilder renders the chapters of a book. pageHref and imageHref decide
+// how cross-page links and images are written for the output format.
+type bookBuilder struct {
+	src       *Subtree
+	opts      BookOptions
+	chapters  []*bookChapter
+	byID      map[string]*bookChapter
+	renderer  *Renderer
+	pageHref  func(from, to *bookChapter, anchor string) string
+	imageHref func(from *bookChapter, pageID, name, mediaType string) (string, error)
+}
+
+func newBookBuilder(src *Subtree, opts BookOptions) *bookBuilder {
+	b := &bookBuilder{src: src, opts: opts, byID: map[string]*bookChapter{}, renderer: NewRenderer()}
+	if strings.TrimSpace(b.opts.Title) == "" {
+		b.opts.Title = src.Root.Title
+		if src.Root.IsWikiRoot() {
+			b.opts.Title = "LeafWiki"
+		}
+	}
+	if strings.TrimSpace(b.opts.Language) == "" {
+		b.opts.Language = "en"
+	}
+
+	var add func(n *Node, number string)
+	add = func(n *Node, number string) {
+		ch := &bookChapter{
+			node:   n,
+			number: number,
+			level:  min(n.Depth+1, 6),
+			anchor: "page-" + n.ID,
+			file:   fmt.Sprintf("chapter-%04d.xhtml", len(b.chapters)),
+		}
+		b.chapters = append(b.chapters, ch)
+		b.byID[n.ID] = ch
+		for i, child := range n.Children {
+			add(child, joinNumber(number, i+1))
+		}
+	}
+	add(src.Root, "")
+	return b
+}
+
+func joinNumber(prefix string, n int) string {
+	if prefix == "" {
+		return strconv.Itoa(n)
+	}
+	return prefix + "." + strconv.Itoa(n)
+}
+
+// headingAnchor is the in-document ID of a heading of a chapter; it is
+// prefixed with the chapter anchor so that IDs stay unique in the book.
+func headingAnchor(ch *bookChapter, id string) string {
+	if id == "" {
+		return ch.anchor
+	}
+	return ch.anchor + "--" + id
+}
+
+// renderChapter renders a chapter as a <section> with a numbered title.
+// Headings inside the page are shifted below the chapter title and numbered
+// after it (2.1 → 2.1.1).
+func (b *bookBuilder) renderChapter(ch *bookChapter, xhtml bool) (string, error) {
+	var counters []int
+	body, err := b.renderer.Render(ch.node.Content, RenderOptions{
+		RewriteLink: func(dest string, image bool) (string, bool) {
+			return b.linkHref(ch, dest, image)
+		},
+		RewriteWikiLink: func(target string) (string, bool) {
+			return wikiLinkDestination(b.src, ch.node, target)
+		},
+		HeadingBase: min(ch.level+1, 6),
+		Heading: func(level int, id, text string) (string, string) {
+			if ch.number == "" {
+				return headingAnchor(ch, id), ""
+			}
+			depth := max(level-min(ch.level+1, 6), 0)
+			for len(counters) <= depth {
+				counters = append(counters, 0)
+			}
+			counters[depth]++
+			counters = counters[:depth+1]
+			number := ch.number
+			for _, c := range counters {
+				number += "." + strconv.Itoa(c)
+			}
+			return headingAnchor(ch, id), number
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("render %s: %w", ch.node.ID, err)
+	}
+	if xhtml {
+		body = xhtmlVoidElementPattern.ReplaceAllString(body, "<$1$2/>")
+	}
+
+	var out strings.Builder
+	out.WriteString(`<section class="book-chapter" id="` + ch.anchor + `">` + "\n")
+	if ch.number != "" {
+		tag := "h" + strconv.Itoa(ch.level)
+		out.WriteString("<" + tag + ` class="chapter-title"><span class="heading-number">` + ch.number + "</span> " + htmlutil.EscapeText(ch.node.Title) + "</" + tag + ">\n")
+	}
+	out.WriteString(body)
+	out.WriteString("\n</section>")
+	return out.String(), nil
+}
+
+func (b *bookBuilder) linkHref(from *bookChapter, dest string, image bool) (string, bool) {
+	target := b.src.ResolveLink(from.node, dest)
+	switch target.Kind {
+	case LinkExternal:
+		return dest, true
+	case LinkAnchor:
+		return b.pageHref(from, from, headingAnchor(from, target.Fragment)), true
+	case LinkPage:
+		to := b.byID[target.Node.ID]
+		if to == nil {
+			return "", false
+		}
+		return b.pageHref(from, to, headingAnchor(to, target.Fragment)), true
+	case LinkAsset:
+		mediaType, ok := bookImageTypes[strings.ToLower(path.Ext(target.AssetName))]
+		if !image || !ok {
+			// Only images can be embedded; other attachments are dropped.
+			return "", false
+		}
+		href, err := b.imageHref(from, target.AssetPageID, target.AssetName, mediaType)
+		if err != nil {
+			return "", false
+		}
+		return href, true
+	default:
+		return "", false
+	}
+}
+
+// renderTOC renders the nested table of contents.
+func (b *bookBuilder) renderTOC(from *bookChapter, epub bool) string {
+	var out strings.Builder
+	var write func(n *Node)
+	write = func(n *Node) {
+		if len(n.Children) == 0 {
+			return
+		}
+		out.WriteString("<ol>")
+		for _, child := range n.Children {
+			ch := b.byID[child.ID]
+			out.WriteString(`<li><a href="` + htmlutil.EscapeText(b.pageHref(from, ch, ch.anchor)) + `">`)
+			if !epub {
+				out.WriteString(`<span class="number">` + ch.number + "</span> ")
+			} else {
+				out.WriteString(ch.number + " ")
+			}
+			out.WriteString(htmlutil.EscapeText(child.Title) + "</a>")
+			write(child)
+			out.WriteString("</li>")
+		}
+		out.WriteString("</ol>")
+	}
+	write(b.src.Root)
+	return out.String()
+}
+
+// ExportBookHTML writes the subtree as one standalone HTML document: a title,
+// a table of contents and every page in tree order with numbered headings.
+// Links between pages point to in-document anchors and images are embedded
+// as data URIs.
+func ExportBookHTML(src *Subtree, w io.Writer, opts BookOptions) (*BookResult, error) {
+	b := newBookBuilder(src, opts)
+	result := &BookResult{Excluded: src.Excluded}
+
+	// The sanitizer drops data URIs, so images are rendered with placeholder
+	// sources that are replaced once the chapters are sanitized.
+	embedded := map[string]string{}
+	var dataURIs []string
+	b.pageHref = func(_, _ *bookChapter, anchor string) string {
+		return "#" + anchor
+	}
+	b.imageHref = func(_ *bookChapter, pageID, name, mediaType string) (string, error) {
+		key := pageID + "/" + name
+		if placeholder, ok := embedded[key]; ok {
+			return placeholder, nil
+		}
+		data, err := os.ReadFile(assetDiskPath(b.opts.AssetsDir, pageID, name))
+		if err != nil {
+			return "", err
+		}
+		placeholder := bookImagePlaceholder + strconv.Itoa(len(dataURIs))
+		embedded[key] = placeholder
+		dataURIs = append(dataURIs, `src="`+placeholder+`"`, `src="data:`+mediaType+";base64,"+base64.StdEncoding.EncodeToString(data)+`"`)
+		result.Images++
+		return placeholder, nil
+	}
+
+	var rendered []string
+	for _, ch := range b.chapters {
+		html, err := b.renderChapter(ch, false)
+		if err != nil {
+			return nil, err
+		}
+		rendered = append(rendered, html)
+		if !ch.node.IsWikiRoot() {
+			result.Chapters++
+		}
+	}
+	embed := strings.NewReplacer(dataURIs...)
+	chapters := make([]template.HTML, 0, len(rendered))
+	for _, html := range rendered {
+		chapters = append(chapters, template.HTML(embed.Replace(html)))
+	}
+
+	err := bookHTMLTemplate.Execute(w, struct {
+		Title    string
+		Language string
+		CSS      template.CSS
+		TOC      template.HTML
+		Chapters []template.HTML
+	}{
+		Title:    b.opts.Title,
+		Language: b.opts.Language,
+		CSS:      template.CSS(bookCSS),
+		TOC:      template.HTML(b.renderTOC(nil, false)),
+		Chapters: chapters,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+const (
+	epubMimetype     = "application/epub+zip"
+	epubContentDir   = "OEBPS"
+	epubNavFile      = "nav.xhtml"
+	epubStyleFile    = "style.css"
+	epubImagesDir    = "images"
+	epubContainerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles>
+<rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+</rootfiles>
+</container>
+`
+)
+
+// ExportBookEPUB writes the subtree as an EPUB 3 package: one content
+// document per page, a navigation document with the table of contents and
+// the embedded images. The package only depends on the exported content
+// (its modification date is the newest page update), so exporting the same
+// tree twice is byte-identical.
+func ExportBookEPUB(src *Subtree, w *ZipWriter, opts BookOptions) (*BookResult, error) {
+	b := newBookBuilder(src, opts)
+	result := &BookResult{Excluded: src.Excluded}
+
+	var images []*bookImage
+	embedded := map[string]*bookImage{}
+	b.pageHref = func(from, to *bookChapter, anchor string) string {
+		if from == to {
+			return "#" + anchor
+		}
+		return to.file + "#" + anchor
+	}
+	b.imageHref = func(_ *bookChapter, pageID, name, mediaType string) (string, error) {
+		key := pageID + "/" + name
+		if img, ok := embedded[key]; ok {
+			return relativeHref("", img.file), nil
+		}
+		diskPath := assetDiskPath(b.opts.AssetsDir, pageID, name)
+		if _, err := os.Stat(diskPath); err != nil {
+			return "", err
+		}
+		img := &bookImage{
+			pageID:    pageID,
+			name:      name,
+			mediaType: mediaType,
+			file:      path.Join(epubImagesDir, pageID, name),
+			itemID:    "img-" + strconv.Itoa(len(images)+1),
+		}
+		if err := w.CopyFile(path.Join(epubContentDir, img.file), diskPath); err != nil {
+			return "", err
+		}
+		embedded[key] = img
+		images = append(images, img)
+		result.Images++
+		return relativeHref("", img.file), nil
+	}
+
+	if err := w.WriteStored("mimetype", []byte(epubMimetype)); err != nil {
+		return nil, err
+	}
+	if err := w.WriteFile("META-INF/container.xml", []byte(epubContainerXML)); err != nil {
+		return nil, err
+	}
+	if err := w.WriteFile(path.Join(epubContentDir, epubStyleFile), []byte(bookCSS)); err != nil {
+		return nil, err
+	}
+
+	for _, ch := range b.chapters {
+		html, err := b.renderChapter(ch, true)
+		if err != nil {
+			return nil, err
+		}
+		title := ch.node.Title
+		if ch.node == b.src.Root {
+			title = b.opts.Title
+		}
+		if ch.number != "" {
+			title = ch.number + " " + title
+		} else {
+			// The export root opens the book, so it carries the book title.
+			html = `<h1 class="book-title">` + htmlutil.EscapeText(b.opts.Title) + "</h1>\n" + html
+		}
+		if err := w.WriteFile(path.Join(epubContentDir, ch.file), []byte(b.xhtmlDocument(title, html))); err != nil {
+			return nil, err
+		}
+		if !ch.node.IsWikiRoot() {
+			result.Chapters++
+		}
+	}
+
+	nav := `<nav epub:type="toc" id="toc">` + "\n<h1>Contents</h1>\n" + b.renderTOC(nil, true) + "\n</nav>"
+	if err := w.WriteFile(path.Join(epubContentDir, epubNavFile), []byte(b.xhtmlDocument("Contents", nav))); err != nil {
+		return nil, err
+	}
+	if err := w.WriteFile(path.Join(epubContentDir, "content.opf"), []byte(b.packageDocument(images))); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (b *bookBuilder) xhtmlDocument(title, body string) string {
+	var out strings.Builder
+	out.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	out.WriteString("<!DOCTYPE html>\n")
+	out.WriteString(`<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="` + htmlutil.EscapeText(b.opts.Language) + `" lang="` + htmlutil.EscapeText(b.opts.Language) + `">` + "\n")
+	out.WriteString("<head>\n<meta charset=\"utf-8\"/>\n<title>" + htmlutil.EscapeText(title) + "</title>\n")
+	out.WriteString(`<link rel="stylesheet" type="text/css" href="` + epubStyleFile + `"/>` + "\n</head>\n<body>\n")
+	out.WriteString(body)
+	out.WriteString("\n</body>\n</html>\n")
+	return out.String()
+}
+
+// packageDocument renders content.opf: metadata, manifest and spine.
+func (b *bookBuilder) packageDocument(images []*bookImage) string {
+	var out strings.Builder
+	out.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	out.WriteString(`<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="` + htmlutil.EscapeText(b.opts.Language) + `">` + "\n")
+	out.WriteString(`<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">` + "\n")
+	out.WriteString(`<dc:identifier id="book-id">urn:leafwiki:` + htmlutil.EscapeText(b.src.Root.ID) + "</dc:identifier>\n")
+	out.WriteString("<dc:title>" + htmlutil.EscapeText(b.opts.Title) + "</dc:title>\n")
+	out.WriteString("<dc:language>" + htmlutil.EscapeText(b.opts.Language) + "</dc:language>\n")
+	out.WriteString(`<meta property="dcterms:modified">` + b.modified().Format("2006-01-02T15:04:05Z") + "</meta>\n")
+	out.WriteString("</metadata>\n<manifest>\n")
+	out.WriteString(`<item id="nav" href="` + epubNavFile + `" media-type="application/xhtml+xml" properties="nav"/>` + "\n")
+	out.WriteString(`<item id="style" href="` + epubStyleFile + `" media-type="text/css"/>` + "\n")
+	for i, ch := range b.chapters {
+		out.WriteString(`<item id="chapter-` + strconv.Itoa(i) + `" href="` + ch.file + `" media-type="application/xhtml+xml"/>` + "\n")
+	}
+	for _, img := range images {
+		out.WriteString(`<item id="` + img.itemID + `" href="` + htmlutil.EscapeText(relativeHref("", img.file)) + `" media-type="` + img.mediaType + `"/>` + "\n")
+	}
+	// The title page (the export root) opens the book, followed by the
+	// table of contents and the chapters.
+	out.WriteString("</manifest>\n<spine>\n")
+	for i := range b.chapters {
+		out.WriteString(`<itemref idref="chapter-` + strconv.Itoa(i) + `"/>` + "\n")
+		if i == 0 {
+			out.WriteString(`<itemref idref="nav"/>` + "\n")
+		}
+	}
+	out.WriteString("</spine>\n</package>\n")
+	return out.String()
+}
+
+// modified returns the newest update time of the exported pages, which is
+// used as the package modification date.
+func (b *bookBuilder) modified() time.Time {
+	var latest time.Time
+	for _, ch := range b.chapters {
+		if ch.node.Metadata.UpdatedAt.After(latest) {
+			latest = ch.node.Metadata.UpdatedAt
+		}
+	}
+	if latest.IsZero() {
+		return zipModTime.UTC()
+	}
+	return latest.UTC()
+}
@@ -0,0 +1,128 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestExportBookHTML_NumbersChaptersAndLinksInDocument(t *testing.T) {
+	f := newExportFixture(t)
+	src, err := CollectSubtree(f.ts, "docs")
+	if err != nil {
+		t.Fatalf("CollectSubtree failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	result, err := ExportBookHTML(src, &buf, BookOptions{AssetsDir: f.assetsDir})
+	if err != nil {
+		t.Fatalf("ExportBookHTML failed: %v", err)
+	}
+	if result.Chapters != 3 || result.Images != 1 || result.Excluded != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	html := buf.String()
+	for _, want := range []string{
+		`<title>Docs</title>`,
+		`<a href="#page-` + f.introID + `"><span class="number">1</span> Intro</a>`,
+		`<a href="#page-` + f.guideID + `"><span class="number">2</span> Guide</a>`,
+		`<section class="book-chapter" id="page-` + f.guideID + `">`,
+		`<h2 class="chapter-title"><span class="heading-number">2</span> Guide</h2>`,
+		`<h3 id="page-` + f.guideID + `--setup">2.1 Setup</h3>`,
+		`href="#page-` + f.guideID + `--setup"`,
+		`href="#page-` + f.introID + `"`,
+		`src="data:image/png;base64,cG5n"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Fatalf("book is missing %q:\n%s", want, html)
+		}
+	}
+	if strings.Contains(html, "Top secret") || strings.Contains(html, `href="/other"`) {
+		t.Fatalf("book must not contain private pages or links outside the export:\n%s", html)
+	}
+}
+
+func TestExportBookEPUB_WritesValidPackage(t *testing.T) {
+	f := newExportFixture(t)
+	src, err := CollectSubtree(f.ts, "docs")
+	if err != nil {
+		t.Fatalf("CollectSubtree failed: %v", err)
+	}
+
+	export := func() []byte {
+		var buf bytes.Buffer
+		zw := NewZipWriter(&buf)
+		if _, err := ExportBookEPUB(src, zw, BookOptions{Title: "Onboarding", AssetsDir: f.assetsDir}); err != nil {
+			t.Fatalf("ExportBookEPUB failed: %v", err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+	data := export()
+	if !bytes.Equal(data, export()) {
+		t.Fatalf("expected byte-identical packages")
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("zip.NewReader failed: %v", err)
+	}
+	if first := zr.File[0]; first.Name != "mimetype" || first.Method != zip.Store {
+		t.Fatalf("first entry must be the stored mimetype, got %s (method %d)", first.Name, first.Method)
+	}
+
+	files := map[string]string{}
+	for _, zf := range zr.File {
+		rc, err := zf.Open()
+		if err != nil {
+			t.Fatalf("open %s failed: %v", zf.Name, err)
+		}
+		content, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			t.Fatalf("read %s failed: %v", zf.Name, err)
+		}
+		files[zf.Name] = string(content)
+
+		if strings.HasSuffix(zf.Name, ".xhtml") || strings.HasSuffix(zf.Name, ".opf") || strings.HasSuffix(zf.Name, ".xml") {
+			dec := xml.NewDecoder(bytes.NewReader(content))
+			for {
+				if _, err := dec.Token(); err != nil {
+					if errors.Is(err, io.EOF) {
+						break
+					}
+					t.Fatalf("%s is not well-formed XML: %v\n%s", zf.Name, err, content)
+				}
+			}
+		}
+	}
+
+	opf := files["OEBPS/content.opf"]
+	for _, want := range []string{
+		"<dc:title>Onboarding</dc:title>",
+		`properties="nav"`,
+		`href="images/` + f.introID + `/diagram.png" media-type="image/png"`,
+		`<itemref idref="chapter-2"/>`,
+	} {
+		if !strings.Contains(opf, want) {
+			t.Fatalf("content.opf is missing %q:\n%s", want, opf)
+		}
+	}
+	if files["OEBPS/images/"+f.introID+"/diagram.png"] != "png" {
+		t.Fatalf("image was not embedded")
+	}
+	if nav := files["OEBPS/nav.xhtml"]; !strings.Contains(nav, `href="chapter-0002.xhtml#page-`+f.guideID+`"`) {
+		t.Fatalf("nav.xhtml is missing the guide chapter:\n%s", nav)
+	}
+	intro := files["OEBPS/chapter-0001.xhtml"]
+	if !strings.Contains(intro, `href="chapter-0002.xhtml#page-`+f.guideID+`--setup"`) || !strings.Contains(intro, `<img src="images/`+f.introID+`/diagram.png" alt="diagram"/>`) {
+		t.Fatalf("chapter links or images were not rewritten:\n%s", intro)
+	}
+}
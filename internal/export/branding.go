@@ -0,0 +1,35 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/perber/wiki/internal/branding"
+)
+
+// BrandingSource is the part of the branding service exports depend on.
+type BrandingSource interface {
+	GetBranding() (*branding.BrandingConfigResponse, error)
+	GetBrandingAssetsDir() string
+}
+
+// SiteOptionsFromBranding resolves the site name and logo of the current
+// branding. A missing logo file is ignored rather than failing the export.
+func SiteOptionsFromBranding(b BrandingSource, assetsDir string) (SiteOptions, error) {
+	opts := SiteOptions{AssetsDir: assetsDir}
+	if b == nil {
+		return opts, nil
+	}
+	cfg, err := b.GetBranding()
+	if err != nil {
+		return opts, err
+	}
+	opts.SiteName = cfg.SiteName
+	if cfg.LogoFile != "" && !branding.ContainsUnsafePath(cfg.LogoFile) {
+		logoPath := filepath.Join(b.GetBrandingAssetsDir(), cfg.LogoFile)
+		if info, err := os.Stat(logoPath); err == nil && info.Mode().IsRegular() {
+			opts.LogoPath = logoPath
+		}
+	}
+	return opts, nil
+}
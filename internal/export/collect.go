@@ -0,0 +1,238 @@
+// Package export turns a wiki subtree into portable, offline artifacts
+// (static HTML sites, Markdown archives, books). Every exporter works on an
+// immutable Node snapshot collected up front, so rendering never holds the
+// tree lock and the output only depends on the content that was collected.
+package export
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/perber/wiki/internal/core/markdown"
+	"github.com/perber/wiki/internal/core/tree"
+)
+
+// PrivateFrontmatterKey marks a page (and everything below it) as excluded
+// from every export. It is a plain frontmatter property, e.g. `private: true`.
+const PrivateFrontmatterKey = "private"
+
+var ErrExportSourceNotFound = errors.New("export source not found")
+
+// Node is a snapshot of one exported tree node with its content loaded.
+type Node struct {
+	ID          string
+	Title       string
+	Slug        string
+	Kind        tree.NodeKind
+	Path        string // wiki route path, e.g. "ops/handbook/intro" ("" for the wiki root)
+	RelPath     string // path relative to the export root ("" for the export root itself)
+	Depth       int    // 0 for the export root
+	Parent      *Node
+	Children    []*Node
+	Content     string // markdown body without frontmatter
+	Frontmatter markdown.Frontmatter
+	Metadata    tree.PageMetadata
+}
+
+// IsWikiRoot reports whether the node is the synthetic wiki root, which has
+// no content of its own.
+func (n *Node) IsWikiRoot() bool {
+	return n != nil && n.ID == "root"
+}
+
+// Walk calls fn for n and every descendant in tree (pre-)order.
+func (n *Node) Walk(fn func(*Node)) {
+	if n == nil {
+		return
+	}
+	fn(n)
+	for _, child := range n.Children {
+		child.Walk(fn)
+	}
+}
+
+// Subtree is the collected export source.
+type Subtree struct {
+	Root *Node
+	byID map[string]*Node
+	// byPath indexes nodes by their wiki route path.
+	byPath map[string]*Node
+	// Excluded counts pages dropped because they (or an ancestor) are private.
+	Excluded int
+}
+
+// NodeByID returns the exported node with the given ID, or nil.
+func (s *Subtree) NodeByID(id string) *Node {
+	return s.byID[id]
+}
+
+// NodeByPath returns the exported node with the given wiki route path, or nil.
+func (s *Subtree) NodeByPath(routePath string) *Node {
+	return s.byPath[strings.Trim(routePath, "/")]
+}
+
+// Nodes returns all exported nodes in tree order.
+func (s *Subtree) Nodes() []*Node {
+	var out []*Node
+	s.Root.Walk(func(n *Node) { out = append(out, n) })
+	return out
+}
+
+// NodesByTitle returns all exported nodes whose title matches
+// case-insensitively, in tree order.
+func (s *Subtree) NodesByTitle(title string) []*Node {
+	var out []*Node
+	s.Root.Walk(func(n *Node) {
+		if !n.IsWikiRoot() && strings.EqualFold(n.Title, title) {
+			out = append(out, n)
+		}
+	})
+	return out
+}
+
+// CollectSubtree snapshots the subtree at routePath ("" = whole wiki) and
+// loads the content of every node. Children are ordered like the tree
+// (Position, then ID), which is the order SaveChildOrder persists and
+// applyChildOrder restores, so the result is deterministic. Private pages
+// and their descendants are dropped; .leafwikiignore'd files never make it
+// into the tree in the first place.
+func CollectSubtree(ts *tree.TreeService, routePath string) (*Subtree, error) {
+	routePath = strings.Trim(strings.TrimSpace(routePath), "/")
+
+	var start *tree.PageNode
+	if routePath == "" {
+		start = ts.GetTree()
+		if start == nil {
+			return nil, tree.ErrTreeNotLoaded
+		}
+	} else {
+		page, err := ts.FindPageByRoutePath(routePath)
+		if err != nil {
+			if errors.Is(err, tree.ErrPageNotFound) {
+				return nil, fmt.Errorf("%w: %s", ErrExportSourceNotFound, routePath)
+			}
+			return nil, err
+		}
+		start = page.PageNode
+	}
+
+	root := snapshotNode(start, nil, routePath, "", 0)
+
+	var ids []string
+	root.Walk(func(n *Node) {
+		if !n.IsWikiRoot() {
+			ids = append(ids, n.ID)
+		}
+	})
+	pages, errs := ts.GetPages(ids)
+	loaded := make(map[string]*tree.Page, len(ids))
+	for i, p := range pages {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("load page %s: %w", ids[i], errs[i])
+		}
+		loaded[ids[i]] = p
+	}
+
+	s := &Subtree{Root: root, byID: map[string]*Node{}, byPath: map[string]*Node{}}
+	if root.IsWikiRoot() {
+		s.register(root)
+	} else if !s.load(root, loaded) {
+		return nil, fmt.Errorf("%w: %s", ErrExportSourceNotFound, routePath)
+	}
+	if root.IsWikiRoot() {
+		root.Children = s.loadChildren(root.Children, loaded)
+	}
+	return s, nil
+}
+
+// load fills n (and recursively its children) from the loaded pages and
+// reports whether n survives the private filter.
+func (s *Subtree) load(n *Node, loaded map[string]*tree.Page) bool {
+	page := loaded[n.ID]
+	if page == nil {
+		return false
+	}
+	fm, body, _, err := markdown.ParseFrontmatter(page.RawContent)
+	if err != nil {
+		body = page.Content
+	}
+	if isPrivate(fm) {
+		n.Walk(func(*Node) { s.Excluded++ })
+		return false
+	}
+	n.Content = body
+	n.Frontmatter = fm
+	s.register(n)
+	n.Children = s.loadChildren(n.Children, loaded)
+	return true
+}
+
+func (s *Subtree) loadChildren(children []*Node, loaded map[string]*tree.Page) []*Node {
+	kept := children[:0]
+	for _, child := range children {
+		if s.load(child, loaded) {
+			kept = append(kept, child)
+		}
+	}
+	return kept
+}
+
+func (s *Subtree) register(n *Node) {
+	s.byID[n.ID] = n
+	s.byPath[n.Path] = n
+}
+
+func snapshotNode(src *tree.PageNode, parent *Node, routePath, relPath string, depth int) *Node {
+	n := &Node{
+		ID:       src.ID,
+		Title:    src.Title,
+		Slug:     src.Slug,
+		Kind:     src.Kind,
+		Path:     routePath,
+		RelPath:  relPath,
+		Depth:    depth,
+		Parent:   parent,
+		Metadata: src.Metadata,
+	}
+
+	children := append([]*tree.PageNode(nil), src.Children...)
+	sort.SliceStable(children, func(i, j int) bool {
+		if children[i].Position != children[j].Position {
+			return children[i].Position < children[j].Position
+		}
+		return children[i].ID < children[j].ID
+	})
+	for _, child := range children {
+		if child == nil {
+			continue
+		}
+		n.Children = append(n.Children, snapshotNode(child, n, joinPath(routePath, child.Slug), joinPath(relPath, child.Slug), depth+1))
+	}
+	return n
+}
+
+func joinPath(base, slug string) string {
+	if base == "" {
+		return slug
+	}
+	return base + "/" + slug
+}
+
+func isPrivate(fm markdown.Frontmatter) bool {
+	value, ok := fm.ExtraFields[PrivateFrontmatterKey]
+	if !ok {
+		return false
+	}
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "yes", "1":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+package export
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// LinkKind classifies a link destination found in exported markdown.
+type LinkKind int
+
+const (
+	// LinkExternal is anything with a scheme (https:, mailto:, …) and is kept as-is.
+	LinkExternal LinkKind = iota
+	// LinkAnchor points into the current page (#heading).
+	LinkAnchor
+	// LinkPage points at a page that is part of the export.
+	LinkPage
+	// LinkAsset points at an attachment (/assets/<pageID>/<file>).
+	LinkAsset
+	// LinkUnresolved is an internal link whose target is not part of the
+	// export (outside the subtree, private or missing).
+	LinkUnresolved
+)
+
+// LinkTarget is the result of resolving a link destination.
+type LinkTarget struct {
+	Kind LinkKind
+	// Node is set for LinkPage.
+	Node *Node
+	// AssetPageID and AssetName are set for LinkAsset.
+	AssetPageID string
+	AssetName   string
+	// Fragment is the "#…" part without the leading hash.
+	Fragment string
+}
+
+// ResolveLink resolves a markdown link destination written on page from.
+// Relative destinations use the wiki's "page is folder" semantics, so
+// "child" on /docs/intro points at /docs/intro/child.
+func (s *Subtree) ResolveLink(from *Node, dest string) LinkTarget {
+	dest = strings.TrimSpace(dest)
+	dest = strings.TrimSuffix(strings.TrimPrefix(dest, "<"), ">")
+	if dest == "" {
+		return LinkTarget{Kind: LinkUnresolved}
+	}
+	if strings.HasPrefix(dest, "#") {
+		return LinkTarget{Kind: LinkAnchor, Fragment: strings.TrimPrefix(dest, "#")}
+	}
+	if isExternalDestination(dest) {
+		return LinkTarget{Kind: LinkExternal}
+	}
+
+	if pageID, name, ok := parseAssetDestination(dest); ok {
+		if s.NodeByID(pageID) == nil {
+			return LinkTarget{Kind: LinkUnresolved}
+		}
+		return LinkTarget{Kind: LinkAsset, AssetPageID: pageID, AssetName: name}
+	}
+
+	ref, err := url.Parse(dest)
+	if err != nil {
+		return LinkTarget{Kind: LinkUnresolved}
+	}
+	fragment := ref.Fragment
+
+	base := "/"
+	if from != nil && from.Path != "" {
+		base = "/" + from.Path + "/"
+	}
+	baseURL, err := url.Parse("https://wiki.invalid" + base)
+	if err != nil {
+		return LinkTarget{Kind: LinkUnresolved}
+	}
+	resolved := baseURL.ResolveReference(&url.URL{Path: ref.Path})
+	target := strings.Trim(path.Clean(resolved.Path), "/")
+	if target == "." {
+		target = ""
+	}
+
+	node := s.NodeByPath(target)
+	if node == nil && strings.HasSuffix(strings.ToLower(target), ".md") {
+		// A trailing .md is ignored for page lookup, like in the wiki itself.
+		node = s.NodeByPath(target[:len(target)-len(".md")])
+	}
+	if node == nil {
+		return LinkTarget{Kind: LinkUnresolved}
+	}
+	return LinkTarget{Kind: LinkPage, Node: node, Fragment: fragment}
+}
+
+// ResolveWikiLink resolves the target of a [[Target]] wikilink: first as a
+// route path, then by (case-insensitive) title. Ambiguous titles resolve to
+// the first match in tree order.
+func (s *Subtree) ResolveWikiLink(from *Node, target string) LinkTarget {
+	target = strings.TrimSpace(target)
+	fragment := ""
+	if i := strings.Index(target, "#"); i >= 0 {
+		target, fragment = target[:i], target[i+1:]
+	}
+	if target == "" {
+		if fragment == "" {
+			return LinkTarget{Kind: LinkUnresolved}
+		}
+		return LinkTarget{Kind: LinkAnchor, Fragment: fragment}
+	}
+	if strings.Contains(target, "/") {
+		if resolved := s.ResolveLink(from, target); resolved.Kind == LinkPage {
+			resolved.Fragment = fragment
+			return resolved
+		}
+	}
+	if matches := s.NodesByTitle(target); len(matches) > 0 {
+		return LinkTarget{Kind: LinkPage, Node: matches[0], Fragment: fragment}
+	}
+	return LinkTarget{Kind: LinkUnresolved}
+}
+
+func isExternalDestination(dest string) bool {
+	if strings.HasPrefix(dest, "//") {
+		return true
+	}
+	ref, err := url.Parse(dest)
+	if err != nil {
+		return false
+	}
+	return ref.Scheme != ""
+}
+
+// parseAssetDestination splits "/assets/<pageID>/<file>" (or the relative
+// "assets/…" form) into its page ID and file name.
+func parseAssetDestination(dest string) (pageID string, name string, ok bool) {
+	p := dest
+	if i := strings.IndexAny(p, "?#"); i >= 0 {
+		p = p[:i]
+	}
+	p = strings.TrimPrefix(p, "/")
+	if !strings.HasPrefix(p, "assets/") {
+		return "", "", false
+	}
+	rest := strings.TrimPrefix(p, "assets/")
+	pageID, name, found := strings.Cut(rest, "/")
+	if !found || pageID == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", false
+	}
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		name = unescaped
+	}
+	if name == "." || name == ".." {
+		return "", "", false
+	}
+	return pageID, name, true
+}
+
+// relativeHref returns the relative URL from the directory of fromFile to
+// toFile. Both are slash separated paths relative to the output root.
+func relativeHref(fromFile, toFile string) string {
+	fromDir := path.Dir(fromFile)
+	if fromDir == "." {
+		fromDir = ""
+	}
+	var fromParts []string
+	if fromDir != "" {
+		fromParts = strings.Split(fromDir, "/")
+	}
+	toParts := strings.Split(toFile, "/")
+
+	common := 0
+	for common < len(fromParts) && common < len(toParts)-1 && fromParts[common] == toParts[common] {
+		common++
+	}
+
+	var b strings.Builder
+	for range fromParts[common:] {
+		b.WriteString("../")
+	}
+	for i, part := range toParts[common:] {
+		if i > 0 {
+			b.WriteByte('/')
+		}
+		b.WriteString(url.PathEscape(part))
+	}
+	return b.String()
+}
@@ -0,0 +1,199 @@
+package export
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/perber/wiki/internal/core/markdown"
+	"github.com/perber/wiki/internal/core/tree"
+	"github.com/perber/wiki/internal/importer"
+)
+
+const markdownIndexFile = "index.md"
+
+// MarkdownOptions configures a Markdown export.
+type MarkdownOptions struct {
+	// AssetsDir is the asset storage directory (<data>/assets).
+	AssetsDir string
+	// WikiLinks writes links between exported pages and attachments as
+	// Obsidian wikilinks ([[docs/guide|Guide]], ![[docs/intro.assets/a.png]])
+	// instead of relative Markdown links.
+	WikiLinks bool
+	// IncludeIDs keeps the LeafWiki page ID in the frontmatter (leafwiki_id).
+	IncludeIDs bool
+}
+
+// MarkdownResult summarizes a Markdown export.
+type MarkdownResult struct {
+	Pages    int
+	Assets   int
+	Excluded int
+}
+
+// ExportMarkdown writes the collected subtree as plain Markdown files in a
+// folder hierarchy that the importer maps back onto the same structure:
+// sections become <path>/index.md, pages <path>.md and the attachments of a
+// page are stored next to it in <file>.assets/. Links between exported pages
+// and to their attachments are rewritten to relative file paths (or
+// wikilinks); links leaving the export are kept as wiki paths.
+func ExportMarkdown(src *Subtree, w Writer, opts MarkdownOptions) (*MarkdownResult, error) {
+	result := &MarkdownResult{Excluded: src.Excluded}
+	prefix := markdownRootPrefix(src)
+
+	for _, node := range src.Nodes() {
+		if node.IsWikiRoot() {
+			continue
+		}
+		out := markdownFilePath(prefix, node)
+
+		files, err := pageAssetFiles(opts.AssetsDir, node.ID)
+		if err != nil {
+			return nil, fmt.Errorf("list assets of %s: %w", node.ID, err)
+		}
+		for _, name := range files {
+			if err := w.CopyFile(markdownAssetPath(out, name), assetDiskPath(opts.AssetsDir, node.ID, name)); err != nil {
+				return nil, err
+			}
+			result.Assets++
+		}
+
+		body, err := rewriteMarkdownForExport(src, node, prefix, out, opts.WikiLinks)
+		if err != nil {
+			return nil, fmt.Errorf("rewrite %s: %w", node.ID, err)
+		}
+		content, err := buildExportedMarkdown(node, body, opts.IncludeIDs)
+		if err != nil {
+			return nil, fmt.Errorf("frontmatter of %s: %w", node.ID, err)
+		}
+		if err := w.WriteFile(out, []byte(content)); err != nil {
+			return nil, err
+		}
+		result.Pages++
+	}
+
+	return result, nil
+}
+
+// markdownRootPrefix is the folder everything is written to: the slug of the
+// exported page, so that importing the archive recreates it, or nothing when
+// the whole wiki is exported.
+func markdownRootPrefix(src *Subtree) string {
+	if src.Root.IsWikiRoot() {
+		return ""
+	}
+	return src.Root.Slug
+}
+
+// markdownFilePath returns the output file of a node, following the
+// importer's conventions: sections (and anything with children) become
+// <path>/index.md, pages <path>.md.
+func markdownFilePath(prefix string, n *Node) string {
+	rel := prefix
+	if n.RelPath != "" {
+		rel = joinPath(prefix, n.RelPath)
+	}
+	if n.Kind == tree.NodeKindSection || len(n.Children) > 0 {
+		return joinPath(rel, markdownIndexFile)
+	}
+	return rel + ".md"
+}
+
+func markdownAssetPath(mdFile, name string) string {
+	return path.Join(importer.SidecarAssetsDir(mdFile), name)
+}
+
+// buildExportedMarkdown prepends the frontmatter: the title (so it survives
+// the import even without a heading), tags and properties, and optionally
+// the page ID.
+func buildExportedMarkdown(n *Node, body string, includeID bool) (string, error) {
+	fields := make(map[string]interface{}, len(n.Frontmatter.ExtraFields)+2)
+	for key, value := range n.Frontmatter.ExtraFields {
+		fields[key] = value
+	}
+	fields["leafwiki_title"] = n.Title
+	if includeID {
+		fields["leafwiki_id"] = n.ID
+	}
+	return markdown.BuildMarkdownWithExtraFrontmatter(fields, body)
+}
+
+// rewriteMarkdownForExport rewrites wikilinks first, turning the resolvable
+// ones into links to wiki paths, and then every link to a wiki path into
+// the link format of the export.
+func rewriteMarkdownForExport(src *Subtree, n *Node, prefix, out string, wikiLinks bool) (string, error) {
+	content, err := importer.RewriteWikiLinks(n.Content, func(link importer.WikiLink) (string, error) {
+		target := src.ResolveWikiLink(n, link.Target)
+		if target.Kind != LinkPage {
+			return link.Raw, nil
+		}
+		label := link.Label
+		if label == "" {
+			label = target.Node.Title
+		}
+		if wikiLinks {
+			return formatWikiLink(false, markdownVaultPath(prefix, target.Node)+fragmentSuffix(target.Fragment), label), nil
+		}
+		return importer.MarkdownLink{Label: label, Destination: "/" + target.Node.Path + fragmentSuffix(target.Fragment)}.String(), nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return importer.RewriteMarkdownLinks(content, func(link importer.MarkdownLink) (string, error) {
+		target := src.ResolveLink(n, link.Href())
+		switch target.Kind {
+		case LinkPage:
+			if wikiLinks && !link.Image && isPlainWikiLabel(link.Label) {
+				return formatWikiLink(false, markdownVaultPath(prefix, target.Node)+fragmentSuffix(target.Fragment), link.Label), nil
+			}
+			return link.WithHref(relativeHref(out, markdownFilePath(prefix, target.Node)) + fragmentSuffix(target.Fragment)).String(), nil
+		case LinkAsset:
+			asset := markdownAssetPath(markdownFilePath(prefix, src.NodeByID(target.AssetPageID)), target.AssetName)
+			if wikiLinks && isPlainWikiLabel(link.Label) {
+				label := link.Label
+				if link.Image || label == path.Base(asset) {
+					label = ""
+				}
+				return formatWikiLink(link.Image, asset, label), nil
+			}
+			return link.WithHref(relativeHref(out, asset)).String(), nil
+		default:
+			return link.String(), nil
+		}
+	})
+}
+
+// markdownVaultPath is the wikilink target of a node: its file path relative
+// to the archive root without the .md extension, as Obsidian expects.
+func markdownVaultPath(prefix string, n *Node) string {
+	return strings.TrimSuffix(markdownFilePath(prefix, n), ".md")
+}
+
+func formatWikiLink(embed bool, target, label string) string {
+	var b strings.Builder
+	if embed {
+		b.WriteByte('!')
+	}
+	b.WriteString("[[")
+	b.WriteString(target)
+	if label != "" {
+		b.WriteByte('|')
+		b.WriteString(label)
+	}
+	b.WriteString("]]")
+	return b.String()
+}
+
+// isPlainWikiLabel reports whether a link label can be used inside a
+// wikilink without breaking it.
+func isPlainWikiLabel(label string) bool {
+	return !strings.ContainsAny(label, "[]|\n")
+}
+
+func fragmentSuffix(fragment string) string {
+	if fragment == "" {
+		return ""
+	}
+	return "#" + fragment
+}
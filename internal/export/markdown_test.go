@@ -0,0 +1,240 @@
+package export
+
+import (
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/perber/wiki/internal/core/tree"
+	"github.com/perber/wiki/internal/importer"
+)
+
+// treeImportWiki is a minimal importer.ImporterWiki backed by a bare
+// TreeService, used to re-import exported archives.
+type treeImportWiki struct {
+	ts        *tree.TreeService
+	assetsDir string
+}
+
+func (w *treeImportWiki) TreeHash() string { return w.ts.TreeHash() }
+
+func (w *treeImportWiki) LookupPagePath(p string) (*tree.PathLookup, error) {
+	return w.ts.LookupPagePath(p)
+}
+
+func (w *treeImportWiki) GetPage(id string) (*tree.Page, error) {
+	return w.ts.GetPage(id)
+}
+
+func (w *treeImportWiki) EnsurePath(userID, targetPath, title string, kind *tree.NodeKind) (*tree.Page, error) {
+	res, err := w.ts.EnsurePagePath(userID, targetPath, title, kind)
+	if err != nil {
+		return nil, err
+	}
+	return w.ts.GetPage(res.Page.ID)
+}
+
+func (w *treeImportWiki) UpdatePage(userID, id, title, slug string, content *string, kind *tree.NodeKind) (*tree.Page, error) {
+	if err := w.ts.UpdateNode(userID, id, title, slug, content, tree.VersionUnchecked, nil, nil, true); err != nil {
+		return nil, err
+	}
+	return w.ts.GetPage(id)
+}
+
+func (w *treeImportWiki) DeletePage(userID, id string) error {
+	return w.ts.DeleteNode(userID, id, false, tree.VersionUnchecked)
+}
+
+func (w *treeImportWiki) MovePage(userID, id, parentID string) error {
+	return w.ts.MoveNode(userID, id, parentID, tree.VersionUnchecked)
+}
+
+func (w *treeImportWiki) UploadAsset(userID, pageID string, file multipart.File, filename string, maxBytes int64) (string, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Join(w.assetsDir, pageID), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(w.assetsDir, pageID, filename), data, 0o644); err != nil {
+		return "", err
+	}
+	return "/assets/" + pageID + "/" + filename, nil
+}
+
+func (w *treeImportWiki) ImportRevisions(pageID string, revisions []importer.ImportedRevision, mapAuthors bool) error {
+	return nil
+}
+
+func importExportedDir(t *testing.T, dir string) *treeImportWiki {
+	t.Helper()
+	storageDir := t.TempDir()
+	ts := tree.NewTreeService(storageDir)
+	if err := ts.LoadTree(); err != nil {
+		t.Fatalf("LoadTree failed: %v", err)
+	}
+	w := &treeImportWiki{ts: ts, assetsDir: filepath.Join(storageDir, "assets")}
+
+	entries, err := importer.FindMarkdownEntries(dir)
+	if err != nil {
+		t.Fatalf("FindMarkdownEntries failed: %v", err)
+	}
+	opts := importer.PlanOptions{SourceBasePath: dir}
+	plan, err := importer.NewPlanner(w, tree.NewSlugService(), storageDir).CreatePlan(entries, opts)
+	if err != nil {
+		t.Fatalf("CreatePlan failed: %v", err)
+	}
+	result, err := importer.NewExecutor(plan, &opts, 0, w, slog.Default()).Execute("system")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result.SkippedCount != 0 {
+		t.Fatalf("re-import skipped items: %+v", result.Items)
+	}
+	return w
+}
+
+// tagGuide adds the tag "howto" and the given properties to the guide page.
+func tagGuide(t *testing.T, f *exportFixture, properties map[string]string) {
+	t.Helper()
+	page, err := f.ts.GetPage(f.guideID)
+	if err != nil {
+		t.Fatalf("GetPage failed: %v", err)
+	}
+	content := page.Content
+	if err := f.ts.UpdateNode("system", f.guideID, page.Title, page.Slug, &content, tree.VersionUnchecked, []string{"howto"}, properties, false); err != nil {
+		t.Fatalf("UpdateNode failed: %v", err)
+	}
+}
+
+func TestExportMarkdown_WritesFilesFrontmatterAndRelativeLinks(t *testing.T) {
+	f := newExportFixture(t)
+	tagGuide(t, f, map[string]string{"owner": "ops"})
+	src, err := CollectSubtree(f.ts, "docs")
+	if err != nil {
+		t.Fatalf("CollectSubtree failed: %v", err)
+	}
+
+	outDir := t.TempDir()
+	result, err := ExportMarkdown(src, NewDirWriter(outDir), MarkdownOptions{AssetsDir: f.assetsDir, IncludeIDs: true})
+	if err != nil {
+		t.Fatalf("ExportMarkdown failed: %v", err)
+	}
+	if result.Pages != 3 || result.Assets != 1 || result.Excluded != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	if got := readExportFile(t, outDir, "docs/index.md"); !strings.Contains(got, "leafwiki_title: Docs") {
+		t.Fatalf("docs/index.md is missing its title:\n%s", got)
+	}
+	intro := readExportFile(t, outDir, "docs/intro.md")
+	for _, want := range []string{
+		"leafwiki_id: " + f.introID,
+		"[guide](guide.md#setup)",
+		"[the guide](guide.md)",
+		"![diagram](intro.assets/diagram.png)",
+		"[other](/other)",
+		"[secret](/docs/secret)",
+	} {
+		if !strings.Contains(intro, want) {
+			t.Fatalf("docs/intro.md missing %q:\n%s", want, intro)
+		}
+	}
+	guide := readExportFile(t, outDir, "docs/guide.md")
+	for _, want := range []string{"owner: ops", "- howto", "[intro](intro.md)"} {
+		if !strings.Contains(guide, want) {
+			t.Fatalf("docs/guide.md missing %q:\n%s", want, guide)
+		}
+	}
+	if got := readExportFile(t, outDir, "docs/intro.assets/diagram.png"); got != "png" {
+		t.Fatalf("asset content = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "docs", "secret.md")); !os.IsNotExist(err) {
+		t.Fatalf("private page must not be exported, stat err=%v", err)
+	}
+}
+
+func TestExportMarkdown_WikiLinks(t *testing.T) {
+	f := newExportFixture(t)
+	src, err := CollectSubtree(f.ts, "")
+	if err != nil {
+		t.Fatalf("CollectSubtree failed: %v", err)
+	}
+
+	outDir := t.TempDir()
+	if _, err := ExportMarkdown(src, NewDirWriter(outDir), MarkdownOptions{AssetsDir: f.assetsDir, WikiLinks: true}); err != nil {
+		t.Fatalf("ExportMarkdown failed: %v", err)
+	}
+
+	intro := readExportFile(t, outDir, "docs/intro.md")
+	for _, want := range []string{
+		"[[docs/guide#setup|guide]]",
+		"[[docs/guide|the guide]]",
+		"![[docs/intro.assets/diagram.png]]",
+		"[[other|other]]",
+		"[web](https://example.com)",
+	} {
+		if !strings.Contains(intro, want) {
+			t.Fatalf("docs/intro.md missing %q:\n%s", want, intro)
+		}
+	}
+	if strings.Contains(intro, "leafwiki_id") {
+		t.Fatalf("IDs must only be written on request:\n%s", intro)
+	}
+}
+
+func TestExportMarkdown_RoundTripsThroughImporter(t *testing.T) {
+	for _, wikiLinks := range []bool{false, true} {
+		f := newExportFixture(t)
+		tagGuide(t, f, nil)
+		src, err := CollectSubtree(f.ts, "docs")
+		if err != nil {
+			t.Fatalf("CollectSubtree failed: %v", err)
+		}
+		outDir := t.TempDir()
+		if _, err := ExportMarkdown(src, NewDirWriter(outDir), MarkdownOptions{AssetsDir: f.assetsDir, WikiLinks: wikiLinks}); err != nil {
+			t.Fatalf("ExportMarkdown failed: %v", err)
+		}
+
+		w := importExportedDir(t, outDir)
+		imported, err := CollectSubtree(w.ts, "docs")
+		if err != nil {
+			t.Fatalf("CollectSubtree of re-import failed: %v", err)
+		}
+
+		var got, want []string
+		describe := func(n *Node) string { return n.Path + ":" + n.Title + ":" + string(n.Kind) }
+		src.Root.Walk(func(n *Node) { want = append(want, describe(n)) })
+		imported.Root.Walk(func(n *Node) { got = append(got, describe(n)) })
+		// Sibling order is not part of the archive; compare the set of nodes.
+		if strings.Join(sortedCopy(got), "\n") != strings.Join(sortedCopy(want), "\n") {
+			t.Fatalf("wikiLinks=%v: structure differs\ngot:  %v\nwant: %v", wikiLinks, got, want)
+		}
+
+		intro := imported.NodeByPath("docs/intro")
+		if !strings.Contains(intro.Content, "(/docs/guide#setup)") {
+			t.Fatalf("wikiLinks=%v: link to guide was not restored:\n%s", wikiLinks, intro.Content)
+		}
+		if !strings.Contains(intro.Content, "/assets/"+intro.ID+"/diagram.png") {
+			t.Fatalf("wikiLinks=%v: asset link was not restored:\n%s", wikiLinks, intro.Content)
+		}
+		if data, err := os.ReadFile(filepath.Join(w.assetsDir, intro.ID, "diagram.png")); err != nil || string(data) != "png" {
+			t.Fatalf("wikiLinks=%v: asset was not re-imported: %v", wikiLinks, err)
+		}
+		if tags := imported.NodeByPath("docs/guide").Frontmatter.ExtraFields["tags"]; tags == nil {
+			t.Fatalf("wikiLinks=%v: tags were not restored", wikiLinks)
+		}
+	}
+}
+
+func sortedCopy(values []string) []string {
+	out := append([]string(nil), values...)
+	sort.Strings(out)
+	return out
+}
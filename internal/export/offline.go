@@ -0,0 +1,58 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/perber/wiki/internal/branding"
+	"github.com/perber/wiki/internal/core/ignore"
+	"github.com/perber/wiki/internal/core/tree"
+)
+
+// loadOfflineTree loads the page tree of dataDir the same way NewWiki does,
+// including .leafwikiignore handling, without starting any other service.
+func loadOfflineTree(dataDir string) (*tree.TreeService, error) {
+	if _, err := os.Stat(filepath.Join(dataDir, "root")); err != nil {
+		return nil, fmt.Errorf("data directory %s has no wiki content: %w", dataDir, err)
+	}
+	ts := tree.NewTreeService(dataDir)
+	ts.SetIgnoreCache(ignore.NewCache(filepath.Join(dataDir, "root")))
+	if err := ts.LoadTree(); err != nil {
+		return nil, fmt.Errorf("load tree: %w", err)
+	}
+	return ts, nil
+}
+
+// ExportSiteOffline renders the subtree at routePath of the wiki stored in
+// dataDir as a static site into outDir. Used by `leafwiki export-site`; it
+// reads the data directory directly, so the server does not need to run.
+func ExportSiteOffline(dataDir, routePath, outDir string) (*SiteResult, error) {
+	ts, err := loadOfflineTree(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	src, err := CollectSubtree(ts, routePath)
+	if err != nil {
+		return nil, err
+	}
+
+	brandingService, err := branding.NewBrandingService(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := SiteOptionsFromBranding(brandingService, filepath.Join(dataDir, "assets"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Refuse to write into a populated directory: stale files from an
+	// earlier export would silently survive next to the new ones.
+	if entries, err := os.ReadDir(outDir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("output directory %s is not empty", outDir)
+	}
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return nil, fmt.Errorf("create output directory: %w", err)
+	}
+	return ExportSite(src, NewDirWriter(outDir), opts)
+}
@@ -0,0 +1,327 @@
+package export
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/perber/wiki/internal/core/shared/htmlutil"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+var (
+	calloutOpenPattern  = regexp.MustCompile(`^( {0,3}):::\s*([A-Za-z][\w-]*)(?:\s+(\S.*))?\s*$`)
+	calloutClosePattern = regexp.MustCompile(`^ {0,3}:::\s*$`)
+	fenceLinePattern    = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	wikiLinkPattern     = regexp.MustCompile(`(!?)\[\[([^\]|\n]+)(?:\|([^\]\n]+))?\]\]`)
+
+	// calloutTypes mirrors the type normalization of the editor preview
+	// (normalizeMarkdownBlocks.ts) so exported callouts look the same.
+	calloutTypes = map[string]string{
+		"caution": "warning",
+		"danger":  "error",
+		"error":   "error",
+		"fail":    "error",
+		"failed":  "error",
+		"failure": "error",
+		"info":    "info",
+		"note":    "info",
+		"success": "success",
+		"tip":     "info",
+		"warn":    "warning",
+		"warning": "warning",
+	}
+)
+
+// LinkRewriter maps a link or image destination of the rendered page to its
+// exported form. ok=false unwraps the link (keeping its text) or drops the
+// image (keeping its alt text).
+type LinkRewriter func(dest string, image bool) (href string, ok bool)
+
+// WikiLinkRewriter maps the target of a [[Target]] wikilink to a regular
+// wiki destination (route path or asset path). The result is turned into a
+// markdown link and then goes through LinkRewriter like any other link.
+type WikiLinkRewriter func(target string) (href string, ok bool)
+
+// HeadingHook can adjust every heading before rendering, e.g. to prefix
+// IDs or to number them. level is the markdown heading level (1–6).
+type HeadingHook func(level int, id string, text string) (newID string, prefix string)
+
+// RenderOptions controls how a page body is converted to HTML.
+type RenderOptions struct {
+	RewriteLink     LinkRewriter
+	RewriteWikiLink WikiLinkRewriter
+	Heading         HeadingHook
+	// HeadingBase shifts all headings so that the topmost heading of the
+	// body gets this level (capped at 6). 0 keeps the levels as written.
+	HeadingBase int
+}
+
+// Renderer converts wiki markdown to sanitized, self-contained HTML.
+type Renderer struct {
+	md     goldmark.Markdown
+	policy *bluemonday.Policy
+}
+
+// NewRenderer returns a renderer supporting the markdown dialect of the
+// editor: GFM, footnotes, callouts (:::type) and wikilinks.
+func NewRenderer() *Renderer {
+	md := goldmark.New(
+		goldmark.WithExtensions(extension.GFM, extension.Footnote),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		goldmark.WithRendererOptions(gmhtml.WithUnsafe(), gmhtml.WithXHTML()),
+	)
+
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("class").OnElements("div", "p", "span", "code", "pre", "aside", "section", "sup", "li", "a", "ol", "hr")
+	policy.AllowAttrs("id").OnElements("h1", "h2", "h3", "h4", "h5", "h6", "li", "div", "section", "sup", "a")
+	policy.AllowAttrs("type", "checked", "disabled").OnElements("input")
+	policy.AllowAttrs("role").OnElements("a", "div", "aside")
+	policy.AllowElements("details", "summary", "aside")
+	policy.AllowAttrs("open").OnElements("details")
+	policy.AllowRelativeURLs(true)
+	policy.RequireNoFollowOnLinks(false)
+
+	return &Renderer{md: md, policy: policy}
+}
+
+// Render converts a markdown body (without frontmatter) to HTML.
+func (r *Renderer) Render(body string, opts RenderOptions) (string, error) {
+	source := []byte(preprocessMarkdown(body, opts.RewriteWikiLink))
+
+	doc := r.md.Parser().Parse(text.NewReader(source))
+	if err := rewriteDocument(doc, source, opts); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := r.md.Renderer().Render(&buf, source, doc); err != nil {
+		return "", err
+	}
+	return r.policy.Sanitize(buf.String()), nil
+}
+
+func rewriteDocument(doc ast.Node, source []byte, opts RenderOptions) error {
+	type unwrap struct {
+		node  ast.Node
+		image bool
+	}
+	var pending []unwrap
+
+	shift := 0
+	if opts.HeadingBase > 0 {
+		if top := topHeadingLevel(doc); top > 0 {
+			shift = opts.HeadingBase - top
+		}
+	}
+
+	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.Link:
+			if opts.RewriteLink == nil {
+				return ast.WalkContinue, nil
+			}
+			href, ok := opts.RewriteLink(string(node.Destination), false)
+			if !ok {
+				pending = append(pending, unwrap{node: node})
+				return ast.WalkContinue, nil
+			}
+			node.Destination = []byte(href)
+		case *ast.Image:
+			if opts.RewriteLink == nil {
+				return ast.WalkContinue, nil
+			}
+			href, ok := opts.RewriteLink(string(node.Destination), true)
+			if !ok {
+				pending = append(pending, unwrap{node: node, image: true})
+				return ast.WalkSkipChildren, nil
+			}
+			node.Destination = []byte(href)
+		case *ast.Heading:
+			node.Level = min(max(node.Level+shift, 1), 6)
+			if opts.Heading == nil {
+				return ast.WalkContinue, nil
+			}
+			id := ""
+			if value, ok := node.AttributeString("id"); ok {
+				if b, ok := value.([]byte); ok {
+					id = string(b)
+				}
+			}
+			newID, prefix := opts.Heading(node.Level, id, headingText(node, source))
+			if newID != "" {
+				node.SetAttributeString("id", []byte(newID))
+			}
+			if prefix != "" {
+				segment := ast.NewString([]byte(prefix + " "))
+				if first := node.FirstChild(); first != nil {
+					node.InsertBefore(node, first, segment)
+				} else {
+					node.AppendChild(node, segment)
+				}
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pending {
+		parent := p.node.Parent()
+		if parent == nil {
+			continue
+		}
+		if p.image {
+			alt := headingText(p.node, source)
+			parent.ReplaceChild(parent, p.node, ast.NewString([]byte(alt)))
+			continue
+		}
+		for child := p.node.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			parent.InsertBefore(parent, p.node, child)
+			child = next
+		}
+		parent.RemoveChild(parent, p.node)
+	}
+	return nil
+}
+
+// topHeadingLevel returns the smallest heading level in doc, or 0 if it has
+// no headings.
+func topHeadingLevel(doc ast.Node) int {
+	top := 0
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if heading, ok := n.(*ast.Heading); ok && entering && (top == 0 || heading.Level < top) {
+			top = heading.Level
+		}
+		return ast.WalkContinue, nil
+	})
+	return top
+}
+
+// headingText returns the plain text of an inline container.
+func headingText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	_ = ast.Walk(n, func(child ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := child.(type) {
+		case *ast.Text:
+			b.Write(t.Segment.Value(source))
+			if t.SoftLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(t.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.TrimSpace(b.String())
+}
+
+// preprocessMarkdown rewrites the non-CommonMark parts of the wiki dialect
+// outside fenced code: callout blocks become HTML containers and wikilinks
+// become regular markdown links (or plain text when unresolvable).
+func preprocessMarkdown(body string, rewriteWiki WikiLinkRewriter) string {
+	lines := strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+	out := make([]string, 0, len(lines))
+	var fence string
+	var open []string
+
+	for _, line := range lines {
+		if fence != "" {
+			out = append(out, line)
+			if m := fenceLinePattern.FindStringSubmatch(line); m != nil && m[1][0] == fence[0] && len(m[1]) >= len(fence) && strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), string(fence[0]))) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if m := fenceLinePattern.FindStringSubmatch(line); m != nil {
+			fence = m[1]
+			out = append(out, line)
+			continue
+		}
+
+		if m := calloutOpenPattern.FindStringSubmatch(line); m != nil {
+			rawType := strings.ReplaceAll(strings.ToLower(m[2]), "_", "-")
+			title := strings.TrimSpace(m[3])
+			switch rawType {
+			case "collapsible", "collapsed":
+				attr := ""
+				if rawType == "collapsible" {
+					attr = " open"
+				}
+				if title == "" {
+					title = "Details"
+				}
+				out = append(out, "", "<details class=\"callout callout-collapsible\""+attr+"><summary>"+htmlutil.EscapeText(title)+"</summary>", "")
+				open = append(open, "</details>")
+			default:
+				kind := rawType
+				if mapped, ok := calloutTypes[rawType]; ok {
+					kind = mapped
+				}
+				out = append(out, "", "<div class=\"callout callout-"+htmlutil.EscapeText(kind)+"\" role=\"note\">")
+				if title != "" {
+					out = append(out, "<p class=\"callout-title\">"+htmlutil.EscapeText(title)+"</p>")
+				}
+				out = append(out, "")
+				open = append(open, "</div>")
+			}
+			continue
+		}
+		if calloutClosePattern.MatchString(line) && len(open) > 0 {
+			out = append(out, "", open[len(open)-1], "")
+			open = open[:len(open)-1]
+			continue
+		}
+
+		out = append(out, rewriteWikiLinks(line, rewriteWiki))
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		out = append(out, "", open[i])
+	}
+	return strings.Join(out, "\n")
+}
+
+func rewriteWikiLinks(line string, rewrite WikiLinkRewriter) string {
+	if !strings.Contains(line, "[[") {
+		return line
+	}
+	return wikiLinkPattern.ReplaceAllStringFunc(line, func(match string) string {
+		m := wikiLinkPattern.FindStringSubmatch(match)
+		embed, target, alias := m[1] == "!", strings.TrimSpace(m[2]), strings.TrimSpace(m[3])
+		label := alias
+		if label == "" {
+			label = target
+		}
+		if rewrite == nil {
+			return escapeMarkdownText(label)
+		}
+		href, ok := rewrite(target)
+		if !ok {
+			return escapeMarkdownText(label)
+		}
+		if embed {
+			return "![" + escapeMarkdownText(label) + "](<" + href + ">)"
+		}
+		return "[" + escapeMarkdownText(label) + "](<" + href + ">)"
+	})
+}
+
+var markdownTextEscaper = strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`, `*`, `\*`, `_`, `\_`, "`", "\\`", `<`, `&lt;`)
+
+func escapeMarkdownText(s string) string {
+	return markdownTextEscaper.Replace(s)
+}
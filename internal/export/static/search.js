@@ -0,0 +1,113 @@
+// Client-side search for LeafWiki static exports. The index is loaded from
+// search-index.js as a global so that the site also works from file://.
+(function () {
+  'use strict'
+
+  var index = window.LEAFWIKI_SEARCH_INDEX || []
+  var root = document.body.getAttribute('data-root') || ''
+  var input = document.getElementById('site-search-input')
+  var results = document.getElementById('site-search-results')
+  if (!input || !results) {
+    return
+  }
+
+  var maxResults = 20
+
+  function terms(query) {
+    return query
+      .toLowerCase()
+      .split(/\s+/)
+      .filter(function (term) {
+        return term.length > 0
+      })
+  }
+
+  function score(entry, words) {
+    var title = entry.t.toLowerCase()
+    var text = entry.x.toLowerCase()
+    var total = 0
+    for (var i = 0; i < words.length; i++) {
+      var inTitle = title.indexOf(words[i]) !== -1
+      var inText = text.indexOf(words[i]) !== -1
+      if (!inTitle && !inText) {
+        return 0
+      }
+      total += inTitle ? 10 : 1
+    }
+    return total
+  }
+
+  function snippet(entry, words) {
+    var text = entry.x
+    var lower = text.toLowerCase()
+    var pos = -1
+    for (var i = 0; i < words.length && pos === -1; i++) {
+      pos = lower.indexOf(words[i])
+    }
+    if (pos === -1) {
+      return text.slice(0, 160)
+    }
+    var start = Math.max(0, pos - 60)
+    return (start > 0 ? '…' : '') + text.slice(start, start + 160) + '…'
+  }
+
+  function clear() {
+    while (results.firstChild) {
+      results.removeChild(results.firstChild)
+    }
+  }
+
+  function search() {
+    var words = terms(input.value)
+    clear()
+    if (words.length === 0) {
+      results.hidden = true
+      return
+    }
+
+    var hits = []
+    for (var i = 0; i < index.length; i++) {
+      var s = score(index[i], words)
+      if (s > 0) {
+        hits.push({ entry: index[i], score: s })
+      }
+    }
+    hits.sort(function (a, b) {
+      return b.score - a.score || a.entry.t.localeCompare(b.entry.t)
+    })
+
+    if (hits.length === 0) {
+      var empty = document.createElement('li')
+      empty.textContent = 'No results'
+      results.appendChild(empty)
+    }
+    for (var j = 0; j < hits.length && j < maxResults; j++) {
+      var entry = hits[j].entry
+      var item = document.createElement('li')
+      var link = document.createElement('a')
+      link.href = root + entry.p
+      link.textContent = entry.t
+      item.appendChild(link)
+      if (entry.c) {
+        var crumb = document.createElement('span')
+        crumb.className = 'crumb'
+        crumb.textContent = entry.c
+        item.appendChild(crumb)
+      }
+      var text = document.createElement('span')
+      text.className = 'snippet'
+      text.textContent = snippet(entry, words)
+      item.appendChild(text)
+      results.appendChild(item)
+    }
+    results.hidden = false
+  }
+
+  input.addEventListener('input', search)
+  input.addEventListener('keydown', function (event) {
+    if (event.key === 'Escape') {
+      input.value = ''
+      search()
+    }
+  })
+})()
@@ -0,0 +1,351 @@
+package export
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/perber/wiki/internal/core/excerpt"
+	"github.com/perber/wiki/internal/core/shared/htmlutil"
+	"github.com/perber/wiki/internal/core/tree"
+)
+
+//go:embed static/site.css static/search.js
+var siteStatic embed.FS
+
+const (
+	siteStaticDir   = "_static"
+	siteAssetsDir   = "assets"
+	siteSearchIndex = siteStaticDir + "/search-index.js"
+
+	// maxSearchTextRunes caps the plain text stored per page in the
+	// client-side search index to keep the index file small.
+	maxSearchTextRunes = 20000
+)
+
+var sitePageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="generator" content="LeafWiki">
+<title>{{if .IsHome}}{{.SiteName}}{{else}}{{.Title}} · {{.SiteName}}{{end}}</title>
+<link rel="stylesheet" href="{{.Root}}_static/site.css">
+</head>
+<body data-root="{{.Root}}">
+<header class="site-header">
+<a class="site-brand" href="{{.HomeHref}}">{{if .LogoHref}}<img class="site-logo" src="{{.LogoHref}}" alt="">{{end}}<span>{{.SiteName}}</span></a>
+<div class="site-search">
+<input type="search" id="site-search-input" placeholder="Search…" aria-label="Search" autocomplete="off">
+<ol id="site-search-results" hidden></ol>
+</div>
+</header>
+<div class="site-layout">
+<nav class="site-nav" aria-label="Pages">{{.Nav}}</nav>
+<main class="site-main">
+{{- if .Breadcrumbs}}
+<nav class="breadcrumbs" aria-label="Breadcrumbs">{{range .Breadcrumbs}}<a href="{{.Href}}">{{.Title}}</a> / {{end}}</nav>
+{{- end}}
+<article class="page">
+<h1 class="page-title">{{.Title}}</h1>
+{{.Content}}
+</article>
+{{- if .Children}}
+<section class="child-pages">
+<h2>Pages in this section</h2>
+<ul>{{range .Children}}<li><a href="{{.Href}}">{{.Title}}</a></li>{{end}}</ul>
+</section>
+{{- end}}
+</main>
+</div>
+<script src="{{.Root}}_static/search-index.js"></script>
+<script src="{{.Root}}_static/search.js"></script>
+</body>
+</html>
+`))
+
+// SiteOptions configures a static site export.
+type SiteOptions struct {
+	// SiteName is shown in the header and page titles (branding site name).
+	SiteName string
+	// LogoPath is the on-disk path of the branding logo; empty for none.
+	LogoPath string
+	// AssetsDir is the asset storage directory (<data>/assets).
+	AssetsDir string
+}
+
+// SiteResult summarizes a static site export.
+type SiteResult struct {
+	Pages    int
+	Assets   int
+	Excluded int
+}
+
+type siteLink struct {
+	Title string
+	Href  string
+}
+
+type sitePage struct {
+	SiteName    string
+	Title       string
+	IsHome      bool
+	Root        string
+	HomeHref    string
+	LogoHref    string
+	Nav         template.HTML
+	Breadcrumbs []siteLink
+	Content     template.HTML
+	Children    []siteLink
+}
+
+type searchEntry struct {
+	Title string `json:"t"`
+	Path  string `json:"p"`
+	Crumb string `json:"c,omitempty"`
+	Text  string `json:"x"`
+}
+
+// ExportSite renders the collected subtree as a self-contained static
+// website: one HTML page per node with navigation, relative links, copied
+// attachments and a client-side search index. The output only depends on
+// the collected content, so exporting the same tree twice is byte-identical.
+func ExportSite(src *Subtree, w Writer, opts SiteOptions) (*SiteResult, error) {
+	siteName := strings.TrimSpace(opts.SiteName)
+	if siteName == "" {
+		siteName = "LeafWiki"
+	}
+	result := &SiteResult{Excluded: src.Excluded}
+	renderer := NewRenderer()
+
+	for _, name := range []string{"site.css", "search.js"} {
+		data, err := siteStatic.ReadFile("static/" + name)
+		if err != nil {
+			return nil, err
+		}
+		if err := w.WriteFile(siteStaticDir+"/"+name, data); err != nil {
+			return nil, err
+		}
+	}
+
+	logoFile := ""
+	if opts.LogoPath != "" {
+		logoFile = siteStaticDir + "/" + filepath.Base(opts.LogoPath)
+		if err := w.CopyFile(logoFile, opts.LogoPath); err != nil {
+			return nil, fmt.Errorf("copy logo: %w", err)
+		}
+	}
+
+	nodes := src.Nodes()
+	var index []searchEntry
+
+	for _, node := range nodes {
+		if node.IsWikiRoot() {
+			continue
+		}
+		files, err := pageAssetFiles(opts.AssetsDir, node.ID)
+		if err != nil {
+			return nil, fmt.Errorf("list assets of %s: %w", node.ID, err)
+		}
+		for _, name := range files {
+			if err := w.CopyFile(siteAssetPath(node.ID, name), assetDiskPath(opts.AssetsDir, node.ID, name)); err != nil {
+				return nil, err
+			}
+			result.Assets++
+		}
+	}
+
+	for _, node := range nodes {
+		out := sitePagePath(node)
+		root := siteRootPrefix(out)
+
+		content, err := renderer.Render(node.Content, RenderOptions{
+			RewriteLink: func(dest string, image bool) (string, bool) {
+				return siteLinkHref(src, node, out, dest)
+			},
+			RewriteWikiLink: func(target string) (string, bool) {
+				return wikiLinkDestination(src, node, target)
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("render %s: %w", node.ID, err)
+		}
+
+		title := node.Title
+		if node.IsWikiRoot() {
+			title = siteName
+		}
+
+		page := sitePage{
+			SiteName: siteName,
+			Title:    title,
+			IsHome:   node == src.Root,
+			Root:     root,
+			HomeHref: relativeHref(out, sitePagePath(src.Root)),
+			Nav:      template.HTML(renderSiteNav(src.Root, node, out)),
+			Content:  template.HTML(content),
+		}
+		if logoFile != "" {
+			page.LogoHref = relativeHref(out, logoFile)
+		}
+		for parent := node.Parent; parent != nil; parent = parent.Parent {
+			page.Breadcrumbs = append([]siteLink{{Title: siteNodeTitle(parent, siteName), Href: relativeHref(out, sitePagePath(parent))}}, page.Breadcrumbs...)
+		}
+		for _, child := range node.Children {
+			page.Children = append(page.Children, siteLink{Title: child.Title, Href: relativeHref(out, sitePagePath(child))})
+		}
+
+		var buf bytes.Buffer
+		if err := sitePageTemplate.Execute(&buf, page); err != nil {
+			return nil, fmt.Errorf("render %s: %w", node.ID, err)
+		}
+		if err := w.WriteFile(out, buf.Bytes()); err != nil {
+			return nil, err
+		}
+		result.Pages++
+
+		if !node.IsWikiRoot() {
+			index = append(index, searchEntry{
+				Title: node.Title,
+				Path:  out,
+				Crumb: siteCrumbText(node, siteName),
+				Text:  truncateRunes(excerpt.PlainTextForSearch(node.Content), maxSearchTextRunes),
+			})
+		}
+	}
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return nil, err
+	}
+	script := append([]byte("window.LEAFWIKI_SEARCH_INDEX = "), data...)
+	script = append(script, []byte(";\n")...)
+	if err := w.WriteFile(siteSearchIndex, script); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// sitePagePath returns the output file of a node: the export root becomes
+// index.html, nodes with children (and sections) become <path>/index.html
+// and leaf pages <path>.html.
+func sitePagePath(n *Node) string {
+	if n.RelPath == "" {
+		return "index.html"
+	}
+	if len(n.Children) > 0 || n.Kind == tree.NodeKindSection {
+		return n.RelPath + "/index.html"
+	}
+	return n.RelPath + ".html"
+}
+
+func siteAssetPath(pageID, name string) string {
+	return path.Join(siteAssetsDir, pageID, name)
+}
+
+// siteRootPrefix returns the relative prefix from an output file back to
+// the export root ("" or "../../").
+func siteRootPrefix(out string) string {
+	return strings.Repeat("../", strings.Count(out, "/"))
+}
+
+func siteNodeTitle(n *Node, siteName string) string {
+	if n.IsWikiRoot() {
+		return siteName
+	}
+	return n.Title
+}
+
+func siteCrumbText(n *Node, siteName string) string {
+	var parts []string
+	for parent := n.Parent; parent != nil; parent = parent.Parent {
+		parts = append([]string{siteNodeTitle(parent, siteName)}, parts...)
+	}
+	return strings.Join(parts, " / ")
+}
+
+func siteLinkHref(src *Subtree, from *Node, out, dest string) (string, bool) {
+	target := src.ResolveLink(from, dest)
+	switch target.Kind {
+	case LinkExternal, LinkAnchor:
+		return dest, true
+	case LinkAsset:
+		return relativeHref(out, siteAssetPath(target.AssetPageID, target.AssetName)), true
+	case LinkPage:
+		href := relativeHref(out, sitePagePath(target.Node))
+		if target.Fragment != "" {
+			href += "#" + target.Fragment
+		}
+		return href, true
+	default:
+		return "", false
+	}
+}
+
+// wikiLinkDestination resolves a wikilink to the wiki route path of its
+// target, which is then rewritten like a regular link.
+func wikiLinkDestination(src *Subtree, from *Node, target string) (string, bool) {
+	resolved := src.ResolveWikiLink(from, target)
+	switch resolved.Kind {
+	case LinkPage:
+		dest := "/" + resolved.Node.Path
+		if resolved.Fragment != "" {
+			dest += "#" + resolved.Fragment
+		}
+		return dest, true
+	case LinkAnchor:
+		return "#" + resolved.Fragment, true
+	default:
+		return "", false
+	}
+}
+
+func renderSiteNav(root *Node, current *Node, out string) string {
+	var b strings.Builder
+	b.WriteString("<ul>")
+	writeSiteNavItem(&b, root, current, out, true)
+	b.WriteString("</ul>")
+	return b.String()
+}
+
+func writeSiteNavItem(b *strings.Builder, n *Node, current *Node, out string, isRoot bool) {
+	if isRoot && n.IsWikiRoot() {
+		for _, child := range n.Children {
+			writeSiteNavItem(b, child, current, out, false)
+		}
+		return
+	}
+
+	class := ""
+	if n == current {
+		class = ` class="current"`
+	}
+	b.WriteString("<li" + class + ">")
+	b.WriteString(`<a href="` + htmlutil.EscapeText(relativeHref(out, sitePagePath(n))) + `"`)
+	if n == current {
+		b.WriteString(` aria-current="page"`)
+	}
+	b.WriteString(">" + htmlutil.EscapeText(n.Title) + "</a>")
+	if len(n.Children) > 0 {
+		b.WriteString("<ul>")
+		for _, child := range n.Children {
+			writeSiteNavItem(b, child, current, out, false)
+		}
+		b.WriteString("</ul>")
+	}
+	b.WriteString("</li>")
+}
+
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
@@ -0,0 +1,256 @@
+package export
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/perber/wiki/internal/core/tree"
+)
+
+type exportFixture struct {
+	ts        *tree.TreeService
+	assetsDir string
+	docsID    string
+	introID   string
+	guideID   string
+	secretID  string
+}
+
+// newExportFixture builds:
+// root
+//
+//	├─ docs (section)
+//	│    ├─ intro
+//	│    ├─ guide
+//	│    └─ secret (private)
+//	└─ other
+func newExportFixture(t *testing.T) *exportFixture {
+	t.Helper()
+
+	storageDir := t.TempDir()
+	ts := tree.NewTreeService(storageDir)
+	if err := ts.LoadTree(); err != nil {
+		t.Fatalf("LoadTree failed: %v", err)
+	}
+
+	section := tree.NodeKindSection
+	page := tree.NodeKindPage
+	create := func(parentID *string, title, slug string, kind *tree.NodeKind) string {
+		t.Helper()
+		id, err := ts.CreateNode("system", parentID, title, slug, kind)
+		if err != nil {
+			t.Fatalf("CreateNode %s failed: %v", slug, err)
+		}
+		return *id
+	}
+	update := func(id, content string, preserveFrontmatter bool) {
+		t.Helper()
+		node, err := ts.FindPageByID(id)
+		if err != nil {
+			t.Fatalf("FindPageByID %s failed: %v", id, err)
+		}
+		if err := ts.UpdateNode("system", id, node.Title, node.Slug, &content, tree.VersionUnchecked, nil, nil, preserveFrontmatter); err != nil {
+			t.Fatalf("UpdateNode %s failed: %v", id, err)
+		}
+	}
+
+	f := &exportFixture{ts: ts, assetsDir: filepath.Join(storageDir, "assets")}
+	f.docsID = create(nil, "Docs", "docs", &section)
+	f.introID = create(&f.docsID, "Intro", "intro", &page)
+	f.guideID = create(&f.docsID, "Guide", "guide", &page)
+	f.secretID = create(&f.docsID, "Secret", "secret", &page)
+	otherID := create(nil, "Other", "other", &page)
+
+	update(f.introID, strings.Join([]string{
+		"Read the [guide](/docs/guide#setup) or [[Guide|the guide]].",
+		"",
+		"![diagram](/assets/" + f.introID + "/diagram.png)",
+		"",
+		"See [other](/other) and [secret](/docs/secret) and [web](https://example.com).",
+		"",
+		":::warning Careful",
+		"Mind the **gap**.",
+		":::",
+	}, "\n"), false)
+	update(f.guideID, "## Setup\n\nInstall it.\n\nBack to [intro](../intro).", false)
+	update(f.secretID, "---\nprivate: true\n---\nTop secret content", true)
+	update(otherID, "Outside the export", false)
+
+	if err := os.MkdirAll(filepath.Join(f.assetsDir, f.introID), 0o755); err != nil {
+		t.Fatalf("mkdir assets failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(f.assetsDir, f.introID, "diagram.png"), []byte("png"), 0o644); err != nil {
+		t.Fatalf("write asset failed: %v", err)
+	}
+	return f
+}
+
+func readExportFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatalf("read %s failed: %v", name, err)
+	}
+	return string(data)
+}
+
+func TestCollectSubtree_ExcludesPrivatePages(t *testing.T) {
+	f := newExportFixture(t)
+
+	src, err := CollectSubtree(f.ts, "/docs/")
+	if err != nil {
+		t.Fatalf("CollectSubtree failed: %v", err)
+	}
+	if src.Root.ID != f.docsID || src.Root.RelPath != "" || src.Root.Path != "docs" {
+		t.Fatalf("unexpected root: %+v", src.Root)
+	}
+	var titles []string
+	for _, child := range src.Root.Children {
+		titles = append(titles, child.Title)
+	}
+	if got := strings.Join(titles, ","); got != "Intro,Guide" {
+		t.Fatalf("children = %s, want Intro,Guide", got)
+	}
+	if src.Excluded != 1 {
+		t.Fatalf("Excluded = %d, want 1", src.Excluded)
+	}
+	if src.NodeByID(f.secretID) != nil {
+		t.Fatalf("private page must not be collected")
+	}
+}
+
+func TestCollectSubtree_UnknownPath(t *testing.T) {
+	f := newExportFixture(t)
+
+	if _, err := CollectSubtree(f.ts, "docs/missing"); err == nil || !strings.Contains(err.Error(), ErrExportSourceNotFound.Error()) {
+		t.Fatalf("expected ErrExportSourceNotFound, got %v", err)
+	}
+	if _, err := CollectSubtree(f.ts, "docs/secret"); err == nil {
+		t.Fatalf("expected private export root to be rejected")
+	}
+}
+
+func TestExportSite_WritesPagesLinksAndAssets(t *testing.T) {
+	f := newExportFixture(t)
+	src, err := CollectSubtree(f.ts, "docs")
+	if err != nil {
+		t.Fatalf("CollectSubtree failed: %v", err)
+	}
+
+	outDir := t.TempDir()
+	logo := filepath.Join(t.TempDir(), "logo.svg")
+	if err := os.WriteFile(logo, []byte("<svg/>"), 0o644); err != nil {
+		t.Fatalf("write logo failed: %v", err)
+	}
+
+	result, err := ExportSite(src, NewDirWriter(outDir), SiteOptions{SiteName: "Team Wiki", LogoPath: logo, AssetsDir: f.assetsDir})
+	if err != nil {
+		t.Fatalf("ExportSite failed: %v", err)
+	}
+	if result.Pages != 3 || result.Assets != 1 || result.Excluded != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	index := readExportFile(t, outDir, "index.html")
+	if !strings.Contains(index, `href="intro.html"`) || !strings.Contains(index, `href="guide.html"`) {
+		t.Fatalf("index is missing nav links:\n%s", index)
+	}
+	if !strings.Contains(index, "Team Wiki") || !strings.Contains(index, `src="_static/logo.svg"`) {
+		t.Fatalf("index is missing branding:\n%s", index)
+	}
+
+	intro := readExportFile(t, outDir, "intro.html")
+	for _, want := range []string{
+		`href="guide.html#setup"`,
+		`>the guide</a>`,
+		`src="assets/` + f.introID + `/diagram.png"`,
+		`href="https://example.com"`,
+		`class="callout callout-warning"`,
+		`<strong>gap</strong>`,
+	} {
+		if !strings.Contains(intro, want) {
+			t.Fatalf("intro.html missing %q:\n%s", want, intro)
+		}
+	}
+	// Links leaving the export (or pointing at private pages) become plain text.
+	if strings.Contains(intro, `/other`) || strings.Contains(intro, "secret.html") {
+		t.Fatalf("intro.html must not link outside the export:\n%s", intro)
+	}
+
+	guide := readExportFile(t, outDir, "guide.html")
+	if !strings.Contains(guide, `id="setup"`) || !strings.Contains(guide, `href="intro.html"`) {
+		t.Fatalf("guide.html missing anchor or relative link:\n%s", guide)
+	}
+
+	if _, err := os.Stat(filepath.Join(outDir, "secret.html")); !os.IsNotExist(err) {
+		t.Fatalf("private page must not be exported, stat err=%v", err)
+	}
+	if got := readExportFile(t, outDir, "assets/"+f.introID+"/diagram.png"); got != "png" {
+		t.Fatalf("asset content = %q", got)
+	}
+
+	searchIndex := readExportFile(t, outDir, "_static/search-index.js")
+	if !strings.Contains(searchIndex, `"t":"Intro"`) || strings.Contains(searchIndex, "Top secret") {
+		t.Fatalf("unexpected search index:\n%s", searchIndex)
+	}
+}
+
+func TestExportSite_WholeWikiUsesNestedPaths(t *testing.T) {
+	f := newExportFixture(t)
+	src, err := CollectSubtree(f.ts, "")
+	if err != nil {
+		t.Fatalf("CollectSubtree failed: %v", err)
+	}
+
+	outDir := t.TempDir()
+	if _, err := ExportSite(src, NewDirWriter(outDir), SiteOptions{AssetsDir: f.assetsDir}); err != nil {
+		t.Fatalf("ExportSite failed: %v", err)
+	}
+
+	intro := readExportFile(t, outDir, "docs/intro.html")
+	if !strings.Contains(intro, `href="../other.html"`) || !strings.Contains(intro, `href="../_static/site.css"`) {
+		t.Fatalf("docs/intro.html has wrong relative links:\n%s", intro)
+	}
+	if !strings.Contains(readExportFile(t, outDir, "docs/index.html"), `href="intro.html"`) {
+		t.Fatalf("docs/index.html should link to its children")
+	}
+}
+
+func TestExportSite_IsDeterministic(t *testing.T) {
+	f := newExportFixture(t)
+	src, err := CollectSubtree(f.ts, "")
+	if err != nil {
+		t.Fatalf("CollectSubtree failed: %v", err)
+	}
+
+	export := func() []byte {
+		var buf bytes.Buffer
+		zw := NewZipWriter(&buf)
+		if _, err := ExportSite(src, zw, SiteOptions{SiteName: "Wiki", AssetsDir: f.assetsDir}); err != nil {
+			t.Fatalf("ExportSite failed: %v", err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	if !bytes.Equal(export(), export()) {
+		t.Fatalf("expected byte-identical archives")
+	}
+}
+
+func TestPreprocessMarkdown_LeavesFencedCodeAlone(t *testing.T) {
+	in := "```\n:::info\n[[Page]]\n```\n:::info\nbody\n:::"
+	out := preprocessMarkdown(in, nil)
+
+	if !strings.Contains(out, "```\n:::info\n[[Page]]\n```") {
+		t.Fatalf("fenced code was rewritten:\n%s", out)
+	}
+	if !strings.Contains(out, `<div class="callout callout-info" role="note">`) || !strings.Contains(out, "</div>") {
+		t.Fatalf("callout was not converted:\n%s", out)
+	}
+}
@@ -0,0 +1,145 @@
+package export
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// zipModTime is the fixed modification time of every archive entry so that
+// exporting the same content twice yields byte-identical archives.
+var zipModTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Writer receives the files of an export. Names are slash separated and
+// relative to the export root.
+type Writer interface {
+	WriteFile(name string, data []byte) error
+	CopyFile(name string, srcPath string) error
+}
+
+// DirWriter writes export files below a directory on disk.
+type DirWriter struct {
+	root string
+}
+
+func NewDirWriter(root string) *DirWriter {
+	return &DirWriter{root: root}
+}
+
+func (w *DirWriter) target(name string) (string, error) {
+	clean := path.Clean("/" + name)
+	if clean == "/" || strings.Contains(name, "\\") {
+		return "", fmt.Errorf("invalid export file name %q", name)
+	}
+	return filepath.Join(w.root, filepath.FromSlash(strings.TrimPrefix(clean, "/"))), nil
+}
+
+func (w *DirWriter) WriteFile(name string, data []byte) error {
+	target, err := w.target(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("create directory for %s: %w", name, err)
+	}
+	if err := os.WriteFile(target, data, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+func (w *DirWriter) CopyFile(name string, srcPath string) error {
+	target, err := w.target(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("create directory for %s: %w", name, err)
+	}
+
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", srcPath, err)
+	}
+	defer src.Close()
+
+	dst, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", name, err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return fmt.Errorf("copy %s: %w", name, err)
+	}
+	return dst.Close()
+}
+
+// ZipWriter writes export files into a zip archive with fixed timestamps.
+type ZipWriter struct {
+	zw *zip.Writer
+}
+
+func NewZipWriter(w io.Writer) *ZipWriter {
+	return &ZipWriter{zw: zip.NewWriter(w)}
+}
+
+func (w *ZipWriter) create(name string, method uint16) (io.Writer, error) {
+	header := &zip.FileHeader{
+		Name:     strings.TrimPrefix(path.Clean("/"+name), "/"),
+		Method:   method,
+		Modified: zipModTime,
+	}
+	header.SetMode(0o644)
+	return w.zw.CreateHeader(header)
+}
+
+func (w *ZipWriter) WriteFile(name string, data []byte) error {
+	f, err := w.create(name, zip.Deflate)
+	if err != nil {
+		return fmt.Errorf("create zip entry %s: %w", name, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("write zip entry %s: %w", name, err)
+	}
+	return nil
+}
+
+// WriteStored writes an uncompressed entry (EPUB requires its mimetype
+// entry to be stored).
+func (w *ZipWriter) WriteStored(name string, data []byte) error {
+	f, err := w.create(name, zip.Store)
+	if err != nil {
+		return fmt.Errorf("create zip entry %s: %w", name, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("write zip entry %s: %w", name, err)
+	}
+	return nil
+}
+
+func (w *ZipWriter) CopyFile(name string, srcPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", srcPath, err)
+	}
+	defer src.Close()
+
+	f, err := w.create(name, zip.Deflate)
+	if err != nil {
+		return fmt.Errorf("create zip entry %s: %w", name, err)
+	}
+	if _, err := io.Copy(f, src); err != nil {
+		return fmt.Errorf("write zip entry %s: %w", name, err)
+	}
+	return nil
+}
+
+// Close finalizes the archive. It does not close the underlying writer.
+func (w *ZipWriter) Close() error {
+	return w.zw.Close()
+}
@@ -1,4 +1,5 @@
-// Package favorites stores each user's private set of favorited pages.
+// Package favorites stores each user's private set of favorited pages and
+// their saved searches, with the subscriptions and notifications of those.
 // Unlike tags/links/properties/search, this data is not derived from the
 // filesystem tree and must never be touched by resync (see ADR-0001).
 package favorites
@@ -57,7 +58,10 @@ func (s *FavoritesStore) ensureSchema() error {
 		);
 		CREATE INDEX IF NOT EXISTS favorites_user_id_idx ON favorites(user_id);
 	`)
-	return err
+	if err != nil {
+		return err
+	}
+	return s.ensureSavedSearchSchema()
 }
 
 // Add favorites pageID for userID. Idempotent — favoriting an already-favorited page is a no-op.
@@ -112,30 +116,64 @@ func (s *FavoritesStore) ListPageIDsForUser(userID string) ([]string, error) {
 	return pageIDs, rows.Err()
 }
 
-// DeleteAllForPage removes every user's favorite of pageID. Called on page delete.
+// DeleteAllForPage removes every user's favorite of pageID, and the saved
+// search matches and notifications about it. Called on page delete.
 func (s *FavoritesStore) DeleteAllForPage(pageID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, err := s.db.Exec(`DELETE FROM favorites WHERE page_id = ?`, pageID)
+	err := s.execInTx(
+		[]string{
+			`DELETE FROM favorites WHERE page_id = ?`,
+			`DELETE FROM saved_search_matches WHERE page_id = ?`,
+			`DELETE FROM saved_search_notifications WHERE page_id = ?`,
+		},
+		pageID,
+	)
 	if err != nil {
 		return fmt.Errorf("failed to delete favorites for page %s: %w", pageID, err)
 	}
 	return nil
 }
 
-// DeleteAllForUser removes every favorite belonging to userID. Called on user delete.
+// DeleteAllForUser removes every favorite, saved search, subscription and
+// notification belonging to userID. Called on user delete.
 func (s *FavoritesStore) DeleteAllForUser(userID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, err := s.db.Exec(`DELETE FROM favorites WHERE user_id = ?`, userID)
+	err := s.execInTx(
+		[]string{
+			`DELETE FROM favorites WHERE user_id = ?`,
+			`DELETE FROM saved_search_subscriptions WHERE user_id = ?`,
+			`DELETE FROM saved_search_notifications WHERE user_id = ?`,
+		},
+		userID,
+	)
+	if err == nil {
+		err = s.deleteSavedSearchesWhere(`user_id = ?`, userID)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to delete favorites for user %s: %w", userID, err)
 	}
 	return nil
 }
 
+// execInTx runs every statement with the same arguments in one transaction.
+func (s *FavoritesStore) execInTx(statements []string, args ...any) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt, args...); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
 func (s *FavoritesStore) Close() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -0,0 +1,534 @@
+package favorites
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/perber/wiki/internal/core/shared"
+)
+
+var ErrSavedSearchNotFound = errors.New("saved search not found")
+
+// SavedSearch is a named search of a user. A shared search is visible to
+// every user, who may subscribe to it; only its owner may change it.
+type SavedSearch struct {
+	ID        string
+	UserID    string
+	Name      string
+	Query     string
+	Tags      []string
+	Shared    bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// SubscribedSearch is a saved search with the users subscribed to it.
+type SubscribedSearch struct {
+	Search      *SavedSearch
+	Subscribers []string
+}
+
+// SearchNotification tells a user that a page started to match a saved
+// search they are subscribed to.
+type SearchNotification struct {
+	ID         string
+	UserID     string
+	SearchID   string
+	SearchName string
+	PageID     string
+	CreatedAt  time.Time
+	ReadAt     *time.Time
+}
+
+// The pages known to match a search are only kept while someone is
+// subscribed to it; a page that is not among them when it starts to match
+// is new to the subscribers.
+func (s *FavoritesStore) ensureSavedSearchSchema() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS saved_searches (
+			id         TEXT PRIMARY KEY,
+			user_id    TEXT NOT NULL,
+			name       TEXT NOT NULL,
+			query      TEXT NOT NULL,
+			tags       TEXT NOT NULL,
+			shared     INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL,
+			updated_at TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS saved_searches_user_id_idx ON saved_searches(user_id);
+
+		CREATE TABLE IF NOT EXISTS saved_search_subscriptions (
+			search_id  TEXT NOT NULL,
+			user_id    TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			PRIMARY KEY (search_id, user_id)
+		);
+		CREATE INDEX IF NOT EXISTS saved_search_subscriptions_user_id_idx ON saved_search_subscriptions(user_id);
+
+		CREATE TABLE IF NOT EXISTS saved_search_matches (
+			search_id TEXT NOT NULL,
+			page_id   TEXT NOT NULL,
+			PRIMARY KEY (search_id, page_id)
+		);
+		CREATE INDEX IF NOT EXISTS saved_search_matches_page_id_idx ON saved_search_matches(page_id);
+
+		CREATE TABLE IF NOT EXISTS saved_search_notifications (
+			id         TEXT PRIMARY KEY,
+			user_id    TEXT NOT NULL,
+			search_id  TEXT NOT NULL,
+			page_id    TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL,
+			read_at    TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS saved_search_notifications_user_id_idx ON saved_search_notifications(user_id, created_at);
+	`)
+	return err
+}
+
+const savedSearchColumns = `id, user_id, name, query, tags, shared, created_at, updated_at`
+
+// CreateSavedSearch stores a new saved search and sets its ID and times.
+func (s *FavoritesStore) CreateSavedSearch(search *SavedSearch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, err := shared.GenerateUniqueID()
+	if err != nil {
+		return err
+	}
+	tags, err := encodeTags(search.Tags)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	_, err = s.db.Exec(
+		`INSERT INTO saved_searches (`+savedSearchColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, search.UserID, search.Name, search.Query, tags, search.Shared, now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create saved search for user %s: %w", search.UserID, err)
+	}
+	search.ID = id
+	search.CreatedAt = now
+	search.UpdatedAt = now
+	return nil
+}
+
+// UpdateSavedSearch stores the name, query, tags and sharing of a saved
+// search. Unsharing a search unsubscribes everyone but its owner.
+func (s *FavoritesStore) UpdateSavedSearch(search *SavedSearch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tags, err := encodeTags(search.Tags)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	res, err := tx.Exec(
+		`UPDATE saved_searches SET name = ?, query = ?, tags = ?, shared = ?, updated_at = ? WHERE id = ?`,
+		search.Name, search.Query, tags, search.Shared, now, search.ID,
+	)
+	if err == nil && !search.Shared {
+		_, err = tx.Exec(`DELETE FROM saved_search_subscriptions WHERE search_id = ? AND user_id <> ?`, search.ID, search.UserID)
+		if err == nil {
+			err = dropUnsubscribedMatches(tx, search.ID)
+		}
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to update saved search %s: %w", search.ID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		_ = tx.Rollback()
+		return ErrSavedSearchNotFound
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	search.UpdatedAt = now
+	return nil
+}
+
+// GetSavedSearch returns the saved search with the given ID.
+func (s *FavoritesStore) GetSavedSearch(id string) (*SavedSearch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	search, err := scanSavedSearch(s.db.QueryRow(`SELECT `+savedSearchColumns+` FROM saved_searches WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSavedSearchNotFound
+	}
+	return search, err
+}
+
+// ListSavedSearches returns the saved searches of userID and those shared
+// by others, by name.
+func (s *FavoritesStore) ListSavedSearches(userID string) ([]*SavedSearch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query(
+		`SELECT `+savedSearchColumns+` FROM saved_searches
+		WHERE user_id = ? OR shared = 1
+		ORDER BY name COLLATE NOCASE, id`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer shared.LogClose(rows.Close, logCloseRowsFailed)
+
+	searches := []*SavedSearch{}
+	for rows.Next() {
+		search, err := scanSavedSearch(rows)
+		if err != nil {
+			return nil, err
+		}
+		searches = append(searches, search)
+	}
+	return searches, rows.Err()
+}
+
+// DeleteSavedSearch removes a saved search with its subscriptions and
+// notifications.
+func (s *FavoritesStore) DeleteSavedSearch(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.deleteSavedSearchesWhere(`id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete saved search %s: %w", id, err)
+	}
+	return nil
+}
+
+// deleteSavedSearchesWhere removes the saved searches selected by where and
+// everything kept about them.
+func (s *FavoritesStore) deleteSavedSearchesWhere(where string, args ...any) error {
+	selected := `SELECT id FROM saved_searches WHERE ` + where
+	return s.execInTx(
+		[]string{
+			`DELETE FROM saved_search_subscriptions WHERE search_id IN (` + selected + `)`,
+			`DELETE FROM saved_search_matches WHERE search_id IN (` + selected + `)`,
+			`DELETE FROM saved_search_notifications WHERE search_id IN (` + selected + `)`,
+			`DELETE FROM saved_searches WHERE ` + where,
+		},
+		args...,
+	)
+}
+
+// Subscribe subscribes userID to a saved search. Idempotent.
+func (s *FavoritesStore) Subscribe(searchID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(
+		`INSERT OR IGNORE INTO saved_search_subscriptions (search_id, user_id, created_at) VALUES (?, ?, ?)`,
+		searchID, userID, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe user %s to saved search %s: %w", userID, searchID, err)
+	}
+	return nil
+}
+
+// Unsubscribe unsubscribes userID from a saved search. Idempotent. The
+// matches of a search nobody is subscribed to any more are dropped.
+func (s *FavoritesStore) Unsubscribe(searchID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM saved_search_subscriptions WHERE search_id = ? AND user_id = ?`, searchID, userID)
+	if err == nil {
+		err = dropUnsubscribedMatches(tx, searchID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	} else {
+		_ = tx.Rollback()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to unsubscribe user %s from saved search %s: %w", userID, searchID, err)
+	}
+	return nil
+}
+
+// dropUnsubscribedMatches forgets the matches of a saved search once nobody
+// is subscribed to it.
+func dropUnsubscribedMatches(tx *sql.Tx, searchID string) error {
+	_, err := tx.Exec(`
+		DELETE FROM saved_search_matches WHERE search_id = ?
+		AND NOT EXISTS (SELECT 1 FROM saved_search_subscriptions WHERE search_id = ?)
+	`, searchID, searchID)
+	return err
+}
+
+// Subscribers returns the users subscribed to a saved search.
+func (s *FavoritesStore) Subscribers(searchID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query(`SELECT user_id FROM saved_search_subscriptions WHERE search_id = ? ORDER BY user_id`, searchID)
+	if err != nil {
+		return nil, err
+	}
+	defer shared.LogClose(rows.Close, logCloseRowsFailed)
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// SubscribedSearchIDs returns the IDs of the saved searches userID is
+// subscribed to.
+func (s *FavoritesStore) SubscribedSearchIDs(userID string) (map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query(`SELECT search_id FROM saved_search_subscriptions WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer shared.LogClose(rows.Close, logCloseRowsFailed)
+
+	ids := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// ListSubscribedSearches returns every saved search someone is subscribed
+// to, with its subscribers.
+func (s *FavoritesStore) ListSubscribedSearches() ([]SubscribedSearch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query(`
+		SELECT s.id, s.user_id, s.name, s.query, s.tags, s.shared, s.created_at, s.updated_at, sub.user_id
+		FROM saved_searches s
+		JOIN saved_search_subscriptions sub ON sub.search_id = s.id
+		ORDER BY s.id, sub.user_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer shared.LogClose(rows.Close, logCloseRowsFailed)
+
+	var result []SubscribedSearch
+	for rows.Next() {
+		var subscriber string
+		search, err := scanSavedSearch(rows, &subscriber)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(result); n > 0 && result[n-1].Search.ID == search.ID {
+			result[n-1].Subscribers = append(result[n-1].Subscribers, subscriber)
+			continue
+		}
+		result = append(result, SubscribedSearch{Search: search, Subscribers: []string{subscriber}})
+	}
+	return result, rows.Err()
+}
+
+// ReplaceMatches records pageIDs as the pages matching a saved search.
+func (s *FavoritesStore) ReplaceMatches(searchID string, pageIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM saved_search_matches WHERE search_id = ?`, searchID); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to replace matches of saved search %s: %w", searchID, err)
+	}
+	if _, err := insertMatches(tx, searchID, pageIDs); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to replace matches of saved search %s: %w", searchID, err)
+	}
+	return tx.Commit()
+}
+
+// UpdateMatches records which of the checked pages match a saved search
+// now, and returns those among matching that did not match before.
+func (s *FavoritesStore) UpdateMatches(searchID string, checked, matching []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	isMatching := make(map[string]bool, len(matching))
+	for _, id := range matching {
+		isMatching[id] = true
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range checked {
+		if isMatching[id] {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM saved_search_matches WHERE search_id = ? AND page_id = ?`, searchID, id); err != nil {
+			_ = tx.Rollback()
+			return nil, fmt.Errorf("failed to update matches of saved search %s: %w", searchID, err)
+		}
+	}
+	added, err := insertMatches(tx, searchID, matching)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, fmt.Errorf("failed to update matches of saved search %s: %w", searchID, err)
+	}
+	return added, tx.Commit()
+}
+
+// insertMatches adds the pages to the matches of a saved search and returns
+// those that were not among them.
+func insertMatches(tx *sql.Tx, searchID string, pageIDs []string) ([]string, error) {
+	added := []string{}
+	for _, id := range pageIDs {
+		res, err := tx.Exec(`INSERT OR IGNORE INTO saved_search_matches (search_id, page_id) VALUES (?, ?)`, searchID, id)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			added = append(added, id)
+		}
+	}
+	return added, nil
+}
+
+// AddNotifications notifies each of userIDs that pageID started to match a
+// saved search.
+func (s *FavoritesStore) AddNotifications(searchID, pageID string, userIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		id, err := shared.GenerateUniqueID()
+		if err == nil {
+			_, err = tx.Exec(
+				`INSERT INTO saved_search_notifications (id, user_id, search_id, page_id, created_at) VALUES (?, ?, ?, ?, ?)`,
+				id, userID, searchID, pageID, now,
+			)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to add notification for saved search %s: %w", searchID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ListNotifications returns up to limit notifications of userID, newest
+// first; only the unread ones when unreadOnly is set.
+func (s *FavoritesStore) ListNotifications(userID string, unreadOnly bool, limit int) ([]*SearchNotification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := `
+		SELECT n.id, n.user_id, n.search_id, s.name, n.page_id, n.created_at, n.read_at
+		FROM saved_search_notifications n
+		JOIN saved_searches s ON s.id = n.search_id
+		WHERE n.user_id = ?`
+	if unreadOnly {
+		query += ` AND n.read_at IS NULL`
+	}
+	query += ` ORDER BY n.created_at DESC, n.id LIMIT ?`
+
+	rows, err := s.db.Query(query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer shared.LogClose(rows.Close, logCloseRowsFailed)
+
+	notifications := []*SearchNotification{}
+	for rows.Next() {
+		var n SearchNotification
+		var readAt sql.NullTime
+		if err := rows.Scan(&n.ID, &n.UserID, &n.SearchID, &n.SearchName, &n.PageID, &n.CreatedAt, &readAt); err != nil {
+			return nil, err
+		}
+		if readAt.Valid {
+			n.ReadAt = &readAt.Time
+		}
+		notifications = append(notifications, &n)
+	}
+	return notifications, rows.Err()
+}
+
+// MarkNotificationsRead marks the given notifications of userID as read,
+// or all of them when ids is empty.
+func (s *FavoritesStore) MarkNotificationsRead(userID string, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := `UPDATE saved_search_notifications SET read_at = ? WHERE user_id = ? AND read_at IS NULL`
+	args := []any{time.Now().UTC(), userID}
+	if len(ids) > 0 {
+		query += ` AND id IN (?` + strings.Repeat(`, ?`, len(ids)-1) + `)`
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+	if _, err := s.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to mark notifications of user %s as read: %w", userID, err)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSavedSearch scans the savedSearchColumns of a row, followed by extra.
+func scanSavedSearch(row rowScanner, extra ...any) (*SavedSearch, error) {
+	var search SavedSearch
+	var tags string
+	dest := append([]any{
+		&search.ID, &search.UserID, &search.Name, &search.Query, &tags,
+		&search.Shared, &search.CreatedAt, &search.UpdatedAt,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(tags), &search.Tags); err != nil {
+		return nil, fmt.Errorf("invalid tags of saved search %s: %w", search.ID, err)
+	}
+	return &search, nil
+}
+
+func encodeTags(tags []string) (string, error) {
+	if tags == nil {
+		tags = []string{}
+	}
+	data, err := json.Marshal(tags)
+	return string(data), err
+}
@@ -0,0 +1,199 @@
+package favorites
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func createSavedSearch(t *testing.T, store *FavoritesStore, userID, name string, shared bool) *SavedSearch {
+	t.Helper()
+	search := &SavedSearch{UserID: userID, Name: name, Query: "status:open", Tags: []string{"incident"}, Shared: shared}
+	if err := store.CreateSavedSearch(search); err != nil {
+		t.Fatalf("CreateSavedSearch: %v", err)
+	}
+	return search
+}
+
+func savedSearchNames(searches []*SavedSearch) []string {
+	names := make([]string, len(searches))
+	for i, s := range searches {
+		names[i] = s.Name
+	}
+	return names
+}
+
+func TestFavoritesStore_SavedSearches_CreateGetUpdateList(t *testing.T) {
+	store := newTestStore(t)
+
+	own := createSavedSearch(t, store, "user-1", "open incidents", false)
+	if own.ID == "" || own.CreatedAt.IsZero() {
+		t.Fatalf("expected ID and times to be set, got %+v", own)
+	}
+	got, err := store.GetSavedSearch(own.ID)
+	if err != nil {
+		t.Fatalf("GetSavedSearch: %v", err)
+	}
+	if got.Query != "status:open" || !slices.Equal(got.Tags, []string{"incident"}) || got.Shared {
+		t.Fatalf("unexpected saved search: %+v", got)
+	}
+
+	createSavedSearch(t, store, "user-2", "Runbooks", true)
+	createSavedSearch(t, store, "user-2", "private", false)
+
+	list, err := store.ListSavedSearches("user-1")
+	if err != nil {
+		t.Fatalf("ListSavedSearches: %v", err)
+	}
+	if names := savedSearchNames(list); !slices.Equal(names, []string{"open incidents", "Runbooks"}) {
+		t.Fatalf("expected own and shared searches by name, got %v", names)
+	}
+
+	own.Name = "all incidents"
+	own.Tags = nil
+	if err := store.UpdateSavedSearch(own); err != nil {
+		t.Fatalf("UpdateSavedSearch: %v", err)
+	}
+	got, err = store.GetSavedSearch(own.ID)
+	if err != nil || got.Name != "all incidents" || len(got.Tags) != 0 {
+		t.Fatalf("GetSavedSearch after update = %+v, %v", got, err)
+	}
+
+	if err := store.UpdateSavedSearch(&SavedSearch{ID: "missing"}); !errors.Is(err, ErrSavedSearchNotFound) {
+		t.Fatalf("expected ErrSavedSearchNotFound, got %v", err)
+	}
+	if _, err := store.GetSavedSearch("missing"); !errors.Is(err, ErrSavedSearchNotFound) {
+		t.Fatalf("expected ErrSavedSearchNotFound, got %v", err)
+	}
+}
+
+func TestFavoritesStore_SavedSearches_SubscriptionsAndMatches(t *testing.T) {
+	store := newTestStore(t)
+	search := createSavedSearch(t, store, "user-1", "incidents", true)
+
+	if err := store.Subscribe(search.ID, "user-1"); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if err := store.Subscribe(search.ID, "user-2"); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	subscribed, err := store.ListSubscribedSearches()
+	if err != nil {
+		t.Fatalf("ListSubscribedSearches: %v", err)
+	}
+	if len(subscribed) != 1 || !slices.Equal(subscribed[0].Subscribers, []string{"user-1", "user-2"}) {
+		t.Fatalf("unexpected subscribed searches: %+v", subscribed)
+	}
+
+	if err := store.ReplaceMatches(search.ID, []string{"page-1"}); err != nil {
+		t.Fatalf("ReplaceMatches: %v", err)
+	}
+	added, err := store.UpdateMatches(search.ID, []string{"page-1", "page-2"}, []string{"page-1", "page-2"})
+	if err != nil || !slices.Equal(added, []string{"page-2"}) {
+		t.Fatalf("UpdateMatches = %v, %v; want [page-2]", added, err)
+	}
+	// page-1 stops matching and matches again: it is new once more.
+	if added, err = store.UpdateMatches(search.ID, []string{"page-1"}, nil); err != nil || len(added) != 0 {
+		t.Fatalf("UpdateMatches = %v, %v; want none", added, err)
+	}
+	if added, err = store.UpdateMatches(search.ID, []string{"page-1"}, []string{"page-1"}); err != nil || !slices.Equal(added, []string{"page-1"}) {
+		t.Fatalf("UpdateMatches = %v, %v; want [page-1]", added, err)
+	}
+
+	// Unsharing unsubscribes everyone but the owner.
+	search.Shared = false
+	if err := store.UpdateSavedSearch(search); err != nil {
+		t.Fatalf("UpdateSavedSearch: %v", err)
+	}
+	ids, err := store.SubscribedSearchIDs("user-2")
+	if err != nil || len(ids) != 0 {
+		t.Fatalf("SubscribedSearchIDs(user-2) = %v, %v", ids, err)
+	}
+
+	if err := store.Unsubscribe(search.ID, "user-1"); err != nil {
+		t.Fatalf("Unsubscribe: %v", err)
+	}
+	if subscribed, err = store.ListSubscribedSearches(); err != nil || len(subscribed) != 0 {
+		t.Fatalf("ListSubscribedSearches = %+v, %v", subscribed, err)
+	}
+	// Without subscribers the matches are forgotten.
+	if added, err = store.UpdateMatches(search.ID, nil, []string{"page-1", "page-2"}); err != nil || len(added) != 2 {
+		t.Fatalf("UpdateMatches = %v, %v; want both pages", added, err)
+	}
+}
+
+func TestFavoritesStore_Notifications_ListAndMarkRead(t *testing.T) {
+	store := newTestStore(t)
+	search := createSavedSearch(t, store, "user-1", "incidents", true)
+
+	if err := store.AddNotifications(search.ID, "page-1", []string{"user-1", "user-2"}); err != nil {
+		t.Fatalf("AddNotifications: %v", err)
+	}
+	if err := store.AddNotifications(search.ID, "page-2", []string{"user-1"}); err != nil {
+		t.Fatalf("AddNotifications: %v", err)
+	}
+
+	list, err := store.ListNotifications("user-1", false, 10)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("ListNotifications = %+v, %v", list, err)
+	}
+	if list[0].SearchName != "incidents" || list[0].ReadAt != nil {
+		t.Fatalf("unexpected notification: %+v", list[0])
+	}
+
+	if err := store.MarkNotificationsRead("user-1", []string{list[0].ID}); err != nil {
+		t.Fatalf("MarkNotificationsRead: %v", err)
+	}
+	unread, err := store.ListNotifications("user-1", true, 10)
+	if err != nil || len(unread) != 1 || unread[0].ID != list[1].ID {
+		t.Fatalf("expected one unread notification, got %+v, %v", unread, err)
+	}
+	if err := store.MarkNotificationsRead("user-1", nil); err != nil {
+		t.Fatalf("MarkNotificationsRead: %v", err)
+	}
+	if unread, err = store.ListNotifications("user-1", true, 10); err != nil || len(unread) != 0 {
+		t.Fatalf("expected all notifications read, got %+v, %v", unread, err)
+	}
+	if other, err := store.ListNotifications("user-2", true, 10); err != nil || len(other) != 1 {
+		t.Fatalf("expected user-2's notification to stay unread, got %+v, %v", other, err)
+	}
+
+	if err := store.DeleteAllForPage("page-1"); err != nil {
+		t.Fatalf("DeleteAllForPage: %v", err)
+	}
+	if other, err := store.ListNotifications("user-2", false, 10); err != nil || len(other) != 0 {
+		t.Fatalf("expected notifications about a deleted page to go, got %+v, %v", other, err)
+	}
+}
+
+func TestFavoritesStore_DeleteAllForUser_RemovesSavedSearches(t *testing.T) {
+	store := newTestStore(t)
+	gone := createSavedSearch(t, store, "user-1", "mine", true)
+	kept := createSavedSearch(t, store, "user-2", "theirs", true)
+	for _, id := range []string{gone.ID, kept.ID} {
+		if err := store.Subscribe(id, "user-1"); err != nil {
+			t.Fatalf("Subscribe: %v", err)
+		}
+		if err := store.Subscribe(id, "user-2"); err != nil {
+			t.Fatalf("Subscribe: %v", err)
+		}
+	}
+	if err := store.AddNotifications(gone.ID, "page-1", []string{"user-2"}); err != nil {
+		t.Fatalf("AddNotifications: %v", err)
+	}
+
+	if err := store.DeleteAllForUser("user-1"); err != nil {
+		t.Fatalf("DeleteAllForUser: %v", err)
+	}
+
+	if _, err := store.GetSavedSearch(gone.ID); !errors.Is(err, ErrSavedSearchNotFound) {
+		t.Fatalf("expected the user's saved search to be deleted, got %v", err)
+	}
+	subscribed, err := store.ListSubscribedSearches()
+	if err != nil || len(subscribed) != 1 || subscribed[0].Search.ID != kept.ID || !slices.Equal(subscribed[0].Subscribers, []string{"user-2"}) {
+		t.Fatalf("unexpected subscriptions left: %+v, %v", subscribed, err)
+	}
+	if list, err := store.ListNotifications("user-2", false, 10); err != nil || len(list) != 0 {
+		t.Fatalf("expected notifications of the deleted search to go, got %+v, %v", list, err)
+	}
+}
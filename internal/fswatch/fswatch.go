@@ -0,0 +1,144 @@
+// Package fswatch reports changes below a set of directories in debounced
+// batches. It uses inotify where the platform provides it and falls back to
+// periodically scanning the directories otherwise.
+package fswatch
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+const (
+	DefaultDebounce     = 500 * time.Millisecond
+	DefaultPollInterval = 2 * time.Second
+
+	// maxDelayFactor bounds how long a steady stream of events can postpone
+	// a batch, as a multiple of the debounce window.
+	maxDelayFactor = 10
+)
+
+type Options struct {
+	// Dirs are watched recursively. They must exist when Run starts.
+	Dirs []string
+	// Debounce is how long the directories must stay quiet before a batch
+	// is delivered. Defaults to DefaultDebounce.
+	Debounce time.Duration
+	// PollInterval is the scan interval of the polling fallback. Defaults to
+	// DefaultPollInterval.
+	PollInterval time.Duration
+	// ForcePolling skips native notifications even where they are available.
+	ForcePolling bool
+	Logger       *slog.Logger
+}
+
+// source emits the absolute path of every changed file or directory. A
+// watched directory itself is emitted when changes below it may have been
+// missed.
+type source interface {
+	run(ctx context.Context, events chan<- string) error
+	close() error
+}
+
+// Run watches opts.Dirs until ctx is done. onChange receives the absolute
+// paths that changed since the previous call, sorted and without duplicates.
+// It runs on the watching goroutine; changes arriving meanwhile are delivered
+// with the next batch.
+func Run(ctx context.Context, opts Options, onChange func(paths []string)) error {
+	if opts.Debounce <= 0 {
+		opts.Debounce = DefaultDebounce
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	log := opts.Logger.With("component", "fswatch")
+
+	src, err := newSource(opts, log)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := src.close(); err != nil {
+			log.Warn("could not close watcher", "error", err)
+		}
+	}()
+
+	events := make(chan string, 256)
+	srcErr := make(chan error, 1)
+	go func() {
+		srcErr <- src.run(ctx, events)
+	}()
+
+	return debounce(ctx, events, srcErr, opts.Debounce, onChange)
+}
+
+func newSource(opts Options, log *slog.Logger) (source, error) {
+	if !opts.ForcePolling {
+		src, err := newNotifySource(opts.Dirs, log)
+		if err == nil {
+			log.Info("watching for file changes", "mode", "notify", "dirs", opts.Dirs)
+			return src, nil
+		}
+		log.Warn("native file notifications unavailable, falling back to polling", "error", err)
+	}
+	src, err := newPollSource(opts.Dirs, opts.PollInterval)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("watching for file changes", "mode", "poll", "interval", opts.PollInterval, "dirs", opts.Dirs)
+	return src, nil
+}
+
+// debounce collects paths from events and hands them to onChange once no
+// new path arrived for wait, or at the latest maxDelayFactor*wait after the
+// first path of the batch.
+func debounce(ctx context.Context, events <-chan string, srcErr <-chan error, wait time.Duration, onChange func(paths []string)) error {
+	pending := map[string]struct{}{}
+	var first time.Time
+	timer := time.NewTimer(wait)
+	timer.Stop()
+	defer timer.Stop()
+
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		paths := make([]string, 0, len(pending))
+		for p := range pending {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		pending = map[string]struct{}{}
+		onChange(paths)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-srcErr:
+			if ctx.Err() != nil {
+				return nil
+			}
+			flush()
+			return err
+		case p := <-events:
+			if len(pending) == 0 {
+				first = time.Now()
+			}
+			pending[p] = struct{}{}
+			if time.Since(first) >= maxDelayFactor*wait {
+				timer.Stop()
+				flush()
+				continue
+			}
+			timer.Reset(wait)
+		case <-timer.C:
+			flush()
+		}
+	}
+}
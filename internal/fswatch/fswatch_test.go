@@ -0,0 +1,231 @@
+package fswatch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collector records the batches delivered by Run.
+type collector struct {
+	mu      sync.Mutex
+	batches [][]string
+	notify  chan struct{}
+}
+
+func newCollector() *collector {
+	return &collector{notify: make(chan struct{}, 16)}
+}
+
+func (c *collector) onChange(paths []string) {
+	c.mu.Lock()
+	c.batches = append(c.batches, paths)
+	c.mu.Unlock()
+	c.notify <- struct{}{}
+}
+
+// waitFor waits until the delivered paths contain all of want.
+func (c *collector) waitFor(t *testing.T, want ...string) {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		c.mu.Lock()
+		var seen []string
+		for _, b := range c.batches {
+			seen = append(seen, b...)
+		}
+		c.mu.Unlock()
+		missing := false
+		for _, w := range want {
+			if !slices.Contains(seen, w) {
+				missing = true
+			}
+		}
+		if !missing {
+			return
+		}
+		select {
+		case <-c.notify:
+		case <-deadline:
+			t.Fatalf("timed out waiting for %v, got %v", want, seen)
+		}
+	}
+}
+
+func startWatching(t *testing.T, opts Options) *collector {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	c := newCollector()
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, opts, c.onChange)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Run returned error: %v", err)
+		}
+	})
+	// Give the source time to set up its watches.
+	time.Sleep(100 * time.Millisecond)
+	return c
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestRun_Polling_ReportsCreatedChangedAndRemovedFiles(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.md")
+	gone := filepath.Join(dir, "gone.md")
+	writeFile(t, existing, "one")
+	writeFile(t, gone, "bye")
+
+	c := startWatching(t, Options{Dirs: []string{dir}, ForcePolling: true, PollInterval: 20 * time.Millisecond, Debounce: 20 * time.Millisecond})
+
+	created := filepath.Join(dir, "sub", "created.md")
+	if err := os.MkdirAll(filepath.Dir(created), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, created, "new")
+	writeFile(t, existing, "changed content")
+	if err := os.Remove(gone); err != nil {
+		t.Fatal(err)
+	}
+
+	c.waitFor(t, created, existing, gone)
+}
+
+func TestRun_Notify_ReportsChangesInNewDirectories(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("native notifications are only implemented on linux")
+	}
+	dir := t.TempDir()
+	c := startWatching(t, Options{Dirs: []string{dir}, Debounce: 20 * time.Millisecond})
+
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	c.waitFor(t, sub)
+
+	nested := filepath.Join(sub, "page.md")
+	writeFile(t, nested, "hello")
+	c.waitFor(t, nested)
+}
+
+func TestRun_Notify_WatchesRecreatedRoot(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("native notifications are only implemented on linux")
+	}
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "root")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	c := startWatching(t, Options{Dirs: []string{dir}, Debounce: 20 * time.Millisecond})
+
+	if err := os.Rename(dir, filepath.Join(parent, "old")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	c.waitFor(t, dir)
+
+	page := filepath.Join(dir, "page.md")
+	writeFile(t, page, "hello")
+	c.waitFor(t, page)
+}
+
+func TestRun_Notify_ReportsContentOfRestoredRoot(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("native notifications are only implemented on linux")
+	}
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "root")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	restored := filepath.Join(parent, "restored")
+	if err := os.MkdirAll(filepath.Join(restored, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(restored, "sub", "page.md"), "hello")
+	c := startWatching(t, Options{Dirs: []string{dir}, Debounce: 20 * time.Millisecond})
+
+	// A restore swaps in a directory whose files raise no events.
+	if err := os.Rename(dir, filepath.Join(parent, "old")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(restored, dir); err != nil {
+		t.Fatal(err)
+	}
+	c.waitFor(t, dir, filepath.Join(dir, "sub", "page.md"))
+
+	page := filepath.Join(dir, "sub", "new.md")
+	writeFile(t, page, "hello")
+	c.waitFor(t, page)
+}
+
+func TestDebounce_BatchesBurstsAndDeduplicates(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan string)
+	batches := make(chan []string, 4)
+	go func() {
+		_ = debounce(ctx, events, nil, 50*time.Millisecond, func(paths []string) { batches <- paths })
+	}()
+
+	for _, p := range []string{"/b", "/a", "/b", "/a"} {
+		events <- p
+	}
+
+	select {
+	case got := <-batches:
+		if !slices.Equal(got, []string{"/a", "/b"}) {
+			t.Fatalf("expected one sorted, deduplicated batch, got %v", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for batch")
+	}
+	select {
+	case got := <-batches:
+		t.Fatalf("expected a single batch, got another one: %v", got)
+	case <-time.After(150 * time.Millisecond):
+	}
+}
+
+func TestDebounce_FlushesSteadyStreamAfterMaxDelay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan string)
+	batches := make(chan []string, 4)
+	wait := 20 * time.Millisecond
+	go func() {
+		_ = debounce(ctx, events, nil, wait, func(paths []string) { batches <- paths })
+	}()
+
+	stop := time.After(maxDelayFactor * wait * 3)
+	for {
+		select {
+		case <-batches:
+			return
+		case <-stop:
+			t.Fatal("expected a batch while events kept arriving")
+		case events <- "/busy":
+			time.Sleep(wait / 2)
+		}
+	}
+}
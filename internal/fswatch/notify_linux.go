@@ -0,0 +1,219 @@
+//go:build linux
+
+package fswatch
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	inotifyMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_MODIFY | unix.IN_CLOSE_WRITE |
+		unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_ATTRIB | unix.IN_DELETE_SELF | unix.IN_MOVE_SELF
+	// pollTimeoutMillis bounds how long a read waits before checking ctx.
+	pollTimeoutMillis = 200
+)
+
+// notifySource receives changes from inotify. inotify watches single
+// directories, so every directory below the roots gets its own watch, and
+// directories created or moved in later are added as they appear. A root
+// that is removed or replaced (e.g. by a restore) is watched again once it
+// reappears.
+type notifySource struct {
+	fd      int
+	roots   []string
+	log     *slog.Logger
+	watches map[int]string
+	missing map[string]bool
+}
+
+func newNotifySource(dirs []string, log *slog.Logger) (source, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify init: %w", err)
+	}
+	s := &notifySource{fd: fd, roots: dirs, log: log, watches: map[int]string{}, missing: map[string]bool{}}
+	for _, dir := range dirs {
+		if _, err := s.addTree(dir); err != nil {
+			_ = unix.Close(fd)
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// addTree watches dir and every directory below it and returns the entries
+// found below dir. A directory is watched before it is listed, so a file
+// created in it meanwhile is either listed or reported by inotify.
+func (s *notifySource) addTree(dir string) ([]string, error) {
+	var found []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if p != dir {
+			found = append(found, p)
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		wd, err := unix.InotifyAddWatch(s.fd, p, inotifyMask)
+		if err != nil {
+			if err == unix.ENOENT {
+				return fs.SkipDir
+			}
+			return fmt.Errorf("inotify watch %s: %w", p, err)
+		}
+		s.watches[wd] = p
+		return nil
+	})
+	return found, err
+}
+
+func (s *notifySource) removeTree(dir string) {
+	for wd, p := range s.watches {
+		if p == dir || strings.HasPrefix(p, dir+string(filepath.Separator)) {
+			_, _ = unix.InotifyRmWatch(s.fd, uint32(wd))
+			delete(s.watches, wd)
+		}
+	}
+}
+
+func (s *notifySource) run(ctx context.Context, events chan<- string) error {
+	buf := make([]byte, 64*1024)
+	fds := []unix.PollFd{{Fd: int32(s.fd), Events: unix.POLLIN}}
+
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+		for _, p := range s.rewatchMissingRoots() {
+			if !send(ctx, events, p) {
+				return nil
+			}
+		}
+		n, err := unix.Poll(fds, pollTimeoutMillis)
+		if err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			return fmt.Errorf("inotify poll: %w", err)
+		}
+		if n == 0 {
+			continue
+		}
+
+		n, err = unix.Read(s.fd, buf)
+		if err != nil {
+			if err == unix.EAGAIN || err == unix.EINTR {
+				continue
+			}
+			return fmt.Errorf("inotify read: %w", err)
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			ev := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + unix.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[nameStart:nameStart+int(ev.Len)]), "\x00")
+			offset = nameStart + int(ev.Len)
+
+			for _, p := range s.handle(int(ev.Wd), ev.Mask, name) {
+				if !send(ctx, events, p) {
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// handle keeps the watches in step with the directory tree and returns the
+// paths to report for one event.
+func (s *notifySource) handle(wd int, mask uint32, name string) []string {
+	if mask&unix.IN_Q_OVERFLOW != 0 {
+		s.log.Warn("inotify queue overflowed, reporting watched directories as changed")
+		return s.roots
+	}
+	if mask&unix.IN_IGNORED != 0 {
+		delete(s.watches, wd)
+		return nil
+	}
+
+	dir, ok := s.watches[wd]
+	if !ok {
+		return nil
+	}
+	p := dir
+	if name != "" {
+		p = filepath.Join(dir, name)
+	}
+
+	if mask&(unix.IN_DELETE_SELF|unix.IN_MOVE_SELF) != 0 {
+		if !s.isRoot(dir) {
+			// Reported through the parent directory as well.
+			return nil
+		}
+		s.removeTree(dir)
+		s.missing[dir] = true
+		return []string{dir}
+	}
+
+	changed := []string{p}
+	if mask&unix.IN_ISDIR != 0 {
+		switch {
+		case mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0:
+			found, err := s.addTree(p)
+			if err != nil {
+				s.log.Warn("could not watch new directory", "path", p, "error", err)
+			}
+			changed = append(changed, found...)
+		case mask&(unix.IN_DELETE|unix.IN_MOVED_FROM) != 0:
+			s.removeTree(p)
+		}
+	}
+	return changed
+}
+
+func (s *notifySource) isRoot(dir string) bool {
+	for _, root := range s.roots {
+		if root == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// rewatchMissingRoots watches roots that came back and reports them as
+// changed together with everything already in them, since files written
+// before the watch was added raise no event of their own.
+func (s *notifySource) rewatchMissingRoots() []string {
+	var back []string
+	for root := range s.missing {
+		if info, err := os.Stat(root); err != nil || !info.IsDir() {
+			continue
+		}
+		found, err := s.addTree(root)
+		if err != nil {
+			s.log.Warn("could not watch directory again", "path", root, "error", err)
+			continue
+		}
+		delete(s.missing, root)
+		back = append(back, root)
+		back = append(back, found...)
+	}
+	return back
+}
+
+func (s *notifySource) close() error {
+	return unix.Close(s.fd)
+}
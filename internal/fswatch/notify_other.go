@@ -0,0 +1,14 @@
+//go:build !linux
+
+package fswatch
+
+import (
+	"errors"
+	"log/slog"
+)
+
+var errNotifyUnsupported = errors.New("native file notifications are not supported on this platform")
+
+func newNotifySource(dirs []string, log *slog.Logger) (source, error) {
+	return nil, errNotifyUnsupported
+}
@@ -0,0 +1,115 @@
+package fswatch
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+func (s fileState) unchanged(other fileState) bool {
+	return s.size == other.size && s.modTime.Equal(other.modTime) && s.isDir == other.isDir
+}
+
+// pollSource finds changes by comparing periodic scans of the directories.
+type pollSource struct {
+	dirs     []string
+	interval time.Duration
+	state    map[string]fileState
+}
+
+func newPollSource(dirs []string, interval time.Duration) (*pollSource, error) {
+	s := &pollSource{dirs: dirs, interval: interval}
+	state, err := s.scan()
+	if err != nil {
+		return nil, err
+	}
+	s.state = state
+	return s, nil
+}
+
+func (s *pollSource) scan() (map[string]fileState, error) {
+	state := map[string]fileState{}
+	for _, dir := range s.dirs {
+		err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					// Removed while scanning; the next scan settles it.
+					return nil
+				}
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			state[p] = fileState{size: info.Size(), modTime: info.ModTime(), isDir: d.IsDir()}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return state, nil
+}
+
+func (s *pollSource) run(ctx context.Context, events chan<- string) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		next, err := s.scan()
+		if err != nil {
+			return err
+		}
+		for p, st := range next {
+			prev, ok := s.state[p]
+			// A directory's own modification time changes with its entries,
+			// which are reported themselves.
+			if ok && (prev.isDir && st.isDir || prev.unchanged(st)) {
+				continue
+			}
+			if !send(ctx, events, p) {
+				return nil
+			}
+		}
+		for p := range s.state {
+			if _, ok := next[p]; ok {
+				continue
+			}
+			if !send(ctx, events, p) {
+				return nil
+			}
+		}
+		s.state = next
+	}
+}
+
+func (s *pollSource) close() error {
+	return nil
+}
+
+func send(ctx context.Context, events chan<- string, p string) bool {
+	select {
+	case events <- p:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -158,10 +158,7 @@ func TestAPIKeys_AdminScopedKeyCannotManageKeys(t *testing.T) {
 
 // TestAPIKeys_ViewerScopedKeyCanReadButNotWrite is the end-to-end proof of the
 // permission model: a viewer-scoped key can reach a read endpoint, but is
-// rejected on a mutating one. The write attempt is rejected for CSRF reasons
-// before role is even evaluated (a pure Bearer client has no CSRF cookie) —
-// which is a stricter outcome than the role check alone would give, and it
-// means no key, regardless of role, can reach a write route in this phase.
+// rejected on a mutating one by the role check.
 func TestAPIKeys_ViewerScopedKeyCanReadButNotWrite(t *testing.T) {
 	w, router := newAPIKeyRouterTest(t)
 
@@ -197,6 +194,44 @@ func TestAPIKeys_ViewerScopedKeyCanReadButNotWrite(t *testing.T) {
 	}
 }
 
+// TestAPIKeys_EditorScopedKeyCanWriteWithoutCSRFToken verifies that Bearer
+// clients such as `leafwiki publish` can write: they have no CSRF cookie, and
+// need none.
+func TestAPIKeys_EditorScopedKeyCanWriteWithoutCSRFToken(t *testing.T) {
+	w, router := newAPIKeyRouterTest(t)
+
+	owner, err := w.UserService().CreateUser("ci-owner", "ci-owner@example.com", "password123", coreauth.RoleEditor)
+	if err != nil {
+		t.Fatalf("CreateUser err: %v", err)
+	}
+
+	createBody := `{"name":"ci key","userId":"` + owner.ID + `","role":"editor"}`
+	createRec := authenticatedRequest(t, router, http.MethodPost, "/api/api-keys", strings.NewReader(createBody))
+	if createRec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 creating key, got %d: %s", createRec.Code, createRec.Body.String())
+	}
+	var created struct {
+		Secret string `json:"secret"`
+	}
+	if err := json.Unmarshal(createRec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("unmarshal create response: %v", err)
+	}
+
+	writeReq := httptest.NewRequest(http.MethodPost, "/api/pages/ensure", strings.NewReader(`{"path":"docs/intro","title":"Intro"}`))
+	writeReq.Header.Set("Authorization", "Bearer "+created.Secret)
+	writeReq.Header.Set("Content-Type", "application/json")
+	writeRec := httptest.NewRecorder()
+	router.ServeHTTP(writeRec, writeReq)
+	if writeRec.Code != http.StatusOK {
+		t.Fatalf("expected 200 writing with an editor key, got %d: %s", writeRec.Code, writeRec.Body.String())
+	}
+	readRec := httptest.NewRecorder()
+	router.ServeHTTP(readRec, bearerRequest(http.MethodGet, "/api/pages/by-path?path=docs/intro", created.Secret))
+	if readRec.Code != http.StatusOK {
+		t.Fatalf("expected created page to be readable, got %d: %s", readRec.Code, readRec.Body.String())
+	}
+}
+
 // TestAPIKeys_RevokedKeyIsRejectedImmediately verifies revocation takes effect
 // on the very next request — no caching, no grace period.
 func TestAPIKeys_RevokedKeyIsRejectedImmediately(t *testing.T) {
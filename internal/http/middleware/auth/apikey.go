@@ -35,13 +35,10 @@ type APIKeyConfig struct {
 //   - LeafWiki-shaped token, store suspended for a
 //     live restore                                   → 503, not counted as a failed attempt
 //
-// Note on writes: every feature's authGroup also runs CSRFMiddleware, which
-// requires a CSRF cookie a pure Bearer client never has — so today, every
-// mutating (POST/PUT/PATCH/DELETE) request made with an API key is rejected
-// regardless of the key's role. This is expected for the read-only phase this
-// feature currently supports; it is not itself an access-control mechanism.
-// A future phase adding API-key write support must address CSRF for Bearer
-// requests explicitly rather than assuming it already works.
+// Note on writes: CSRFMiddleware lets requests authenticated here through
+// without a CSRF token, as a browser never sends an Authorization header on
+// its own. A key can therefore write wherever role(user) ∩ role(key) allows
+// it, which is what clients like `leafwiki publish` rely on.
 func InjectAPIKeyUser(cfg APIKeyConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if cfg.Service == nil {
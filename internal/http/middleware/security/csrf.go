@@ -19,6 +19,15 @@ func CSRFMiddleware(csrf *CSRFCookie) gin.HandlerFunc {
 			return
 		}
 
+		// Requests authenticated with a Bearer API key (see
+		// auth.InjectAPIKeyUser) carry no session cookie: a browser never
+		// attaches an Authorization header on its own, so they cannot be
+		// forged cross-site and need no CSRF token.
+		if c.GetBool("apiKeyAuth") {
+			c.Next()
+			return
+		}
+
 		cookieToken, err := csrf.Read(c)
 		if err != nil || cookieToken == "" {
 			slog.Default().Warn("CSRF token missing or error reading token", "error", err)
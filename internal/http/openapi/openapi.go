@@ -0,0 +1,43 @@
+// Package openapi holds the OpenAPI 3.1 description of the REST API.
+//
+// openapi.json is maintained by hand next to the routes it describes. The
+// tests of this package fail when a registered route is missing from it, when
+// it describes a route that does not exist, or when a schema no longer
+// matches the JSON of its Go type, so a change of the API has to update it.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+// Spec returns the OpenAPI document as stored.
+func Spec() []byte {
+	return spec
+}
+
+// Handler serves the OpenAPI document. A non-empty basePath becomes the
+// server URL, so clients generated from it reach the API behind a reverse
+// proxy.
+func Handler(basePath string) gin.HandlerFunc {
+	body := spec
+	if basePath = strings.TrimRight(basePath, "/"); basePath != "" {
+		var doc map[string]any
+		if err := json.Unmarshal(spec, &doc); err == nil {
+			doc["servers"] = []map[string]string{{"url": basePath}}
+			if withServer, err := json.Marshal(doc); err == nil {
+				body = withServer
+			}
+		}
+	}
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+	}
+}
@@ -0,0 +1,513 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/perber/wiki/internal/backup"
+	"github.com/perber/wiki/internal/branding"
+	coreassets "github.com/perber/wiki/internal/core/assets"
+	coreauth "github.com/perber/wiki/internal/core/auth"
+	"github.com/perber/wiki/internal/core/tree"
+	httpinternal "github.com/perber/wiki/internal/http"
+	"github.com/perber/wiki/internal/http/dto"
+	"github.com/perber/wiki/internal/http/openapi"
+	coreimporter "github.com/perber/wiki/internal/importer"
+	"github.com/perber/wiki/internal/links"
+	"github.com/perber/wiki/internal/related"
+	"github.com/perber/wiki/internal/restore"
+	"github.com/perber/wiki/internal/search"
+	"github.com/perber/wiki/internal/snapshot"
+	"github.com/perber/wiki/internal/test_utils"
+	"github.com/perber/wiki/internal/wiki"
+	wikibackup "github.com/perber/wiki/internal/wiki/backup"
+	"github.com/perber/wiki/internal/wiki/pages"
+	wikirelated "github.com/perber/wiki/internal/wiki/related"
+	wikirestore "github.com/perber/wiki/internal/wiki/restore"
+	wikiresync "github.com/perber/wiki/internal/wiki/resync"
+	"github.com/perber/wiki/internal/wiki/revisions"
+	wikisearch "github.com/perber/wiki/internal/wiki/search"
+	wikisnapshot "github.com/perber/wiki/internal/wiki/snapshot"
+)
+
+// schemaTypes maps the schemas of openapi.json to the Go types the handlers
+// serialise. Schemas of gin.H responses are not listed.
+var schemaTypes = map[string]any{
+	"Node":                  dto.Node{},
+	"NodeMetadata":          dto.NodeMetadata{},
+	"Page":                  dto.Page{},
+	"TagCount":              dto.TagCount{},
+	"TaggedPage":            dto.TaggedPage{},
+	"PropertyKeyCount":      dto.PropertyKeyCount{},
+	"PropertyPage":          dto.PropertyPage{},
+	"PropertyEntry":         dto.PropertyEntry{},
+	"User":                  coreauth.PublicUser{},
+	"UserLabel":             coreauth.UserLabel{},
+	"PathLookup":            tree.PathLookup{},
+	"PathSegment":           tree.PathSegment{},
+	"PermalinkTarget":       tree.PermalinkTarget{},
+	"WikiLinkMatch":         pages.WikiLinkMatch{},
+	"TitleMatches":          pages.FindByTitleOutput{},
+	"RefactorPreview":       pages.RefactorPreview{},
+	"RefactorPreviewCounts": pages.RefactorPreviewCounts{},
+	"RefactorAffectedPage":  pages.RefactorAffectedPage{},
+	"SearchAttachmentHit":   search.SearchAttachmentHit{},
+	"SearchResult":          search.SearchResult{},
+	"SearchResultItem":      search.SearchResultItem{},
+	"SearchHeadingHit":      search.SearchHeadingHit{},
+	"SearchTagFacet":        search.SearchTagFacet{},
+	"IndexingStatus":        search.IndexingStatus{},
+	"HistorySearchResult":   search.HistorySearchResult{},
+	"HistorySearchHit":      search.HistorySearchHit{},
+	"SavedSearch":           wikisearch.SavedSearchResponse{},
+	"SearchNotification":    wikisearch.SearchNotificationResponse{},
+	"LinkStatus":            links.LinkStatusResult{},
+	"LinkStatusCounts":      links.LinkStatusCounts{},
+	"Backlink":              links.BacklinkResultItem{},
+	"OutgoingLink":          links.OutgoingResultItem{},
+	"LinkGraph":             links.Graph{},
+	"LinkGraphNode":         links.GraphNode{},
+	"LinkGraphEdge":         links.GraphEdge{},
+	"RelatedPages":          wikirelated.RelatedPagesResult{},
+	"RelatedPage":           wikirelated.RelatedPageItem{},
+	"RelatedReason":         related.Reason{},
+	"Revision":              revisions.RevisionResponse{},
+	"RevisionAsset":         revisions.RevisionAssetResponse{},
+	"RevisionSnapshot":      revisions.RevisionSnapshotResponse{},
+	"RevisionAssetChange":   revisions.RevisionAssetDeltaResponse{},
+	"RevisionComparison":    revisions.RevisionComparisonResponse{},
+	"ResyncStatus":          wikiresync.JobStatus{},
+	"Snapshot":              snapshot.SnapshotEntry{},
+	"SnapshotRunStatus":     snapshot.StatusSnapshot{},
+	"RestoreStatus":         restore.JobStatus{},
+	"BackupStatus":          backup.StatusSnapshot{},
+	"Branding":              branding.BrandingConfigResponse{},
+	"BrandingConstraints":   branding.BrandingConstraintsResponse{},
+	"ImportPlan":            coreimporter.CurrentPlanState{},
+	"ImportPlanItem":        coreimporter.PlanItem{},
+	"ImportExecutionResult": coreimporter.ExecutionResult{},
+	"ImportExecutionItem":   coreimporter.ExecutionItemResult{},
+}
+
+type document struct {
+	OpenAPI    string                                `json:"openapi"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+}
+
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 any                `json:"type"`
+	Enum                 []string           `json:"enum"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	Items                *schema            `json:"items"`
+	AdditionalProperties *schema            `json:"additionalProperties"`
+}
+
+// hasType reports whether the type of the schema is or includes typ, as 3.1
+// allows a list of types.
+func (s *schema) hasType(typ string) bool {
+	switch v := s.Type.(type) {
+	case string:
+		return v == typ
+	case []any:
+		return slices.Contains(v, any(typ))
+	}
+	return false
+}
+
+func loadDocument(t *testing.T) *document {
+	t.Helper()
+	var doc document
+	if err := json.Unmarshal(openapi.Spec(), &doc); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	return &doc
+}
+
+func TestSpec_IsOpenAPI31WithResolvableRefs(t *testing.T) {
+	doc := loadDocument(t)
+	if doc.OpenAPI != "3.1.0" {
+		t.Fatalf("openapi = %q, want 3.1.0", doc.OpenAPI)
+	}
+
+	var raw any
+	if err := json.Unmarshal(openapi.Spec(), &raw); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok {
+				const schemaPrefix, responsePrefix = "#/components/schemas/", "#/components/responses/"
+				switch {
+				case strings.HasPrefix(ref, schemaPrefix):
+					if doc.Components.Schemas[strings.TrimPrefix(ref, schemaPrefix)] == nil {
+						t.Errorf("unresolved $ref %s", ref)
+					}
+				case strings.HasPrefix(ref, responsePrefix):
+					responses := raw.(map[string]any)["components"].(map[string]any)["responses"].(map[string]any)
+					if responses[strings.TrimPrefix(ref, responsePrefix)] == nil {
+						t.Errorf("unresolved $ref %s", ref)
+					}
+				default:
+					t.Errorf("unexpected $ref %s", ref)
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(raw)
+}
+
+// newFullRouter registers every route of the API: all optional features are
+// enabled and backup, snapshot and restore get their routes without managers,
+// as with the features switched off.
+func newFullRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	w, err := wiki.NewWiki(&wiki.WikiOptions{
+		StorageDir:             t.TempDir(),
+		AdminPassword:          "adminpassword",
+		JWTSecret:              "secretkey",
+		AccessTokenTimeout:     15 * time.Minute,
+		RefreshTokenTimeout:    7 * 24 * time.Hour,
+		EnableRevision:         true,
+		EnableAPIKeyManagement: true,
+		SearchHistory:          true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create wiki instance: %v", err)
+	}
+	t.Cleanup(func() { test_utils.WrapCloseWithErrorCheck(w.Close, t) })
+	w.SetBackupRoutes(wikibackup.NewRoutes(nil, nil, w.AuthService()))
+	w.SetSnapshotRoutes(wikisnapshot.NewRoutes(nil, nil, w.AuthService(), 0))
+	w.SetRestoreRoutes(wikirestore.NewRoutes(nil, w.AuthService()))
+
+	return httpinternal.NewRouter(w.Registrars(), w.FrontendConfig(), httpinternal.RouterOptions{
+		AllowInsecure:           true,
+		AccessTokenTimeout:      15 * time.Minute,
+		RefreshTokenTimeout:     7 * 24 * time.Hour,
+		MaxAssetUploadSizeBytes: coreassets.DefaultMaxUploadSizeBytes,
+		EnableRevision:          true,
+		EnableLinkRefactor:      true,
+		APIKeyService:           w.APIKeyService(),
+		EnableAPIKeyManagement:  true,
+	})
+}
+
+var ginParam = regexp.MustCompile(`[:*]([A-Za-z]+)`)
+
+func TestSpec_DescribesExactlyTheRegisteredRoutes(t *testing.T) {
+	doc := loadDocument(t)
+	router := newFullRouter(t)
+
+	registered := map[string]bool{}
+	for _, route := range router.Routes() {
+		if !strings.HasPrefix(route.Path, "/api/") {
+			continue
+		}
+		key := strings.ToLower(route.Method) + " " + ginParam.ReplaceAllString(route.Path, "{$1}")
+		registered[key] = true
+		if _, ok := doc.Paths[ginParam.ReplaceAllString(route.Path, "{$1}")][strings.ToLower(route.Method)]; !ok {
+			t.Errorf("route %s %s is missing from openapi.json", route.Method, route.Path)
+		}
+	}
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			if method == "parameters" {
+				continue
+			}
+			if !registered[method+" "+path] {
+				t.Errorf("openapi.json describes %s %s, which is not registered", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestSpec_SchemasMatchGoTypes(t *testing.T) {
+	doc := loadDocument(t)
+	names := map[reflect.Type]string{}
+	for name, v := range schemaTypes {
+		names[reflect.TypeOf(v)] = name
+	}
+
+	for name, v := range schemaTypes {
+		s := doc.Components.Schemas[name]
+		if s == nil {
+			t.Errorf("schema %s is missing", name)
+			continue
+		}
+		fields := jsonFields(reflect.TypeOf(v))
+		var wantRequired []string
+		for _, f := range fields {
+			prop := s.Properties[f.name]
+			if prop == nil {
+				t.Errorf("%s: property %q is missing", name, f.name)
+				continue
+			}
+			if !f.omitempty {
+				wantRequired = append(wantRequired, f.name)
+			}
+			checkType(t, doc, names, name+"."+f.name, f.typ, prop)
+		}
+		for prop := range s.Properties {
+			if !slices.ContainsFunc(fields, func(f jsonField) bool { return f.name == prop }) {
+				t.Errorf("%s: property %q does not exist in %T", name, prop, v)
+			}
+		}
+		sort.Strings(wantRequired)
+		gotRequired := slices.Sorted(slices.Values(s.Required))
+		if !slices.Equal(gotRequired, wantRequired) {
+			t.Errorf("%s: required = %v, want the fields without omitempty %v", name, gotRequired, wantRequired)
+		}
+	}
+}
+
+type jsonField struct {
+	name      string
+	typ       reflect.Type
+	omitempty bool
+}
+
+// jsonFields returns the fields encoding/json writes for a struct, with the
+// fields of embedded structs promoted unless a shallower field has the name.
+func jsonFields(typ reflect.Type) []jsonField {
+	var fields []jsonField
+	var embedded []reflect.Type
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			embedded = append(embedded, ft)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, jsonField{name: name, typ: f.Type, omitempty: strings.Contains(opts, "omitempty")})
+	}
+	for _, et := range embedded {
+		for _, f := range jsonFields(et) {
+			if !slices.ContainsFunc(fields, func(existing jsonField) bool { return existing.name == f.name }) {
+				fields = append(fields, f)
+			}
+		}
+	}
+	return fields
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func checkType(t *testing.T, doc *document, names map[reflect.Type]string, at string, typ reflect.Type, s *schema) {
+	t.Helper()
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if s.Ref != "" {
+		refName := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+		if name, ok := names[typ]; ok {
+			if name != refName {
+				t.Errorf("%s: refers to %s, want %s", at, refName, name)
+			}
+			return
+		}
+		target := doc.Components.Schemas[refName]
+		if target == nil || typ.Kind() != reflect.String || !target.hasType("string") {
+			t.Errorf("%s: refers to %s, which does not describe %s", at, refName, typ)
+		}
+		return
+	}
+
+	var want string
+	switch {
+	case typ == timeType:
+		want = "string"
+	case typ.Kind() == reflect.Struct:
+		t.Errorf("%s: %s has no schema to refer to", at, typ)
+		return
+	case typ.Kind() == reflect.String:
+		want = "string"
+	case typ.Kind() == reflect.Bool:
+		want = "boolean"
+	case typ.Kind() >= reflect.Int && typ.Kind() <= reflect.Uint64:
+		want = "integer"
+	case typ.Kind() == reflect.Float32 || typ.Kind() == reflect.Float64:
+		want = "number"
+	case typ.Kind() == reflect.Slice:
+		want = "array"
+	case typ.Kind() == reflect.Map:
+		want = "object"
+	default:
+		t.Errorf("%s: unsupported Go type %s", at, typ)
+		return
+	}
+	if !s.hasType(want) {
+		t.Errorf("%s: type = %v, want %s for %s", at, s.Type, want, typ)
+		return
+	}
+	switch {
+	case want == "array" && s.Items == nil:
+		t.Errorf("%s: array without items", at)
+	case want == "array":
+		checkType(t, doc, names, at+"[]", typ.Elem(), s.Items)
+	case want == "object" && s.AdditionalProperties == nil:
+		t.Errorf("%s: map without additionalProperties", at)
+	case want == "object":
+		checkType(t, doc, names, at+"{}", typ.Elem(), s.AdditionalProperties)
+	}
+}
+
+// errorCodes collects the codes the handlers respond with: the ErrCode
+// constants of the domain packages and the codes of localized errors created
+// with a literal code.
+func errorCodes(t *testing.T) map[string]bool {
+	t.Helper()
+	codes := map[string]bool{"validation_error": true}
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(filepath.Join("..", ".."), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return err
+		}
+		file, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.ValueSpec:
+				for i, name := range n.Names {
+					if strings.HasPrefix(name.Name, "ErrCode") && i < len(n.Values) {
+						addLiteral(codes, n.Values[i])
+					}
+				}
+			case *ast.CallExpr:
+				if isCall(n, "NewLocalizedError") && len(n.Args) > 0 {
+					addLiteral(codes, n.Args[0])
+				}
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("scan sources: %v", err)
+	}
+	return codes
+}
+
+func isCall(call *ast.CallExpr, name string) bool {
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		return fun.Name == name
+	case *ast.SelectorExpr:
+		return fun.Sel.Name == name
+	}
+	return false
+}
+
+func addLiteral(codes map[string]bool, expr ast.Expr) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return
+	}
+	if code, err := strconv.Unquote(lit.Value); err == nil {
+		codes[code] = true
+	}
+}
+
+func TestSpec_ErrorCodeListsEveryCode(t *testing.T) {
+	doc := loadDocument(t)
+	enum := map[string]bool{}
+	for _, code := range doc.Components.Schemas["ErrorCode"].Enum {
+		enum[code] = true
+	}
+	codes := errorCodes(t)
+	for code := range codes {
+		if !enum[code] {
+			t.Errorf("error code %q is missing from the ErrorCode schema", code)
+		}
+	}
+	for code := range enum {
+		if !codes[code] {
+			t.Errorf("ErrorCode lists %q, which no handler uses", code)
+		}
+	}
+}
+
+func TestHandler_ServesSpecWithBasePathAsServer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/api/openapi.json", openapi.Handler("/wiki/"))
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var doc struct {
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+		Paths map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(doc.Servers) != 1 || doc.Servers[0].URL != "/wiki" {
+		t.Fatalf("servers = %+v, want /wiki", doc.Servers)
+	}
+	if doc.Paths["/api/pages/{id}"] == nil {
+		t.Fatalf("paths are missing from the served document")
+	}
+}
+
+func TestRouter_ServesOpenAPIDocument(t *testing.T) {
+	router := newFullRouter(t)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/json") {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	if !json.Valid(rec.Body.Bytes()) {
+		t.Fatalf("body is not JSON")
+	}
+}
@@ -18,6 +18,7 @@ import (
 	auth_middleware "github.com/perber/wiki/internal/http/middleware/auth"
 	"github.com/perber/wiki/internal/http/middleware/maintenance"
 	"github.com/perber/wiki/internal/http/middleware/security"
+	"github.com/perber/wiki/internal/http/openapi"
 	"github.com/perber/wiki/internal/restore"
 )
 
@@ -180,9 +181,8 @@ func NewRouter(registrars []RouteRegistrar, frontendCfg FrontendConfig, opts Rou
 		// exactly as powerful as role(user) ∩ role(key) everywhere the normal
 		// RequireAuth/RequireAdmin/RequireEditorOrAdmin guards apply — the
 		// same way roles work everywhere else in the app — rather than being
-		// restricted to a curated subset of read routes. Writes are, for now,
-		// separately blocked for Bearer callers by CSRFMiddleware (see
-		// InjectAPIKeyUser's doc comment).
+		// restricted to a curated subset of read routes. Writes need no CSRF
+		// token for Bearer callers (see InjectAPIKeyUser's doc comment).
 		base.Use(auth_middleware.InjectAPIKeyUser(auth_middleware.APIKeyConfig{
 			Service: opts.APIKeyService,
 			// Only failed attempts count toward the limit (NotifyResult
@@ -205,6 +205,9 @@ func NewRouter(registrars []RouteRegistrar, frontendCfg FrontendConfig, opts Rou
 		r.RegisterRoutes(ctx)
 	}
 
+	// Machine-readable description of the routes registered above.
+	base.GET("/api/openapi.json", openapi.Handler(opts.BasePath))
+
 	// Resolve custom stylesheet: prefer pre-validated FrontendConfig path,
 	// fall back to normalizing opts.CustomStylesheet against StorageDir.
 	customStylesheetPath := frontendCfg.CustomStylesheetPath
@@ -4118,6 +4118,199 @@ func TestIndexingStatusEndpoint(t *testing.T) {
 	}
 }
 
+func TestSearchSuggestEndpoint_CompletesTitlesAndRespectsPublicAccess(t *testing.T) {
+	w := createWikiTestInstance(t)
+	defer test_utils.WrapCloseWithErrorCheck(w.Close, t)
+	router := createRouterTestInstance(w, t)
+	createPageViaAPI(t, router, "Deployment Guide", "deployment-guide", nil, nil)
+	createPageViaAPI(t, router, "Release Notes", "release-notes", nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/search/suggest?q=depl", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 for an anonymous caller of a private wiki, got %d", rec.Code)
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/search/suggest?q=depl", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d - %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/x-suggestions+json") {
+		t.Errorf("Expected the OpenSearch suggestions media type, got %q", got)
+	}
+	want := `["depl",["Deployment Guide"],["deployment-guide"],["http://example.com/deployment-guide"]]`
+	if got := strings.TrimSpace(rec.Body.String()); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+
+	public := httpinternal.NewRouter(w.Registrars(), w.FrontendConfig(), httpinternal.RouterOptions{
+		PublicAccess:        true,
+		AllowInsecure:       true,
+		AccessTokenTimeout:  15 * time.Minute,
+		RefreshTokenTimeout: 7 * 24 * time.Hour,
+	})
+	rec = httptest.NewRecorder()
+	public.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/search/suggest?q=notes&limit=5", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `["Release Notes"]`) {
+		t.Fatalf("Expected anonymous suggestions on a public wiki, got %d - %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	public.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/search/suggest?q=notes&limit=100", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for a limit above the maximum, got %d", rec.Code)
+	}
+}
+
+func TestOpenSearchDescription_UsesBasePathAndSiteName(t *testing.T) {
+	w := createWikiTestInstance(t)
+	defer test_utils.WrapCloseWithErrorCheck(w.Close, t)
+	if err := w.BrandingService().UpdateBranding("Platform Team Handbook"); err != nil {
+		t.Fatalf("UpdateBranding failed: %v", err)
+	}
+	router := httpinternal.NewRouter(w.Registrars(), w.FrontendConfig(), httpinternal.RouterOptions{
+		AllowInsecure:       true,
+		AccessTokenTimeout:  15 * time.Minute,
+		RefreshTokenTimeout: 7 * 24 * time.Hour,
+		BasePath:            "/wiki",
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/wiki/opensearch.xml", nil)
+	req.Host = "docs.example.com"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK without credentials, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/opensearchdescription+xml") {
+		t.Errorf("Expected the OpenSearch description media type, got %q", got)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`<OpenSearchDescription xmlns="http://a9.com/-/spec/opensearch/1.1/">`,
+		// Short names are cut to the 16 characters the specification allows.
+		"<ShortName>Platform Team Ha</ShortName>",
+		"<Description>Search Platform Team Handbook</Description>",
+		`template="https://docs.example.com/wiki/?q={searchTerms}"`,
+		`template="https://docs.example.com/wiki/api/search/suggest?q={searchTerms}"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %s in the description, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestLinkGraphEndpoint_ReturnsNodesAndEdgesInEachFormat(t *testing.T) {
+	w := createWikiTestInstance(t)
+	defer test_utils.WrapCloseWithErrorCheck(w.Close, t)
+	router := createRouterTestInstance(w, t)
+
+	guides := createPageViaAPI(t, router, "Guides", "guides", nil, nil)
+	setup := createPageViaAPI(t, router, "Setup", "setup", &guides.ID, nil)
+	faq := createPageViaAPI(t, router, "FAQ", "faq", nil, nil)
+	for _, update := range []struct {
+		page    *apiPage
+		title   string
+		slug    string
+		content string
+	}{
+		{guides, "Guides", "guides", "See [Setup](/guides/setup) and [the old page](/guides/removed)."},
+		{setup, "Setup", "setup", "Questions? Read the [FAQ](/faq)."},
+	} {
+		body, _ := json.Marshal(map[string]string{
+			"version": update.page.Version,
+			"title":   update.title,
+			"slug":    update.slug,
+			"content": update.content,
+		})
+		rec := authenticatedRequest(t, router, http.MethodPut, "/api/pages/"+update.page.ID, strings.NewReader(string(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200 OK updating %s, got %d - %s", update.title, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/links/graph", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 for an anonymous caller of a private wiki, got %d", rec.Code)
+	}
+
+	type graph struct {
+		Nodes []struct {
+			ID   string `json:"id"`
+			Path string `json:"path"`
+			Kind string `json:"kind"`
+		} `json:"nodes"`
+		Edges []struct {
+			Source string `json:"source"`
+			Target string `json:"target"`
+			Broken bool   `json:"broken"`
+		} `json:"edges"`
+	}
+	getGraph := func(query string) graph {
+		t.Helper()
+		rec := authenticatedRequest(t, router, http.MethodGet, "/api/links/graph"+query, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200 OK for %q, got %d - %s", query, rec.Code, rec.Body.String())
+		}
+		var g graph
+		if err := json.Unmarshal(rec.Body.Bytes(), &g); err != nil {
+			t.Fatalf("Invalid JSON response: %v", err)
+		}
+		return g
+	}
+
+	// The new wiki's welcome page is a node of the whole graph too.
+	whole := getGraph("?broken=true")
+	if len(whole.Nodes) != 5 || len(whole.Edges) != 3 {
+		t.Fatalf("Expected 4 pages, 1 missing page and 3 edges, got %+v", whole)
+	}
+	if missing := whole.Nodes[4]; missing.Kind != "missing" || missing.ID != "/guides/removed" {
+		t.Errorf("Expected the broken link target as the last node, got %+v", missing)
+	}
+
+	subtree := getGraph("?root=" + guides.ID)
+	if len(subtree.Nodes) != 2 || len(subtree.Edges) != 1 ||
+		subtree.Edges[0].Source != guides.ID || subtree.Edges[0].Target != setup.ID {
+		t.Errorf("Expected the Guides subtree with the one link inside it, got %+v", subtree)
+	}
+
+	around := getGraph("?page=" + faq.ID + "&depth=1")
+	if len(around.Nodes) != 2 || around.Nodes[0].ID != faq.ID || around.Nodes[1].ID != setup.ID {
+		t.Errorf("Expected FAQ and the page linking to it, got %+v", around)
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/links/graph?format=dot", nil)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/vnd.graphviz") {
+		t.Fatalf("Expected a DOT graph, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if want := `"` + setup.ID + `" -> "` + faq.ID + `";`; !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("Expected %s in the DOT graph, got:\n%s", want, rec.Body.String())
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/links/graph?format=graphml", nil)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "application/graphml+xml") {
+		t.Fatalf("Expected a GraphML graph, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if want := `<edge source="` + guides.ID + `" target="` + setup.ID + `">`; !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("Expected %s in the GraphML graph, got:\n%s", want, rec.Body.String())
+	}
+
+	for _, query := range []string{"?format=svg", "?root=" + guides.ID + "&page=" + faq.ID, "?page=" + faq.ID + "&depth=9"} {
+		rec = authenticatedRequest(t, router, http.MethodGet, "/api/links/graph"+query, nil)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %q, got %d", query, rec.Code)
+		}
+	}
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/links/graph?page=missing-id", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown page, got %d", rec.Code)
+	}
+}
+
 // uploadTestAsset is a helper function that creates a page, uploads an asset, and returns the asset URL and auth cookies.
 // If needsAuth is true, it will obtain authentication cookies; otherwise it will get CSRF token only (for AuthDisabled mode).
 func uploadTestAsset(t *testing.T, router *gin.Engine, w *wiki.Wiki, content string, needsAuth bool) (assetURL string, cookies []*http.Cookie) {
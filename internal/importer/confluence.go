@@ -0,0 +1,713 @@
+package importer
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/perber/wiki/internal/core/markdown"
+	"github.com/perber/wiki/internal/core/tree"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// A Confluence HTML space export ("Export space" → HTML) is a folder with
+// one HTML file per page, index.html listing the page tree under
+// "Available Pages" and the attachments in attachments/<page id>/.
+
+const (
+	confluenceIndexFile       = "index.html"
+	confluenceAttachmentsDir  = "attachments"
+	confluenceThumbnailsDir   = "thumbnails"
+	confluenceAvailablePages  = "Available Pages"
+	confluenceGeneratorMarker = "Confluence"
+)
+
+var (
+	confluencePageIDPattern = regexp.MustCompile(`(?:^|_)(\d+)\.html$`)
+	confluenceDisplayPath   = regexp.MustCompile(`/display/[^/]+/([^/?#]+)$`)
+	confluenceBrushPattern  = regexp.MustCompile(`brush:\s*([\w#+-]+)`)
+)
+
+// confluenceCalloutTypes maps the information macros (info, tip, note,
+// warning) to callout types by their class: confluence-information-macro-<type>
+// in current exports, aui-message <type> in older ones.
+var (
+	confluenceCalloutTypes = map[string]string{
+		"confluence-information-macro-information": "info",
+		"confluence-information-macro-tip":         "success",
+		"confluence-information-macro-note":        "warning",
+		"confluence-information-macro-warning":     "danger",
+	}
+	confluenceLegacyCalloutTypes = map[string]string{
+		"hint":    "info",
+		"success": "success",
+		"warning": "warning",
+		"problem": "danger",
+	}
+)
+
+// confluenceCodeLanguages maps syntax highlighter brushes to the fence
+// languages of the editor.
+var confluenceCodeLanguages = map[string]string{
+	"none":  "",
+	"text":  "",
+	"plain": "",
+	"js":    "javascript",
+	"py":    "python",
+	"ps":    "powershell",
+	"c#":    "csharp",
+	"shell": "bash",
+}
+
+// confluenceEmoticons maps emoticon names to emoji.
+var confluenceEmoticons = map[string]string{
+	"smile":        "🙂",
+	"sad":          "🙁",
+	"cheeky":       "😛",
+	"laugh":        "😃",
+	"wink":         "😉",
+	"thumbs-up":    "👍",
+	"thumbs-down":  "👎",
+	"information":  "ℹ️",
+	"tick":         "✅",
+	"cross":        "❌",
+	"warning":      "⚠️",
+	"plus":         "➕",
+	"minus":        "➖",
+	"question":     "❓",
+	"light-on":     "💡",
+	"light-off":    "💡",
+	"yellow-star":  "⭐",
+	"red-star":     "⭐",
+	"green-star":   "⭐",
+	"blue-star":    "⭐",
+	"heart":        "❤️",
+	"broken-heart": "💔",
+}
+
+// confluenceHandledMacros are converted (or deliberately ignored) without
+// a note; other macros are reported.
+var confluenceHandledMacros = map[string]bool{
+	"info":        true,
+	"note":        true,
+	"tip":         true,
+	"warning":     true,
+	"panel":       true,
+	"code":        true,
+	"noformat":    true,
+	"expand":      true,
+	"status":      true,
+	"anchor":      true,
+	"jira":        true,
+	"children":    true,
+	"excerpt":     true,
+	"section":     true,
+	"column":      true,
+	"details":     true,
+	"view-file":   true,
+	"attachments": true,
+}
+
+// DetectConfluenceExport reports whether root holds an extracted Confluence
+// HTML space export and returns its space folder: root itself or its only
+// top-level folder, which is how Confluence packs the export.
+func DetectConfluenceExport(root string) (string, bool) {
+	return findExportDir(root, isConfluenceSpaceDir)
+}
+
+func isConfluenceSpaceDir(dir string) bool {
+	raw, err := os.ReadFile(filepath.Join(dir, confluenceIndexFile))
+	if err != nil {
+		return false
+	}
+	content := string(raw)
+	return strings.Contains(content, confluenceGeneratorMarker) && strings.Contains(content, confluenceAvailablePages)
+}
+
+// confluencePage is one page of the export.
+type confluencePage struct {
+	packageNode[*confluencePage]
+	// id is the Confluence page ID from the file name (may be empty).
+	id string
+	// file is the HTML file relative to the space folder.
+	file   string
+	parent *confluencePage
+	// assets maps attachment source paths to file names in the sidecar
+	// folder of the page.
+	assets map[string]string
+	notes  []string
+}
+
+func (p *confluencePage) addNote(format string, args ...any) {
+	note := fmt.Sprintf(format, args...)
+	for _, existing := range p.notes {
+		if existing == note {
+			return
+		}
+	}
+	p.notes = append(p.notes, note)
+}
+
+type confluenceConverter struct {
+	spaceDir string
+	outDir   string
+	slugger  *tree.SlugService
+	pages    []*confluencePage
+	roots    []*confluencePage
+	byFile   map[string]*confluencePage
+	byID     map[string]*confluencePage
+	byTitle  map[string]*confluencePage
+	// aliases maps attachment source paths to their original file names.
+	aliases map[string]string
+}
+
+// ConvertConfluenceExport converts a Confluence HTML space export into a
+// Markdown package in outDir that the planner imports like any other:
+// the page tree of index.html becomes the folder hierarchy (pages with
+// children become sections), attachments are stored in the sidecar folder
+// of their page and links between pages are rewritten to the Markdown
+// files. The entries are returned in tree order; macros that cannot be
+// converted are reported as notes of their page.
+func ConvertConfluenceExport(spaceDir, outDir string) ([]ImportMDFile, error) {
+	cv := &confluenceConverter{
+		spaceDir: spaceDir,
+		outDir:   outDir,
+		slugger:  tree.NewSlugService(),
+		byFile:   map[string]*confluencePage{},
+		byID:     map[string]*confluencePage{},
+		byTitle:  map[string]*confluencePage{},
+		aliases:  map[string]string{},
+	}
+	if err := cv.loadPages(); err != nil {
+		return nil, err
+	}
+	if err := cv.buildTree(); err != nil {
+		return nil, err
+	}
+	assignPackagePaths(cv.slugger, "", cv.roots, func(page *confluencePage) bool { return len(page.children) > 0 })
+
+	var entries []ImportMDFile
+	for _, page := range packageTreeOrder(cv.roots) {
+		if err := cv.convertPage(page); err != nil {
+			return nil, fmt.Errorf("convert %s: %w", page.file, err)
+		}
+		entries = append(entries, ImportMDFile{SourcePath: page.mdPath, Notes: page.notes})
+	}
+	return entries, nil
+}
+
+// loadPages parses every page of the export.
+func (cv *confluenceConverter) loadPages() error {
+	entries, err := os.ReadDir(cv.spaceDir)
+	if err != nil {
+		return fmt.Errorf("read confluence export: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.EqualFold(path.Ext(name), ".html") || strings.EqualFold(name, confluenceIndexFile) {
+			continue
+		}
+		doc, err := parseHTMLFile(filepath.Join(cv.spaceDir, name))
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", name, err)
+		}
+		// Documents are parsed again when converted so that large exports
+		// are not held in memory at once.
+		page := &confluencePage{file: name, assets: map[string]string{}}
+		page.title = confluencePageTitle(doc, name)
+		if m := confluencePageIDPattern.FindStringSubmatch(name); m != nil {
+			page.id = m[1]
+			page.slugFallback = "page-" + page.id
+			cv.byID[page.id] = page
+		}
+		cv.pages = append(cv.pages, page)
+		cv.byFile[strings.ToLower(name)] = page
+		cv.collectAliases(doc)
+	}
+	return nil
+}
+
+// confluencePageTitle reads the title of a page; the heading repeats the
+// space name ("Space : Title"), which is removed.
+func confluencePageTitle(doc *html.Node, file string) string {
+	for _, find := range []func(*html.Node) bool{
+		func(e *html.Node) bool { return htmlAttr(e, "id") == "title-text" },
+		func(e *html.Node) bool { return e.DataAtom == atom.Title },
+	} {
+		if n := findHTMLElement(doc, find); n != nil {
+			title := strings.TrimSpace(collapseHTMLSpace(htmlText(n)))
+			if _, after, found := strings.Cut(title, " : "); found {
+				title = strings.TrimSpace(after)
+			}
+			if title != "" {
+				return title
+			}
+		}
+	}
+	name := strings.TrimSuffix(file, path.Ext(file))
+	if m := confluencePageIDPattern.FindStringSubmatchIndex(file); m != nil && m[0] > 0 {
+		name = file[:m[0]]
+	}
+	return strings.ReplaceAll(name, "-", " ")
+}
+
+// collectAliases records the original file names of attachments, which the
+// export stores under their attachment ID.
+func (cv *confluenceConverter) collectAliases(doc *html.Node) {
+	for _, n := range findHTMLElements(doc, func(e *html.Node) bool { return e.DataAtom == atom.A || e.DataAtom == atom.Img }) {
+		ref := htmlAttr(n, "href")
+		if n.DataAtom == atom.Img {
+			ref = htmlAttr(n, "data-image-src")
+			if ref == "" {
+				ref = htmlAttr(n, "src")
+			}
+		}
+		src, ok := confluenceAttachmentSource(ref)
+		if !ok {
+			continue
+		}
+		alias := htmlAttr(n, "data-linked-resource-default-alias")
+		if alias == "" && n.DataAtom == atom.A && cv.aliases[src] == "" {
+			// Links of the attachments section are labeled with the file name.
+			if text := strings.TrimSpace(collapseHTMLSpace(htmlText(n))); path.Ext(text) != "" {
+				alias = text
+			}
+		}
+		if alias != "" {
+			cv.aliases[src] = alias
+		}
+	}
+}
+
+// buildTree rebuilds the page hierarchy from the "Available Pages" tree of
+// index.html. Pages missing from it are added at the top level.
+func (cv *confluenceConverter) buildTree() error {
+	index, err := parseHTMLFile(filepath.Join(cv.spaceDir, confluenceIndexFile))
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", confluenceIndexFile, err)
+	}
+
+	placed := map[*confluencePage]bool{}
+	if list := confluencePageTreeList(index); list != nil {
+		cv.roots = cv.readTreeList(list, nil, placed)
+	}
+
+	var orphans []*confluencePage
+	for _, page := range cv.pages {
+		if !placed[page] {
+			page.addNote("Page is not part of the page tree of the Confluence export and was imported at the top level")
+			orphans = append(orphans, page)
+		}
+	}
+	sort.SliceStable(orphans, func(i, j int) bool { return orphans[i].title < orphans[j].title })
+	cv.roots = append(cv.roots, orphans...)
+
+	for _, page := range cv.pages {
+		key := strings.ToLower(page.title)
+		if _, exists := cv.byTitle[key]; !exists {
+			cv.byTitle[key] = page
+		}
+	}
+	return nil
+}
+
+// confluencePageTreeList finds the list below the "Available Pages" heading.
+func confluencePageTreeList(index *html.Node) *html.Node {
+	heading := findHTMLElement(index, func(e *html.Node) bool {
+		switch e.DataAtom {
+		case atom.H1, atom.H2, atom.H3, atom.H4:
+			return strings.Contains(htmlText(e), confluenceAvailablePages)
+		}
+		return false
+	})
+	if heading == nil {
+		return nil
+	}
+	// The heading is wrapped in a section header; the list follows it.
+	for scope := heading; scope != nil; scope = scope.Parent {
+		for sibling := scope.NextSibling; sibling != nil; sibling = sibling.NextSibling {
+			if sibling.Type != html.ElementNode {
+				continue
+			}
+			if sibling.DataAtom == atom.Ul {
+				return sibling
+			}
+			if list := findHTMLElement(sibling, func(e *html.Node) bool { return e.DataAtom == atom.Ul }); list != nil {
+				return list
+			}
+		}
+		if htmlHasClass(scope, "pageSection") {
+			break
+		}
+	}
+	return nil
+}
+
+func (cv *confluenceConverter) readTreeList(list *html.Node, parent *confluencePage, placed map[*confluencePage]bool) []*confluencePage {
+	var pages []*confluencePage
+	for li := list.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.DataAtom != atom.Li {
+			continue
+		}
+		link := findHTMLElement(li, func(e *html.Node) bool {
+			return e.DataAtom == atom.A || e.DataAtom == atom.Ul
+		})
+		var page *confluencePage
+		if link != nil && link.DataAtom == atom.A {
+			target, _ := splitURLSuffix(htmlAttr(link, "href"))
+			page = cv.byFile[strings.ToLower(decodeImportTarget(target))]
+		}
+		nested := findHTMLElement(li, func(e *html.Node) bool { return e.DataAtom == atom.Ul })
+		if page == nil || placed[page] {
+			// Keep the children of entries without a page file.
+			if nested != nil {
+				pages = append(pages, cv.readTreeList(nested, parent, placed)...)
+			}
+			continue
+		}
+		placed[page] = true
+		page.parent = parent
+		if title := strings.TrimSpace(collapseHTMLSpace(htmlText(link))); title != "" {
+			page.title = title
+		}
+		if nested != nil {
+			page.children = cv.readTreeList(nested, page, placed)
+		}
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+func (cv *confluenceConverter) convertPage(page *confluencePage) error {
+	doc, err := parseHTMLFile(filepath.Join(cv.spaceDir, page.file))
+	if err != nil {
+		return err
+	}
+	content := findHTMLElement(doc, func(e *html.Node) bool { return htmlAttr(e, "id") == "main-content" })
+	if content == nil {
+		content = findHTMLElement(doc, func(e *html.Node) bool { return e.DataAtom == atom.Body })
+	}
+	if content == nil {
+		content = doc
+	}
+
+	var convErr error
+	body := convertHTMLToMarkdown(content, htmlMarkdownOptions{
+		Link: func(href string, image bool) (string, bool) {
+			dest, ok, err := cv.rewriteLink(page, href, image)
+			if err != nil && convErr == nil {
+				convErr = err
+			}
+			return dest, ok
+		},
+		Element: func(c *htmlMarkdownConverter, n *html.Node) (string, bool, bool) {
+			return cv.convertElement(page, c, n)
+		},
+	})
+	if convErr != nil {
+		return convErr
+	}
+
+	// Attachments that are not used in the content are imported as well.
+	for _, n := range confluenceAttachmentLinks(doc) {
+		if src, ok := confluenceAttachmentSource(htmlAttr(n, "href")); ok {
+			if _, err := cv.attachment(page, src); err != nil {
+				return err
+			}
+		}
+	}
+
+	md, err := markdown.BuildMarkdownWithExtraFrontmatter(map[string]interface{}{"leafwiki_title": page.title}, body+"\n")
+	if err != nil {
+		return err
+	}
+	out := filepath.Join(cv.outDir, filepath.FromSlash(page.mdPath))
+	if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(out, []byte(md), 0o644)
+}
+
+// confluenceAttachmentLinks returns the links of the attachments section
+// at the bottom of a page.
+func confluenceAttachmentLinks(doc *html.Node) []*html.Node {
+	heading := findHTMLElement(doc, func(e *html.Node) bool { return htmlAttr(e, "id") == "attachments" })
+	if heading == nil {
+		return nil
+	}
+	section := heading
+	for section.Parent != nil && !htmlHasClass(section, "pageSection") {
+		section = section.Parent
+	}
+	return findHTMLElements(section, func(e *html.Node) bool { return e.DataAtom == atom.A })
+}
+
+// rewriteLink maps links and images of a page into the converted package.
+func (cv *confluenceConverter) rewriteLink(page *confluencePage, href string, image bool) (string, bool, error) {
+	if strings.HasPrefix(href, "#") {
+		return href, true, nil
+	}
+	if src, ok := confluenceAttachmentSource(href); ok {
+		file, err := cv.attachment(page, src)
+		if err != nil || file == "" {
+			return "", false, err
+		}
+		return relativePackagePath(page.mdPath, file), true, nil
+	}
+	if target := cv.linkedPage(href); target != nil {
+		return relativePackagePath(page.mdPath, target.mdPath), true, nil
+	}
+	if isExternalHref(href) {
+		return href, true, nil
+	}
+	// Icons, user profiles and other pages of the Confluence instance do
+	// not exist in the wiki.
+	return "", false, nil
+}
+
+// linkedPage resolves a link to a page of the export: a page file or a
+// link to the Confluence instance (…?pageId=123, /display/SPACE/Title).
+func (cv *confluenceConverter) linkedPage(href string) *confluencePage {
+	u, err := url.Parse(href)
+	if err != nil {
+		return nil
+	}
+	if id := u.Query().Get("pageId"); id != "" {
+		return cv.byID[id]
+	}
+	if u.Scheme == "" && u.Host == "" {
+		if page := cv.byFile[strings.ToLower(path.Base(u.Path))]; page != nil && !strings.Contains(u.Path, "/") {
+			return page
+		}
+	}
+	if m := confluenceDisplayPath.FindStringSubmatch(u.Path); m != nil {
+		title, err := url.QueryUnescape(m[1])
+		if err == nil {
+			return cv.byTitle[strings.ToLower(title)]
+		}
+	}
+	return nil
+}
+
+// confluenceAttachmentSource returns the normalized path of an attachment
+// reference (attachments/<page id>/<file>), mapping thumbnails to the
+// attachment itself.
+func confluenceAttachmentSource(ref string) (string, bool) {
+	target, _ := splitURLSuffix(strings.TrimSpace(ref))
+	target = strings.TrimPrefix(decodeImportTarget(target), "./")
+	parts := strings.Split(target, "/")
+	if len(parts) < 3 || parts[0] != confluenceAttachmentsDir {
+		return "", false
+	}
+	if parts[1] == confluenceThumbnailsDir {
+		parts = append(parts[:1], parts[2:]...)
+	}
+	if len(parts) != 3 || parts[1] == "" || parts[2] == "" || parts[2] == ".." {
+		return "", false
+	}
+	return path.Join(parts...), true
+}
+
+// attachment copies an attachment into the sidecar folder of the page it
+// belongs to (by its page ID, else the linking page) and returns its path
+// in the package. Missing files are noted and return "".
+func (cv *confluenceConverter) attachment(from *confluencePage, src string) (string, error) {
+	owner := cv.byID[strings.Split(src, "/")[1]]
+	if owner == nil {
+		owner = from
+	}
+	sidecar := SidecarAssetsDir(owner.mdPath)
+	if name, ok := owner.assets[src]; ok {
+		return path.Join(sidecar, name), nil
+	}
+
+	srcAbs := filepath.Join(cv.spaceDir, filepath.FromSlash(src))
+	info, err := os.Stat(srcAbs)
+	if err != nil || !info.Mode().IsRegular() {
+		from.addNote("Attachment %q is missing from the Confluence export", cv.attachmentName(src))
+		return "", nil
+	}
+
+	name := uniqueAssetName(cv.attachmentName(src), owner.assets)
+	owner.assets[src] = name
+
+	dest := filepath.Join(cv.outDir, filepath.FromSlash(sidecar), name)
+	if err := copyFile(srcAbs, dest); err != nil {
+		return "", fmt.Errorf("copy attachment %s: %w", src, err)
+	}
+	return path.Join(sidecar, name), nil
+}
+
+// attachmentName is the original file name of an attachment; the export
+// stores it under its ID.
+func (cv *confluenceConverter) attachmentName(src string) string {
+	name := cv.aliases[src]
+	if name == "" {
+		name = path.Base(src)
+	}
+	name = strings.NewReplacer("/", "-", `\`, "-").Replace(strings.TrimSpace(name))
+	if name == "" || strings.HasPrefix(name, ".") {
+		name = "attachment" + name
+	}
+	return name
+}
+
+// convertElement converts Confluence macros and reports the ones that are
+// not supported.
+func (cv *confluenceConverter) convertElement(page *confluencePage, c *htmlMarkdownConverter, n *html.Node) (string, bool, bool) {
+	switch {
+	case htmlHasClass(n, "confluence-information-macro") || htmlHasClass(n, "information-macro"):
+		return cv.informationMacro(c, n), false, true
+	case n.DataAtom == atom.Div && htmlHasClass(n, "code") && htmlHasClass(n, "panel"):
+		return cv.codeMacro(c, n), false, true
+	case n.DataAtom == atom.Pre && (htmlHasAttr(n, "data-syntaxhighlighter-params") || strings.Contains(htmlAttr(n, "class"), "brush:")):
+		return markdownCodeFence(htmlText(n), confluenceCodeLanguage(n)), false, true
+	case n.DataAtom == atom.Div && htmlHasClass(n, "panel") && !htmlHasClass(n, "preformatted"):
+		return cv.panelMacro(c, n), false, true
+	case htmlHasClass(n, "expand-container"):
+		return cv.expandMacro(c, n), false, true
+	case htmlHasClass(n, "toc-macro") || htmlHasClass(n, "client-side-toc-macro") || htmlAttr(n, "data-macro-name") == "toc":
+		page.addNote("Confluence table of contents macro was removed")
+		return "", false, true
+	case htmlHasClass(n, "status-macro"):
+		return wrapMarkdownInline("**", c.inline(n)), true, true
+	case htmlHasClass(n, "confluence-anchor-link"):
+		return "", true, true
+	case n.DataAtom == atom.Img && htmlHasClass(n, "emoticon"):
+		if emoji, ok := confluenceEmoticons[htmlAttr(n, "data-emoticon-name")]; ok {
+			return emoji, true, true
+		}
+		return escapeMarkdownText(htmlAttr(n, "alt")), true, true
+	case n.DataAtom == atom.Ul && htmlHasClass(n, "inline-task-list"):
+		return cv.taskList(c, n), false, true
+	}
+
+	name := htmlAttr(n, "data-macro-name")
+	if name == "" || confluenceHandledMacros[name] {
+		return "", false, false
+	}
+	// Unknown macros keep whatever content the export rendered for them.
+	md := c.blocks(n)
+	if strings.TrimSpace(md) == "" {
+		page.addNote("Confluence macro %q could not be converted and was removed", name)
+		return "", false, true
+	}
+	page.addNote("Confluence macro %q is not supported; only its rendered content was imported", name)
+	return md, false, true
+}
+
+func (cv *confluenceConverter) informationMacro(c *htmlMarkdownConverter, n *html.Node) string {
+	types := confluenceCalloutTypes
+	if htmlHasClass(n, "aui-message") {
+		types = confluenceLegacyCalloutTypes
+	}
+	kind := "info"
+	for _, class := range strings.Fields(htmlAttr(n, "class")) {
+		if mapped, ok := types[class]; ok {
+			kind = mapped
+			break
+		}
+	}
+	title := ""
+	if t := findHTMLElement(n, func(e *html.Node) bool { return htmlHasClass(e, "title") }); t != nil {
+		title = finishInline(c.inline(t), " ")
+	}
+	body := findHTMLElement(n, func(e *html.Node) bool {
+		return htmlHasClass(e, "confluence-information-macro-body") || htmlHasClass(e, "message-content")
+	})
+	if body == nil {
+		body = n
+	}
+	return markdownCallout(kind, title, c.blocksExcept(body, func(e *html.Node) bool {
+		return e.Type == html.ElementNode && (htmlHasClass(e, "title") || htmlHasClass(e, "aui-icon"))
+	}))
+}
+
+func (cv *confluenceConverter) panelMacro(c *htmlMarkdownConverter, n *html.Node) string {
+	title := ""
+	if header := findHTMLElement(n, func(e *html.Node) bool { return htmlHasClass(e, "panelHeader") }); header != nil {
+		title = finishInline(c.inline(header), " ")
+	}
+	body := findHTMLElement(n, func(e *html.Node) bool { return htmlHasClass(e, "panelContent") })
+	if body == nil {
+		body = n
+	}
+	return markdownCallout("info", title, c.blocks(body))
+}
+
+func (cv *confluenceConverter) codeMacro(c *htmlMarkdownConverter, n *html.Node) string {
+	pre := findHTMLElement(n, func(e *html.Node) bool { return e.DataAtom == atom.Pre })
+	if pre == nil {
+		return c.blocks(n)
+	}
+	code := markdownCodeFence(htmlText(pre), confluenceCodeLanguage(pre))
+	if header := findHTMLElement(n, func(e *html.Node) bool { return htmlHasClass(e, "codeHeader") }); header != nil {
+		// The header is bold already; only its text is kept.
+		if title := finishInline(escapeMarkdownText(collapseHTMLSpace(htmlText(header))), " "); title != "" {
+			return "**" + title + "**\n\n" + code
+		}
+	}
+	return code
+}
+
+func confluenceCodeLanguage(pre *html.Node) string {
+	params := htmlAttr(pre, "data-syntaxhighlighter-params")
+	if params == "" {
+		params = htmlAttr(pre, "class")
+	}
+	m := confluenceBrushPattern.FindStringSubmatch(params)
+	if m == nil {
+		return htmlCodeLanguage(pre)
+	}
+	brush := strings.ToLower(m[1])
+	if lang, ok := confluenceCodeLanguages[brush]; ok {
+		return lang
+	}
+	return brush
+}
+
+func (cv *confluenceConverter) expandMacro(c *htmlMarkdownConverter, n *html.Node) string {
+	title := "Details"
+	if control := findHTMLElement(n, func(e *html.Node) bool { return htmlHasClass(e, "expand-control-text") }); control != nil {
+		if text := finishInline(c.inline(control), " "); text != "" {
+			title = text
+		}
+	}
+	body := findHTMLElement(n, func(e *html.Node) bool { return htmlHasClass(e, "expand-content") })
+	if body == nil {
+		body = n
+	}
+	return markdownCallout("collapsed", title, c.blocks(body))
+}
+
+// taskList converts Confluence task lists, which mark done items with the
+// class "checked" instead of a checkbox.
+func (cv *confluenceConverter) taskList(c *htmlMarkdownConverter, n *html.Node) string {
+	var items []string
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- [ ] "
+		if htmlHasClass(li, "checked") {
+			marker = "- [x] "
+		}
+		items = append(items, c.listItem(li, marker))
+	}
+	return strings.Join(items, "\n")
+}
+
+func parseHTMLFile(p string) (*html.Node, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return html.Parse(f)
+}
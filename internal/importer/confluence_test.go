@@ -0,0 +1,120 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/perber/wiki/internal/test_utils"
+)
+
+func confluenceFixtureDir(t *testing.T) string {
+	t.Helper()
+	return test_utils.FixturePath(t, "confluence-space-export", "fixtures", "internal/importer/fixtures")
+}
+
+func TestDetectConfluenceExport_FindsSpaceFolder(t *testing.T) {
+	spaceDir, ok := DetectConfluenceExport(confluenceFixtureDir(t))
+	if !ok || filepath.Base(spaceDir) != "DOCS" {
+		t.Fatalf("DetectConfluenceExport = %q, %v", spaceDir, ok)
+	}
+
+	tmp := t.TempDir()
+	test_utils.WriteFile(t, tmp, "docs/index.html", "<html><body>Available Pages</body></html>")
+	if _, ok := DetectConfluenceExport(tmp); ok {
+		t.Fatalf("expected plain HTML package not to be detected as Confluence export")
+	}
+}
+
+func TestConvertConfluenceExport_BuildsTreeFromNavigation(t *testing.T) {
+	out := t.TempDir()
+	entries, err := ConvertConfluenceExport(filepath.Join(confluenceFixtureDir(t), "DOCS"), out)
+	if err != nil {
+		t.Fatalf("ConvertConfluenceExport err: %v", err)
+	}
+
+	var paths []string
+	notes := map[string][]string{}
+	for _, entry := range entries {
+		paths = append(paths, entry.SourcePath)
+		notes[entry.SourcePath] = entry.Notes
+	}
+	want := []string{
+		"docs-home/index.md",
+		"docs-home/getting-started/index.md",
+		"docs-home/getting-started/install-guide.md",
+		"docs-home/faq.md",
+		"old-notes.md",
+	}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Fatalf("entries = %v, want %v", paths, want)
+	}
+
+	if len(notes["old-notes.md"]) != 1 || !strings.Contains(notes["old-notes.md"][0], "not part of the page tree") {
+		t.Fatalf("orphan page notes = %#v", notes["old-notes.md"])
+	}
+	gettingStartedNotes := strings.Join(notes["docs-home/getting-started/index.md"], "\n")
+	for _, expected := range []string{
+		"table of contents macro was removed",
+		`macro "roadmap" could not be converted`,
+		`macro "excerpt-include" is not supported`,
+	} {
+		if !strings.Contains(gettingStartedNotes, expected) {
+			t.Fatalf("expected note %q, got:\n%s", expected, gettingStartedNotes)
+		}
+	}
+	if len(notes["docs-home/faq.md"]) != 0 {
+		t.Fatalf("unexpected notes for faq: %#v", notes["docs-home/faq.md"])
+	}
+
+	read := func(rel string) string {
+		t.Helper()
+		raw, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(rel)))
+		if err != nil {
+			t.Fatalf("read %s: %v", rel, err)
+		}
+		return string(raw)
+	}
+
+	gettingStarted := read("docs-home/getting-started/index.md")
+	for _, expected := range []string{
+		"leafwiki_title: Getting Started",
+		"## Overview",
+		"[install guide](install-guide.md)",
+		"[FAQ](../faq.md) 🙂",
+		":::info\nInstallation takes about *five minutes*.\n:::",
+		":::warning Before you start\nBack up your data.\n:::",
+		"**Main.java**\n\n```java\npublic class Main {\n    // prints *stars*\n}\n```",
+		"| Option | Default |\n| --- | --- |\n| port | `8080` |\n| mode | a \\| b |",
+		"![](index.assets/diagram.png)",
+		":::collapsed Show details\n- First\n- Second\n  - Nested\n:::",
+		"- [x] Download\n- [ ] Configure",
+		"Status: **DONE**",
+		"Included text.",
+	} {
+		if !strings.Contains(gettingStarted, expected) {
+			t.Fatalf("expected getting started page to contain %q, got:\n%s", expected, gettingStarted)
+		}
+	}
+
+	install := read("docs-home/getting-started/install-guide.md")
+	for _, expected := range []string{
+		"1. Download the [handbook](index.assets/handbook.pdf).",
+		"Back to [Getting Started](index.md).",
+	} {
+		if !strings.Contains(install, expected) {
+			t.Fatalf("expected install guide to contain %q, got:\n%s", expected, install)
+		}
+	}
+	if faq := read("docs-home/faq.md"); !strings.Contains(faq, "[external help](https://example.com/help) or Jane.") {
+		t.Fatalf("expected external link to stay and user link to be unwrapped, got:\n%s", faq)
+	}
+
+	if got := read("docs-home/getting-started/index.assets/diagram.png"); got != "PNG" {
+		t.Fatalf("diagram attachment = %q", got)
+	}
+	if got := read("docs-home/getting-started/index.assets/handbook.pdf"); got != "PDF" {
+		t.Fatalf("handbook attachment = %q", got)
+	}
+}
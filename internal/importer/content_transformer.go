@@ -71,193 +71,111 @@ func (t *contentTransformer) TransformContent(
 	content string,
 	wiki ImporterWiki,
 ) (string, error) {
-	rewritten, err := rewriteOutsideCodeSpans(content, func(segment string) (string, error) {
-		return t.rewriteMarkdownLinks(userID, sourcePath, page, segment, wiki)
+	rewritten, err := RewriteMarkdownLinks(content, func(link MarkdownLink) (string, error) {
+		return t.rewriteMarkdownLink(userID, sourcePath, page, link, wiki)
 	})
 	if err != nil {
 		return "", err
 	}
-	return rewriteOutsideCodeSpans(rewritten, func(segment string) (string, error) {
-		return t.rewriteWikiLinks(userID, sourcePath, page, segment, wiki)
+	return RewriteWikiLinks(rewritten, func(link WikiLink) (string, error) {
+		return t.rewriteWikiLink(userID, sourcePath, page, link, wiki)
 	})
 }
 
-// rewriteMarkdownLinks rewrites regular Markdown links and images in non-code segments only.
-func (t *contentTransformer) rewriteMarkdownLinks(
+// rewriteMarkdownLink rewrites the destination of a regular Markdown link or image.
+func (t *contentTransformer) rewriteMarkdownLink(
 	userID string,
 	sourcePath string,
 	page *tree.Page,
-	content string,
+	link MarkdownLink,
 	wiki ImporterWiki,
 ) (string, error) {
-	var out strings.Builder
-	for i := 0; i < len(content); i++ {
-		if content[i] != '[' && (content[i] != '!' || i+1 >= len(content) || content[i+1] != '[') {
-			out.WriteByte(content[i])
-			continue
-		}
-
-		start := i
-		isImage := false
-		if content[i] == '!' {
-			isImage = true
-			i++
-		}
-
-		labelEnd := strings.IndexByte(content[i:], ']')
-		if labelEnd < 0 {
-			out.WriteString(content[start:])
-			return out.String(), nil
-		}
-		labelEnd += i
-		if labelEnd+1 >= len(content) || content[labelEnd+1] != '(' {
-			out.WriteString(content[start : labelEnd+1])
-			i = labelEnd
-			continue
-		}
-
-		destStart := labelEnd + 2
-		destEnd := findMarkdownLinkDestinationEnd(content, destStart)
-		if destEnd < 0 {
-			out.WriteString(content[start:])
-			return out.String(), nil
-		}
-
-		destination := content[destStart:destEnd]
-		rewritten, err := t.rewriteDestination(userID, sourcePath, page, destination, wiki)
-		if err != nil {
-			return "", err
-		}
-
-		if isImage {
-			out.WriteByte('!')
-		}
-		out.WriteString(content[i : labelEnd+2])
-		out.WriteString(rewritten)
-		out.WriteByte(')')
-		i = destEnd
+	rewritten, err := t.rewriteDestination(userID, sourcePath, page, link.Destination, wiki)
+	if err != nil {
+		return "", err
 	}
-
-	return out.String(), nil
+	link.Destination = rewritten
+	return link.String(), nil
 }
 
-// rewriteWikiLinks handles Obsidian-style wiki links and converts them to plain Markdown links.
-func (t *contentTransformer) rewriteWikiLinks(
+// rewriteWikiLink handles Obsidian-style wiki links and converts them to plain Markdown links.
+func (t *contentTransformer) rewriteWikiLink(
 	userID string,
 	sourcePath string,
 	page *tree.Page,
-	content string,
+	link WikiLink,
 	wiki ImporterWiki,
 ) (string, error) {
-	var out strings.Builder
-	for i := 0; i < len(content); {
-		nextImage := strings.Index(content[i:], "![[")
-		nextLink := strings.Index(content[i:], "[[")
-		if nextImage < 0 && nextLink < 0 {
-			out.WriteString(content[i:])
-			break
-		}
-
-		next := -1
-		isImage := false
-		switch {
-		case nextImage >= 0 && (nextLink < 0 || nextImage <= nextLink):
-			next = i + nextImage
-			isImage = true
-		case nextLink >= 0:
-			next = i + nextLink
-		}
-
-		out.WriteString(content[i:next])
-
-		startOffset := 2
-		if isImage {
-			startOffset = 3
-		}
-		end := strings.Index(content[next+startOffset:], "]]")
-		if end < 0 {
-			out.WriteString(content[next:])
-			break
-		}
-		end += next + startOffset
-
-		inner := strings.TrimSpace(content[next+startOffset : end])
-		targetPart, label := splitWikiLink(inner)
-		targetPart = normalizeImportedHref(targetPart)
-		_, anchorSuffix := splitURLSuffix(targetPart)
-		href, isAsset, err := t.resolveDestination(userID, sourcePath, page, targetPart, wiki)
-		if err != nil {
-			return "", err
-		}
+	targetPart := normalizeImportedHref(link.Target)
+	label := link.Label
+	_, anchorSuffix := splitURLSuffix(targetPart)
+	href, isAsset, err := t.resolveDestination(userID, sourcePath, page, targetPart, wiki)
+	if err != nil {
+		return "", err
+	}
 
-		if isAsset {
-			if label == "" {
-				label = defaultWikiLinkLabel(targetPart)
-			}
-			if shouldRenderWikiLinkAsImage(isImage, targetPart) {
-				out.WriteByte('!')
-			}
-			out.WriteString("[")
-			out.WriteString(label)
-			out.WriteString("](")
-			out.WriteString(href)
-			out.WriteByte(')')
-			i = end + 2
-			continue
+	var out strings.Builder
+	if isAsset {
+		if label == "" {
+			label = defaultWikiLinkLabel(targetPart)
 		}
-
-		// Native WikiLinks don't support anchors — convert anchor links to Markdown.
-		if anchorSuffix != "" {
-			if href == "" {
-				fallbackHref, ok := t.fallbackWikiPageHref(sourcePath, targetPart)
-				if !ok {
-					out.WriteString(content[next : end+2])
-					i = end + 2
-					continue
-				}
-				href = fallbackHref
-			}
-			if label == "" {
-				label = defaultWikiLinkLabel(targetPart)
-			}
-			out.WriteString("[")
-			out.WriteString(label)
-			out.WriteString("](")
-			out.WriteString(href)
-			out.WriteByte(')')
-			i = end + 2
-			continue
+		if shouldRenderWikiLinkAsImage(link.Embed, targetPart) {
+			out.WriteByte('!')
 		}
+		out.WriteString("[")
+		out.WriteString(label)
+		out.WriteString("](")
+		out.WriteString(href)
+		out.WriteByte(')')
+		return out.String(), nil
+	}
 
-		// Plain page WikiLink (no anchor, no asset): keep as [[...]] so LeafWiki's
-		// native title-based resolution handles it — rename-aware and self-healing.
-		//
-		// Path-hinted links ([[Folder/Page]]) use the plan's resolved slug path so the
-		// refactoring engine's path-hint regex matches exactly on rename. Unresolved
-		// path-hinted links are slugified so the link indexer and heal mechanism can
-		// match them when the target page is later created.
-		// Obsidian page-embeds (![[Page]]) are kept as plain [[...]] because LeafWiki
-		// has no page-transclusion support.
-		target := targetPart
-		if strings.Contains(targetPart, "/") {
-			if href != "" {
-				target = strings.TrimPrefix(href, "/")
-			} else if slugged, ok := t.normalizeWikiHrefToRoutePath(targetPart); ok {
-				target = slugged
+	// Native WikiLinks don't support anchors — convert anchor links to Markdown.
+	if anchorSuffix != "" {
+		if href == "" {
+			fallbackHref, ok := t.fallbackWikiPageHref(sourcePath, targetPart)
+			if !ok {
+				return link.Raw, nil
 			}
+			href = fallbackHref
 		}
-		out.WriteString("[[")
-		if label != "" {
-			out.WriteString(target)
-			out.WriteByte('|')
-			out.WriteString(label)
-		} else {
-			out.WriteString(target)
+		if label == "" {
+			label = defaultWikiLinkLabel(targetPart)
 		}
-		out.WriteString("]]")
-		i = end + 2
+		out.WriteString("[")
+		out.WriteString(label)
+		out.WriteString("](")
+		out.WriteString(href)
+		out.WriteByte(')')
+		return out.String(), nil
+	}
+
+	// Plain page WikiLink (no anchor, no asset): keep as [[...]] so LeafWiki's
+	// native title-based resolution handles it — rename-aware and self-healing.
+	//
+	// Path-hinted links ([[Folder/Page]]) use the plan's resolved slug path so the
+	// refactoring engine's path-hint regex matches exactly on rename. Unresolved
+	// path-hinted links are slugified so the link indexer and heal mechanism can
+	// match them when the target page is later created.
+	// Obsidian page-embeds (![[Page]]) are kept as plain [[...]] because LeafWiki
+	// has no page-transclusion support.
+	target := targetPart
+	if strings.Contains(targetPart, "/") {
+		if href != "" {
+			target = strings.TrimPrefix(href, "/")
+		} else if slugged, ok := t.normalizeWikiHrefToRoutePath(targetPart); ok {
+			target = slugged
+		}
+	}
+	out.WriteString("[[")
+	if label != "" {
+		out.WriteString(target)
+		out.WriteByte('|')
+		out.WriteString(label)
+	} else {
+		out.WriteString(target)
 	}
-
+	out.WriteString("]]")
 	return out.String(), nil
 }
 
@@ -372,10 +290,46 @@ func (t *contentTransformer) resolveAndUploadAsset(
 	wiki ImporterWiki,
 ) (string, error) {
 	assetAbs, ok := resolveAssetPath(t.sourceBasePath, sourcePath, href)
+	if !ok && !strings.HasPrefix(href, "/") && !strings.HasPrefix(href, ".") {
+		// Like page links, path-hinted references may be relative to the package root.
+		assetAbs, ok = resolveAssetPath(t.sourceBasePath, sourcePath, "/"+href)
+	}
 	if !ok {
 		return "", nil
 	}
 
+	return t.uploadAsset(userID, page, assetAbs, wiki)
+}
+
+// uploadSidecarAssets uploads every file in the page's sidecar folder
+// (see SidecarAssetsDir), so attachments survive a round trip even when the
+// content does not link them. Files already uploaded for a link are skipped.
+func (t *contentTransformer) uploadSidecarAssets(userID string, sourcePath string, page *tree.Page, wiki ImporterWiki) error {
+	dir := filepath.Join(t.sourceBasePath, filepath.FromSlash(SidecarAssetsDir(sourcePath)))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read assets folder %q: %w", dir, err)
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		assetAbs, ok := resolveAssetPath(t.sourceBasePath, sourcePath, "/"+path.Join(SidecarAssetsDir(sourcePath), entry.Name()))
+		if !ok {
+			continue
+		}
+		if _, err := t.uploadAsset(userID, page, assetAbs, wiki); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uploadAsset uploads a resolved file once per page and returns its public path.
+func (t *contentTransformer) uploadAsset(userID string, page *tree.Page, assetAbs string, wiki ImporterWiki) (string, error) {
 	cacheKey := page.ID + "::" + assetAbs
 	if uploaded, ok := t.assetUploads[cacheKey]; ok {
 		return uploaded, nil
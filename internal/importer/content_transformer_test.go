@@ -15,6 +15,7 @@ func TestContentTransformer_TransformContent_TableDriven(t *testing.T) {
 	writeTmp(t, tmp, "Image.png", "png-bytes")
 	writeTmp(t, tmp, "img.png", "png-bytes")
 	writeTmp(t, tmp, "obsidian_repo.png", "png-bytes")
+	writeTmp(t, tmp, "media/chart.png", "png-bytes")
 
 	transformer := newContentTransformer(&PlanResult{
 		Items: []PlanItem{
@@ -117,6 +118,12 @@ func TestContentTransformer_TransformContent_TableDriven(t *testing.T) {
 			content:    "![[../img.png]]",
 			want:       "![img.png](/assets/p1/img.png)",
 		},
+		{
+			name:       "asset embed relative to package root",
+			sourcePath: "docs/current.md",
+			content:    "![[media/chart.png]]",
+			want:       "![chart.png](/assets/p1/chart.png)",
+		},
 		{
 			name:       "non image wiki asset stays a link",
 			sourcePath: "docs/current.md",
@@ -0,0 +1,466 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/perber/wiki/internal/core/markdown"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// Packages may contain HTML pages and Word documents next to (or instead
+// of) Markdown files. They are converted into Markdown pages of the same
+// name; every other file is copied into the converted package unchanged so
+// that relative links keep working.
+
+// documentExtensions are the document formats converted to Markdown.
+var documentExtensions = map[string]bool{
+	".html": true,
+	".htm":  true,
+	".docx": true,
+}
+
+// sourceDocument is a document of the package and the Markdown page it is
+// converted to.
+type sourceDocument struct {
+	// source is the document relative to the package folder.
+	source string
+	// mdPath is the Markdown file in the converted package.
+	mdPath string
+	// assets maps extracted images to file names in the sidecar folder.
+	assets map[string]string
+	notes  []string
+}
+
+func (d *sourceDocument) addNote(format string, args ...any) {
+	note := fmt.Sprintf(format, args...)
+	for _, existing := range d.notes {
+		if existing == note {
+			return
+		}
+	}
+	d.notes = append(d.notes, note)
+}
+
+// DetectDocuments reports whether root holds HTML or Word documents that
+// have to be converted to Markdown.
+func DetectDocuments(root string) (string, bool) {
+	found := false
+	_ = walkPackageFiles(root, func(rel string) error {
+		if documentExtensions[strings.ToLower(path.Ext(rel))] {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return root, found
+}
+
+// walkPackageFiles calls fn with the slash separated path of every file of
+// a package, skipping hidden files, the converted package and archive
+// metadata such as __MACOSX.
+func walkPackageFiles(root string, fn func(rel string) error) error {
+	return filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if p != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "__") || strings.HasPrefix(name, "~$")) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(rel))
+	})
+}
+
+type documentConverter struct {
+	root   string
+	outDir string
+	// documents maps lower-cased source paths to documents.
+	documents map[string]*sourceDocument
+}
+
+// ConvertDocuments converts the HTML and Word documents of a package into
+// Markdown pages in outDir and copies all other files. The title of a page
+// is taken from the document; parts that cannot be represented in Markdown
+// are reported as notes.
+func ConvertDocuments(root, outDir string) ([]ImportMDFile, error) {
+	cv := &documentConverter{root: root, outDir: outDir, documents: map[string]*sourceDocument{}}
+
+	var files, documents []string
+	taken := map[string]bool{}
+	err := walkPackageFiles(root, func(rel string) error {
+		if documentExtensions[strings.ToLower(path.Ext(rel))] {
+			documents = append(documents, rel)
+			return nil
+		}
+		files = append(files, rel)
+		taken[strings.ToLower(rel)] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read package: %w", err)
+	}
+	sort.Strings(documents)
+
+	for _, rel := range documents {
+		doc := &sourceDocument{source: rel, assets: map[string]string{}}
+		base := strings.TrimSuffix(rel, path.Ext(rel))
+		doc.mdPath = base + ".md"
+		for i := 2; taken[strings.ToLower(doc.mdPath)]; i++ {
+			doc.mdPath = fmt.Sprintf("%s-%d.md", base, i)
+		}
+		if doc.mdPath != base+".md" {
+			doc.addNote("Converted to %q because %q already exists", path.Base(doc.mdPath), path.Base(base)+".md")
+		}
+		taken[strings.ToLower(doc.mdPath)] = true
+		cv.documents[strings.ToLower(rel)] = doc
+	}
+
+	for _, rel := range files {
+		if err := cv.copyFile(rel); err != nil {
+			return nil, fmt.Errorf("copy %s: %w", rel, err)
+		}
+	}
+	for _, rel := range documents {
+		doc := cv.documents[strings.ToLower(rel)]
+		if err := cv.convert(doc); err != nil {
+			// A broken document should not fail the whole import; the
+			// page is created empty and the plan says why.
+			doc.addNote("Document could not be converted: %v", err)
+			if err := cv.writePage(doc, strings.TrimSuffix(path.Base(rel), path.Ext(rel)), ""); err != nil {
+				return nil, fmt.Errorf("convert %s: %w", rel, err)
+			}
+		}
+	}
+
+	entries, err := FindMarkdownEntries(outDir)
+	if err != nil {
+		return nil, err
+	}
+	notes := map[string][]string{}
+	for _, doc := range cv.documents {
+		notes[doc.mdPath] = doc.notes
+	}
+	for i := range entries {
+		entries[i].Notes = notes[entries[i].SourcePath]
+	}
+	return entries, nil
+}
+
+// copyFile copies a file into the converted package. Links of Markdown
+// files to converted documents are rewritten to the Markdown pages.
+func (cv *documentConverter) copyFile(rel string) error {
+	src := filepath.Join(cv.root, filepath.FromSlash(rel))
+	dest := filepath.Join(cv.outDir, filepath.FromSlash(rel))
+	if !strings.EqualFold(path.Ext(rel), ".md") {
+		return copyFile(src, dest)
+	}
+
+	raw, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	content, err := RewriteMarkdownLinks(string(raw), func(link MarkdownLink) (string, error) {
+		if dest, ok := cv.documentLink(rel, link.Href()); ok {
+			return link.WithHref(dest).String(), nil
+		}
+		return link.String(), nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(dest, []byte(content), 0o644)
+}
+
+// documentLink maps a relative link of the file from to a converted
+// document onto its Markdown page.
+func (cv *documentConverter) documentLink(from, href string) (string, bool) {
+	if href == "" || strings.HasPrefix(href, "#") || isExternalHref(href) {
+		return "", false
+	}
+	target, suffix := splitURLSuffix(href)
+	if u, err := url.Parse(target); err != nil || u.Scheme != "" {
+		return "", false
+	}
+	target = path.Join(path.Dir(from), decodeImportTarget(target))
+	doc, ok := cv.documents[strings.ToLower(target)]
+	if !ok {
+		return "", false
+	}
+	return relativePackagePath(from, doc.mdPath) + suffix, true
+}
+
+func (cv *documentConverter) convert(doc *sourceDocument) error {
+	src := filepath.Join(cv.root, filepath.FromSlash(doc.source))
+
+	var (
+		root  *html.Node
+		title string
+		media func(name string) ([]byte, bool)
+	)
+	switch strings.ToLower(path.Ext(doc.source)) {
+	case ".docx":
+		d, err := openDocx(src)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = d.Close() }()
+		if root, title, err = d.toHTML(doc); err != nil {
+			return err
+		}
+		media = d.media
+	default:
+		var err error
+		if root, err = parseHTMLDocument(src); err != nil {
+			return err
+		}
+		if t := findHTMLElement(root, func(e *html.Node) bool { return e.DataAtom == atom.Title }); t != nil {
+			title = strings.TrimSpace(collapseHTMLSpace(htmlText(t)))
+		}
+	}
+
+	body := findHTMLElement(root, func(e *html.Node) bool { return e.DataAtom == atom.Body })
+	if body == nil {
+		body = root
+	}
+	title = documentTitle(body, title)
+	if title == "" {
+		title = strings.TrimSuffix(path.Base(doc.source), path.Ext(doc.source))
+	}
+	noteLossyHTML(doc, body)
+
+	content := convertHTMLToMarkdown(body, htmlMarkdownOptions{
+		Link: func(href string, image bool) (string, bool) {
+			return cv.rewriteLink(doc, media, href, image)
+		},
+	})
+
+	return cv.writePage(doc, title, content)
+}
+
+func (cv *documentConverter) writePage(doc *sourceDocument, title, content string) error {
+	md, err := markdown.BuildMarkdownWithExtraFrontmatter(map[string]interface{}{"leafwiki_title": title}, content+"\n")
+	if err != nil {
+		return err
+	}
+	out := filepath.Join(cv.outDir, filepath.FromSlash(doc.mdPath))
+	if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(out, []byte(md), 0o644)
+}
+
+// documentTitle returns the title of a document: the given title, else
+// its first top-level heading. A heading that opens the document and
+// repeats the title is removed, as the page shows its title anyway.
+func documentTitle(body *html.Node, title string) string {
+	h1 := findHTMLElement(body, func(e *html.Node) bool { return e.DataAtom == atom.H1 })
+	if h1 == nil {
+		return title
+	}
+	heading := strings.TrimSpace(collapseHTMLSpace(htmlText(h1)))
+	if title == "" {
+		title = heading
+	}
+	if strings.EqualFold(heading, title) && opensDocument(body, h1) {
+		h1.Parent.RemoveChild(h1)
+	}
+	return title
+}
+
+// opensDocument reports whether no text or image precedes n in body.
+func opensDocument(body, n *html.Node) bool {
+	var visit func(e *html.Node) (found, content bool)
+	visit = func(e *html.Node) (bool, bool) {
+		for child := e.FirstChild; child != nil; child = child.NextSibling {
+			switch {
+			case child == n:
+				return true, false
+			case child.Type == html.TextNode && strings.TrimSpace(child.Data) != "":
+				return false, true
+			case child.Type == html.ElementNode && child.DataAtom == atom.Img:
+				return false, true
+			case child.Type == html.ElementNode && !htmlSkippedElements[child.DataAtom]:
+				if found, content := visit(child); found || content {
+					return found, content
+				}
+			}
+		}
+		return false, false
+	}
+	found, _ := visit(body)
+	return found
+}
+
+// htmlEmbeddedElements hold content that Markdown cannot represent.
+var htmlEmbeddedElements = map[atom.Atom]bool{
+	atom.Iframe: true,
+	atom.Object: true,
+	atom.Embed:  true,
+	atom.Video:  true,
+	atom.Audio:  true,
+	atom.Canvas: true,
+	atom.Svg:    true,
+	atom.Math:   true,
+}
+
+// noteLossyHTML reports the parts of a document that do not survive the
+// conversion to Markdown.
+func noteLossyHTML(doc *sourceDocument, n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		switch {
+		case htmlEmbeddedElements[child.DataAtom]:
+			doc.addNote("Embedded content (<%s>) was removed", child.Data)
+			continue
+		case child.DataAtom == atom.Td || child.DataAtom == atom.Th:
+			if htmlSpan(child, "colspan") > 1 || htmlSpan(child, "rowspan") > 1 {
+				doc.addNote("Merged table cells were split into separate cells")
+			}
+			if findHTMLElement(child, func(e *html.Node) bool { return e.DataAtom == atom.Table }) != nil {
+				doc.addNote("Nested tables were flattened to text")
+			}
+		case child.DataAtom == atom.Input || child.DataAtom == atom.Select || child.DataAtom == atom.Textarea:
+			if !strings.EqualFold(htmlAttr(child, "type"), "checkbox") {
+				doc.addNote("Form fields were removed")
+			}
+		}
+		noteLossyHTML(doc, child)
+	}
+}
+
+func htmlSpan(n *html.Node, key string) int {
+	span, err := strconv.Atoi(strings.TrimSpace(htmlAttr(n, key)))
+	if err != nil {
+		return 1
+	}
+	return span
+}
+
+// rewriteLink maps links and images of a converted document. Embedded
+// images (data: URLs and images of Word documents) are extracted into the
+// sidecar folder of the page; links to other documents of the package
+// point to their Markdown pages.
+func (cv *documentConverter) rewriteLink(doc *sourceDocument, media func(string) ([]byte, bool), href string, image bool) (string, bool) {
+	switch {
+	case strings.HasPrefix(href, docxMediaScheme):
+		name := strings.TrimPrefix(href, docxMediaScheme)
+		data, ok := []byte(nil), false
+		if media != nil {
+			data, ok = media(name)
+		}
+		if !ok {
+			doc.addNote("Image %q is missing from the document and was removed", path.Base(name))
+			return "", false
+		}
+		return cv.asset(doc, name, path.Base(name), data)
+	case strings.HasPrefix(strings.ToLower(href), "data:"):
+		if !image {
+			return "", false
+		}
+		data, ext, ok := decodeDataURL(href)
+		if !ok {
+			doc.addNote("An embedded image could not be decoded and was removed")
+			return "", false
+		}
+		return cv.asset(doc, href, fmt.Sprintf("image-%d%s", len(doc.assets)+1, ext), data)
+	}
+	if dest, ok := cv.documentLink(doc.source, href); ok {
+		// The converted page lives next to the document, so all other
+		// relative links stay valid.
+		return dest, true
+	}
+	return href, true
+}
+
+// asset writes an extracted image into the sidecar folder of the page and
+// returns the link to it.
+func (cv *documentConverter) asset(doc *sourceDocument, key, name string, data []byte) (string, bool) {
+	sidecar := SidecarAssetsDir(doc.mdPath)
+	if existing, ok := doc.assets[key]; ok {
+		return relativePackagePath(doc.mdPath, path.Join(sidecar, existing)), true
+	}
+	name = uniqueAssetName(name, doc.assets)
+	dest := filepath.Join(cv.outDir, filepath.FromSlash(sidecar), name)
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		doc.addNote("Image %q could not be extracted: %v", name, err)
+		return "", false
+	}
+	if err := os.WriteFile(dest, data, 0o644); err != nil {
+		doc.addNote("Image %q could not be extracted: %v", name, err)
+		return "", false
+	}
+	doc.assets[key] = name
+	return relativePackagePath(doc.mdPath, path.Join(sidecar, name)), true
+}
+
+// decodeDataURL decodes a base64 data: URL and returns its content and a
+// file extension for its media type.
+func decodeDataURL(href string) ([]byte, string, bool) {
+	meta, payload, ok := strings.Cut(href[len("data:"):], ",")
+	if !ok || !strings.HasSuffix(strings.ToLower(meta), ";base64") {
+		return nil, "", false
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(payload), ""))
+	if err != nil {
+		return nil, "", false
+	}
+	mediaType, _, _ := strings.Cut(strings.ToLower(meta), ";")
+	ext, ok := dataURLExtensions[mediaType]
+	if !ok {
+		ext = ".bin"
+	}
+	return data, ext, true
+}
+
+var dataURLExtensions = map[string]string{
+	"image/png":     ".png",
+	"image/jpeg":    ".jpg",
+	"image/gif":     ".gif",
+	"image/webp":    ".webp",
+	"image/svg+xml": ".svg",
+	"image/bmp":     ".bmp",
+}
+
+// parseHTMLDocument parses an HTML file. Files that are not valid UTF-8
+// (such as pages saved by Word) are decoded using their declared charset.
+func parseHTMLDocument(p string) (*html.Node, error) {
+	raw, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	if utf8.Valid(raw) {
+		return html.Parse(bytes.NewReader(raw))
+	}
+	r, err := charset.NewReader(bytes.NewReader(raw), "text/html")
+	if err != nil {
+		return nil, err
+	}
+	return html.Parse(r)
+}
@@ -0,0 +1,140 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/perber/wiki/internal/test_utils"
+)
+
+func documentsFixtureDir(t *testing.T) string {
+	t.Helper()
+	return test_utils.FixturePath(t, "documents-package", "fixtures", "internal/importer/fixtures")
+}
+
+func TestDetectDocuments_FindsHTMLAndWordFiles(t *testing.T) {
+	if _, ok := DetectDocuments(documentsFixtureDir(t)); !ok {
+		t.Fatalf("expected documents package to be detected")
+	}
+
+	tmp := t.TempDir()
+	test_utils.WriteFile(t, tmp, "docs/page.md", "# Page")
+	test_utils.WriteFile(t, tmp, "__MACOSX/docs/._page.html", "")
+	test_utils.WriteFile(t, tmp, "docs/~$draft.docx", "")
+	if _, ok := DetectDocuments(tmp); ok {
+		t.Fatalf("expected markdown package not to be detected")
+	}
+}
+
+func TestConvertDocuments_ConvertsHTMLAndWordDocuments(t *testing.T) {
+	out := t.TempDir()
+	entries, err := ConvertDocuments(documentsFixtureDir(t), out)
+	if err != nil {
+		t.Fatalf("ConvertDocuments err: %v", err)
+	}
+
+	var paths []string
+	notes := map[string][]string{}
+	for _, entry := range entries {
+		paths = append(paths, entry.SourcePath)
+		notes[entry.SourcePath] = entry.Notes
+	}
+	if got := strings.Join(paths, ","); got != "handbook.md,onboarding.md,release-notes.md" {
+		t.Fatalf("entries = %v", paths)
+	}
+
+	read := func(rel string) string {
+		t.Helper()
+		raw, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(rel)))
+		if err != nil {
+			t.Fatalf("ReadFile %s err: %v", rel, err)
+		}
+		return string(raw)
+	}
+
+	if handbook := read("handbook.md"); !strings.Contains(handbook, "[onboarding guide](onboarding.md) and read the [release notes](release-notes.md#fixes)") {
+		t.Fatalf("expected links to documents to be rewritten, got:\n%s", handbook)
+	}
+
+	onboarding := read("onboarding.md")
+	for _, expected := range []string{
+		"leafwiki_title: Onboarding Guide\n---\n# First day\n",
+		"Welcome to the **team wiki**, please read the [*policies*](https://example.com/policies).\\[1\\]",
+		"- Get a laptop\n  - Install the VPN\n- Meet the team\n\n1. Sign the contract\n2. Pick a desk",
+		"| Room | Floor |\n| --- | --- |\n| Kitchen | 2 |",
+		"![Office map](onboarding.assets/image1.png)",
+		"## Second week\n\n```\nmake setup\nmake test\n```",
+		"---\n\n1. Updated every year.",
+	} {
+		if !strings.Contains(onboarding, expected) {
+			t.Fatalf("expected word document to contain %q, got:\n%s", expected, onboarding)
+		}
+	}
+	if got := strings.Join(notes["onboarding.md"], "\n"); got != "Comments were not imported" {
+		t.Fatalf("word document notes = %q", got)
+	}
+
+	releaseNotes := read("release-notes.md")
+	for _, expected := range []string{
+		"leafwiki_title: Release Notes\n---\nVersion 2.0 is **ready** for café owners. Back to the [handbook](handbook.md).",
+		"- Faster search\n  - Typo tolerance\n- New [onboarding guide](onboarding.md)",
+		"![Logo](images/logo.png) ![Chart](release-notes.assets/image-1.png)",
+	} {
+		if !strings.Contains(releaseNotes, expected) {
+			t.Fatalf("expected html document to contain %q, got:\n%s", expected, releaseNotes)
+		}
+	}
+	if got := strings.Join(notes["release-notes.md"], "\n"); got != "Merged table cells were split into separate cells\nEmbedded content (<iframe>) was removed" {
+		t.Fatalf("html document notes = %q", got)
+	}
+
+	for rel, want := range map[string]string{
+		"onboarding.assets/image1.png":     "PNG-MAP",
+		"release-notes.assets/image-1.png": "PNG-INLINE",
+		"images/logo.png":                  "PNG-LOGO",
+	} {
+		if got := read(rel); got != want {
+			t.Fatalf("%s = %q, want %q", rel, got, want)
+		}
+	}
+}
+
+func TestConvertDocuments_KeepsExistingMarkdownAndNotesBrokenDocuments(t *testing.T) {
+	tmp := t.TempDir()
+	test_utils.WriteFile(t, tmp, "notes.md", "# Notes")
+	test_utils.WriteFile(t, tmp, "notes.html", "<p>From <i>HTML</i></p>")
+	test_utils.WriteFile(t, tmp, "broken.docx", "not a zip")
+
+	out := t.TempDir()
+	entries, err := ConvertDocuments(tmp, out)
+	if err != nil {
+		t.Fatalf("ConvertDocuments err: %v", err)
+	}
+	notes := map[string]string{}
+	for _, entry := range entries {
+		notes[entry.SourcePath] = strings.Join(entry.Notes, "\n")
+	}
+	if len(entries) != 3 {
+		t.Fatalf("entries = %#v", entries)
+	}
+	if !strings.Contains(notes["notes-2.md"], `Converted to "notes-2.md" because "notes.md" already exists`) {
+		t.Fatalf("notes-2.md notes = %q", notes["notes-2.md"])
+	}
+	if !strings.Contains(notes["broken.md"], "Document could not be converted") {
+		t.Fatalf("broken.md notes = %q", notes["broken.md"])
+	}
+
+	raw, err := os.ReadFile(filepath.Join(out, "notes-2.md"))
+	if err != nil {
+		t.Fatalf("ReadFile err: %v", err)
+	}
+	if !strings.Contains(string(raw), "leafwiki_title: notes\n---\nFrom *HTML*") {
+		t.Fatalf("expected file name as title, got:\n%s", raw)
+	}
+	raw, err = os.ReadFile(filepath.Join(out, "notes.md"))
+	if err != nil || string(raw) != "# Notes" {
+		t.Fatalf("expected markdown file to be copied unchanged, got %q, %v", raw, err)
+	}
+}
@@ -0,0 +1,783 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Word documents (.docx) are ZIP packages of XML parts. The main part
+// word/document.xml is rendered to HTML using the paragraph styles and
+// list definitions of the document and then converted like any other HTML
+// document. Images are referenced as docxMediaScheme links and extracted
+// when the Markdown is written.
+
+// docxMediaScheme marks images that are stored inside the Word document.
+const docxMediaScheme = "docx-media:"
+
+// docxMaxPartBytes limits the size of a single uncompressed part.
+const docxMaxPartBytes = 256 << 20
+
+// docxNode is an element of a Word XML part. Names are local names without
+// their namespace (w:p becomes p).
+type docxNode struct {
+	name     string
+	attrs    map[string]string
+	children []*docxNode
+	text     string
+}
+
+func (n *docxNode) attr(name string) string {
+	if n == nil {
+		return ""
+	}
+	return n.attrs[name]
+}
+
+// child returns the first direct child with the given name.
+func (n *docxNode) child(name string) *docxNode {
+	if n == nil {
+		return nil
+	}
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// find returns the first descendant with the given name.
+func (n *docxNode) find(name string) *docxNode {
+	if n == nil {
+		return nil
+	}
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+		if found := c.find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// plainText returns the text of all text elements below n.
+func (n *docxNode) plainText() string {
+	if n == nil {
+		return ""
+	}
+	var b strings.Builder
+	var visit func(e *docxNode)
+	visit = func(e *docxNode) {
+		switch e.name {
+		case "t":
+			b.WriteString(e.text)
+			return
+		case "tab":
+			b.WriteString("\t")
+		case "br", "cr":
+			b.WriteString("\n")
+		case "del", "moveFrom", "instrText", "delText":
+			return
+		}
+		for _, c := range e.children {
+			visit(c)
+		}
+	}
+	visit(n)
+	return b.String()
+}
+
+// enabled reports whether a toggle property such as w:b is switched on.
+func (n *docxNode) enabled() bool {
+	if n == nil {
+		return false
+	}
+	switch strings.ToLower(n.attr("val")) {
+	case "0", "false", "off", "none":
+		return false
+	}
+	return true
+}
+
+func parseDocxXML(r io.Reader) (*docxNode, error) {
+	dec := xml.NewDecoder(r)
+	root := &docxNode{}
+	stack := []*docxNode{root}
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return root, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &docxNode{name: t.Name.Local, attrs: map[string]string{}}
+			for _, a := range t.Attr {
+				n.attrs[a.Name.Local] = a.Value
+			}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			stack[len(stack)-1].text += string(t)
+		}
+	}
+}
+
+// docxStyle is what the conversion needs to know about a paragraph or
+// character style.
+type docxStyle struct {
+	name    string
+	basedOn string
+	// heading is the heading level; the document title has level 1.
+	heading int
+	title   bool
+	code    bool
+	quote   bool
+	// list is "bullet" or "number" for the built-in list styles.
+	list      string
+	listLevel int
+}
+
+type docxRel struct {
+	target   string
+	external bool
+}
+
+type docxFile struct {
+	zip   *zip.ReadCloser
+	files map[string]*zip.File
+	// rels maps relationship IDs of the main part to their targets.
+	rels   map[string]docxRel
+	styles map[string]docxStyle
+	// numbering maps list IDs and levels ("<numId>/<ilvl>") to whether
+	// the list is numbered.
+	numbering map[string]bool
+	notes     map[string]*docxNode
+	// noteRefs are the footnotes and endnotes in the order they are
+	// referenced.
+	noteRefs []string
+}
+
+func openDocx(p string) (*docxFile, error) {
+	zr, err := zip.OpenReader(p)
+	if err != nil {
+		return nil, fmt.Errorf("open word document: %w", err)
+	}
+	f := &docxFile{
+		zip:       zr,
+		files:     map[string]*zip.File{},
+		rels:      map[string]docxRel{},
+		styles:    map[string]docxStyle{},
+		numbering: map[string]bool{},
+		notes:     map[string]*docxNode{},
+	}
+	for _, file := range zr.File {
+		f.files[strings.TrimPrefix(file.Name, "/")] = file
+	}
+	if _, ok := f.files["word/document.xml"]; !ok {
+		_ = zr.Close()
+		return nil, errors.New("not a word document: word/document.xml is missing")
+	}
+	return f, nil
+}
+
+func (f *docxFile) Close() error {
+	return f.zip.Close()
+}
+
+func (f *docxFile) read(name string) ([]byte, bool) {
+	file, ok := f.files[name]
+	if !ok {
+		return nil, false
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return nil, false
+	}
+	defer func() { _ = rc.Close() }()
+	data, err := io.ReadAll(io.LimitReader(rc, docxMaxPartBytes+1))
+	if err != nil || len(data) > docxMaxPartBytes {
+		return nil, false
+	}
+	return data, true
+}
+
+// part parses an XML part; missing parts return nil.
+func (f *docxFile) part(name string) (*docxNode, error) {
+	data, ok := f.read(name)
+	if !ok {
+		return nil, nil
+	}
+	n, err := parseDocxXML(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", name, err)
+	}
+	return n, nil
+}
+
+// media returns an image stored in the document.
+func (f *docxFile) media(name string) ([]byte, bool) {
+	return f.read(name)
+}
+
+func (f *docxFile) load() error {
+	rels, err := f.part("word/_rels/document.xml.rels")
+	if err != nil {
+		return err
+	}
+	for _, rel := range rels.find("Relationships").childrenNamed("Relationship") {
+		target := rel.attr("Target")
+		external := strings.EqualFold(rel.attr("TargetMode"), "External")
+		if !external {
+			if strings.HasPrefix(target, "/") {
+				target = strings.TrimPrefix(target, "/")
+			} else {
+				target = path.Join("word", target)
+			}
+		}
+		f.rels[rel.attr("Id")] = docxRel{target: target, external: external}
+	}
+
+	styles, err := f.part("word/styles.xml")
+	if err != nil {
+		return err
+	}
+	for _, s := range styles.find("styles").childrenNamed("style") {
+		style := docxStyle{
+			name:    strings.ToLower(strings.TrimSpace(s.child("name").attr("val"))),
+			basedOn: s.child("basedOn").attr("val"),
+		}
+		if lvl, err := strconv.Atoi(s.child("pPr").child("outlineLvl").attr("val")); err == nil && lvl < 9 {
+			style.heading = lvl + 1
+		}
+		f.styles[s.attr("styleId")] = style
+	}
+
+	numbering, err := f.part("word/numbering.xml")
+	if err != nil {
+		return err
+	}
+	abstract := map[string]map[string]bool{}
+	for _, a := range numbering.find("numbering").childrenNamed("abstractNum") {
+		levels := map[string]bool{}
+		for _, lvl := range a.childrenNamed("lvl") {
+			format := lvl.child("numFmt").attr("val")
+			levels[lvl.attr("ilvl")] = format != "bullet" && format != "none" && format != ""
+		}
+		abstract[a.attr("abstractNumId")] = levels
+	}
+	for _, num := range numbering.find("numbering").childrenNamed("num") {
+		for ilvl, ordered := range abstract[num.child("abstractNumId").attr("val")] {
+			f.numbering[num.attr("numId")+"/"+ilvl] = ordered
+		}
+	}
+
+	for _, kind := range []string{"footnotes", "endnotes"} {
+		part, err := f.part("word/" + kind + ".xml")
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(kind, "s")
+		for _, note := range part.find(kind).childrenNamed(name) {
+			f.notes[name+"/"+note.attr("id")] = note
+		}
+	}
+	return nil
+}
+
+func (n *docxNode) childrenNamed(name string) []*docxNode {
+	if n == nil {
+		return nil
+	}
+	var out []*docxNode
+	for _, c := range n.children {
+		if c.name == name {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// style resolves a style ID, including what it inherits from the styles
+// it is based on.
+func (f *docxFile) style(id string) docxStyle {
+	var style docxStyle
+	for depth := 0; id != "" && depth < 10; depth++ {
+		s, ok := f.styles[id]
+		if !ok {
+			// Documents without styles.xml still use the built-in IDs.
+			s.name = strings.ToLower(id)
+			if level, err := strconv.Atoi(strings.TrimPrefix(s.name, "heading")); err == nil {
+				s.name = "heading " + strconv.Itoa(level)
+			}
+		}
+		if style.name == "" {
+			style.name = s.name
+		}
+		name := s.name
+		switch {
+		case name == "title":
+			style.title = true
+		case strings.HasPrefix(name, "heading "):
+			if level, err := strconv.Atoi(strings.TrimPrefix(name, "heading ")); err == nil && style.heading == 0 {
+				style.heading = level
+			}
+		case strings.Contains(name, "code") || strings.Contains(name, "preformatted") || strings.Contains(name, "source") || name == "plain text":
+			style.code = true
+		case strings.Contains(name, "quote") || name == "block text":
+			style.quote = true
+		case strings.HasPrefix(name, "list bullet"), strings.HasPrefix(name, "list number"):
+			// "List Bullet 2" is the second level of a bulleted list.
+			if fields := strings.Fields(name); style.list == "" {
+				style.list = fields[1]
+				if len(fields) > 2 {
+					if level, err := strconv.Atoi(fields[2]); err == nil {
+						style.listLevel = level - 1
+					}
+				}
+			}
+		}
+		if style.heading == 0 {
+			style.heading = s.heading
+		}
+		id = s.basedOn
+	}
+	if style.heading > 6 {
+		style.heading = 6
+	}
+	return style
+}
+
+// toHTML renders the document to HTML and returns its title: the text of
+// a paragraph in the Title style, else the title of the document
+// properties.
+func (f *docxFile) toHTML(doc *sourceDocument) (*html.Node, string, error) {
+	if err := f.load(); err != nil {
+		return nil, "", err
+	}
+	main, err := f.part("word/document.xml")
+	if err != nil {
+		return nil, "", err
+	}
+	w := &docxWriter{f: f, doc: doc}
+	w.blocks(main.find("body").children)
+	w.close()
+	w.footnotes()
+
+	title := w.title
+	if title == "" {
+		core, err := f.part("docProps/core.xml")
+		if err != nil {
+			return nil, "", err
+		}
+		if t := core.find("title"); t != nil {
+			title = strings.TrimSpace(t.text)
+		}
+	}
+	root, err := html.Parse(strings.NewReader("<html><body>" + w.b.String() + "</body></html>"))
+	return root, title, err
+}
+
+// docxWriter renders paragraphs and tables to HTML. Consecutive list
+// items, code and quote paragraphs are grouped into one element.
+type docxWriter struct {
+	f     *docxFile
+	doc   *sourceDocument
+	b     strings.Builder
+	title string
+	// open is the grouping element that is open: "pre", "blockquote" or
+	// "list" (lists holds the open list elements).
+	open  string
+	lists []string
+	// pending holds blocks found inside a paragraph, such as text boxes;
+	// they follow the paragraph.
+	pending strings.Builder
+}
+
+func (w *docxWriter) close() {
+	switch w.open {
+	case "pre", "blockquote":
+		w.b.WriteString("</" + w.open + ">")
+	case "list":
+		for len(w.lists) > 0 {
+			w.b.WriteString("</li></" + w.lists[len(w.lists)-1] + ">")
+			w.lists = w.lists[:len(w.lists)-1]
+		}
+	}
+	w.open = ""
+}
+
+func (w *docxWriter) blocks(nodes []*docxNode) {
+	for _, n := range nodes {
+		switch n.name {
+		case "p":
+			w.paragraph(n)
+		case "tbl":
+			w.close()
+			w.table(n)
+		case "sdt":
+			w.blocks(n.child("sdtContent").children)
+		case "customXml", "ins", "moveTo", "smartTag":
+			w.blocks(n.children)
+		case "del", "moveFrom":
+			w.doc.addNote("Tracked changes were accepted; deleted text was not imported")
+		}
+	}
+}
+
+func (w *docxWriter) paragraph(p *docxNode) {
+	props := p.child("pPr")
+	style := w.f.style(props.child("pStyle").attr("val"))
+	if lvl, err := strconv.Atoi(props.child("outlineLvl").attr("val")); err == nil && lvl < 6 && style.heading == 0 {
+		style.heading = lvl + 1
+	}
+	content := strings.TrimSpace(w.inline(p.children))
+
+	switch {
+	case style.title || style.heading > 0:
+		w.close()
+		if content == "" {
+			break
+		}
+		level := style.heading
+		if style.title {
+			level = 1
+			if w.title == "" {
+				w.title = strings.TrimSpace(p.plainText())
+			}
+		}
+		fmt.Fprintf(&w.b, "<h%d>%s</h%d>", level, content, level)
+	case style.code:
+		if w.open != "pre" {
+			w.close()
+			w.open = "pre"
+			w.b.WriteString("<pre>")
+		} else {
+			w.b.WriteString("\n")
+		}
+		w.b.WriteString(html.EscapeString(p.plainText()))
+	case style.quote:
+		if w.open != "blockquote" {
+			w.close()
+			w.open = "blockquote"
+			w.b.WriteString("<blockquote>")
+		}
+		w.b.WriteString("<p>" + content + "</p>")
+	default:
+		numPr := props.child("numPr")
+		numID, level := numPr.child("numId").attr("val"), 0
+		if l, err := strconv.Atoi(numPr.child("ilvl").attr("val")); err == nil {
+			level = l
+		}
+		switch {
+		case numPr != nil && numID != "0":
+			w.listItem(level, w.f.numbering[numID+"/"+strconv.Itoa(level)], content)
+		case style.list != "":
+			w.listItem(style.listLevel, style.list == "number", content)
+		default:
+			w.close()
+			if content != "" {
+				w.b.WriteString("<p>" + content + "</p>")
+			}
+		}
+	}
+
+	if w.pending.Len() > 0 {
+		w.close()
+		w.b.WriteString(w.pending.String())
+		w.pending.Reset()
+	}
+}
+
+// listItem adds an item to the open lists, opening and closing nested
+// lists to reach its level.
+func (w *docxWriter) listItem(level int, ordered bool, content string) {
+	if w.open != "list" {
+		w.close()
+		w.open = "list"
+	}
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+	depth := level + 1
+	for len(w.lists) > depth {
+		w.b.WriteString("</li></" + w.lists[len(w.lists)-1] + ">")
+		w.lists = w.lists[:len(w.lists)-1]
+	}
+	if len(w.lists) == depth && w.lists[depth-1] != tag {
+		w.b.WriteString("</li></" + w.lists[depth-1] + ">")
+		w.lists = w.lists[:depth-1]
+	}
+	if len(w.lists) == depth {
+		w.b.WriteString("</li>")
+	}
+	for len(w.lists) < depth {
+		w.b.WriteString("<" + tag + ">")
+		w.lists = append(w.lists, tag)
+		if len(w.lists) < depth {
+			w.b.WriteString("<li>")
+		}
+	}
+	w.b.WriteString("<li>" + content)
+}
+
+func (w *docxWriter) table(tbl *docxNode) {
+	w.b.WriteString("<table>")
+	for _, tr := range tbl.childrenNamed("tr") {
+		w.b.WriteString("<tr>")
+		for _, tc := range tr.childrenNamed("tc") {
+			props := tc.child("tcPr")
+			attrs := ""
+			if span, err := strconv.Atoi(props.child("gridSpan").attr("val")); err == nil && span > 1 {
+				attrs = fmt.Sprintf(` colspan="%d"`, span)
+			}
+			if merge := props.child("vMerge"); merge != nil && merge.attr("val") != "restart" {
+				w.doc.addNote("Merged table cells were split into separate cells")
+				w.b.WriteString("<td" + attrs + "></td>")
+				continue
+			}
+			cell := &docxWriter{f: w.f, doc: w.doc}
+			cell.blocks(tc.children)
+			cell.close()
+			w.b.WriteString("<td" + attrs + ">" + cell.b.String() + "</td>")
+		}
+		w.b.WriteString("</tr>")
+	}
+	w.b.WriteString("</table>")
+}
+
+// docxFormat is the character formatting of a run.
+type docxFormat struct {
+	bold, italic, strike, code, sup, sub bool
+}
+
+// docxSpan is a piece of inline HTML. Text spans with the same format are
+// merged before they are wrapped, links and images are kept as they are.
+type docxSpan struct {
+	format docxFormat
+	html   string
+	text   bool
+}
+
+func (w *docxWriter) inline(nodes []*docxNode) string {
+	return renderDocxSpans(w.spans(nodes))
+}
+
+func (w *docxWriter) spans(nodes []*docxNode) []docxSpan {
+	var out []docxSpan
+	for _, n := range nodes {
+		switch n.name {
+		case "r":
+			out = append(out, w.run(n)...)
+		case "hyperlink":
+			inner := w.inline(n.children)
+			href := ""
+			if rel, ok := w.f.rels[n.attr("id")]; ok && rel.external {
+				href = rel.target
+			} else if anchor := n.attr("anchor"); anchor != "" {
+				href = "#" + anchor
+			}
+			if href == "" {
+				out = append(out, docxSpan{html: inner})
+				continue
+			}
+			out = append(out, docxSpan{html: `<a href="` + html.EscapeString(href) + `">` + inner + `</a>`})
+		case "ins", "moveTo", "smartTag", "customXml", "fldSimple":
+			out = append(out, w.spans(n.children)...)
+		case "sdt":
+			out = append(out, w.spans(n.child("sdtContent").children)...)
+		case "del", "moveFrom":
+			w.doc.addNote("Tracked changes were accepted; deleted text was not imported")
+		case "oMath", "oMathPara":
+			w.doc.addNote("Equations were converted to plain text")
+			var text strings.Builder
+			for _, t := range collectDocxNodes(n, "t") {
+				text.WriteString(t.text)
+			}
+			out = append(out, docxSpan{html: "<code>" + html.EscapeString(text.String()) + "</code>"})
+		}
+	}
+	return out
+}
+
+func collectDocxNodes(n *docxNode, name string) []*docxNode {
+	var out []*docxNode
+	for _, c := range n.children {
+		if c.name == name {
+			out = append(out, c)
+			continue
+		}
+		out = append(out, collectDocxNodes(c, name)...)
+	}
+	return out
+}
+
+func (w *docxWriter) run(r *docxNode) []docxSpan {
+	props := r.child("rPr")
+	format := docxFormat{
+		bold:   props.child("b").enabled(),
+		italic: props.child("i").enabled(),
+		strike: props.child("strike").enabled() || props.child("dstrike").enabled(),
+		sup:    props.child("vertAlign").attr("val") == "superscript",
+		sub:    props.child("vertAlign").attr("val") == "subscript",
+	}
+	if style := props.child("rStyle").attr("val"); style != "" && w.f.style(style).code {
+		format.code = true
+	}
+	if font := strings.ToLower(props.child("rFonts").attr("ascii")); strings.Contains(font, "courier") || strings.Contains(font, "consolas") || strings.Contains(font, "mono") {
+		format.code = true
+	}
+
+	var out []docxSpan
+	text := func(s string) {
+		out = append(out, docxSpan{format: format, html: html.EscapeString(s), text: true})
+	}
+	for _, n := range r.children {
+		switch n.name {
+		case "t":
+			text(n.text)
+		case "tab":
+			text("\t")
+		case "noBreakHyphen":
+			text("-")
+		case "br", "cr":
+			if t := n.attr("type"); t != "page" && t != "column" {
+				out = append(out, docxSpan{html: "<br>"})
+			}
+		case "drawing", "pict":
+			out = append(out, w.drawing(n)...)
+		case "object":
+			w.doc.addNote("Embedded objects were removed")
+		case "footnoteReference", "endnoteReference":
+			kind := strings.TrimSuffix(n.name, "Reference")
+			if _, ok := w.f.notes[kind+"/"+n.attr("id")]; ok {
+				w.f.noteRefs = append(w.f.noteRefs, kind+"/"+n.attr("id"))
+				out = append(out, docxSpan{html: fmt.Sprintf("<sup>[%d]</sup>", len(w.f.noteRefs))})
+			}
+		case "commentReference":
+			w.doc.addNote("Comments were not imported")
+		}
+	}
+	return out
+}
+
+// drawing renders the images of a drawing; text boxes are added after the
+// paragraph, charts and diagrams are noted.
+func (w *docxWriter) drawing(n *docxNode) []docxSpan {
+	var out []docxSpan
+	if n.find("chart") != nil {
+		w.doc.addNote("Charts were removed")
+	}
+	if n.find("relIds") != nil {
+		w.doc.addNote("SmartArt graphics were removed")
+	}
+	if box := n.find("txbxContent"); box != nil {
+		w.doc.addNote("Text boxes were converted to regular paragraphs")
+		inner := &docxWriter{f: w.f, doc: w.doc}
+		inner.blocks(box.children)
+		inner.close()
+		w.pending.WriteString(inner.b.String())
+	}
+
+	alt := n.find("docPr").attr("descr")
+	if alt == "" {
+		alt = n.find("docPr").attr("title")
+	}
+	id := n.find("blip").attr("embed")
+	if id == "" {
+		id = n.find("imagedata").attr("id")
+	}
+	if id == "" {
+		if link := n.find("blip").attr("link"); link != "" {
+			w.doc.addNote("Linked image %q was not imported", w.f.rels[link].target)
+		}
+		return out
+	}
+	rel, ok := w.f.rels[id]
+	if !ok || rel.external {
+		w.doc.addNote("Linked image %q was not imported", rel.target)
+		return out
+	}
+	return append(out, docxSpan{html: `<img src="` + html.EscapeString(docxMediaScheme+rel.target) + `" alt="` + html.EscapeString(alt) + `">`})
+}
+
+// footnotes adds the referenced footnotes and endnotes as a numbered list.
+func (w *docxWriter) footnotes() {
+	if len(w.f.noteRefs) == 0 {
+		return
+	}
+	w.b.WriteString("<hr><ol>")
+	// Notes may reference notes themselves, so the list can grow.
+	for i := 0; i < len(w.f.noteRefs); i++ {
+		var parts []string
+		for _, p := range w.f.notes[w.f.noteRefs[i]].childrenNamed("p") {
+			if text := strings.TrimSpace(w.inline(p.children)); text != "" {
+				parts = append(parts, text)
+			}
+		}
+		w.b.WriteString("<li>" + strings.Join(parts, " ") + "</li>")
+	}
+	w.b.WriteString("</ol>")
+}
+
+func renderDocxSpans(spans []docxSpan) string {
+	var b strings.Builder
+	for i := 0; i < len(spans); {
+		span := spans[i]
+		if !span.text {
+			b.WriteString(span.html)
+			i++
+			continue
+		}
+		var text strings.Builder
+		for ; i < len(spans) && spans[i].text && spans[i].format == span.format; i++ {
+			text.WriteString(spans[i].html)
+		}
+		b.WriteString(wrapDocxFormat(text.String(), span.format))
+	}
+	return b.String()
+}
+
+func wrapDocxFormat(text string, format docxFormat) string {
+	wrap := func(tag string) {
+		text = "<" + tag + ">" + text + "</" + tag + ">"
+	}
+	if format.code {
+		wrap("code")
+	}
+	if format.sup {
+		wrap("sup")
+	}
+	if format.sub {
+		wrap("sub")
+	}
+	if format.strike {
+		wrap("del")
+	}
+	if format.italic {
+		wrap("em")
+	}
+	if format.bold {
+		wrap("strong")
+	}
+	return text
+}
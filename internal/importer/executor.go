@@ -3,17 +3,20 @@ package importer
 import (
 	"fmt"
 	"log/slog"
+	"path"
 	"path/filepath"
 	"time"
 
 	"github.com/perber/wiki/internal/core/assets"
 	"github.com/perber/wiki/internal/core/markdown"
+	"github.com/perber/wiki/internal/core/tree"
 )
 
 type ExecutionResult struct {
 	ImportedCount  int                   `json:"imported_count"`
 	UpdatedCount   int                   `json:"updated_count"`
 	SkippedCount   int                   `json:"skipped_count"`
+	RemovedCount   int                   `json:"removed_count"` // pages of removed source files that were deleted or trashed
 	Items          []ExecutionItemResult `json:"items"`
 	TreeHash       string                `json:"tree_hash"`        // hash of the state of the wiki tree after import
 	TreeHashBefore string                `json:"tree_hash_before"` // hash of the state of the wiki tree before import
@@ -33,11 +36,14 @@ const (
 	ExecutionActionCreated ExecutionAction = "created"
 	ExecutionActionUpdated ExecutionAction = "updated"
 	ExecutionActionSkipped ExecutionAction = "skipped"
+	ExecutionActionDeleted ExecutionAction = "deleted"
+	ExecutionActionTrashed ExecutionAction = "trashed"
 )
 
 type ExecutionItemResult struct {
 	SourcePath string          `json:"source_path"`
 	TargetPath string          `json:"target_path"`
+	PageID     string          `json:"page_id,omitempty"`
 	Action     ExecutionAction `json:"action"`
 	Error      *string         `json:"error,omitempty"`
 	Notes      []string        `json:"notes,omitempty"`
@@ -89,6 +95,23 @@ func buildImportedContent(mdFile *markdown.MarkdownFile) (string, error) {
 	return markdown.BuildMarkdownWithExtraFrontmatter(fm.ExtraFields, mdFile.GetContent())
 }
 
+// mergeExistingFrontmatter adds the frontmatter fields of an existing page
+// that the imported file does not set.
+func mergeExistingFrontmatter(mdFile *markdown.MarkdownFile, existing *tree.Page) {
+	existingFM, _, has, err := markdown.ParseFrontmatter(existing.RawContent)
+	if err != nil || !has || len(existingFM.ExtraFields) == 0 {
+		return
+	}
+	fields := map[string]interface{}{}
+	for key, value := range existingFM.ExtraFields {
+		fields[key] = value
+	}
+	for key, value := range mdFile.GetFrontmatter().ExtraFields {
+		fields[key] = value
+	}
+	mdFile.SetExtraFields(fields)
+}
+
 // Execute runs the import based on the provided plan
 func (e *Executor) Execute(userID string) (*ExecutionResult, error) {
 	beforeExecution := e.wiki.TreeHash()
@@ -143,10 +166,23 @@ func (e *Executor) Execute(userID string) (*ExecutionResult, error) {
 			Error:      nil,
 		}
 
-		switch item.Action {
-		case PlanActionCreate:
-			// Creates the page or section and also all necessary parent sections
-			page, err := e.wiki.EnsurePath(userID, item.TargetPath, item.Title, &item.Kind)
+		action := item.Action
+		if item.Excluded {
+			action = PlanActionSkip
+		}
+
+		switch action {
+		case PlanActionCreate, PlanActionUpdate:
+			var page *tree.Page
+			var err error
+			if action == PlanActionCreate {
+				// Creates the page or section and also all necessary parent sections
+				page, err = e.wiki.EnsurePath(userID, item.TargetPath, item.Title, &item.Kind)
+			} else if item.ExistingID == nil {
+				err = fmt.Errorf("no existing page to update")
+			} else {
+				page, err = e.wiki.GetPage(*item.ExistingID)
+			}
 			if err != nil {
 				errMsg := err.Error()
 				execItem.Action = ExecutionActionSkipped
@@ -167,6 +203,10 @@ func (e *Executor) Execute(userID string) (*ExecutionResult, error) {
 				e.logger.Error("Could not create page", "target_path", item.TargetPath, "error", errMsg)
 				continue
 			}
+			title, slug := page.Title, page.Slug
+			if action == PlanActionUpdate {
+				title, slug = item.Title, item.DesiredSlug
+			}
 			sourceAbs := filepath.Join(e.planOptions.SourceBasePath, filepath.FromSlash(item.SourcePath))
 			mdFile, err := markdown.LoadMarkdownFile(sourceAbs)
 			if err != nil {
@@ -178,6 +218,9 @@ func (e *Executor) Execute(userID string) (*ExecutionResult, error) {
 				e.logger.Error("Failed to load source file", "source_path", sourceAbs, "error", err)
 				continue
 			}
+			if action == PlanActionUpdate && e.plan.strategyFor(item) == ConflictStrategyMergeFrontmatter {
+				mergeExistingFrontmatter(mdFile, page)
+			}
 			importedContent, err := buildImportedContent(mdFile)
 			if err != nil {
 				errMsg := err.Error()
@@ -198,7 +241,16 @@ func (e *Executor) Execute(userID string) (*ExecutionResult, error) {
 				e.logger.Error("Failed to transform imported content", "source_path", sourceAbs, "error", err)
 				continue
 			}
-			if _, err := e.wiki.UpdatePage(userID, page.ID, page.Title, page.Slug, &importedContent, &page.Kind); err != nil {
+			if err := transformer.uploadSidecarAssets(userID, item.SourcePath, page, e.wiki); err != nil {
+				errMsg := err.Error()
+				execItem.Action = ExecutionActionSkipped
+				execItem.Error = &errMsg
+				result.SkippedCount++
+				result.Items = append(result.Items, execItem)
+				e.logger.Error("Failed to import page assets", "source_path", sourceAbs, "error", err)
+				continue
+			}
+			if _, err := e.wiki.UpdatePage(userID, page.ID, title, slug, &importedContent, &page.Kind); err != nil {
 				errMsg := err.Error()
 				execItem.Action = ExecutionActionSkipped
 				execItem.Error = &errMsg
@@ -207,10 +259,43 @@ func (e *Executor) Execute(userID string) (*ExecutionResult, error) {
 				e.logger.Error("Failed to update page content", "page_id", page.ID, "error", err)
 				continue
 			}
-			execItem.Action = ExecutionActionCreated
-			result.ImportedCount++
-			e.logger.Info("Imported page", "source_path", item.SourcePath, "target_path", item.TargetPath, "page_id", page.ID)
+			if e.planOptions.ImportHistory {
+				if note := e.importHistory(userID, item.SourcePath, page, transformer); note != "" {
+					execItem.Notes = append(execItem.Notes, note)
+					e.logger.Warn("Failed to import page history", "source_path", item.SourcePath, "note", note)
+				}
+			}
+			execItem.PageID = page.ID
+			if action == PlanActionUpdate {
+				execItem.Action = ExecutionActionUpdated
+				result.UpdatedCount++
+				e.logger.Info("Updated page", "source_path", item.SourcePath, "target_path", item.TargetPath, "page_id", page.ID)
+			} else {
+				execItem.Action = ExecutionActionCreated
+				result.ImportedCount++
+				e.logger.Info("Imported page", "source_path", item.SourcePath, "target_path", item.TargetPath, "page_id", page.ID)
+			}
+		case PlanActionDelete, PlanActionTrash:
+			execItem.PageID = *item.ExistingID
+			if err := e.removePage(userID, item); err != nil {
+				errMsg := err.Error()
+				execItem.Action = ExecutionActionSkipped
+				execItem.Error = &errMsg
+				result.SkippedCount++
+				result.Items = append(result.Items, execItem)
+				e.logger.Error("Failed to remove page", "source_path", item.SourcePath, "page_id", *item.ExistingID, "error", err)
+				continue
+			}
+			execItem.Action = ExecutionActionDeleted
+			if action == PlanActionTrash {
+				execItem.Action = ExecutionActionTrashed
+			}
+			result.RemovedCount++
+			e.logger.Info("Removed page", "source_path", item.SourcePath, "target_path", item.TargetPath, "action", action)
 		case PlanActionSkip:
+			if item.ExistingID != nil {
+				execItem.PageID = *item.ExistingID
+			}
 			execItem.Action = ExecutionActionSkipped
 			e.logger.Info("Skipped page", "source_path", item.SourcePath, "target_path", item.TargetPath)
 			result.SkippedCount++
@@ -243,6 +328,36 @@ func (e *Executor) Execute(userID string) (*ExecutionResult, error) {
 	return result, nil
 }
 
+// removePage deletes or trashes the page of a removed source file. Trashed
+// pages are renamed to their slug in the trash before they are moved.
+func (e *Executor) removePage(userID string, item PlanItem) error {
+	if item.ExistingID == nil {
+		return fmt.Errorf("no page to remove")
+	}
+	if item.Action == PlanActionDelete {
+		return e.wiki.DeletePage(userID, *item.ExistingID)
+	}
+
+	page, err := e.wiki.GetPage(*item.ExistingID)
+	if err != nil {
+		return err
+	}
+	sectionKind := tree.NodeKindSection
+	trash, err := e.wiki.EnsurePath(userID, path.Dir(item.TargetPath), importTrashTitle, &sectionKind)
+	if err != nil {
+		return err
+	}
+	if trash == nil {
+		return fmt.Errorf("could not create %s", path.Dir(item.TargetPath))
+	}
+	if slug := path.Base(item.TargetPath); slug != page.Slug {
+		if _, err := e.wiki.UpdatePage(userID, page.ID, page.Title, slug, nil, &page.Kind); err != nil {
+			return err
+		}
+	}
+	return e.wiki.MovePage(userID, page.ID, trash.ID)
+}
+
 func (e *Executor) reportProgress(progress ExecutionProgress, result *ExecutionResult) {
 	if e.progressFn == nil {
 		return
@@ -29,6 +29,11 @@ type fakeExecWiki struct {
 	uploadCalls        int
 	uploadedAssets     []string
 	lastUploadMaxBytes int64
+	importedRevisions  map[string][]ImportedRevision
+	importRevisionsErr error
+	pages              map[string]*tree.Page
+	deletedPages       []string
+	movedPages         []string
 }
 
 func (f *fakeExecWiki) TreeHash() string { return f.hash }
@@ -37,6 +42,13 @@ func (f *fakeExecWiki) LookupPagePath(path string) (*tree.PathLookup, error) {
 	panic("not used by Executor")
 }
 
+func (f *fakeExecWiki) GetPage(id string) (*tree.Page, error) {
+	if page, ok := f.pages[id]; ok {
+		return page, nil
+	}
+	return nil, tree.ErrPageNotFound
+}
+
 func (f *fakeExecWiki) EnsurePath(userID string, targetPath string, title string, kind *tree.NodeKind) (*tree.Page, error) {
 	f.ensureCalls++
 	f.ensureTargets = append(f.ensureTargets, targetPath)
@@ -61,6 +73,16 @@ func (f *fakeExecWiki) UpdatePage(userID string, id, title, slug string, content
 	return &tree.Page{PageNode: &tree.PageNode{ID: id, Title: title, Slug: slug, Kind: *kind}}, nil
 }
 
+func (f *fakeExecWiki) DeletePage(userID, id string) error {
+	f.deletedPages = append(f.deletedPages, id)
+	return nil
+}
+
+func (f *fakeExecWiki) MovePage(userID, id, parentID string) error {
+	f.movedPages = append(f.movedPages, id+"->"+parentID)
+	return nil
+}
+
 func (f *fakeExecWiki) UploadAsset(userID, pageID string, file multipart.File, filename string, maxBytes int64) (string, error) {
 	f.uploadCalls++
 	f.uploadedAssets = append(f.uploadedAssets, filename)
@@ -68,6 +90,17 @@ func (f *fakeExecWiki) UploadAsset(userID, pageID string, file multipart.File, f
 	return "/assets/" + pageID + "/" + filename, nil
 }
 
+func (f *fakeExecWiki) ImportRevisions(pageID string, revisions []ImportedRevision, mapAuthors bool) error {
+	if f.importRevisionsErr != nil {
+		return f.importRevisionsErr
+	}
+	if f.importedRevisions == nil {
+		f.importedRevisions = map[string][]ImportedRevision{}
+	}
+	f.importedRevisions[pageID] = append(f.importedRevisions[pageID], revisions...)
+	return nil
+}
+
 func writeTmp(t *testing.T, dir, rel, content string) {
 	t.Helper()
 	abs := filepath.Join(dir, filepath.FromSlash(rel))
@@ -259,13 +292,90 @@ func TestExecutor_Create_EnsurePathError_SkipsItem(t *testing.T) {
 	}
 }
 
+func TestExecutor_Update_OverwritesOrMergesExistingPage(t *testing.T) {
+	for _, tc := range []struct {
+		strategy ConflictStrategy
+		want     map[string]interface{}
+	}{
+		{ConflictStrategyOverwrite, map[string]interface{}{"status": "imported"}},
+		{ConflictStrategyMergeFrontmatter, map[string]interface{}{"status": "imported", "owner": "team-a"}},
+	} {
+		t.Run(string(tc.strategy), func(t *testing.T) {
+			tmp := t.TempDir()
+			writeTmp(t, tmp, "a.md", "---\nstatus: imported\n---\nNew body")
+
+			existingID := "existing-a"
+			w := &fakeExecWiki{
+				hash: "h1",
+				pages: map[string]*tree.Page{
+					existingID: {
+						PageNode:   &tree.PageNode{ID: existingID, Title: "Old", Slug: "a", Kind: tree.NodeKindPage},
+						RawContent: "---\nleafwiki_id: existing-a\nowner: team-a\nstatus: draft\n---\nOld body",
+					},
+				},
+			}
+			plan := &PlanResult{
+				TreeHash:         "h1",
+				ConflictStrategy: tc.strategy,
+				Items: []PlanItem{
+					{SourcePath: "a.md", TargetPath: "docs/a", Title: "A", DesiredSlug: "a", Kind: tree.NodeKindPage, Exists: true, ExistingID: &existingID, Action: PlanActionUpdate},
+				},
+			}
+
+			res, err := NewExecutor(plan, &PlanOptions{SourceBasePath: tmp}, 0, w, slog.Default()).Execute("user1")
+			if err != nil {
+				t.Fatalf("Execute err: %v", err)
+			}
+			if res.UpdatedCount != 1 || res.ImportedCount != 0 || res.Items[0].Action != ExecutionActionUpdated {
+				t.Fatalf("result = %#v", res)
+			}
+			if w.ensureCalls != 0 || len(w.updateTitles) != 1 || w.updateTitles[0] != "A" {
+				t.Fatalf("calls ensure=%d titles=%v", w.ensureCalls, w.updateTitles)
+			}
+			fm, body, _, err := markdown.ParseFrontmatter(*w.lastUpdatedContent)
+			if err != nil {
+				t.Fatalf("ParseFrontmatter err: %v", err)
+			}
+			if body != "New body" || len(fm.ExtraFields) != len(tc.want) {
+				t.Fatalf("content = %q", *w.lastUpdatedContent)
+			}
+			for key, value := range tc.want {
+				if fm.ExtraFields[key] != value {
+					t.Fatalf("%s = %#v, want %#v", key, fm.ExtraFields[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestExecutor_ExcludedItem_IsSkipped(t *testing.T) {
+	w := &fakeExecWiki{hash: "h1"}
+	plan := &PlanResult{
+		TreeHash: "h1",
+		Items: []PlanItem{
+			{SourcePath: "a.md", TargetPath: "docs/a", Title: "A", Kind: tree.NodeKindPage, Action: PlanActionCreate, Excluded: true},
+		},
+	}
+
+	res, err := NewExecutor(plan, &PlanOptions{SourceBasePath: t.TempDir()}, 0, w, slog.Default()).Execute("user1")
+	if err != nil {
+		t.Fatalf("Execute err: %v", err)
+	}
+	if res.SkippedCount != 1 || res.Items[0].Action != ExecutionActionSkipped || res.Items[0].Error != nil {
+		t.Fatalf("result = %#v", res)
+	}
+	if w.ensureCalls != 0 || w.updateCalls != 0 {
+		t.Fatalf("expected no wiki calls, got ensure=%d update=%d", w.ensureCalls, w.updateCalls)
+	}
+}
+
 func TestExecutor_UnknownAction_SkipsItem(t *testing.T) {
 	tmp := t.TempDir()
 	w := &fakeExecWiki{hash: "h1"}
 	plan := &PlanResult{
 		TreeHash: "h1",
 		Items: []PlanItem{
-			{SourcePath: "a.md", TargetPath: "docs/a", Action: PlanActionUpdate}, // not handled in switch
+			{SourcePath: "a.md", TargetPath: "docs/a", Action: PlanAction("rename")}, // not handled in switch
 		},
 	}
 	opts := &PlanOptions{SourceBasePath: tmp}
@@ -428,6 +538,101 @@ func TestExecutor_Create_UploadsRelativeAndRootAssets(t *testing.T) {
 	}
 }
 
+func TestExecutor_Create_UploadsUnreferencedSidecarAssetsOnce(t *testing.T) {
+	tmp := t.TempDir()
+	writeTmp(t, tmp, "Guides/Setup.md", "# Setup\n\n![Logo](Setup.assets/logo.png)\n")
+	writeTmp(t, tmp, "Guides/Setup.assets/logo.png", "png-bytes")
+	writeTmp(t, tmp, "Guides/Setup.assets/notes.txt", "text")
+	writeTmp(t, tmp, "Guides/Setup.assets/.DS_Store", "junk")
+
+	w := &fakeExecWiki{hash: "h1"}
+	plan := &PlanResult{
+		TreeHash: "h1",
+		Items: []PlanItem{
+			{SourcePath: "Guides/Setup.md", TargetPath: "guides/setup", Title: "Setup", Kind: tree.NodeKindPage, Action: PlanActionCreate},
+		},
+	}
+
+	ex := NewExecutor(plan, &PlanOptions{SourceBasePath: tmp}, 1234, w, slog.Default())
+	if _, err := ex.Execute("user1"); err != nil {
+		t.Fatalf("Execute err: %v", err)
+	}
+
+	if got := strings.Join(w.uploadedAssets, ","); got != "logo.png,notes.txt" {
+		t.Fatalf("uploaded assets = %q, want logo.png,notes.txt", got)
+	}
+	if w.lastUpdatedContent == nil || !strings.Contains(*w.lastUpdatedContent, "![Logo](/assets/p1/logo.png)") {
+		t.Fatalf("expected sidecar asset link rewrite, got: %v", w.lastUpdatedContent)
+	}
+}
+
+func TestExecutor_Create_ImportsHistoryWhenEnabled(t *testing.T) {
+	tmp := t.TempDir()
+	writeTmp(t, tmp, "Guides/Setup.md", "Current\n")
+	writeTmp(t, tmp, "Guides/Setup.history.json", `[
+		{"author": "Alice", "timestamp": "2012-03-04T05:06:07Z", "summary": "created", "content": "First ![Logo](Setup.assets/logo.png)"},
+		{"author": "Bob", "timestamp": "2013-03-04T05:06:07Z", "content": "Current\n"}
+	]`)
+	writeTmp(t, tmp, "Guides/Setup.assets/logo.png", "png-bytes")
+
+	plan := &PlanResult{
+		TreeHash: "h1",
+		Items: []PlanItem{
+			{SourcePath: "Guides/Setup.md", TargetPath: "guides/setup", Title: "Setup", Kind: tree.NodeKindPage, Action: PlanActionCreate},
+		},
+	}
+
+	w := &fakeExecWiki{hash: "h1"}
+	if _, err := NewExecutor(plan, &PlanOptions{SourceBasePath: tmp}, 0, w, slog.Default()).Execute("user1"); err != nil {
+		t.Fatalf("Execute err: %v", err)
+	}
+	if len(w.importedRevisions) != 0 {
+		t.Fatalf("expected history to be ignored without ImportHistory, got %#v", w.importedRevisions)
+	}
+
+	w = &fakeExecWiki{hash: "h1"}
+	if _, err := NewExecutor(plan, &PlanOptions{SourceBasePath: tmp, ImportHistory: true}, 0, w, slog.Default()).Execute("user1"); err != nil {
+		t.Fatalf("Execute err: %v", err)
+	}
+	revisions := w.importedRevisions["p1"]
+	if len(revisions) != 2 {
+		t.Fatalf("expected 2 imported revisions, got %#v", w.importedRevisions)
+	}
+	if revisions[0].Author != "Alice" || revisions[0].Summary != "created" || revisions[0].Timestamp.Year() != 2012 {
+		t.Fatalf("unexpected first revision: %#v", revisions[0])
+	}
+	if revisions[0].Content != "First ![Logo](/assets/p1/logo.png)" {
+		t.Fatalf("expected links of old revisions to be rewritten, got %q", revisions[0].Content)
+	}
+	if got := strings.Join(w.uploadedAssets, ","); got != "logo.png" {
+		t.Fatalf("uploaded assets = %q, want logo.png", got)
+	}
+}
+
+func TestExecutor_Create_HistoryFailureBecomesNote(t *testing.T) {
+	tmp := t.TempDir()
+	writeTmp(t, tmp, "Setup.md", "Current\n")
+	writeTmp(t, tmp, "Setup.history.json", `[{"author": "Alice", "timestamp": "2012-03-04T05:06:07Z", "content": "First"}]`)
+
+	w := &fakeExecWiki{hash: "h1", importRevisionsErr: ErrRevisionsDisabled}
+	plan := &PlanResult{
+		TreeHash: "h1",
+		Items: []PlanItem{
+			{SourcePath: "Setup.md", TargetPath: "setup", Title: "Setup", Kind: tree.NodeKindPage, Action: PlanActionCreate},
+		},
+	}
+	res, err := NewExecutor(plan, &PlanOptions{SourceBasePath: tmp, ImportHistory: true}, 0, w, slog.Default()).Execute("user1")
+	if err != nil {
+		t.Fatalf("Execute err: %v", err)
+	}
+	if res.ImportedCount != 1 || res.Items[0].Action != ExecutionActionCreated {
+		t.Fatalf("expected page to be imported, got %#v", res)
+	}
+	if len(res.Items[0].Notes) != 1 || !strings.Contains(res.Items[0].Notes[0], "revisions are disabled") {
+		t.Fatalf("notes = %#v", res.Items[0].Notes)
+	}
+}
+
 func TestExecutor_Create_WikiLinkToNonImageAssetStaysNormalLink(t *testing.T) {
 	tmp := t.TempDir()
 	writeTmp(t, tmp, "Guides/Setup.md", strings.Join([]string{
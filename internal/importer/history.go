@@ -0,0 +1,91 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/perber/wiki/internal/core/tree"
+)
+
+// Converters of exports with a page history store the earlier versions of
+// a page next to its Markdown file, e.g. "docs/guide.history.json" for
+// "docs/guide.md". With PlanOptions.ImportHistory they are imported as
+// revisions of the page.
+const historyFileSuffix = ".history.json"
+
+// ErrRevisionsDisabled is returned by ImporterWiki.ImportRevisions when the
+// wiki does not keep revisions.
+var ErrRevisionsDisabled = errors.New("revisions are disabled")
+
+// ImportedRevision is an earlier version of an imported page.
+type ImportedRevision struct {
+	Author    string    `json:"author"`
+	Timestamp time.Time `json:"timestamp"`
+	Summary   string    `json:"summary,omitempty"`
+	// Content is the Markdown body without frontmatter.
+	Content string `json:"content"`
+}
+
+// HistoryFile returns the history file of a Markdown source path.
+func HistoryFile(sourcePath string) string {
+	return strings.TrimSuffix(sourcePath, path.Ext(sourcePath)) + historyFileSuffix
+}
+
+func writeHistoryFile(file string, revisions []ImportedRevision) error {
+	raw, err := json.MarshalIndent(revisions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, raw, 0o644)
+}
+
+func loadHistoryFile(file string) ([]ImportedRevision, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var revisions []ImportedRevision
+	if err := json.Unmarshal(raw, &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// importHistory records the history file of an imported page as revisions.
+// The links of every version are rewritten like those of the page itself.
+// A history that cannot be imported does not fail the page; the returned
+// note says why it is missing.
+func (e *Executor) importHistory(userID, sourcePath string, page *tree.Page, transformer *contentTransformer) string {
+	file := filepath.Join(e.planOptions.SourceBasePath, filepath.FromSlash(HistoryFile(sourcePath)))
+	revisions, err := loadHistoryFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return ""
+	}
+	if err != nil {
+		return fmt.Sprintf("Page history could not be read: %v", err)
+	}
+
+	for i := range revisions {
+		content, err := transformer.TransformContent(userID, sourcePath, page, revisions[i].Content, e.wiki)
+		if err != nil {
+			return fmt.Sprintf("Page history was not imported: %v", err)
+		}
+		revisions[i].Content = content
+	}
+	if err := e.wiki.ImportRevisions(page.ID, revisions, e.planOptions.MapHistoryAuthors); err != nil {
+		if errors.Is(err, ErrRevisionsDisabled) {
+			return "Page history was not imported because revisions are disabled"
+		}
+		return fmt.Sprintf("Page history was not imported: %v", err)
+	}
+	return ""
+}
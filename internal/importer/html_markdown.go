@@ -0,0 +1,710 @@
+package importer
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// htmlHardBreak marks a <br> while inline content is collected; it is
+// replaced once the surrounding block knows how line breaks are written.
+const htmlHardBreak = "\x1e"
+
+var (
+	htmlSpacePattern       = regexp.MustCompile(`[ \t\r\n\f\x{00a0}]+`)
+	markdownBlockStart     = regexp.MustCompile(`^(#{1,6}(\s|$)|>|[-+]\s|[-=]{3,}\s*$)`)
+	markdownOrderedStart   = regexp.MustCompile(`^(\d{1,9})([.)])(\s|$)`)
+	htmlCodeLanguageClass  = regexp.MustCompile(`(?:^|\s)(?:language|lang)-([\w#+-]+)`)
+	markdownListItemMarker = regexp.MustCompile(`^(\s*)([-+*]|\d{1,9}[.)])\s`)
+)
+
+// htmlSkippedElements are never converted, including their content.
+var htmlSkippedElements = map[atom.Atom]bool{
+	atom.Head:     true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Title:    true,
+	atom.Meta:     true,
+	atom.Link:     true,
+	atom.Button:   true,
+	atom.Select:   true,
+	atom.Textarea: true,
+	atom.Iframe:   true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Svg:      true,
+	atom.Canvas:   true,
+}
+
+// htmlContainerElements separate their content from the surrounding text
+// but add no Markdown of their own.
+var htmlContainerElements = map[atom.Atom]bool{
+	atom.P:          true,
+	atom.Div:        true,
+	atom.Section:    true,
+	atom.Article:    true,
+	atom.Main:       true,
+	atom.Header:     true,
+	atom.Footer:     true,
+	atom.Aside:      true,
+	atom.Nav:        true,
+	atom.Figure:     true,
+	atom.Figcaption: true,
+	atom.Center:     true,
+	atom.Address:    true,
+	atom.Body:       true,
+	atom.Html:       true,
+	atom.Form:       true,
+	atom.Fieldset:   true,
+	atom.Li:         true,
+	atom.Dd:         true,
+	atom.Td:         true,
+	atom.Th:         true,
+}
+
+// htmlInlineElements are converted to inline Markdown.
+var htmlInlineElements = map[atom.Atom]bool{
+	atom.A:      true,
+	atom.Abbr:   true,
+	atom.B:      true,
+	atom.Br:     true,
+	atom.Cite:   true,
+	atom.Code:   true,
+	atom.Del:    true,
+	atom.Em:     true,
+	atom.Font:   true,
+	atom.I:      true,
+	atom.Img:    true,
+	atom.Input:  true,
+	atom.Ins:    true,
+	atom.Kbd:    true,
+	atom.Label:  true,
+	atom.Mark:   true,
+	atom.Q:      true,
+	atom.S:      true,
+	atom.Samp:   true,
+	atom.Small:  true,
+	atom.Span:   true,
+	atom.Strike: true,
+	atom.Strong: true,
+	atom.Sub:    true,
+	atom.Sup:    true,
+	atom.Time:   true,
+	atom.Tt:     true,
+	atom.U:      true,
+	atom.Var:    true,
+}
+
+// htmlMarkdownOptions adapts the HTML to Markdown conversion to a source
+// format.
+type htmlMarkdownOptions struct {
+	// Link maps the destination of a link or image. ok=false unwraps the
+	// link (keeping its text) or replaces the image by its alt text.
+	Link func(href string, image bool) (dest string, ok bool)
+	// Element converts elements the source format handles itself, such as
+	// macros. ok=false falls back to the generic conversion.
+	Element func(c *htmlMarkdownConverter, n *html.Node) (markdown string, inline bool, ok bool)
+}
+
+// htmlMarkdownConverter converts HTML to the Markdown dialect of the editor:
+// GFM with tables and task lists, callouts (:::type) and collapsible blocks.
+type htmlMarkdownConverter struct {
+	opts htmlMarkdownOptions
+}
+
+// convertHTMLToMarkdown converts the content of an HTML node to Markdown.
+func convertHTMLToMarkdown(n *html.Node, opts htmlMarkdownOptions) string {
+	c := &htmlMarkdownConverter{opts: opts}
+	return c.blocks(n)
+}
+
+// markdownBlocks collects the blocks of a container; inline content is
+// buffered until the next block starts.
+type markdownBlocks struct {
+	blocks []string
+	lists  []bool
+	inline strings.Builder
+}
+
+func (b *markdownBlocks) flush() {
+	text := finishInline(b.inline.String(), "\\\n")
+	b.inline.Reset()
+	if text == "" {
+		return
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = escapeMarkdownBlockStart(line)
+	}
+	b.blocks = append(b.blocks, strings.Join(lines, "\n"))
+	b.lists = append(b.lists, false)
+}
+
+func (b *markdownBlocks) add(block string, list bool) {
+	b.flush()
+	block = strings.Trim(block, "\n")
+	if strings.TrimSpace(block) == "" {
+		return
+	}
+	b.blocks = append(b.blocks, block)
+	b.lists = append(b.lists, list)
+}
+
+// join separates blocks by blank lines; a nested list directly follows
+// the text of its list item.
+func (b *markdownBlocks) join(tight bool) string {
+	b.flush()
+	var out strings.Builder
+	for i, block := range b.blocks {
+		if i > 0 {
+			if tight && b.lists[i] {
+				out.WriteString("\n")
+			} else {
+				out.WriteString("\n\n")
+			}
+		}
+		out.WriteString(block)
+	}
+	return out.String()
+}
+
+// blocks converts the children of n as a sequence of Markdown blocks.
+func (c *htmlMarkdownConverter) blocks(n *html.Node) string {
+	return c.blocksExcept(n, nil)
+}
+
+// blocksExcept is blocks without the children for which skip returns true.
+func (c *htmlMarkdownConverter) blocksExcept(n *html.Node, skip func(*html.Node) bool) string {
+	var out markdownBlocks
+	c.walk(n, &out, skip)
+	return out.join(false)
+}
+
+func (c *htmlMarkdownConverter) walk(n *html.Node, out *markdownBlocks, skip func(*html.Node) bool) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if skip != nil && skip(child) {
+			continue
+		}
+		switch child.Type {
+		case html.TextNode:
+			out.inline.WriteString(escapeMarkdownText(collapseHTMLSpace(child.Data)))
+		case html.ElementNode:
+			c.element(child, out)
+		}
+	}
+}
+
+func (c *htmlMarkdownConverter) element(n *html.Node, out *markdownBlocks) {
+	if c.opts.Element != nil {
+		if md, inline, ok := c.opts.Element(c, n); ok {
+			if inline {
+				out.inline.WriteString(md)
+			} else {
+				out.add(md, false)
+			}
+			return
+		}
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		if text := finishInline(c.inline(n), " "); text != "" {
+			out.add(strings.Repeat("#", level)+" "+text, false)
+		}
+		return
+	case atom.Ul, atom.Ol:
+		out.add(c.list(n), true)
+		return
+	case atom.Pre:
+		out.add(markdownCodeFence(htmlText(n), htmlCodeLanguage(n)), false)
+		return
+	case atom.Blockquote:
+		out.add(prefixMarkdownLines(c.blocks(n), "> "), false)
+		return
+	case atom.Hr:
+		out.add("---", false)
+		return
+	case atom.Table:
+		out.add(c.table(n), false)
+		return
+	case atom.Dt:
+		if text := finishInline(c.inline(n), " "); text != "" {
+			out.add("**"+text+"**", false)
+		}
+		return
+	case atom.Details:
+		title := "Details"
+		if summary := findHTMLElement(n, func(e *html.Node) bool { return e.DataAtom == atom.Summary }); summary != nil {
+			if text := finishInline(c.inline(summary), " "); text != "" {
+				title = text
+			}
+		}
+		body := c.blocksExcept(n, func(e *html.Node) bool { return e.DataAtom == atom.Summary })
+		out.add(markdownCallout("collapsed", title, body), false)
+		return
+	}
+
+	switch {
+	case htmlSkippedElements[n.DataAtom]:
+	case htmlInlineElements[n.DataAtom]:
+		out.inline.WriteString(c.inlineElement(n))
+	case htmlContainerElements[n.DataAtom]:
+		out.flush()
+		c.walk(n, out, nil)
+		out.flush()
+	default:
+		c.walk(n, out, nil)
+	}
+}
+
+// inline converts the children of n to inline Markdown. Block elements are
+// flattened to their text; line breaks are marked with htmlHardBreak.
+func (c *htmlMarkdownConverter) inline(n *html.Node) string {
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		switch child.Type {
+		case html.TextNode:
+			b.WriteString(escapeMarkdownText(collapseHTMLSpace(child.Data)))
+		case html.ElementNode:
+			if c.opts.Element != nil {
+				if md, inline, ok := c.opts.Element(c, child); ok {
+					if !inline {
+						md = " " + strings.ReplaceAll(md, "\n", " ") + " "
+					}
+					b.WriteString(md)
+					continue
+				}
+			}
+			switch {
+			case htmlSkippedElements[child.DataAtom]:
+			case htmlInlineElements[child.DataAtom]:
+				b.WriteString(c.inlineElement(child))
+			default:
+				b.WriteString(" " + c.inline(child) + " ")
+			}
+		}
+	}
+	return b.String()
+}
+
+func (c *htmlMarkdownConverter) inlineElement(n *html.Node) string {
+	switch n.DataAtom {
+	case atom.B, atom.Strong:
+		return wrapMarkdownInline("**", c.inline(n))
+	case atom.I, atom.Em, atom.Cite, atom.Var:
+		return wrapMarkdownInline("*", c.inline(n))
+	case atom.Del, atom.S, atom.Strike:
+		return wrapMarkdownInline("~~", c.inline(n))
+	case atom.Code, atom.Kbd, atom.Samp, atom.Tt:
+		return markdownCodeSpan(collapseHTMLSpace(htmlText(n)))
+	case atom.Br:
+		return htmlHardBreak
+	case atom.A:
+		return c.link(n)
+	case atom.Img:
+		return c.image(n)
+	case atom.Input:
+		if !strings.EqualFold(htmlAttr(n, "type"), "checkbox") {
+			return ""
+		}
+		if htmlHasAttr(n, "checked") {
+			return "[x] "
+		}
+		return "[ ] "
+	default:
+		return c.inline(n)
+	}
+}
+
+func (c *htmlMarkdownConverter) link(n *html.Node) string {
+	text := c.inline(n)
+	href := strings.TrimSpace(htmlAttr(n, "href"))
+	if href == "" || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return text
+	}
+	dest, ok := href, true
+	if c.opts.Link != nil {
+		dest, ok = c.opts.Link(href, false)
+	}
+	if !ok {
+		return text
+	}
+
+	lead, label, trail := splitSurroundingSpace(text)
+	label = finishInline(label, " ")
+	if label == "" {
+		label = escapeMarkdownText(dest)
+	}
+	return lead + "[" + label + "](" + markdownDestination(dest) + ")" + trail
+}
+
+func (c *htmlMarkdownConverter) image(n *html.Node) string {
+	alt := escapeMarkdownText(collapseHTMLSpace(htmlAttr(n, "alt")))
+	src := strings.TrimSpace(htmlAttr(n, "src"))
+	if src == "" {
+		return alt
+	}
+	dest, ok := src, true
+	if c.opts.Link != nil {
+		dest, ok = c.opts.Link(src, true)
+	}
+	if !ok {
+		return alt
+	}
+	return "![" + alt + "](" + markdownDestination(dest) + ")"
+}
+
+// list converts <ul>/<ol>. Lists nested directly in a list (instead of in
+// an item) are attached to the preceding item.
+func (c *htmlMarkdownConverter) list(n *html.Node) string {
+	ordered := n.DataAtom == atom.Ol
+	number := 1
+	if start, err := strconv.Atoi(htmlAttr(n, "start")); err == nil && ordered {
+		number = start
+	}
+
+	var items []string
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		if child.DataAtom == atom.Ul || child.DataAtom == atom.Ol {
+			nested := c.list(child)
+			if nested == "" {
+				continue
+			}
+			if len(items) == 0 {
+				items = append(items, nested)
+				continue
+			}
+			items[len(items)-1] += "\n" + indentMarkdown(nested, listIndent(items[len(items)-1]))
+			continue
+		}
+		marker := "- "
+		if ordered {
+			marker = strconv.Itoa(number) + ". "
+			number++
+		}
+		items = append(items, c.listItem(child, marker))
+	}
+	return strings.Join(items, "\n")
+}
+
+func (c *htmlMarkdownConverter) listItem(li *html.Node, marker string) string {
+	var out markdownBlocks
+	c.walk(li, &out, nil)
+	body := out.join(true)
+	if body == "" {
+		return strings.TrimRight(marker, " ")
+	}
+	first, rest, found := strings.Cut(body, "\n")
+	if !found {
+		return marker + first
+	}
+	return marker + first + "\n" + indentMarkdown(rest, len(marker))
+}
+
+func listIndent(item string) int {
+	if m := markdownListItemMarker.FindStringSubmatch(item); m != nil {
+		return len(m[0])
+	}
+	return 2
+}
+
+// table converts a table to a GFM table. The first row becomes the header;
+// cell content is kept on one line.
+func (c *htmlMarkdownConverter) table(n *html.Node) string {
+	var rows [][]string
+	var visit func(e *html.Node)
+	visit = func(e *html.Node) {
+		for child := e.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch child.DataAtom {
+			case atom.Tr:
+				var row []string
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+						row = append(row, c.tableCell(cell))
+					}
+				}
+				rows = append(rows, row)
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				visit(child)
+			}
+		}
+	}
+	visit(n)
+
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	if width == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	writeRow := func(row []string) {
+		out.WriteString("|")
+		for i := 0; i < width; i++ {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			out.WriteString(" " + cell + " |")
+		}
+		out.WriteString("\n")
+	}
+	writeRow(rows[0])
+	out.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+func (c *htmlMarkdownConverter) tableCell(cell *html.Node) string {
+	text := c.blocks(cell)
+	text = strings.ReplaceAll(text, "\\\n", "<br>")
+	text = strings.ReplaceAll(text, "\n\n", "<br>")
+	text = strings.ReplaceAll(text, "\n", "<br>")
+	return strings.ReplaceAll(text, "|", `\|`)
+}
+
+// markdownCallout renders a callout (:::info Title … :::) or, for kind
+// "collapsed"/"collapsible", a collapsible block.
+func markdownCallout(kind, title, body string) string {
+	open := ":::" + kind
+	if title = strings.TrimSpace(strings.ReplaceAll(title, "\n", " ")); title != "" {
+		open += " " + title
+	}
+	return open + "\n" + strings.Trim(body, "\n") + "\n:::"
+}
+
+// markdownCodeFence renders a fenced code block whose fence is longer than
+// any backtick run in the code.
+func markdownCodeFence(code, language string) string {
+	code = strings.Trim(code, "\n")
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + code + "\n" + fence
+}
+
+func markdownCodeSpan(code string) string {
+	if strings.TrimSpace(code) == "" {
+		return code
+	}
+	fence := "`"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		return fence + " " + code + " " + fence
+	}
+	return fence + code + fence
+}
+
+func htmlCodeLanguage(pre *html.Node) string {
+	for _, n := range []*html.Node{pre, findHTMLElement(pre, func(e *html.Node) bool { return e.DataAtom == atom.Code })} {
+		if n == nil {
+			continue
+		}
+		if lang := htmlAttr(n, "data-language"); lang != "" {
+			return strings.ToLower(lang)
+		}
+		if m := htmlCodeLanguageClass.FindStringSubmatch(htmlAttr(n, "class")); m != nil {
+			return strings.ToLower(m[1])
+		}
+	}
+	return ""
+}
+
+// markdownDestination writes a link destination that survives spaces and
+// parentheses.
+func markdownDestination(dest string) string {
+	dest = strings.ReplaceAll(dest, " ", "%20")
+	if strings.ContainsAny(dest, "()<>") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(dest) + ">"
+	}
+	return dest
+}
+
+// wrapMarkdownInline wraps text in an emphasis marker, keeping surrounding
+// spaces outside so that the marker stays valid.
+func wrapMarkdownInline(marker, text string) string {
+	lead, inner, trail := splitSurroundingSpace(text)
+	if strings.Trim(inner, " "+htmlHardBreak) == "" {
+		return text
+	}
+	return lead + marker + inner + marker + trail
+}
+
+func splitSurroundingSpace(text string) (lead, inner, trail string) {
+	inner = strings.TrimLeft(text, " ")
+	lead = text[:len(text)-len(inner)]
+	trimmed := strings.TrimRight(inner, " ")
+	trail = inner[len(trimmed):]
+	return lead, trimmed, trail
+}
+
+// finishInline normalizes collected inline content: spaces are collapsed,
+// empty lines dropped and hard breaks replaced by lineBreak.
+func finishInline(text, lineBreak string) string {
+	parts := strings.Split(text, htmlHardBreak)
+	lines := parts[:0]
+	for _, part := range parts {
+		part = strings.TrimSpace(htmlSpacePattern.ReplaceAllString(part, " "))
+		if part != "" {
+			lines = append(lines, part)
+		}
+	}
+	return strings.Join(lines, lineBreak)
+}
+
+func collapseHTMLSpace(text string) string {
+	return htmlSpacePattern.ReplaceAllString(strings.ReplaceAll(text, htmlHardBreak, ""), " ")
+}
+
+// escapeMarkdownText escapes characters that would otherwise start Markdown
+// syntax. Underscores inside words are left alone, as they are in GFM.
+func escapeMarkdownText(text string) string {
+	runes := []rune(text)
+	var b strings.Builder
+	for i, r := range runes {
+		switch r {
+		case '\\', '`', '*', '[', ']', '<':
+			b.WriteByte('\\')
+		case '_':
+			if i == 0 || i == len(runes)-1 || !isMarkdownWordRune(runes[i-1]) || !isMarkdownWordRune(runes[i+1]) {
+				b.WriteByte('\\')
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func isMarkdownWordRune(r rune) bool {
+	return r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 0x7f
+}
+
+// escapeMarkdownBlockStart keeps paragraph text from being read as a
+// heading, quote, list or rule.
+func escapeMarkdownBlockStart(line string) string {
+	if markdownBlockStart.MatchString(line) {
+		return `\` + line
+	}
+	return markdownOrderedStart.ReplaceAllString(line, `$1\$2$3`)
+}
+
+func indentMarkdown(text string, width int) string {
+	pad := strings.Repeat(" ", width)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func prefixMarkdownLines(text, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(prefix+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ─── HTML helpers ────────────────────────────────────────────────────────────
+
+func htmlAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && strings.EqualFold(a.Key, key) {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func htmlHasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Namespace == "" && strings.EqualFold(a.Key, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func htmlHasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(htmlAttr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// findHTMLElement returns the first element below n (depth first) matching
+// match.
+func findHTMLElement(n *html.Node, match func(*html.Node) bool) *html.Node {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		if match(child) {
+			return child
+		}
+		if found := findHTMLElement(child, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// findHTMLElements returns every element below n matching match, without
+// descending into matches.
+func findHTMLElements(n *html.Node, match func(*html.Node) bool) []*html.Node {
+	var out []*html.Node
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		if match(child) {
+			out = append(out, child)
+			continue
+		}
+		out = append(out, findHTMLElements(child, match)...)
+	}
+	return out
+}
+
+// htmlText returns the raw text of n; <br> becomes a line break.
+func htmlText(n *html.Node) string {
+	var b strings.Builder
+	var visit func(e *html.Node)
+	visit = func(e *html.Node) {
+		for child := e.FirstChild; child != nil; child = child.NextSibling {
+			switch {
+			case child.Type == html.TextNode:
+				b.WriteString(child.Data)
+			case child.Type == html.ElementNode && child.DataAtom == atom.Br:
+				b.WriteString("\n")
+			case child.Type == html.ElementNode && !htmlSkippedElements[child.DataAtom]:
+				visit(child)
+			}
+		}
+	}
+	visit(n)
+	return b.String()
+}
@@ -0,0 +1,113 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func convertHTMLStringToMarkdown(t *testing.T, input string, opts htmlMarkdownOptions) string {
+	t.Helper()
+	doc, err := html.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("html.Parse err: %v", err)
+	}
+	return convertHTMLToMarkdown(doc, opts)
+}
+
+func TestConvertHTMLToMarkdown_TableDriven(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "paragraphs and inline formatting",
+			input: "<p>Hello <strong>bold </strong><em>world</em> and <code>x()</code></p><p>Second <del>old</del></p>",
+			want:  "Hello **bold** *world* and `x()`\n\nSecond ~~old~~",
+		},
+		{
+			name:  "headings",
+			input: "<h1>Title</h1><h3>Sub <em>title</em></h3>",
+			want:  "# Title\n\n### Sub *title*",
+		},
+		{
+			name:  "divs separate paragraphs",
+			input: "<div>one</div><div>two<br>three<br></div>",
+			want:  "one\n\ntwo\\\nthree",
+		},
+		{
+			name:  "escapes markdown syntax in text",
+			input: "<p>1. not a list, *stars*, [brackets] and snake_case _x_</p><p># no heading</p>",
+			want:  "1\\. not a list, \\*stars\\*, \\[brackets\\] and snake_case \\_x\\_\n\n\\# no heading",
+		},
+		{
+			name:  "nested lists",
+			input: "<ul><li>a<ul><li>b</li></ul></li><li><p>c</p></li></ul><ol start=\"3\"><li>x</li><li>y</li></ol>",
+			want:  "- a\n  - b\n- c\n\n3. x\n4. y",
+		},
+		{
+			name:  "list nested directly in list",
+			input: "<ol><li>a</li><ol><li>b</li></ol></ol>",
+			want:  "1. a\n   1. b",
+		},
+		{
+			name:  "task list",
+			input: "<ul><li><input type=\"checkbox\" checked> done</li><li><input type=\"checkbox\"> open</li></ul>",
+			want:  "- [x] done\n- [ ] open",
+		},
+		{
+			name:  "code block keeps content and language",
+			input: "<pre><code class=\"language-go\">if a &lt; b {\n\treturn \"```\"\n}</code></pre>",
+			want:  "````go\nif a < b {\n\treturn \"```\"\n}\n````",
+		},
+		{
+			name:  "blockquote",
+			input: "<blockquote><p>quote</p><p>more</p></blockquote>",
+			want:  "> quote\n>\n> more",
+		},
+		{
+			name:  "table",
+			input: "<table><tr><th>A</th><th>B</th></tr><tr><td>1 | 2</td><td><p>x</p><p>y</p></td></tr><tr><td>only</td></tr></table>",
+			want:  "| A | B |\n| --- | --- |\n| 1 \\| 2 | x<br>y |\n| only |  |",
+		},
+		{
+			name:  "details become collapsible blocks",
+			input: "<details><summary>More</summary><p>Hidden</p></details>",
+			want:  ":::collapsed More\nHidden\n:::",
+		},
+		{
+			name:  "links and images",
+			input: "<p><a href=\"https://example.com/a b\">site</a> <a href=\"#top\"></a> <img src=\"pic (1).png\" alt=\"Pic\"></p>",
+			want:  "[site](https://example.com/a%20b) [#top](#top) ![Pic](<pic%20(1).png>)",
+		},
+		{
+			name:  "skips scripts and styles",
+			input: "<style>p{}</style><p>text</p><script>alert(1)</script>",
+			want:  "text",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convertHTMLStringToMarkdown(t, tt.input, htmlMarkdownOptions{}); got != tt.want {
+				t.Fatalf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertHTMLToMarkdown_LinkCallbackUnwrapsAndRewrites(t *testing.T) {
+	got := convertHTMLStringToMarkdown(t, `<p><a href="keep.html">Keep</a> <a href="drop.html">Drop</a> <img src="drop.png" alt="alt text"></p>`, htmlMarkdownOptions{
+		Link: func(href string, image bool) (string, bool) {
+			if href == "keep.html" {
+				return "keep.md", true
+			}
+			return "", false
+		},
+	})
+	if want := "[Keep](keep.md) Drop alt text"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
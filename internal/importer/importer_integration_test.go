@@ -1,13 +1,16 @@
 package importer_test
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/perber/wiki/internal/core/auth"
 	"github.com/perber/wiki/internal/core/markdown"
+	"github.com/perber/wiki/internal/core/revision"
 	"github.com/perber/wiki/internal/core/tree"
 	"github.com/perber/wiki/internal/importer"
 	"github.com/perber/wiki/internal/properties"
@@ -84,6 +87,7 @@ func newTestImporterService(t *testing.T, w *wiki.Wiki) *importer.ImporterServic
 	t.Helper()
 	planner := importer.NewPlanner(wiki.NewWikiImportAdapter(w), tree.NewSlugService(), "")
 	importerDir := filepath.Join(w.GetStorageDir(), ".importer")
+	planner.SetSourceMappings(importer.NewSourceMappingStore(filepath.Join(importerDir, "sources")))
 	return importer.NewImporterService(
 		planner,
 		importer.NewPlanStore(filepath.Join(importerDir, "current-plan.json")),
@@ -551,3 +555,416 @@ func TestImporterService_ExecuteCurrentPlan_ImportsObsidianWikiLinksFixture(t *t
 		t.Fatalf("expected 1 uploaded asset, got %#v", assets)
 	}
 }
+
+func TestImporterService_ExecuteCurrentPlan_ImportsConfluenceSpaceExport(t *testing.T) {
+	ws := integCopyFixtureToTemp(t, "confluence-space-export")
+
+	w := newTestWiki(t)
+	defer test_utils.WrapCloseWithErrorCheck(w.Close, t)
+	is := newTestImporterService(t, w)
+	probe := newImporterProbe(w)
+
+	plan, err := is.CreateImportPlanFromFolder(ws, "wiki")
+	if err != nil {
+		t.Fatalf("createImportPlanFromFolder err: %v", err)
+	}
+	if len(plan.Items) != 5 {
+		t.Fatalf("expected five plan items, got %#v", plan.Items)
+	}
+	var gettingStartedItem importer.PlanItem
+	for _, item := range plan.Items {
+		if item.TargetPath == "wiki/docs-home/getting-started" {
+			gettingStartedItem = item
+		}
+	}
+	if gettingStartedItem.Kind != tree.NodeKindSection || gettingStartedItem.Title != "Getting Started" {
+		t.Fatalf("unexpected getting started item: %#v", gettingStartedItem)
+	}
+	if !strings.Contains(strings.Join(gettingStartedItem.Notes, "\n"), `"roadmap"`) {
+		t.Fatalf("expected unsupported macro note, got %#v", gettingStartedItem.Notes)
+	}
+
+	res, err := is.ExecuteCurrentPlan("system")
+	if err != nil {
+		t.Fatalf("ExecuteCurrentPlan err: %v", err)
+	}
+	if res.ImportedCount != 5 {
+		t.Fatalf("expected five imported pages, got %#v", res.Items)
+	}
+
+	home, err := probe.FindByPath("wiki/docs-home")
+	if err != nil {
+		t.Fatalf("FindByPath docs-home err: %v", err)
+	}
+	gettingStarted, err := probe.FindByPath("wiki/docs-home/getting-started")
+	if err != nil {
+		t.Fatalf("FindByPath getting-started err: %v", err)
+	}
+	install, err := probe.FindByPath("wiki/docs-home/getting-started/install-guide")
+	if err != nil {
+		t.Fatalf("FindByPath install-guide err: %v", err)
+	}
+	if _, err := probe.FindByPath("wiki/old-notes"); err != nil {
+		t.Fatalf("FindByPath old-notes err: %v", err)
+	}
+
+	if !strings.Contains(home.Content, "[Getting Started](/wiki/docs-home/getting-started)") {
+		t.Fatalf("expected home to link getting started, got:\n%s", home.Content)
+	}
+	for _, expected := range []string{
+		"[install guide](/wiki/docs-home/getting-started/install-guide)",
+		"[FAQ](/wiki/docs-home/faq)",
+		"![](/assets/" + gettingStarted.ID + "/diagram.png)",
+		":::warning Before you start",
+	} {
+		if !strings.Contains(gettingStarted.Content, expected) {
+			t.Fatalf("expected getting started content to contain %q, got:\n%s", expected, gettingStarted.Content)
+		}
+	}
+	if !strings.Contains(install.Content, "[handbook](/assets/"+install.ID+"/handbook.pdf)") {
+		t.Fatalf("expected install guide to link the uploaded attachment, got:\n%s", install.Content)
+	}
+
+	assets, err := probe.ListAssets(gettingStarted.ID)
+	if err != nil {
+		t.Fatalf("ListAssets err: %v", err)
+	}
+	if len(assets) != 2 {
+		t.Fatalf("expected 2 uploaded attachments, got %#v", assets)
+	}
+}
+
+func TestImporterService_ExecuteCurrentPlan_ImportsNotionExport(t *testing.T) {
+	ws := integCopyFixtureToTemp(t, "notion-export")
+
+	w := newTestWiki(t)
+	defer test_utils.WrapCloseWithErrorCheck(w.Close, t)
+	is := newTestImporterService(t, w)
+	probe := newImporterProbe(w)
+
+	plan, err := is.CreateImportPlanFromFolder(ws, "")
+	if err != nil {
+		t.Fatalf("createImportPlanFromFolder err: %v", err)
+	}
+	if len(plan.Items) != 7 {
+		t.Fatalf("expected seven plan items, got %#v", plan.Items)
+	}
+
+	res, err := is.ExecuteCurrentPlan("system")
+	if err != nil {
+		t.Fatalf("ExecuteCurrentPlan err: %v", err)
+	}
+	if res.ImportedCount != 7 {
+		t.Fatalf("expected seven imported pages, got %#v", res.Items)
+	}
+
+	projects, err := probe.FindByPath("team-wiki/projects")
+	if err != nil {
+		t.Fatalf("FindByPath projects err: %v", err)
+	}
+	if projects.Kind != tree.NodeKindSection || projects.Title != "Projects" {
+		t.Fatalf("expected database to become a section, got %#v", projects.PageNode)
+	}
+	onboarding, err := probe.FindByPath("team-wiki/onboarding")
+	if err != nil {
+		t.Fatalf("FindByPath onboarding err: %v", err)
+	}
+	for _, expected := range []string{
+		"![Architecture diagram](/assets/" + onboarding.ID + "/diagram-1.png)",
+		"[Website Relaunch](/team-wiki/projects/website-relaunch#goals)",
+		"[Team Wiki](/team-wiki)",
+	} {
+		if !strings.Contains(onboarding.Content, expected) {
+			t.Fatalf("expected onboarding content to contain %q, got:\n%s", expected, onboarding.Content)
+		}
+	}
+	assets, err := probe.ListAssets(onboarding.ID)
+	if err != nil {
+		t.Fatalf("ListAssets err: %v", err)
+	}
+	if len(assets) != 3 {
+		t.Fatalf("expected 3 uploaded files, got %#v", assets)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(w.GetStorageDir(), "root", "team-wiki", "projects", "website-relaunch.md"))
+	if err != nil {
+		t.Fatalf("ReadFile err: %v", err)
+	}
+	for _, expected := range []string{"Owner: Jane", "Status: In progress", "- web\n    - design"} {
+		if !strings.Contains(string(raw), expected) {
+			t.Fatalf("expected row frontmatter to contain %q, got:\n%s", expected, raw)
+		}
+	}
+}
+
+func TestImporterService_ExecuteCurrentPlan_ImportsMediaWikiDumpWithHistory(t *testing.T) {
+	ws := integCopyFixtureToTemp(t, "mediawiki-dump")
+
+	w, err := wiki.NewWiki(&wiki.WikiOptions{
+		StorageDir:          t.TempDir(),
+		AdminPassword:       "adminpassword",
+		JWTSecret:           "secretkey",
+		AccessTokenTimeout:  15 * time.Minute,
+		RefreshTokenTimeout: 7 * 24 * time.Hour,
+		EnableRevision:      true,
+	})
+	if err != nil {
+		t.Fatalf("NewWiki err: %v", err)
+	}
+	defer test_utils.WrapCloseWithErrorCheck(w.Close, t)
+	alice, err := w.UserService().CreateUser("Alice", "alice@example.com", "password123", auth.RoleEditor)
+	if err != nil {
+		t.Fatalf("CreateUser err: %v", err)
+	}
+	is := newTestImporterService(t, w)
+	probe := newImporterProbe(w)
+
+	plan, err := is.CreateImportPlanFromFolder(ws, "wiki", importer.ImportOptions{ImportHistory: true, MapHistoryAuthors: true})
+	if err != nil {
+		t.Fatalf("createImportPlanFromFolder err: %v", err)
+	}
+	if len(plan.Items) != 8 {
+		t.Fatalf("expected eight plan items, got %#v", plan.Items)
+	}
+
+	res, err := is.ExecuteCurrentPlan("system")
+	if err != nil {
+		t.Fatalf("ExecuteCurrentPlan err: %v", err)
+	}
+	if res.ImportedCount != 8 {
+		t.Fatalf("expected eight imported pages, got %#v", res.Items)
+	}
+
+	mainPage, err := probe.FindByPath("wiki/main-page")
+	if err != nil {
+		t.Fatalf("FindByPath main page err: %v", err)
+	}
+	for _, expected := range []string{
+		"[install guide](/wiki/installation-guide)",
+		"![The architecture](/assets/" + mainPage.ID + "/diagram.png)",
+	} {
+		if !strings.Contains(mainPage.Content, expected) {
+			t.Fatalf("expected main page content to contain %q, got:\n%s", expected, mainPage.Content)
+		}
+	}
+	if notes, err := probe.FindByPath("wiki/user-1/alice/notes"); err != nil || notes.Content != "My notes.\n" {
+		t.Fatalf("expected subpage below the user namespace, got %#v, %v", notes, err)
+	}
+
+	revisions, err := revision.NewFSStore(w.GetStorageDir(), nil).ListRevisions(mainPage.ID)
+	if err != nil {
+		t.Fatalf("ListRevisions err: %v", err)
+	}
+	if len(revisions) < 3 {
+		t.Fatalf("expected the import revisions and two imported revisions, got %d", len(revisions))
+	}
+	// Imported revisions keep their timestamps and sort before the import.
+	anonymous, first := revisions[len(revisions)-2], revisions[len(revisions)-1]
+	if first.AuthorID != alice.ID || first.AuthorName != "Alice" || first.Summary != "Created page" || first.CreatedAt.Year() != 2012 {
+		t.Fatalf("unexpected first revision: %#v", first)
+	}
+	if anonymous.AuthorID != "" || anonymous.AuthorName != "192.0.2.1" {
+		t.Fatalf("unexpected anonymous revision: %#v", anonymous)
+	}
+}
+
+func TestImporterService_ExecuteCurrentPlan_HistoryAuthorsAreNotLinkedToUsersByDefault(t *testing.T) {
+	ws := t.TempDir()
+	integMustWrite(t, ws, "Setup.md", "# Setup\nCurrent")
+	integMustWrite(t, ws, "Setup.history.json", `[{"author": "admin", "timestamp": "2012-03-04T05:06:07Z", "content": "First"}]`)
+
+	w, err := wiki.NewWiki(&wiki.WikiOptions{
+		StorageDir:          t.TempDir(),
+		AdminPassword:       "adminpassword",
+		JWTSecret:           "secretkey",
+		AccessTokenTimeout:  15 * time.Minute,
+		RefreshTokenTimeout: 7 * 24 * time.Hour,
+		EnableRevision:      true,
+	})
+	if err != nil {
+		t.Fatalf("NewWiki err: %v", err)
+	}
+	defer test_utils.WrapCloseWithErrorCheck(w.Close, t)
+	admin, err := w.UserService().GetUserByUsername("admin")
+	if err != nil {
+		t.Fatalf("GetUserByUsername err: %v", err)
+	}
+	is := newTestImporterService(t, w)
+
+	if _, err := is.CreateImportPlanFromFolder(ws, "", importer.ImportOptions{ImportHistory: true}); err != nil {
+		t.Fatalf("createImportPlanFromFolder err: %v", err)
+	}
+	if _, err := is.ExecuteCurrentPlan("system"); err != nil {
+		t.Fatalf("ExecuteCurrentPlan err: %v", err)
+	}
+
+	page, err := newImporterProbe(w).FindByPath("setup")
+	if err != nil {
+		t.Fatalf("FindByPath err: %v", err)
+	}
+	revisions, err := revision.NewFSStore(w.GetStorageDir(), nil).ListRevisions(page.ID)
+	if err != nil {
+		t.Fatalf("ListRevisions err: %v", err)
+	}
+	imported := revisions[len(revisions)-1]
+	if imported.AuthorID == admin.ID || imported.AuthorID != "" || imported.AuthorName != "admin" {
+		t.Fatalf("expected the history author to be a name only, got %#v", imported)
+	}
+}
+
+func TestImporterService_CreateImportPlan_MappingAuthorsNeedsHistory(t *testing.T) {
+	w := newTestWiki(t)
+	defer test_utils.WrapCloseWithErrorCheck(w.Close, t)
+	is := newTestImporterService(t, w)
+
+	_, err := is.CreateImportPlanFromFolder(t.TempDir(), "", importer.ImportOptions{MapHistoryAuthors: true})
+	if !errors.Is(err, importer.ErrInvalidImportOptions) {
+		t.Fatalf("expected ErrInvalidImportOptions, got %v", err)
+	}
+}
+
+func TestImporterService_ExecuteCurrentPlan_ImportsHTMLAndWordDocuments(t *testing.T) {
+	ws := integCopyFixtureToTemp(t, "documents-package")
+
+	w := newTestWiki(t)
+	defer test_utils.WrapCloseWithErrorCheck(w.Close, t)
+	is := newTestImporterService(t, w)
+	probe := newImporterProbe(w)
+
+	plan, err := is.CreateImportPlanFromFolder(ws, "docs")
+	if err != nil {
+		t.Fatalf("createImportPlanFromFolder err: %v", err)
+	}
+	titles := map[string]string{}
+	for _, item := range plan.Items {
+		titles[item.TargetPath] = item.Title
+	}
+	if titles["docs/onboarding"] != "Onboarding Guide" || titles["docs/release-notes"] != "Release Notes" || len(plan.Items) != 3 {
+		t.Fatalf("unexpected plan items: %#v", plan.Items)
+	}
+
+	res, err := is.ExecuteCurrentPlan("system")
+	if err != nil {
+		t.Fatalf("ExecuteCurrentPlan err: %v", err)
+	}
+	if res.ImportedCount != 3 {
+		t.Fatalf("expected three imported pages, got %#v", res.Items)
+	}
+
+	onboarding, err := probe.FindByPath("docs/onboarding")
+	if err != nil {
+		t.Fatalf("FindByPath onboarding err: %v", err)
+	}
+	if expected := "![Office map](/assets/" + onboarding.ID + "/image1.png)"; !strings.Contains(onboarding.Content, expected) {
+		t.Fatalf("expected onboarding content to contain %q, got:\n%s", expected, onboarding.Content)
+	}
+	releaseNotes, err := probe.FindByPath("docs/release-notes")
+	if err != nil {
+		t.Fatalf("FindByPath release notes err: %v", err)
+	}
+	for _, expected := range []string{
+		"[onboarding guide](/docs/onboarding)",
+		"![Logo](/assets/" + releaseNotes.ID + "/logo.png)",
+		"![Chart](/assets/" + releaseNotes.ID + "/image-1.png)",
+	} {
+		if !strings.Contains(releaseNotes.Content, expected) {
+			t.Fatalf("expected release notes content to contain %q, got:\n%s", expected, releaseNotes.Content)
+		}
+	}
+	handbook, err := probe.FindByPath("docs/handbook")
+	if err != nil {
+		t.Fatalf("FindByPath handbook err: %v", err)
+	}
+	if !strings.Contains(handbook.Content, "[release notes](/docs/release-notes#fixes)") {
+		t.Fatalf("expected handbook link to the converted page, got:\n%s", handbook.Content)
+	}
+}
+
+func TestImporterService_ReimportWithSource_UpdatesOnlyChangedPages(t *testing.T) {
+	w := newTestWiki(t)
+	defer test_utils.WrapCloseWithErrorCheck(w.Close, t)
+	is := newTestImporterService(t, w)
+	probe := newImporterProbe(w)
+	opts := importer.ImportOptions{Source: "Handbook repo", RemovedPages: importer.RemovedPagesTrash}
+
+	first := t.TempDir()
+	integMustWrite(t, first, "guide.md", "# Guide\n\nVersion 1")
+	integMustWrite(t, first, "faq.md", "# FAQ\n\nAsk us")
+	integMustWrite(t, first, "old.md", "# Old\n\nOutdated")
+	integMustWrite(t, first, "setup.md", "# Setup\n\nRun make")
+	if _, err := is.CreateImportPlanFromFolder(first, "docs", opts); err != nil {
+		t.Fatalf("first plan err: %v", err)
+	}
+	if res, err := is.ExecuteCurrentPlan("system"); err != nil || res.ImportedCount != 4 {
+		t.Fatalf("first import = %#v, %v", res, err)
+	}
+	setup, err := probe.FindByPath("docs/setup")
+	if err != nil {
+		t.Fatalf("FindByPath setup err: %v", err)
+	}
+
+	second := t.TempDir()
+	integMustWrite(t, second, "guide.md", "# Guide\n\nVersion 2")
+	integMustWrite(t, second, "faq.md", "# FAQ\n\nAsk us")
+	integMustWrite(t, second, "install/setup-guide.md", "# Setup\n\nRun make")
+	integMustWrite(t, second, "new.md", "# New")
+	plan, err := is.CreateImportPlanFromFolder(second, "docs", opts)
+	if err != nil {
+		t.Fatalf("second plan err: %v", err)
+	}
+	changes := map[string]string{}
+	for _, item := range plan.Items {
+		changes[item.SourcePath] = string(item.Change) + " " + string(item.Action) + " " + item.TargetPath
+	}
+	for source, want := range map[string]string{
+		"guide.md":               "modified update docs/guide",
+		"faq.md":                 "unchanged skip docs/faq",
+		"install/setup-guide.md": "moved skip docs/setup",
+		"new.md":                 "new create docs/new",
+		"old.md":                 "removed trash import-trash/old",
+	} {
+		if changes[source] != want {
+			t.Fatalf("%s = %q, want %q (all: %#v)", source, changes[source], want, changes)
+		}
+	}
+
+	res, err := is.ExecuteCurrentPlan("system")
+	if err != nil {
+		t.Fatalf("second import err: %v", err)
+	}
+	if res.ImportedCount != 1 || res.UpdatedCount != 1 || res.RemovedCount != 1 || res.SkippedCount != 2 {
+		t.Fatalf("second import = %#v", res)
+	}
+	guide, err := probe.FindByPath("docs/guide")
+	if err != nil || !strings.Contains(guide.Content, "Version 2") {
+		t.Fatalf("expected guide to be updated, got %#v, %v", guide, err)
+	}
+	if _, err := probe.FindByPath("import-trash/old"); err != nil {
+		t.Fatalf("expected old page in the import trash: %v", err)
+	}
+	if _, err := probe.FindByPath("docs/old"); err == nil {
+		t.Fatalf("expected old page to be moved away")
+	}
+
+	// The mapping follows renamed files, so a third run changes nothing.
+	third := t.TempDir()
+	integMustWrite(t, third, "guide.md", "# Guide\n\nVersion 2")
+	integMustWrite(t, third, "faq.md", "# FAQ\n\nAsk us")
+	integMustWrite(t, third, "install/setup-guide.md", "# Setup\n\nRun make")
+	integMustWrite(t, third, "new.md", "# New")
+	plan, err = is.CreateImportPlanFromFolder(third, "docs", opts)
+	if err != nil {
+		t.Fatalf("third plan err: %v", err)
+	}
+	if len(plan.Items) != 4 {
+		t.Fatalf("expected no removed items, got %#v", plan.Items)
+	}
+	for _, item := range plan.Items {
+		if item.Change != importer.ImportChangeUnchanged || item.Action != importer.PlanActionSkip {
+			t.Fatalf("expected %s to be unchanged, got %s %s", item.SourcePath, item.Change, item.Action)
+		}
+		if item.SourcePath == "install/setup-guide.md" && (item.ExistingID == nil || *item.ExistingID != setup.ID) {
+			t.Fatalf("expected renamed file to stay mapped to %s, got %#v", setup.ID, item.ExistingID)
+		}
+	}
+}
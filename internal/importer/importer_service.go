@@ -15,6 +15,8 @@ import (
 	"github.com/perber/wiki/internal/core/shared"
 )
 
+var ErrInvalidImportOptions = errors.New("invalid import options")
+
 type ImporterService struct {
 	planner                 *Planner
 	planStore               *PlanStore
@@ -26,14 +28,17 @@ type ImporterService struct {
 }
 
 type CurrentPlanState struct {
-	ID              string           `json:"id"`
-	TreeHash        string           `json:"tree_hash"`
-	Items           []PlanItem       `json:"items"`
-	Errors          []string         `json:"errors"`
-	ExecutionStatus ExecutionStatus  `json:"execution_status"`
-	CancelRequested bool             `json:"cancel_requested"`
-	ExecutionResult *ExecutionResult `json:"execution_result,omitempty"`
-	ExecutionError  *string          `json:"execution_error,omitempty"`
+	ID               string           `json:"id"`
+	TreeHash         string           `json:"tree_hash"`
+	Items            []PlanItem       `json:"items"`
+	Errors           []string         `json:"errors"`
+	Source           string           `json:"source,omitempty"`
+	ConflictStrategy ConflictStrategy `json:"conflict_strategy"`
+	ExcludedPaths    []string         `json:"excluded_paths,omitempty"`
+	ExecutionStatus  ExecutionStatus  `json:"execution_status"`
+	CancelRequested  bool             `json:"cancel_requested"`
+	ExecutionResult  *ExecutionResult `json:"execution_result,omitempty"`
+	ExecutionError   *string          `json:"execution_error,omitempty"`
 	ExecutionProgress
 }
 
@@ -87,8 +92,39 @@ func NewImporterService(planner *Planner, planStore *PlanStore, workspaceBaseDir
 	return service
 }
 
+// ImportOptions are optional settings of an import plan.
+type ImportOptions struct {
+	ImportHistory     bool // import the page history of exports that have one as revisions
+	MapHistoryAuthors bool // link history authors to the users of the same name
+	// Source names the origin of the package. Imports with the same source
+	// only update pages whose file changed since the last import.
+	Source       string
+	RemovedPages RemovedPages // what happens to pages whose source file was removed
+}
+
 // CreateImportPlanFromFolder creates an import plan from a folder path
-func (is *ImporterService) CreateImportPlanFromFolder(folderPath string, targetBasePath string) (*PlanResult, error) {
+func (is *ImporterService) CreateImportPlanFromFolder(folderPath string, targetBasePath string, importOpts ...ImportOptions) (*PlanResult, error) {
+	opts := PlanOptions{
+		TargetBasePath: targetBasePath,
+	}
+	if len(importOpts) > 0 {
+		opts.ImportHistory = importOpts[0].ImportHistory
+		opts.MapHistoryAuthors = importOpts[0].MapHistoryAuthors
+		opts.Source = strings.TrimSpace(importOpts[0].Source)
+		opts.RemovedPages = importOpts[0].RemovedPages
+	}
+	switch opts.RemovedPages {
+	case "", RemovedPagesKeep, RemovedPagesDelete, RemovedPagesTrash:
+	default:
+		return nil, fmt.Errorf("%w: unknown removed pages option %q", ErrInvalidImportOptions, opts.RemovedPages)
+	}
+	if opts.MapHistoryAuthors && !opts.ImportHistory {
+		return nil, fmt.Errorf("%w: history authors can only be mapped when importing the history", ErrInvalidImportOptions)
+	}
+	if opts.RemovedPages != "" && opts.RemovedPages != RemovedPagesKeep && opts.Source == "" {
+		return nil, fmt.Errorf("%w: removed pages can only be deleted or trashed for imports with a source", ErrInvalidImportOptions)
+	}
+
 	// single-plan semantics: cleanup old plan workspace if present
 	if old, err := is.planStore.Get(); err == nil && old != nil {
 		if old.ExecutionStatus == ExecutionStatusRunning {
@@ -104,15 +140,11 @@ func (is *ImporterService) CreateImportPlanFromFolder(folderPath string, targetB
 		is.logger.Info("Old import workspace cleaned up")
 	}
 
-	entries, err := FindMarkdownEntries(folderPath)
+	entries, sourceBasePath, err := findImportEntries(folderPath)
 	if err != nil {
 		return nil, err
 	}
-
-	opts := PlanOptions{
-		SourceBasePath: folderPath,
-		TargetBasePath: targetBasePath,
-	}
+	opts.SourceBasePath = sourceBasePath
 
 	plan, err := is.planner.CreatePlan(entries, opts)
 	if err != nil {
@@ -141,6 +173,21 @@ func (is *ImporterService) GetCurrentPlan() (*CurrentPlanState, error) {
 	return currentPlanStateFromStored(sp), nil
 }
 
+// EditCurrentPlan applies edit to the currently stored import plan. Plans
+// can only be edited before they are executed.
+func (is *ImporterService) EditCurrentPlan(edit PlanEdit) (*CurrentPlanState, error) {
+	sp, err := is.planStore.Update(func(sp *StoredPlan) error {
+		if sp.ExecutionStatus != ExecutionStatusPlanned {
+			return ErrPlanNotEditable
+		}
+		return is.planner.EditPlan(sp.Plan, edit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return currentPlanStateFromStored(sp), nil
+}
+
 // ClearCurrentPlan clears the currently stored import plan
 func (is *ImporterService) ClearCurrentPlan() error {
 	if sp, err := is.planStore.Get(); err == nil && sp != nil {
@@ -281,13 +328,14 @@ func FindMarkdownEntries(sourceBasePath string) ([]ImportMDFile, error) {
 func (is *ImporterService) CreateImportPlanFromZipUpload(
 	r io.Reader,
 	targetBasePath string,
+	importOpts ...ImportOptions,
 ) (*PlanResult, error) {
 	ws, err := is.extractZipReaderToTemp(r)
 	if err != nil {
 		return nil, fmt.Errorf("extract zip to temp: %w", err)
 	}
 
-	plan, err := is.CreateImportPlanFromFolder(ws.Root, targetBasePath)
+	plan, err := is.CreateImportPlanFromFolder(ws.Root, targetBasePath, importOpts...)
 	if err != nil {
 		if err := ws.Cleanup(); err != nil {
 			is.logger.Error("cleanup failed", "error", err)
@@ -339,7 +387,31 @@ func (is *ImporterService) executeStoredPlan(sp *StoredPlan) (*ExecutionResult,
 			return is.planStore.IsCancelRequested(sp.Plan.ID)
 		}).
 		WithResumeState(sp.ProcessedItems, sp.ExecutionResult)
-	return exec.Execute(sp.ExecutionUserID)
+	res, err := exec.Execute(sp.ExecutionUserID)
+	if res != nil {
+		is.recordSourceMapping(sp, res)
+	}
+	return res, err
+}
+
+// recordSourceMapping remembers the pages of an import with a source for the
+// next import of the same source. A failure only costs the next import its
+// incremental plan, so it is logged.
+func (is *ImporterService) recordSourceMapping(sp *StoredPlan, res *ExecutionResult) {
+	mappings := is.planner.mappings
+	if mappings == nil || sp.PlanOptions.Source == "" {
+		return
+	}
+	mapping, err := mappings.Get(sp.PlanOptions.Source)
+	if err != nil {
+		is.logger.Error("failed to load source mapping", "source", sp.PlanOptions.Source, "error", err)
+		return
+	}
+	mapping.Source = sp.PlanOptions.Source
+	updateSourceMapping(mapping, sp.Plan, res)
+	if err := mappings.Save(mapping); err != nil {
+		is.logger.Error("failed to save source mapping", "source", sp.PlanOptions.Source, "error", err)
+	}
 }
 
 func (is *ImporterService) cleanupWorkspace(workspaceRoot string) {
@@ -357,14 +429,17 @@ func currentPlanStateFromStored(sp *StoredPlan) *CurrentPlanState {
 	}
 
 	return &CurrentPlanState{
-		ID:              sp.Plan.ID,
-		TreeHash:        sp.Plan.TreeHash,
-		Items:           sp.Plan.Items,
-		Errors:          sp.Plan.Errors,
-		ExecutionStatus: sp.ExecutionStatus,
-		CancelRequested: sp.CancelRequested,
-		ExecutionResult: sp.ExecutionResult,
-		ExecutionError:  sp.ExecutionError,
+		ID:               sp.Plan.ID,
+		TreeHash:         sp.Plan.TreeHash,
+		Items:            sp.Plan.Items,
+		Errors:           sp.Plan.Errors,
+		Source:           sp.PlanOptions.Source,
+		ConflictStrategy: sp.Plan.ConflictStrategy,
+		ExcludedPaths:    sp.Plan.ExcludedPaths,
+		ExecutionStatus:  sp.ExecutionStatus,
+		CancelRequested:  sp.CancelRequested,
+		ExecutionResult:  sp.ExecutionResult,
+		ExecutionError:   sp.ExecutionError,
 		ExecutionProgress: ExecutionProgress{
 			ProcessedItems:        sp.ProcessedItems,
 			TotalItems:            sp.TotalItems,
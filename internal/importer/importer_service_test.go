@@ -194,6 +194,52 @@ func TestImporterService_ClearCurrentPlan(t *testing.T) {
 	}
 }
 
+func TestImporterService_EditCurrentPlan_StoresEditsUntilExecution(t *testing.T) {
+	tmp := t.TempDir()
+	mustWrite(t, tmp, "a.md", "# A")
+	mustWrite(t, tmp, "b.md", "# B")
+
+	w := &fakeWiki{treeHash: "h1", lookups: map[string]*tree.PathLookup{}}
+	is := newServiceWithFakeWiki(t, w)
+	plan, err := is.CreateImportPlanFromFolder(tmp, "docs")
+	if err != nil {
+		t.Fatalf("CreateImportPlanFromFolder err: %v", err)
+	}
+
+	title := "Renamed"
+	state, err := is.EditCurrentPlan(PlanEdit{TreeHash: plan.TreeHash, Items: []PlanItemEdit{{SourcePath: "a.md", Title: &title}}, Exclude: []string{"b.md"}})
+	if err != nil {
+		t.Fatalf("EditCurrentPlan err: %v", err)
+	}
+	if state.Items[0].Title != "Renamed" || !state.Items[1].Excluded || len(state.ExcludedPaths) != 1 {
+		t.Fatalf("unexpected state: %#v", state)
+	}
+
+	// A rejected edit leaves the stored plan unchanged.
+	empty := ""
+	if _, err := is.EditCurrentPlan(PlanEdit{Items: []PlanItemEdit{{SourcePath: "a.md", Title: &empty}}, Include: []string{"b.md"}}); !errors.Is(err, ErrInvalidPlanEdit) {
+		t.Fatalf("expected ErrInvalidPlanEdit, got %v", err)
+	}
+	stored, err := is.GetCurrentPlan()
+	if err != nil {
+		t.Fatalf("GetCurrentPlan err: %v", err)
+	}
+	if stored.Items[0].Title != "Renamed" || !stored.Items[1].Excluded {
+		t.Fatalf("expected stored plan to keep the first edit, got %#v", stored.Items)
+	}
+
+	res, err := is.ExecuteCurrentPlan("user1")
+	if err != nil {
+		t.Fatalf("ExecuteCurrentPlan err: %v", err)
+	}
+	if res.ImportedCount != 1 || res.SkippedCount != 1 {
+		t.Fatalf("counts imported=%d skipped=%d", res.ImportedCount, res.SkippedCount)
+	}
+	if _, err := is.EditCurrentPlan(PlanEdit{}); !errors.Is(err, ErrPlanNotEditable) {
+		t.Fatalf("expected ErrPlanNotEditable after execution, got %v", err)
+	}
+}
+
 func TestImporterService_ExecuteCurrentPlan_NoPlan(t *testing.T) {
 	w := &fakeWiki{treeHash: "h1", lookups: map[string]*tree.PathLookup{}}
 	is := newServiceWithFakeWiki(t, w)
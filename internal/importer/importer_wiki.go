@@ -9,7 +9,15 @@ import (
 type ImporterWiki interface {
 	TreeHash() string
 	LookupPagePath(path string) (*tree.PathLookup, error)
+	GetPage(id string) (*tree.Page, error)
 	EnsurePath(userID string, targetPath string, title string, kind *tree.NodeKind) (*tree.Page, error)
 	UpdatePage(userID string, id, title, slug string, content *string, kind *tree.NodeKind) (*tree.Page, error)
+	// DeletePage deletes a page without its children.
+	DeletePage(userID, id string) error
+	MovePage(userID, id, parentID string) error
 	UploadAsset(userID, pageID string, file multipart.File, filename string, maxBytes int64) (string, error)
+	// ImportRevisions adds the history of an imported page, oldest first.
+	// Authors are kept as names only unless mapAuthors links them to the
+	// users of the same name.
+	ImportRevisions(pageID string, revisions []ImportedRevision, mapAuthors bool) error
 }
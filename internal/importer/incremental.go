@@ -0,0 +1,250 @@
+package importer
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/perber/wiki/internal/core/markdown"
+	"github.com/perber/wiki/internal/core/tree"
+)
+
+// importTrashPath is the section that receives pages of removed source files
+// when they are trashed instead of deleted.
+const importTrashPath = "import-trash"
+
+const importTrashTitle = "Import trash"
+
+// applySourceMapping compares a plan with the last import of the same source.
+// Files that were imported before update their page (or are unchanged),
+// renamed and moved files are recognised by their content or leafwiki_id,
+// and pages whose file disappeared are added as removed items.
+func (p *Planner) applySourceMapping(plan *PlanResult, options PlanOptions) error {
+	mapping, err := p.mappings.Get(options.Source)
+	if err != nil {
+		return fmt.Errorf("load source mapping: %w", err)
+	}
+	bySource := make(map[string]SourceMappingEntry, len(mapping.Entries))
+	for _, entry := range mapping.Entries {
+		bySource[entry.SourcePath] = entry
+	}
+	present := make(map[string]bool, len(plan.Items))
+	for _, item := range plan.Items {
+		present[item.SourcePath] = true
+	}
+
+	matched := map[string]bool{}
+	pageIDs := make([]string, len(plan.Items))
+	var unmatched []int
+	for i := range plan.Items {
+		item := &plan.Items[i]
+		sourceAbs := filepath.Join(options.SourceBasePath, filepath.FromSlash(item.SourcePath))
+		hash, err := fileContentHash(sourceAbs)
+		if err != nil {
+			return fmt.Errorf("hash %s: %w", item.SourcePath, err)
+		}
+		item.ContentHash = hash
+		if md, err := markdown.LoadMarkdownFile(sourceAbs); err == nil {
+			pageIDs[i] = md.GetFrontmatter().LeafWikiID
+		}
+
+		entry, ok := bySource[item.SourcePath]
+		if !ok {
+			unmatched = append(unmatched, i)
+			continue
+		}
+		matched[entry.SourcePath] = true
+		page := p.mappedPage(entry.PageID)
+		if page == nil {
+			item.Change = ImportChangeNew
+			continue
+		}
+		change := ImportChangeModified
+		if entry.ContentHash == hash {
+			change = ImportChangeUnchanged
+		}
+		planMappedItem(item, page, change, entry.ContentHash == hash)
+	}
+
+	// Files that are new to the source may be earlier files under a new
+	// name. A matching leafwiki_id wins over matching content.
+	for _, i := range unmatched {
+		item := &plan.Items[i]
+		item.Change = ImportChangeNew
+		var candidates []SourceMappingEntry
+		for _, entry := range mapping.Entries {
+			if !present[entry.SourcePath] && !matched[entry.SourcePath] && entry.PageID == pageIDs[i] {
+				candidates = append(candidates, entry)
+			}
+		}
+		for _, entry := range mapping.Entries {
+			if !present[entry.SourcePath] && !matched[entry.SourcePath] && entry.ContentHash == item.ContentHash {
+				candidates = append(candidates, entry)
+			}
+		}
+		for _, entry := range candidates {
+			page := p.mappedPage(entry.PageID)
+			if page == nil {
+				continue
+			}
+			matched[entry.SourcePath] = true
+			planMappedItem(item, page, ImportChangeMoved, entry.ContentHash == item.ContentHash)
+			item.Notes = append(item.Notes, fmt.Sprintf("Moved from %q", entry.SourcePath))
+			break
+		}
+	}
+
+	// Pages of removed files are processed children first, so that deleting
+	// a section does not fail because of a page that is removed as well.
+	var removed []PlanItem
+	for _, entry := range mapping.Entries {
+		if present[entry.SourcePath] || matched[entry.SourcePath] {
+			continue
+		}
+		page := p.mappedPage(entry.PageID)
+		if page == nil {
+			continue
+		}
+		item := PlanItem{SourcePath: entry.SourcePath, Action: PlanActionSkip, Notes: []string{}}
+		planMappedItem(&item, page, ImportChangeRemoved, true)
+		item.Title = page.Title
+		switch options.RemovedPages {
+		case RemovedPagesDelete:
+			item.Action = PlanActionDelete
+		case RemovedPagesTrash:
+			item.Action = PlanActionTrash
+		}
+		removed = append(removed, item)
+	}
+	sort.SliceStable(removed, func(i, j int) bool {
+		return strings.Count(removed[i].TargetPath, "/") > strings.Count(removed[j].TargetPath, "/")
+	})
+	for _, item := range removed {
+		plan.Items = append(plan.Items, item)
+		if err := p.placeRemovedItem(plan, &plan.Items[len(plan.Items)-1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mappedPage returns the page of a mapping entry, or nil if it was deleted
+// from the wiki since.
+func (p *Planner) mappedPage(id string) *tree.Page {
+	if id == "" {
+		return nil
+	}
+	page, err := p.wiki.GetPage(id)
+	if err != nil || page == nil || page.PageNode == nil {
+		return nil
+	}
+	return page
+}
+
+// planMappedItem points an item at the page of an earlier import. The page
+// keeps its place in the wiki, even if users moved it since.
+func planMappedItem(item *PlanItem, page *tree.Page, change ImportChange, unchanged bool) {
+	id := page.ID
+	item.Change = change
+	item.TargetPath = strings.Trim(page.CalculatePath(), "/")
+	item.DesiredSlug = page.Slug
+	item.Kind = page.Kind
+	item.Exists, item.ExistingID, item.ConflictPath = true, &id, ""
+	item.Action = PlanActionUpdate
+	if unchanged {
+		item.Action = PlanActionSkip
+	}
+}
+
+// placeRemovedItem sets the target of a removed item: its page, or a free
+// path in the import trash when the page is trashed. The page path is kept
+// in ConflictPath meanwhile.
+func (p *Planner) placeRemovedItem(plan *PlanResult, item *PlanItem) error {
+	if item.ConflictPath != "" {
+		item.TargetPath, item.ConflictPath = item.ConflictPath, ""
+	}
+	if item.Action != PlanActionTrash {
+		return nil
+	}
+	base := path.Join(importTrashPath, path.Base(item.TargetPath))
+	target := base
+	lookup, err := p.wiki.LookupPagePath(base)
+	if err != nil {
+		return err
+	}
+	taken := lookup.Exists
+	for i := range plan.Items {
+		if other := &plan.Items[i]; other != item && other.TargetPath == base {
+			taken = true
+		}
+	}
+	if taken {
+		if target, err = p.freeTargetPath(plan, item, base); err != nil {
+			return err
+		}
+	}
+	item.ConflictPath, item.TargetPath = item.TargetPath, target
+	return nil
+}
+
+// updateSourceMapping records the pages of an executed plan in the mapping
+// of its source. Items that failed or were not processed keep their earlier
+// entry, so the next import plans them again.
+func updateSourceMapping(mapping *SourceMapping, plan *PlanResult, result *ExecutionResult) {
+	entries := make(map[string]SourceMappingEntry, len(mapping.Entries))
+	for _, entry := range mapping.Entries {
+		entries[entry.SourcePath] = entry
+	}
+	results := make(map[string]ExecutionItemResult, len(result.Items))
+	for _, item := range result.Items {
+		results[item.SourcePath] = item
+	}
+
+	for _, item := range plan.Items {
+		res, ok := results[item.SourcePath]
+		if !ok || res.Error != nil || res.PageID == "" {
+			continue
+		}
+		switch res.Action {
+		case ExecutionActionDeleted, ExecutionActionTrashed:
+			delete(entries, item.SourcePath)
+			continue
+		case ExecutionActionSkipped:
+			// Skipped files are only recorded if their page already has
+			// their content; pages of other sources are never adopted.
+			if item.Change != ImportChangeUnchanged && item.Change != ImportChangeMoved {
+				continue
+			}
+			if !hasMappedContent(mapping, res.PageID, item.ContentHash) {
+				continue
+			}
+		}
+		if item.Change == ImportChangeRemoved {
+			continue
+		}
+		for source, entry := range entries {
+			if entry.PageID == res.PageID {
+				delete(entries, source)
+			}
+		}
+		entries[item.SourcePath] = SourceMappingEntry{SourcePath: item.SourcePath, ContentHash: item.ContentHash, PageID: res.PageID}
+	}
+
+	mapping.Entries = make([]SourceMappingEntry, 0, len(entries))
+	for _, entry := range entries {
+		mapping.Entries = append(mapping.Entries, entry)
+	}
+	mapping.UpdatedAt = time.Now()
+}
+
+func hasMappedContent(mapping *SourceMapping, pageID, hash string) bool {
+	for _, entry := range mapping.Entries {
+		if entry.PageID == pageID && entry.ContentHash == hash {
+			return true
+		}
+	}
+	return false
+}
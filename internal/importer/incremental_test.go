@@ -0,0 +1,185 @@
+package importer
+
+import (
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/perber/wiki/internal/core/tree"
+	"github.com/perber/wiki/internal/test_utils"
+)
+
+func newMappedWiki(pages ...*tree.PageNode) *fakeWiki {
+	docs := &tree.PageNode{ID: "docs", Slug: "docs", Kind: tree.NodeKindSection, Parent: &tree.PageNode{ID: "root", Slug: "root"}}
+	w := &fakeWiki{treeHash: "h1", lookups: map[string]*tree.PathLookup{}, pages: map[string]*tree.Page{}}
+	for _, node := range pages {
+		node.Parent = docs
+		node.Kind = tree.NodeKindPage
+		w.pages[node.ID] = &tree.Page{PageNode: node}
+	}
+	return w
+}
+
+func TestPlanner_CreatePlan_WithSource_ComparesWithLastImport(t *testing.T) {
+	tmp := t.TempDir()
+	test_utils.WriteFile(t, tmp, "renamed.md", "---\nleafwiki_id: page-b\n---\n# B, edited")
+	test_utils.WriteFile(t, tmp, "recreated.md", "# C")
+
+	mappings := NewSourceMappingStore(filepath.Join(t.TempDir(), "sources"))
+	if err := mappings.Save(&SourceMapping{Source: "repo", Entries: []SourceMappingEntry{
+		{SourcePath: "b.md", ContentHash: "old-hash", PageID: "page-b"},
+		{SourcePath: "recreated.md", ContentHash: "c-hash", PageID: "deleted-page"},
+		{SourcePath: "section/index.md", ContentHash: "s-hash", PageID: "page-s"},
+		{SourcePath: "section/child.md", ContentHash: "d-hash", PageID: "page-d"},
+	}}); err != nil {
+		t.Fatalf("Save err: %v", err)
+	}
+
+	w := newMappedWiki(
+		&tree.PageNode{ID: "page-b", Slug: "b", Title: "B"},
+		&tree.PageNode{ID: "page-s", Slug: "section", Title: "Section"},
+	)
+	child := &tree.PageNode{ID: "page-d", Slug: "child", Title: "Child", Kind: tree.NodeKindPage, Parent: w.pages["page-s"].PageNode}
+	w.pages["page-d"] = &tree.Page{PageNode: child}
+	p := newPlannerWithFake(w)
+	p.SetSourceMappings(mappings)
+
+	plan, err := p.CreatePlan([]ImportMDFile{{SourcePath: "renamed.md"}, {SourcePath: "recreated.md"}}, PlanOptions{
+		SourceBasePath: tmp,
+		TargetBasePath: "docs",
+		Source:         "Repo",
+		RemovedPages:   RemovedPagesDelete,
+	})
+	if err != nil {
+		t.Fatalf("CreatePlan err: %v", err)
+	}
+
+	var got []string
+	for _, item := range plan.Items {
+		got = append(got, item.SourcePath+" "+string(item.Change)+" "+string(item.Action)+" "+item.TargetPath)
+	}
+	want := []string{
+		"renamed.md moved update docs/b",         // matched by leafwiki_id, content changed
+		"recreated.md new create docs/recreated", // mapped page was deleted from the wiki
+		"section/child.md removed delete docs/section/child",
+		"section/index.md removed delete docs/section",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("items = %#v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("item %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+	if plan.Items[0].ContentHash == "" || plan.Items[0].Notes[len(plan.Items[0].Notes)-1] != `Moved from "b.md"` {
+		t.Fatalf("moved item = %#v", plan.Items[0])
+	}
+
+	// Removed items can only be skipped, deleted or trashed.
+	trash := PlanActionTrash
+	if err := p.EditPlan(plan, PlanEdit{Items: []PlanItemEdit{{SourcePath: "section/index.md", Action: &trash}}}); err != nil {
+		t.Fatalf("EditPlan err: %v", err)
+	}
+	if item := plan.Items[3]; item.TargetPath != "import-trash/section" || item.ConflictPath != "docs/section" {
+		t.Fatalf("trashed item = %#v", item)
+	}
+	skip := PlanActionSkip
+	if err := p.EditPlan(plan, PlanEdit{Items: []PlanItemEdit{{SourcePath: "section/index.md", Action: &skip}}}); err != nil {
+		t.Fatalf("EditPlan err: %v", err)
+	}
+	if item := plan.Items[3]; item.TargetPath != "docs/section" || item.ConflictPath != "" {
+		t.Fatalf("skipped item = %#v", item)
+	}
+	update := PlanActionUpdate
+	if err := p.EditPlan(plan, PlanEdit{Items: []PlanItemEdit{{SourcePath: "section/index.md", Action: &update}}}); !errors.Is(err, ErrInvalidPlanEdit) {
+		t.Fatalf("expected ErrInvalidPlanEdit for updating a removed page, got %v", err)
+	}
+	target := "docs/elsewhere"
+	if err := p.EditPlan(plan, PlanEdit{Items: []PlanItemEdit{{SourcePath: "renamed.md", TargetPath: &target}}}); !errors.Is(err, ErrInvalidPlanEdit) {
+		t.Fatalf("expected ErrInvalidPlanEdit for moving a mapped item, got %v", err)
+	}
+}
+
+func TestUpdateSourceMapping_RecordsImportedPages(t *testing.T) {
+	mapping := &SourceMapping{Source: "repo", Entries: []SourceMappingEntry{
+		{SourcePath: "a.md", ContentHash: "a1", PageID: "page-a"},
+		{SourcePath: "moved.md", ContentHash: "m1", PageID: "page-m"},
+		{SourcePath: "gone.md", ContentHash: "g1", PageID: "page-g"},
+		{SourcePath: "failed.md", ContentHash: "f1", PageID: "page-f"},
+	}}
+	errMsg := "boom"
+	plan := &PlanResult{Items: []PlanItem{
+		{SourcePath: "a.md", ContentHash: "a2", Change: ImportChangeModified},
+		{SourcePath: "new/moved.md", ContentHash: "m1", Change: ImportChangeMoved},
+		{SourcePath: "conflict.md", ContentHash: "x1", Change: ImportChangeNew},
+		{SourcePath: "failed.md", ContentHash: "f2", Change: ImportChangeModified},
+		{SourcePath: "gone.md", Change: ImportChangeRemoved},
+	}}
+	result := &ExecutionResult{Items: []ExecutionItemResult{
+		{SourcePath: "a.md", PageID: "page-a", Action: ExecutionActionUpdated},
+		{SourcePath: "new/moved.md", PageID: "page-m", Action: ExecutionActionSkipped},
+		{SourcePath: "conflict.md", PageID: "foreign-page", Action: ExecutionActionSkipped},
+		{SourcePath: "failed.md", PageID: "page-f", Action: ExecutionActionSkipped, Error: &errMsg},
+		{SourcePath: "gone.md", PageID: "page-g", Action: ExecutionActionDeleted},
+	}}
+
+	updateSourceMapping(mapping, plan, result)
+
+	got := map[string]string{}
+	for _, entry := range mapping.Entries {
+		got[entry.SourcePath] = entry.PageID + " " + entry.ContentHash
+	}
+	want := map[string]string{
+		"a.md":         "page-a a2",
+		"new/moved.md": "page-m m1",
+		"failed.md":    "page-f f1",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("entries = %#v", got)
+	}
+	for source, entry := range want {
+		if got[source] != entry {
+			t.Fatalf("%s = %q, want %q", source, got[source], entry)
+		}
+	}
+}
+
+func TestExecutor_RemovedPages_AreDeletedOrTrashed(t *testing.T) {
+	pageA, pageB := "page-a", "page-b"
+	w := &fakeExecWiki{
+		hash: "h1",
+		pages: map[string]*tree.Page{
+			pageB: {PageNode: &tree.PageNode{ID: pageB, Title: "B", Slug: "b", Kind: tree.NodeKindPage}},
+		},
+		ensureFn: func(userID, targetPath, title string, kind *tree.NodeKind) (*tree.Page, error) {
+			return &tree.Page{PageNode: &tree.PageNode{ID: "trash", Title: title, Slug: targetPath, Kind: *kind}}, nil
+		},
+	}
+	plan := &PlanResult{
+		TreeHash: "h1",
+		Items: []PlanItem{
+			{SourcePath: "a.md", TargetPath: "docs/a", Exists: true, ExistingID: &pageA, Change: ImportChangeRemoved, Action: PlanActionDelete},
+			{SourcePath: "b.md", TargetPath: "import-trash/b-2", ConflictPath: "docs/b", Exists: true, ExistingID: &pageB, Change: ImportChangeRemoved, Action: PlanActionTrash},
+		},
+	}
+
+	res, err := NewExecutor(plan, &PlanOptions{SourceBasePath: t.TempDir()}, 0, w, slog.Default()).Execute("user1")
+	if err != nil {
+		t.Fatalf("Execute err: %v", err)
+	}
+	if res.RemovedCount != 2 || res.Items[0].Action != ExecutionActionDeleted || res.Items[1].Action != ExecutionActionTrashed {
+		t.Fatalf("result = %#v", res)
+	}
+	if len(w.deletedPages) != 1 || w.deletedPages[0] != pageA {
+		t.Fatalf("deleted = %v", w.deletedPages)
+	}
+	if len(w.ensureTargets) != 1 || w.ensureTargets[0] != "import-trash" || len(w.movedPages) != 1 || w.movedPages[0] != "page-b->trash" {
+		t.Fatalf("ensure = %v, moved = %v", w.ensureTargets, w.movedPages)
+	}
+	// The trashed page is renamed to b-2 without touching its content.
+	if w.updateCalls != 1 || w.lastUpdatedContent != nil {
+		t.Fatalf("expected one rename without content, got %d calls", w.updateCalls)
+	}
+}
@@ -0,0 +1,50 @@
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/perber/wiki/internal/core/assets"
+	"github.com/perber/wiki/internal/core/markdown"
+	"github.com/perber/wiki/internal/core/tree"
+)
+
+// LinkRewriter applies the importer's link and asset rules to files that are
+// written to the wiki outside of an import run, such as by `leafwiki publish`.
+// Links between the files of the plan point to their target pages afterwards.
+type LinkRewriter struct {
+	sourceBasePath string
+	transformer    *contentTransformer
+}
+
+func NewLinkRewriter(plan *PlanResult, sourceBasePath string, assetMaxBytes int64) *LinkRewriter {
+	if assetMaxBytes <= 0 {
+		assetMaxBytes = assets.DefaultMaxUploadSizeBytes
+	}
+	return &LinkRewriter{
+		sourceBasePath: sourceBasePath,
+		transformer:    newContentTransformer(plan, sourceBasePath, assetMaxBytes),
+	}
+}
+
+// PageContent returns the content the importer would write to page for the
+// file at sourcePath: its extra frontmatter and body with rewritten links.
+// Referenced and sidecar assets are uploaded through wiki.
+func (r *LinkRewriter) PageContent(userID, sourcePath string, page *tree.Page, wiki ImporterWiki) (string, error) {
+	mdFile, err := markdown.LoadMarkdownFile(filepath.Join(r.sourceBasePath, filepath.FromSlash(sourcePath)))
+	if err != nil {
+		return "", err
+	}
+	content, err := buildImportedContent(mdFile)
+	if err != nil {
+		return "", err
+	}
+	content, err = r.transformer.TransformContent(userID, sourcePath, page, content, wiki)
+	if err != nil {
+		return "", err
+	}
+	if err := r.transformer.uploadSidecarAssets(userID, sourcePath, page, wiki); err != nil {
+		return "", fmt.Errorf("import page assets: %w", err)
+	}
+	return content, nil
+}
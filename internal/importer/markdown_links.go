@@ -0,0 +1,180 @@
+package importer
+
+import (
+	"path"
+	"strings"
+)
+
+// SidecarAssetsSuffix names the folder that holds the attachments of a
+// Markdown file: "guide.md" keeps its files in "guide.assets/". Every file in
+// that folder is uploaded to the imported page, even when the page content
+// does not reference it.
+const SidecarAssetsSuffix = ".assets"
+
+// SidecarAssetsDir returns the attachment folder of a Markdown source path,
+// e.g. "docs/guide.assets" for "docs/guide.md".
+func SidecarAssetsDir(sourcePath string) string {
+	return strings.TrimSuffix(sourcePath, path.Ext(sourcePath)) + SidecarAssetsSuffix
+}
+
+// MarkdownLink is a regular Markdown link or image, e.g. [label](dest "title").
+type MarkdownLink struct {
+	Image bool
+	// Label is the raw text between the brackets.
+	Label string
+	// Destination is the raw text between the parentheses, including an
+	// optional <…> wrapper and title.
+	Destination string
+}
+
+// String renders the link back to Markdown.
+func (l MarkdownLink) String() string {
+	var b strings.Builder
+	if l.Image {
+		b.WriteByte('!')
+	}
+	b.WriteByte('[')
+	b.WriteString(l.Label)
+	b.WriteString("](")
+	b.WriteString(l.Destination)
+	b.WriteByte(')')
+	return b.String()
+}
+
+// Href returns the bare destination without <…> wrapper and title.
+func (l MarkdownLink) Href() string {
+	_, href, _ := splitMarkdownDestination(strings.TrimSpace(l.Destination))
+	return href
+}
+
+// WithHref returns a copy of the link pointing at href while keeping the
+// destination wrapper and title intact.
+func (l MarkdownLink) WithHref(href string) MarkdownLink {
+	prefix, _, suffix := splitMarkdownDestination(strings.TrimSpace(l.Destination))
+	l.Destination = prefix + href + suffix
+	return l
+}
+
+// WikiLink is an Obsidian-style [[Target|Label]] link or ![[Target]] embed.
+type WikiLink struct {
+	Embed  bool
+	Target string
+	Label  string
+	// Raw is the original link text including the brackets.
+	Raw string
+}
+
+// RewriteMarkdownLinks replaces every Markdown link and image outside inline
+// code and fenced code blocks with the text returned by rewrite.
+func RewriteMarkdownLinks(content string, rewrite func(MarkdownLink) (string, error)) (string, error) {
+	return rewriteOutsideCodeSpans(content, func(segment string) (string, error) {
+		return scanMarkdownLinks(segment, rewrite)
+	})
+}
+
+// RewriteWikiLinks replaces every wiki link and embed outside inline code and
+// fenced code blocks with the text returned by rewrite.
+func RewriteWikiLinks(content string, rewrite func(WikiLink) (string, error)) (string, error) {
+	return rewriteOutsideCodeSpans(content, func(segment string) (string, error) {
+		return scanWikiLinks(segment, rewrite)
+	})
+}
+
+func scanMarkdownLinks(content string, rewrite func(MarkdownLink) (string, error)) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(content); i++ {
+		if content[i] != '[' && (content[i] != '!' || i+1 >= len(content) || content[i+1] != '[') {
+			out.WriteByte(content[i])
+			continue
+		}
+
+		start := i
+		isImage := false
+		if content[i] == '!' {
+			isImage = true
+			i++
+		}
+
+		labelEnd := strings.IndexByte(content[i:], ']')
+		if labelEnd < 0 {
+			out.WriteString(content[start:])
+			return out.String(), nil
+		}
+		labelEnd += i
+		if labelEnd+1 >= len(content) || content[labelEnd+1] != '(' {
+			out.WriteString(content[start : labelEnd+1])
+			i = labelEnd
+			continue
+		}
+
+		destStart := labelEnd + 2
+		destEnd := findMarkdownLinkDestinationEnd(content, destStart)
+		if destEnd < 0 {
+			out.WriteString(content[start:])
+			return out.String(), nil
+		}
+
+		rewritten, err := rewrite(MarkdownLink{
+			Image:       isImage,
+			Label:       content[i+1 : labelEnd],
+			Destination: content[destStart:destEnd],
+		})
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(rewritten)
+		i = destEnd
+	}
+
+	return out.String(), nil
+}
+
+func scanWikiLinks(content string, rewrite func(WikiLink) (string, error)) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(content); {
+		nextImage := strings.Index(content[i:], "![[")
+		nextLink := strings.Index(content[i:], "[[")
+		if nextImage < 0 && nextLink < 0 {
+			out.WriteString(content[i:])
+			break
+		}
+
+		next := -1
+		isImage := false
+		switch {
+		case nextImage >= 0 && (nextLink < 0 || nextImage <= nextLink):
+			next = i + nextImage
+			isImage = true
+		case nextLink >= 0:
+			next = i + nextLink
+		}
+
+		out.WriteString(content[i:next])
+
+		startOffset := 2
+		if isImage {
+			startOffset = 3
+		}
+		end := strings.Index(content[next+startOffset:], "]]")
+		if end < 0 {
+			out.WriteString(content[next:])
+			break
+		}
+		end += next + startOffset
+
+		target, label := splitWikiLink(strings.TrimSpace(content[next+startOffset : end]))
+		rewritten, err := rewrite(WikiLink{
+			Embed:  isImage,
+			Target: target,
+			Label:  label,
+			Raw:    content[next : end+2],
+		})
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(rewritten)
+		i = end + 2
+	}
+
+	return out.String(), nil
+}
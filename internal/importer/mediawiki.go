@@ -0,0 +1,640 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+
+	"github.com/perber/wiki/internal/core/markdown"
+	"github.com/perber/wiki/internal/core/tree"
+)
+
+// A MediaWiki XML dump (Special:Export or dumpBackup.php) holds the pages of
+// a wiki with their revisions as wikitext. Uploaded files are not part of
+// the dump; they are taken from an "images" folder packed next to it (the
+// upload folder of the wiki or the output of dumpUploads.php).
+
+const mediaWikiImagesDir = "images"
+
+// Namespaces that hold no content pages: talk pages (odd numbers), files,
+// system messages, templates and modules. Categories become tags.
+var mediaWikiSkippedNamespaces = map[int]bool{6: true, 8: true, 10: true, 14: true, 828: true}
+
+// mediaWikiDefaultNamespaces are used when the dump has no siteinfo.
+var mediaWikiDefaultNamespaces = map[int]string{
+	2: "User", 4: "Project", 6: "File", 8: "MediaWiki", 10: "Template", 12: "Help", 14: "Category", 828: "Module",
+}
+
+// Folders of the upload folder with thumbnails and old versions of files.
+var mediaWikiSkippedImageDirs = map[string]bool{"thumb": true, "archive": true, "temp": true, "deleted": true, "lockdir": true}
+
+// DetectMediaWikiDump reports whether root holds a MediaWiki XML dump and
+// returns its folder: root itself or its only top-level folder.
+func DetectMediaWikiDump(root string) (string, bool) {
+	return findExportDir(root, func(dir string) bool {
+		return len(mediaWikiDumpFiles(dir)) > 0
+	})
+}
+
+// mediaWikiDumpFiles returns the XML files of a folder that are MediaWiki
+// dumps.
+func mediaWikiDumpFiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(path.Ext(entry.Name()), ".xml") {
+			continue
+		}
+		if isMediaWikiDumpFile(filepath.Join(dir, entry.Name())) {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files
+}
+
+func isMediaWikiDumpFile(file string) bool {
+	f, err := os.Open(file)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+	head := make([]byte, 1024)
+	n, _ := io.ReadFull(f, head)
+	return strings.Contains(string(head[:n]), "<mediawiki")
+}
+
+type mediaWikiSiteInfo struct {
+	Namespaces []struct {
+		Key  int    `xml:"key,attr"`
+		Name string `xml:",chardata"`
+	} `xml:"namespaces>namespace"`
+}
+
+type mediaWikiPageXML struct {
+	Title    string `xml:"title"`
+	NS       int    `xml:"ns"`
+	Redirect *struct {
+		Title string `xml:"title,attr"`
+	} `xml:"redirect"`
+	Revisions []mediaWikiRevisionXML `xml:"revision"`
+}
+
+type mediaWikiRevisionXML struct {
+	Timestamp   time.Time `xml:"timestamp"`
+	Contributor struct {
+		Username string `xml:"username"`
+		IP       string `xml:"ip"`
+	} `xml:"contributor"`
+	Comment string `xml:"comment"`
+	Model   string `xml:"model"`
+	Text    string `xml:"text"`
+}
+
+// readMediaWikiDump streams the siteinfo and the pages of a dump.
+func readMediaWikiDump(file string, siteInfo func(*mediaWikiSiteInfo), page func(*mediaWikiPageXML) error) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	dec := xml.NewDecoder(f)
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read %s: %w", filepath.Base(file), err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "siteinfo":
+			var info mediaWikiSiteInfo
+			if err := dec.DecodeElement(&info, &start); err != nil {
+				return fmt.Errorf("read %s: %w", filepath.Base(file), err)
+			}
+			if siteInfo != nil {
+				siteInfo(&info)
+			}
+		case "page":
+			var p mediaWikiPageXML
+			if err := dec.DecodeElement(&p, &start); err != nil {
+				return fmt.Errorf("read %s: %w", filepath.Base(file), err)
+			}
+			if err := page(&p); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// mediaWikiPage is a page of the dump, a section created for a namespace
+// or a missing parent of a subpage.
+type mediaWikiPage struct {
+	packageNode[*mediaWikiPage]
+	// key is the normalized title including the namespace.
+	key string
+	// source is true for pages of the dump.
+	source bool
+	parent *mediaWikiPage
+	assets map[string]string
+	notes  []string
+}
+
+func (p *mediaWikiPage) addNote(format string, args ...any) {
+	note := fmt.Sprintf(format, args...)
+	for _, existing := range p.notes {
+		if existing == note {
+			return
+		}
+	}
+	p.notes = append(p.notes, note)
+}
+
+type mediaWikiConverter struct {
+	dumps      []string
+	outDir     string
+	slugger    *tree.SlugService
+	namespaces map[int]string
+	// pages maps normalized titles to pages; sections of namespaces are
+	// keyed by the namespace name followed by a colon.
+	pages     map[string]*mediaWikiPage
+	redirects map[string]string
+	roots     []*mediaWikiPage
+	// images maps normalized file names to files of the upload folder.
+	images   map[string]string
+	renderer *wikitextRenderer
+}
+
+// ConvertMediaWikiDump converts a MediaWiki XML dump into a Markdown package
+// in outDir that the planner imports like any other. Pages of the main
+// namespace are imported at the top level, those of other content
+// namespaces into a section per namespace, and subpages ("A/B") below their
+// parent. The latest revision of each page is converted from wikitext to
+// Markdown; the earlier ones are stored in the history file of the page
+// (see HistoryFile). Templates cannot be expanded and are reported as notes.
+func ConvertMediaWikiDump(dumpDir, outDir string) ([]ImportMDFile, error) {
+	cv := &mediaWikiConverter{
+		dumps:      mediaWikiDumpFiles(dumpDir),
+		outDir:     outDir,
+		slugger:    tree.NewSlugService(),
+		namespaces: map[int]string{},
+		pages:      map[string]*mediaWikiPage{},
+		redirects:  map[string]string{},
+		images:     map[string]string{},
+	}
+	if len(cv.dumps) == 0 {
+		return nil, fmt.Errorf("no mediawiki dump found")
+	}
+	for key, name := range mediaWikiDefaultNamespaces {
+		cv.namespaces[key] = name
+	}
+	if err := cv.scanPages(); err != nil {
+		return nil, err
+	}
+	if err := cv.findImages(dumpDir); err != nil {
+		return nil, err
+	}
+
+	var fileNamespaces, categoryNamespaces []string
+	fileNamespaces = append(fileNamespaces, "File", "Image", cv.namespaces[6])
+	categoryNamespaces = append(categoryNamespaces, "Category", cv.namespaces[14])
+	cv.renderer = newWikitextRenderer(fileNamespaces, categoryNamespaces)
+
+	cv.sortChildren(cv.roots)
+	assignPackagePaths(cv.slugger, "", cv.roots, func(page *mediaWikiPage) bool {
+		return len(page.children) > 0 || !page.source
+	})
+
+	converted := map[*mediaWikiPage]bool{}
+	for _, dump := range cv.dumps {
+		err := readMediaWikiDump(dump, nil, func(p *mediaWikiPageXML) error {
+			page := cv.pages[cv.pageKey(p.Title)]
+			if page == nil || !page.source || p.Redirect != nil || converted[page] {
+				return nil
+			}
+			converted[page] = true
+			if err := cv.convertPage(page, p); err != nil {
+				return fmt.Errorf("convert %s: %w", p.Title, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var entries []ImportMDFile
+	for _, page := range packageTreeOrder(cv.roots) {
+		if !page.source {
+			if err := cv.writePage(page, nil, ""); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, ImportMDFile{SourcePath: page.mdPath, Notes: page.notes})
+	}
+	return entries, nil
+}
+
+// scanPages reads the titles of all pages and redirects and builds the
+// tree from namespaces and subpages.
+func (cv *mediaWikiConverter) scanPages() error {
+	var titles []string
+	for _, dump := range cv.dumps {
+		err := readMediaWikiDump(dump, func(info *mediaWikiSiteInfo) {
+			for _, ns := range info.Namespaces {
+				if name := strings.TrimSpace(ns.Name); name != "" {
+					cv.namespaces[ns.Key] = name
+				}
+			}
+		}, func(p *mediaWikiPageXML) error {
+			if p.NS < 0 || p.NS%2 == 1 || mediaWikiSkippedNamespaces[p.NS] || len(p.Revisions) == 0 {
+				return nil
+			}
+			key := cv.pageKey(p.Title)
+			if p.Redirect != nil {
+				cv.redirects[key] = p.Redirect.Title
+				return nil
+			}
+			if _, ok := cv.pages[key]; !ok {
+				cv.pages[key] = &mediaWikiPage{key: key, source: true, assets: map[string]string{}}
+				titles = append(titles, p.Title)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, title := range titles {
+		cv.place(cv.pages[cv.pageKey(title)], title)
+	}
+	return nil
+}
+
+// place adds a page to the tree: below its parent page for subpages, else
+// below the section of its namespace.
+func (cv *mediaWikiConverter) place(page *mediaWikiPage, title string) {
+	namespace, name := cv.splitNamespace(title)
+	segments := strings.Split(name, "/")
+	for _, segment := range segments {
+		if strings.TrimSpace(segment) == "" {
+			segments = []string{name}
+			break
+		}
+	}
+	page.title = strings.TrimSpace(segments[len(segments)-1])
+
+	var parent *mediaWikiPage
+	if namespace != "" {
+		key := namespace + ":"
+		parent = cv.pages[key]
+		if parent == nil {
+			parent = &mediaWikiPage{key: key, assets: map[string]string{}}
+			parent.title = namespace
+			cv.pages[key] = parent
+			cv.roots = append(cv.roots, parent)
+		}
+	}
+	for i := 1; i < len(segments); i++ {
+		parentTitle := strings.Join(segments[:i], "/")
+		if namespace != "" {
+			parentTitle = namespace + ":" + parentTitle
+		}
+		key := cv.pageKey(parentTitle)
+		next := cv.pages[key]
+		if next == nil {
+			next = &mediaWikiPage{key: key, assets: map[string]string{}}
+			next.title = strings.TrimSpace(segments[i-1])
+			next.addNote("Page %q is not part of the dump; an empty page was created for its subpages", parentTitle)
+			cv.pages[key] = next
+			cv.attach(next, parent)
+		}
+		parent = next
+	}
+	cv.attach(page, parent)
+}
+
+func (cv *mediaWikiConverter) attach(page, parent *mediaWikiPage) {
+	if page.parent != nil || (parent == nil && cv.isRoot(page)) {
+		return
+	}
+	page.parent = parent
+	if parent == nil {
+		cv.roots = append(cv.roots, page)
+		return
+	}
+	parent.children = append(parent.children, page)
+}
+
+func (cv *mediaWikiConverter) isRoot(page *mediaWikiPage) bool {
+	for _, root := range cv.roots {
+		if root == page {
+			return true
+		}
+	}
+	return false
+}
+
+// splitNamespace returns the namespace of a title ("" for the main
+// namespace) and the title without it.
+func (cv *mediaWikiConverter) splitNamespace(title string) (string, string) {
+	prefix, name, ok := strings.Cut(title, ":")
+	if !ok {
+		return "", strings.TrimSpace(title)
+	}
+	for key, namespace := range cv.namespaces {
+		if key != 0 && strings.EqualFold(normalizeWikiNamespace(namespace), normalizeWikiNamespace(prefix)) {
+			return namespace, strings.TrimSpace(name)
+		}
+	}
+	return "", strings.TrimSpace(title)
+}
+
+// pageKey normalizes a title the way MediaWiki does: underscores are
+// spaces and the first letter of the namespace and of the title are
+// upper case.
+func (cv *mediaWikiConverter) pageKey(title string) string {
+	title = strings.Join(strings.Fields(strings.ReplaceAll(title, "_", " ")), " ")
+	namespace, name := cv.splitNamespace(title)
+	if namespace == "" {
+		return upperFirst(name)
+	}
+	return namespace + ":" + upperFirst(name)
+}
+
+func upperFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// findImages indexes the files of the upload folder by their name.
+func (cv *mediaWikiConverter) findImages(dumpDir string) error {
+	entries, err := os.ReadDir(dumpDir)
+	if err != nil {
+		return fmt.Errorf("read mediawiki dump: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.EqualFold(entry.Name(), mediaWikiImagesDir) {
+			continue
+		}
+		root := filepath.Join(dumpDir, entry.Name())
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if p != root && (mediaWikiSkippedImageDirs[strings.ToLower(d.Name())] || strings.HasPrefix(d.Name(), ".")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasPrefix(d.Name(), ".") {
+				return nil
+			}
+			key := mediaWikiFileKey(d.Name())
+			if _, ok := cv.images[key]; !ok {
+				cv.images[key] = p
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("read mediawiki images: %w", err)
+		}
+	}
+	return nil
+}
+
+func mediaWikiFileKey(name string) string {
+	return upperFirst(strings.Join(strings.Fields(strings.ReplaceAll(name, "_", " ")), " "))
+}
+
+// sortChildren orders pages by title; MediaWiki has no order of pages.
+func (cv *mediaWikiConverter) sortChildren(pages []*mediaWikiPage) {
+	sort.SliceStable(pages, func(i, j int) bool {
+		// Pages of the main namespace come before the namespace sections.
+		si, sj := strings.HasSuffix(pages[i].key, ":"), strings.HasSuffix(pages[j].key, ":")
+		if si != sj {
+			return sj
+		}
+		return strings.ToLower(pages[i].title) < strings.ToLower(pages[j].title)
+	})
+	for _, page := range pages {
+		cv.sortChildren(page.children)
+	}
+}
+
+func (cv *mediaWikiConverter) convertPage(page *mediaWikiPage, p *mediaWikiPageXML) error {
+	revisions := append([]mediaWikiRevisionXML{}, p.Revisions...)
+	sort.SliceStable(revisions, func(i, j int) bool { return revisions[i].Timestamp.Before(revisions[j].Timestamp) })
+
+	var tags []string
+	var history []ImportedRevision
+	var body string
+	for i, rev := range revisions {
+		latest := i == len(revisions)-1
+		// Notes are only kept for the revision that becomes the page.
+		notes := page.notes
+		content, categories := cv.convertWikitext(page, rev)
+		if !latest {
+			page.notes = notes
+		}
+		author := rev.Contributor.Username
+		if author == "" {
+			author = rev.Contributor.IP
+		}
+		history = append(history, ImportedRevision{Author: author, Timestamp: rev.Timestamp.UTC(), Summary: rev.Comment, Content: content})
+		if latest {
+			body, tags = content, categories
+		}
+	}
+
+	if err := cv.writePage(page, tags, body); err != nil {
+		return err
+	}
+	return writeHistoryFile(filepath.Join(cv.outDir, filepath.FromSlash(HistoryFile(page.mdPath))), history)
+}
+
+// convertWikitext converts a revision to Markdown and returns it with its
+// categories.
+func (cv *mediaWikiConverter) convertWikitext(page *mediaWikiPage, rev mediaWikiRevisionXML) (string, []string) {
+	if rev.Model != "" && rev.Model != "wikitext" {
+		page.addNote("Page has the content model %q and was imported as a code block", rev.Model)
+		return markdownCodeFence(rev.Text, mediaWikiModelLanguage(rev.Model)) + "\n", nil
+	}
+
+	result := cv.renderer.render(rev.Text)
+	for _, name := range result.Templates {
+		page.addNote("Template %q was not converted and was removed", name)
+	}
+	doc, err := html.Parse(strings.NewReader(result.HTML))
+	if err != nil {
+		page.addNote("Wikitext could not be converted: %v", err)
+		return "", result.Categories
+	}
+	md := convertHTMLToMarkdown(doc, htmlMarkdownOptions{
+		Link: func(href string, image bool) (string, bool) {
+			return cv.rewriteLink(page, href)
+		},
+		Element: func(c *htmlMarkdownConverter, n *html.Node) (string, bool, bool) {
+			if n.Type != html.ElementNode || n.Data != wikiMathElement {
+				return "", false, false
+			}
+			tex := strings.TrimSpace(htmlText(n))
+			if htmlAttr(n, "display") == "block" {
+				return "$$\n" + tex + "\n$$", false, true
+			}
+			return "$" + tex + "$", true, true
+		},
+	})
+	return strings.TrimSpace(md) + "\n", result.Categories
+}
+
+func mediaWikiModelLanguage(model string) string {
+	switch model {
+	case "css", "sanitized-css":
+		return "css"
+	case "javascript":
+		return "javascript"
+	case "json":
+		return "json"
+	case "Scribunto":
+		return "lua"
+	}
+	return ""
+}
+
+func (cv *mediaWikiConverter) writePage(page *mediaWikiPage, tags []string, body string) error {
+	fields := map[string]interface{}{"leafwiki_title": page.title}
+	if len(tags) > 0 {
+		fields["tags"] = tags
+	}
+	md, err := markdown.BuildMarkdownWithExtraFrontmatter(fields, body)
+	if err != nil {
+		return err
+	}
+	out := filepath.Join(cv.outDir, filepath.FromSlash(page.mdPath))
+	if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(out, []byte(md), 0o644)
+}
+
+// rewriteLink maps the links of the rendered wikitext into the converted
+// package: links to pages (following redirects) and to uploaded files.
+func (cv *mediaWikiConverter) rewriteLink(page *mediaWikiPage, href string) (string, bool) {
+	switch {
+	case strings.HasPrefix(href, wikiFileScheme):
+		name := strings.TrimPrefix(href, wikiFileScheme)
+		src, ok := cv.images[mediaWikiFileKey(name)]
+		if !ok {
+			page.addNote("File %q is not part of the export and was removed", name)
+			return "", false
+		}
+		file, err := cv.attachment(page, src)
+		if err != nil {
+			page.addNote("File %q could not be copied: %v", name, err)
+			return "", false
+		}
+		return relativePackagePath(page.mdPath, file), true
+	case strings.HasPrefix(href, wikiPageScheme):
+		target, fragment, _ := strings.Cut(strings.TrimPrefix(href, wikiPageScheme), "#")
+		anchor := ""
+		if fragment != "" {
+			anchor = "#" + mediaWikiAnchor(fragment)
+		}
+		if strings.TrimSpace(target) == "" {
+			return anchor, anchor != ""
+		}
+		linked := cv.linkedPage(target)
+		if linked == nil {
+			page.addNote("Link to %q could not be resolved and was removed", strings.TrimSpace(target))
+			return "", false
+		}
+		if redirect, ok := cv.redirects[cv.pageKey(target)]; ok && anchor == "" {
+			if _, fragment, ok := strings.Cut(redirect, "#"); ok {
+				anchor = "#" + mediaWikiAnchor(fragment)
+			}
+		}
+		if linked == page && anchor != "" {
+			return anchor, true
+		}
+		return relativePackagePath(page.mdPath, linked.mdPath) + anchor, true
+	}
+	return href, true
+}
+
+// linkedPage resolves a link target, following redirects.
+func (cv *mediaWikiConverter) linkedPage(target string) *mediaWikiPage {
+	key := cv.pageKey(target)
+	for range 5 {
+		if page, ok := cv.pages[key]; ok && page.source {
+			return page
+		}
+		redirect, ok := cv.redirects[key]
+		if !ok {
+			return nil
+		}
+		redirect, _, _ = strings.Cut(redirect, "#")
+		key = cv.pageKey(redirect)
+	}
+	return nil
+}
+
+// mediaWikiAnchor returns the anchor of a heading as the editor generates
+// it.
+func mediaWikiAnchor(heading string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(strings.ReplaceAll(heading, "_", " "))) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			b.WriteRune(r)
+			dash = false
+		case unicode.IsSpace(r) || r == '-':
+			if !dash && b.Len() > 0 {
+				b.WriteByte('-')
+				dash = true
+			}
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// attachment copies a file of the upload folder into the sidecar folder of
+// the page and returns its path in the package.
+func (cv *mediaWikiConverter) attachment(page *mediaWikiPage, src string) (string, error) {
+	sidecar := SidecarAssetsDir(page.mdPath)
+	if name, ok := page.assets[src]; ok {
+		return path.Join(sidecar, name), nil
+	}
+	name := uniqueAssetName(filepath.Base(src), page.assets)
+	page.assets[src] = name
+	if err := copyFile(src, filepath.Join(cv.outDir, filepath.FromSlash(sidecar), name)); err != nil {
+		return "", err
+	}
+	return path.Join(sidecar, name), nil
+}
@@ -0,0 +1,135 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/perber/wiki/internal/test_utils"
+)
+
+func mediaWikiFixtureDir(t *testing.T) string {
+	t.Helper()
+	return test_utils.FixturePath(t, "mediawiki-dump", "fixtures", "internal/importer/fixtures")
+}
+
+func TestDetectMediaWikiDump_FindsDumpFolder(t *testing.T) {
+	dir, ok := DetectMediaWikiDump(mediaWikiFixtureDir(t))
+	if !ok || dir != mediaWikiFixtureDir(t) {
+		t.Fatalf("DetectMediaWikiDump = %q, %v", dir, ok)
+	}
+
+	tmp := t.TempDir()
+	test_utils.WriteFile(t, tmp, "export/feed.xml", "<?xml version=\"1.0\"?><rss></rss>")
+	if _, ok := DetectMediaWikiDump(tmp); ok {
+		t.Fatalf("expected other XML files not to be detected as MediaWiki dump")
+	}
+}
+
+func TestConvertMediaWikiDump_MapsNamespacesSubpagesAndHistory(t *testing.T) {
+	out := t.TempDir()
+	entries, err := ConvertMediaWikiDump(mediaWikiFixtureDir(t), out)
+	if err != nil {
+		t.Fatalf("ConvertMediaWikiDump err: %v", err)
+	}
+
+	var paths []string
+	notes := map[string][]string{}
+	for _, entry := range entries {
+		paths = append(paths, entry.SourcePath)
+		notes[entry.SourcePath] = entry.Notes
+	}
+	want := []string{
+		"installation-guide/index.md",
+		"installation-guide/linux.md",
+		"main-page.md",
+		"help/index.md",
+		"help/editing.md",
+		"user-1/index.md",
+		"user-1/alice/index.md",
+		"user-1/alice/notes.md",
+	}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Fatalf("entries = %v, want %v", paths, want)
+	}
+
+	mainNotes := strings.Join(notes["main-page.md"], "\n")
+	for _, expected := range []string{
+		`Template "Infobox" was not converted and was removed`,
+		`File "Missing.png" is not part of the export and was removed`,
+		`Link to "Nowhere" could not be resolved and was removed`,
+	} {
+		if !strings.Contains(mainNotes, expected) {
+			t.Fatalf("expected note %q, got:\n%s", expected, mainNotes)
+		}
+	}
+	if strings.Contains(mainNotes, "Logo") {
+		t.Fatalf("expected nested templates not to be reported, got:\n%s", mainNotes)
+	}
+	if len(notes["user-1/alice/index.md"]) != 1 || !strings.Contains(notes["user-1/alice/index.md"][0], `"User:Alice" is not part of the dump`) {
+		t.Fatalf("missing parent notes = %#v", notes["user-1/alice/index.md"])
+	}
+
+	read := func(rel string) string {
+		t.Helper()
+		raw, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(rel)))
+		if err != nil {
+			t.Fatalf("read %s: %v", rel, err)
+		}
+		return string(raw)
+	}
+
+	mainPage := read("main-page.md")
+	for _, expected := range []string{
+		"leafwiki_title: Main Page",
+		"tags:\n    - Docs\n    - Start",
+		"Welcome to **Acme**, the *best* ***wiki***.",
+		"## Overview",
+		"[install guide](installation-guide/index.md) and [requirements](installation-guide/index.md#requirements)",
+		"  - Editing is explained in [Help:Editing](help/editing.md).",
+		"![The architecture](main-page.assets/Diagram.png)",
+		"[handbook](main-page.assets/Handbook.pdf)",
+		"[Example](https://example.com)",
+		"Literal \\[\\[not a link\\]\\].",
+	} {
+		if !strings.Contains(mainPage, expected) {
+			t.Fatalf("expected main page to contain %q, got:\n%s", expected, mainPage)
+		}
+	}
+
+	guide := read("installation-guide/index.md")
+	for _, expected := range []string{
+		"| System | Version |\n| --- | --- |\n| Linux | 5.x |\n| Windows | 11 |",
+		"```bash\n./install.sh --prefix /opt\n```",
+		"The load is $E = mc^2$.",
+		"## Notes\n\n1. See the manual.",
+	} {
+		if !strings.Contains(guide, expected) {
+			t.Fatalf("expected installation guide to contain %q, got:\n%s", expected, guide)
+		}
+	}
+	if editing := read("help/editing.md"); !strings.Contains(editing, "[install](../installation-guide/index.md)") || !strings.Contains(editing, "[overview](../main-page.md#overview)") {
+		t.Fatalf("expected redirect and anchor links to resolve, got:\n%s", editing)
+	}
+	if got := read("main-page.assets/Diagram.png"); got != "PNG" {
+		t.Fatalf("image = %q", got)
+	}
+
+	history, err := loadHistoryFile(filepath.Join(out, "main-page.history.json"))
+	if err != nil {
+		t.Fatalf("loadHistoryFile err: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 revisions, got %#v", history)
+	}
+	if history[0].Author != "Alice" || history[0].Summary != "Created page" || history[0].Timestamp.Year() != 2012 {
+		t.Fatalf("unexpected first revision: %#v", history[0])
+	}
+	if history[0].Content != "Welcome to **Acme**. See the [install guide](installation-guide/index.md).\n" {
+		t.Fatalf("first revision content = %q", history[0].Content)
+	}
+	if history[1].Author != "192.0.2.1" {
+		t.Fatalf("expected anonymous edits to keep the IP as author, got %#v", history[1])
+	}
+}
@@ -0,0 +1,570 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/perber/wiki/internal/core/markdown"
+	"github.com/perber/wiki/internal/core/tree"
+)
+
+// A Notion "Markdown & CSV" export names every page "<Title> <id>.md" with
+// a 32 character hex ID. The subpages and files of a page are stored in the
+// folder "<Title> <id>/" next to it. Databases are exported as
+// "<Title> <id>.csv" (newer exports add "<Title> <id>_all.csv" with the rows
+// of all views) and their rows as pages in the folder of the database.
+
+var (
+	notionFilePattern   = regexp.MustCompile(`^(.*?) ?([0-9a-fA-F]{32})(_all)?\.(md|csv)$`)
+	notionFolderPattern = regexp.MustCompile(`^(.*?) ?([0-9a-fA-F]{32})$`)
+	notionURLIDPattern  = regexp.MustCompile(`([0-9a-fA-F]{32})$`)
+)
+
+// DetectNotionExport reports whether root holds an extracted Notion
+// "Markdown & CSV" export and returns its folder: root itself or its only
+// top-level folder.
+func DetectNotionExport(root string) (string, bool) {
+	return findExportDir(root, isNotionExportDir)
+}
+
+func isNotionExportDir(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && notionFilePattern.MatchString(entry.Name()) {
+			return true
+		}
+	}
+	return false
+}
+
+// notionPage is a page, database or database row of the export.
+type notionPage struct {
+	packageNode[*notionPage]
+	id string
+	// source is the Markdown file relative to the export folder; rows
+	// without a page file have none.
+	source string
+	// csv is the database export of the page.
+	csv string
+	// dir is the folder with the subpages and files of the page.
+	dir    string
+	parent *notionPage
+	// files are the files of the page folder; they are imported even when
+	// no page links them.
+	files []string
+	// columns and values hold the database row of the page.
+	columns []string
+	values  []string
+	// assets maps source files to file names in the sidecar folder.
+	assets map[string]string
+	notes  []string
+}
+
+func (p *notionPage) addNote(format string, args ...any) {
+	note := fmt.Sprintf(format, args...)
+	for _, existing := range p.notes {
+		if existing == note {
+			return
+		}
+	}
+	p.notes = append(p.notes, note)
+}
+
+type notionConverter struct {
+	root     string
+	outDir   string
+	slugger  *tree.SlugService
+	roots    []*notionPage
+	byID     map[string]*notionPage
+	bySource map[string]*notionPage
+	// owners maps files to the page whose folder holds them.
+	owners map[string]*notionPage
+}
+
+// ConvertNotionExport converts a Notion "Markdown & CSV" export into a
+// Markdown package in outDir that the planner imports like any other: the
+// IDs are stripped from titles and file names, links between pages and to
+// files are rewritten and every database becomes a section whose rows are
+// pages with the columns as frontmatter properties. The entries are returned
+// in tree order; anything that cannot be mapped is reported as a note of
+// its page.
+func ConvertNotionExport(exportDir, outDir string) ([]ImportMDFile, error) {
+	cv := &notionConverter{
+		root:     exportDir,
+		outDir:   outDir,
+		slugger:  tree.NewSlugService(),
+		byID:     map[string]*notionPage{},
+		bySource: map[string]*notionPage{},
+		owners:   map[string]*notionPage{},
+	}
+	roots, err := cv.scanDir("", nil)
+	if err != nil {
+		return nil, err
+	}
+	cv.roots = roots
+	assignPackagePaths(cv.slugger, "", cv.roots, func(page *notionPage) bool {
+		return len(page.children) > 0 || page.csv != ""
+	})
+
+	var entries []ImportMDFile
+	for _, page := range packageTreeOrder(cv.roots) {
+		if err := cv.convertPage(page); err != nil {
+			return nil, fmt.Errorf("convert %s: %w", page.title, err)
+		}
+		entries = append(entries, ImportMDFile{SourcePath: page.mdPath, Notes: page.notes})
+	}
+	return entries, nil
+}
+
+// scanDir reads the pages of a folder and, recursively, their subpages.
+// Folders that do not belong to a page (e.g. the folder the export is
+// packed in) add their pages to parent.
+func (cv *notionConverter) scanDir(dir string, parent *notionPage) ([]*notionPage, error) {
+	entries, err := os.ReadDir(filepath.Join(cv.root, filepath.FromSlash(dir)))
+	if err != nil {
+		return nil, fmt.Errorf("read notion export: %w", err)
+	}
+
+	var pages []*notionPage
+	byID := map[string]*notionPage{}
+	pageFor := func(id, title string) *notionPage {
+		id = strings.ToLower(id)
+		if page, ok := byID[id]; ok {
+			return page
+		}
+		page := &notionPage{id: id, parent: parent, assets: map[string]string{}}
+		if page.title = strings.TrimSpace(title); page.title == "" {
+			page.title = "Untitled"
+		}
+		byID[id] = page
+		cv.byID[id] = page
+		pages = append(pages, page)
+		return page
+	}
+
+	var folders []string
+	for _, entry := range entries {
+		name := entry.Name()
+		rel := path.Join(dir, name)
+		if entry.IsDir() {
+			folders = append(folders, name)
+			continue
+		}
+		m := notionFilePattern.FindStringSubmatch(name)
+		switch {
+		case m != nil && strings.EqualFold(m[4], "md"):
+			page := pageFor(m[2], m[1])
+			page.source = rel
+			cv.bySource[rel] = page
+			if title := notionPageTitle(filepath.Join(cv.root, filepath.FromSlash(rel))); title != "" {
+				page.title = title
+			}
+		case m != nil:
+			// The export of all rows wins over the one of the default view.
+			page := pageFor(m[2], m[1])
+			if page.csv == "" || m[3] != "" {
+				page.csv = rel
+			}
+			cv.bySource[rel] = page
+		case parent != nil:
+			parent.files = append(parent.files, rel)
+			cv.owners[rel] = parent
+		}
+	}
+
+	var loose []*notionPage
+	for _, name := range folders {
+		rel := path.Join(dir, name)
+		if m := notionFolderPattern.FindStringSubmatch(name); m != nil {
+			if page, ok := byID[strings.ToLower(m[2])]; ok {
+				page.dir = rel
+				continue
+			}
+		}
+		children, err := cv.scanDir(rel, parent)
+		if err != nil {
+			return nil, err
+		}
+		loose = append(loose, children...)
+	}
+
+	for _, page := range pages {
+		if page.dir != "" {
+			children, err := cv.scanDir(page.dir, page)
+			if err != nil {
+				return nil, err
+			}
+			page.children = children
+		}
+		if page.csv != "" {
+			cv.readDatabase(page)
+		} else {
+			cv.sortChildren(page)
+		}
+	}
+	return append(pages, loose...), nil
+}
+
+// notionPageTitle reads the title from the first heading of a page.
+func notionPageTitle(file string) string {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	line, _, _ := strings.Cut(strings.TrimPrefix(string(raw), "\ufeff"), "\n")
+	if title, ok := strings.CutPrefix(strings.TrimSpace(line), "# "); ok {
+		return strings.TrimSpace(title)
+	}
+	return ""
+}
+
+// sortChildren orders subpages the way the page links them; Notion lists
+// every subpage in the content of its parent. The others follow by title.
+func (cv *notionConverter) sortChildren(page *notionPage) {
+	if page.source == "" || len(page.children) < 2 {
+		return
+	}
+	raw, err := os.ReadFile(filepath.Join(cv.root, filepath.FromSlash(page.source)))
+	if err != nil {
+		return
+	}
+	position := map[*notionPage]int{}
+	_, _ = RewriteMarkdownLinks(string(raw), func(link MarkdownLink) (string, error) {
+		if target := cv.linkedPage(page, link.Href()); target != nil && target.parent == page {
+			if _, seen := position[target]; !seen {
+				position[target] = len(position)
+			}
+		}
+		return "", nil
+	})
+	sort.SliceStable(page.children, func(i, j int) bool {
+		pi, iok := position[page.children[i]]
+		pj, jok := position[page.children[j]]
+		switch {
+		case iok && jok:
+			return pi < pj
+		case iok != jok:
+			return iok
+		default:
+			return page.children[i].title < page.children[j].title
+		}
+	})
+}
+
+// readDatabase matches the rows of a database export to the row pages in
+// its folder. Rows keep the order of the export; rows without a page file
+// become pages with properties only.
+func (cv *notionConverter) readDatabase(db *notionPage) {
+	raw, err := os.ReadFile(filepath.Join(cv.root, filepath.FromSlash(db.csv)))
+	if err != nil {
+		db.addNote("Database export %q could not be read; its rows were imported without properties", path.Base(db.csv))
+		return
+	}
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(raw, []byte("\ufeff"))))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil || len(records) == 0 {
+		db.addNote("Database export %q could not be read; its rows were imported without properties", path.Base(db.csv))
+		return
+	}
+	columns := records[0]
+	checkNotionColumns(db, columns)
+
+	matched := map[*notionPage]bool{}
+	var rows []*notionPage
+	for _, record := range records[1:] {
+		if len(record) == 0 || (len(record) == 1 && strings.TrimSpace(record[0]) == "") {
+			continue
+		}
+		title := strings.TrimSpace(record[0])
+		var row *notionPage
+		for _, child := range db.children {
+			if !matched[child] && child.title == title {
+				row = child
+				break
+			}
+		}
+		if row == nil {
+			if title == "" {
+				title = "Untitled"
+			}
+			row = &notionPage{parent: db, assets: map[string]string{}}
+			row.title = title
+		}
+		matched[row] = true
+		row.columns = columns
+		row.values = record
+		rows = append(rows, row)
+	}
+	for _, child := range db.children {
+		if !matched[child] {
+			child.addNote("Page is not a row of the database %q and was imported without properties", db.title)
+			rows = append(rows, child)
+		}
+	}
+	db.children = rows
+}
+
+func (cv *notionConverter) convertPage(page *notionPage) error {
+	var body string
+	if page.source != "" {
+		raw, err := os.ReadFile(filepath.Join(cv.root, filepath.FromSlash(page.source)))
+		if err != nil {
+			return err
+		}
+		body = notionPageBody(strings.TrimPrefix(string(raw), "\ufeff"), page.columns)
+	}
+
+	body, err := RewriteMarkdownLinks(body, func(link MarkdownLink) (string, error) {
+		dest, ok, err := cv.rewriteLink(page, link.Href())
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return link.Label, nil
+		}
+		return MarkdownLink{Image: link.Image, Label: link.Label, Destination: markdownDestination(dest)}.String(), nil
+	})
+	if err != nil {
+		return err
+	}
+	body = convertNotionAsides(body)
+
+	// Files of the page folder that are not linked are imported as well.
+	for _, file := range page.files {
+		if _, err := cv.attachment(page, file); err != nil {
+			return err
+		}
+	}
+
+	fields := cv.rowFrontmatter(page)
+	fields["leafwiki_title"] = page.title
+	md, err := markdown.BuildMarkdownWithExtraFrontmatter(fields, strings.TrimSpace(body)+"\n")
+	if err != nil {
+		return err
+	}
+	out := filepath.Join(cv.outDir, filepath.FromSlash(page.mdPath))
+	if err := os.MkdirAll(filepath.Dir(out), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(out, []byte(md), 0o644)
+}
+
+// notionPageBody removes the title heading and, for database rows, the
+// "Column: value" lines Notion writes below it.
+func notionPageBody(content string, columns []string) string {
+	lines := strings.Split(content, "\n")
+	i := 0
+	for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+		i++
+	}
+	if i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "# ") {
+		i++
+	}
+	if len(columns) > 1 {
+		for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+			i++
+		}
+		for i < len(lines) && isNotionPropertyLine(lines[i], columns[1:]) {
+			i++
+		}
+	}
+	return strings.Join(lines[i:], "\n")
+}
+
+func isNotionPropertyLine(line string, columns []string) bool {
+	for _, column := range columns {
+		if column != "" && (line == column+":" || strings.HasPrefix(line, column+": ")) {
+			return true
+		}
+	}
+	return false
+}
+
+// convertNotionAsides turns the <aside> blocks Notion exports for callouts
+// into info callouts.
+func convertNotionAsides(content string) string {
+	lines := strings.Split(content, "\n")
+	inAside := false
+	for i, line := range lines {
+		switch strings.TrimSpace(line) {
+		case "<aside>":
+			if !inAside {
+				lines[i] = ":::info"
+				inAside = true
+			}
+		case "</aside>":
+			if inAside {
+				lines[i] = ":::"
+				inAside = false
+			}
+		}
+	}
+	if inAside {
+		lines = append(lines, ":::")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// notionColumnKey returns the property a column of a database is stored
+// as; reserved, unnamed and repeated columns are skipped.
+func notionColumnKey(column string, seen map[string]bool) (string, bool) {
+	key := strings.TrimSpace(column)
+	duplicate := seen[strings.ToLower(key)]
+	seen[strings.ToLower(key)] = true
+	if key == "" || duplicate || (markdown.IsSystemKey(key) && !strings.EqualFold(key, "tags")) {
+		return key, false
+	}
+	return key, true
+}
+
+// checkNotionColumns notes the columns of a database that cannot be imported.
+func checkNotionColumns(db *notionPage, columns []string) {
+	seen := map[string]bool{}
+	for i, column := range columns[1:] {
+		key, ok := notionColumnKey(column, seen)
+		switch {
+		case ok:
+		case key == "":
+			db.addNote("Column %d of the database has no name and was not imported", i+2)
+		case markdown.IsSystemKey(key):
+			db.addNote("Column %q of the database uses a reserved name and was not imported", key)
+		default:
+			db.addNote("Column %q of the database is not unique; only its first value was imported", key)
+		}
+	}
+}
+
+// rowFrontmatter stores the columns of a database row as properties; a
+// "Tags" column becomes the tags of the page. Values that cannot be stored
+// as properties are noted.
+func (cv *notionConverter) rowFrontmatter(page *notionPage) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if len(page.columns) < 2 {
+		return fields
+	}
+	seen := map[string]bool{}
+	for i, column := range page.columns[1:] {
+		key, ok := notionColumnKey(column, seen)
+		if !ok {
+			continue
+		}
+		value := ""
+		if i+1 < len(page.values) {
+			value = strings.TrimSpace(page.values[i+1])
+		}
+		switch {
+		case value == "":
+		case strings.EqualFold(key, "tags"):
+			fields["tags"] = splitNotionList(value)
+		case strings.ContainsAny(value, "\r\n"):
+			page.addNote("Column %q has a value with several lines that cannot be stored as a property", key)
+		default:
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+func splitNotionList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// rewriteLink maps a link of a page into the converted package. Links to
+// pages and files of the export are resolved by their path and, as Notion
+// shortens long names, by their ID.
+func (cv *notionConverter) rewriteLink(page *notionPage, href string) (string, bool, error) {
+	if href == "" || strings.HasPrefix(href, "#") {
+		return href, true, nil
+	}
+	base, suffix := splitURLSuffix(href)
+	if target := cv.linkedPage(page, href); target != nil {
+		if strings.HasPrefix(suffix, "?") {
+			suffix = ""
+		}
+		return relativePackagePath(page.mdPath, target.mdPath) + suffix, true, nil
+	}
+	if isExternalHref(href) {
+		return href, true, nil
+	}
+
+	target := path.Clean(path.Join(path.Dir(page.source), decodeImportTarget(base)))
+	if target != ".." && !strings.HasPrefix(target, "../") {
+		abs := filepath.Join(cv.root, filepath.FromSlash(target))
+		if info, err := os.Stat(abs); err == nil && info.Mode().IsRegular() {
+			file, err := cv.attachment(page, target)
+			if err != nil {
+				return "", false, err
+			}
+			return relativePackagePath(page.mdPath, file), true, nil
+		}
+	}
+	page.addNote("Link to %q could not be resolved and was removed", decodeImportTarget(base))
+	return "", false, nil
+}
+
+// linkedPage resolves a link to a page of the export: a relative link to
+// its Markdown or CSV file or a notion.so link with its ID.
+func (cv *notionConverter) linkedPage(from *notionPage, href string) *notionPage {
+	base, _ := splitURLSuffix(href)
+	if isExternalHref(href) {
+		u, err := url.Parse(href)
+		if err != nil || !(strings.HasSuffix(u.Hostname(), "notion.so") || strings.HasSuffix(u.Hostname(), "notion.site")) {
+			return nil
+		}
+		if m := notionURLIDPattern.FindStringSubmatch(u.Path); m != nil {
+			return cv.byID[strings.ToLower(m[1])]
+		}
+		return nil
+	}
+	if from.source == "" {
+		return nil
+	}
+	target := path.Clean(path.Join(path.Dir(from.source), decodeImportTarget(base)))
+	if page := cv.bySource[target]; page != nil {
+		return page
+	}
+	if m := notionFilePattern.FindStringSubmatch(path.Base(target)); m != nil {
+		return cv.byID[strings.ToLower(m[2])]
+	}
+	return nil
+}
+
+// attachment copies a file into the sidecar folder of the page whose folder
+// holds it (else the linking page) and returns its path in the package.
+func (cv *notionConverter) attachment(from *notionPage, src string) (string, error) {
+	owner := cv.owners[src]
+	if owner == nil {
+		owner = from
+	}
+	sidecar := SidecarAssetsDir(owner.mdPath)
+	if name, ok := owner.assets[src]; ok {
+		return path.Join(sidecar, name), nil
+	}
+
+	name := uniqueAssetName(path.Base(src), owner.assets)
+	owner.assets[src] = name
+	dest := filepath.Join(cv.outDir, filepath.FromSlash(sidecar), name)
+	if err := copyFile(filepath.Join(cv.root, filepath.FromSlash(src)), dest); err != nil {
+		return "", fmt.Errorf("copy file %s: %w", src, err)
+	}
+	return path.Join(sidecar, name), nil
+}
@@ -0,0 +1,133 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/perber/wiki/internal/core/markdown"
+	"github.com/perber/wiki/internal/test_utils"
+)
+
+func notionFixtureDir(t *testing.T) string {
+	t.Helper()
+	return test_utils.FixturePath(t, "notion-export", "fixtures", "internal/importer/fixtures")
+}
+
+func TestDetectNotionExport_FindsExportFolder(t *testing.T) {
+	exportDir, ok := DetectNotionExport(notionFixtureDir(t))
+	if !ok || filepath.Base(exportDir) != "Export-7f3c2a" {
+		t.Fatalf("DetectNotionExport = %q, %v", exportDir, ok)
+	}
+
+	tmp := t.TempDir()
+	test_utils.WriteFile(t, tmp, "docs/guide.md", "# Guide")
+	if _, ok := DetectNotionExport(tmp); ok {
+		t.Fatalf("expected plain markdown package not to be detected as Notion export")
+	}
+}
+
+func TestConvertNotionExport_MapsPagesAndDatabases(t *testing.T) {
+	exportDir, _ := DetectNotionExport(notionFixtureDir(t))
+	out := t.TempDir()
+	entries, err := ConvertNotionExport(exportDir, out)
+	if err != nil {
+		t.Fatalf("ConvertNotionExport err: %v", err)
+	}
+
+	var paths []string
+	notes := map[string]string{}
+	for _, entry := range entries {
+		paths = append(paths, entry.SourcePath)
+		notes[entry.SourcePath] = strings.Join(entry.Notes, "\n")
+	}
+	want := []string{
+		"team-wiki/index.md",
+		"team-wiki/projects/index.md",
+		"team-wiki/projects/website-relaunch.md",
+		"team-wiki/projects/mobile-app.md",
+		"team-wiki/projects/planning-only.md",
+		"team-wiki/projects/stray.md",
+		"team-wiki/onboarding.md",
+	}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Fatalf("entries = %v, want %v", paths, want)
+	}
+
+	for source, expected := range map[string][]string{
+		"team-wiki/index.md":                     {`Link to "Old Handbook 99999999999999999999999999999999.md" could not be resolved`},
+		"team-wiki/projects/index.md":            {`Column "leafwiki_id" of the database uses a reserved name`},
+		"team-wiki/projects/website-relaunch.md": {`Column "Notes" has a value with several lines`},
+		"team-wiki/projects/stray.md":            {`not a row of the database "Projects"`},
+	} {
+		for _, note := range expected {
+			if !strings.Contains(notes[source], note) {
+				t.Fatalf("expected note %q for %s, got:\n%s", note, source, notes[source])
+			}
+		}
+	}
+	if notes["team-wiki/onboarding.md"] != "" || notes["team-wiki/projects/mobile-app.md"] != "" {
+		t.Fatalf("unexpected notes: %#v", notes)
+	}
+
+	load := func(rel string) *markdown.MarkdownFile {
+		t.Helper()
+		mf, err := markdown.LoadMarkdownFile(filepath.Join(out, filepath.FromSlash(rel)))
+		if err != nil {
+			t.Fatalf("load %s: %v", rel, err)
+		}
+		return mf
+	}
+
+	home := load("team-wiki/index.md")
+	for _, expected := range []string{
+		"Start with the [Projects](projects/index.md) database and read [Onboarding](onboarding.md) first.",
+		":::info\n💡 Ask in the help channel if you are stuck.\n:::",
+		"The old handbook is gone.",
+	} {
+		if !strings.Contains(home.GetContent(), expected) {
+			t.Fatalf("expected home to contain %q, got:\n%s", expected, home.GetContent())
+		}
+	}
+	if strings.Contains(home.GetContent(), "# Team Wiki") {
+		t.Fatalf("expected title heading to be removed, got:\n%s", home.GetContent())
+	}
+
+	onboarding := load("team-wiki/onboarding.md")
+	for _, expected := range []string{
+		"![Architecture diagram](onboarding.assets/diagram%201.png)",
+		"[checklist](onboarding.assets/checklist.pdf)",
+		"[Website Relaunch](projects/website-relaunch.md#goals)",
+		"Back to [Team Wiki](index.md).",
+	} {
+		if !strings.Contains(onboarding.GetContent(), expected) {
+			t.Fatalf("expected onboarding to contain %q, got:\n%s", expected, onboarding.GetContent())
+		}
+	}
+	for _, file := range []string{"diagram 1.png", "checklist.pdf", "notes.txt"} {
+		if _, err := os.Stat(filepath.Join(out, "team-wiki", "onboarding.assets", file)); err != nil {
+			t.Fatalf("expected %s in sidecar folder: %v", file, err)
+		}
+	}
+
+	relaunch := load("team-wiki/projects/website-relaunch.md")
+	fm := relaunch.GetFrontmatter()
+	if fm.LeafWikiTitle != "Website Relaunch" || fm.ExtraFields["Status"] != "In progress" || fm.ExtraFields["Owner"] != "Jane" {
+		t.Fatalf("unexpected frontmatter: %#v", fm)
+	}
+	if tags, _ := fm.ExtraFields["tags"].([]interface{}); len(tags) != 2 || tags[0] != "web" || tags[1] != "design" {
+		t.Fatalf("unexpected tags: %#v", fm.ExtraFields["tags"])
+	}
+	if _, ok := fm.ExtraFields["Notes"]; ok {
+		t.Fatalf("multi-line value must not become a property: %#v", fm.ExtraFields)
+	}
+	if content := relaunch.GetContent(); strings.HasPrefix(content, "Status:") || !strings.HasPrefix(content, "## Goals") || !strings.Contains(content, "[Mobile App](mobile-app.md)") {
+		t.Fatalf("unexpected row content:\n%s", content)
+	}
+
+	planning := load("team-wiki/projects/planning-only.md")
+	if planning.GetFrontmatter().ExtraFields["Status"] != "Planned" || strings.TrimSpace(planning.GetContent()) != "" {
+		t.Fatalf("unexpected page for row without file: %#v %q", planning.GetFrontmatter(), planning.GetContent())
+	}
+}
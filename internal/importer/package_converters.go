@@ -0,0 +1,197 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/perber/wiki/internal/core/tree"
+)
+
+// Exports of other tools are converted into a Markdown package inside the
+// workspace before planning, so that the planner and executor handle them
+// like any other import.
+
+// convertedPackageDir is the folder inside the workspace that exports of
+// other tools are converted to before planning.
+const convertedPackageDir = ".leafwiki-converted"
+
+// packageConverter recognises an export format in a workspace and converts
+// it into a Markdown package.
+type packageConverter struct {
+	name string
+	// detect returns the folder of the export inside the workspace.
+	detect  func(root string) (string, bool)
+	convert func(exportDir, outDir string) ([]ImportMDFile, error)
+}
+
+var packageConverters = []packageConverter{
+	{name: "confluence export", detect: DetectConfluenceExport, convert: ConvertConfluenceExport},
+	{name: "notion export", detect: DetectNotionExport, convert: ConvertNotionExport},
+	{name: "mediawiki dump", detect: DetectMediaWikiDump, convert: ConvertMediaWikiDump},
+	// Plain packages with HTML or Word documents come last, as the exports
+	// above contain HTML files as well.
+	{name: "documents", detect: DetectDocuments, convert: ConvertDocuments},
+}
+
+// findImportEntries returns the markdown files to import from a workspace
+// and the folder they are relative to. Exports of other tools are converted
+// to a markdown package inside the workspace first.
+func findImportEntries(folderPath string) ([]ImportMDFile, string, error) {
+	for _, pc := range packageConverters {
+		exportDir, ok := pc.detect(folderPath)
+		if !ok {
+			continue
+		}
+		outDir := filepath.Join(folderPath, convertedPackageDir)
+		if err := os.RemoveAll(outDir); err != nil {
+			return nil, "", fmt.Errorf("reset converted package: %w", err)
+		}
+		entries, err := pc.convert(exportDir, outDir)
+		if err != nil {
+			return nil, "", fmt.Errorf("convert %s: %w", pc.name, err)
+		}
+		return entries, outDir, nil
+	}
+
+	entries, err := FindMarkdownEntries(folderPath)
+	if err != nil {
+		return nil, "", err
+	}
+	return entries, folderPath, nil
+}
+
+// findExportDir returns root or, as exports are often packed into one
+// folder, its only top-level folder if isExport accepts it.
+func findExportDir(root string, isExport func(dir string) bool) (string, bool) {
+	if isExport(root) {
+		return root, true
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return "", false
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") && !strings.HasPrefix(entry.Name(), "__") {
+			dirs = append(dirs, entry.Name())
+		}
+	}
+	if len(dirs) != 1 {
+		return "", false
+	}
+	dir := filepath.Join(root, dirs[0])
+	return dir, isExport(dir)
+}
+
+// packageNode places a page of an export in the converted package. The page
+// types of the converters embed it.
+type packageNode[P any] struct {
+	title    string
+	children []P
+	// slugFallback is the slug of a page whose title yields none ("page"
+	// when empty).
+	slugFallback string
+	// mdPath is the Markdown file in the converted package.
+	mdPath string
+}
+
+func (n *packageNode[P]) node() *packageNode[P] {
+	return n
+}
+
+// packagePage is a page type that embeds packageNode.
+type packagePage[P any] interface {
+	node() *packageNode[P]
+}
+
+// assignPackagePaths gives every page its Markdown file: <slug>/index.md
+// for pages that hasFolder accepts, <slug>.md otherwise. Slugs are unique
+// per folder.
+func assignPackagePaths[P packagePage[P]](slugger *tree.SlugService, dir string, pages []P, hasFolder func(P) bool) {
+	used := map[string]bool{}
+	for _, page := range pages {
+		n := page.node()
+		base := slugger.GenerateValidSlug(n.title)
+		if base == "" {
+			base = n.slugFallback
+		}
+		if base == "" {
+			base = "page"
+		}
+		slug := base
+		for i := 2; used[slug]; i++ {
+			slug = fmt.Sprintf("%s-%d", base, i)
+		}
+		used[slug] = true
+
+		if hasFolder(page) {
+			n.mdPath = path.Join(dir, slug, indexFilename)
+			assignPackagePaths(slugger, path.Join(dir, slug), n.children, hasFolder)
+		} else {
+			n.mdPath = path.Join(dir, slug+".md")
+		}
+	}
+}
+
+// packageTreeOrder returns the pages below roots in tree order, parents
+// first.
+func packageTreeOrder[P packagePage[P]](roots []P) []P {
+	var out []P
+	var visit func(pages []P)
+	visit = func(pages []P) {
+		for _, page := range pages {
+			out = append(out, page)
+			visit(page.node().children)
+		}
+	}
+	visit(roots)
+	return out
+}
+
+// relativePackagePath returns the link from one file of the package to
+// another.
+func relativePackagePath(from, to string) string {
+	rel, err := filepath.Rel(filepath.FromSlash(path.Dir(from)), filepath.FromSlash(to))
+	if err != nil {
+		return "/" + to
+	}
+	return filepath.ToSlash(rel)
+}
+
+// uniqueAssetName returns name, numbered if a file of the same name is
+// already in the sidecar folder (assets maps sources to file names).
+func uniqueAssetName(name string, assets map[string]string) string {
+	taken := map[string]bool{}
+	for _, existing := range assets {
+		taken[strings.ToLower(existing)] = true
+	}
+	base, ext := strings.TrimSuffix(name, path.Ext(name)), path.Ext(name)
+	for i := 2; taken[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	return name
+}
+
+func copyFile(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,328 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/perber/wiki/internal/core/tree"
+)
+
+// ConflictStrategy decides what happens to an item whose target page
+// already exists in the wiki.
+type ConflictStrategy string
+
+const (
+	ConflictStrategySkip             ConflictStrategy = "skip"              // keeps the existing page
+	ConflictStrategyOverwrite        ConflictStrategy = "overwrite"         // replaces content and frontmatter of the existing page
+	ConflictStrategyMergeFrontmatter ConflictStrategy = "merge_frontmatter" // replaces content, keeps frontmatter fields the import does not set
+	ConflictStrategyKeepBoth         ConflictStrategy = "keep_both"         // imports next to the existing page with a numbered slug
+)
+
+var ErrPlanStale = errors.New("plan is stale")
+var ErrInvalidPlanEdit = errors.New("invalid plan edit")
+var ErrPlanNotEditable = errors.New("plan can no longer be edited")
+
+func (s ConflictStrategy) valid() bool {
+	switch s {
+	case ConflictStrategySkip, ConflictStrategyOverwrite, ConflictStrategyMergeFrontmatter, ConflictStrategyKeepBoth:
+		return true
+	}
+	return false
+}
+
+// PlanItemEdit changes one item of a plan, identified by its source path.
+// Nil fields are left unchanged.
+type PlanItemEdit struct {
+	SourcePath string
+	// Action "skip" skips the item. For items whose target exists,
+	// "update" overwrites the existing page and "create" keeps both.
+	Action     *PlanAction
+	TargetPath *string
+	Title      *string
+	Slug       *string
+	// Strategy overrides the conflict strategy of the plan for this item;
+	// an empty strategy follows the plan again.
+	Strategy *ConflictStrategy
+}
+
+// PlanEdit is a set of changes to a stored plan that are applied together.
+type PlanEdit struct {
+	// TreeHash is the tree hash of the plan the edit is based on; an
+	// empty hash skips the check.
+	TreeHash         string
+	ConflictStrategy *ConflictStrategy
+	Items            []PlanItemEdit
+	// Exclude and Include add and remove source paths (files or folders)
+	// whose items are not imported.
+	Exclude []string
+	Include []string
+}
+
+// EditPlan applies edit to plan and revalidates the result against the
+// current wiki. The plan is rejected with ErrPlanStale when the wiki
+// changed since the plan was created. On error, plan may be partially
+// modified; callers edit a copy.
+func (p *Planner) EditPlan(plan *PlanResult, edit PlanEdit) error {
+	if edit.TreeHash != "" && edit.TreeHash != plan.TreeHash {
+		return fmt.Errorf("%w: edit is based on tree_hash %s but plan has %s", ErrPlanStale, edit.TreeHash, plan.TreeHash)
+	}
+	if current := p.wiki.TreeHash(); current != plan.TreeHash {
+		return fmt.Errorf("%w: expected tree_hash %s but got %s", ErrPlanStale, plan.TreeHash, current)
+	}
+
+	if edit.ConflictStrategy != nil {
+		if !edit.ConflictStrategy.valid() {
+			return fmt.Errorf("%w: unknown conflict strategy %q", ErrInvalidPlanEdit, *edit.ConflictStrategy)
+		}
+		plan.ConflictStrategy = *edit.ConflictStrategy
+	}
+	for _, source := range edit.Exclude {
+		if source = normalizePlanSourcePath(source); source != "" && !slices.Contains(plan.ExcludedPaths, source) {
+			plan.ExcludedPaths = append(plan.ExcludedPaths, source)
+		}
+	}
+	for _, source := range edit.Include {
+		source = normalizePlanSourcePath(source)
+		plan.ExcludedPaths = slices.DeleteFunc(plan.ExcludedPaths, func(excluded string) bool { return excluded == source })
+	}
+
+	bySource := make(map[string]int, len(plan.Items))
+	for i, item := range plan.Items {
+		bySource[item.SourcePath] = i
+	}
+	edited := map[int]PlanItemEdit{}
+	moved := map[int]bool{}
+	for _, itemEdit := range edit.Items {
+		i, ok := bySource[itemEdit.SourcePath]
+		if !ok {
+			return fmt.Errorf("%w: no item for source path %q", ErrInvalidPlanEdit, itemEdit.SourcePath)
+		}
+		itemMoved, err := p.applyItemEdit(&plan.Items[i], itemEdit)
+		if err != nil {
+			return err
+		}
+		edited[i] = itemEdit
+		moved[i] = moved[i] || itemMoved
+	}
+
+	// Items follow the conflict strategy unless they were moved; conflicts
+	// are resolved again for every item, as a changed target or strategy
+	// can affect the numbered slugs of others.
+	for i := range plan.Items {
+		item := &plan.Items[i]
+		itemEdit, isEdited := edited[i]
+		if err := p.resolveConflict(plan, item, moved[i]); err != nil {
+			return err
+		}
+		if isEdited && itemEdit.Action != nil && *itemEdit.Action == PlanActionSkip {
+			item.Action = PlanActionSkip
+		}
+		if isEdited && itemEdit.Slug != nil && item.Action == PlanActionUpdate {
+			item.DesiredSlug = *itemEdit.Slug
+		}
+		item.Excluded = isExcludedSource(plan.ExcludedPaths, item.SourcePath)
+	}
+
+	return validatePlanTargets(plan)
+}
+
+// applyItemEdit changes an item and reports whether its target moved.
+func (p *Planner) applyItemEdit(item *PlanItem, edit PlanItemEdit) (bool, error) {
+	moved := false
+	if item.Change == ImportChangeRemoved && (edit.Title != nil || edit.TargetPath != nil || edit.Slug != nil || edit.Strategy != nil) {
+		return false, fmt.Errorf("%w: source of %q was removed, only its action can be changed", ErrInvalidPlanEdit, item.SourcePath)
+	}
+	if edit.Title != nil {
+		title := strings.TrimSpace(*edit.Title)
+		if title == "" {
+			return false, fmt.Errorf("%w: title of %q must not be empty", ErrInvalidPlanEdit, item.SourcePath)
+		}
+		item.Title = title
+	}
+	if edit.Strategy != nil {
+		if *edit.Strategy != "" && !edit.Strategy.valid() {
+			return false, fmt.Errorf("%w: unknown conflict strategy %q", ErrInvalidPlanEdit, *edit.Strategy)
+		}
+		item.Strategy = *edit.Strategy
+	}
+	if edit.Action != nil {
+		switch *edit.Action {
+		case PlanActionSkip, PlanActionCreate, PlanActionUpdate:
+			if item.Change == ImportChangeRemoved && *edit.Action != PlanActionSkip {
+				return false, fmt.Errorf("%w: source of %q was removed, it can only be skipped, deleted or trashed", ErrInvalidPlanEdit, item.SourcePath)
+			}
+		case PlanActionDelete, PlanActionTrash:
+			if item.Change != ImportChangeRemoved {
+				return false, fmt.Errorf("%w: only pages of removed sources can be deleted or trashed, not %q", ErrInvalidPlanEdit, item.SourcePath)
+			}
+		default:
+			return false, fmt.Errorf("%w: unknown action %q", ErrInvalidPlanEdit, *edit.Action)
+		}
+		// For items whose target exists, the action selects a strategy.
+		if !item.Change.mapped() && (item.Exists || item.ConflictPath != "") {
+			switch *edit.Action {
+			case PlanActionSkip:
+				item.Strategy = ConflictStrategySkip
+			case PlanActionCreate:
+				item.Strategy = ConflictStrategyKeepBoth
+			case PlanActionUpdate:
+				if item.Strategy != ConflictStrategyMergeFrontmatter {
+					item.Strategy = ConflictStrategyOverwrite
+				}
+			}
+		}
+		item.Action = *edit.Action
+	}
+	if edit.TargetPath != nil {
+		target, err := p.slugger.NormalizePath(strings.Trim(strings.TrimSpace(*edit.TargetPath), "/"), true)
+		if err != nil {
+			return false, fmt.Errorf("%w: target path of %q: %v", ErrInvalidPlanEdit, item.SourcePath, err)
+		}
+		if target == "" && item.Kind != tree.NodeKindSection {
+			return false, fmt.Errorf("%w: target path of %q must not be empty", ErrInvalidPlanEdit, item.SourcePath)
+		}
+		item.TargetPath, item.ConflictPath = target, ""
+		moved = true
+	}
+	if edit.Slug != nil {
+		if err := p.slugger.IsValidSlug(*edit.Slug); err != nil {
+			return false, fmt.Errorf("%w: slug of %q: %v", ErrInvalidPlanEdit, item.SourcePath, err)
+		}
+		if item.Action != PlanActionUpdate {
+			base := item.TargetPath
+			if item.ConflictPath != "" {
+				base = item.ConflictPath
+			}
+			item.TargetPath, item.ConflictPath = strings.TrimPrefix(path.Join(path.Dir(base), *edit.Slug), "./"), ""
+			moved = true
+		}
+	}
+	return moved, nil
+}
+
+// resolveConflict looks up the target of an item and applies its conflict
+// strategy when the target exists. Items without a conflict keep their
+// action; moved items are created.
+func (p *Planner) resolveConflict(plan *PlanResult, item *PlanItem, moved bool) error {
+	// Items of an earlier import of the source belong to their page.
+	if item.Change.mapped() {
+		if moved {
+			return fmt.Errorf("%w: %q belongs to a page of an earlier import and cannot be moved", ErrInvalidPlanEdit, item.SourcePath)
+		}
+		if item.Change == ImportChangeRemoved {
+			return p.placeRemovedItem(plan, item)
+		}
+		if item.Action == PlanActionCreate {
+			return fmt.Errorf("%w: %q belongs to a page of an earlier import and cannot be created again", ErrInvalidPlanEdit, item.SourcePath)
+		}
+		return nil
+	}
+	if !moved && !item.Exists && item.ConflictPath == "" {
+		if item.Action == PlanActionUpdate {
+			return fmt.Errorf("%w: %q does not exist and cannot be updated", ErrInvalidPlanEdit, item.TargetPath)
+		}
+		return nil
+	}
+
+	base := item.TargetPath
+	if item.ConflictPath != "" {
+		base = item.ConflictPath
+	}
+	lookup, err := p.wiki.LookupPagePath(base)
+	if err != nil {
+		return err
+	}
+	item.Exists, item.ExistingID, item.ConflictPath = false, nil, ""
+	item.TargetPath = base
+	if !lookup.Exists || len(lookup.Segments) == 0 {
+		item.DesiredSlug = path.Base(base)
+		if moved || item.Action == PlanActionUpdate {
+			item.Action = PlanActionCreate
+		}
+		return nil
+	}
+
+	existing := lookup.Segments[len(lookup.Segments)-1]
+	switch plan.strategyFor(*item) {
+	case ConflictStrategyOverwrite, ConflictStrategyMergeFrontmatter:
+		item.Exists, item.ExistingID, item.DesiredSlug = true, existing.ID, existing.Slug
+		item.Action = PlanActionUpdate
+	case ConflictStrategyKeepBoth:
+		target, err := p.freeTargetPath(plan, item, base)
+		if err != nil {
+			return err
+		}
+		item.ConflictPath, item.TargetPath, item.DesiredSlug = base, target, path.Base(target)
+		item.Action = PlanActionCreate
+	default:
+		item.Exists, item.ExistingID, item.DesiredSlug = true, existing.ID, existing.Slug
+		item.Action = PlanActionSkip
+	}
+	return nil
+}
+
+// freeTargetPath numbers the slug of base until it neither exists in the
+// wiki nor is the target of another item.
+func (p *Planner) freeTargetPath(plan *PlanResult, item *PlanItem, base string) (string, error) {
+	taken := map[string]bool{}
+	for i := range plan.Items {
+		if other := &plan.Items[i]; other != item {
+			taken[other.TargetPath] = true
+		}
+	}
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", base, i)
+		if taken[candidate] {
+			continue
+		}
+		lookup, err := p.wiki.LookupPagePath(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !lookup.Exists {
+			return candidate, nil
+		}
+	}
+}
+
+// strategyFor returns the conflict strategy that applies to an item.
+func (plan *PlanResult) strategyFor(item PlanItem) ConflictStrategy {
+	if item.Strategy != "" {
+		return item.Strategy
+	}
+	if plan.ConflictStrategy != "" {
+		return plan.ConflictStrategy
+	}
+	return ConflictStrategySkip
+}
+
+// validatePlanTargets rejects plans in which two imported items share a
+// target.
+func validatePlanTargets(plan *PlanResult) error {
+	seen := map[string]string{}
+	for _, item := range plan.Items {
+		if item.Excluded || item.Action == PlanActionSkip {
+			continue
+		}
+		if other, ok := seen[item.TargetPath]; ok {
+			return fmt.Errorf("%w: %q and %q both target %q", ErrInvalidPlanEdit, other, item.SourcePath, item.TargetPath)
+		}
+		seen[item.TargetPath] = item.SourcePath
+	}
+	return nil
+}
+
+// isExcludedSource reports whether a source path is one of the excluded
+// paths or inside an excluded folder.
+func isExcludedSource(excluded []string, source string) bool {
+	source = normalizePlanSourcePath(source)
+	for _, prefix := range excluded {
+		if source == prefix || strings.HasPrefix(source, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package importer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/perber/wiki/internal/core/tree"
+	"github.com/perber/wiki/internal/test_utils"
+)
+
+// newEditablePlan plans a.md, b.md and guides/c.md into docs, where docs/a
+// already exists.
+func newEditablePlan(t *testing.T) (*fakeWiki, *Planner, *PlanResult) {
+	t.Helper()
+	tmp := t.TempDir()
+	test_utils.WriteFile(t, tmp, "a.md", "# A")
+	test_utils.WriteFile(t, tmp, "b.md", "# B")
+	test_utils.WriteFile(t, tmp, "guides/c.md", "# C")
+
+	existingID := "existing-a"
+	wiki := &fakeWiki{
+		treeHash: "h1",
+		lookups: map[string]*tree.PathLookup{
+			"docs/a": {
+				Path:   "docs/a",
+				Exists: true,
+				Segments: []tree.PathSegment{
+					{Slug: "docs", Exists: true},
+					{Slug: "a", Exists: true, ID: &existingID},
+				},
+			},
+		},
+	}
+	p := newPlannerWithFake(wiki)
+	plan, err := p.CreatePlan([]ImportMDFile{{SourcePath: "a.md"}, {SourcePath: "b.md"}, {SourcePath: "guides/c.md"}}, PlanOptions{
+		SourceBasePath: tmp,
+		TargetBasePath: "docs",
+	})
+	if err != nil {
+		t.Fatalf("CreatePlan err: %v", err)
+	}
+	if len(plan.Items) != 3 || plan.Items[0].Action != PlanActionSkip {
+		t.Fatalf("unexpected plan: %#v", plan.Items)
+	}
+	return wiki, p, plan
+}
+
+func strategyPtr(s ConflictStrategy) *ConflictStrategy { return &s }
+
+func TestPlanner_EditPlan_GlobalStrategyUpdatesExistingPages(t *testing.T) {
+	_, p, plan := newEditablePlan(t)
+
+	if err := p.EditPlan(plan, PlanEdit{TreeHash: "h1", ConflictStrategy: strategyPtr(ConflictStrategyOverwrite)}); err != nil {
+		t.Fatalf("EditPlan err: %v", err)
+	}
+	a := plan.Items[0]
+	if a.Action != PlanActionUpdate || a.ExistingID == nil || *a.ExistingID != "existing-a" || a.DesiredSlug != "a" {
+		t.Fatalf("existing item = %#v", a)
+	}
+	if plan.Items[1].Action != PlanActionCreate {
+		t.Fatalf("new item action = %q", plan.Items[1].Action)
+	}
+
+	// An item strategy wins over the plan strategy.
+	edit := PlanEdit{Items: []PlanItemEdit{{SourcePath: "a.md", Strategy: strategyPtr(ConflictStrategySkip)}}}
+	if err := p.EditPlan(plan, edit); err != nil {
+		t.Fatalf("EditPlan err: %v", err)
+	}
+	if plan.Items[0].Action != PlanActionSkip {
+		t.Fatalf("Action = %q, want skip", plan.Items[0].Action)
+	}
+}
+
+func TestPlanner_EditPlan_KeepBothNumbersTheSlug(t *testing.T) {
+	wiki, p, plan := newEditablePlan(t)
+	otherID := "existing-a-2"
+	wiki.lookups["docs/a-2"] = &tree.PathLookup{Path: "docs/a-2", Exists: true, Segments: []tree.PathSegment{{Slug: "docs"}, {Slug: "a-2", ID: &otherID}}}
+	slug := "a-3"
+	edit := PlanEdit{Items: []PlanItemEdit{{SourcePath: "b.md", Slug: &slug}}}
+	edit.Items = append(edit.Items, PlanItemEdit{SourcePath: "a.md", Strategy: strategyPtr(ConflictStrategyKeepBoth)})
+
+	if err := p.EditPlan(plan, edit); err != nil {
+		t.Fatalf("EditPlan err: %v", err)
+	}
+	a := plan.Items[0]
+	if a.Action != PlanActionCreate || a.TargetPath != "docs/a-4" || a.ConflictPath != "docs/a" || a.DesiredSlug != "a-4" || a.Exists {
+		t.Fatalf("keep both item = %#v", a)
+	}
+	if plan.Items[1].TargetPath != "docs/a-3" || plan.Items[1].Action != PlanActionCreate {
+		t.Fatalf("renamed item = %#v", plan.Items[1])
+	}
+
+	// Switching back to skip returns the item to the existing page.
+	action := PlanActionSkip
+	if err := p.EditPlan(plan, PlanEdit{Items: []PlanItemEdit{{SourcePath: "a.md", Action: &action}}}); err != nil {
+		t.Fatalf("EditPlan err: %v", err)
+	}
+	if a := plan.Items[0]; a.TargetPath != "docs/a" || a.ConflictPath != "" || !a.Exists || a.Action != PlanActionSkip {
+		t.Fatalf("skipped item = %#v", a)
+	}
+}
+
+func TestPlanner_EditPlan_MovesItemsAndRejectsDuplicateTargets(t *testing.T) {
+	_, p, plan := newEditablePlan(t)
+
+	target, title := "/docs/a/", "Renamed B"
+	edit := PlanEdit{Items: []PlanItemEdit{{SourcePath: "b.md", TargetPath: &target, Title: &title}}}
+	if err := p.EditPlan(plan, edit); err != nil {
+		t.Fatalf("EditPlan err: %v", err)
+	}
+	b := plan.Items[1]
+	if b.Action != PlanActionSkip || !b.Exists || b.Title != "Renamed B" {
+		t.Fatalf("moved onto existing page with skip strategy, got %#v", b)
+	}
+
+	target = "docs/guides/c"
+	edit = PlanEdit{Items: []PlanItemEdit{{SourcePath: "b.md", TargetPath: &target}}}
+	if err := p.EditPlan(plan, edit); !errors.Is(err, ErrInvalidPlanEdit) {
+		t.Fatalf("expected ErrInvalidPlanEdit for duplicate target, got %v", err)
+	}
+
+	action := PlanActionUpdate
+	edit = PlanEdit{Items: []PlanItemEdit{{SourcePath: "guides/c.md", Action: &action}}}
+	if err := p.EditPlan(plan, edit); !errors.Is(err, ErrInvalidPlanEdit) {
+		t.Fatalf("expected ErrInvalidPlanEdit for updating a missing page, got %v", err)
+	}
+}
+
+func TestPlanner_EditPlan_ExcludesAndIncludesSubtrees(t *testing.T) {
+	_, p, plan := newEditablePlan(t)
+
+	if err := p.EditPlan(plan, PlanEdit{Exclude: []string{"/guides/"}}); err != nil {
+		t.Fatalf("EditPlan err: %v", err)
+	}
+	if len(plan.ExcludedPaths) != 1 || plan.ExcludedPaths[0] != "guides" {
+		t.Fatalf("ExcludedPaths = %#v", plan.ExcludedPaths)
+	}
+	for _, item := range plan.Items {
+		if item.Excluded != (item.SourcePath == "guides/c.md") {
+			t.Fatalf("item %s Excluded = %v", item.SourcePath, item.Excluded)
+		}
+	}
+
+	if err := p.EditPlan(plan, PlanEdit{Include: []string{"guides"}}); err != nil {
+		t.Fatalf("EditPlan err: %v", err)
+	}
+	if len(plan.ExcludedPaths) != 0 || plan.Items[2].Excluded {
+		t.Fatalf("expected subtree to be included again, got %#v", plan)
+	}
+}
+
+func TestPlanner_EditPlan_RejectsStalePlansAndInvalidEdits(t *testing.T) {
+	wiki, p, plan := newEditablePlan(t)
+
+	if err := p.EditPlan(plan, PlanEdit{TreeHash: "other"}); !errors.Is(err, ErrPlanStale) {
+		t.Fatalf("expected ErrPlanStale for edit hash, got %v", err)
+	}
+	if err := p.EditPlan(plan, PlanEdit{ConflictStrategy: strategyPtr("replace")}); !errors.Is(err, ErrInvalidPlanEdit) {
+		t.Fatalf("expected ErrInvalidPlanEdit for unknown strategy, got %v", err)
+	}
+	if err := p.EditPlan(plan, PlanEdit{Items: []PlanItemEdit{{SourcePath: "missing.md"}}}); !errors.Is(err, ErrInvalidPlanEdit) {
+		t.Fatalf("expected ErrInvalidPlanEdit for unknown item, got %v", err)
+	}
+
+	wiki.treeHash = "h2"
+	if err := p.EditPlan(plan, PlanEdit{TreeHash: "h1"}); !errors.Is(err, ErrPlanStale) {
+		t.Fatalf("expected ErrPlanStale after wiki change, got %v", err)
+	}
+}
@@ -84,6 +84,34 @@ func (ps *PlanStore) Get() (*StoredPlan, error) {
 	return cloneStoredPlan(ps.plan), nil
 }
 
+// Update applies fn to a copy of the stored plan and stores the copy when
+// fn succeeds.
+func (ps *PlanStore) Update(fn func(*StoredPlan) error) (*StoredPlan, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.stateErr != nil {
+		return nil, ps.stateErr
+	}
+	if ps.plan == nil {
+		return nil, ErrNoPlan
+	}
+	if ps.plan.Plan == nil {
+		return nil, ErrImportStateUnavailable
+	}
+
+	updated := cloneStoredPlan(ps.plan)
+	updated.Plan = clonePlanResult(ps.plan.Plan)
+	if err := fn(updated); err != nil {
+		return nil, err
+	}
+	ps.plan = updated
+	if err := ps.persistLocked(); err != nil {
+		ps.stateErr = fmt.Errorf(errWrapFmt, ErrImportStateUnavailable, err)
+		return nil, ps.stateErr
+	}
+	return cloneStoredPlan(ps.plan), nil
+}
+
 func (ps *PlanStore) Clear() (*StoredPlan, error) {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
@@ -313,6 +341,23 @@ func cloneStoredPlan(sp *StoredPlan) *StoredPlan {
 	return &clone
 }
 
+func clonePlanResult(plan *PlanResult) *PlanResult {
+	clone := *plan
+	clone.Errors = append([]string(nil), plan.Errors...)
+	clone.ExcludedPaths = append([]string(nil), plan.ExcludedPaths...)
+	clone.Items = make([]PlanItem, len(plan.Items))
+	for i, item := range plan.Items {
+		item.Conflicts = append([]string(nil), item.Conflicts...)
+		item.Notes = append([]string(nil), item.Notes...)
+		if item.ExistingID != nil {
+			id := *item.ExistingID
+			item.ExistingID = &id
+		}
+		clone.Items[i] = item
+	}
+	return &clone
+}
+
 func cloneExecutionResult(res *ExecutionResult) *ExecutionResult {
 	if res == nil {
 		return nil
@@ -21,11 +21,40 @@ const (
 	PlanActionCreate PlanAction = "create" // creates new node
 	PlanActionUpdate PlanAction = "update" // updates existing node
 	PlanActionSkip   PlanAction = "skip"   // skips existing node
+	PlanActionDelete PlanAction = "delete" // deletes the page of a removed source file
+	PlanActionTrash  PlanAction = "trash"  // moves the page of a removed source file to the import trash
+)
+
+// ImportChange describes how an item changed since the last import of the
+// same source. It is only set for imports with a source.
+type ImportChange string
+
+const (
+	ImportChangeNew       ImportChange = "new"
+	ImportChangeUnchanged ImportChange = "unchanged"
+	ImportChangeModified  ImportChange = "modified"
+	ImportChangeMoved     ImportChange = "moved"   // source file was renamed or moved
+	ImportChangeRemoved   ImportChange = "removed" // source file no longer exists
+)
+
+// mapped reports whether the item belongs to a page of an earlier import.
+func (c ImportChange) mapped() bool {
+	return c != "" && c != ImportChangeNew
+}
+
+// RemovedPages decides what happens to pages whose source file was removed.
+type RemovedPages string
+
+const (
+	RemovedPagesKeep   RemovedPages = "keep"
+	RemovedPagesDelete RemovedPages = "delete"
+	RemovedPagesTrash  RemovedPages = "trash"
 )
 
 // ImportMDFile represents a markdown file to be imported
 type ImportMDFile struct {
-	SourcePath string // relative path to the markdown file in the zip directory
+	SourcePath string   // relative path to the markdown file in the zip directory
+	Notes      []string // notes from converting the source (e.g. unsupported Confluence macros)
 }
 
 // PlanItem represents a single item in the import plan
@@ -41,12 +70,28 @@ type PlanItem struct {
 	Action    PlanAction `json:"action"`
 	Conflicts []string   `json:"conflicts"`
 	Notes     []string   `json:"notes"`
+
+	Strategy     ConflictStrategy `json:"strategy,omitempty"`      // overrides the conflict strategy of the plan
+	Excluded     bool             `json:"excluded,omitempty"`      // inside an excluded source path, not imported
+	ConflictPath string           `json:"conflict_path,omitempty"` // existing page the item was moved away from (keep both, trash)
+
+	Change      ImportChange `json:"change,omitempty"`       // change since the last import of the source
+	ContentHash string       `json:"content_hash,omitempty"` // recorded in the source mapping after import
 }
 
 // PlanOptions represents options for creating an import plan
 type PlanOptions struct {
 	SourceBasePath string // base path in the import source
 	TargetBasePath string // base path in the wiki where to import
+	ImportHistory  bool   // import the page history of the source as revisions
+	// MapHistoryAuthors links the authors of the imported history to the
+	// users of the same name. Anyone can upload a history naming any user,
+	// so only admins may set it.
+	MapHistoryAuthors bool
+	// Source names the origin of the package; imports with the same source
+	// update the pages of earlier imports.
+	Source       string
+	RemovedPages RemovedPages // what happens to pages whose source file was removed
 }
 
 // PlanResult represents the result of the import plan
@@ -55,6 +100,9 @@ type PlanResult struct {
 	TreeHash string     `json:"tree_hash"` // hash of the state of the wiki tree before import
 	Items    []PlanItem `json:"items"`
 	Errors   []string   `json:"errors"`
+
+	ConflictStrategy ConflictStrategy `json:"conflict_strategy"`        // applies to items without a strategy of their own
+	ExcludedPaths    []string         `json:"excluded_paths,omitempty"` // source paths that are not imported
 }
 
 // Planner is responsible for creating an import plan
@@ -64,6 +112,7 @@ type Planner struct {
 	slugger     *tree.SlugService
 	storageDir  string
 	ignoreCache *ignore.Cache
+	mappings    *SourceMappingStore
 }
 
 // NewPlanner creates a new Planner
@@ -81,6 +130,11 @@ func (p *Planner) SetIgnoreCache(ignoreCache *ignore.Cache) {
 	p.ignoreCache = ignoreCache
 }
 
+// SetSourceMappings sets the store of source mappings for incremental imports.
+func (p *Planner) SetSourceMappings(mappings *SourceMappingStore) {
+	p.mappings = mappings
+}
+
 // getIgnoreForDir returns the compiled ignore rules for the given directory.
 func (p *Planner) getIgnoreForDir(dir string) *ignore.IgnoreFile {
 	if p.ignoreCache == nil {
@@ -101,6 +155,8 @@ func (p *Planner) CreatePlan(entries []ImportMDFile, options PlanOptions) (*Plan
 		Items:    []PlanItem{},
 		Errors:   []string{},
 		TreeHash: p.wiki.TreeHash(),
+
+		ConflictStrategy: ConflictStrategySkip,
 	}
 	for _, entry := range entries {
 		resEntry, err := p.analyzeEntry(entry, options)
@@ -123,6 +179,11 @@ func (p *Planner) CreatePlan(entries []ImportMDFile, options PlanOptions) (*Plan
 
 		result.Items = append(result.Items, *resEntry)
 	}
+	if options.Source != "" && p.mappings != nil {
+		if err := p.applySourceMapping(result, options); err != nil {
+			return nil, err
+		}
+	}
 	return result, nil
 }
 
@@ -183,7 +244,7 @@ func (p *Planner) analyzeEntry(mdFile ImportMDFile, options PlanOptions) (*PlanI
 		return nil, err
 	}
 
-	var notes []string
+	notes := append([]string(nil), mdFile.Notes...)
 	md, err := markdown.LoadMarkdownFile(sourcePath)
 	if err != nil {
 		notes = append(notes, fmt.Sprintf("Failed to load markdown file for title extraction: %v", err))
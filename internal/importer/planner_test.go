@@ -19,6 +19,7 @@ type fakeWiki struct {
 	// planner part
 	lookups   map[string]*tree.PathLookup
 	lookupErr error
+	pages     map[string]*tree.Page
 
 	// executor part
 	ensureCalls        int
@@ -43,6 +44,13 @@ func (f *fakeWiki) LookupPagePath(p string) (*tree.PathLookup, error) {
 	return &tree.PathLookup{Path: p, Exists: false, Segments: []tree.PathSegment{}}, nil
 }
 
+func (f *fakeWiki) GetPage(id string) (*tree.Page, error) {
+	if page, ok := f.pages[id]; ok {
+		return page, nil
+	}
+	return nil, tree.ErrPageNotFound
+}
+
 func (f *fakeWiki) EnsurePath(userID string, targetPath string, title string, kind *tree.NodeKind) (*tree.Page, error) {
 	f.ensureCalls++
 	if f.ensureFn != nil {
@@ -87,10 +95,22 @@ func (f *fakeWiki) UpdatePage(userID string, id, title, slug string, content *st
 	}}, nil
 }
 
+func (f *fakeWiki) DeletePage(userID, id string) error {
+	return nil
+}
+
+func (f *fakeWiki) MovePage(userID, id, parentID string) error {
+	return nil
+}
+
 func (f *fakeWiki) UploadAsset(userID, pageID string, file multipart.File, filename string, maxBytes int64) (string, error) {
 	return "/assets/" + pageID + "/" + filename, nil
 }
 
+func (f *fakeWiki) ImportRevisions(pageID string, revisions []ImportedRevision, mapAuthors bool) error {
+	return nil
+}
+
 func newPlannerWithFake(w *fakeWiki) *Planner {
 	return NewPlanner(w, tree.NewSlugService(), "")
 }
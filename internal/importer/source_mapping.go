@@ -0,0 +1,104 @@
+package importer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SourceMapping remembers which wiki page each file of an import source
+// became, so that the next import of the same source updates those pages
+// instead of planning from scratch.
+type SourceMapping struct {
+	Source    string               `json:"source"`
+	UpdatedAt time.Time            `json:"updated_at"`
+	Entries   []SourceMappingEntry `json:"entries"`
+}
+
+type SourceMappingEntry struct {
+	SourcePath  string `json:"source_path"`
+	ContentHash string `json:"content_hash"` // sha256 of the imported file
+	PageID      string `json:"page_id"`
+}
+
+// SourceMappingStore persists one SourceMapping per import source as a JSON
+// file in dir.
+type SourceMappingStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+func NewSourceMappingStore(dir string) *SourceMappingStore {
+	return &SourceMappingStore{dir: dir}
+}
+
+// Get returns the mapping of source, or an empty mapping if the source was
+// never imported.
+func (s *SourceMappingStore) Get(source string) (*SourceMapping, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw, err := os.ReadFile(s.fileFor(source))
+	if errors.Is(err, os.ErrNotExist) {
+		return &SourceMapping{Source: source, Entries: []SourceMappingEntry{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var mapping SourceMapping
+	if err := json.Unmarshal(raw, &mapping); err != nil {
+		return nil, err
+	}
+	return &mapping, nil
+}
+
+// Save replaces the stored mapping of mapping.Source.
+func (s *SourceMappingStore) Save(mapping *SourceMapping) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sort.Slice(mapping.Entries, func(i, j int) bool {
+		return mapping.Entries[i].SourcePath < mapping.Entries[j].SourcePath
+	})
+	raw, err := json.MarshalIndent(mapping, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	file := s.fileFor(mapping.Source)
+	tmpPath := file + ".tmp"
+	if err := os.WriteFile(tmpPath, raw, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, file)
+}
+
+// fileFor names mapping files by a hash of the source, as source names are
+// free text.
+func (s *SourceMappingStore) fileFor(source string) string {
+	sum := sha256.Sum256([]byte(normalizeImportSource(source)))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+func normalizeImportSource(source string) string {
+	return strings.ToLower(strings.TrimSpace(source))
+}
+
+// fileContentHash returns the sha256 of a file as hex.
+func fileContentHash(file string) (string, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,896 @@
+package importer
+
+import (
+	"fmt"
+	"html"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Wikitext is rendered to HTML, which convertHTMLToMarkdown turns into
+// Markdown. Internal links point to "mw-page:<target>" and files to
+// "mw-file:<name>", so that the MediaWiki converter can map them into the
+// converted package.
+
+const (
+	wikiPageScheme = "mw-page:"
+	wikiFileScheme = "mw-file:"
+	// wikiMathElement carries the TeX of <math> to the HTML converter.
+	wikiMathElement = "mw-math"
+)
+
+// Sections of the text that must not be parsed as wikitext (nowiki, code,
+// math, references) are replaced by placeholders until the HTML is built.
+const (
+	wikiPlaceholderStart = "\ue000"
+	wikiPlaceholderEnd   = "\ue001"
+	// wikiReferencesMarker is the line the list of references is placed at.
+	wikiReferencesMarker = "\ue002"
+)
+
+var (
+	wikiCommentPattern    = regexp.MustCompile(`(?s)<!--.*?(-->|$)`)
+	wikiMagicWordPattern  = regexp.MustCompile(`__[A-Z]+__`)
+	wikiHeadingPattern    = regexp.MustCompile(`^(={1,6})\s*(.+?)\s*(={1,6})\s*$`)
+	wikiRulePattern       = regexp.MustCompile(`^-{4,}\s*$`)
+	wikiExternalPattern   = regexp.MustCompile(`^\[((?:https?:|ftp:|mailto:|//)[^\s\]]+)(?:\s+([^\]]*))?\]`)
+	wikiBareURLPattern    = regexp.MustCompile(`^(?:https?|ftp)://[^\s<>\[\]"|{}]+`)
+	wikiGalleryPattern    = regexp.MustCompile(`(?is)<gallery[^>]*>(.*?)</gallery\s*>`)
+	wikiRefPattern        = regexp.MustCompile(`(?is)<ref(\s[^>]*?)?(?:/>|>(.*?)</ref\s*>)`)
+	wikiRefNamePattern    = regexp.MustCompile(`(?i)name\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s/>]+))`)
+	wikiReferencesPattern = regexp.MustCompile(`(?is)<references(?:\s[^>]*)?(?:/>|>.*?</references\s*>)`)
+	wikiLangPattern       = regexp.MustCompile(`(?i)lang\s*=\s*["']?([\w+#-]+)`)
+	wikiImageSizePattern  = regexp.MustCompile(`^\d*(x\d+)?px$`)
+	wikiImageParamPattern = regexp.MustCompile(`^(?i)(link|page|class|lang|thumb|thumbnail)$`)
+	wikiCellAttrsPattern  = regexp.MustCompile(`^\s*([\w-]+\s*=\s*("[^"]*"|'[^']*'|[^\s|]+)\s*)*$`)
+	// wikiHTMLTagPattern matches the HTML tags wikitext may contain; any
+	// other "<" is text.
+	wikiHTMLTagPattern = regexp.MustCompile(`(?i)^</?(b|i|u|s|del|ins|strike|strong|em|small|big|sub|sup|span|div|p|br|hr|blockquote|center|font|tt|code|kbd|samp|var|cite|abbr|dfn|mark|q|dl|dt|dd|ol|ul|li|table|caption|thead|tbody|tr|td|th|h[1-6])\b[^<>]*>`)
+)
+
+// wikiRawTags are parsed first; their content is not wikitext.
+var wikiRawTags = func() []wikiRawTag {
+	var tags []wikiRawTag
+	for _, name := range []string{"nowiki", "pre", "syntaxhighlight", "source", "math", "code"} {
+		tags = append(tags, wikiRawTag{
+			name:    name,
+			pattern: regexp.MustCompile(`(?is)<` + name + `(\s[^>]*?)?(?:/>|>(.*?)</` + name + `\s*>)`),
+		})
+	}
+	return tags
+}()
+
+type wikiRawTag struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+var wikiImageOptions = map[string]bool{
+	"thumb": true, "thumbnail": true, "frame": true, "framed": true, "frameless": true, "border": true,
+	"left": true, "right": true, "center": true, "centre": true, "none": true, "upright": true,
+	"baseline": true, "middle": true, "sub": true, "super": true, "top": true, "text-top": true,
+	"bottom": true, "text-bottom": true,
+}
+
+var wikiImageExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true, ".bmp": true,
+}
+
+// wikitextResult is a page rendered to HTML together with what could not
+// be rendered.
+type wikitextResult struct {
+	HTML       string
+	Templates  []string // names of the removed templates, in order
+	Categories []string
+}
+
+type wikitextRenderer struct {
+	// filePrefixes and categoryPrefixes are the lower-case namespace names
+	// of files and categories, including aliases ("image", "media").
+	filePrefixes     map[string]bool
+	categoryPrefixes map[string]bool
+
+	raw        []string
+	refs       []string
+	templates  []string
+	categories []string
+}
+
+func newWikitextRenderer(filePrefixes, categoryPrefixes []string) *wikitextRenderer {
+	r := &wikitextRenderer{filePrefixes: map[string]bool{}, categoryPrefixes: map[string]bool{}}
+	for _, prefix := range filePrefixes {
+		r.filePrefixes[normalizeWikiNamespace(prefix)] = true
+	}
+	for _, prefix := range categoryPrefixes {
+		r.categoryPrefixes[normalizeWikiNamespace(prefix)] = true
+	}
+	return r
+}
+
+func normalizeWikiNamespace(name string) string {
+	return strings.ToLower(strings.TrimSpace(strings.ReplaceAll(name, "_", " ")))
+}
+
+// render renders the wikitext of a page to HTML.
+func (r *wikitextRenderer) render(text string) wikitextResult {
+	r.raw, r.refs, r.templates, r.categories = nil, nil, nil, nil
+
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.NewReplacer(wikiPlaceholderStart, "", wikiPlaceholderEnd, "", wikiReferencesMarker, "").Replace(text)
+	text = r.protectRawTags(text)
+	text = wikiCommentPattern.ReplaceAllString(text, "")
+	text = r.removeTemplates(text)
+	text = wikiGalleryPattern.ReplaceAllStringFunc(text, r.gallery)
+	text = r.extractRefs(text)
+	text = wikiMagicWordPattern.ReplaceAllString(text, "")
+	text = escapeWikitextHTML(text)
+
+	body := r.blocks(text)
+	if len(r.refs) > 0 && !strings.Contains(text, wikiReferencesMarker) {
+		body += r.referenceList()
+	}
+	return wikitextResult{HTML: r.restore(body), Templates: r.templates, Categories: r.categories}
+}
+
+// protect stores rendered HTML and returns its placeholder.
+func (r *wikitextRenderer) protect(rendered string) string {
+	r.raw = append(r.raw, rendered)
+	return wikiPlaceholderStart + strconv.Itoa(len(r.raw)-1) + wikiPlaceholderEnd
+}
+
+func (r *wikitextRenderer) restore(text string) string {
+	for strings.Contains(text, wikiPlaceholderStart) {
+		before, rest, _ := strings.Cut(text, wikiPlaceholderStart)
+		index, after, ok := strings.Cut(rest, wikiPlaceholderEnd)
+		i, err := strconv.Atoi(index)
+		if !ok || err != nil || i >= len(r.raw) {
+			return before + rest
+		}
+		text = before + r.raw[i] + after
+	}
+	return text
+}
+
+func (r *wikitextRenderer) protectRawTags(text string) string {
+	for _, tag := range wikiRawTags {
+		text = tag.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			m := tag.pattern.FindStringSubmatch(match)
+			attrs, content := m[1], m[2]
+			switch tag.name {
+			case "nowiki":
+				if content == "" {
+					return ""
+				}
+				return r.protect(html.EscapeString(html.UnescapeString(content)))
+			case "pre":
+				return r.protect("<pre>" + html.EscapeString(strings.Trim(content, "\n")) + "</pre>")
+			case "syntaxhighlight", "source":
+				if strings.Contains(strings.ToLower(attrs), "inline") {
+					return r.protect("<code>" + html.EscapeString(content) + "</code>")
+				}
+				class := ""
+				if lang := wikiLangPattern.FindStringSubmatch(attrs); lang != nil {
+					class = ` class="language-` + html.EscapeString(strings.ToLower(lang[1])) + `"`
+				}
+				return r.protect("<pre><code" + class + ">" + html.EscapeString(strings.Trim(content, "\n")) + "</code></pre>")
+			case "math":
+				display := ""
+				if strings.Contains(strings.ToLower(attrs), "block") {
+					display = ` display="block"`
+				}
+				return r.protect("<" + wikiMathElement + display + ">" + html.EscapeString(strings.TrimSpace(content)) + "</" + wikiMathElement + ">")
+			default:
+				return r.protect("<code>" + html.EscapeString(html.UnescapeString(content)) + "</code>")
+			}
+		})
+	}
+	return text
+}
+
+// removeTemplates removes templates, template parameters and parser
+// functions, which cannot be expanded without the source wiki.
+func (r *wikitextRenderer) removeTemplates(text string) string {
+	text = strings.NewReplacer("{{!}}", "|", "{{=}}", "=").Replace(text)
+
+	var b strings.Builder
+	for {
+		start := strings.Index(text, "{{")
+		if start < 0 {
+			b.WriteString(text)
+			return b.String()
+		}
+		end := matchingWikiBraces(text, start)
+		if end < 0 {
+			b.WriteString(text)
+			return b.String()
+		}
+		b.WriteString(text[:start])
+		if !strings.HasPrefix(text[start:], "{{{") {
+			r.addTemplate(text[start+2 : end-2])
+		}
+		text = text[end:]
+	}
+}
+
+// matchingWikiBraces returns the end of the template or parameter that
+// starts at start, or -1 if it is not closed.
+func matchingWikiBraces(text string, start int) int {
+	var closers []string
+	for i := start; i < len(text); {
+		switch {
+		case strings.HasPrefix(text[i:], "{{{") && !strings.HasPrefix(text[i:], "{{{{"):
+			closers = append(closers, "}}}")
+			i += 3
+		case strings.HasPrefix(text[i:], "{{"):
+			closers = append(closers, "}}")
+			i += 2
+		case len(closers) > 0 && strings.HasPrefix(text[i:], closers[len(closers)-1]):
+			i += len(closers[len(closers)-1])
+			closers = closers[:len(closers)-1]
+			if len(closers) == 0 {
+				return i
+			}
+		case len(closers) > 0 && strings.HasPrefix(text[i:], "}}"):
+			// A parameter closed like a template.
+			closers = closers[:len(closers)-1]
+			i += 2
+			if len(closers) == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+func (r *wikitextRenderer) addTemplate(inner string) {
+	name, _, _ := strings.Cut(inner, "|")
+	if fn, _, ok := strings.Cut(name, ":"); ok && strings.HasPrefix(fn, "#") {
+		name = fn
+	}
+	name = strings.TrimSpace(strings.ReplaceAll(name, "_", " "))
+	if prefix, rest, ok := strings.Cut(name, ":"); ok && strings.EqualFold(strings.TrimSpace(prefix), "template") {
+		name = strings.TrimSpace(rest)
+	}
+	if name == "" {
+		return
+	}
+	for _, existing := range r.templates {
+		if existing == name {
+			return
+		}
+	}
+	r.templates = append(r.templates, name)
+}
+
+// gallery turns the images of a <gallery> into file links.
+func (r *wikitextRenderer) gallery(match string) string {
+	m := wikiGalleryPattern.FindStringSubmatch(match)
+	var out []string
+	for _, line := range strings.Split(m[1], "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if prefix, _, ok := strings.Cut(line, ":"); !ok || !r.filePrefixes[normalizeWikiNamespace(prefix)] {
+			line = "File:" + line
+		}
+		out = append(out, "[["+line+"]]")
+	}
+	return "\n" + strings.Join(out, "\n\n") + "\n"
+}
+
+// extractRefs replaces footnotes by their number; the notes are listed at
+// <references /> or at the end of the page.
+func (r *wikitextRenderer) extractRefs(text string) string {
+	text = wikiReferencesPattern.ReplaceAllString(text, "\n"+wikiReferencesMarker+"\n")
+	named := map[string]int{}
+	return wikiRefPattern.ReplaceAllStringFunc(text, func(match string) string {
+		m := wikiRefPattern.FindStringSubmatch(match)
+		name := ""
+		if n := wikiRefNamePattern.FindStringSubmatch(m[1]); n != nil {
+			name = n[1] + n[2] + n[3]
+		}
+		number, ok := named[name]
+		if !ok || name == "" {
+			if strings.TrimSpace(m[2]) == "" && name != "" {
+				// A reuse of a named note that is defined later.
+				r.refs = append(r.refs, "")
+			} else {
+				r.refs = append(r.refs, strings.TrimSpace(m[2]))
+			}
+			number = len(r.refs)
+			if name != "" {
+				named[name] = number
+			}
+		} else if r.refs[number-1] == "" {
+			r.refs[number-1] = strings.TrimSpace(m[2])
+		}
+		return r.protect("<sup>[" + strconv.Itoa(number) + "]</sup>")
+	})
+}
+
+func (r *wikitextRenderer) referenceList() string {
+	if len(r.refs) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("<ol>")
+	for _, ref := range r.refs {
+		b.WriteString("<li>" + r.inline(escapeWikitextHTML(ref)) + "</li>")
+	}
+	b.WriteString("</ol>")
+	return b.String()
+}
+
+// escapeWikitextHTML escapes every "<" that does not start an HTML tag
+// wikitext allows.
+func escapeWikitextHTML(text string) string {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(text, '<')
+		if i < 0 {
+			b.WriteString(text)
+			return b.String()
+		}
+		b.WriteString(text[:i])
+		if tag := wikiHTMLTagPattern.FindString(text[i:]); tag != "" {
+			b.WriteString(tag)
+			text = text[i+len(tag):]
+			continue
+		}
+		b.WriteString("&lt;")
+		text = text[i+1:]
+	}
+}
+
+// blocks renders headings, lists, tables, preformatted text and
+// paragraphs.
+func (r *wikitextRenderer) blocks(text string) string {
+	lines := strings.Split(text, "\n")
+	var b strings.Builder
+	var para []string
+	var lists []byte
+
+	flushPara := func() {
+		if len(para) > 0 {
+			b.WriteString("<p>" + strings.Join(para, "\n") + "</p>")
+			para = nil
+		}
+	}
+	flush := func() {
+		flushPara()
+		r.closeLists(&b, lists, 0)
+		lists = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == wikiReferencesMarker:
+			flush()
+			b.WriteString(r.referenceList())
+			r.refs = nil
+		case strings.HasPrefix(trimmed, "{|"):
+			flush()
+			end := wikiTableEnd(lines, i)
+			b.WriteString(r.table(lines[i : end+1]))
+			i = end
+		case wikiHeadingPattern.MatchString(trimmed):
+			flush()
+			m := wikiHeadingPattern.FindStringSubmatch(trimmed)
+			level := min(len(m[1]), len(m[3]))
+			content := strings.Repeat("=", len(m[1])-level) + m[2] + strings.Repeat("=", len(m[3])-level)
+			tag := "h" + strconv.Itoa(level)
+			b.WriteString("<" + tag + ">" + r.inline(content) + "</" + tag + ">")
+		case wikiRulePattern.MatchString(trimmed):
+			flush()
+			b.WriteString("<hr>")
+		case line != "" && strings.ContainsRune("*#:;", rune(line[0])):
+			flushPara()
+			lists = r.listItem(&b, lists, line)
+		case strings.HasPrefix(line, " ") && trimmed != "":
+			flush()
+			var pre []string
+			for ; i < len(lines) && strings.HasPrefix(lines[i], " ") && strings.TrimSpace(lines[i]) != ""; i++ {
+				pre = append(pre, lines[i][1:])
+			}
+			i--
+			b.WriteString("<pre>" + html.EscapeString(html.UnescapeString(strings.Join(pre, "\n"))) + "</pre>")
+		case trimmed == "":
+			flush()
+		default:
+			if len(lists) > 0 {
+				flush()
+			}
+			para = append(para, r.inline(line))
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// wikiTableEnd returns the line that closes the table starting at start.
+func wikiTableEnd(lines []string, start int) int {
+	depth := 0
+	for i := start; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		switch {
+		case strings.HasPrefix(trimmed, "{|"):
+			depth++
+		case strings.HasPrefix(trimmed, "|}"):
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(lines) - 1
+}
+
+func wikiListTags(marker byte) (list, item string) {
+	switch marker {
+	case '*':
+		return "ul", "li"
+	case '#':
+		return "ol", "li"
+	case ';':
+		return "dl", "dt"
+	default:
+		return "dl", "dd"
+	}
+}
+
+func (r *wikitextRenderer) closeLists(b *strings.Builder, lists []byte, keep int) {
+	for i := len(lists) - 1; i >= keep; i-- {
+		list, item := wikiListTags(lists[i])
+		b.WriteString("</" + item + "></" + list + ">")
+	}
+}
+
+// listItem renders a line of a list; the markers of the line ("*", "#",
+// ";" and ":") give the nesting. It returns the markers of the open lists.
+func (r *wikitextRenderer) listItem(b *strings.Builder, lists []byte, line string) []byte {
+	n := 0
+	for n < len(line) && strings.ContainsRune("*#:;", rune(line[n])) {
+		n++
+	}
+	markers := []byte(line[:n])
+	content := strings.TrimSpace(line[n:])
+
+	sameList := func(a, b byte) bool {
+		return a == b || (strings.ContainsRune(";:", rune(a)) && strings.ContainsRune(";:", rune(b)))
+	}
+	common := 0
+	for common < len(lists) && common < len(markers) && sameList(lists[common], markers[common]) {
+		common++
+	}
+	if common == len(markers) && common > 0 {
+		// A new item of an open list.
+		r.closeLists(b, lists, common)
+		_, prevItem := wikiListTags(lists[common-1])
+		_, item := wikiListTags(markers[common-1])
+		b.WriteString("</" + prevItem + "><" + item + ">")
+	} else {
+		r.closeLists(b, lists, common)
+		for _, marker := range markers[common:] {
+			list, item := wikiListTags(marker)
+			b.WriteString("<" + list + "><" + item + ">")
+		}
+	}
+
+	if markers[len(markers)-1] == ';' {
+		// "; term : definition" on one line.
+		if term, def, ok := cutWikiDefinition(content); ok {
+			b.WriteString(r.inline(term) + "</dt><dd>" + r.inline(def))
+			markers[len(markers)-1] = ':'
+			return markers
+		}
+	}
+	b.WriteString(r.inline(content))
+	return markers
+}
+
+// cutWikiDefinition splits "term : definition" at the first colon outside
+// of links.
+func cutWikiDefinition(content string) (string, string, bool) {
+	depth := 0
+	for i := 0; i < len(content); i++ {
+		switch {
+		case strings.HasPrefix(content[i:], "[["):
+			depth++
+			i++
+		case strings.HasPrefix(content[i:], "]]") && depth > 0:
+			depth--
+			i++
+		case content[i] == ':' && depth == 0 && !strings.HasPrefix(content[i:], "://"):
+			return strings.TrimSpace(content[:i]), strings.TrimSpace(content[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// table renders a table; its lines start with "{|" and end with "|}".
+func (r *wikitextRenderer) table(lines []string) string {
+	var b, caption strings.Builder
+	var cellTag string
+	var cell []string
+	inRow := false
+
+	flushCell := func() {
+		if cellTag == "" {
+			return
+		}
+		content := strings.Join(cell, "\n")
+		if len(cell) > 1 {
+			content = r.blocks(content)
+		} else {
+			content = r.inline(strings.TrimSpace(content))
+		}
+		b.WriteString("<" + cellTag + ">" + content + "</" + cellTag + ">")
+		cellTag, cell = "", nil
+	}
+	startRow := func() {
+		if !inRow {
+			b.WriteString("<tr>")
+			inRow = true
+		}
+	}
+
+	for i := 1; i < len(lines)-1; i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		switch {
+		case strings.HasPrefix(trimmed, "{|"):
+			// A nested table is part of the current cell.
+			end := wikiTableEnd(lines, i)
+			if cellTag != "" {
+				cell = append(cell, lines[i:end+1]...)
+			}
+			i = end
+		case strings.HasPrefix(trimmed, "|+"):
+			caption.WriteString(r.inline(wikiCellContent(trimmed[2:])))
+		case strings.HasPrefix(trimmed, "|-"):
+			flushCell()
+			if inRow {
+				b.WriteString("</tr>")
+				inRow = false
+			}
+		case strings.HasPrefix(trimmed, "!"), strings.HasPrefix(trimmed, "|"):
+			flushCell()
+			startRow()
+			tag, separator := "td", "||"
+			if trimmed[0] == '!' {
+				tag = "th"
+				trimmed = strings.ReplaceAll(trimmed, "!!", "||")
+			}
+			cells := splitWikiCells(trimmed[1:], separator)
+			for j, content := range cells {
+				cellTag, cell = tag, []string{wikiCellContent(content)}
+				if j < len(cells)-1 {
+					flushCell()
+				}
+			}
+		default:
+			if cellTag != "" {
+				cell = append(cell, lines[i])
+			}
+		}
+	}
+	flushCell()
+	if inRow {
+		b.WriteString("</tr>")
+	}
+
+	out := "<table>" + b.String() + "</table>"
+	if caption.Len() > 0 {
+		out = "<p>" + caption.String() + "</p>" + out
+	}
+	return out
+}
+
+// splitWikiCells splits a table line into cells outside of links.
+func splitWikiCells(line, separator string) []string {
+	var cells []string
+	depth, start := 0, 0
+	for i := 0; i < len(line); i++ {
+		switch {
+		case strings.HasPrefix(line[i:], "[["):
+			depth++
+			i++
+		case strings.HasPrefix(line[i:], "]]") && depth > 0:
+			depth--
+			i++
+		case depth == 0 && strings.HasPrefix(line[i:], separator):
+			cells = append(cells, line[start:i])
+			start = i + len(separator)
+			i += len(separator) - 1
+		}
+	}
+	return append(cells, line[start:])
+}
+
+// wikiCellContent strips the attributes of a cell ("style=... | content").
+func wikiCellContent(cell string) string {
+	depth := 0
+	for i := 0; i < len(cell); i++ {
+		switch {
+		case strings.HasPrefix(cell[i:], "[["):
+			depth++
+			i++
+		case strings.HasPrefix(cell[i:], "]]") && depth > 0:
+			depth--
+			i++
+		case cell[i] == '|' && depth == 0:
+			if wikiCellAttrsPattern.MatchString(cell[:i]) {
+				return cell[i+1:]
+			}
+			return cell
+		}
+	}
+	return cell
+}
+
+// inline renders bold and italic text and links of a line.
+func (r *wikitextRenderer) inline(text string) string {
+	var b strings.Builder
+	var quotes wikiQuotes
+	for i := 0; i < len(text); {
+		rest := text[i:]
+		switch {
+		case strings.HasPrefix(rest, "[["):
+			end := matchingWikiBrackets(text, i)
+			if end < 0 {
+				b.WriteString("[[")
+				i += 2
+				continue
+			}
+			inner := text[i+2 : end-2]
+			trail := end
+			for trail < len(text) {
+				c, size := utf8.DecodeRuneInString(text[trail:])
+				if !unicode.IsLetter(c) {
+					break
+				}
+				trail += size
+			}
+			b.WriteString(r.wikiLink(inner, text[end:trail]))
+			i = trail
+		case strings.HasPrefix(rest, "''"):
+			n := 0
+			for i+n < len(text) && text[i+n] == '\'' {
+				n++
+			}
+			b.WriteString(quotes.toggle(n))
+			i += n
+		case rest[0] == '[' && wikiExternalPattern.MatchString(rest):
+			m := wikiExternalPattern.FindStringSubmatch(rest)
+			label := strings.TrimSpace(m[2])
+			if label == "" {
+				label = html.EscapeString(m[1])
+			} else {
+				label = r.inline(label)
+			}
+			b.WriteString(`<a href="` + html.EscapeString(m[1]) + `">` + label + `</a>`)
+			i += len(m[0])
+		case (i == 0 || !isWikiWordByte(text[i-1])) && wikiBareURLPattern.MatchString(rest):
+			u := strings.TrimRight(wikiBareURLPattern.FindString(rest), ".,;:!?)'")
+			b.WriteString(`<a href="` + html.EscapeString(u) + `">` + html.EscapeString(u) + `</a>`)
+			i += len(u)
+		default:
+			b.WriteByte(text[i])
+			i++
+		}
+	}
+	b.WriteString(quotes.close())
+	return b.String()
+}
+
+func isWikiWordByte(c byte) bool {
+	return c == '/' || c == '"' || c == '=' || c >= 0x80 || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
+
+// matchingWikiBrackets returns the end of the link that starts at start,
+// or -1 if it is not closed. Links may contain links, e.g. in captions.
+func matchingWikiBrackets(text string, start int) int {
+	depth := 0
+	for i := start; i < len(text)-1; i++ {
+		switch {
+		case text[i] == '[' && text[i+1] == '[':
+			depth++
+			i++
+		case text[i] == ']' && text[i+1] == ']':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		case text[i] == '\n':
+			return -1
+		}
+	}
+	return -1
+}
+
+// wikiLink renders [[target|label]] links, files and categories.
+func (r *wikitextRenderer) wikiLink(inner, trail string) string {
+	parts := splitWikiCells(inner, "|")
+	target := strings.TrimSpace(parts[0])
+	leadingColon := strings.HasPrefix(target, ":")
+	target = strings.TrimSpace(strings.TrimPrefix(target, ":"))
+
+	prefix, name, hasPrefix := strings.Cut(target, ":")
+	namespace := normalizeWikiNamespace(prefix)
+	switch {
+	case hasPrefix && r.categoryPrefixes[namespace] && !leadingColon:
+		r.addCategory(name)
+		return ""
+	case hasPrefix && namespace == "media", hasPrefix && r.filePrefixes[namespace] && leadingColon:
+		label := strings.TrimSpace(name)
+		if len(parts) > 1 {
+			label = strings.Join(parts[1:], "|")
+		}
+		return `<a href="` + wikiFileScheme + html.EscapeString(strings.TrimSpace(name)) + `">` + r.inline(label) + trail + `</a>`
+	case hasPrefix && r.filePrefixes[namespace]:
+		return r.file(strings.TrimSpace(name), parts[1:]) + trail
+	}
+
+	label := html.EscapeString(target)
+	if len(parts) > 1 {
+		label = r.inline(strings.Join(parts[1:], "|"))
+		if strings.TrimSpace(label) == "" {
+			// The "pipe trick" hides the namespace and a parenthesised
+			// disambiguation.
+			label = target
+			if hasPrefix {
+				label = name
+			}
+			if i := strings.Index(label, " ("); i > 0 {
+				label = label[:i]
+			}
+			label = html.EscapeString(strings.TrimSpace(label))
+		}
+	}
+	return `<a href="` + wikiPageScheme + html.EscapeString(target) + `">` + label + trail + `</a>`
+}
+
+func (r *wikitextRenderer) addCategory(name string) {
+	name = strings.TrimSpace(strings.ReplaceAll(name, "_", " "))
+	if name == "" {
+		return
+	}
+	for _, existing := range r.categories {
+		if existing == name {
+			return
+		}
+	}
+	r.categories = append(r.categories, name)
+}
+
+// file renders an embedded file: images as images with their caption as
+// alternative text, other files as links.
+func (r *wikitextRenderer) file(name string, params []string) string {
+	var caption, alt string
+	for _, param := range params {
+		param = strings.TrimSpace(param)
+		key, value, hasValue := strings.Cut(param, "=")
+		switch {
+		case wikiImageOptions[strings.ToLower(param)], wikiImageSizePattern.MatchString(param):
+		case hasValue && strings.EqualFold(strings.TrimSpace(key), "alt"):
+			alt = strings.TrimSpace(value)
+		case hasValue && wikiImageOptions[strings.ToLower(strings.TrimSpace(key))]:
+		case hasValue && wikiImageParamPattern.MatchString(strings.TrimSpace(key)):
+		default:
+			caption = param
+		}
+	}
+
+	src := html.EscapeString(wikiFileScheme + name)
+	if !wikiImageExtensions[strings.ToLower(path.Ext(name))] {
+		label := html.EscapeString(name)
+		if caption != "" {
+			label = r.inline(caption)
+		}
+		return `<a href="` + src + `">` + label + `</a>`
+	}
+	if alt == "" {
+		alt = wikitextPlain(caption)
+	}
+	return fmt.Sprintf(`<img src="%s" alt="%s">`, src, html.EscapeString(alt))
+}
+
+// wikitextPlain returns the text of inline wikitext without markup.
+func wikitextPlain(text string) string {
+	text = strings.ReplaceAll(text, "'''", "")
+	text = strings.ReplaceAll(text, "''", "")
+	for {
+		start := strings.Index(text, "[[")
+		if start < 0 {
+			return strings.TrimSpace(text)
+		}
+		end := matchingWikiBrackets(text, start)
+		if end < 0 {
+			return strings.TrimSpace(text)
+		}
+		parts := splitWikiCells(text[start+2:end-2], "|")
+		text = text[:start] + strings.TrimPrefix(parts[len(parts)-1], ":") + text[end:]
+	}
+}
+
+// wikiQuotes tracks the italic and bold text opened on a line with two
+// and three apostrophes.
+type wikiQuotes struct {
+	open []string
+}
+
+func (q *wikiQuotes) toggle(n int) string {
+	prefix := ""
+	switch {
+	case n == 4:
+		prefix, n = "'", 3
+	case n > 5:
+		prefix, n = strings.Repeat("'", n-5), 5
+	}
+	switch n {
+	case 2:
+		return prefix + q.flip("i")
+	case 3:
+		return prefix + q.flip("b")
+	default:
+		bold, italic := q.isOpen("b"), q.isOpen("i")
+		switch {
+		case bold && italic:
+			return prefix + q.close()
+		case !bold && !italic:
+			return prefix + q.flip("i") + q.flip("b")
+		default:
+			return prefix + q.flip("b") + q.flip("i")
+		}
+	}
+}
+
+func (q *wikiQuotes) isOpen(tag string) bool {
+	for _, open := range q.open {
+		if open == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// flip opens tag or closes it together with the tags opened after it,
+// which are opened again.
+func (q *wikiQuotes) flip(tag string) string {
+	for i, open := range q.open {
+		if open != tag {
+			continue
+		}
+		var b strings.Builder
+		for j := len(q.open) - 1; j >= i; j-- {
+			b.WriteString("</" + q.open[j] + ">")
+		}
+		reopen := append([]string{}, q.open[i+1:]...)
+		q.open = q.open[:i]
+		for _, t := range reopen {
+			b.WriteString("<" + t + ">")
+			q.open = append(q.open, t)
+		}
+		return b.String()
+	}
+	q.open = append(q.open, tag)
+	return "<" + tag + ">"
+}
+
+func (q *wikiQuotes) close() string {
+	var b strings.Builder
+	for j := len(q.open) - 1; j >= 0; j-- {
+		b.WriteString("</" + q.open[j] + ">")
+	}
+	q.open = nil
+	return b.String()
+}
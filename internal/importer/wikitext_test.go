@@ -0,0 +1,105 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func wikitextToMarkdown(t *testing.T, text string) (string, wikitextResult) {
+	t.Helper()
+	result := newWikitextRenderer([]string{"File", "Image"}, []string{"Category"}).render(text)
+	doc, err := html.Parse(strings.NewReader(result.HTML))
+	if err != nil {
+		t.Fatalf("html.Parse err: %v", err)
+	}
+	md := convertHTMLToMarkdown(doc, htmlMarkdownOptions{
+		Link: func(href string, image bool) (string, bool) {
+			return strings.NewReplacer(wikiPageScheme, "page:", wikiFileScheme, "file:", " ", "_").Replace(href), true
+		},
+	})
+	return md, result
+}
+
+func TestRenderWikitext_TableDriven(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "headings and paragraphs",
+			input: "== Intro ==\nfirst line\nsame paragraph\n\nsecond\n=== Sub ===",
+			want:  "## Intro\n\nfirst line same paragraph\n\nsecond\n\n### Sub",
+		},
+		{
+			name:  "bold and italic",
+			input: "''italic'' '''bold''' '''''both''''' ''open",
+			want:  "*italic* **bold** ***both*** *open*",
+		},
+		{
+			name:  "nested lists",
+			input: "* a\n** b\n* c\n# one\n#* mixed\n# two",
+			want:  "- a\n  - b\n- c\n\n1. one\n   - mixed\n2. two",
+		},
+		{
+			name:  "table with header, attributes and inline cells",
+			input: "{| class=\"wikitable\"\n! A !! B\n|-\n| 1 || [[Page|x]]\n|-\n| style=\"x\" | 2\n| y\n|}",
+			want:  "| A | B |\n| --- | --- |\n| 1 | [x](page:Page) |\n| 2 | y |",
+		},
+		{
+			name:  "links with labels, trails and external links",
+			input: "[[Main Page]]s, [[Help:Editing|]] and [https://example.com site] https://example.org.",
+			want:  "[Main Pages](page:Main_Page), [Editing](page:Help:Editing) and [site](https://example.com) [https://example.org](https://example.org).",
+		},
+		{
+			name:  "files and categories",
+			input: "[[File:Pic_1.png|thumb|left|100px|A ''nice'' pic]] [[Image:Doc.pdf]] [[Category:Docs]]",
+			want:  "![A nice pic](file:Pic_1.png) [Doc.pdf](file:Doc.pdf)",
+		},
+		{
+			name:  "code, nowiki and preformatted text",
+			input: "<nowiki>''raw''</nowiki> <code>a < b</code>\n<syntaxhighlight lang=\"Go\">\nfunc main() {}\n</syntaxhighlight>\n pre ''text''",
+			want:  "''raw'' `a < b`\n\n```go\nfunc main() {}\n```\n\n```\npre ''text''\n```",
+		},
+		{
+			name:  "templates and magic words are removed",
+			input: "__NOTOC__\n{{Infobox|a={{nested}}|b=[[x]]}}Text {{{param|default}}} end",
+			want:  "Text end",
+		},
+		{
+			name:  "references",
+			input: "Fact.<ref name=\"a\">Source [[A]]</ref> Again.<ref name=\"a\" />\n\n== Notes ==\n<references />",
+			want:  "Fact.\\[1\\] Again.\\[1\\]\n\n## Notes\n\n1. Source [A](page:A)",
+		},
+		{
+			name:  "text that looks like html is escaped",
+			input: "a <b>bold</b> and if x<y then <unknown>",
+			want:  "a **bold** and if x\\<y then \\<unknown>",
+		},
+		{
+			name:  "comments and rules",
+			input: "one<!-- hidden -->\n----\ntwo",
+			want:  "one\n\n---\n\ntwo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := wikitextToMarkdown(t, tt.input); got != tt.want {
+				t.Fatalf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderWikitext_ReportsTemplatesAndCategories(t *testing.T) {
+	_, result := wikitextToMarkdown(t, "{{Infobox|x={{Logo}}}} {{#if:a|b}} {{Template:Stub}} {{Infobox}}\n[[Category:Docs|sort]] [[:Category:Linked]]")
+	if got := strings.Join(result.Templates, ","); got != "Infobox,#if,Stub" {
+		t.Fatalf("templates = %q", got)
+	}
+	if got := strings.Join(result.Categories, ","); got != "Docs" {
+		t.Fatalf("categories = %q", got)
+	}
+}
@@ -0,0 +1,279 @@
+package links
+
+import (
+	"encoding/xml"
+	"io"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GraphNodeMissing is the kind of the nodes broken links lead to; the other
+// nodes have the kind of their page in the tree.
+const GraphNodeMissing = "missing"
+
+// GraphNode is a page of a link graph.
+type GraphNode struct {
+	ID         string            `json:"id"`
+	Title      string            `json:"title"`
+	Path       string            `json:"path"`
+	Kind       string            `json:"kind"`
+	Tags       []string          `json:"tags,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+// GraphEdge is a link from one node of a link graph to another. A page that
+// links to another more than once still has one edge to it.
+type GraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Broken bool   `json:"broken"`
+}
+
+// Graph is a set of pages and the links between them.
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// BuildGraph connects the nodes by the given links. Links from or to a page
+// that is not one of the nodes are left out, and so are links of a page to
+// itself. With includeBroken, each broken link leads to a node of kind
+// missing whose ID and path are the target path of the link; otherwise
+// broken links are left out as well. Edges follow the order of the nodes.
+func BuildGraph(nodes []GraphNode, links []Outgoing, includeBroken bool) *Graph {
+	position := make(map[string]int, len(nodes))
+	for i, node := range nodes {
+		position[node.ID] = i
+	}
+
+	g := &Graph{Nodes: slices.Clone(nodes), Edges: []GraphEdge{}}
+	if g.Nodes == nil {
+		g.Nodes = []GraphNode{}
+	}
+	seen := map[GraphEdge]bool{}
+	missing := map[string]bool{}
+	for _, link := range links {
+		if _, ok := position[link.FromPageID]; !ok {
+			continue
+		}
+		edge := GraphEdge{Source: link.FromPageID, Target: link.ToPageID, Broken: link.Broken || link.ToPageID == ""}
+		if edge.Broken {
+			if !includeBroken {
+				continue
+			}
+			edge.Target = link.ToPath
+			missing[link.ToPath] = true
+		} else if _, ok := position[edge.Target]; !ok || edge.Target == edge.Source {
+			continue
+		}
+		if !seen[edge] {
+			seen[edge] = true
+			g.Edges = append(g.Edges, edge)
+		}
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(missing)) {
+		position[path] = len(g.Nodes)
+		g.Nodes = append(g.Nodes, GraphNode{ID: path, Title: path, Path: path, Kind: GraphNodeMissing})
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.Source != b.Source {
+			return position[a.Source] < position[b.Source]
+		}
+		return position[a.Target] < position[b.Target]
+	})
+	return g
+}
+
+// Neighbourhood returns start and the pages at most depth resolved links
+// away from it, following links in either direction, nearest first.
+func Neighbourhood(links []Outgoing, start string, depth int) []string {
+	adjacent := map[string][]string{}
+	for _, link := range links {
+		if link.Broken || link.ToPageID == "" || link.ToPageID == link.FromPageID {
+			continue
+		}
+		adjacent[link.FromPageID] = append(adjacent[link.FromPageID], link.ToPageID)
+		adjacent[link.ToPageID] = append(adjacent[link.ToPageID], link.FromPageID)
+	}
+
+	reached := []string{start}
+	seen := map[string]bool{start: true}
+	frontier := []string{start}
+	for hop := 0; hop < depth && len(frontier) > 0; hop++ {
+		var next []string
+		for _, id := range frontier {
+			for _, other := range adjacent[id] {
+				if !seen[other] {
+					seen[other] = true
+					next = append(next, other)
+				}
+			}
+		}
+		slices.Sort(next)
+		reached = append(reached, next...)
+		frontier = next
+	}
+	return reached
+}
+
+// ─── GraphML ─────────────────────────────────────────────────────────────────
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML writes the graph as GraphML, for tools like Gephi, yEd or
+// Cytoscape. Tags are joined by commas; each property becomes an attribute
+// named "property:<key>".
+func (g *Graph) WriteGraphML(w io.Writer) error {
+	doc := graphML{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "title", For: "node", Name: "title", Type: "string"},
+			{ID: "path", For: "node", Name: "path", Type: "string"},
+			{ID: "kind", For: "node", Name: "kind", Type: "string"},
+			{ID: "tags", For: "node", Name: "tags", Type: "string"},
+		},
+		Graph: graphMLGraph{ID: "wiki", EdgeDefault: "directed"},
+	}
+	propertyKeys := g.propertyKeys()
+	for i, key := range propertyKeys {
+		doc.Keys = append(doc.Keys, graphMLKey{ID: "p" + strconv.Itoa(i), For: "node", Name: "property:" + key, Type: "string"})
+	}
+	doc.Keys = append(doc.Keys, graphMLKey{ID: "broken", For: "edge", Name: "broken", Type: "boolean"})
+
+	for _, node := range g.Nodes {
+		n := graphMLNode{ID: node.ID, Data: []graphMLData{
+			{Key: "title", Value: node.Title},
+			{Key: "path", Value: node.Path},
+			{Key: "kind", Value: node.Kind},
+		}}
+		if len(node.Tags) > 0 {
+			n.Data = append(n.Data, graphMLData{Key: "tags", Value: strings.Join(node.Tags, ",")})
+		}
+		for i, key := range propertyKeys {
+			if value, ok := node.Properties[key]; ok {
+				n.Data = append(n.Data, graphMLData{Key: "p" + strconv.Itoa(i), Value: value})
+			}
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, n)
+	}
+	for _, edge := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: edge.Source,
+			Target: edge.Target,
+			Data:   []graphMLData{{Key: "broken", Value: strconv.FormatBool(edge.Broken)}},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ─── DOT ─────────────────────────────────────────────────────────────────────
+
+// WriteDOT writes the graph in the DOT language of Graphviz. Nodes are
+// labelled with their title; missing pages and broken links are dashed.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph wiki {\n")
+	propertyKeys := g.propertyKeys()
+	for _, node := range g.Nodes {
+		attrs := [][2]string{{"label", node.Title}, {"path", node.Path}, {"kind", node.Kind}}
+		if len(node.Tags) > 0 {
+			attrs = append(attrs, [2]string{"tags", strings.Join(node.Tags, ",")})
+		}
+		for _, key := range propertyKeys {
+			if value, ok := node.Properties[key]; ok {
+				attrs = append(attrs, [2]string{"property:" + key, value})
+			}
+		}
+		if node.Kind == GraphNodeMissing {
+			attrs = append(attrs, [2]string{"style", "dashed"})
+		}
+		b.WriteString("  " + dotID(node.ID) + " " + dotAttributes(attrs) + ";\n")
+	}
+	for _, edge := range g.Edges {
+		b.WriteString("  " + dotID(edge.Source) + " -> " + dotID(edge.Target))
+		if edge.Broken {
+			b.WriteString(" " + dotAttributes([][2]string{{"broken", "true"}, {"style", "dashed"}}))
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotID quotes s as a DOT identifier.
+func dotID(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+func dotAttributes(attrs [][2]string) string {
+	parts := make([]string, len(attrs))
+	for i, attr := range attrs {
+		parts[i] = dotID(attr[0]) + "=" + dotID(attr[1])
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// propertyKeys returns the keys of the properties of all nodes, sorted.
+func (g *Graph) propertyKeys() []string {
+	keys := map[string]bool{}
+	for _, node := range g.Nodes {
+		for key := range node.Properties {
+			keys[key] = true
+		}
+	}
+	return slices.Sorted(maps.Keys(keys))
+}
@@ -0,0 +1,146 @@
+package links
+
+import (
+	"bytes"
+	"encoding/xml"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/perber/wiki/internal/test_utils"
+)
+
+func graphTestLinks() []Outgoing {
+	return []Outgoing{
+		{FromPageID: "a", ToPageID: "b", ToPath: "/b"},
+		{FromPageID: "a", ToPageID: "b", ToPath: "/b-old"},
+		{FromPageID: "a", ToPageID: "a", ToPath: "/a"},
+		{FromPageID: "a", ToPath: "/gone", Broken: true},
+		{FromPageID: "b", ToPageID: "c", ToPath: "/c"},
+		{FromPageID: "c", ToPageID: "outside", ToPath: "/outside"},
+		{FromPageID: "d", ToPageID: "c", ToPath: "/c"},
+	}
+}
+
+func graphTestNodes(ids ...string) []GraphNode {
+	nodes := make([]GraphNode, len(ids))
+	for i, id := range ids {
+		nodes[i] = GraphNode{ID: id, Title: strings.ToUpper(id), Path: "/" + id, Kind: "page"}
+	}
+	return nodes
+}
+
+func TestBuildGraph_KeepsLinksBetweenNodes(t *testing.T) {
+	g := BuildGraph(graphTestNodes("a", "b", "c"), graphTestLinks(), false)
+
+	want := []GraphEdge{{Source: "a", Target: "b"}, {Source: "b", Target: "c"}}
+	if !slices.Equal(g.Edges, want) {
+		t.Fatalf("edges = %+v, want %+v", g.Edges, want)
+	}
+	if len(g.Nodes) != 3 {
+		t.Fatalf("expected no missing nodes without broken links, got %+v", g.Nodes)
+	}
+
+	withBroken := BuildGraph(graphTestNodes("a", "b", "c"), graphTestLinks(), true)
+	if last := withBroken.Nodes[len(withBroken.Nodes)-1]; last.ID != "/gone" || last.Kind != GraphNodeMissing {
+		t.Fatalf("expected a missing node for the broken link, got %+v", withBroken.Nodes)
+	}
+	if !slices.Contains(withBroken.Edges, GraphEdge{Source: "a", Target: "/gone", Broken: true}) {
+		t.Fatalf("expected the broken edge, got %+v", withBroken.Edges)
+	}
+}
+
+func TestNeighbourhood_FollowsLinksBothWays(t *testing.T) {
+	links := graphTestLinks()
+	if got := Neighbourhood(links, "c", 1); !slices.Equal(got, []string{"c", "b", "d", "outside"}) {
+		t.Fatalf("one hop = %v", got)
+	}
+	if got := Neighbourhood(links, "c", 2); !slices.Equal(got, []string{"c", "b", "d", "outside", "a"}) {
+		t.Fatalf("two hops = %v", got)
+	}
+	if got := Neighbourhood(links, "lonely", 3); !slices.Equal(got, []string{"lonely"}) {
+		t.Fatalf("a page without links = %v", got)
+	}
+}
+
+func TestGraph_WriteGraphML(t *testing.T) {
+	nodes := graphTestNodes("a", "b")
+	nodes[0].Tags = []string{"ops", "db"}
+	nodes[0].Properties = map[string]string{"team": "core & ops"}
+	g := BuildGraph(nodes, graphTestLinks(), true)
+
+	var buf bytes.Buffer
+	if err := g.WriteGraphML(&buf); err != nil {
+		t.Fatalf("WriteGraphML: %v", err)
+	}
+	var doc graphML
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("not valid XML: %v\n%s", err, buf.String())
+	}
+	if len(doc.Graph.Nodes) != 3 || len(doc.Graph.Edges) != 2 {
+		t.Fatalf("unexpected graph: %+v", doc.Graph)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`<key id="p0" for="node" attr.name="property:team" attr.type="string"></key>`,
+		`<data key="p0">core &amp; ops</data>`,
+		`<data key="tags">ops,db</data>`,
+		`<edge source="a" target="/gone">`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in:\n%s", want, out)
+		}
+	}
+}
+
+func TestGraph_WriteDOT(t *testing.T) {
+	nodes := graphTestNodes("a", "b")
+	nodes[1].Title = `Say "hi"`
+	g := BuildGraph(nodes, graphTestLinks(), true)
+
+	var buf bytes.Buffer
+	if err := g.WriteDOT(&buf); err != nil {
+		t.Fatalf("WriteDOT: %v", err)
+	}
+	want := `digraph wiki {
+  "a" ["label"="A", "path"="/a", "kind"="page"];
+  "b" ["label"="Say \"hi\"", "path"="/b", "kind"="page"];
+  "/gone" ["label"="/gone", "path"="/gone", "kind"="missing", "style"="dashed"];
+  "a" -> "b";
+  "a" -> "/gone" ["broken"="true", "style"="dashed"];
+}
+`
+	if got := buf.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLinksStore_GetAllLinks(t *testing.T) {
+	store, err := NewLinksStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLinksStore err: %v", err)
+	}
+	defer test_utils.WrapCloseWithErrorCheck(store.Close, t)
+
+	if err := store.AddLinks("b", "B", []TargetLink{{TargetPageID: "c", TargetPagePath: "/c"}}); err != nil {
+		t.Fatalf("AddLinks err: %v", err)
+	}
+	if err := store.AddLinks("a", "A", []TargetLink{{TargetPageID: "b", TargetPagePath: "/b"}, {TargetPagePath: "/gone", Broken: true}}); err != nil {
+		t.Fatalf("AddLinks err: %v", err)
+	}
+
+	links, err := store.GetAllLinks()
+	if err != nil {
+		t.Fatalf("GetAllLinks err: %v", err)
+	}
+	var got []string
+	for _, l := range links {
+		got = append(got, l.FromPageID+">"+l.ToPath)
+	}
+	if !slices.Equal(got, []string{"a>/b", "a>/gone", "b>/c"}) {
+		t.Fatalf("GetAllLinks = %v", got)
+	}
+	if !links[1].Broken || links[1].ToPageID != "" {
+		t.Errorf("expected the broken link to stay broken, got %+v", links[1])
+	}
+}
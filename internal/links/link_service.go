@@ -91,6 +91,16 @@ func (b *LinkService) GetOutgoingLinksForPage(pageID string) (*OutgoingResult, e
 	return toOutgoingLinkResult(b.treeService, outgoingLinks), err
 }
 
+// GetLinkNeighbourhood returns the pages related to pageID through links.
+func (b *LinkService) GetLinkNeighbourhood(pageID string) (*LinkNeighbourhood, error) {
+	return b.store.GetLinkNeighbourhood(pageID)
+}
+
+// GetAllLinks returns every link of the wiki.
+func (b *LinkService) GetAllLinks() ([]Outgoing, error) {
+	return b.store.GetAllLinks()
+}
+
 func (b *LinkService) GetRefactorMatchesForPrefix(oldPrefix string) ([]RefactorLinkMatch, error) {
 	return b.store.GetRefactorMatchesForPrefix(oldPrefix)
 }
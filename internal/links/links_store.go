@@ -444,6 +444,41 @@ func (s *LinksStore) appendOutgoingLinksForPageBatch(outgoingByPageID map[string
 	return rows.Err()
 }
 
+// GetAllLinks returns every link of the wiki, ordered by source page and
+// target path.
+func (s *LinksStore) GetAllLinks() ([]Outgoing, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows, err := s.db.Query(`
+        SELECT from_page_id, to_page_id, to_path, from_title, broken
+        FROM links
+        ORDER BY from_page_id, to_path
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Default().Error(logCloseRowsFailed, "error", err)
+		}
+	}()
+
+	var outgoings []Outgoing
+	for rows.Next() {
+		var o Outgoing
+		var toPageID sql.NullString
+		var brokenInt int
+		if err := rows.Scan(&o.FromPageID, &toPageID, &o.ToPath, &o.FromTitle, &brokenInt); err != nil {
+			return nil, err
+		}
+		o.ToPageID = toPageID.String
+		o.Broken = brokenInt != 0
+		outgoings = append(outgoings, o)
+	}
+	return outgoings, rows.Err()
+}
+
 func (s *LinksStore) GetRefactorMatchesForPrefix(oldPrefix string) ([]RefactorLinkMatch, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -615,6 +650,83 @@ func (s *LinksStore) HealWikiLinksForTitle(title string, pageID string) error {
 	return err
 }
 
+// LinkNeighbourhood describes how other pages relate to a page through
+// resolved links.
+type LinkNeighbourhood struct {
+	// Linked holds the pages the page links to or that link to it.
+	Linked map[string]bool
+	// CoCited counts, per page, the pages that link to both it and the page.
+	CoCited map[string]int
+	// Coupled counts, per page, the pages that both it and the page link to.
+	Coupled map[string]int
+}
+
+// GetLinkNeighbourhood returns the pages linked with pageID and those that
+// are cited together with it (co-citation) or cite the same pages
+// (bibliographic coupling). Broken links are left out.
+func (s *LinksStore) GetLinkNeighbourhood(pageID string) (*LinkNeighbourhood, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := &LinkNeighbourhood{Linked: map[string]bool{}, CoCited: map[string]int{}, Coupled: map[string]int{}}
+	queries := []struct {
+		query  string
+		record func(id string, count int)
+	}{
+		{`
+			SELECT to_page_id, 1 FROM links
+			WHERE from_page_id = ? AND broken = 0 AND to_page_id IS NOT NULL
+			UNION
+			SELECT from_page_id, 1 FROM links
+			WHERE to_page_id = ? AND broken = 0
+		`, func(id string, _ int) { n.Linked[id] = true }},
+		{`
+			SELECT other.to_page_id, COUNT(DISTINCT other.from_page_id)
+			FROM links self
+			JOIN links other ON other.from_page_id = self.from_page_id
+			WHERE self.to_page_id = ? AND self.broken = 0 AND other.broken = 0
+				AND other.to_page_id IS NOT NULL AND other.to_page_id <> ?
+			GROUP BY other.to_page_id
+		`, func(id string, count int) { n.CoCited[id] = count }},
+		{`
+			SELECT other.from_page_id, COUNT(DISTINCT other.to_page_id)
+			FROM links self
+			JOIN links other ON other.to_page_id = self.to_page_id
+			WHERE self.from_page_id = ? AND self.broken = 0 AND other.broken = 0
+				AND other.from_page_id <> ?
+			GROUP BY other.from_page_id
+		`, func(id string, count int) { n.Coupled[id] = count }},
+	}
+	for _, q := range queries {
+		if err := s.scanPageCounts(q.query, pageID, q.record); err != nil {
+			return nil, err
+		}
+	}
+	delete(n.Linked, pageID)
+	return n, nil
+}
+
+func (s *LinksStore) scanPageCounts(query, pageID string, record func(id string, count int)) error {
+	rows, err := s.db.Query(query, pageID, pageID)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Default().Error(logCloseRowsFailed, "error", err)
+		}
+	}()
+	for rows.Next() {
+		var id string
+		var count int
+		if err := rows.Scan(&id, &count); err != nil {
+			return err
+		}
+		record(id, count)
+	}
+	return rows.Err()
+}
+
 func (s *LinksStore) Clear() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -35,10 +35,18 @@ func (s *PropertiesService) GetAllPropertyKeys(filter string, limit int) ([]Prop
 	return s.store.GetAllPropertyKeys(filter, limit)
 }
 
+func (s *PropertiesService) HasPropertyKey(key string) (bool, error) {
+	return s.store.HasPropertyKey(key)
+}
+
 func (s *PropertiesService) GetPageIDsByProperty(key, value string) ([]string, error) {
 	return s.store.GetPageIDsByProperty(key, value)
 }
 
+func (s *PropertiesService) GetSharedProperties(pageID string) ([]SharedProperty, error) {
+	return s.store.GetSharedProperties(pageID)
+}
+
 func (s *PropertiesService) GetPropertiesForPages(pageIDs []string) (map[string]map[string]PropertyEntry, error) {
 	return s.store.GetPropertiesForPages(pageIDs)
 }
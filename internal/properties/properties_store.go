@@ -2,6 +2,7 @@ package properties
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -157,6 +158,22 @@ func (s *PropertiesStore) GetAllPropertyKeys(filter string, limit int) ([]Proper
 	return result, rows.Err()
 }
 
+// HasPropertyKey reports whether any page has a property named key.
+func (s *PropertiesStore) HasPropertyKey(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var found int
+	err := s.db.QueryRow(`SELECT 1 FROM page_properties WHERE key = ? LIMIT 1`, key).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // GetPageIDsByProperty returns page IDs where key = key AND value = value (exact match).
 func (s *PropertiesStore) GetPageIDsByProperty(key, value string) ([]string, error) {
 	s.mu.Lock()
@@ -221,6 +238,47 @@ func (s *PropertiesStore) GetPropertiesForPages(pageIDs []string) (map[string]ma
 	return result, rows.Err()
 }
 
+// SharedProperty is a property value a page has in common with another
+// page.
+type SharedProperty struct {
+	PageID string
+	Key    string
+	Value  string
+	// Pages is the number of pages with the same value for the key.
+	Pages int
+}
+
+// GetSharedProperties returns the non-empty property values pageID shares
+// with other pages, one entry per other page and key.
+func (s *PropertiesStore) GetSharedProperties(pageID string) ([]SharedProperty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query(`
+		SELECT other.page_id, other.key, other.value,
+			(SELECT COUNT(*) FROM page_properties counted WHERE counted.key = other.key AND counted.value = other.value)
+		FROM page_properties self
+		JOIN page_properties other
+			ON other.key = self.key AND other.value = self.value AND other.page_id <> self.page_id
+		WHERE self.page_id = ? AND self.value <> ''
+		ORDER BY other.page_id, other.key
+	`, pageID)
+	if err != nil {
+		return nil, err
+	}
+	defer shared.LogClose(rows.Close, logCloseRowsFailed)
+
+	var result []SharedProperty
+	for rows.Next() {
+		var sp SharedProperty
+		if err := rows.Scan(&sp.PageID, &sp.Key, &sp.Value, &sp.Pages); err != nil {
+			return nil, err
+		}
+		result = append(result, sp)
+	}
+	return result, rows.Err()
+}
+
 func (s *PropertiesStore) Close() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
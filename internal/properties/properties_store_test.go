@@ -330,6 +330,23 @@ func TestPropertiesStore_GetAllPropertyKeys_FilterEscapesLikeWildcards(t *testin
 	}
 }
 
+// ─── HasPropertyKey ──────────────────────────────────────────────────────────
+
+func TestPropertiesStore_HasPropertyKey(t *testing.T) {
+	store := newTestStore(t)
+	_ = store.SetPropertiesForPage("page-1", props("status", "draft"))
+
+	for key, want := range map[string]bool{"status": true, "stat": false, "https": false} {
+		got, err := store.HasPropertyKey(key)
+		if err != nil {
+			t.Fatalf("HasPropertyKey(%q): %v", key, err)
+		}
+		if got != want {
+			t.Errorf("HasPropertyKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
 // ─── GetPageIDsByProperty ─────────────────────────────────────────────────────
 
 func TestPropertiesStore_GetPageIDsByProperty_ExactMatch(t *testing.T) {
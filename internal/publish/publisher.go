@@ -0,0 +1,444 @@
+// Package publish publishes a local directory of Markdown files into a
+// section of a wiki through its REST API. Pages are planned and their links
+// rewritten with the rules of the importer.
+package publish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/perber/wiki/internal/core/markdown"
+	"github.com/perber/wiki/internal/core/tree"
+	"github.com/perber/wiki/internal/importer"
+	"github.com/perber/wiki/pkg/client"
+)
+
+type Action string
+
+const (
+	ActionCreate    Action = "create"    // creates the page
+	ActionUpdate    Action = "update"    // replaces the content of the page
+	ActionMove      Action = "move"      // moves a page whose file was renamed or moved, then updates it
+	ActionUnchanged Action = "unchanged" // the page already matches its file
+)
+
+// Options of a publish run.
+type Options struct {
+	SourceDir  string // local directory with the Markdown files
+	TargetPath string // path of the section in the wiki
+}
+
+// PlanItem is what publishing does for one Markdown file.
+type PlanItem struct {
+	Action     Action
+	SourcePath string // relative to the source directory
+	TargetPath string
+	FromPath   string // current path of a moved page
+	Title      string
+	Slug       string
+	Kind       tree.NodeKind
+	PageID     string // existing page, empty for created pages
+}
+
+// Plan is the diff between a source directory and the wiki.
+type Plan struct {
+	SourceDir  string
+	TargetPath string
+	Items      []PlanItem
+	Orphans    []string // pages below the target without a source file, kept as they are
+	Errors     []string // files that cannot be published
+
+	importPlan *importer.PlanResult
+}
+
+// Counts returns the number of items per action.
+func (p *Plan) Counts() map[Action]int {
+	counts := map[Action]int{}
+	for _, item := range p.Items {
+		counts[item.Action]++
+	}
+	return counts
+}
+
+// Result of executing a plan.
+type Result struct {
+	Created   int
+	Updated   int
+	Moved     int
+	Unchanged int
+	Errors    []string
+}
+
+// Publisher plans and publishes directories through a client of the wiki.
+type Publisher struct {
+	client *client.Client
+}
+
+func NewPublisher(c *client.Client) *Publisher {
+	return &Publisher{client: c}
+}
+
+// remotePage is a page below the target of a publish run.
+type remotePage struct {
+	id    string
+	path  string
+	kind  tree.NodeKind
+	page  *client.Page // loaded on demand
+	taken bool         // claimed by a source file
+}
+
+// Plan compares the Markdown files of opts.SourceDir with the pages below
+// opts.TargetPath without changing the wiki. Files map to pages like in an
+// import. A file without a page takes over the page of a removed file that has
+// its leafwiki_id or the same content, which then moves.
+func (p *Publisher) Plan(ctx context.Context, opts Options) (*Plan, error) {
+	target := strings.Trim(strings.TrimSpace(opts.TargetPath), "/")
+	if target == "" {
+		return nil, errors.New("target path is required")
+	}
+	info, err := os.Stat(opts.SourceDir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", opts.SourceDir)
+	}
+
+	entries, err := importer.FindMarkdownEntries(opts.SourceDir)
+	if err != nil {
+		return nil, fmt.Errorf("find markdown files: %w", err)
+	}
+	wiki := newRemoteWiki(ctx, p.client, true)
+	importPlan, err := importer.NewPlanner(wiki, tree.NewSlugService(), "").CreatePlan(entries, importer.PlanOptions{
+		SourceBasePath: opts.SourceDir,
+		TargetBasePath: target,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	remote, err := p.remotePages(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[string]*remotePage{}
+	for _, page := range remote {
+		byID[page.id] = page
+	}
+
+	plan := &Plan{
+		SourceDir:  opts.SourceDir,
+		TargetPath: target,
+		Items:      []PlanItem{},
+		Orphans:    []string{},
+		Errors:     append([]string{}, importPlan.Errors...),
+		importPlan: importPlan,
+	}
+	rewriter := importer.NewLinkRewriter(importPlan, opts.SourceDir, 0)
+
+	// Existing pages are claimed first so that moves only take over pages
+	// no file maps to.
+	var created []int
+	for _, planned := range importPlan.Items {
+		item := PlanItem{
+			Action:     ActionCreate,
+			SourcePath: planned.SourcePath,
+			TargetPath: planned.TargetPath,
+			Title:      planned.Title,
+			Slug:       planned.DesiredSlug,
+			Kind:       planned.Kind,
+		}
+		if planned.Exists && planned.ExistingID != nil {
+			item.PageID = *planned.ExistingID
+			if page, ok := byID[item.PageID]; ok {
+				page.taken = true
+			}
+			item.Action, err = p.compare(wiki, rewriter, &item, byID[item.PageID])
+			if err != nil {
+				plan.Errors = append(plan.Errors, fmt.Sprintf("%s: %v", item.SourcePath, err))
+			}
+		} else {
+			created = append(created, len(plan.Items))
+		}
+		plan.Items = append(plan.Items, item)
+	}
+
+	for _, i := range created {
+		item := &plan.Items[i]
+		page, err := p.findMovedPage(wiki, rewriter, opts.SourceDir, item, remote)
+		if err != nil {
+			plan.Errors = append(plan.Errors, fmt.Sprintf("%s: %v", item.SourcePath, err))
+			continue
+		}
+		if page != nil {
+			page.taken = true
+			item.Action, item.PageID, item.FromPath = ActionMove, page.id, page.path
+		}
+	}
+
+	for _, page := range remote {
+		if !page.taken && !isAncestorOfAny(page.path, plan.Items) {
+			plan.Orphans = append(plan.Orphans, page.path)
+		}
+	}
+	return plan, nil
+}
+
+// Execute publishes a plan. It continues after failed files and returns an
+// error if any file failed.
+func (p *Publisher) Execute(ctx context.Context, plan *Plan) (*Result, error) {
+	wiki := newRemoteWiki(ctx, p.client, false)
+	rewriter := importer.NewLinkRewriter(plan.importPlan, plan.SourceDir, 0)
+	result := &Result{Errors: []string{}}
+
+	for _, item := range plan.Items {
+		if item.Action == ActionUnchanged {
+			result.Unchanged++
+			continue
+		}
+		if err := p.executeItem(wiki, rewriter, item); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", item.SourcePath, err))
+			continue
+		}
+		switch item.Action {
+		case ActionCreate:
+			result.Created++
+		case ActionUpdate:
+			result.Updated++
+		case ActionMove:
+			result.Moved++
+		}
+	}
+	if len(result.Errors) > 0 {
+		return result, fmt.Errorf("%d of %d files failed to publish", len(result.Errors), len(plan.Items))
+	}
+	return result, nil
+}
+
+func (p *Publisher) executeItem(wiki *remoteWiki, rewriter *importer.LinkRewriter, item PlanItem) error {
+	kind := item.Kind
+	var page *tree.Page
+	var err error
+	switch item.Action {
+	case ActionCreate:
+		page, err = wiki.EnsurePath("", item.TargetPath, item.Title, &kind)
+	case ActionUpdate:
+		page, err = wiki.GetPage(item.PageID)
+	case ActionMove:
+		page, err = p.movePage(wiki, item)
+	default:
+		return fmt.Errorf("unknown action %q", item.Action)
+	}
+	if err != nil {
+		return err
+	}
+
+	content, err := rewriter.PageContent("", item.SourcePath, page, wiki)
+	if err != nil {
+		return err
+	}
+	_, err = wiki.UpdatePage("", page.ID, item.Title, item.Slug, &content, &kind)
+	return err
+}
+
+// movePage moves the page of item to its target path, creating missing
+// parent sections. A page that is there already, e.g. because its section
+// moved, stays.
+func (p *Publisher) movePage(wiki *remoteWiki, item PlanItem) (*tree.Page, error) {
+	current, err := p.client.Page(wiki.ctx, item.PageID)
+	if err != nil {
+		return nil, err
+	}
+	if current.Path == item.TargetPath {
+		return toTreePage(current), nil
+	}
+
+	if current.Slug != item.Slug {
+		if _, err := wiki.UpdatePage("", current.ID, current.Title, item.Slug, nil, nil); err != nil {
+			return nil, err
+		}
+	}
+	parentPath := path.Dir(item.TargetPath)
+	if parentPath != path.Dir(current.Path) {
+		parentID := "root"
+		if parentPath != "." {
+			section := tree.NodeKindSection
+			parent, err := wiki.EnsurePath("", parentPath, path.Base(parentPath), &section)
+			if err != nil {
+				return nil, err
+			}
+			parentID = parent.ID
+		}
+		if err := wiki.MovePage("", current.ID, parentID); err != nil {
+			return nil, err
+		}
+	}
+	return wiki.GetPage(current.ID)
+}
+
+// compare returns whether the page of an existing item would change.
+func (p *Publisher) compare(wiki *remoteWiki, rewriter *importer.LinkRewriter, item *PlanItem, remote *remotePage) (Action, error) {
+	page, err := p.loadPage(wiki, remote, item.PageID)
+	if err != nil {
+		return ActionUpdate, err
+	}
+	same, err := p.matches(wiki, rewriter, item, page)
+	if err != nil || !same || page.Title != item.Title {
+		return ActionUpdate, err
+	}
+	return ActionUnchanged, nil
+}
+
+// findMovedPage returns the unclaimed page that the file of item was
+// published to before, matched by the leafwiki_id of the file or its content.
+func (p *Publisher) findMovedPage(wiki *remoteWiki, rewriter *importer.LinkRewriter, sourceDir string, item *PlanItem, remote []*remotePage) (*remotePage, error) {
+	mdFile, err := markdown.LoadMarkdownFile(filepath.Join(sourceDir, filepath.FromSlash(item.SourcePath)))
+	if err != nil {
+		return nil, err
+	}
+	if id := strings.TrimSpace(mdFile.GetFrontmatter().LeafWikiID); id != "" {
+		for _, page := range remote {
+			if page.id == id && !page.taken {
+				return page, nil
+			}
+		}
+	}
+
+	for _, page := range remote {
+		if page.taken || page.kind != item.Kind || isAncestorOfAny(page.path, []PlanItem{*item}) {
+			continue
+		}
+		loaded, err := p.loadPage(wiki, page, page.id)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(loaded.Content) == "" {
+			continue
+		}
+		same, err := p.matches(wiki, rewriter, item, loaded)
+		if err != nil {
+			return nil, err
+		}
+		if same {
+			return page, nil
+		}
+	}
+	return nil, nil
+}
+
+// matches reports whether publishing the file of item to page would leave its
+// body, tags, properties and assets unchanged.
+func (p *Publisher) matches(wiki *remoteWiki, rewriter *importer.LinkRewriter, item *PlanItem, page *client.Page) (bool, error) {
+	uploads := wiki.pendingUploads
+	content, err := rewriter.PageContent("", item.SourcePath, toTreePage(page), wiki)
+	if err != nil {
+		return false, err
+	}
+	body, tags, properties, err := splitPageContent(content)
+	if err != nil {
+		return false, err
+	}
+	return wiki.pendingUploads == uploads &&
+		strings.TrimSpace(body) == strings.TrimSpace(page.Content) &&
+		sameTags(tags, page.Tags) &&
+		maps.Equal(properties, page.Properties), nil
+}
+
+func (p *Publisher) loadPage(wiki *remoteWiki, remote *remotePage, id string) (*client.Page, error) {
+	if remote != nil && remote.page != nil {
+		return remote.page, nil
+	}
+	page, err := p.client.Page(wiki.ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if remote != nil {
+		remote.page = page
+	}
+	return page, nil
+}
+
+// remotePages returns the pages below target, sorted by path.
+func (p *Publisher) remotePages(ctx context.Context, target string) ([]*remotePage, error) {
+	root, err := p.client.Tree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	node := root
+	for _, slug := range strings.Split(target, "/") {
+		node = childBySlug(node, slug)
+		if node == nil {
+			return nil, nil
+		}
+	}
+
+	var pages []*remotePage
+	var walk func(n *client.Node, nodePath string)
+	walk = func(n *client.Node, nodePath string) {
+		for _, child := range n.Children {
+			childPath := nodePath + "/" + child.Slug
+			pages = append(pages, &remotePage{id: child.ID, path: childPath, kind: tree.NodeKind(child.Kind)})
+			walk(child, childPath)
+		}
+	}
+	walk(node, target)
+	sort.Slice(pages, func(i, j int) bool { return pages[i].path < pages[j].path })
+	return pages, nil
+}
+
+func childBySlug(node *client.Node, slug string) *client.Node {
+	for _, child := range node.Children {
+		if child.Slug == slug {
+			return child
+		}
+	}
+	return nil
+}
+
+func isAncestorOfAny(pagePath string, items []PlanItem) bool {
+	for _, item := range items {
+		if strings.HasPrefix(item.TargetPath, pagePath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func sameTags(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// WritePlan writes a plan as one line per change, followed by a summary.
+func WritePlan(w io.Writer, plan *Plan) {
+	for _, item := range plan.Items {
+		switch item.Action {
+		case ActionUnchanged:
+			continue
+		case ActionMove:
+			_, _ = fmt.Fprintf(w, "%-7s %s -> %s (%s)\n", item.Action, item.FromPath, item.TargetPath, item.SourcePath)
+		default:
+			_, _ = fmt.Fprintf(w, "%-7s %s (%s)\n", item.Action, item.TargetPath, item.SourcePath)
+		}
+	}
+	for _, orphan := range plan.Orphans {
+		_, _ = fmt.Fprintf(w, "%-7s %s (no source file)\n", "keep", orphan)
+	}
+	for _, msg := range plan.Errors {
+		_, _ = fmt.Fprintf(w, "%-7s %s\n", "error", msg)
+	}
+	counts := plan.Counts()
+	_, _ = fmt.Fprintf(w, "%d to create, %d to update, %d to move, %d unchanged\n",
+		counts[ActionCreate], counts[ActionUpdate], counts[ActionMove], counts[ActionUnchanged])
+}
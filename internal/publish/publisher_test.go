@@ -0,0 +1,182 @@
+package publish_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	coreassets "github.com/perber/wiki/internal/core/assets"
+	coreauth "github.com/perber/wiki/internal/core/auth"
+	httpinternal "github.com/perber/wiki/internal/http"
+	"github.com/perber/wiki/internal/publish"
+	"github.com/perber/wiki/internal/test_utils"
+	"github.com/perber/wiki/internal/wiki"
+	"github.com/perber/wiki/pkg/client"
+)
+
+func newPublishTestServer(t *testing.T) *client.Client {
+	t.Helper()
+	w, err := wiki.NewWiki(&wiki.WikiOptions{
+		StorageDir:             t.TempDir(),
+		AdminPassword:          "adminpassword",
+		JWTSecret:              "secretkey",
+		AccessTokenTimeout:     15 * time.Minute,
+		RefreshTokenTimeout:    7 * 24 * time.Hour,
+		EnableAPIKeyManagement: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create wiki instance: %v", err)
+	}
+	t.Cleanup(func() { test_utils.WrapCloseWithErrorCheck(w.Close, t) })
+	router := httpinternal.NewRouter(w.Registrars(), w.FrontendConfig(), httpinternal.RouterOptions{
+		AllowInsecure:           true,
+		AccessTokenTimeout:      15 * time.Minute,
+		RefreshTokenTimeout:     7 * 24 * time.Hour,
+		MaxAssetUploadSizeBytes: coreassets.DefaultMaxUploadSizeBytes,
+		APIKeyService:           w.APIKeyService(),
+		EnableAPIKeyManagement:  true,
+	})
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	owner, err := w.UserService().CreateUser("ci", "ci@example.com", "password123", coreauth.RoleEditor)
+	if err != nil {
+		t.Fatalf("CreateUser err: %v", err)
+	}
+	_, secret, err := w.APIKeyService().CreateAPIKey(coreauth.CreateAPIKeyParams{Name: "ci", UserID: owner.ID, Role: coreauth.RoleEditor})
+	if err != nil {
+		t.Fatalf("CreateAPIKey err: %v", err)
+	}
+	c, err := client.New(server.URL, client.Options{APIKey: secret})
+	if err != nil {
+		t.Fatalf("New client err: %v", err)
+	}
+	return c
+}
+
+func writeSourceFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	p := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
+
+func planAndExecute(t *testing.T, publisher *publish.Publisher, opts publish.Options) *publish.Plan {
+	t.Helper()
+	plan, err := publisher.Plan(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Plan err: %v", err)
+	}
+	if len(plan.Errors) > 0 {
+		t.Fatalf("unexpected plan errors: %v", plan.Errors)
+	}
+	if result, err := publisher.Execute(context.Background(), plan); err != nil {
+		t.Fatalf("Execute err: %v %v", err, result.Errors)
+	}
+	return plan
+}
+
+func TestPublisher_PublishesUpdatesAndMovesPages(t *testing.T) {
+	c := newPublishTestServer(t)
+	publisher := publish.NewPublisher(c)
+	ctx := context.Background()
+	src := t.TempDir()
+	writeSourceFile(t, src, "index.md", "# Service docs\n\nStart with the [guide](guide.md).\n")
+	writeSourceFile(t, src, "guide.md", "---\ntags: [CI]\nowner: platform\n---\n# Guide\n\n![diagram](img/flow.png)\n")
+	writeSourceFile(t, src, "img/flow.png", "png-bytes")
+	opts := publish.Options{SourceDir: src, TargetPath: "services/billing"}
+
+	plan := planAndExecute(t, publisher, opts)
+	if counts := plan.Counts(); counts[publish.ActionCreate] != 2 {
+		t.Fatalf("expected 2 creates on first publish, got %v", counts)
+	}
+
+	lookup, err := c.LookupPath(ctx, "services/billing/guide")
+	if err != nil || !lookup.Exists {
+		t.Fatalf("expected guide page to exist, lookup=%+v err=%v", lookup, err)
+	}
+	guideID := *lookup.Segments[len(lookup.Segments)-1].ID
+	guide, err := c.Page(ctx, guideID)
+	if err != nil {
+		t.Fatalf("Page err: %v", err)
+	}
+	if !strings.Contains(guide.Content, "/assets/"+guideID+"/flow.png") {
+		t.Fatalf("expected asset link to be rewritten, got %q", guide.Content)
+	}
+	if len(guide.Tags) != 1 || guide.Tags[0] != "ci" || guide.Properties["owner"] != "platform" {
+		t.Fatalf("expected frontmatter as tags and properties, got tags=%v properties=%v", guide.Tags, guide.Properties)
+	}
+	lookup, err = c.LookupPath(ctx, "services/billing")
+	if err != nil || !lookup.Exists {
+		t.Fatalf("expected section to exist, lookup=%+v err=%v", lookup, err)
+	}
+	section, err := c.Page(ctx, *lookup.Segments[len(lookup.Segments)-1].ID)
+	if err != nil {
+		t.Fatalf("Page err: %v", err)
+	}
+	if section.Kind != client.KindSection || !strings.Contains(section.Content, "(/services/billing/guide)") {
+		t.Fatalf("expected section with rewritten page link, got kind=%s content=%q", section.Kind, section.Content)
+	}
+
+	plan, err = publisher.Plan(ctx, opts)
+	if err != nil {
+		t.Fatalf("Plan err: %v", err)
+	}
+	if counts := plan.Counts(); counts[publish.ActionUnchanged] != 2 {
+		t.Fatalf("expected republishing unchanged files to be a no-op, got %+v", plan.Items)
+	}
+	assets, err := c.Assets(ctx, guideID)
+	if err != nil || len(assets) != 1 {
+		t.Fatalf("expected a single asset, got %v err=%v", assets, err)
+	}
+
+	if err := os.Rename(filepath.Join(src, "guide.md"), filepath.Join(src, "handbook.md")); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	writeSourceFile(t, src, "index.md", "# Service docs\n\nStart with the [handbook](handbook.md).\n")
+	plan = planAndExecute(t, publisher, opts)
+
+	var moved *publish.PlanItem
+	for i := range plan.Items {
+		if plan.Items[i].Action == publish.ActionMove {
+			moved = &plan.Items[i]
+		}
+	}
+	if moved == nil || moved.FromPath != "services/billing/guide" || moved.TargetPath != "services/billing/handbook" {
+		t.Fatalf("expected guide to move to handbook, got %+v", plan.Items)
+	}
+	lookup, err = c.LookupPath(ctx, "services/billing/handbook")
+	if err != nil || !lookup.Exists || *lookup.Segments[len(lookup.Segments)-1].ID != guideID {
+		t.Fatalf("expected handbook to keep the page of guide, lookup=%+v err=%v", lookup, err)
+	}
+}
+
+func TestPublisher_DryRunKeepsWikiUnchanged(t *testing.T) {
+	c := newPublishTestServer(t)
+	publisher := publish.NewPublisher(c)
+	ctx := context.Background()
+	src := t.TempDir()
+	writeSourceFile(t, src, "intro.md", "# Intro\n")
+
+	plan, err := publisher.Plan(ctx, publish.Options{SourceDir: src, TargetPath: "docs"})
+	if err != nil {
+		t.Fatalf("Plan err: %v", err)
+	}
+	var out strings.Builder
+	publish.WritePlan(&out, plan)
+	if !strings.Contains(out.String(), "create  docs/intro (intro.md)") {
+		t.Fatalf("unexpected plan output:\n%s", out.String())
+	}
+	lookup, err := c.LookupPath(ctx, "docs")
+	if err != nil || lookup.Exists {
+		t.Fatalf("expected planning to leave the wiki unchanged, lookup=%+v err=%v", lookup, err)
+	}
+}
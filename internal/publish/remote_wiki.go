@@ -0,0 +1,259 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"mime/multipart"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/perber/wiki/internal/core/markdown"
+	"github.com/perber/wiki/internal/core/tree"
+	"github.com/perber/wiki/internal/importer"
+	"github.com/perber/wiki/pkg/client"
+)
+
+var errDryRun = errors.New("dry run: the wiki is not changed")
+
+// remoteWiki is the importer's view of a wiki behind the REST API, so that
+// publishing plans pages and rewrites links exactly like an import does.
+// In a dry run it writes nothing; assets that would be uploaded are counted
+// in pendingUploads instead. The importer interface has no context, so the
+// context of the run is kept with the wiki.
+type remoteWiki struct {
+	ctx            context.Context
+	client         *client.Client
+	dryRun         bool
+	slugger        *tree.SlugService
+	assets         map[string][]string // public asset paths per page ID
+	pendingUploads int
+}
+
+var _ importer.ImporterWiki = (*remoteWiki)(nil)
+
+func newRemoteWiki(ctx context.Context, c *client.Client, dryRun bool) *remoteWiki {
+	return &remoteWiki{ctx: ctx, client: c, dryRun: dryRun, slugger: tree.NewSlugService(), assets: map[string][]string{}}
+}
+
+// TreeHash is not available over the API. Publishing does not need it, as
+// every write is checked against the version of its page instead.
+func (w *remoteWiki) TreeHash() string {
+	return ""
+}
+
+func (w *remoteWiki) LookupPagePath(routePath string) (*tree.PathLookup, error) {
+	lookup, err := w.client.LookupPath(w.ctx, routePath)
+	if err != nil {
+		return nil, err
+	}
+	return toTreePathLookup(lookup), nil
+}
+
+func (w *remoteWiki) GetPage(id string) (*tree.Page, error) {
+	page, err := w.client.Page(w.ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toTreePage(page), nil
+}
+
+func (w *remoteWiki) EnsurePath(_ string, targetPath string, title string, kind *tree.NodeKind) (*tree.Page, error) {
+	if w.dryRun {
+		return nil, errDryRun
+	}
+	k := tree.NodeKindPage
+	if kind != nil {
+		k = *kind
+	}
+	page, err := w.client.EnsurePath(w.ctx, targetPath, title, client.Kind(k))
+	if err != nil {
+		return nil, err
+	}
+	return toTreePage(page), nil
+}
+
+// UpdatePage sends the frontmatter of content as the tags and properties of
+// the page, as the API stores content as the page body. Without content, both
+// are kept.
+func (w *remoteWiki) UpdatePage(_ string, id, title, slug string, content *string, _ *tree.NodeKind) (*tree.Page, error) {
+	if w.dryRun {
+		return nil, errDryRun
+	}
+	current, err := w.client.Page(w.ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	update := client.UpdatePage{Version: current.Version, Title: title, Slug: slug}
+	if content != nil {
+		body, tags, properties, err := splitPageContent(*content)
+		if err != nil {
+			return nil, err
+		}
+		update.Content, update.Tags, update.Properties = &body, tags, properties
+	}
+	page, err := w.client.UpdatePage(w.ctx, id, update)
+	if err != nil {
+		return nil, err
+	}
+	return toTreePage(page), nil
+}
+
+func (w *remoteWiki) DeletePage(_ string, id string) error {
+	if w.dryRun {
+		return errDryRun
+	}
+	current, err := w.client.Page(w.ctx, id)
+	if err != nil {
+		return err
+	}
+	return w.client.DeletePage(w.ctx, id, current.Version, false)
+}
+
+func (w *remoteWiki) MovePage(_ string, id, parentID string) error {
+	if w.dryRun {
+		return errDryRun
+	}
+	current, err := w.client.Page(w.ctx, id)
+	if err != nil {
+		return err
+	}
+	return w.client.MovePage(w.ctx, id, current.Version, parentID)
+}
+
+// UploadAsset keeps an asset of the same name and content instead of
+// uploading it again, and replaces one with other content, so that
+// publishing unchanged files leaves their pages untouched.
+func (w *remoteWiki) UploadAsset(_ string, pageID string, file multipart.File, filename string, _ int64) (string, error) {
+	name := w.slugger.GenerateUniqueFilename(nil, filename)
+	publicPath := "/" + path.Join("assets", pageID, name)
+
+	existing, err := w.pageAssets(pageID)
+	if err != nil {
+		return "", err
+	}
+	if slices.Contains(existing, publicPath) {
+		same, err := w.sameAsset(publicPath, file)
+		if err != nil {
+			return "", err
+		}
+		if same {
+			return publicPath, nil
+		}
+		if !w.dryRun {
+			if err := w.client.DeleteAsset(w.ctx, pageID, name); err != nil {
+				return "", err
+			}
+		}
+	}
+	if w.dryRun {
+		w.pendingUploads++
+		return publicPath, nil
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	uploaded, err := w.client.UploadAsset(w.ctx, pageID, filename, file)
+	delete(w.assets, pageID)
+	if err != nil {
+		return "", err
+	}
+	return uploaded, nil
+}
+
+func (w *remoteWiki) ImportRevisions(string, []importer.ImportedRevision, bool) error {
+	return importer.ErrRevisionsDisabled
+}
+
+func (w *remoteWiki) pageAssets(pageID string) ([]string, error) {
+	if files, ok := w.assets[pageID]; ok {
+		return files, nil
+	}
+	files, err := w.client.Assets(w.ctx, pageID)
+	if err != nil {
+		return nil, err
+	}
+	w.assets[pageID] = files
+	return files, nil
+}
+
+func (w *remoteWiki) sameAsset(publicPath string, file io.ReadSeeker) (bool, error) {
+	remote := sha256.New()
+	if err := w.client.DownloadAsset(w.ctx, publicPath, remote); err != nil {
+		return false, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+	local := sha256.New()
+	if _, err := io.Copy(local, file); err != nil {
+		return false, err
+	}
+	return bytes.Equal(local.Sum(nil), remote.Sum(nil)), nil
+}
+
+func toTreePage(page *client.Page) *tree.Page {
+	node := &tree.PageNode{ID: page.ID, Title: page.Title, Slug: page.Slug, Kind: tree.NodeKind(page.Kind)}
+	return &tree.Page{PageNode: node, Content: page.Content}
+}
+
+func toTreePathLookup(lookup *client.PathLookup) *tree.PathLookup {
+	out := &tree.PathLookup{Path: lookup.Path, Exists: lookup.Exists, CanCreate: lookup.CanCreate}
+	for _, segment := range lookup.Segments {
+		converted := tree.PathSegment{Slug: segment.Slug, Exists: segment.Exists, Title: segment.Title, ID: segment.ID}
+		if segment.Kind != nil {
+			kind := tree.NodeKind(*segment.Kind)
+			converted.Kind = &kind
+		}
+		out.Segments = append(out.Segments, converted)
+	}
+	return out
+}
+
+// splitPageContent splits imported content into the body and the tags and
+// string properties of its frontmatter. Both are always set, as the published
+// files own the metadata of their pages. Other frontmatter values cannot be
+// set through the API and are dropped.
+func splitPageContent(content string) (string, []string, map[string]string, error) {
+	fm, body, _, err := markdown.ParseFrontmatter(content)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	tags := []string{}
+	properties := map[string]string{}
+	for rawKey, value := range fm.ExtraFields {
+		key := strings.TrimSpace(rawKey)
+		switch {
+		case strings.EqualFold(key, "tags"):
+			tags = normalizeTags(value)
+		case key == "" || markdown.IsSystemKey(key):
+		default:
+			// The server only reports single-line string properties back.
+			text, ok := value.(string)
+			text = strings.TrimSpace(text)
+			if ok && text != "" && !strings.ContainsRune(text, '\n') {
+				properties[key] = text
+			}
+		}
+	}
+	return body, tags, properties, nil
+}
+
+// normalizeTags lowercases and deduplicates a list of tags like the server
+// does.
+func normalizeTags(value any) []string {
+	tags := []string{}
+	list, _ := value.([]any)
+	for _, item := range list {
+		tag, ok := item.(string)
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if ok && tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
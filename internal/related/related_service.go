@@ -0,0 +1,288 @@
+package related
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/perber/wiki/internal/links"
+	"github.com/perber/wiki/internal/properties"
+	"github.com/perber/wiki/internal/search"
+	"github.com/perber/wiki/internal/tags"
+)
+
+// Kinds of reasons why a page is related to another.
+const (
+	ReasonTag        = "tag"         // the pages share tags
+	ReasonProperty   = "property"    // the pages share property values
+	ReasonLink       = "link"        // one page links to the other
+	ReasonCoCitation = "co_citation" // other pages link to both
+	ReasonCoupling   = "coupling"    // both link to the same pages
+	ReasonText       = "text"        // the texts are similar
+)
+
+// reasonWeights are the shares of the reasons in the score of a related
+// page; each reason has a strength between 0 and 1, so the score is too.
+var reasonWeights = map[string]float64{
+	ReasonTag:        0.25,
+	ReasonProperty:   0.15,
+	ReasonLink:       0.2,
+	ReasonCoCitation: 0.1,
+	ReasonCoupling:   0.1,
+	ReasonText:       0.2,
+}
+
+const (
+	// MaxRelated is the number of related pages computed for a page.
+	MaxRelated = 50
+	// similarCandidates is the number of similar texts looked at.
+	similarCandidates = 50
+	// maxCachedPages bounds the cache; it is emptied when full.
+	maxCachedPages = 1000
+)
+
+// Reason explains part of the score of a related page.
+type Reason struct {
+	Kind string `json:"kind"`
+	// Score is the reason's contribution to the page's score.
+	Score float64 `json:"score"`
+	// Values are the shared tags, the shared properties as key=value or
+	// the characteristic terms both texts contain.
+	Values []string `json:"values,omitempty"`
+	// Count is the number of pages citing both pages (co_citation) or
+	// cited by both (coupling).
+	Count int `json:"count,omitempty"`
+}
+
+// RelatedPage is a page related to another, with the reasons why.
+type RelatedPage struct {
+	PageID string `json:"page_id"`
+	// Score is between 0 and 1, the higher the more related.
+	Score   float64  `json:"score"`
+	Reasons []Reason `json:"reasons"`
+}
+
+// RelatedService ranks the pages related to a page by shared tags and
+// properties, their link neighbourhood and the similarity of their texts.
+// Results are cached until Invalidate is called; any change to a page may
+// change the related pages of others, so every change invalidates all.
+type RelatedService struct {
+	tags   *tags.TagsService
+	props  *properties.PropertiesService
+	links  *links.LinkService
+	search *search.SQLiteIndex
+
+	mu         sync.Mutex
+	cache      map[string][]RelatedPage
+	generation uint64
+}
+
+// NewRelatedService returns a service combining the given sources; any of
+// them may be nil.
+func NewRelatedService(t *tags.TagsService, p *properties.PropertiesService, l *links.LinkService, s *search.SQLiteIndex) *RelatedService {
+	return &RelatedService{tags: t, props: p, links: l, search: s, cache: map[string][]RelatedPage{}}
+}
+
+// Invalidate drops all cached results.
+func (s *RelatedService) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cache = map[string][]RelatedPage{}
+	s.generation++
+}
+
+// RelatedPages returns up to MaxRelated pages related to pageID, the most
+// related first. The result is shared and must not be modified.
+func (s *RelatedService) RelatedPages(pageID string) ([]RelatedPage, error) {
+	s.mu.Lock()
+	cached, ok := s.cache[pageID]
+	generation := s.generation
+	s.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	result, err := s.compute(pageID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// A change while computing may have made the result stale already.
+	if s.generation == generation {
+		if len(s.cache) >= maxCachedPages {
+			s.cache = map[string][]RelatedPage{}
+		}
+		s.cache[pageID] = result
+	}
+	return result, nil
+}
+
+func (s *RelatedService) compute(pageID string) ([]RelatedPage, error) {
+	pages := map[string]*RelatedPage{}
+	add := func(id string, reason Reason, strength float64) {
+		if id == pageID || strength <= 0 {
+			return
+		}
+		page := pages[id]
+		if page == nil {
+			page = &RelatedPage{PageID: id}
+			pages[id] = page
+		}
+		reason.Score = roundScore(reasonWeights[reason.Kind] * math.Min(1, strength))
+		page.Score += reasonWeights[reason.Kind] * math.Min(1, strength)
+		page.Reasons = append(page.Reasons, reason)
+	}
+
+	if err := s.addTags(pageID, add); err != nil {
+		return nil, err
+	}
+	if err := s.addProperties(pageID, add); err != nil {
+		return nil, err
+	}
+	if err := s.addLinks(pageID, add); err != nil {
+		return nil, err
+	}
+	if s.search != nil {
+		similar, err := s.search.SimilarPages(pageID, similarCandidates)
+		if err != nil {
+			return nil, err
+		}
+		for _, page := range similar {
+			add(page.PageID, Reason{Kind: ReasonText, Values: page.Terms}, page.Similarity)
+		}
+	}
+
+	result := make([]RelatedPage, 0, len(pages))
+	for _, page := range pages {
+		page.Score = roundScore(page.Score)
+		result = append(result, *page)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].PageID < result[j].PageID
+	})
+	if len(result) > MaxRelated {
+		result = result[:MaxRelated]
+	}
+	return result, nil
+}
+
+type addFunc func(id string, reason Reason, strength float64)
+
+// labelWeight is the weight of a tag or property value that pages pages
+// have: the rarer, the more telling.
+func labelWeight(pages int) float64 {
+	return 1 / math.Log2(1+float64(max(pages, 1)))
+}
+
+// addTags relates the pages sharing tags with pageID. The strength is the
+// weight of the shared tags over the weight of all tags of the page.
+func (s *RelatedService) addTags(pageID string, add addFunc) error {
+	if s.tags == nil {
+		return nil
+	}
+	shared, err := s.tags.GetSharedTags(pageID)
+	if err != nil || len(shared) == 0 {
+		return err
+	}
+	own, err := s.tags.GetTagsForPages([]string{pageID})
+	if err != nil {
+		return err
+	}
+	counts := map[string]int{}
+	values := map[string][]string{}
+	weights := map[string]float64{}
+	for _, st := range shared {
+		counts[st.Tag] = st.Pages
+		values[st.PageID] = append(values[st.PageID], st.Tag)
+		weights[st.PageID] += labelWeight(st.Pages)
+	}
+	total := 0.0
+	for _, tag := range own[pageID] {
+		total += labelWeight(max(counts[tag], 1))
+	}
+	for _, id := range sortedKeys(values) {
+		add(id, Reason{Kind: ReasonTag, Values: values[id]}, weights[id]/total)
+	}
+	return nil
+}
+
+// addProperties relates the pages sharing property values with pageID,
+// weighted like tags.
+func (s *RelatedService) addProperties(pageID string, add addFunc) error {
+	if s.props == nil {
+		return nil
+	}
+	shared, err := s.props.GetSharedProperties(pageID)
+	if err != nil || len(shared) == 0 {
+		return err
+	}
+	own, err := s.props.GetPropertiesForPages([]string{pageID})
+	if err != nil {
+		return err
+	}
+	counts := map[string]int{}
+	values := map[string][]string{}
+	weights := map[string]float64{}
+	for _, sp := range shared {
+		counts[sp.Key] = sp.Pages
+		values[sp.PageID] = append(values[sp.PageID], sp.Key+"="+sp.Value)
+		weights[sp.PageID] += labelWeight(sp.Pages)
+	}
+	total := 0.0
+	for key, entry := range own[pageID] {
+		if entry.Value != "" {
+			total += labelWeight(max(counts[key], 1))
+		}
+	}
+	for _, id := range sortedKeys(values) {
+		add(id, Reason{Kind: ReasonProperty, Values: values[id]}, weights[id]/total)
+	}
+	return nil
+}
+
+// addLinks relates the pages linked with pageID, and those cited with it
+// or citing the same pages; n shared neighbours give a strength of
+// n/(n+1).
+func (s *RelatedService) addLinks(pageID string, add addFunc) error {
+	if s.links == nil {
+		return nil
+	}
+	n, err := s.links.GetLinkNeighbourhood(pageID)
+	if err != nil {
+		return err
+	}
+	for _, id := range sortedKeys(n.Linked) {
+		add(id, Reason{Kind: ReasonLink}, 1)
+	}
+	for _, id := range sortedKeys(n.CoCited) {
+		count := n.CoCited[id]
+		add(id, Reason{Kind: ReasonCoCitation, Count: count}, saturate(count))
+	}
+	for _, id := range sortedKeys(n.Coupled) {
+		count := n.Coupled[id]
+		add(id, Reason{Kind: ReasonCoupling, Count: count}, saturate(count))
+	}
+	return nil
+}
+
+func saturate(n int) float64 {
+	return float64(n) / float64(n+1)
+}
+
+func roundScore(score float64) float64 {
+	return math.Round(score*1000) / 1000
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,172 @@
+package related
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/perber/wiki/internal/links"
+	"github.com/perber/wiki/internal/properties"
+	"github.com/perber/wiki/internal/tags"
+	"github.com/perber/wiki/internal/test_utils"
+)
+
+type testSources struct {
+	tags  *tags.TagsService
+	props *properties.PropertiesService
+	links *links.LinksStore
+	svc   *RelatedService
+}
+
+func newTestSources(t *testing.T) *testSources {
+	t.Helper()
+	dir := t.TempDir()
+	tagsStore, err := tags.NewTagsStore(dir)
+	if err != nil {
+		t.Fatalf("NewTagsStore failed: %v", err)
+	}
+	t.Cleanup(func() { test_utils.WrapCloseWithErrorCheck(tagsStore.Close, t) })
+	propsStore, err := properties.NewPropertiesStore(dir)
+	if err != nil {
+		t.Fatalf("NewPropertiesStore failed: %v", err)
+	}
+	t.Cleanup(func() { test_utils.WrapCloseWithErrorCheck(propsStore.Close, t) })
+	linksStore, err := links.NewLinksStore(dir)
+	if err != nil {
+		t.Fatalf("NewLinksStore failed: %v", err)
+	}
+	t.Cleanup(func() { test_utils.WrapCloseWithErrorCheck(linksStore.Close, t) })
+
+	s := &testSources{
+		tags:  tags.NewTagsService(tagsStore),
+		props: properties.NewPropertiesService(propsStore),
+		links: linksStore,
+	}
+	s.svc = NewRelatedService(s.tags, s.props, links.NewLinkService(dir, nil, linksStore), nil)
+	return s
+}
+
+func (s *testSources) setTags(t *testing.T, pageID string, values ...string) {
+	t.Helper()
+	if err := s.tags.SetTagsForPage(pageID, values); err != nil {
+		t.Fatalf("SetTagsForPage(%s) failed: %v", pageID, err)
+	}
+}
+
+func (s *testSources) link(t *testing.T, from string, to ...string) {
+	t.Helper()
+	targets := make([]links.TargetLink, len(to))
+	for i, id := range to {
+		targets[i] = links.TargetLink{TargetPageID: id, TargetPagePath: "/" + id}
+	}
+	if err := s.links.AddLinks(from, from, targets); err != nil {
+		t.Fatalf("AddLinks(%s) failed: %v", from, err)
+	}
+}
+
+func findRelated(pages []RelatedPage, id string) *RelatedPage {
+	for i := range pages {
+		if pages[i].PageID == id {
+			return &pages[i]
+		}
+	}
+	return nil
+}
+
+func reasonKinds(page *RelatedPage) []string {
+	kinds := make([]string, len(page.Reasons))
+	for i, r := range page.Reasons {
+		kinds[i] = r.Kind
+	}
+	return kinds
+}
+
+func TestRelatedService_RelatedPages_CombinesReasons(t *testing.T) {
+	s := newTestSources(t)
+	s.setTags(t, "a", "go", "db")
+	s.setTags(t, "b", "go")
+	s.setTags(t, "c", "db")
+	for _, id := range []string{"a", "c"} {
+		if err := s.props.SetPropertiesForPage(id, map[string]properties.PropertyEntry{"team": {Value: "core", Type: "text"}}); err != nil {
+			t.Fatalf("SetPropertiesForPage(%s) failed: %v", id, err)
+		}
+	}
+	s.link(t, "a", "b", "d")
+	s.link(t, "x", "a", "c")
+	s.link(t, "y", "a", "c")
+	s.link(t, "e", "d")
+
+	got, err := s.svc.RelatedPages("a")
+	if err != nil {
+		t.Fatalf("RelatedPages failed: %v", err)
+	}
+	if findRelated(got, "a") != nil {
+		t.Errorf("a page must not be related to itself: %+v", got)
+	}
+	if len(got) < 2 || got[0].PageID != "c" || got[1].PageID != "b" {
+		t.Fatalf("expected c then b first, got %+v", got)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i-1].Score < got[i].Score {
+			t.Errorf("results are not sorted by score: %+v", got)
+		}
+	}
+
+	c := got[0]
+	if kinds := reasonKinds(&c); !slices.Equal(kinds, []string{ReasonTag, ReasonProperty, ReasonCoCitation}) {
+		t.Errorf("unexpected reasons for c: %+v", c.Reasons)
+	}
+	if !slices.Equal(c.Reasons[0].Values, []string{"db"}) || !slices.Equal(c.Reasons[1].Values, []string{"team=core"}) || c.Reasons[2].Count != 2 {
+		t.Errorf("unexpected reason details for c: %+v", c.Reasons)
+	}
+	if kinds := reasonKinds(&got[1]); !slices.Equal(kinds, []string{ReasonTag, ReasonLink}) {
+		t.Errorf("unexpected reasons for b: %+v", got[1].Reasons)
+	}
+
+	e := findRelated(got, "e")
+	if e == nil || !slices.Equal(reasonKinds(e), []string{ReasonCoupling}) || e.Reasons[0].Count != 1 {
+		t.Errorf("expected e to be related by coupling, got %+v", e)
+	}
+	for _, id := range []string{"d", "x", "y"} {
+		if p := findRelated(got, id); p == nil || !slices.Equal(reasonKinds(p), []string{ReasonLink}) {
+			t.Errorf("expected %s to be related by a link, got %+v", id, p)
+		}
+	}
+	for _, page := range got {
+		if page.Score <= 0 || page.Score > 1 {
+			t.Errorf("score of %s out of range: %v", page.PageID, page.Score)
+		}
+	}
+}
+
+func TestRelatedService_RelatedPages_CachesUntilInvalidated(t *testing.T) {
+	s := newTestSources(t)
+	s.setTags(t, "a", "go")
+	s.setTags(t, "b", "go")
+
+	first, err := s.svc.RelatedPages("a")
+	if err != nil || len(first) != 1 {
+		t.Fatalf("RelatedPages = %+v, %v", first, err)
+	}
+
+	s.setTags(t, "c", "go")
+	cached, err := s.svc.RelatedPages("a")
+	if err != nil || len(cached) != 1 {
+		t.Fatalf("expected the cached result, got %+v, %v", cached, err)
+	}
+
+	s.svc.Invalidate()
+	fresh, err := s.svc.RelatedPages("a")
+	if err != nil || len(fresh) != 2 {
+		t.Fatalf("expected b and c after Invalidate, got %+v, %v", fresh, err)
+	}
+}
+
+func TestRelatedService_RelatedPages_UnknownPageHasNone(t *testing.T) {
+	s := newTestSources(t)
+	s.setTags(t, "a", "go")
+
+	got, err := s.svc.RelatedPages("missing")
+	if err != nil || len(got) != 0 {
+		t.Fatalf("RelatedPages = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,295 @@
+package search
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/perber/wiki/internal/search/snowball"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Language selects how the words of a page are stemmed.
+type Language string
+
+const (
+	// LanguageNone leaves words unstemmed.
+	LanguageNone    Language = ""
+	LanguageEnglish Language = "en"
+	LanguageGerman  Language = "de"
+)
+
+// analyzerVersion is part of the stored analyzer settings. Bump it whenever
+// the analysis of a word changes, so that existing indexes are reanalyzed.
+const analyzerVersion = 1
+
+// ParseLanguage reads a language name or tag such as "de", "de-AT",
+// "german" or "none".
+func ParseLanguage(s string) (Language, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if i := strings.IndexAny(name, "-_"); i >= 0 {
+		name = name[:i]
+	}
+	switch name {
+	case "", "none":
+		return LanguageNone, nil
+	case "en", "eng", "english":
+		return LanguageEnglish, nil
+	case "de", "deu", "ger", "german", "deutsch":
+		return LanguageGerman, nil
+	}
+	return LanguageNone, fmt.Errorf("unsupported search language %q (supported: en, de, none)", s)
+}
+
+// pageLanguage returns the language of a page with the given frontmatter
+// lang value. Pages in languages without a stemmer are not stemmed.
+func pageLanguage(lang interface{}, fallback Language) Language {
+	s, ok := lang.(string)
+	if !ok || strings.TrimSpace(s) == "" {
+		return fallback
+	}
+	l, err := ParseLanguage(s)
+	if err != nil {
+		return LanguageNone
+	}
+	return l
+}
+
+// Analyzer turns text into the terms of the stemmed columns of the index:
+// words are lower-cased, optionally stripped of stopwords, stemmed in the
+// language of their page and folded to plain letters ("Zertifikate" becomes
+// "zertifikat", "Größe" becomes "gross").
+type Analyzer struct {
+	// Language is the language of pages without a lang frontmatter field.
+	Language Language
+	// Stopwords leaves out the most frequent words of a page's language.
+	Stopwords bool
+}
+
+// settings identifies the analyzer in the index, see SetAnalyzer.
+func (a Analyzer) settings() string {
+	return fmt.Sprintf("v%d language=%s stopwords=%t", analyzerVersion, a.Language, a.Stopwords)
+}
+
+// analyze returns the terms of text in language lang, separated by spaces.
+func (a Analyzer) analyze(text string, lang Language) string {
+	var b strings.Builder
+	for _, word := range splitWords(text) {
+		word = strings.ToLower(word)
+		if a.Stopwords && isStopword(word, lang) {
+			continue
+		}
+		term := foldDiacritics(stem(word, lang))
+		if term == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(term)
+	}
+	return b.String()
+}
+
+// splitWords splits text into runs of letters and digits.
+func splitWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func stem(word string, lang Language) string {
+	switch lang {
+	case LanguageEnglish:
+		return snowball.English(word)
+	case LanguageGerman:
+		return snowball.German(word)
+	}
+	return word
+}
+
+// foldReplacements are letters without a decomposition that folding
+// replaces.
+var foldReplacements = strings.NewReplacer(
+	"ß", "ss", "æ", "ae", "œ", "oe", "ø", "o", "đ", "d", "ð", "d", "ł", "l", "þ", "th", "ı", "i",
+)
+
+// foldDiacritics removes accents and umlauts from the letters of s.
+func foldDiacritics(s string) string {
+	decomposed := norm.NFD.String(foldReplacements.Replace(s))
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, decomposed)
+}
+
+// stemForms returns the stemmed terms a query term is looked up as, given
+// the languages of the indexed pages. Forms equal to the term itself are
+// left out, since the unstemmed columns match those already.
+func (a Analyzer) stemForms(text string, languages []Language) []string {
+	plain := strings.Join(splitWords(strings.ToLower(text)), " ")
+	var forms []string
+	for _, lang := range languages {
+		form := a.analyze(text, lang)
+		if form != "" && form != plain && !slices.Contains(forms, form) {
+			forms = append(forms, form)
+		}
+	}
+	return forms
+}
+
+// withoutStopwords returns q without the positive single-word clauses that
+// are stopwords of the analyzer's language, unless that would leave no
+// positive clause.
+func (a Analyzer) withoutStopwords(q *Query) *Query {
+	if !a.Stopwords || a.Language == LanguageNone {
+		return q
+	}
+	var kept []QueryClause
+	positive := 0
+	for _, c := range q.Clauses {
+		if !c.Negated && len(c.Terms) == 1 && !c.Terms[0].Phrase && isStopword(strings.ToLower(c.Terms[0].Text), a.Language) {
+			continue
+		}
+		if !c.Negated {
+			positive++
+		}
+		kept = append(kept, c)
+	}
+	if positive == 0 || len(kept) == len(q.Clauses) {
+		return q
+	}
+	return &Query{Clauses: kept, Filters: q.Filters, source: q.source}
+}
+
+// Keys of search_settings.
+const (
+	// settingAnalyzer holds the settings of the analyzer the stemmed
+	// columns were filled with.
+	settingAnalyzer = "analyzer"
+	// settingLanguages lists the languages of the analyzed pages.
+	settingLanguages = "languages"
+)
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func writeSetting(db execer, key, value string) error {
+	_, err := db.Exec(`INSERT OR REPLACE INTO search_settings (key, value) VALUES (?, ?)`, key, value)
+	return err
+}
+
+func readSetting(db *sql.DB, key string) (string, error) {
+	var value string
+	err := db.QueryRow(`SELECT value FROM search_settings WHERE key = ?`, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return value, err
+}
+
+func formatLanguages(languages []Language) string {
+	names := make([]string, len(languages))
+	for i, l := range languages {
+		names[i] = string(l)
+		if l == LanguageNone {
+			names[i] = "none"
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func parseLanguages(s string) []Language {
+	languages := []Language{LanguageNone}
+	for _, name := range strings.Split(s, ",") {
+		if l, err := ParseLanguage(name); err == nil && !slices.Contains(languages, l) {
+			languages = append(languages, l)
+		}
+	}
+	return languages
+}
+
+// SetAnalyzer sets the analyzer for the stemmed columns. An index that
+// holds pages analyzed with other settings is outdated until every page is
+// indexed again and MarkAnalyzed is called; meanwhile searches keep working,
+// and pages not yet reindexed are found by their unstemmed text and their
+// old stems.
+func (s *SQLiteIndex) SetAnalyzer(a Analyzer) error {
+	return s.withDB(func(db *sql.DB) error {
+		stored, err := readSetting(db, settingAnalyzer)
+		if err != nil {
+			return err
+		}
+		storedLanguages, err := readSetting(db, settingLanguages)
+		if err != nil {
+			return err
+		}
+		languages := parseLanguages(storedLanguages)
+		if !slices.Contains(languages, a.Language) {
+			languages = append(languages, a.Language)
+		}
+
+		outdated := stored != a.settings()
+		if outdated && stored == "" {
+			// A new index has no pages analyzed in any other way.
+			var empty bool
+			if err := db.QueryRow(`SELECT NOT EXISTS (SELECT 1 FROM pages)`).Scan(&empty); err != nil {
+				return err
+			}
+			if empty {
+				if err := writeSetting(db, settingAnalyzer, a.settings()); err != nil {
+					return err
+				}
+				outdated = false
+			}
+		}
+
+		s.analyzer = a
+		s.analyzerOutdated = outdated
+		s.languages = languages
+		return nil
+	})
+}
+
+// AnalyzerOutdated reports whether pages were analyzed with other settings
+// than the current analyzer's.
+func (s *SQLiteIndex) AnalyzerOutdated() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.analyzerOutdated
+}
+
+// MarkAnalyzed records that every page was indexed with the current
+// analyzer.
+func (s *SQLiteIndex) MarkAnalyzed() error {
+	return s.withDB(func(db *sql.DB) error {
+		if err := writeSetting(db, settingAnalyzer, s.analyzer.settings()); err != nil {
+			return err
+		}
+		s.analyzerOutdated = false
+		return nil
+	})
+}
+
+func (s *SQLiteIndex) currentAnalyzer() Analyzer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.analyzer
+}
+
+// stemFunc returns the stemmed forms of query terms for the languages of
+// the indexed pages.
+func (s *SQLiteIndex) stemFunc() stemFunc {
+	s.mu.RLock()
+	a, languages := s.analyzer, s.languages
+	s.mu.RUnlock()
+	return func(text string) []string {
+		return a.stemForms(text, languages)
+	}
+}
@@ -0,0 +1,174 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/perber/wiki/internal/core/tree"
+	"github.com/perber/wiki/internal/test_utils"
+)
+
+func TestParseLanguage(t *testing.T) {
+	cases := map[string]Language{
+		"":        LanguageNone,
+		"none":    LanguageNone,
+		"en":      LanguageEnglish,
+		"en-US":   LanguageEnglish,
+		"English": LanguageEnglish,
+		"de":      LanguageGerman,
+		"de_AT":   LanguageGerman,
+		"Deutsch": LanguageGerman,
+	}
+	for input, want := range cases {
+		got, err := ParseLanguage(input)
+		if err != nil || got != want {
+			t.Errorf("ParseLanguage(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParseLanguage("fr"); err == nil {
+		t.Error("expected an error for an unsupported language")
+	}
+}
+
+func TestAnalyzer_Analyze(t *testing.T) {
+	cases := []struct {
+		analyzer Analyzer
+		lang     Language
+		text     string
+		want     string
+	}{
+		{Analyzer{}, LanguageGerman, "Die Zertifikate für den Server", "die zertifikat fur den serv"},
+		{Analyzer{Stopwords: true}, LanguageGerman, "Die Zertifikate für den Server", "zertifikat serv"},
+		{Analyzer{Stopwords: true}, LanguageEnglish, "Renewing the certificates", "renew certif"},
+		{Analyzer{Stopwords: true}, LanguageNone, "Straße, Café & the Ångström-unit", "strasse cafe the angstrom unit"},
+	}
+	for _, tc := range cases {
+		if got := tc.analyzer.analyze(tc.text, tc.lang); got != tc.want {
+			t.Errorf("analyze(%q, %q) = %q, want %q", tc.text, tc.lang, got, tc.want)
+		}
+	}
+}
+
+func TestAnalyzer_WithoutStopwords(t *testing.T) {
+	a := Analyzer{Language: LanguageEnglish, Stopwords: true}
+	q, err := ParseQuery(`the deployment of "the app" -the`)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+	got := a.withoutStopwords(q)
+	if len(got.Clauses) != 3 || got.Clauses[0].Terms[0].Text != "deployment" {
+		t.Fatalf("expected the unquoted positive stopwords to be dropped, got %+v", got.Clauses)
+	}
+
+	q, err = ParseQuery(`the of`)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+	if got := a.withoutStopwords(q); len(got.Clauses) != 2 {
+		t.Fatalf("expected a query of stopwords only to be kept, got %+v", got.Clauses)
+	}
+}
+
+func TestSQLiteIndex_Search_MatchesInflectedForms(t *testing.T) {
+	index, err := NewSQLiteIndex(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer test_utils.WrapCloseWithErrorCheck(index.Close, t)
+	if err := index.SetAnalyzer(Analyzer{Language: LanguageGerman}); err != nil {
+		t.Fatalf("SetAnalyzer failed: %v", err)
+	}
+
+	pages := map[string]string{
+		"de":    "Das Zertifikat muss jährlich erneuert werden.",
+		"en":    "---\nlang: en\n---\nRenewing certificates every year.",
+		"other": "---\nlang: fr\n---\nLes certificats sont renouvelés.",
+	}
+	for id, raw := range pages {
+		if err := index.IndexPage("docs/"+id, "docs/"+id+".md", id, id, tree.NodeKindPage, raw); err != nil {
+			t.Fatalf("IndexPage %s failed: %v", id, err)
+		}
+	}
+
+	cases := map[string][]string{
+		"Zertifikate":           {"de"},
+		"Zertifikats jaehrlich": {"de"},
+		"renewed certificate":   {"en"},
+		"renouvelé":             {"other"},
+		"certificats":           {"other"},
+	}
+	for query, want := range cases {
+		ids, err := index.SearchPageIDs(query, nil)
+		if err != nil {
+			t.Fatalf("SearchPageIDs(%q) failed: %v", query, err)
+		}
+		if len(ids) != len(want) || (len(ids) > 0 && ids[0] != want[0]) {
+			t.Errorf("SearchPageIDs(%q) = %v, want %v", query, ids, want)
+		}
+	}
+}
+
+func TestSQLiteIndex_SetAnalyzer_ReportsOutdatedIndex(t *testing.T) {
+	dir := t.TempDir()
+	index, err := NewSQLiteIndex(dir)
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	if index.AnalyzerOutdated() {
+		t.Fatal("expected a new index to be up to date")
+	}
+	if err := index.IndexPage("docs/a", "docs/a.md", "a", "A", tree.NodeKindPage, "Zertifikate"); err != nil {
+		t.Fatalf("IndexPage failed: %v", err)
+	}
+
+	german := Analyzer{Language: LanguageGerman, Stopwords: true}
+	if err := index.SetAnalyzer(german); err != nil {
+		t.Fatalf("SetAnalyzer failed: %v", err)
+	}
+	if !index.AnalyzerOutdated() {
+		t.Fatal("expected the index to be outdated after the analyzer changed")
+	}
+	// Until the page is reindexed it is still found by its text.
+	if ids, err := index.SearchPageIDs("zertifikate", nil); err != nil || len(ids) != 1 {
+		t.Fatalf("expected the page to stay searchable, got %v, %v", ids, err)
+	}
+	if err := index.IndexPage("docs/a", "docs/a.md", "a", "A", tree.NodeKindPage, "Zertifikate"); err != nil {
+		t.Fatalf("IndexPage failed: %v", err)
+	}
+	if err := index.MarkAnalyzed(); err != nil {
+		t.Fatalf("MarkAnalyzed failed: %v", err)
+	}
+	if index.AnalyzerOutdated() {
+		t.Fatal("expected the index to be up to date after MarkAnalyzed")
+	}
+	if err := index.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	index, err = NewSQLiteIndex(dir)
+	if err != nil {
+		t.Fatalf("failed to reopen SQLiteIndex: %v", err)
+	}
+	defer test_utils.WrapCloseWithErrorCheck(index.Close, t)
+	if err := index.SetAnalyzer(german); err != nil {
+		t.Fatalf("SetAnalyzer failed: %v", err)
+	}
+	if index.AnalyzerOutdated() {
+		t.Fatal("expected the stored analyzer settings to be kept")
+	}
+	if ids, err := index.SearchPageIDs("Zertifikat", nil); err != nil || len(ids) != 1 {
+		t.Fatalf("expected the stemmed page to be found, got %v, %v", ids, err)
+	}
+
+	if err := index.SetAnalyzer(Analyzer{}); err != nil {
+		t.Fatalf("SetAnalyzer failed: %v", err)
+	}
+	if !index.AnalyzerOutdated() {
+		t.Fatal("expected the index to be outdated after the analyzer changed")
+	}
+	if err := index.Clear(); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if index.AnalyzerOutdated() {
+		t.Fatal("expected a cleared index to be up to date")
+	}
+}
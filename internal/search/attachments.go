@@ -0,0 +1,277 @@
+package search
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/perber/wiki/internal/core/excerpt"
+	"github.com/perber/wiki/internal/search/pdftext"
+	"golang.org/x/net/html"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// DefaultAttachmentMaxBytes is the default of AttachmentOptions.MaxBytes.
+const DefaultAttachmentMaxBytes = 1 << 20
+
+// AttachmentOptions selects the attachments whose text is indexed.
+type AttachmentOptions struct {
+	// MaxBytes is the number of bytes read from a text attachment; longer
+	// files are indexed up to there. PDFs larger than this are skipped.
+	MaxBytes int64
+	// PDF enables the extraction of text from PDF attachments.
+	PDF bool
+}
+
+// textAttachmentExtensions are the extensions of attachments read as text.
+var textAttachmentExtensions = map[string]bool{
+	".txt": true, ".text": true, ".log": true, ".csv": true, ".tsv": true,
+	".json": true, ".xml": true, ".yaml": true, ".yml": true, ".toml": true,
+	".ini": true, ".conf": true, ".cfg": true,
+	".md": true, ".markdown": true, ".html": true, ".htm": true,
+}
+
+// Indexes reports whether attachments named filename are indexed.
+func (o AttachmentOptions) Indexes(filename string) bool {
+	ext := strings.ToLower(path.Ext(filename))
+	return textAttachmentExtensions[ext] || (o.PDF && ext == ".pdf")
+}
+
+// AttachmentText returns the searchable text of an attachment from its
+// first MaxBytes bytes, or false when it has none: its type is not indexed,
+// it is binary, or it is a PDF cut off by the size limit.
+func (o AttachmentOptions) AttachmentText(filename string, data []byte, truncated bool) (string, bool) {
+	if !o.Indexes(filename) {
+		return "", false
+	}
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".pdf":
+		if truncated {
+			return "", false
+		}
+		text, err := pdftext.Extract(data, int(o.MaxBytes))
+		if err != nil {
+			slog.Default().Debug("failed to extract PDF text", "filename", filename, "error", err)
+			return "", false
+		}
+		return text, text != ""
+	}
+
+	if bytes.IndexByte(data, 0) >= 0 {
+		return "", false
+	}
+	text := decodeText(data, truncated)
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".md", ".markdown":
+		text = excerpt.PlainTextForSearch(excerpt.NormalizeMarkdownBody(text))
+	case ".html", ".htm":
+		text = htmlText(text)
+	}
+	text = strings.TrimSpace(text)
+	return text, text != ""
+}
+
+// decodeText reads data as UTF-8, or as Windows-1252 when it is not valid
+// UTF-8. A truncated rune at the end of a truncated file is dropped.
+func decodeText(data []byte, truncated bool) string {
+	if truncated {
+		for i := 0; i < utf8.UTFMax && len(data) > 0; i++ {
+			r, size := utf8.DecodeLastRune(data)
+			if r != utf8.RuneError || size != 1 {
+				break
+			}
+			data = data[:len(data)-1]
+		}
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	decoded, err := charmap.Windows1252.NewDecoder().Bytes(data)
+	if err != nil {
+		return strings.ToValidUTF8(string(data), " ")
+	}
+	return string(decoded)
+}
+
+// htmlText returns the text of an HTML document without its scripts and
+// styles.
+func htmlText(src string) string {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(src))
+	skip := 0
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return b.String()
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			if tag := string(name); tag == "script" || tag == "style" {
+				skip++
+			}
+			b.WriteByte(' ')
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			if tag := string(name); (tag == "script" || tag == "style") && skip > 0 {
+				skip--
+			}
+			b.WriteByte(' ')
+		case html.TextToken:
+			if skip == 0 {
+				b.Write(z.Text())
+			}
+		}
+	}
+}
+
+// IndexAttachment replaces the indexed text of an attachment of a page;
+// empty text removes it.
+func (s *SQLiteIndex) IndexAttachment(pageID, filename, text string) error {
+	return s.ReplaceAttachments(pageID, map[string]string{filename: text}, false)
+}
+
+// ReplaceAttachments indexes the text of attachments of a page by filename.
+// With all set, the page's other attachments are removed from the index.
+func (s *SQLiteIndex) ReplaceAttachments(pageID string, texts map[string]string, all bool) error {
+	return s.withDB(func(db *sql.DB) error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = tx.Rollback()
+		}()
+
+		if all {
+			if _, err := tx.Exec(`DELETE FROM attachments WHERE pageID = ?`, pageID); err != nil {
+				return err
+			}
+		}
+		for filename, text := range texts {
+			if !all {
+				if _, err := tx.Exec(`DELETE FROM attachments WHERE pageID = ? AND filename = ?`, pageID, filename); err != nil {
+					return err
+				}
+			}
+			if text == "" {
+				continue
+			}
+			if _, err := tx.Exec(`INSERT INTO attachments (pageID, filename, content) VALUES (?, ?, ?)`, pageID, filename, text); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+// RemoveAttachment removes an attachment of a page from the index.
+func (s *SQLiteIndex) RemoveAttachment(pageID, filename string) error {
+	return s.withDB(func(db *sql.DB) error {
+		_, err := db.Exec(`DELETE FROM attachments WHERE pageID = ? AND filename = ?`, pageID, filename)
+		return err
+	})
+}
+
+// maxAttachmentHits is the number of attachment hits a search returns.
+const maxAttachmentHits = 10
+
+// searchAttachments returns the attachments of the pages in scope that
+// match q, best first, and their number.
+func searchAttachments(db *sql.DB, q *Query, scope SearchScope) ([]SearchAttachmentHit, int, error) {
+	match := q.ftsAttachmentMatch()
+	if match == "" {
+		return nil, 0, nil
+	}
+	clauses := []string{"attachments MATCH ?"}
+	args := []interface{}{match}
+	if exclude := q.ftsAttachmentExclude(); exclude != "" {
+		clauses = append(clauses, "rowid NOT IN (SELECT rowid FROM attachments WHERE attachments MATCH ?)")
+		args = append(args, exclude)
+	}
+	if pagesWhere, pagesArgs := buildSearchWhereClause(&Query{Filters: q.Filters}, scope, nil); pagesWhere != "1" {
+		clauses = append(clauses, "pageID IN (SELECT pageID FROM pages WHERE "+pagesWhere+")")
+		args = append(args, pagesArgs...)
+	}
+	where := strings.Join(clauses, " AND ")
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM attachments WHERE `+where, args...).Scan(&count); err != nil {
+		return nil, 0, err
+	}
+	if count == 0 {
+		return nil, 0, nil
+	}
+
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT
+			pageID,
+			filename,
+			snippet(attachments, 2, '<b>', '</b>', '...', 16),
+			bm25(attachments, 0.0, 5.0, 1.0) AS bm25_score
+		FROM attachments
+		WHERE %s
+		ORDER BY bm25_score ASC
+		LIMIT ?;
+	`, where), append(args, maxAttachmentHits)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Default().Error("could not close rows", "error", err)
+		}
+	}()
+
+	var hits []SearchAttachmentHit
+	for rows.Next() {
+		var hit SearchAttachmentHit
+		var bm25Score float64
+		if err := rows.Scan(&hit.PageID, &hit.Filename, &hit.Excerpt, &bm25Score); err != nil {
+			return nil, 0, err
+		}
+		hit.Rank = 1.0 / (1.0 + max(bm25Score, 0))
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return hits, count, attachPages(db, hits)
+}
+
+// attachPages fills in the title and path of the pages of hits.
+func attachPages(db *sql.DB, hits []SearchAttachmentHit) error {
+	ids := make([]interface{}, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.PageID)
+	}
+	rows, err := db.Query(fmt.Sprintf(`SELECT pageID, title, path FROM pages WHERE pageID IN (%s)`, placeholders(len(ids))), ids...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Default().Error("could not close rows", "error", err)
+		}
+	}()
+
+	type page struct{ title, path string }
+	pages := map[string]page{}
+	for rows.Next() {
+		var id string
+		var p page
+		if err := rows.Scan(&id, &p.title, &p.path); err != nil {
+			return err
+		}
+		pages[id] = p
+	}
+	for i := range hits {
+		p := pages[hits[i].PageID]
+		hits[i].PageTitle = sanitizeSearchTitle(p.title)
+		hits[i].PagePath = p.path
+	}
+	return rows.Err()
+}
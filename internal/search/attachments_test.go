@@ -0,0 +1,157 @@
+package search
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/perber/wiki/internal/core/tree"
+	"github.com/perber/wiki/internal/test_utils"
+)
+
+func TestAttachmentOptions_AttachmentText(t *testing.T) {
+	opts := AttachmentOptions{MaxBytes: DefaultAttachmentMaxBytes}
+	tests := []struct {
+		name      string
+		filename  string
+		data      string
+		truncated bool
+		want      string
+		ok        bool
+	}{
+		{"plain text", "notes.TXT", "deploy checklist\n", false, "deploy checklist", true},
+		{"csv", "export.csv", "host,status\nweb-1,down", false, "host,status\nweb-1,down", true},
+		{"markdown", "README.md", "# Setup\nRun **make**.", false, "Setup Run make.", true},
+		{"html", "page.html", "<html><head><style>p{}</style><script>var x</script></head><body><p>Hello <b>world</b></p></body></html>", false, "Hello  world", true},
+		{"windows-1252", "legacy.log", "Gr\xfc\xdfe", false, "Grüße", true},
+		{"cut rune", "cut.txt", "Gr\xc3\xbc\xc3", true, "Grü", true},
+		{"binary", "data.json", "{\x00}", false, "", false},
+		{"unsupported", "image.png", "text", false, "", false},
+		{"pdf disabled", "report.pdf", "%PDF-1.4", false, "", false},
+		{"empty", "empty.txt", "  \n", false, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := opts.AttachmentText(tt.filename, []byte(tt.data), tt.truncated)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("AttachmentText(%q) = %q, %v; want %q, %v", tt.filename, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+
+	pdf := AttachmentOptions{MaxBytes: DefaultAttachmentMaxBytes, PDF: true}
+	if _, ok := pdf.AttachmentText("report.pdf", []byte("%PDF-1.4"), true); ok {
+		t.Errorf("expected a truncated PDF to be skipped")
+	}
+}
+
+func TestSQLiteIndex_Search_ReturnsAttachmentHits(t *testing.T) {
+	index, err := NewSQLiteIndex(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer test_utils.WrapCloseWithErrorCheck(index.Close, t)
+
+	if err := index.IndexPage("ops/incidents", "ops/incidents.md", "incidents", "Incidents", tree.NodeKindPage, "Postmortems of outages."); err != nil {
+		t.Fatalf("IndexPage failed: %v", err)
+	}
+	if err := index.IndexPage("dev/notes", "dev/notes.md", "notes", "Notes", tree.NodeKindPage, "Nothing here."); err != nil {
+		t.Fatalf("IndexPage failed: %v", err)
+	}
+	if err := index.IndexAttachment("incidents", "db-failover.log", "12:03 replica promoted after failover of primary"); err != nil {
+		t.Fatalf("IndexAttachment failed: %v", err)
+	}
+	if err := index.IndexAttachment("notes", "failover.txt", "manual failover steps"); err != nil {
+		t.Fatalf("IndexAttachment failed: %v", err)
+	}
+
+	result, err := index.Search("failover", nil, 0, 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if result.Count != 0 {
+		t.Errorf("expected no page hits, got %+v", result.Items)
+	}
+	if result.AttachmentCount != 2 || len(result.Attachments) != 2 {
+		t.Fatalf("expected 2 attachment hits, got %+v", result.Attachments)
+	}
+	hits := map[string]SearchAttachmentHit{}
+	for _, hit := range result.Attachments {
+		hits[hit.Filename] = hit
+	}
+	hit := hits["db-failover.log"]
+	if hit.PageID != "incidents" || hit.PageTitle != "Incidents" || hit.PagePath != "ops/incidents" {
+		t.Errorf("expected the owning page with the hit, got %+v", hit)
+	}
+	if !strings.Contains(hit.Excerpt, "<b>failover</b>") {
+		t.Errorf("expected a highlighted excerpt, got %q", hit.Excerpt)
+	}
+
+	// Page filters apply to the owning page; title: terms never match attachments.
+	for query, want := range map[string]int{
+		"failover path:ops":     1,
+		"failover -path:ops":    1,
+		"failover -replica":     1,
+		"content:promoted":      1,
+		"title:failover":        0,
+		"failover incidents":    0,
+		"filename:failover.txt": 0,
+	} {
+		result, err := index.Search(query, nil, 0, 10)
+		if err != nil {
+			t.Fatalf("Search(%q) failed: %v", query, err)
+		}
+		if result.AttachmentCount != want {
+			t.Errorf("Search(%q): expected %d attachment hits, got %+v", query, want, result.Attachments)
+		}
+	}
+	result, err = index.Search("failover", []string{"notes"}, 0, 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(result.Attachments) != 1 || result.Attachments[0].PageID != "notes" {
+		t.Errorf("expected only attachments of pages in scope, got %+v", result.Attachments)
+	}
+
+	if err := index.RemoveAttachment("notes", "failover.txt"); err != nil {
+		t.Fatalf("RemoveAttachment failed: %v", err)
+	}
+	if err := index.RemovePage("incidents"); err != nil {
+		t.Fatalf("RemovePage failed: %v", err)
+	}
+	var attachments int
+	if err := index.withDBRead(func(db *sql.DB) error {
+		return db.QueryRow(`SELECT COUNT(*) FROM attachments`).Scan(&attachments)
+	}); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if attachments != 0 {
+		t.Errorf("expected the attachments to be removed, got %d", attachments)
+	}
+}
+
+func TestSQLiteIndex_ReplaceAttachments(t *testing.T) {
+	index, err := NewSQLiteIndex(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer test_utils.WrapCloseWithErrorCheck(index.Close, t)
+
+	if err := index.IndexPage("page", "page.md", "page", "Page", tree.NodeKindPage, "Body"); err != nil {
+		t.Fatalf("IndexPage failed: %v", err)
+	}
+	if err := index.IndexAttachment("page", "old.txt", "stale words"); err != nil {
+		t.Fatalf("IndexAttachment failed: %v", err)
+	}
+	if err := index.ReplaceAttachments("page", map[string]string{"new.txt": "fresh words"}, true); err != nil {
+		t.Fatalf("ReplaceAttachments failed: %v", err)
+	}
+
+	result, err := index.Search("words", nil, 0, 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(result.Attachments) != 1 || result.Attachments[0].Filename != "new.txt" {
+		t.Errorf("expected only the new attachment, got %+v", result.Attachments)
+	}
+}
@@ -0,0 +1,337 @@
+package search
+
+import (
+	"database/sql"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Spelling tolerance
+//
+// Queries with fewer than fuzzyHitThreshold exact hits are expanded with
+// spelling corrections: every word of the query that is unknown or rare in
+// the index is joined by OR with up to maxCorrectionsPerWord close terms
+// from the vocabulary of the pages table. Hits of the expanded query that
+// are not exact hits follow the exact hits, and the best correction of each
+// word makes up the "did you mean" suggestion.
+//
+// Candidates are looked up in search_terms, a trigram index of the terms in
+// pages_vocab (an fts5vocab view of the pages table). search_terms is brought
+// up to date lazily, the first time it is needed after the pages changed.
+
+const (
+	// fuzzyHitThreshold is the number of exact hits below which a query is
+	// expanded with spelling corrections.
+	fuzzyHitThreshold = 3
+	// minFuzzyWordLength is the length of the shortest word that is
+	// corrected; shorter words have too many close neighbours.
+	minFuzzyWordLength = 4
+	// maxCorrectionsPerWord limits the terms a word is expanded with.
+	maxCorrectionsPerWord = 3
+	// fuzzyCandidateLimit is the number of vocabulary terms sharing
+	// trigrams with a word that are compared with it.
+	fuzzyCandidateLimit = 200
+	// fuzzyRankFactor scales the rank of fuzzy hits.
+	fuzzyRankFactor = 0.5
+)
+
+// vocabColumns restricts pages_vocab to the text columns.
+const vocabColumns = "col IN ('title', 'headings', 'content')"
+
+// maxEditsFor returns how many edits a correction of word may need.
+func maxEditsFor(word string) int {
+	if utf8.RuneCountInString(word) <= 5 {
+		return 1
+	}
+	return 2
+}
+
+// refreshTerms brings search_terms up to date with pages_vocab.
+func (s *SQLiteIndex) refreshTerms() error {
+	return s.withDB(func(db *sql.DB) error {
+		if !s.termsStale {
+			return nil
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = tx.Rollback()
+		}()
+		if _, err := tx.Exec(`
+			DELETE FROM search_terms
+			WHERE term NOT IN (SELECT term FROM pages_vocab WHERE ` + vocabColumns + `);
+		`); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO search_terms (term)
+			SELECT DISTINCT term FROM pages_vocab
+			WHERE ` + vocabColumns + `
+				AND length(term) >= 3
+				AND term NOT IN (SELECT term FROM search_terms);
+		`); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		s.termsStale = false
+		return nil
+	})
+}
+
+// fuzzySearch is the part of a search contributed by spelling corrections.
+type fuzzySearch struct {
+	// query is the query with its corrections.
+	query      *Query
+	where      string
+	args       []interface{}
+	suggestion string
+}
+
+// fuzzyExpansion returns the fuzzy part of a search whose exact part matches
+// exactWhere with exactCount hits, or nil when the query is not expanded.
+// The fuzzy part leaves out the exact hits.
+func (s *SQLiteIndex) fuzzyExpansion(q *Query, scope SearchScope, stems stemFunc, exactWhere string, exactArgs []interface{}, exactCount int) (*fuzzySearch, error) {
+	if !q.HasText() || exactCount >= fuzzyHitThreshold {
+		return nil, nil
+	}
+	corrections, err := s.spellingCorrections(q)
+	if err != nil || len(corrections) == 0 {
+		return nil, err
+	}
+	expanded := expandQuery(q, corrections)
+	where, args := buildSearchWhereClause(expanded, scope, stems)
+	return &fuzzySearch{
+		query:      expanded,
+		where:      where + " AND pageID NOT IN (SELECT pageID FROM pages WHERE " + exactWhere + ")",
+		args:       append(args, exactArgs...),
+		suggestion: suggestion(q, corrections),
+	}, nil
+}
+
+// termPosition identifies a term of a query by clause and term index.
+type termPosition struct {
+	clause, term int
+}
+
+// spellingCorrections returns close vocabulary terms for the words of q
+// that are unknown or rarer than their corrections, best first.
+func (s *SQLiteIndex) spellingCorrections(q *Query) (map[termPosition][]string, error) {
+	type word struct {
+		at   termPosition
+		text string
+	}
+	var words []word
+	for i, c := range q.Clauses {
+		if c.Negated {
+			continue
+		}
+		for j, t := range c.Terms {
+			if t.Phrase || utf8.RuneCountInString(t.Text) < minFuzzyWordLength {
+				continue
+			}
+			words = append(words, word{at: termPosition{i, j}, text: strings.ToLower(t.Text)})
+		}
+	}
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	if err := s.refreshTerms(); err != nil {
+		return nil, err
+	}
+
+	corrections := make(map[termPosition][]string)
+	err := s.withDBRead(func(db *sql.DB) error {
+		for _, w := range words {
+			terms, err := correctWord(db, w.text)
+			if err != nil {
+				return err
+			}
+			if len(terms) > 0 {
+				corrections[w.at] = terms
+			}
+		}
+		return nil
+	})
+	return corrections, err
+}
+
+func correctWord(db *sql.DB, word string) ([]string, error) {
+	grams := trigrams(word)
+	if len(grams) == 0 {
+		return nil, nil
+	}
+	wordDocs, err := prefixDocCount(db, word)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]string, len(grams))
+	for i, g := range grams {
+		parts[i] = `"` + strings.ReplaceAll(g, `"`, `""`) + `"`
+	}
+	rows, err := db.Query(`
+		SELECT term FROM search_terms
+		WHERE search_terms MATCH ?
+		ORDER BY rank
+		LIMIT ?;
+	`, strings.Join(parts, " OR "), fuzzyCandidateLimit)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []string
+	for rows.Next() {
+		var term string
+		if err := rows.Scan(&term); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		candidates = append(candidates, term)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	type match struct {
+		term     string
+		distance int
+		docs     int
+	}
+	maxEdits := maxEditsFor(word)
+	var matches []match
+	for _, term := range candidates {
+		if strings.HasPrefix(term, word) {
+			continue
+		}
+		distance := editDistance(word, term)
+		if distance > maxEdits {
+			continue
+		}
+		var docs int
+		if err := db.QueryRow(`SELECT COALESCE(SUM(doc), 0) FROM pages_vocab WHERE term = ? AND `+vocabColumns, term).Scan(&docs); err != nil {
+			return nil, err
+		}
+		if docs <= wordDocs {
+			continue
+		}
+		matches = append(matches, match{term: term, distance: distance, docs: docs})
+	}
+
+	slices.SortFunc(matches, func(a, b match) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		if a.docs != b.docs {
+			return b.docs - a.docs
+		}
+		return strings.Compare(a.term, b.term)
+	})
+	var result []string
+	for _, m := range matches[:min(len(matches), maxCorrectionsPerWord)] {
+		result = append(result, m.term)
+	}
+	return result, nil
+}
+
+// prefixDocCount estimates how many pages contain a term starting with
+// prefix.
+func prefixDocCount(db *sql.DB, prefix string) (int, error) {
+	var docs int
+	err := db.QueryRow(`
+		SELECT COALESCE(SUM(doc), 0) FROM pages_vocab
+		WHERE term >= ? AND term < ? AND `+vocabColumns,
+		prefix, prefix+string(utf8.MaxRune),
+	).Scan(&docs)
+	return docs, err
+}
+
+// expandQuery returns q with every corrected word joined by OR with its
+// corrections.
+func expandQuery(q *Query, corrections map[termPosition][]string) *Query {
+	expanded := &Query{Filters: q.Filters, source: q.source}
+	for i, c := range q.Clauses {
+		clause := QueryClause{Negated: c.Negated}
+		for j, t := range c.Terms {
+			clause.Terms = append(clause.Terms, t)
+			for _, term := range corrections[termPosition{i, j}] {
+				clause.Terms = append(clause.Terms, QueryTerm{Field: t.Field, Text: term, Phrase: true, Pos: t.Pos})
+			}
+		}
+		expanded.Clauses = append(expanded.Clauses, clause)
+	}
+	return expanded
+}
+
+// suggestion returns the query as written with every corrected word
+// replaced by its best correction.
+func suggestion(q *Query, corrections map[termPosition][]string) string {
+	type replacement struct {
+		pos, end int
+		text     string
+	}
+	var replacements []replacement
+	for at, terms := range corrections {
+		t := q.Clauses[at.clause].Terms[at.term]
+		replacements = append(replacements, replacement{pos: t.Pos, end: t.Pos + utf8.RuneCountInString(t.Text), text: terms[0]})
+	}
+	if len(replacements) == 0 {
+		return ""
+	}
+	slices.SortFunc(replacements, func(a, b replacement) int { return b.pos - a.pos })
+
+	out := slices.Clone(q.source)
+	for _, r := range replacements {
+		if r.pos < 0 || r.end > len(out) {
+			continue
+		}
+		out = slices.Concat(out[:r.pos], []rune(r.text), out[r.end:])
+	}
+	return string(out)
+}
+
+// trigrams returns the distinct three-character substrings of word.
+func trigrams(word string) []string {
+	runes := []rune(word)
+	var grams []string
+	for i := 0; i+3 <= len(runes); i++ {
+		gram := string(runes[i : i+3])
+		if !slices.Contains(grams, gram) {
+			grams = append(grams, gram)
+		}
+	}
+	return grams
+}
+
+// editDistance returns the optimal string alignment distance between a and
+// b: the number of insertions, deletions, substitutions and transpositions
+// of adjacent characters needed to turn a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// prev2, prev and cur are the last three rows of the distance matrix.
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
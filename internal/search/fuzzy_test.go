@@ -0,0 +1,189 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/perber/wiki/internal/core/tree"
+	"github.com/perber/wiki/internal/test_utils"
+)
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"kubernets", "kubernetes", 1},
+		{"postgress", "postgres", 1},
+		{"teh", "the", 1},
+		{"recieve", "receive", 1},
+		{"deploy", "deploy", 0},
+		{"grüße", "grusse", 3},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+	}
+	for _, tc := range cases {
+		if got := editDistance(tc.a, tc.b); got != tc.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func newFuzzyTestIndex(t *testing.T, pages map[string]string) *SQLiteIndex {
+	t.Helper()
+	index, err := NewSQLiteIndex(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	t.Cleanup(func() { test_utils.WrapCloseWithErrorCheck(index.Close, t) })
+	for id, content := range pages {
+		if err := index.IndexPage("docs/"+id, "docs/"+id+".md", id, id, tree.NodeKindPage, content); err != nil {
+			t.Fatalf("IndexPage %s failed: %v", id, err)
+		}
+	}
+	return index
+}
+
+func TestSQLiteIndex_Search_ExpandsMisspelledWords(t *testing.T) {
+	index := newFuzzyTestIndex(t, map[string]string{
+		"cluster":  "Running Kubernetes in production.",
+		"operator": "A Kubernetes operator for Postgres.",
+		"backup":   "Backing up Postgres databases.",
+	})
+
+	result, err := index.Search("kubernets", nil, 0, 10)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if result.Count != 2 || len(result.Items) != 2 {
+		t.Fatalf("expected both kubernetes pages as fuzzy hits, got count=%d items=%+v", result.Count, result.Items)
+	}
+	for _, item := range result.Items {
+		if !item.Fuzzy {
+			t.Errorf("expected %s to be marked as a fuzzy hit", item.PageID)
+		}
+	}
+	if result.Suggestion != "kubernetes" {
+		t.Errorf("suggestion = %q, want %q", result.Suggestion, "kubernetes")
+	}
+
+	result, err = index.Search(`title:operator postgress -"kubernets"`, nil, 0, 10)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if result.Count != 1 || result.Items[0].PageID != "operator" {
+		t.Fatalf("expected the operator page, got count=%d items=%+v", result.Count, result.Items)
+	}
+	if want := `title:operator postgres -"kubernets"`; result.Suggestion != want {
+		t.Errorf("suggestion = %q, want %q", result.Suggestion, want)
+	}
+}
+
+func TestSQLiteIndex_Search_RanksExactHitsAboveFuzzyHits(t *testing.T) {
+	index := newFuzzyTestIndex(t, map[string]string{
+		"typo":   "Notes about kubernets, spelled wrong.",
+		"first":  "Kubernetes networking.",
+		"second": "Kubernetes storage.",
+	})
+
+	result, err := index.Search("kubernets", nil, 0, 10)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if result.Count != 3 || len(result.Items) != 3 {
+		t.Fatalf("expected one exact and two fuzzy hits, got count=%d items=%+v", result.Count, result.Items)
+	}
+	if result.Items[0].PageID != "typo" || result.Items[0].Fuzzy {
+		t.Fatalf("expected the exact hit first, got %+v", result.Items[0])
+	}
+	for _, item := range result.Items[1:] {
+		if !item.Fuzzy || item.Rank >= result.Items[0].Rank {
+			t.Errorf("expected fuzzy hit %s to rank below the exact hit, got %+v", item.PageID, item)
+		}
+	}
+
+	// Paging continues from the exact hits into the fuzzy ones.
+	page, err := index.Search("kubernets", nil, 1, 1)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if page.Count != 3 || len(page.Items) != 1 || !page.Items[0].Fuzzy {
+		t.Fatalf("expected the first fuzzy hit on the second page, got count=%d items=%+v", page.Count, page.Items)
+	}
+	ids, err := index.SearchPageIDs("kubernets", nil)
+	if err != nil {
+		t.Fatalf("SearchPageIDs failed: %v", err)
+	}
+	if len(ids) != 3 || ids[0] != "typo" {
+		t.Fatalf("expected the exact hit first among all page IDs, got %v", ids)
+	}
+
+	q, err := ParseQuery("kubernets")
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+	exact, err := index.ExactQueryPageIDs(q, SearchScope{})
+	if err != nil {
+		t.Fatalf("ExactQueryPageIDs failed: %v", err)
+	}
+	if len(exact) != 1 || exact[0] != "typo" {
+		t.Fatalf("expected only the exact hit, got %v", exact)
+	}
+}
+
+func TestSQLiteIndex_Search_DoesNotExpandQueriesWithEnoughHits(t *testing.T) {
+	index := newFuzzyTestIndex(t, map[string]string{
+		"one":   "deploy",
+		"two":   "deploy",
+		"three": "deploy",
+		"other": "deplay",
+	})
+
+	result, err := index.Search("deploy", nil, 0, 10)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if result.Count != 3 || result.Suggestion != "" {
+		t.Fatalf("expected only the exact hits, got count=%d suggestion=%q", result.Count, result.Suggestion)
+	}
+	for _, item := range result.Items {
+		if item.Fuzzy {
+			t.Fatalf("unexpected fuzzy hit %+v", item)
+		}
+	}
+}
+
+func TestSQLiteIndex_Search_VocabularyFollowsIndexChanges(t *testing.T) {
+	index := newFuzzyTestIndex(t, map[string]string{
+		"cluster": "Kubernetes cluster.",
+	})
+
+	result, err := index.Search("kubernets", nil, 0, 10)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if result.Suggestion != "kubernetes" {
+		t.Fatalf("suggestion = %q, want %q", result.Suggestion, "kubernetes")
+	}
+
+	if err := index.RemovePage("cluster"); err != nil {
+		t.Fatalf("RemovePage failed: %v", err)
+	}
+	if err := index.IndexPage("docs/db", "docs/db.md", "db", "Database", tree.NodeKindPage, "Postgres tuning."); err != nil {
+		t.Fatalf("IndexPage failed: %v", err)
+	}
+
+	result, err = index.Search("kubernets", nil, 0, 10)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if result.Count != 0 || result.Suggestion != "" {
+		t.Fatalf("expected the removed term to be forgotten, got count=%d suggestion=%q", result.Count, result.Suggestion)
+	}
+	result, err = index.Search("postgress", nil, 0, 10)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if result.Count != 1 || result.Suggestion != "postgres" {
+		t.Fatalf("expected the new term to be suggested, got count=%d suggestion=%q", result.Count, result.Suggestion)
+	}
+}
@@ -0,0 +1,145 @@
+package search
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/perber/wiki/internal/core/excerpt"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+	"golang.org/x/text/unicode/norm"
+)
+
+// headingSection is the text below one heading of a page, up to the next
+// heading.
+type headingSection struct {
+	heading string
+	// anchor is the id the preview gives the heading, see headingSlug.
+	anchor  string
+	content string
+}
+
+// extractHeadingSections splits a markdown body at its top-level headings.
+// Text before the first heading and headings without an anchor are not
+// sections of their own; their text stays with the preceding section.
+func extractHeadingSections(src string) []headingSection {
+	srcBytes := []byte(src)
+	doc := headingParser.Parser().Parse(text.NewReader(srcBytes))
+
+	type boundary struct {
+		start, end int
+		heading    string
+		anchor     string
+	}
+	var boundaries []boundary
+	slugs := map[string]int{}
+
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		h, ok := n.(*ast.Heading)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+		heading := headingText(h, srcBytes)
+		anchor := headingSlug(heading)
+		if anchor != "" {
+			// Duplicate headings get the suffixes the preview gives them.
+			count := slugs[anchor]
+			slugs[anchor] = count + 1
+			if count > 0 {
+				anchor += "-" + strconv.Itoa(count)
+			}
+		}
+		if anchor != "" && h.Parent() == doc && h.Lines().Len() > 0 {
+			start, end := headingLineRange(h, srcBytes)
+			boundaries = append(boundaries, boundary{start: start, end: end, heading: strings.TrimSpace(heading), anchor: anchor})
+		}
+		return ast.WalkSkipChildren, nil
+	})
+
+	sections := make([]headingSection, 0, len(boundaries))
+	for i, b := range boundaries {
+		end := len(srcBytes)
+		if i+1 < len(boundaries) {
+			end = boundaries[i+1].start
+		}
+		sections = append(sections, headingSection{
+			heading: b.heading,
+			anchor:  b.anchor,
+			content: excerpt.PlainTextForSearch(string(srcBytes[b.end:end])),
+		})
+	}
+	return sections
+}
+
+// headingText returns the text of a heading the way the preview reads it
+// for the heading's id: its text nodes joined as they are.
+func headingText(h *ast.Heading, src []byte) string {
+	var buf bytes.Buffer
+	_ = ast.Walk(h, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if t, ok := n.(*ast.Text); ok {
+			buf.Write(t.Segment.Value(src))
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				buf.WriteByte(' ')
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return buf.String()
+}
+
+// headingLineRange returns the byte range of the lines of a heading,
+// including the underline of a setext heading.
+func headingLineRange(h *ast.Heading, src []byte) (int, int) {
+	lines := h.Lines()
+	start := bytes.LastIndexByte(src[:lines.At(0).Start], '\n') + 1
+	end := nextLine(src, max(start, lines.At(lines.Len()-1).Stop-1))
+	if !isATXHeading(src[start:]) {
+		end = nextLine(src, end)
+	}
+	return start, end
+}
+
+// nextLine returns the start of the line after the one pos is on.
+func nextLine(src []byte, pos int) int {
+	if i := bytes.IndexByte(src[pos:], '\n'); i >= 0 {
+		return pos + i + 1
+	}
+	return len(src)
+}
+
+func isATXHeading(line []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(line, " \t"), []byte("#"))
+}
+
+var headingSlugReplacer = strings.NewReplacer("ß", "ss", "ẞ", "ss")
+
+// headingSlug returns the id the preview gives a heading with the given
+// text (slugifyHeadline in the UI): letters and digits lower-cased and
+// stripped of accents, with runs of spaces and dashes turned into single
+// dashes and everything else left out.
+func headingSlug(heading string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(norm.NFKD.String(headingSlugReplacer.Replace(heading))) {
+		switch {
+		case unicode.Is(unicode.M, r):
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-':
+			dash = true
+		}
+	}
+	return b.String()
+}
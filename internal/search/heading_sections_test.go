@@ -0,0 +1,158 @@
+package search
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/perber/wiki/internal/core/tree"
+	"github.com/perber/wiki/internal/test_utils"
+)
+
+func TestHeadingSlug(t *testing.T) {
+	cases := map[string]string{
+		"Getting Started":           "getting-started",
+		"  Größe & Gewicht  ":       "grosse-gewicht",
+		"Café — déjà vu":            "cafe-deja-vu",
+		"snake_case and kebab--x":   "snakecase-and-kebab-x",
+		"v1.2: What's new?":         "v12-whats-new",
+		"!!!":                       "",
+		"Übersicht der Zertifikate": "ubersicht-der-zertifikate",
+	}
+	for heading, want := range cases {
+		if got := headingSlug(heading); got != want {
+			t.Errorf("headingSlug(%q) = %q, want %q", heading, got, want)
+		}
+	}
+}
+
+func TestExtractHeadingSections(t *testing.T) {
+	src := strings.Join([]string{
+		"Intro text.",
+		"",
+		"# Setup",
+		"Install the *agent*.",
+		"",
+		"## Setup",
+		"",
+		"> ### Quoted heading",
+		"> stays in the section",
+		"",
+		"Upgrade Notes",
+		"-------------",
+		"Drain the node first.",
+		"",
+		"## !!!",
+		"No anchor.",
+	}, "\n")
+
+	got := extractHeadingSections(src)
+	want := []headingSection{
+		{heading: "Setup", anchor: "setup", content: "Install the agent."},
+		{heading: "Setup", anchor: "setup-1", content: "Quoted heading stays in the section"},
+		{heading: "Upgrade Notes", anchor: "upgrade-notes", content: "Drain the node first. !!! No anchor."},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d sections, got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i].heading != want[i].heading || got[i].anchor != want[i].anchor {
+			t.Errorf("section %d = %q #%s, want %q #%s", i, got[i].heading, got[i].anchor, want[i].heading, want[i].anchor)
+		}
+		if strings.Join(strings.Fields(got[i].content), " ") != want[i].content {
+			t.Errorf("section %d content = %q, want %q", i, got[i].content, want[i].content)
+		}
+	}
+}
+
+func TestQuery_FTSSectionMatch(t *testing.T) {
+	q, err := ParseQuery(`title:runbook drain -legacy`)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+	want := `(title : "runbook"* AND {title headings content} : "drain"*) AND ({headings content} : "drain"*)`
+	if got := q.ftsSectionMatch(nil); got != want {
+		t.Errorf("ftsSectionMatch() = %s, want %s", got, want)
+	}
+
+	q, err = ParseQuery(`title:runbook`)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+	if got := q.ftsSectionMatch(nil); got != "" {
+		t.Errorf("expected no section match for title terms only, got %s", got)
+	}
+}
+
+func TestSQLiteIndex_Search_ReturnsHeadingHits(t *testing.T) {
+	index, err := NewSQLiteIndex(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer test_utils.WrapCloseWithErrorCheck(index.Close, t)
+
+	runbook := strings.Join([]string{
+		"Operational notes for the cluster.",
+		"## Backups",
+		"Snapshots run nightly.",
+		"## Node Upgrade",
+		"Drain the node, then upgrade the kubelet.",
+		"## Troubleshooting",
+		"If the upgrade fails, drain again.",
+	}, "\n")
+	if err := index.IndexPage("ops/runbook", "ops/runbook.md", "runbook", "Cluster Runbook", tree.NodeKindPage, runbook); err != nil {
+		t.Fatalf("IndexPage failed: %v", err)
+	}
+	if err := index.IndexPage("ops/plain", "ops/plain.md", "plain", "Plain", tree.NodeKindPage, "Upgrade without headings."); err != nil {
+		t.Fatalf("IndexPage failed: %v", err)
+	}
+
+	result, err := index.Search("upgrade", nil, 0, 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if result.Count != 2 {
+		t.Fatalf("expected 2 hits, got %+v", result.Items)
+	}
+	hits := map[string][]SearchHeadingHit{}
+	for _, item := range result.Items {
+		hits[item.PageID] = item.Headings
+	}
+	if len(hits["plain"]) != 0 {
+		t.Errorf("expected no heading hits for a page without headings, got %+v", hits["plain"])
+	}
+	var anchors []string
+	for _, h := range hits["runbook"] {
+		anchors = append(anchors, h.Anchor)
+	}
+	if !reflect.DeepEqual(anchors, []string{"node-upgrade", "troubleshooting"}) {
+		t.Fatalf("expected the matching sections best first, got %+v", hits["runbook"])
+	}
+	first := hits["runbook"][0]
+	if first.Heading != "Node <b>Upgrade</b>" || !strings.Contains(first.Excerpt, "<b>upgrade</b>") {
+		t.Errorf("expected highlighted heading and excerpt, got %+v", first)
+	}
+
+	// Terms matched only by the page title don't select sections.
+	result, err = index.Search("cluster snapshots", nil, 0, 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(result.Items) != 1 || len(result.Items[0].Headings) != 1 || result.Items[0].Headings[0].Anchor != "backups" {
+		t.Fatalf("expected only the backups section, got %+v", result.Items)
+	}
+
+	if err := index.RemovePage("runbook"); err != nil {
+		t.Fatalf("RemovePage failed: %v", err)
+	}
+	var sections int
+	if err := index.withDBRead(func(db *sql.DB) error {
+		return db.QueryRow(`SELECT COUNT(*) FROM heading_sections`).Scan(&sections)
+	}); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if sections != 0 {
+		t.Errorf("expected the sections to be removed with the page, got %d", sections)
+	}
+}
@@ -0,0 +1,604 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/perber/wiki/internal/core/excerpt"
+	"github.com/perber/wiki/internal/core/shared/sqliteutil"
+)
+
+// historySchemaVersion is stored as the history database's user_version.
+// Bump it whenever the tables change; the index is then recreated empty.
+const historySchemaVersion = 1
+
+// HistoryIndexFilename is the name of the history index database in the
+// storage directory.
+const HistoryIndexFilename = "search_history.db"
+
+// HistoryIndex is a full-text index of the revisions of pages. Revisions
+// of a page with the same content share one indexed text, so a search
+// lists each version of a page's text once.
+type HistoryIndex struct {
+	mu        sync.RWMutex
+	dbPath    string
+	db        *sql.DB
+	recreated bool
+}
+
+// HistoryRevision is a revision as it is indexed.
+type HistoryRevision struct {
+	PageID      string
+	RevisionID  string
+	ContentHash string
+	Title       string
+	Path        string
+	AuthorID    string
+	AuthorName  string
+	CreatedAt   time.Time
+	// Content is the page's Markdown without frontmatter.
+	Content string
+}
+
+// HistorySearchOptions selects the versions a history search looks at.
+type HistorySearchOptions struct {
+	// From and To limit the search to the versions that were current at
+	// some time between them; either may be zero for an open end. Without
+	// both, only the current version of each page is searched.
+	From, To time.Time
+	// AnyVersion searches every stored version, ignoring From and To.
+	AnyVersion bool
+	// AuthorIDs maps the values of author: filters to the IDs of the users
+	// they name. Revisions also match by author ID and by the name of
+	// imported authors.
+	AuthorIDs map[string][]string
+	// Now is the time used without a range; zero means time.Now.
+	Now time.Time
+}
+
+// HistorySearchResult is a page of history search hits.
+type HistorySearchResult struct {
+	Limit  int                `json:"limit"`
+	Offset int                `json:"offset"`
+	Count  int                `json:"count"`
+	Items  []HistorySearchHit `json:"items"`
+}
+
+// HistorySearchHit is a version of a page whose text matches a query. It
+// names the oldest matching revision with that text.
+type HistorySearchHit struct {
+	PageID     string `json:"page_id"`
+	RevisionID string `json:"revision_id"`
+	// Title and Path are those of the page at the time of the revision.
+	Title      string    `json:"title"`
+	Path       string    `json:"path"`
+	AuthorID   string    `json:"author_id,omitempty"`
+	AuthorName string    `json:"author_name,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	Excerpt    string    `json:"excerpt"`
+	Rank       float64   `json:"rank"`
+}
+
+func NewHistoryIndex(storageDir string) (*HistoryIndex, error) {
+	h := &HistoryIndex{dbPath: searchIndexDatabasePath(storageDir, HistoryIndexFilename)}
+	err := sqliteutil.RetryOnCorruption(h.dbPath, func() error {
+		if err := h.ensureSchema(); err != nil {
+			if closeErr := h.Close(); closeErr != nil {
+				slog.Default().Warn("failed to close corrupt history database before recovery", "error", closeErr)
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// RemoveHistoryIndex deletes the history index database in storageDir, so
+// that it is filled from scratch when it is used again.
+func RemoveHistoryIndex(storageDir string) {
+	sqliteutil.RemoveSQLiteFiles(searchIndexDatabasePath(storageDir, HistoryIndexFilename))
+}
+
+func (h *HistoryIndex) connect() (*sql.DB, error) {
+	h.mu.RLock()
+	if h.db != nil {
+		db := h.db
+		h.mu.RUnlock()
+		return db, nil
+	}
+	h.mu.RUnlock()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.db == nil {
+		db, err := sql.Open("sqlite", h.dbPath+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+		if err != nil {
+			return nil, err
+		}
+		h.db = db
+	}
+	return h.db, nil
+}
+
+// withDB runs fn under the exclusive lock — use for anything that writes.
+func (h *HistoryIndex) withDB(fn func(db *sql.DB) error) error {
+	db, err := h.connect()
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return fn(db)
+}
+
+// withDBRead runs fn under a shared read lock.
+func (h *HistoryIndex) withDBRead(fn func(db *sql.DB) error) error {
+	db, err := h.connect()
+	if err != nil {
+		return err
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return fn(db)
+}
+
+func (h *HistoryIndex) ensureSchema() error {
+	return h.withDB(func(db *sql.DB) error {
+		var version int
+		if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+			return err
+		}
+		if version == historySchemaVersion {
+			return nil
+		}
+		h.recreated = true
+		_, err := db.Exec(`
+			DROP TABLE IF EXISTS history_revisions;
+			DROP TABLE IF EXISTS history_texts;
+			-- One row per distinct content of a page. The columns are named
+			-- like those of the pages table so that both take the same MATCH
+			-- expressions.
+			CREATE VIRTUAL TABLE history_texts USING fts5(
+				title,
+				headings,
+				content,
+				tokenize = "unicode61 remove_diacritics 2 tokenchars '-_/+#.'"
+			);
+			-- One row per revision; textID is the rowid of its text.
+			CREATE TABLE history_revisions (
+				pageID TEXT NOT NULL,
+				revisionID TEXT NOT NULL,
+				textID INTEGER NOT NULL,
+				contentHash TEXT NOT NULL,
+				title TEXT NOT NULL,
+				path TEXT NOT NULL,
+				authorID TEXT NOT NULL,
+				authorName TEXT NOT NULL,
+				createdAt INTEGER NOT NULL,
+				PRIMARY KEY (pageID, revisionID)
+			);
+			CREATE INDEX history_revisions_content ON history_revisions (pageID, contentHash);
+			CREATE INDEX history_revisions_text ON history_revisions (textID);
+			PRAGMA user_version = ` + strconv.Itoa(historySchemaVersion) + `;
+		`)
+		return err
+	})
+}
+
+// Recreated reports whether opening the index created it empty.
+func (h *HistoryIndex) Recreated() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.recreated
+}
+
+func (h *HistoryIndex) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.db != nil {
+		err := h.db.Close()
+		h.db = nil
+		return err
+	}
+	return nil
+}
+
+// Clear removes all revisions.
+func (h *HistoryIndex) Clear() error {
+	return h.withDB(func(db *sql.DB) error {
+		_, err := db.Exec(`DELETE FROM history_revisions; DELETE FROM history_texts;`)
+		return err
+	})
+}
+
+// IndexRevision adds a revision, or updates it when it is indexed already.
+func (h *HistoryIndex) IndexRevision(rev HistoryRevision) error {
+	body := excerpt.NormalizeMarkdownBody(rev.Content)
+	headings := extractHeadings(body)
+	text := excerpt.PlainTextForSearch(body)
+
+	return h.withDB(func(db *sql.DB) error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = tx.Rollback()
+		}()
+
+		var oldTextID int64
+		var oldHash string
+		err = tx.QueryRow(`SELECT textID, contentHash FROM history_revisions WHERE pageID = ? AND revisionID = ?`,
+			rev.PageID, rev.RevisionID).Scan(&oldTextID, &oldHash)
+		found := err == nil
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if found {
+			if _, err := tx.Exec(`DELETE FROM history_revisions WHERE pageID = ? AND revisionID = ?`, rev.PageID, rev.RevisionID); err != nil {
+				return err
+			}
+		}
+
+		textID, err := historyTextID(tx, rev, headings, text, found && oldHash == rev.ContentHash, oldTextID)
+		if err != nil {
+			return err
+		}
+		if found && oldHash != rev.ContentHash {
+			if err := removeUnusedHistoryTexts(tx, []int64{oldTextID}); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.Exec(`
+			INSERT INTO history_revisions (pageID, revisionID, textID, contentHash, title, path, authorID, authorName, createdAt)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, rev.PageID, rev.RevisionID, textID, rev.ContentHash, rev.Title, strings.TrimPrefix(rev.Path, "/"),
+			rev.AuthorID, rev.AuthorName, rev.CreatedAt.UnixNano()); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+// historyTextID returns the text of rev: oldTextID when it is unchanged,
+// the text of another revision of the page with the same content, or a new
+// text.
+func historyTextID(tx *sql.Tx, rev HistoryRevision, headings, text string, unchanged bool, oldTextID int64) (int64, error) {
+	if unchanged {
+		return oldTextID, nil
+	}
+	var textID int64
+	err := tx.QueryRow(`SELECT textID FROM history_revisions WHERE pageID = ? AND contentHash = ? LIMIT 1`,
+		rev.PageID, rev.ContentHash).Scan(&textID)
+	if err != sql.ErrNoRows {
+		return textID, err
+	}
+	res, err := tx.Exec(`INSERT INTO history_texts (title, headings, content) VALUES (?, ?, ?)`, rev.Title, headings, text)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// RemoveRevisions removes revisions of a page; nil revisionIDs removes all
+// of them.
+func (h *HistoryIndex) RemoveRevisions(pageID string, revisionIDs []string) error {
+	if revisionIDs != nil && len(revisionIDs) == 0 {
+		return nil
+	}
+	return h.withDB(func(db *sql.DB) error {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = tx.Rollback()
+		}()
+
+		where := `pageID = ?`
+		args := []interface{}{pageID}
+		if revisionIDs != nil {
+			where += fmt.Sprintf(` AND revisionID IN (%s)`, placeholders(len(revisionIDs)))
+			for _, id := range revisionIDs {
+				args = append(args, id)
+			}
+		}
+		rows, err := tx.Query(`SELECT DISTINCT textID FROM history_revisions WHERE `+where, args...)
+		if err != nil {
+			return err
+		}
+		var textIDs []int64
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				_ = rows.Close()
+				return err
+			}
+			textIDs = append(textIDs, id)
+		}
+		if err := rows.Close(); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM history_revisions WHERE `+where, args...); err != nil {
+			return err
+		}
+		if err := removeUnusedHistoryTexts(tx, textIDs); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+// removeUnusedHistoryTexts removes those of textIDs that no revision uses.
+func removeUnusedHistoryTexts(tx *sql.Tx, textIDs []int64) error {
+	for _, id := range textIDs {
+		if _, err := tx.Exec(`
+			DELETE FROM history_texts
+			WHERE rowid = ? AND NOT EXISTS (SELECT 1 FROM history_revisions WHERE textID = ?)
+		`, id, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AuthorIDs returns the IDs of the authors of indexed revisions.
+func (h *HistoryIndex) AuthorIDs() ([]string, error) {
+	var ids []string
+	err := h.withDBRead(func(db *sql.DB) error {
+		rows, err := db.Query(`SELECT DISTINCT authorID FROM history_revisions WHERE authorID != ''`)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := rows.Close(); err != nil {
+				slog.Default().Error("could not close rows", "error", err)
+			}
+		}()
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		return rows.Err()
+	})
+	return ids, err
+}
+
+// Search returns the versions of pages in scope that match q, best first.
+// Path filters apply to the path of a page at the time of a revision and
+// updated: filters to the time of the revision; the caller resolves tags
+// and properties into scope.
+func (h *HistoryIndex) Search(q *Query, scope SearchScope, opts HistorySearchOptions, offset, limit int) (*HistorySearchResult, error) {
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	result := &HistorySearchResult{Offset: offset, Limit: limit, Items: []HistorySearchHit{}}
+	if scope.PageIDs != nil && len(scope.PageIDs) == 0 {
+		return result, nil
+	}
+
+	// matches holds the texts that match the query's words.
+	matches := `SELECT rowid AS textID, 0.0 AS score FROM history_texts`
+	var matchClauses []string
+	var args []interface{}
+	match := q.ftsMatch(nil)
+	if match != "" {
+		matches = `SELECT rowid AS textID, bm25(history_texts, 20.0, 5.0, 1.0) AS score FROM history_texts`
+		matchClauses = append(matchClauses, "history_texts MATCH ?")
+		args = append(args, match)
+	}
+	if exclude := q.ftsExclude(nil); exclude != "" {
+		matchClauses = append(matchClauses, "rowid NOT IN (SELECT rowid FROM history_texts WHERE history_texts MATCH ?)")
+		args = append(args, exclude)
+	}
+	if len(matchClauses) > 0 {
+		matches += " WHERE " + strings.Join(matchClauses, " AND ")
+	}
+
+	where, whereArgs := historyWhereClause(q, scope, opts)
+	args = append(args, whereArgs...)
+	hits := `
+		WITH versions AS (
+			SELECT *, LEAD(createdAt) OVER (PARTITION BY pageID ORDER BY createdAt) AS validUntil
+			FROM history_revisions
+		),
+		matches AS (` + matches + `),
+		hits AS (
+			SELECT v.*, m.score, ROW_NUMBER() OVER (PARTITION BY v.textID ORDER BY v.createdAt) AS n
+			FROM versions v JOIN matches m ON m.textID = v.textID
+			WHERE ` + where + `
+		)`
+
+	err := h.withDBRead(func(db *sql.DB) error {
+		if err := db.QueryRow(hits+` SELECT COUNT(*) FROM hits WHERE n = 1`, args...).Scan(&result.Count); err != nil {
+			return err
+		}
+		if result.Count == 0 {
+			return nil
+		}
+
+		rows, err := db.Query(hits+`
+			SELECT pageID, revisionID, textID, title, path, authorID, authorName, createdAt, score
+			FROM hits WHERE n = 1
+			ORDER BY score ASC, createdAt DESC
+			LIMIT ? OFFSET ?
+		`, append(args, limit, offset)...)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := rows.Close(); err != nil {
+				slog.Default().Error("could not close rows", "error", err)
+			}
+		}()
+
+		var textIDs []int64
+		for rows.Next() {
+			var hit HistorySearchHit
+			var textID, createdAt int64
+			var score float64
+			if err := rows.Scan(&hit.PageID, &hit.RevisionID, &textID, &hit.Title, &hit.Path,
+				&hit.AuthorID, &hit.AuthorName, &createdAt, &score); err != nil {
+				return err
+			}
+			hit.Title = sanitizeSearchTitle(hit.Title)
+			hit.CreatedAt = time.Unix(0, createdAt).UTC()
+			hit.Rank = 1.0 / (1.0 + max(score, 0))
+			result.Items = append(result.Items, hit)
+			textIDs = append(textIDs, textID)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if match == "" {
+			return nil
+		}
+		return attachHistoryExcerpts(db, match, textIDs, result.Items)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// historyWhereClause returns the conditions on the versions CTE for the
+// filters of q, scope and the time range of opts.
+func historyWhereClause(q *Query, scope SearchScope, opts HistorySearchOptions) (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+
+	if !opts.AnyVersion {
+		from, to := opts.From, opts.To
+		if from.IsZero() && to.IsZero() {
+			now := opts.Now
+			if now.IsZero() {
+				now = time.Now()
+			}
+			from, to = now, now
+		}
+		if !to.IsZero() {
+			clauses = append(clauses, "v.createdAt <= ?")
+			args = append(args, to.UnixNano())
+		}
+		if !from.IsZero() {
+			clauses = append(clauses, "(v.validUntil IS NULL OR v.validUntil > ?)")
+			args = append(args, from.UnixNano())
+		}
+	}
+
+	for _, f := range q.FiltersOf(FilterPath) {
+		clause := `(v.path = ? OR v.path LIKE ? ESCAPE '\')`
+		if f.Negated {
+			clause = "NOT " + clause
+		}
+		clauses = append(clauses, clause)
+		args = append(args, f.Value, escapeLikePrefix(f.Value)+"/%")
+	}
+
+	for _, f := range q.FiltersOf(FilterAuthor) {
+		ids := opts.AuthorIDs[f.Value]
+		clause := "v.authorID = ? OR v.authorName = ? COLLATE NOCASE"
+		args = append(args, f.Value, f.Value)
+		if len(ids) > 0 {
+			clause += fmt.Sprintf(" OR v.authorID IN (%s)", placeholders(len(ids)))
+			for _, id := range ids {
+				args = append(args, id)
+			}
+		}
+		clause = "(" + clause + ")"
+		if f.Negated {
+			clause = "NOT " + clause
+		}
+		clauses = append(clauses, clause)
+	}
+
+	for _, f := range q.FiltersOf(FilterUpdated) {
+		start, end := f.Date.UnixNano(), f.Date.AddDate(0, 0, 1).UnixNano()
+		var clause string
+		switch f.Op {
+		case ">":
+			clause, args = "v.createdAt >= ?", append(args, end)
+		case ">=":
+			clause, args = "v.createdAt >= ?", append(args, start)
+		case "<":
+			clause, args = "v.createdAt < ?", append(args, start)
+		case "<=":
+			clause, args = "v.createdAt < ?", append(args, end)
+		default:
+			clause, args = "(v.createdAt >= ? AND v.createdAt < ?)", append(args, start, end)
+		}
+		if f.Negated {
+			clause = "NOT " + clause
+		}
+		clauses = append(clauses, clause)
+	}
+
+	if scope.PageIDs != nil {
+		clauses = append(clauses, fmt.Sprintf("v.pageID IN (%s)", placeholders(len(scope.PageIDs))))
+		for _, pageID := range scope.PageIDs {
+			args = append(args, pageID)
+		}
+	}
+	if len(scope.ExcludedPageIDs) > 0 {
+		clauses = append(clauses, fmt.Sprintf("v.pageID NOT IN (%s)", placeholders(len(scope.ExcludedPageIDs))))
+		for _, pageID := range scope.ExcludedPageIDs {
+			args = append(args, pageID)
+		}
+	}
+
+	if len(clauses) == 0 {
+		return "1", args
+	}
+	return strings.Join(clauses, " AND "), args
+}
+
+// attachHistoryExcerpts sets the excerpts of items, whose texts are textIDs.
+func attachHistoryExcerpts(db *sql.DB, match string, textIDs []int64, items []HistorySearchHit) error {
+	args := []interface{}{match}
+	for _, id := range textIDs {
+		args = append(args, id)
+	}
+	rows, err := db.Query(fmt.Sprintf(`
+		SELECT rowid, snippet(history_texts, 2, '<b>', '</b>', '...', 16)
+		FROM history_texts
+		WHERE history_texts MATCH ? AND rowid IN (%s)
+	`, placeholders(len(textIDs))), args...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Default().Error("could not close rows", "error", err)
+		}
+	}()
+
+	excerpts := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var excerpt string
+		if err := rows.Scan(&id, &excerpt); err != nil {
+			return err
+		}
+		excerpts[id] = excerpt
+	}
+	for i, id := range textIDs {
+		items[i].Excerpt = excerpts[id]
+	}
+	return rows.Err()
+}
@@ -0,0 +1,153 @@
+package search
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/perber/wiki/internal/test_utils"
+)
+
+func newTestHistoryIndex(t *testing.T) *HistoryIndex {
+	t.Helper()
+	index, err := NewHistoryIndex(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create HistoryIndex: %v", err)
+	}
+	t.Cleanup(func() { test_utils.WrapCloseWithErrorCheck(index.Close, t) })
+	if !index.Recreated() {
+		t.Errorf("expected a new history index to be recreated")
+	}
+	return index
+}
+
+func day(d int) time.Time {
+	return time.Date(2026, time.March, d, 12, 0, 0, 0, time.UTC)
+}
+
+func indexHistory(t *testing.T, index *HistoryIndex, revs ...HistoryRevision) {
+	t.Helper()
+	for _, rev := range revs {
+		if rev.ContentHash == "" {
+			rev.ContentHash = rev.Content
+		}
+		if err := index.IndexRevision(rev); err != nil {
+			t.Fatalf("IndexRevision(%s) failed: %v", rev.RevisionID, err)
+		}
+	}
+}
+
+func searchHistory(t *testing.T, index *HistoryIndex, query string, opts HistorySearchOptions) []string {
+	t.Helper()
+	q, err := ParseQuery(query)
+	if err != nil {
+		t.Fatalf("ParseQuery(%q) failed: %v", query, err)
+	}
+	result, err := index.Search(q, SearchScope{}, opts, 0, 20)
+	if err != nil {
+		t.Fatalf("Search(%q) failed: %v", query, err)
+	}
+	if result.Count != len(result.Items) {
+		t.Errorf("Search(%q): count %d does not match %d items", query, result.Count, len(result.Items))
+	}
+	ids := make([]string, 0, len(result.Items))
+	for _, item := range result.Items {
+		ids = append(ids, item.RevisionID)
+	}
+	return ids
+}
+
+func TestHistoryIndex_Search(t *testing.T) {
+	index := newTestHistoryIndex(t)
+	indexHistory(t, index,
+		HistoryRevision{PageID: "deploy", RevisionID: "r1", Title: "Deployment", Path: "/ops/deployment", AuthorID: "u1", CreatedAt: day(1), Content: "Deploy with **Jenkins** every Friday."},
+		HistoryRevision{PageID: "deploy", RevisionID: "r2", Title: "Deployment", Path: "/ops/deployment", AuthorID: "u1", CreatedAt: day(2), Content: "Deploy with **Jenkins** every Friday."},
+		HistoryRevision{PageID: "deploy", RevisionID: "r3", Title: "Deployment", Path: "/ops/deployment", AuthorID: "u2", CreatedAt: day(10), Content: "Deploy with ArgoCD on every merge."},
+		HistoryRevision{PageID: "notes", RevisionID: "n1", Title: "Notes", Path: "/dev/notes", AuthorName: "Jane Doe", CreatedAt: day(5), Content: "Jenkins is being replaced."},
+	)
+
+	tests := []struct {
+		name  string
+		query string
+		opts  HistorySearchOptions
+		want  []string
+	}{
+		{"current versions by default", "jenkins", HistorySearchOptions{Now: day(20)}, []string{"n1"}},
+		{"range", "jenkins", HistorySearchOptions{From: day(3), To: day(4)}, []string{"r2"}},
+		{"open range", "argocd", HistorySearchOptions{From: day(11)}, []string{"r3"}},
+		{"range before the text", "argocd", HistorySearchOptions{To: day(9)}, []string{}},
+		{"any version", "deploy", HistorySearchOptions{AnyVersion: true}, []string{"r1", "r3"}},
+		{"negation", "deploy -argocd", HistorySearchOptions{AnyVersion: true}, []string{"r1"}},
+		{"path filter", "jenkins path:ops", HistorySearchOptions{AnyVersion: true}, []string{"r1"}},
+		{"author by id", "author:u2", HistorySearchOptions{AnyVersion: true}, []string{"r3"}},
+		{"author by resolved name", "author:alice", HistorySearchOptions{AnyVersion: true, AuthorIDs: map[string][]string{"alice": {"u1"}}}, []string{"r1"}},
+		{"imported author", `author:"jane doe"`, HistorySearchOptions{AnyVersion: true}, []string{"n1"}},
+		{"updated filter", "deploy updated:>2026-03-05", HistorySearchOptions{AnyVersion: true}, []string{"r3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := searchHistory(t, index, tt.query, tt.opts)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	q, _ := ParseQuery("jenkins")
+	result, err := index.Search(q, SearchScope{PageIDs: []string{"deploy"}}, HistorySearchOptions{AnyVersion: true}, 0, 20)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(result.Items) != 1 {
+		t.Fatalf("expected one hit in scope, got %+v", result.Items)
+	}
+	hit := result.Items[0]
+	if hit.PageID != "deploy" || hit.Path != "ops/deployment" || hit.Title != "Deployment" || hit.AuthorID != "u1" || !hit.CreatedAt.Equal(day(1)) {
+		t.Errorf("unexpected hit %+v", hit)
+	}
+	if !strings.Contains(hit.Excerpt, "<b>Jenkins</b>") {
+		t.Errorf("expected a highlighted excerpt, got %q", hit.Excerpt)
+	}
+}
+
+func TestHistoryIndex_RemoveRevisions(t *testing.T) {
+	index := newTestHistoryIndex(t)
+	indexHistory(t, index,
+		HistoryRevision{PageID: "p", RevisionID: "r1", Title: "Page", CreatedAt: day(1), Content: "first draft"},
+		HistoryRevision{PageID: "p", RevisionID: "r2", Title: "Page", CreatedAt: day(2), Content: "second draft"},
+		HistoryRevision{PageID: "q", RevisionID: "s1", Title: "Other", CreatedAt: day(1), Content: "other draft"},
+	)
+
+	if err := index.RemoveRevisions("p", []string{"r1"}); err != nil {
+		t.Fatalf("RemoveRevisions failed: %v", err)
+	}
+	if got := searchHistory(t, index, "draft", HistorySearchOptions{AnyVersion: true}); strings.Join(got, ",") != "r2,s1" && strings.Join(got, ",") != "s1,r2" {
+		t.Errorf("expected the pruned revision to be gone, got %v", got)
+	}
+	if got := searchHistory(t, index, "first", HistorySearchOptions{AnyVersion: true}); len(got) != 0 {
+		t.Errorf("expected the text of the pruned revision to be gone, got %v", got)
+	}
+
+	if err := index.RemoveRevisions("p", nil); err != nil {
+		t.Fatalf("RemoveRevisions failed: %v", err)
+	}
+	if got := searchHistory(t, index, "draft", HistorySearchOptions{AnyVersion: true}); strings.Join(got, ",") != "s1" {
+		t.Errorf("expected only the other page, got %v", got)
+	}
+}
+
+func TestHistoryIndex_IndexRevision_UpdatesInPlace(t *testing.T) {
+	index := newTestHistoryIndex(t)
+	indexHistory(t, index,
+		HistoryRevision{PageID: "p", RevisionID: "r1", Title: "Page", CreatedAt: day(1), Content: "typo teh"},
+		HistoryRevision{PageID: "p", RevisionID: "r1", Title: "Page", CreatedAt: day(1), Content: "typo fixed"},
+		HistoryRevision{PageID: "p", RevisionID: "r1", Title: "Page", CreatedAt: day(1), Content: "typo fixed"},
+	)
+
+	if got := searchHistory(t, index, "teh", HistorySearchOptions{AnyVersion: true}); len(got) != 0 {
+		t.Errorf("expected the replaced text to be gone, got %v", got)
+	}
+	if got := searchHistory(t, index, "fixed", HistorySearchOptions{AnyVersion: true}); strings.Join(got, ",") != "r1" {
+		t.Errorf("expected the updated revision, got %v", got)
+	}
+}
@@ -10,6 +10,7 @@ type IndexingStatus struct {
 	Active     bool      `json:"active"`      // Indicates if indexing is currently active
 	Indexed    int       `json:"indexed"`     // Number of pages indexed
 	Failed     int       `json:"failed"`      // Number of pages that failed to index
+	Total      int       `json:"total"`       // Number of pages to index when known, 0 otherwise
 	FinishedAt time.Time `json:"finished_at"` // Timestamp when indexing finished
 }
 
@@ -27,6 +28,7 @@ func (s *IndexingStatus) Start() {
 	s.Active = true
 	s.Indexed = 0
 	s.Failed = 0
+	s.Total = 0
 	s.FinishedAt = time.Time{} // Reset finished time
 }
 
@@ -43,6 +45,14 @@ func (s *IndexingStatus) Success() {
 	s.Indexed++
 }
 
+// Progress reports that indexed of total pages are done.
+func (s *IndexingStatus) Progress(indexed, total int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Indexed = indexed
+	s.Total = total
+}
+
 func (s *IndexingStatus) Fail() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -77,6 +87,7 @@ func (s *IndexingStatus) Snapshot() *IndexingStatus {
 		Active:     s.Active,
 		Indexed:    s.Indexed,
 		Failed:     s.Failed,
+		Total:      s.Total,
 		FinishedAt: s.FinishedAt,
 	}
 }
@@ -0,0 +1,204 @@
+package pdftext
+
+type tokenKind int
+
+const (
+	tokenOperator tokenKind = iota
+	tokenNumber
+	tokenString
+	// tokenOther stands for names, dictionaries, array brackets and other
+	// operands that text extraction does not look at.
+	tokenOther
+)
+
+type token struct {
+	kind  tokenKind
+	value []byte
+}
+
+// lexer splits a content stream into operands and operators.
+type lexer struct {
+	data []byte
+	pos  int
+}
+
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func (l *lexer) next() (token, bool) {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isWhitespace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		case c == '(':
+			l.pos++
+			return token{kind: tokenString, value: l.literalString()}, true
+		case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+			l.pos += 2
+			return token{kind: tokenOther}, true
+		case c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+			l.pos += 2
+			return token{kind: tokenOther}, true
+		case c == '<':
+			l.pos++
+			return token{kind: tokenString, value: l.hexString()}, true
+		case c == '[' || c == ']' || c == '{' || c == '}' || c == ')' || c == '>':
+			l.pos++
+			return token{kind: tokenOther}, true
+		case c == '/':
+			l.pos++
+			l.word()
+			return token{kind: tokenOther}, true
+		default:
+			w := l.word()
+			if len(w) == 0 {
+				l.pos++
+				continue
+			}
+			if isNumber(w) {
+				return token{kind: tokenNumber, value: w}, true
+			}
+			if string(w) == "BI" {
+				l.skipInlineImage()
+				continue
+			}
+			return token{kind: tokenOperator, value: w}, true
+		}
+	}
+	return token{}, false
+}
+
+func (l *lexer) word() []byte {
+	start := l.pos
+	for l.pos < len(l.data) && !isWhitespace(l.data[l.pos]) && !isDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	return l.data[start:l.pos]
+}
+
+func isNumber(w []byte) bool {
+	for i, c := range w {
+		if (c < '0' || c > '9') && c != '.' && !((c == '-' || c == '+') && i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// literalString reads a (string) after its opening parenthesis.
+func (l *lexer) literalString() []byte {
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				// A backslash at the end of a line continues the string.
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					n := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						n = n*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					out = append(out, byte(n))
+				} else {
+					out = append(out, e)
+				}
+			}
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// hexString reads a <hex string> after its opening bracket.
+func (l *lexer) hexString() []byte {
+	var out []byte
+	var digit byte
+	half := false
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		var v byte
+		switch {
+		case c == '>':
+			if half {
+				out = append(out, digit<<4)
+			}
+			return out
+		case c >= '0' && c <= '9':
+			v = c - '0'
+		case c >= 'a' && c <= 'f':
+			v = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			v = c - 'A' + 10
+		default:
+			continue
+		}
+		if half {
+			out = append(out, digit<<4|v)
+		} else {
+			digit = v
+		}
+		half = !half
+	}
+	return out
+}
+
+// skipInlineImage skips the data of an inline image up to its EI operator.
+func (l *lexer) skipInlineImage() {
+	for l.pos+2 < len(l.data) {
+		if isWhitespace(l.data[l.pos]) && l.data[l.pos+1] == 'E' && l.data[l.pos+2] == 'I' &&
+			(l.pos+3 == len(l.data) || isWhitespace(l.data[l.pos+3])) {
+			l.pos += 3
+			return
+		}
+		l.pos++
+	}
+	l.pos = len(l.data)
+}
@@ -0,0 +1,297 @@
+// Package pdftext extracts the text of PDF files for the search index.
+//
+// It reads the text showing operators of the page content streams, plain or
+// Flate-compressed, and decodes their strings as WinAnsi or UTF-16. That
+// covers PDFs exported by office suites and browsers with standard fonts;
+// text drawn with embedded fonts that use their own glyph encodings is not
+// recovered, and neither is the order of text placed out of reading order.
+package pdftext
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// ErrNotPDF is returned for data that does not start like a PDF file.
+var ErrNotPDF = errors.New("pdftext: not a PDF file")
+
+// Extract returns up to maxBytes of the text of a PDF file; maxBytes <= 0
+// means no limit.
+func Extract(data []byte, maxBytes int) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF-")) {
+		return "", ErrNotPDF
+	}
+	var out strings.Builder
+	for _, content := range streams(data, maxBytes) {
+		extractText(content, &out)
+		if maxBytes > 0 && out.Len() >= maxBytes {
+			break
+		}
+	}
+	text := out.String()
+	if maxBytes > 0 && len(text) > maxBytes {
+		text = strings.ToValidUTF8(text[:maxBytes], "")
+	}
+	return strings.TrimSpace(text), nil
+}
+
+var (
+	streamKeyword    = []byte("stream")
+	endstreamKeyword = []byte("endstream")
+)
+
+// streams returns the decoded data of the streams in a PDF file that may
+// hold page content.
+func streams(data []byte, maxBytes int) [][]byte {
+	var result [][]byte
+	for pos := 0; ; {
+		i := bytes.Index(data[pos:], streamKeyword)
+		if i < 0 {
+			return result
+		}
+		start := pos + i
+		pos = start + len(streamKeyword)
+		if start >= 3 && bytes.HasSuffix(data[:start], []byte("end")) {
+			continue
+		}
+		dict := data[max(0, start-1024):start]
+		if j := bytes.LastIndex(dict, []byte("<<")); j >= 0 {
+			dict = dict[j:]
+		}
+		// The data starts after the end of the line of the keyword.
+		body := pos
+		if body < len(data) && data[body] == '\r' {
+			body++
+		}
+		if body < len(data) && data[body] == '\n' {
+			body++
+		}
+		end := bytes.Index(data[body:], endstreamKeyword)
+		if end < 0 {
+			return result
+		}
+		raw := data[body : body+end]
+		pos = body + end + len(endstreamKeyword)
+
+		if !isContentStream(dict) {
+			continue
+		}
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			decoded, err := inflate(raw, maxBytes)
+			if err != nil && len(decoded) == 0 {
+				continue
+			}
+			raw = decoded
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			// Other filters are used for images and fonts.
+			continue
+		}
+		result = append(result, raw)
+	}
+}
+
+// isContentStream rules out the streams of images, fonts and other
+// non-content objects by their dictionary.
+func isContentStream(dict []byte) bool {
+	for _, key := range []string{"/Image", "/Length1", "/Length2", "/FontFile", "/XRef", "/ObjStm", "/Metadata", "/ICCBased", "/N 3", "/N 4"} {
+		if bytes.Contains(dict, []byte(key)) {
+			return false
+		}
+	}
+	return true
+}
+
+// inflate decompresses a Flate stream, reading at most a bounded amount of
+// data so that a crafted stream cannot exhaust memory.
+func inflate(raw []byte, maxBytes int) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+	limit := int64(64 << 20)
+	if maxBytes > 0 {
+		limit = min(limit, int64(maxBytes)*16)
+	}
+	decoded, err := io.ReadAll(io.LimitReader(r, limit))
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		// Truncated streams are common; keep what was decoded.
+		err = nil
+	}
+	return decoded, err
+}
+
+// extractText writes the strings shown by the text objects of a content
+// stream to out, one line per line of text.
+func extractText(content []byte, out *strings.Builder) {
+	l := lexer{data: content}
+	var operands []token
+	inText := false
+	for {
+		t, ok := l.next()
+		if !ok {
+			return
+		}
+		if t.kind != tokenOperator {
+			operands = append(operands, t)
+			continue
+		}
+		switch string(t.value) {
+		case "BT":
+			inText = true
+		case "ET":
+			if inText {
+				newline(out)
+			}
+			inText = false
+		case "Tj":
+			if inText {
+				showStrings(operands, out)
+			}
+		case "'", "\"":
+			if inText {
+				newline(out)
+				showStrings(operands, out)
+			}
+		case "TJ":
+			if inText {
+				showArray(operands, out)
+			}
+		case "Td", "TD", "T*", "Tm":
+			if inText {
+				separate(out, operands, t)
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+func showStrings(operands []token, out *strings.Builder) {
+	for _, op := range operands {
+		if op.kind == tokenString {
+			out.WriteString(decodeString(op.value))
+		}
+	}
+}
+
+// showArray writes the strings of a TJ array; large negative adjustments
+// between them are taken as spaces.
+func showArray(operands []token, out *strings.Builder) {
+	for _, op := range operands {
+		switch op.kind {
+		case tokenString:
+			out.WriteString(decodeString(op.value))
+		case tokenNumber:
+			if parseNumber(op.value) < -200 {
+				space(out)
+			}
+		}
+	}
+}
+
+// separate breaks the line when the text position moves down and adds a
+// space when it moves along the line.
+func separate(out *strings.Builder, operands []token, op token) {
+	switch string(op.value) {
+	case "T*":
+		newline(out)
+	case "Td", "TD":
+		if len(operands) >= 2 && parseNumber(operands[len(operands)-1].value) != 0 {
+			newline(out)
+		} else {
+			space(out)
+		}
+	case "Tm":
+		newline(out)
+	}
+}
+
+func newline(out *strings.Builder) {
+	s := out.String()
+	if s == "" || strings.HasSuffix(s, "\n") {
+		return
+	}
+	out.WriteByte('\n')
+}
+
+func space(out *strings.Builder) {
+	s := out.String()
+	if s == "" || strings.HasSuffix(s, " ") || strings.HasSuffix(s, "\n") {
+		return
+	}
+	out.WriteByte(' ')
+}
+
+// decodeString decodes a PDF string: UTF-16 with a byte order mark, or
+// WinAnsi.
+func decodeString(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		return decodeUTF16(b[2:])
+	}
+	if looksLikeUTF16(b) {
+		return decodeUTF16(b)
+	}
+	s, err := charmap.Windows1252.NewDecoder().Bytes(b)
+	if err != nil {
+		return ""
+	}
+	return string(s)
+}
+
+// looksLikeUTF16 reports whether a string is made of two-byte codes for
+// ASCII characters, as written by some producers without a byte order mark.
+func looksLikeUTF16(b []byte) bool {
+	if len(b) < 2 || len(b)%2 != 0 {
+		return false
+	}
+	for i := 0; i < len(b); i += 2 {
+		if b[i] != 0 || b[i+1] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func decodeUTF16(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+func parseNumber(b []byte) float64 {
+	var n, frac float64
+	neg, seenDot := false, false
+	scale := 1.0
+	for i, c := range b {
+		switch {
+		case c == '-' && i == 0:
+			neg = true
+		case c == '+' && i == 0:
+		case c == '.' && !seenDot:
+			seenDot = true
+		case c >= '0' && c <= '9':
+			if seenDot {
+				scale /= 10
+				frac += float64(c-'0') * scale
+			} else {
+				n = n*10 + float64(c-'0')
+			}
+		default:
+			return 0
+		}
+	}
+	if neg {
+		return -(n + frac)
+	}
+	return n + frac
+}
@@ -0,0 +1,72 @@
+package pdftext
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF returns a small PDF with one page per content stream.
+func buildPDF(t *testing.T, compress bool, contents ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	buf.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	buf.WriteString("3 0 obj\n<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>\nendobj\n")
+	for i, content := range contents {
+		data := []byte(content)
+		filter := ""
+		if compress {
+			var z bytes.Buffer
+			w := zlib.NewWriter(&z)
+			if _, err := w.Write(data); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			data = z.Bytes()
+			filter = " /Filter /FlateDecode"
+		}
+		fmt.Fprintf(&buf, "%d 0 obj\n<< /Length %d%s >>\nstream\n", 10+i, len(data), filter)
+		buf.Write(data)
+		buf.WriteString("\nendstream\nendobj\n")
+	}
+	buf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return buf.Bytes()
+}
+
+func TestExtract(t *testing.T) {
+	page1 := "BT /F1 12 Tf 72 720 Td (Quarterly \\(Q3\\) report) Tj 0 -14 Td [(Re) 20 (venue) -250 (grew)] TJ ET"
+	page2 := "q 1 0 0 1 0 0 cm BI /W 1 /H 1 ID \x00\xff EI Q BT <FEFF00DC00620065007200730069006300680074> Tj T* (Gr\\374\\337e) Tj ET"
+
+	for _, compress := range []bool{false, true} {
+		got, err := Extract(buildPDF(t, compress, page1, page2), 0)
+		if err != nil {
+			t.Fatalf("Extract failed: %v", err)
+		}
+		want := "Quarterly (Q3) report\nRevenue grew\nÜbersicht\nGrüße"
+		if got != want {
+			t.Errorf("Extract(compress=%v) = %q, want %q", compress, got, want)
+		}
+	}
+}
+
+func TestExtract_LimitsText(t *testing.T) {
+	content := "BT " + strings.Repeat("(lorem ipsum) Tj T* ", 1000) + "ET"
+	got, err := Extract(buildPDF(t, true, content), 100)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if len(got) > 100 || !strings.HasPrefix(got, "lorem ipsum\nlorem") {
+		t.Errorf("expected at most 100 bytes of text, got %d: %q", len(got), got)
+	}
+}
+
+func TestExtract_RejectsOtherFiles(t *testing.T) {
+	if _, err := Extract([]byte("just some text"), 0); err != ErrNotPDF {
+		t.Errorf("expected ErrNotPDF, got %v", err)
+	}
+}
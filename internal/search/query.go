@@ -0,0 +1,613 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Search query language
+//
+// A query is a list of items separated by spaces; a page matches when it
+// matches every item:
+//
+//	deploy                 a word starting with "deploy"
+//	"rolling deploy"       the exact phrase
+//	deploy OR release      either word; OR only joins search terms
+//	-draft                 pages without the word (also -"a phrase")
+//	title:deploy           the word in the title; headings: and content: work alike
+//	path:ops/runbooks      pages at or below ops/runbooks
+//	tag:ops                pages tagged ops
+//	author:alice           pages created or last edited by alice
+//	updated:>2026-01-01    pages last changed after that day; also >=, <, <= and =
+//	status:draft           pages whose property status has the value draft
+//
+// Any other "name:value" filters by a property only while some page has a
+// property called name; until then it is searched for as text, like a URL
+// such as https://example.com. A query with status:draft therefore starts to
+// filter once the first page gets a status property. Every filter can be
+// negated with a leading "-" and takes a quoted value when the value
+// contains spaces (owner:"Jane Doe"). Words and phrases are sent to FTS5 as
+// quoted strings, so characters that are FTS5 syntax have no special
+// meaning in a query.
+
+// Text fields a search term can be limited to.
+const (
+	TermFieldTitle    = "title"
+	TermFieldHeadings = "headings"
+	TermFieldContent  = "content"
+)
+
+// QueryTerm is a word or phrase to search for.
+type QueryTerm struct {
+	// Field limits the term to one text field. Empty searches all of them.
+	Field string
+	Text  string
+	// Phrase terms match exactly; words also match as a prefix.
+	Phrase bool
+	// Pos is the offset of Text in the query, counted in characters from 0.
+	Pos int
+}
+
+// QueryClause is a set of alternative terms.
+type QueryClause struct {
+	Terms   []QueryTerm
+	Negated bool
+}
+
+// FilterField names what a QueryFilter restricts.
+type FilterField string
+
+const (
+	FilterPath     FilterField = "path"
+	FilterTag      FilterField = "tag"
+	FilterAuthor   FilterField = "author"
+	FilterUpdated  FilterField = "updated"
+	FilterProperty FilterField = "property"
+)
+
+// QueryFilter restricts the pages a query matches by something other than
+// their text.
+type QueryFilter struct {
+	Field FilterField
+	// Key is the property name of a FilterProperty.
+	Key   string
+	Value string
+	// Op and Date are set for FilterUpdated. Op is one of >, >=, <, <= and =.
+	Op      string
+	Date    time.Time
+	Negated bool
+}
+
+// MatchesDay reports whether t satisfies an updated: filter, comparing
+// whole days in UTC. Negation is left to the caller.
+func (f QueryFilter) MatchesDay(t time.Time) bool {
+	start := f.Date
+	end := start.AddDate(0, 0, 1)
+	t = t.UTC()
+	switch f.Op {
+	case ">":
+		return !t.Before(end)
+	case ">=":
+		return !t.Before(start)
+	case "<":
+		return t.Before(start)
+	case "<=":
+		return t.Before(end)
+	default:
+		return !t.Before(start) && t.Before(end)
+	}
+}
+
+// Query is a parsed search query.
+type Query struct {
+	Clauses []QueryClause
+	Filters []QueryFilter
+	// source is the query as written, for suggestions.
+	source []rune
+}
+
+// IsEmpty reports whether the query neither searches nor filters.
+func (q *Query) IsEmpty() bool {
+	return q == nil || (len(q.Clauses) == 0 && len(q.Filters) == 0)
+}
+
+// HasText reports whether the query contains a term pages must match, as
+// opposed to only terms they must not match.
+func (q *Query) HasText() bool {
+	if q == nil {
+		return false
+	}
+	for _, c := range q.Clauses {
+		if !c.Negated {
+			return true
+		}
+	}
+	return false
+}
+
+// FiltersOf returns the filters on field.
+func (q *Query) FiltersOf(field FilterField) []QueryFilter {
+	if q == nil {
+		return nil
+	}
+	var result []QueryFilter
+	for _, f := range q.Filters {
+		if f.Field == field {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// QuerySyntaxError reports a query that could not be parsed.
+type QuerySyntaxError struct {
+	// Pos is the offset of the offending character, counted in characters
+	// from 0.
+	Pos     int
+	Message string
+}
+
+func (e *QuerySyntaxError) Error() string {
+	return fmt.Sprintf("invalid search query at position %d: %s", e.Pos, e.Message)
+}
+
+// QueryOptions adjust how ParseQueryWithOptions reads a query.
+type QueryOptions struct {
+	// IsPropertyKey reports whether a name is a property key. Nil accepts
+	// every name.
+	IsPropertyKey func(name string) bool
+}
+
+// ParseQuery parses a query written in the search query language, taking
+// every name that is not a field for a property key.
+func ParseQuery(input string) (*Query, error) {
+	return ParseQueryWithOptions(input, QueryOptions{})
+}
+
+// ParseQueryWithOptions parses a query written in the search query language.
+func ParseQueryWithOptions(input string, opts QueryOptions) (*Query, error) {
+	p := &queryParser{input: []rune(input), isPropertyKey: opts.IsPropertyKey}
+	return p.parse()
+}
+
+type queryParser struct {
+	input         []rune
+	pos           int
+	isPropertyKey func(name string) bool
+}
+
+// queryItem is a single term or filter as it appears in the query.
+type queryItem struct {
+	start   int
+	negated bool
+	term    *QueryTerm
+	filter  *QueryFilter
+}
+
+func (p *queryParser) parse() (*Query, error) {
+	q := &Query{source: p.input}
+	// orPos is the position of an OR still waiting for its right-hand
+	// term, or -1.
+	orPos := -1
+	lastWasTerm := false
+	for {
+		p.skipSpace()
+		if p.eof() {
+			break
+		}
+		if p.atKeyword("OR") {
+			if !lastWasTerm || orPos >= 0 {
+				return nil, p.errorAt(p.pos, "OR must stand between two search terms")
+			}
+			orPos = p.pos
+			p.pos += 2
+			continue
+		}
+
+		item, err := p.parseItem()
+		if err != nil {
+			return nil, err
+		}
+		if item.filter != nil {
+			if orPos >= 0 {
+				return nil, p.errorAt(orPos, "OR must stand between two search terms")
+			}
+			item.filter.Negated = item.negated
+			q.Filters = append(q.Filters, *item.filter)
+			lastWasTerm = false
+			continue
+		}
+
+		if !isSearchableText(item.term.Text) {
+			// Punctuation alone matches nothing; drop it like the
+			// tokenizer would, together with an OR in front of it.
+			orPos = -1
+			continue
+		}
+		if orPos >= 0 {
+			last := &q.Clauses[len(q.Clauses)-1]
+			if item.negated || last.Negated {
+				return nil, p.errorAt(item.start, "negated terms cannot be joined with OR")
+			}
+			last.Terms = append(last.Terms, *item.term)
+			orPos = -1
+			continue
+		}
+		q.Clauses = append(q.Clauses, QueryClause{Terms: []QueryTerm{*item.term}, Negated: item.negated})
+		lastWasTerm = true
+	}
+	if orPos >= 0 {
+		return nil, p.errorAt(orPos, "OR must stand between two search terms")
+	}
+	return q, nil
+}
+
+func (p *queryParser) parseItem() (queryItem, error) {
+	item := queryItem{start: p.pos}
+	if p.peek() == '-' {
+		item.negated = true
+		p.pos++
+		if p.eof() || unicode.IsSpace(p.peek()) {
+			return item, p.errorAt(item.start, `expected a search term or filter after "-"`)
+		}
+	}
+
+	if p.peek() == '"' {
+		text, err := p.parsePhrase()
+		if err != nil {
+			return item, err
+		}
+		item.term = &QueryTerm{Text: text, Phrase: true, Pos: item.start + 1}
+		if item.negated {
+			item.term.Pos++
+		}
+		return item, nil
+	}
+
+	if field, ok := p.fieldPrefix(); ok && p.isField(field) {
+		p.pos += len([]rune(field)) + 1
+		valueStart := p.pos
+		value, phrase, err := p.parseValue()
+		if err != nil {
+			return item, err
+		}
+		if strings.TrimSpace(value) == "" {
+			return item, p.errorAt(valueStart, fmt.Sprintf("missing value after %s:", field))
+		}
+		return p.fieldItem(item, field, value, phrase, valueStart)
+	}
+
+	pos := p.pos
+	item.term = &QueryTerm{Text: strings.TrimRight(p.parseWord(), "*"), Pos: pos}
+	return item, nil
+}
+
+func (p *queryParser) fieldItem(item queryItem, field, value string, phrase bool, valueStart int) (queryItem, error) {
+	switch name := strings.ToLower(field); name {
+	case TermFieldTitle, TermFieldHeadings, TermFieldContent:
+		if !phrase {
+			value = strings.TrimRight(value, "*")
+		}
+		pos := valueStart
+		if phrase {
+			pos++
+		}
+		item.term = &QueryTerm{Field: name, Text: value, Phrase: phrase, Pos: pos}
+	case string(FilterPath):
+		path := strings.Trim(strings.ToLower(strings.TrimSpace(value)), "/")
+		if path == "" {
+			return item, p.errorAt(valueStart, "missing value after path:")
+		}
+		item.filter = &QueryFilter{Field: FilterPath, Value: path}
+	case string(FilterTag):
+		item.filter = &QueryFilter{Field: FilterTag, Value: strings.ToLower(strings.TrimSpace(value))}
+	case string(FilterAuthor):
+		item.filter = &QueryFilter{Field: FilterAuthor, Value: strings.TrimSpace(value)}
+	case string(FilterUpdated):
+		filter, err := p.updatedFilter(value, phrase, valueStart)
+		if err != nil {
+			return item, err
+		}
+		item.filter = filter
+	default:
+		item.filter = &QueryFilter{Field: FilterProperty, Key: field, Value: strings.TrimSpace(value)}
+	}
+	return item, nil
+}
+
+func (p *queryParser) updatedFilter(value string, phrase bool, valueStart int) (*QueryFilter, error) {
+	datePos := valueStart
+	if phrase {
+		datePos++
+	}
+	op := "="
+	for _, candidate := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(value, candidate) {
+			op = candidate
+			value = value[len(candidate):]
+			datePos += len(candidate)
+			break
+		}
+	}
+	date, err := time.Parse(time.DateOnly, strings.TrimSpace(value))
+	if err != nil {
+		return nil, p.errorAt(datePos, "expected a date like 2026-01-31 after updated:"+strings.TrimPrefix(op, "="))
+	}
+	return &QueryFilter{Field: FilterUpdated, Op: op, Value: value, Date: date}, nil
+}
+
+// isField reports whether the name of a fieldPrefix starts a field term or
+// a filter rather than a word.
+func (p *queryParser) isField(name string) bool {
+	switch strings.ToLower(name) {
+	case TermFieldTitle, TermFieldHeadings, TermFieldContent,
+		string(FilterPath), string(FilterTag), string(FilterAuthor), string(FilterUpdated):
+		return true
+	}
+	rest := p.input[p.pos+len([]rune(name))+1:]
+	if len(rest) >= 2 && rest[0] == '/' && rest[1] == '/' {
+		// The scheme of a URL.
+		return false
+	}
+	return p.isPropertyKey == nil || p.isPropertyKey(name)
+}
+
+// fieldPrefix returns the field name when the input continues with
+// "name:". Names start with a letter and may contain letters, digits and
+// "_", "." and "-".
+func (p *queryParser) fieldPrefix() (string, bool) {
+	i := p.pos
+	if i >= len(p.input) || !unicode.IsLetter(p.input[i]) {
+		return "", false
+	}
+	for i < len(p.input) {
+		r := p.input[i]
+		if r == ':' {
+			return string(p.input[p.pos:i]), true
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' && r != '-' {
+			return "", false
+		}
+		i++
+	}
+	return "", false
+}
+
+func (p *queryParser) parseValue() (string, bool, error) {
+	if p.peek() == '"' {
+		text, err := p.parsePhrase()
+		return text, true, err
+	}
+	return p.parseWord(), false, nil
+}
+
+func (p *queryParser) parsePhrase() (string, error) {
+	start := p.pos
+	p.pos++
+	for i := p.pos; i < len(p.input); i++ {
+		if p.input[i] == '"' {
+			text := string(p.input[p.pos:i])
+			p.pos = i + 1
+			return text, nil
+		}
+	}
+	return "", p.errorAt(start, "unterminated quoted phrase")
+}
+
+func (p *queryParser) parseWord() string {
+	start := p.pos
+	for !p.eof() && !unicode.IsSpace(p.peek()) {
+		p.pos++
+	}
+	return string(p.input[start:p.pos])
+}
+
+// atKeyword reports whether the input continues with keyword as a word of
+// its own.
+func (p *queryParser) atKeyword(keyword string) bool {
+	end := p.pos + len(keyword)
+	if end > len(p.input) || string(p.input[p.pos:end]) != keyword {
+		return false
+	}
+	return end == len(p.input) || unicode.IsSpace(p.input[end])
+}
+
+func (p *queryParser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(p.peek()) {
+		p.pos++
+	}
+}
+
+func (p *queryParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+// peek returns the next character, or 0 at the end of the input.
+func (p *queryParser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *queryParser) errorAt(pos int, message string) *QuerySyntaxError {
+	return &QuerySyntaxError{Pos: pos, Message: message}
+}
+
+// tokenChars are the characters the pages table's tokenizer keeps inside
+// tokens in addition to letters and digits.
+const tokenChars = "-_/+#."
+
+func isSearchableText(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) || strings.ContainsRune(tokenChars, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// textColumns are the columns a term without a field is searched in.
+const textColumns = "{title headings content}"
+
+// stemColumns are the analyzed counterparts of textColumns; the analyzed
+// column of a field is named <field>_stems.
+const stemColumns = "{title_stems headings_stems content_stems}"
+
+// stemFunc returns the analyzed forms a term is also looked up as in the
+// stemmed columns; see Analyzer.stemForms.
+type stemFunc func(text string) []string
+
+func ftsString(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func ftsTerm(t QueryTerm, stems stemFunc) string {
+	column, stemColumn := textColumns, stemColumns
+	if t.Field != "" {
+		column, stemColumn = t.Field, t.Field+"_stems"
+	}
+	return ftsTermIn(t, stems, column, stemColumn)
+}
+
+func ftsTermIn(t QueryTerm, stems stemFunc, column, stemColumn string) string {
+	s := column + " : " + ftsString(t.Text)
+	if !t.Phrase {
+		s += "*"
+	}
+	if stems == nil {
+		return s
+	}
+	forms := stems(t.Text)
+	if len(forms) == 0 {
+		return s
+	}
+	parts := []string{s}
+	for _, form := range forms {
+		parts = append(parts, stemColumn+" : "+ftsString(form))
+	}
+	return "(" + strings.Join(parts, " OR ") + ")"
+}
+
+func ftsClause(c QueryClause, stems stemFunc) string {
+	if len(c.Terms) == 1 {
+		return ftsTerm(c.Terms[0], stems)
+	}
+	parts := make([]string, len(c.Terms))
+	for i, t := range c.Terms {
+		parts[i] = ftsTerm(t, stems)
+	}
+	return "(" + strings.Join(parts, " OR ") + ")"
+}
+
+// ftsMatch returns the FTS5 expression pages have to match, or "" when the
+// query has no positive terms. stems may be nil.
+func (q *Query) ftsMatch(stems stemFunc) string {
+	var parts []string
+	for _, c := range q.Clauses {
+		if !c.Negated {
+			parts = append(parts, ftsClause(c, stems))
+		}
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// ftsExclude returns the FTS5 expression matching the pages excluded by
+// negated terms, or "". stems may be nil.
+func (q *Query) ftsExclude(stems stemFunc) string {
+	var parts []string
+	for _, c := range q.Clauses {
+		if c.Negated {
+			parts = append(parts, ftsClause(c, stems))
+		}
+	}
+	return strings.Join(parts, " OR ")
+}
+
+// sectionColumns are the columns of heading_sections holding a section's
+// own text, as opposed to the title of its page.
+const (
+	sectionColumns     = "{headings content}"
+	sectionStemColumns = "{headings_stems content_stems}"
+)
+
+// ftsSectionMatch returns the FTS5 expression the heading sections of a
+// matching page have to match to be shown as hits: the page's expression,
+// and at least one positive term in the section's heading or text. It is ""
+// when no term can match the text of a section.
+func (q *Query) ftsSectionMatch(stems stemFunc) string {
+	var own []string
+	for _, c := range q.Clauses {
+		if c.Negated {
+			continue
+		}
+		for _, t := range c.Terms {
+			switch t.Field {
+			case "":
+				own = append(own, ftsTermIn(t, stems, sectionColumns, sectionStemColumns))
+			case TermFieldHeadings, TermFieldContent:
+				own = append(own, ftsTerm(t, stems))
+			}
+		}
+	}
+	if len(own) == 0 {
+		return ""
+	}
+	return "(" + q.ftsMatch(stems) + ") AND (" + strings.Join(own, " OR ") + ")"
+}
+
+// Columns of the attachments table: a term without a field is searched in
+// the filename and the text of an attachment, a content: term in its text.
+// Attachments are matched by their words as written, without stemming.
+const attachmentColumns = "{filename content}"
+
+// ftsAttachmentMatch returns the FTS5 expression attachments have to match,
+// or "" when the query has no positive terms or a positive clause only
+// searches title: or headings:, which attachments don't have.
+func (q *Query) ftsAttachmentMatch() string {
+	var parts []string
+	for _, c := range q.Clauses {
+		if c.Negated {
+			continue
+		}
+		clause := ftsAttachmentClause(c)
+		if clause == "" {
+			return ""
+		}
+		parts = append(parts, clause)
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// ftsAttachmentExclude returns the FTS5 expression matching the attachments
+// excluded by negated terms, or "".
+func (q *Query) ftsAttachmentExclude() string {
+	var parts []string
+	for _, c := range q.Clauses {
+		if !c.Negated {
+			continue
+		}
+		if clause := ftsAttachmentClause(c); clause != "" {
+			parts = append(parts, clause)
+		}
+	}
+	return strings.Join(parts, " OR ")
+}
+
+func ftsAttachmentClause(c QueryClause) string {
+	var terms []string
+	for _, t := range c.Terms {
+		switch t.Field {
+		case "":
+			terms = append(terms, ftsTermIn(t, nil, attachmentColumns, ""))
+		case TermFieldContent:
+			terms = append(terms, ftsTermIn(t, nil, t.Field, ""))
+		}
+	}
+	if len(terms) <= 1 {
+		return strings.Join(terms, "")
+	}
+	return "(" + strings.Join(terms, " OR ") + ")"
+}
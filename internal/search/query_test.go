@@ -0,0 +1,196 @@
+package search
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseQuery_TermsAndPhrases(t *testing.T) {
+	q, err := ParseQuery(`deploy "rolling update" -draft title:runbook* content:"exact words"`)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+	want := []QueryClause{
+		{Terms: []QueryTerm{{Text: "deploy", Pos: 0}}},
+		{Terms: []QueryTerm{{Text: "rolling update", Phrase: true, Pos: 8}}},
+		{Terms: []QueryTerm{{Text: "draft", Pos: 25}}, Negated: true},
+		{Terms: []QueryTerm{{Field: TermFieldTitle, Text: "runbook", Pos: 37}}},
+		{Terms: []QueryTerm{{Field: TermFieldContent, Text: "exact words", Phrase: true, Pos: 55}}},
+	}
+	if !reflect.DeepEqual(q.Clauses, want) {
+		t.Fatalf("clauses = %+v, want %+v", q.Clauses, want)
+	}
+	if len(q.Filters) != 0 {
+		t.Fatalf("expected no filters, got %+v", q.Filters)
+	}
+}
+
+func TestParseQuery_OrJoinsTerms(t *testing.T) {
+	q, err := ParseQuery(`deploy OR release "hot fix" or`)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+	want := []QueryClause{
+		{Terms: []QueryTerm{{Text: "deploy", Pos: 0}, {Text: "release", Pos: 10}}},
+		{Terms: []QueryTerm{{Text: "hot fix", Phrase: true, Pos: 19}}},
+		{Terms: []QueryTerm{{Text: "or", Pos: 28}}},
+	}
+	if !reflect.DeepEqual(q.Clauses, want) {
+		t.Fatalf("clauses = %+v, want %+v", q.Clauses, want)
+	}
+}
+
+func TestParseQuery_Filters(t *testing.T) {
+	q, err := ParseQuery(`path:/Ops/Runbooks/ tag:OPS -tag:legacy author:alice updated:>=2026-01-01 -status:draft owner:"Jane Doe"`)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+	want := []QueryFilter{
+		{Field: FilterPath, Value: "ops/runbooks"},
+		{Field: FilterTag, Value: "ops"},
+		{Field: FilterTag, Value: "legacy", Negated: true},
+		{Field: FilterAuthor, Value: "alice"},
+		{Field: FilterUpdated, Op: ">=", Value: "2026-01-01", Date: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Field: FilterProperty, Key: "status", Value: "draft", Negated: true},
+		{Field: FilterProperty, Key: "owner", Value: "Jane Doe"},
+	}
+	if !reflect.DeepEqual(q.Filters, want) {
+		t.Fatalf("filters = %+v\nwant %+v", q.Filters, want)
+	}
+	if q.HasText() || q.IsEmpty() {
+		t.Fatalf("expected a query with filters only, HasText=%v IsEmpty=%v", q.HasText(), q.IsEmpty())
+	}
+}
+
+func TestParseQuery_URLsAreWords(t *testing.T) {
+	q, err := ParseQuery(`https://example.com/docs -ftp://files.example.com`)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+	want := []QueryClause{
+		{Terms: []QueryTerm{{Text: "https://example.com/docs", Pos: 0}}},
+		{Terms: []QueryTerm{{Text: "ftp://files.example.com", Pos: 26}}, Negated: true},
+	}
+	if !reflect.DeepEqual(q.Clauses, want) || len(q.Filters) != 0 {
+		t.Fatalf("clauses = %+v, filters = %+v, want %+v and no filters", q.Clauses, q.Filters, want)
+	}
+}
+
+func TestParseQueryWithOptions_UnknownKeysAreWords(t *testing.T) {
+	opts := QueryOptions{IsPropertyKey: func(name string) bool { return name == "status" }}
+	q, err := ParseQueryWithOptions(`TODO: note:later status:draft tag:ops`, opts)
+	if err != nil {
+		t.Fatalf("ParseQueryWithOptions failed: %v", err)
+	}
+	wantClauses := []QueryClause{
+		{Terms: []QueryTerm{{Text: "TODO:", Pos: 0}}},
+		{Terms: []QueryTerm{{Text: "note:later", Pos: 6}}},
+	}
+	if !reflect.DeepEqual(q.Clauses, wantClauses) {
+		t.Fatalf("clauses = %+v, want %+v", q.Clauses, wantClauses)
+	}
+	wantFilters := []QueryFilter{
+		{Field: FilterProperty, Key: "status", Value: "draft"},
+		{Field: FilterTag, Value: "ops"},
+	}
+	if !reflect.DeepEqual(q.Filters, wantFilters) {
+		t.Fatalf("filters = %+v, want %+v", q.Filters, wantFilters)
+	}
+}
+
+func TestParseQuery_DropsTermsWithoutSearchableCharacters(t *testing.T) {
+	q, err := ParseQuery(`!!! deploy OR ??? * NEAR(`)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+	want := []QueryClause{
+		{Terms: []QueryTerm{{Text: "deploy", Pos: 4}}},
+		{Terms: []QueryTerm{{Text: "NEAR(", Pos: 20}}},
+	}
+	if !reflect.DeepEqual(q.Clauses, want) {
+		t.Fatalf("clauses = %+v, want %+v", q.Clauses, want)
+	}
+}
+
+func TestParseQuery_SyntaxErrorsReportPosition(t *testing.T) {
+	cases := []struct {
+		query string
+		pos   int
+	}{
+		{`deploy "rolling update`, 7},
+		{`OR deploy`, 0},
+		{`deploy OR`, 7},
+		{`deploy OR tag:ops`, 7},
+		{`deploy OR OR release`, 10},
+		{`deploy OR -draft`, 10},
+		{`deploy - draft`, 7},
+		{`tag: ops`, 4},
+		{`status:""`, 7},
+		{`path:/`, 5},
+		{`updated:>yesterday`, 9},
+		{`updated:"<2026-13-01"`, 10},
+		{`äöü tag:`, 8},
+	}
+	for _, tc := range cases {
+		_, err := ParseQuery(tc.query)
+		var syntaxErr *QuerySyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("ParseQuery(%q): expected a syntax error, got %v", tc.query, err)
+			continue
+		}
+		if syntaxErr.Pos != tc.pos {
+			t.Errorf("ParseQuery(%q): position = %d, want %d (%s)", tc.query, syntaxErr.Pos, tc.pos, syntaxErr.Message)
+		}
+	}
+}
+
+func TestQuery_FTSExpressionsQuoteUserInput(t *testing.T) {
+	q, err := ParseQuery(`a"b OR title:c* -"x y" -content:z`)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+	if got, want := q.ftsMatch(nil), `({title headings content} : "a""b"* OR title : "c"*)`; got != want {
+		t.Errorf("ftsMatch = %s, want %s", got, want)
+	}
+	if got, want := q.ftsExclude(nil), `{title headings content} : "x y" OR content : "z"*`; got != want {
+		t.Errorf("ftsExclude = %s, want %s", got, want)
+	}
+}
+
+func TestQuery_FTSExpressionsLookUpStemmedForms(t *testing.T) {
+	q, err := ParseQuery(`Zertifikate title:deploy "Größe"`)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+	stems := map[string][]string{"Zertifikate": {"zertifikat", "zertifik"}, "Größe": {"gross"}}
+	got := q.ftsMatch(func(text string) []string { return stems[text] })
+	want := `({title headings content} : "Zertifikate"* OR {title_stems headings_stems content_stems} : "zertifikat" OR {title_stems headings_stems content_stems} : "zertifik")` +
+		` AND title : "deploy"*` +
+		` AND ({title headings content} : "Größe" OR {title_stems headings_stems content_stems} : "gross")`
+	if got != want {
+		t.Errorf("ftsMatch =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestQueryFilter_MatchesDay(t *testing.T) {
+	day := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := day.Add(-time.Second)
+	during := day.Add(12 * time.Hour)
+	after := day.AddDate(0, 0, 1)
+	cases := map[string][3]bool{
+		">":  {false, false, true},
+		">=": {false, true, true},
+		"<":  {true, false, false},
+		"<=": {true, true, false},
+		"=":  {false, true, false},
+	}
+	for op, want := range cases {
+		f := QueryFilter{Field: FilterUpdated, Op: op, Date: day}
+		got := [3]bool{f.MatchesDay(before), f.MatchesDay(during), f.MatchesDay(after)}
+		if got != want {
+			t.Errorf("updated:%s2026-01-01 matches (before, during, after) = %v, want %v", op, got, want)
+		}
+	}
+}
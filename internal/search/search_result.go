@@ -6,6 +6,14 @@ type SearchResult struct {
 	Count     int                `json:"count"`
 	Items     []SearchResultItem `json:"items"`
 	TagFacets []SearchTagFacet   `json:"tag_facets"`
+	// Suggestion is the query with misspelled words corrected, when the
+	// query had few exact hits and close matches were found.
+	Suggestion string `json:"suggestion,omitempty"`
+	// Attachments are the best matching attachments of the pages in scope,
+	// listed with the first page of results only.
+	Attachments []SearchAttachmentHit `json:"attachments,omitempty"`
+	// AttachmentCount is the number of matching attachments.
+	AttachmentCount int `json:"attachment_count,omitempty"`
 }
 
 type SearchResultItem struct {
@@ -16,6 +24,31 @@ type SearchResultItem struct {
 	Rank    float64  `json:"rank"`
 	Excerpt string   `json:"excerpt"`
 	Tags    []string `json:"tags,omitempty"`
+	// Fuzzy marks hits that only match a spelling correction of the query.
+	Fuzzy bool `json:"fuzzy,omitempty"`
+	// Headings are the best matching sections of the page, best first.
+	Headings []SearchHeadingHit `json:"headings,omitempty"`
+}
+
+// SearchHeadingHit is a section of a page that matches a query.
+type SearchHeadingHit struct {
+	// Heading is the section's heading with the matching words in <b>.
+	Heading string `json:"heading"`
+	// Anchor is the id of the heading in the rendered page, for links to
+	// page#anchor.
+	Anchor  string `json:"anchor"`
+	Excerpt string `json:"excerpt"`
+}
+
+// SearchAttachmentHit is an attachment of a page whose text matches a
+// query. It is served at /assets/<page id>/<filename>.
+type SearchAttachmentHit struct {
+	PageID    string  `json:"page_id"`
+	PageTitle string  `json:"page_title"`
+	PagePath  string  `json:"page_path"`
+	Filename  string  `json:"filename"`
+	Excerpt   string  `json:"excerpt"`
+	Rank      float64 `json:"rank"`
 }
 
 type SearchTagFacet struct {
@@ -0,0 +1,263 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Pages are compared by their characteristic terms: the analyzed terms of a
+// page weighted by TF-IDF, where a term counts more in the title and the
+// headings than in the text. The other pages are ranked by bm25 over those
+// terms, which is itself a TF-IDF measure.
+
+const (
+	// similarTermLimit is the number of characteristic terms a page is
+	// compared by.
+	similarTermLimit = 12
+	// minSimilarTermLength is the length of the shortest term that counts.
+	minSimilarTermLength = 3
+	// maxVocabQueryArgs limits the terms looked up in pages_vocab at once.
+	maxVocabQueryArgs = 500
+)
+
+// stemColumnWeights are the weights of a term in the analyzed columns,
+// both for its frequency in the page and for ranking the other pages.
+var stemColumnWeights = []struct {
+	column string
+	weight float64
+}{
+	{"title_stems", 3},
+	{"headings_stems", 2},
+	{"content_stems", 1},
+}
+
+// SimilarPage is a page whose text resembles another page's.
+type SimilarPage struct {
+	PageID string
+	// Similarity is between 0 and 1, where 1 is as similar to the page as
+	// the page is to itself.
+	Similarity float64
+	// Terms are the characteristic terms of the page that the similar page
+	// contains, most characteristic first.
+	Terms []string
+}
+
+// SimilarPages returns up to limit pages whose text is most similar to the
+// page's, most similar first. A page that is not indexed or has no term in
+// common with other pages has no similar pages.
+func (s *SQLiteIndex) SimilarPages(pageID string, limit int) ([]SimilarPage, error) {
+	var result []SimilarPage
+	err := s.withDBRead(func(db *sql.DB) error {
+		terms, err := characteristicTerms(db, pageID)
+		if err != nil || len(terms) == 0 {
+			return err
+		}
+		quoted := make([]string, len(terms))
+		for i, term := range terms {
+			quoted[i] = ftsString(term)
+		}
+		match := stemColumns + " : (" + strings.Join(quoted, " OR ") + ")"
+
+		var self float64
+		err = db.QueryRow(`SELECT `+similarRankExpr+` FROM pages WHERE pages MATCH ? AND pageID = ?`, match, pageID).Scan(&self)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil || self >= 0 {
+			return err
+		}
+
+		rows, err := db.Query(`
+			SELECT pageID, `+similarRankExpr+` AS score
+			FROM pages
+			WHERE pages MATCH ? AND pageID <> ?
+			ORDER BY score ASC
+			LIMIT ?
+		`, match, pageID, limit)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = rows.Close()
+		}()
+		for rows.Next() {
+			var page SimilarPage
+			var score float64
+			if err := rows.Scan(&page.PageID, &score); err != nil {
+				return err
+			}
+			// bm25 scores are negative, the better the lower.
+			page.Similarity = math.Min(1, score/self)
+			result = append(result, page)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return attachSimilarTerms(db, terms, result)
+	})
+	return result, err
+}
+
+// similarRankExpr ranks pages by the analyzed columns only.
+var similarRankExpr = func() string {
+	weights := []string{"0", "0", "0", "0", "0", "0", "0", "0"}
+	for _, c := range stemColumnWeights {
+		weights = append(weights, fmt.Sprint(c.weight))
+	}
+	return "bm25(pages, " + strings.Join(weights, ", ") + ")"
+}()
+
+// characteristicTerms returns the terms of the page with the highest
+// TF-IDF, leaving out terms no other page has.
+func characteristicTerms(db *sql.DB, pageID string) ([]string, error) {
+	var lang string
+	columns := make([]string, len(stemColumnWeights))
+	names := make([]string, len(stemColumnWeights))
+	dest := []interface{}{&lang}
+	for i, c := range stemColumnWeights {
+		names[i] = c.column
+		dest = append(dest, &columns[i])
+	}
+	err := db.QueryRow(`SELECT lang, `+strings.Join(names, ", ")+` FROM pages WHERE pageID = ?`, pageID).Scan(dest...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	frequencies := map[string]float64{}
+	for i, c := range stemColumnWeights {
+		for _, term := range strings.Fields(columns[i]) {
+			if isCharacteristicTerm(term, Language(lang)) {
+				frequencies[term] += c.weight
+			}
+		}
+	}
+	if len(frequencies) == 0 {
+		return nil, nil
+	}
+
+	var pages int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM pages`).Scan(&pages); err != nil {
+		return nil, err
+	}
+	docs, err := termDocCounts(db, frequencies)
+	if err != nil {
+		return nil, err
+	}
+
+	type weighted struct {
+		term   string
+		weight float64
+	}
+	var candidates []weighted
+	for term, tf := range frequencies {
+		df := docs[term]
+		if df < 2 || df >= pages {
+			continue
+		}
+		candidates = append(candidates, weighted{term, tf * math.Log(float64(pages)/float64(df))})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].weight != candidates[j].weight {
+			return candidates[i].weight > candidates[j].weight
+		}
+		return candidates[i].term < candidates[j].term
+	})
+	if len(candidates) > similarTermLimit {
+		candidates = candidates[:similarTermLimit]
+	}
+	terms := make([]string, len(candidates))
+	for i, c := range candidates {
+		terms[i] = c.term
+	}
+	return terms, nil
+}
+
+func isCharacteristicTerm(term string, lang Language) bool {
+	if utf8.RuneCountInString(term) < minSimilarTermLength || isStopword(term, lang) {
+		return false
+	}
+	return strings.IndexFunc(term, unicode.IsLetter) >= 0
+}
+
+// termDocCounts returns the number of pages with each of the terms in one
+// of the analyzed columns.
+func termDocCounts(db *sql.DB, terms map[string]float64) (map[string]int, error) {
+	all := make([]string, 0, len(terms))
+	for term := range terms {
+		all = append(all, term)
+	}
+	docs := make(map[string]int, len(all))
+	for start := 0; start < len(all); start += maxVocabQueryArgs {
+		batch := all[start:min(start+maxVocabQueryArgs, len(all))]
+		args := make([]interface{}, len(batch))
+		for i, term := range batch {
+			args[i] = term
+		}
+		rows, err := db.Query(fmt.Sprintf(`
+			SELECT term, MAX(doc) FROM pages_vocab
+			WHERE col IN ('title_stems', 'headings_stems', 'content_stems') AND term IN (%s)
+			GROUP BY term
+		`, placeholders(len(batch))), args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var term string
+			var doc int
+			if err := rows.Scan(&term, &doc); err != nil {
+				_ = rows.Close()
+				return nil, err
+			}
+			docs[term] = doc
+		}
+		err = rows.Err()
+		_ = rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return docs, nil
+}
+
+// attachSimilarTerms sets the terms of pages, which are among terms.
+func attachSimilarTerms(db *sql.DB, terms []string, pages []SimilarPage) error {
+	if len(pages) == 0 {
+		return nil
+	}
+	index := make(map[string]int, len(pages))
+	args := []interface{}{nil}
+	for i, page := range pages {
+		index[page.PageID] = i
+		args = append(args, page.PageID)
+	}
+	query := fmt.Sprintf(`SELECT pageID FROM pages WHERE pages MATCH ? AND pageID IN (%s)`, placeholders(len(pages)))
+	for _, term := range terms {
+		args[0] = stemColumns + " : " + ftsString(term)
+		rows, err := db.Query(query, args...)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				_ = rows.Close()
+				return err
+			}
+			pages[index[id]].Terms = append(pages[index[id]].Terms, term)
+		}
+		err = rows.Err()
+		_ = rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package search
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/perber/wiki/internal/core/tree"
+	"github.com/perber/wiki/internal/test_utils"
+)
+
+func TestSQLiteIndex_SimilarPages(t *testing.T) {
+	index, err := NewSQLiteIndex(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer test_utils.WrapCloseWithErrorCheck(index.Close, t)
+
+	pages := []struct{ id, title, content string }{
+		{"kafka", "Kafka Consumers", "# Rebalancing\nConsumers of a topic partition rebalance when the broker restarts."},
+		{"broker", "Broker Restarts", "Restarting the broker makes consumers rebalance their partition."},
+		{"topics", "Topic Layout", "Every topic has one partition per consumer."},
+		{"lunch", "Lunch Menu", "Soup and bread on Mondays, pasta on Fridays."},
+		{"dinner", "Dinner Menu", "Soup, pasta and dessert."},
+	}
+	for _, p := range pages {
+		if err := index.IndexPage(p.id, p.id+".md", p.id, p.title, tree.NodeKindPage, p.content); err != nil {
+			t.Fatalf("IndexPage(%s) failed: %v", p.id, err)
+		}
+	}
+
+	similar, err := index.SimilarPages("kafka", 10)
+	if err != nil {
+		t.Fatalf("SimilarPages failed: %v", err)
+	}
+	if len(similar) != 2 || similar[0].PageID != "broker" || similar[1].PageID != "topics" {
+		t.Fatalf("expected broker then topics, got %+v", similar)
+	}
+	if similar[0].Similarity <= similar[1].Similarity || similar[0].Similarity > 1 || similar[1].Similarity <= 0 {
+		t.Errorf("unexpected similarities: %+v", similar)
+	}
+	if !slices.Contains(similar[0].Terms, "broker") {
+		t.Errorf("expected broker to be a shared term, got %v", similar[0].Terms)
+	}
+
+	limited, err := index.SimilarPages("kafka", 1)
+	if err != nil || len(limited) != 1 || limited[0].PageID != "broker" {
+		t.Errorf("SimilarPages with limit 1 = %+v, %v", limited, err)
+	}
+
+	none, err := index.SimilarPages("missing", 10)
+	if err != nil || len(none) != 0 {
+		t.Errorf("SimilarPages of an unindexed page = %+v, %v", none, err)
+	}
+}
@@ -0,0 +1,304 @@
+package snowball
+
+import "unicode/utf8"
+
+// englishExceptions are stemmed by lookup instead of by the algorithm.
+var englishExceptions = map[string]string{
+	"skis":   "ski",
+	"skies":  "sky",
+	"dying":  "die",
+	"lying":  "lie",
+	"tying":  "tie",
+	"idly":   "idl",
+	"gently": "gentl",
+	"ugly":   "ugli",
+	"early":  "earli",
+	"only":   "onli",
+	"singly": "singl",
+	"sky":    "sky",
+	"news":   "news",
+	"howe":   "howe",
+	"atlas":  "atlas",
+	"cosmos": "cosmos",
+	"bias":   "bias",
+	"andes":  "andes",
+}
+
+// englishInvariants are left alone once step 1a has run.
+var englishInvariants = map[string]bool{
+	"inning":  true,
+	"outing":  true,
+	"canning": true,
+	"herring": true,
+	"earring": true,
+	"proceed": true,
+	"exceed":  true,
+	"succeed": true,
+}
+
+var (
+	englishStep1bSuffixes = []string{"eedly", "ingly", "edly", "eed", "ing", "ed"}
+	englishStep2Suffixes  = []string{
+		"ization", "ational", "fulness", "ousness", "iveness",
+		"tional", "biliti", "lessli",
+		"entli", "ation", "alism", "aliti", "ousli", "iviti", "fulli",
+		"enci", "anci", "abli", "izer", "ator", "alli",
+		"bli", "ogi",
+		"li",
+	}
+	englishStep2Replacements = map[string]string{
+		"ization": "ize", "ational": "ate", "fulness": "ful", "ousness": "ous", "iveness": "ive",
+		"tional": "tion", "biliti": "ble", "lessli": "less",
+		"entli": "ent", "ation": "ate", "alism": "al", "aliti": "al", "ousli": "ous", "iviti": "ive", "fulli": "ful",
+		"enci": "ence", "anci": "ance", "abli": "able", "izer": "ize", "ator": "ate", "alli": "al",
+		"bli": "ble", "ogi": "og",
+		"li": "",
+	}
+	englishStep3Suffixes     = []string{"ational", "tional", "alize", "icate", "iciti", "ative", "ical", "ness", "ful"}
+	englishStep3Replacements = map[string]string{
+		"ational": "ate", "tional": "tion", "alize": "al", "icate": "ic", "iciti": "ic",
+		"ative": "", "ical": "ic", "ness": "", "ful": "",
+	}
+	englishStep4Suffixes = []string{
+		"ement",
+		"ance", "ence", "able", "ible", "ment",
+		"ant", "ent", "ism", "ate", "iti", "ous", "ive", "ize", "ion",
+		"al", "er", "ic",
+	}
+)
+
+func isEnglishVowel(r rune) bool {
+	switch r {
+	case 'a', 'e', 'i', 'o', 'u', 'y':
+		return true
+	}
+	return false
+}
+
+// English returns the stem of a lower case English word.
+func English(word string) string {
+	if utf8.RuneCountInString(word) <= 2 {
+		return word
+	}
+	if stem, ok := englishExceptions[word]; ok {
+		return stem
+	}
+
+	w := []rune(word)
+	if w[0] == '\'' {
+		w = w[1:]
+	}
+	for i, r := range w {
+		if r == 'y' && (i == 0 || isEnglishVowel(w[i-1])) {
+			w[i] = 'Y'
+		}
+	}
+	r1, r2 := englishRegions(w)
+
+	w = englishStep0(w)
+	w = englishStep1a(w)
+	if englishInvariants[string(w)] {
+		return englishPostlude(w)
+	}
+	w = englishStep1b(w, r1)
+	w = englishStep1c(w)
+	w = englishStep2(w, r1)
+	w = englishStep3(w, r1, r2)
+	w = englishStep4(w, r2)
+	w = englishStep5(w, r1, r2)
+	return englishPostlude(w)
+}
+
+func englishRegions(w []rune) (r1, r2 int) {
+	s := string(w)
+	switch {
+	case len(s) >= 5 && (s[:5] == "gener" || s[:5] == "arsen"):
+		r1 = 5
+	case len(s) >= 6 && s[:6] == "commun":
+		r1 = 6
+	default:
+		r1 = region(w, 0, isEnglishVowel)
+	}
+	return r1, region(w, r1, isEnglishVowel)
+}
+
+func englishPostlude(w []rune) string {
+	for i, r := range w {
+		if r == 'Y' {
+			w[i] = 'y'
+		}
+	}
+	return string(w)
+}
+
+// containsEnglishVowel reports whether w has a vowel.
+func containsEnglishVowel(w []rune) bool {
+	for _, r := range w {
+		if isEnglishVowel(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// endsWithShortSyllable reports whether w ends with a vowel followed by a
+// non-vowel other than w, x or Y and preceded by a non-vowel, or consists
+// of a vowel followed by a non-vowel.
+func endsWithShortSyllable(w []rune) bool {
+	n := len(w)
+	if n == 2 {
+		return isEnglishVowel(w[0]) && !isEnglishVowel(w[1])
+	}
+	if n < 3 {
+		return false
+	}
+	last := w[n-1]
+	return !isEnglishVowel(w[n-3]) && isEnglishVowel(w[n-2]) &&
+		!isEnglishVowel(last) && last != 'w' && last != 'x' && last != 'Y'
+}
+
+func isEnglishDouble(w []rune) bool {
+	n := len(w)
+	if n < 2 || w[n-1] != w[n-2] {
+		return false
+	}
+	switch w[n-1] {
+	case 'b', 'd', 'f', 'g', 'm', 'n', 'p', 'r', 't':
+		return true
+	}
+	return false
+}
+
+func isValidLiEnding(r rune) bool {
+	switch r {
+	case 'c', 'd', 'e', 'g', 'h', 'k', 'm', 'n', 'r', 't':
+		return true
+	}
+	return false
+}
+
+func englishStep0(w []rune) []rune {
+	for _, s := range []string{"'s'", "'s", "'"} {
+		if hasSuffix(w, s) {
+			return w[:suffixStart(w, s)]
+		}
+	}
+	return w
+}
+
+func englishStep1a(w []rune) []rune {
+	switch {
+	case hasSuffix(w, "sses"):
+		return replaceSuffix(w, 4, "ss")
+	case hasSuffix(w, "ied"), hasSuffix(w, "ies"):
+		if len(w) > 4 {
+			return replaceSuffix(w, 3, "i")
+		}
+		return replaceSuffix(w, 3, "ie")
+	case hasSuffix(w, "us"), hasSuffix(w, "ss"):
+		return w
+	case hasSuffix(w, "s"):
+		if containsEnglishVowel(w[:len(w)-2]) {
+			return w[:len(w)-1]
+		}
+	}
+	return w
+}
+
+func englishStep1b(w []rune, r1 int) []rune {
+	suffix := longestSuffix(w, englishStep1bSuffixes)
+	switch suffix {
+	case "":
+		return w
+	case "eed", "eedly":
+		if suffixStart(w, suffix) >= r1 {
+			return replaceSuffix(w, len(suffix), "ee")
+		}
+		return w
+	}
+
+	stem := w[:suffixStart(w, suffix)]
+	if !containsEnglishVowel(stem) {
+		return w
+	}
+	switch {
+	case hasSuffix(stem, "at"), hasSuffix(stem, "bl"), hasSuffix(stem, "iz"):
+		return append(stem, 'e')
+	case isEnglishDouble(stem):
+		return stem[:len(stem)-1]
+	case r1 >= len(stem) && endsWithShortSyllable(stem):
+		return append(stem, 'e')
+	}
+	return stem
+}
+
+func englishStep1c(w []rune) []rune {
+	n := len(w)
+	if n > 2 && (w[n-1] == 'y' || w[n-1] == 'Y') && !isEnglishVowel(w[n-2]) {
+		w[n-1] = 'i'
+	}
+	return w
+}
+
+func englishStep2(w []rune, r1 int) []rune {
+	suffix := longestSuffix(w, englishStep2Suffixes)
+	if suffix == "" || suffixStart(w, suffix) < r1 {
+		return w
+	}
+	start := suffixStart(w, suffix)
+	switch suffix {
+	case "ogi":
+		if start == 0 || w[start-1] != 'l' {
+			return w
+		}
+	case "li":
+		if start == 0 || !isValidLiEnding(w[start-1]) {
+			return w
+		}
+	}
+	return replaceSuffix(w, len(suffix), englishStep2Replacements[suffix])
+}
+
+func englishStep3(w []rune, r1, r2 int) []rune {
+	suffix := longestSuffix(w, englishStep3Suffixes)
+	if suffix == "" || suffixStart(w, suffix) < r1 {
+		return w
+	}
+	if suffix == "ative" && suffixStart(w, suffix) < r2 {
+		return w
+	}
+	return replaceSuffix(w, len(suffix), englishStep3Replacements[suffix])
+}
+
+func englishStep4(w []rune, r2 int) []rune {
+	suffix := longestSuffix(w, englishStep4Suffixes)
+	if suffix == "" {
+		return w
+	}
+	start := suffixStart(w, suffix)
+	if start < r2 {
+		return w
+	}
+	if suffix == "ion" && (start == 0 || (w[start-1] != 's' && w[start-1] != 't')) {
+		return w
+	}
+	return w[:start]
+}
+
+func englishStep5(w []rune, r1, r2 int) []rune {
+	n := len(w)
+	if n == 0 {
+		return w
+	}
+	switch w[n-1] {
+	case 'e':
+		if n-1 >= r2 || (n-1 >= r1 && !endsWithShortSyllable(w[:n-1])) {
+			return w[:n-1]
+		}
+	case 'l':
+		if n-1 >= r2 && n >= 2 && w[n-2] == 'l' {
+			return w[:n-1]
+		}
+	}
+	return w
+}
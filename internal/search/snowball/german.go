@@ -0,0 +1,182 @@
+package snowball
+
+var (
+	germanStep1Suffixes = []string{"ern", "em", "er", "en", "es", "e", "s"}
+	germanStep2Suffixes = []string{"est", "en", "er", "st"}
+	germanStep3Suffixes = []string{"isch", "lich", "heit", "keit", "end", "ung", "ig", "ik"}
+)
+
+func isGermanVowel(r rune) bool {
+	switch r {
+	case 'a', 'e', 'i', 'o', 'u', 'y', 'ä', 'ö', 'ü':
+		return true
+	}
+	return false
+}
+
+// isGermanSEnding reports whether an s may be removed after r.
+func isGermanSEnding(r rune) bool {
+	switch r {
+	case 'b', 'd', 'f', 'g', 'h', 'k', 'l', 'm', 'n', 'r', 't':
+		return true
+	}
+	return false
+}
+
+// isGermanStEnding reports whether an st may be removed after r.
+func isGermanStEnding(r rune) bool {
+	return r != 'r' && isGermanSEnding(r)
+}
+
+// German returns the stem of a lower case German word. Like the Snowball
+// "german2" variant it treats ae, oe and ue as ä, ö and ü, so that words
+// spelled without umlauts have the same stem.
+func German(word string) string {
+	w := germanPrelude([]rune(word))
+
+	r1 := max(region(w, 0, isGermanVowel), 3)
+	r2 := region(w, r1, isGermanVowel)
+
+	w = germanStep1(w, r1)
+	w = germanStep2(w, r1)
+	w = germanStep3(w, r1, r2)
+	return germanPostlude(w)
+}
+
+// germanPrelude marks u and y between vowels as consonants (U and Y) and
+// replaces ß by ss and ae, oe and ue (but not in qu) by umlauts.
+func germanPrelude(w []rune) []rune {
+	for i := 1; i+1 < len(w); i++ {
+		if (w[i] == 'u' || w[i] == 'y') && isGermanVowel(w[i-1]) && isGermanVowel(w[i+1]) {
+			if w[i] == 'u' {
+				w[i] = 'U'
+			} else {
+				w[i] = 'Y'
+			}
+		}
+	}
+
+	out := make([]rune, 0, len(w))
+	for i := 0; i < len(w); i++ {
+		r := w[i]
+		switch {
+		case r == 'ß':
+			out = append(out, 's', 's')
+		case r == 'q' && i+1 < len(w) && w[i+1] == 'u':
+			out = append(out, 'q', 'u')
+			i++
+		case i+1 < len(w) && w[i+1] == 'e' && (r == 'a' || r == 'o' || r == 'u'):
+			out = append(out, umlaut(r))
+			i++
+		default:
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// umlaut returns a, o or u with an umlaut.
+func umlaut(r rune) rune {
+	switch r {
+	case 'a':
+		return 'ä'
+	case 'o':
+		return 'ö'
+	}
+	return 'ü'
+}
+
+func germanPostlude(w []rune) string {
+	for i, r := range w {
+		switch r {
+		case 'U':
+			w[i] = 'u'
+		case 'Y':
+			w[i] = 'y'
+		case 'ä':
+			w[i] = 'a'
+		case 'ö':
+			w[i] = 'o'
+		case 'ü':
+			w[i] = 'u'
+		}
+	}
+	return string(w)
+}
+
+func germanStep1(w []rune, r1 int) []rune {
+	suffix := longestSuffix(w, germanStep1Suffixes)
+	if suffix == "" {
+		return w
+	}
+	start := suffixStart(w, suffix)
+	if start < r1 {
+		return w
+	}
+	switch suffix {
+	case "s":
+		if start == 0 || !isGermanSEnding(w[start-1]) {
+			return w
+		}
+		return w[:start]
+	case "e", "en", "es":
+		w = w[:start]
+		if hasSuffix(w, "niss") {
+			w = w[:len(w)-1]
+		}
+		return w
+	}
+	return w[:start]
+}
+
+func germanStep2(w []rune, r1 int) []rune {
+	suffix := longestSuffix(w, germanStep2Suffixes)
+	if suffix == "" {
+		return w
+	}
+	start := suffixStart(w, suffix)
+	if start < r1 {
+		return w
+	}
+	if suffix == "st" && (start < 4 || !isGermanStEnding(w[start-1])) {
+		return w
+	}
+	return w[:start]
+}
+
+func germanStep3(w []rune, r1, r2 int) []rune {
+	suffix := longestSuffix(w, germanStep3Suffixes)
+	if suffix == "" {
+		return w
+	}
+	start := suffixStart(w, suffix)
+	if start < r2 {
+		return w
+	}
+	precededByE := start > 0 && w[start-1] == 'e'
+	switch suffix {
+	case "end", "ung":
+		w = w[:start]
+		if hasSuffix(w, "ig") {
+			igStart := suffixStart(w, "ig")
+			if igStart >= r2 && (igStart == 0 || w[igStart-1] != 'e') {
+				w = w[:igStart]
+			}
+		}
+	case "ig", "ik", "isch":
+		if !precededByE {
+			w = w[:start]
+		}
+	case "lich", "heit":
+		w = w[:start]
+		if (hasSuffix(w, "er") || hasSuffix(w, "en")) && suffixStart(w, "er") >= r1 {
+			w = w[:len(w)-2]
+		}
+	case "keit":
+		w = w[:start]
+		if next := longestSuffix(w, []string{"lich", "ig"}); next != "" && suffixStart(w, next) >= r2 {
+			w = w[:suffixStart(w, next)]
+		}
+	}
+	return w
+}
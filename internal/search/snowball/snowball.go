@@ -0,0 +1,54 @@
+// Package snowball implements the English (Porter2) and German stemmers of
+// the Snowball project (https://snowballstem.org) in plain Go.
+//
+// The stemmers expect a single lower case word and return its stem. They do
+// not tokenize, and words in other scripts pass through mostly unchanged.
+package snowball
+
+// region returns the start of the region after the first non-vowel that
+// follows a vowel at or after start, or len(w) when there is none. It is
+// used to find R1 (from 0) and R2 (from R1).
+func region(w []rune, start int, isVowel func(rune) bool) int {
+	for i := start + 1; i < len(w); i++ {
+		if isVowel(w[i-1]) && !isVowel(w[i]) {
+			return i + 1
+		}
+	}
+	return len(w)
+}
+
+// hasSuffix reports whether w ends with suffix.
+func hasSuffix(w []rune, suffix string) bool {
+	s := []rune(suffix)
+	if len(s) > len(w) {
+		return false
+	}
+	for i := range s {
+		if w[len(w)-len(s)+i] != s[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// longestSuffix returns the first of suffixes that w ends with, or "".
+// suffixes are ordered longest first, so that the first match is the
+// longest one as the Snowball "among" construct requires.
+func longestSuffix(w []rune, suffixes []string) string {
+	for _, s := range suffixes {
+		if hasSuffix(w, s) {
+			return s
+		}
+	}
+	return ""
+}
+
+// replaceSuffix replaces the last n runes of w with replacement.
+func replaceSuffix(w []rune, n int, replacement string) []rune {
+	return append(w[:len(w)-n], []rune(replacement)...)
+}
+
+// suffixStart returns the index in w at which suffix starts.
+func suffixStart(w []rune, suffix string) int {
+	return len(w) - len([]rune(suffix))
+}
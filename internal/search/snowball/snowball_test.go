@@ -0,0 +1,73 @@
+package snowball
+
+import "testing"
+
+func TestEnglish(t *testing.T) {
+	cases := map[string]string{
+		"consign":        "consign",
+		"consigned":      "consign",
+		"consignment":    "consign",
+		"consistency":    "consist",
+		"consistently":   "consist",
+		"consolation":    "consol",
+		"consolatory":    "consolatori",
+		"consolidating":  "consolid",
+		"consolingly":    "consol",
+		"conspicuously":  "conspicu",
+		"conspiracy":     "conspiraci",
+		"conspirators":   "conspir",
+		"constables":     "constabl",
+		"knackeries":     "knackeri",
+		"knaves":         "knave",
+		"kneaded":        "knead",
+		"knightly":       "knight",
+		"knitting":       "knit",
+		"knives":         "knive",
+		"generously":     "generous",
+		"communication":  "communic",
+		"deployments":    "deploy",
+		"caresses":       "caress",
+		"ponies":         "poni",
+		"ties":           "tie",
+		"cries":          "cri",
+		"hopping":        "hop",
+		"hoping":         "hope",
+		"luxuriating":    "luxuri",
+		"skies":          "sky",
+		"dying":          "die",
+		"succeeding":     "succeed",
+		"'tis":           "tis",
+		"by":             "by",
+		"certificates":   "certif",
+		"certifications": "certif",
+	}
+	for word, want := range cases {
+		if got := English(word); got != want {
+			t.Errorf("English(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestGerman(t *testing.T) {
+	cases := map[string]string{
+		"zertifikat":           "zertifikat",
+		"zertifikate":          "zertifikat",
+		"zertifikaten":         "zertifikat",
+		"zertifikats":          "zertifikat",
+		"haus":                 "haus",
+		"häuser":               "haus",
+		"häusern":              "haus",
+		"bedeutung":            "bedeut",
+		"größe":                "gross",
+		"groesse":              "gross",
+		"ergebnisse":           "ergebnis",
+		"aufeinanderfolgenden": "aufeinanderfolg",
+		"möglichkeiten":        "moglich",
+		"quelle":               "quell",
+	}
+	for word, want := range cases {
+		if got := German(word); got != want {
+			t.Errorf("German(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
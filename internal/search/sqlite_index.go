@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log/slog"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -20,11 +22,25 @@ import (
 	_ "modernc.org/sqlite"
 )
 
+// searchSchemaVersion is stored as the database's user_version. Bump it
+// whenever the tables change; the index is then recreated empty.
+const searchSchemaVersion = 5
+
 type SQLiteIndex struct {
 	mu         sync.RWMutex
 	storageDir string
 	filename   string
 	db         *sql.DB
+	recreated  bool
+	// termsStale is set when pages changed since search_terms was last
+	// brought up to date; see refreshTerms.
+	termsStale bool
+	// analyzer fills the stemmed columns; see SetAnalyzer.
+	analyzer         Analyzer
+	analyzerOutdated bool
+	// languages are the languages pages were analyzed in, always including
+	// LanguageNone and the analyzer's language.
+	languages []Language
 }
 
 func searchIndexDatabasePath(storageDir string, filename string) string {
@@ -73,40 +89,11 @@ func extractHeadings(src string) string {
 	return excerpt.PlainTextFromMarkdown(buf.String())
 }
 
-func buildFuzzyQuery(q string) string {
-	q = strings.TrimSpace(q)
-	if q == "" {
-		return q
-	}
-
-	// if the query contains special FTS5 syntax, return as is
-	if strings.ContainsAny(q, "\"*():") ||
-		strings.Contains(strings.ToUpper(q), " OR ") ||
-		strings.Contains(strings.ToUpper(q), " AND ") {
-		return q
-	}
-
-	if strings.ContainsAny(q, "-_+#/.") {
-		return `"` + q + `"`
-	}
-
-	// Append wildcard to each term
-	terms := strings.Fields(q)
-	for i, t := range terms {
-		// Skip if already has wildcard
-		if strings.Contains(t, "*") {
-			continue
-		}
-		terms[i] = t + "*"
-	}
-
-	return strings.Join(terms, " ")
-}
-
 func NewSQLiteIndex(storageDir string) (*SQLiteIndex, error) {
 	s := &SQLiteIndex{
 		storageDir: storageDir,
 		filename:   "search.db",
+		termsStale: true,
 	}
 
 	err := sqliteutil.RetryOnCorruption(searchIndexDatabasePath(s.storageDir, s.filename), func() error {
@@ -121,6 +108,10 @@ func NewSQLiteIndex(storageDir string) (*SQLiteIndex, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := s.SetAnalyzer(Analyzer{}); err != nil {
+		_ = s.Close()
+		return nil, err
+	}
 
 	return s, nil
 }
@@ -183,7 +174,20 @@ func (s *SQLiteIndex) withDBRead(fn func(db *sql.DB) error) error {
 
 func (s *SQLiteIndex) ensureSchema() error {
 	return s.withDB(func(db *sql.DB) error {
+		var version int
+		if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+			return err
+		}
+		if version == searchSchemaVersion {
+			return nil
+		}
+		s.recreated = true
 		_, err := db.Exec(`
+			DROP TABLE IF EXISTS search_settings;
+			DROP TABLE IF EXISTS heading_sections;
+			DROP TABLE IF EXISTS attachments;
+			DROP TABLE IF EXISTS search_terms;
+			DROP TABLE IF EXISTS pages_vocab;
 			DROP TABLE IF EXISTS pages;
 			CREATE VIRTUAL TABLE IF NOT EXISTS pages USING fts5(
 				path UNINDEXED,
@@ -193,17 +197,94 @@ func (s *SQLiteIndex) ensureSchema() error {
 				title,
 				headings,
 				content,
-				tokenize = "unicode61 tokenchars '-_/+#.'"
+				lang UNINDEXED,
+				title_stems,
+				headings_stems,
+				content_stems,
+				tokenize = "unicode61 remove_diacritics 2 tokenchars '-_/+#.'"
+			);
+			CREATE VIRTUAL TABLE IF NOT EXISTS pages_vocab USING fts5vocab(pages, 'col');
+			-- One row per top-level heading of a page. The columns are named
+			-- like those of pages so that both take the same MATCH
+			-- expressions; title repeats the page title and headings holds
+			-- the section's heading.
+			CREATE VIRTUAL TABLE IF NOT EXISTS heading_sections USING fts5(
+				pageID UNINDEXED,
+				anchor UNINDEXED,
+				title,
+				headings,
+				content,
+				title_stems,
+				headings_stems,
+				content_stems,
+				tokenize = "unicode61 remove_diacritics 2 tokenchars '-_/+#.'"
+			);
+			-- The text of the attachments of pages, see IndexAttachment.
+			CREATE VIRTUAL TABLE IF NOT EXISTS attachments USING fts5(
+				pageID UNINDEXED,
+				filename,
+				content,
+				tokenize = "unicode61 remove_diacritics 2 tokenchars '-_/+#.'"
 			);
+			CREATE VIRTUAL TABLE IF NOT EXISTS search_terms USING fts5(
+				term,
+				tokenize = "trigram"
+			);
+			CREATE TABLE IF NOT EXISTS search_settings (
+				key TEXT PRIMARY KEY,
+				value TEXT NOT NULL
+			);
+			PRAGMA user_version = ` + strconv.Itoa(searchSchemaVersion) + `;
         `)
 		return err
 	})
 }
 
+// Recreated reports whether opening the index created it empty, either
+// because it did not exist or because its schema was outdated.
+func (s *SQLiteIndex) Recreated() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.recreated
+}
+
+// Clear removes all pages and attachments. The empty index counts as analyzed with the
+// current analyzer.
 func (s *SQLiteIndex) Clear() error {
 	return s.withDB(func(db *sql.DB) error {
-		_, err := db.Exec(`DELETE FROM pages`)
-		return err
+		s.termsStale = true
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = tx.Rollback()
+		}()
+		if _, err := tx.Exec(`DELETE FROM pages`); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM heading_sections`); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM attachments`); err != nil {
+			return err
+		}
+		languages := []Language{LanguageNone}
+		if s.analyzer.Language != LanguageNone {
+			languages = append(languages, s.analyzer.Language)
+		}
+		if err := writeSetting(tx, settingAnalyzer, s.analyzer.settings()); err != nil {
+			return err
+		}
+		if err := writeSetting(tx, settingLanguages, formatLanguages(languages)); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		s.analyzerOutdated = false
+		s.languages = languages
+		return nil
 	})
 }
 
@@ -262,22 +343,34 @@ func (s *SQLiteIndex) IndexPages(inputs []IndexPageInput) ([]IndexFailure, error
 		return nil, nil
 	}
 
+	type preparedSection struct {
+		headingSection
+		headingStems, contentStems string
+	}
 	type prepared struct {
 		path, filePath, pageID, title string
 		kind                          tree.NodeKind
 		headings, sanitizedBody       string
+		lang                          Language
+		titleStems, headingsStems     string
+		contentStems                  string
+		sections                      []preparedSection
 	}
 
+	s.mu.RLock()
+	analyzer := s.analyzer
+	s.mu.RUnlock()
+
 	var failures []IndexFailure
 	prepped := make([]prepared, 0, len(inputs))
 	for _, in := range inputs {
-		_, content, _, err := markdown.ParseFrontmatter(in.Raw)
+		fm, content, _, err := markdown.ParseFrontmatter(in.Raw)
 		if err != nil {
 			failures = append(failures, IndexFailure{PageID: in.PageID, Err: err})
 			continue
 		}
 		content = excerpt.NormalizeMarkdownBody(content)
-		prepped = append(prepped, prepared{
+		p := prepared{
 			path:          in.Path,
 			filePath:      in.FilePath,
 			pageID:        in.PageID,
@@ -285,7 +378,19 @@ func (s *SQLiteIndex) IndexPages(inputs []IndexPageInput) ([]IndexFailure, error
 			kind:          in.Kind,
 			headings:      extractHeadings(content),
 			sanitizedBody: excerpt.PlainTextForSearch(content),
-		})
+			lang:          pageLanguage(fm.ExtraFields["lang"], analyzer.Language),
+		}
+		p.titleStems = analyzer.analyze(p.title, p.lang)
+		p.headingsStems = analyzer.analyze(p.headings, p.lang)
+		p.contentStems = analyzer.analyze(p.sanitizedBody, p.lang)
+		for _, section := range extractHeadingSections(content) {
+			p.sections = append(p.sections, preparedSection{
+				headingSection: section,
+				headingStems:   analyzer.analyze(section.heading, p.lang),
+				contentStems:   analyzer.analyze(section.content, p.lang),
+			})
+		}
+		prepped = append(prepped, p)
 	}
 
 	if len(prepped) == 0 {
@@ -293,6 +398,7 @@ func (s *SQLiteIndex) IndexPages(inputs []IndexPageInput) ([]IndexFailure, error
 	}
 
 	err := s.withDB(func(db *sql.DB) error {
+		s.termsStale = true
 		tx, err := db.Begin()
 		if err != nil {
 			return err
@@ -310,8 +416,8 @@ func (s *SQLiteIndex) IndexPages(inputs []IndexPageInput) ([]IndexFailure, error
 		}()
 
 		insertStmt, err := tx.Prepare(`
-			INSERT INTO pages (path, filepath, pageID, kind, title, headings, content)
-			VALUES (?, ?, ?, ?, ?, ?, ?);
+			INSERT INTO pages (path, filepath, pageID, kind, title, headings, content, lang, title_stems, headings_stems, content_stems)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
 		`)
 		if err != nil {
 			return err
@@ -320,16 +426,58 @@ func (s *SQLiteIndex) IndexPages(inputs []IndexPageInput) ([]IndexFailure, error
 			_ = insertStmt.Close()
 		}()
 
+		deleteSectionsStmt, err := tx.Prepare(`DELETE FROM heading_sections WHERE pageID = ?`)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = deleteSectionsStmt.Close()
+		}()
+
+		insertSectionStmt, err := tx.Prepare(`
+			INSERT INTO heading_sections (pageID, anchor, title, headings, content, title_stems, headings_stems, content_stems)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?);
+		`)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = insertSectionStmt.Close()
+		}()
+
+		languages := s.languages
 		for _, p := range prepped {
 			if _, err := deleteStmt.Exec(p.pageID); err != nil {
 				return err
 			}
-			if _, err := insertStmt.Exec(p.path, p.filePath, p.pageID, string(p.kind), p.title, p.headings, p.sanitizedBody); err != nil {
+			if _, err := insertStmt.Exec(p.path, p.filePath, p.pageID, string(p.kind), p.title, p.headings, p.sanitizedBody,
+				string(p.lang), p.titleStems, p.headingsStems, p.contentStems); err != nil {
+				return err
+			}
+			if _, err := deleteSectionsStmt.Exec(p.pageID); err != nil {
+				return err
+			}
+			for _, section := range p.sections {
+				if _, err := insertSectionStmt.Exec(p.pageID, section.anchor, p.title, section.heading, section.content,
+					p.titleStems, section.headingStems, section.contentStems); err != nil {
+					return err
+				}
+			}
+			if !slices.Contains(languages, p.lang) {
+				languages = append(slices.Clip(languages), p.lang)
+			}
+		}
+		if len(languages) != len(s.languages) {
+			if err := writeSetting(tx, settingLanguages, formatLanguages(languages)); err != nil {
 				return err
 			}
 		}
 
-		return tx.Commit()
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		s.languages = languages
+		return nil
 	})
 
 	return failures, err
@@ -355,6 +503,7 @@ func (s *SQLiteIndex) RemovePages(pageIDs []string) error {
 		return nil
 	}
 	return s.withDB(func(db *sql.DB) error {
+		s.termsStale = true
 		tx, err := db.Begin()
 		if err != nil {
 			return err
@@ -371,9 +520,27 @@ func (s *SQLiteIndex) RemovePages(pageIDs []string) error {
 			_ = stmt.Close()
 		}()
 
+		sectionsStmt, err := tx.Prepare(`DELETE FROM heading_sections WHERE pageID = ?`)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = sectionsStmt.Close()
+		}()
+
+		attachmentsStmt, err := tx.Prepare(`DELETE FROM attachments WHERE pageID = ?`)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = attachmentsStmt.Close()
+		}()
+
 		for _, pageID := range pageIDs {
-			if _, err := stmt.Exec(pageID); err != nil {
-				return err
+			for _, st := range []*sql.Stmt{stmt, sectionsStmt, attachmentsStmt} {
+				if _, err := st.Exec(pageID); err != nil {
+					return err
+				}
 			}
 		}
 
@@ -382,16 +549,27 @@ func (s *SQLiteIndex) RemovePages(pageIDs []string) error {
 }
 
 func (s *SQLiteIndex) RemovePage(pageID string) error {
-	return s.withDB(func(db *sql.DB) error {
-		_, err := db.Exec(`DELETE FROM pages WHERE pageID = ?`, pageID)
-		return err
-	})
+	return s.RemovePages([]string{pageID})
 }
 
 func (s *SQLiteIndex) RemovePageByFilePath(filePath string) (int64, error) {
 	var rows int64
 	err := s.withDB(func(db *sql.DB) error {
-		res, err := db.Exec(`DELETE FROM pages WHERE filepath = ?`, filePath)
+		s.termsStale = true
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = tx.Rollback()
+		}()
+
+		for _, table := range []string{"heading_sections", "attachments"} {
+			if _, err := tx.Exec(`DELETE FROM `+table+` WHERE pageID IN (SELECT pageID FROM pages WHERE filepath = ?)`, filePath); err != nil {
+				return err
+			}
+		}
+		res, err := tx.Exec(`DELETE FROM pages WHERE filepath = ?`, filePath)
 		if err != nil {
 			return err
 		}
@@ -400,25 +578,43 @@ func (s *SQLiteIndex) RemovePageByFilePath(filePath string) (int64, error) {
 			return err
 		}
 		rows = r
-		return nil
+		return tx.Commit()
 	})
 	return rows, err
 }
 
-func (s *SQLiteIndex) Search(query string, pageIDs []string, offset, limit int) (*SearchResult, error) {
-	query = strings.TrimSpace(query)
+// SearchScope restricts a search to a set of pages.
+type SearchScope struct {
+	// PageIDs limits the search to these pages when non-nil; an empty,
+	// non-nil slice matches nothing.
+	PageIDs []string
+	// ExcludedPageIDs are never returned.
+	ExcludedPageIDs []string
+}
 
-	if len(pageIDs) == 0 && pageIDs != nil {
-		return &SearchResult{
-			Count:     0,
-			Items:     []SearchResultItem{},
-			Offset:    offset,
-			Limit:     limit,
-			TagFacets: []SearchTagFacet{},
-		}, nil
+// Search parses query with ParseQuery and runs it on pageIDs (all pages when
+// nil).
+func (s *SQLiteIndex) Search(query string, pageIDs []string, offset, limit int) (*SearchResult, error) {
+	q, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
 	}
+	return s.SearchQuery(q, SearchScope{PageIDs: pageIDs}, offset, limit)
+}
 
-	if query == "" && len(pageIDs) == 0 {
+// SearchQuery runs a parsed query. The index applies the query's terms and
+// path filters; filters on tags, properties, authors and update times are
+// left to the caller, which resolves them into scope. Without terms to
+// match, the pages in scope are listed by title. A query with few exact hits
+// is expanded with spelling corrections; the fuzzy hits follow the exact
+// ones.
+//
+// Pages are ranked on their whole text, which adds up what each of their
+// sections contributes. Every hit lists its best matching sections, see
+// SearchHeadingHit. The first page of results also lists the matching
+// attachments of the pages in scope.
+func (s *SQLiteIndex) SearchQuery(q *Query, scope SearchScope, offset, limit int) (*SearchResult, error) {
+	if (scope.PageIDs != nil && len(scope.PageIDs) == 0) || (q.IsEmpty() && scope.PageIDs == nil) {
 		return &SearchResult{
 			Count:     0,
 			Items:     []SearchResultItem{},
@@ -428,20 +624,82 @@ func (s *SQLiteIndex) Search(query string, pageIDs []string, offset, limit int)
 		}, nil
 	}
 
-	sr := &SearchResult{TagFacets: []SearchTagFacet{}}
-	ftsQuery := buildFuzzyQuery(query)
+	q = s.currentAnalyzer().withoutStopwords(q)
+	stems := s.stemFunc()
+	hasQuery := q.HasText()
+	whereClause, whereArgs := buildSearchWhereClause(q, scope, stems)
+
+	var exactCount int
+	if err := s.withDBRead(func(db *sql.DB) (err error) {
+		exactCount, err = countMatches(db, whereClause, whereArgs)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	fuzzy, err := s.fuzzyExpansion(q, scope, stems, whereClause, whereArgs, exactCount)
+	if err != nil {
+		return nil, err
+	}
 
-	err := s.withDBRead(func(db *sql.DB) error {
-		var total int
-		whereClause, whereArgs := buildSearchWhereClause(query, ftsQuery, pageIDs)
+	sr := &SearchResult{Count: exactCount, Offset: offset, Limit: limit, TagFacets: []SearchTagFacet{}}
+	err = s.withDBRead(func(db *sql.DB) error {
+		if offset == 0 {
+			attachments, count, err := searchAttachments(db, q, scope)
+			if err != nil {
+				return err
+			}
+			sr.Attachments, sr.AttachmentCount = attachments, count
+		}
+		if offset < exactCount {
+			items, err := searchItems(db, whereClause, whereArgs, hasQuery, offset, limit)
+			if err != nil {
+				return err
+			}
+			if err := attachHeadingHits(db, items, q.ftsSectionMatch(stems)); err != nil {
+				return err
+			}
+			sr.Items = items
+		}
+		if fuzzy == nil {
+			return nil
+		}
 
-		countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM pages WHERE %s;`, whereClause)
-		if err := db.QueryRow(countQuery, whereArgs...).Scan(&total); err != nil {
+		sr.Suggestion = fuzzy.suggestion
+		fuzzyCount, err := countMatches(db, fuzzy.where, fuzzy.args)
+		if err != nil {
+			return err
+		}
+		sr.Count += fuzzyCount
+		remaining := limit - len(sr.Items)
+		if fuzzyCount == 0 || remaining <= 0 {
+			return nil
+		}
+		items, err := searchItems(db, fuzzy.where, fuzzy.args, true, max(0, offset-exactCount), remaining)
+		if err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].Fuzzy = true
+			items[i].Rank *= fuzzyRankFactor
+		}
+		if err := attachHeadingHits(db, items, fuzzy.query.ftsSectionMatch(stems)); err != nil {
 			return err
 		}
-		sr.Count = total
+		sr.Items = append(sr.Items, items...)
+		return nil
+	})
 
-		searchQuery := fmt.Sprintf(`
+	return sr, err
+}
+
+func countMatches(db *sql.DB, whereClause string, whereArgs []interface{}) (int, error) {
+	var total int
+	err := db.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM pages WHERE %s;`, whereClause), whereArgs...).Scan(&total)
+	return total, err
+}
+
+func searchItems(db *sql.DB, whereClause string, whereArgs []interface{}, hasQuery bool, offset, limit int) ([]SearchResultItem, error) {
+	searchQuery := fmt.Sprintf(`
 		SELECT 
 			pageID,
 			path,
@@ -455,130 +713,251 @@ func (s *SQLiteIndex) Search(query string, pageIDs []string, offset, limit int)
 		ORDER BY %s
 		LIMIT ? OFFSET ?;
 	`,
-			searchTitleExpr(query != ""),
-			searchExcerptExpr(query != ""),
-			searchRankExpr(query != ""),
-			whereClause,
-			searchOrderByExpr(query != ""),
+		searchTitleExpr(hasQuery),
+		searchExcerptExpr(hasQuery),
+		searchRankExpr(hasQuery),
+		whereClause,
+		searchOrderByExpr(hasQuery),
+	)
+
+	queryArgs := append(append([]interface{}{}, whereArgs...), limit, offset)
+	rows, err := db.Query(searchQuery, queryArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Default().Error("could not close rows", "error", err)
+		}
+	}()
+
+	var results []SearchResultItem
+	for rows.Next() {
+		var r SearchResultItem
+		var bm25Score float64
+		var content string
+
+		if err := rows.Scan(&r.PageID, &r.Path, &r.Kind, &r.Title, &r.Excerpt, &content, &bm25Score); err != nil {
+			return nil, err
+		}
+		r.Title = sanitizeSearchTitle(r.Title)
+		if strings.TrimSpace(r.Excerpt) == "" {
+			r.Excerpt = excerpt.FromBody(content)
+		}
+
+		if !hasQuery {
+			r.Rank = 1
+		} else {
+			// Convert bm25 score to a rank (lower score = higher rank)
+			if bm25Score < 0 {
+				bm25Score = 0
+			}
+			r.Rank = 1.0 / (1.0 + bm25Score)
+		}
+
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// maxHeadingHits is the number of sections listed per search hit.
+const maxHeadingHits = 3
+
+// attachHeadingHits adds to each item the best sections matching the FTS5
+// expression match, see Query.ftsSectionMatch.
+func attachHeadingHits(db *sql.DB, items []SearchResultItem, match string) error {
+	if match == "" || len(items) == 0 {
+		return nil
+	}
+	stmt, err := db.Prepare(`
+		SELECT
+			anchor,
+			highlight(heading_sections, 3, char(2), char(3)),
+			snippet(heading_sections, 4, '<b>', '</b>', '...', 16)
+		FROM heading_sections
+		WHERE heading_sections MATCH ? AND pageID = ?
+		ORDER BY bm25(heading_sections,
+			0.0, -- pageID
+			0.0, -- anchor
+			0.0, -- title, the same in every section of a page
+			5.0, -- headings
+			1.0, -- content
+			0.0, -- title_stems
+			2.5, -- headings_stems
+			0.5  -- content_stems
 		)
+		LIMIT ?;
+	`)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
 
-		queryArgs := append(append([]interface{}{}, whereArgs...), limit, offset)
-		rows, err := db.Query(searchQuery, queryArgs...)
+	for i := range items {
+		rows, err := stmt.Query(match, items[i].PageID, maxHeadingHits)
 		if err != nil {
 			return err
 		}
-		defer func() {
-			if err := rows.Close(); err != nil {
-				slog.Default().Error("could not close rows", "error", err)
-			}
-		}()
-
-		var results []SearchResultItem
 		for rows.Next() {
-			var r SearchResultItem
-			var bm25Score float64
-			var content string
-
-			if err := rows.Scan(&r.PageID, &r.Path, &r.Kind, &r.Title, &r.Excerpt, &content, &bm25Score); err != nil {
+			var hit SearchHeadingHit
+			if err := rows.Scan(&hit.Anchor, &hit.Heading, &hit.Excerpt); err != nil {
+				_ = rows.Close()
 				return err
 			}
-			r.Title = sanitizeSearchTitle(r.Title)
-			if strings.TrimSpace(r.Excerpt) == "" {
-				r.Excerpt = excerpt.FromBody(content)
-			}
-
-			if query == "" {
-				r.Rank = 1
-			} else {
-				// Convert bm25 score to a rank (lower score = higher rank)
-				if bm25Score < 0 {
-					bm25Score = 0
-				}
-				r.Rank = 1.0 / (1.0 + bm25Score)
-			}
-
-			results = append(results, r)
+			hit.Heading = sanitizeSearchTitle(hit.Heading)
+			items[i].Headings = append(items[i].Headings, hit)
+		}
+		if err := rows.Close(); err != nil {
+			return err
 		}
 		if err := rows.Err(); err != nil {
 			return err
 		}
-		sr.Items = results
-		sr.Offset = offset
-		sr.Limit = limit
-		return nil
-	})
-
-	return sr, err
+	}
+	return nil
 }
 
+// SearchPageIDs parses query with ParseQuery and returns the IDs of all
+// matching pages in rank order.
 func (s *SQLiteIndex) SearchPageIDs(query string, pageIDs []string) ([]string, error) {
-	query = strings.TrimSpace(query)
-
-	if len(pageIDs) == 0 && pageIDs != nil {
-		return []string{}, nil
+	q, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
 	}
+	return s.SearchQueryPageIDs(q, SearchScope{PageIDs: pageIDs})
+}
+
+// SearchQueryPageIDs returns the IDs of all pages matching a parsed query in
+// rank order, including fuzzy hits; see SearchQuery.
+func (s *SQLiteIndex) SearchQueryPageIDs(q *Query, scope SearchScope) ([]string, error) {
+	return s.queryPageIDs(q, scope, true)
+}
+
+// ExactQueryPageIDs is SearchQueryPageIDs without the hits of spelling
+// corrections.
+func (s *SQLiteIndex) ExactQueryPageIDs(q *Query, scope SearchScope) ([]string, error) {
+	return s.queryPageIDs(q, scope, false)
+}
 
-	if query == "" && len(pageIDs) == 0 {
+func (s *SQLiteIndex) queryPageIDs(q *Query, scope SearchScope, fuzzy bool) ([]string, error) {
+	if (scope.PageIDs != nil && len(scope.PageIDs) == 0) || (q.IsEmpty() && scope.PageIDs == nil) {
 		return []string{}, nil
 	}
 
-	ftsQuery := buildFuzzyQuery(query)
+	q = s.currentAnalyzer().withoutStopwords(q)
+	stems := s.stemFunc()
+	hasQuery := q.HasText()
+	whereClause, whereArgs := buildSearchWhereClause(q, scope, stems)
+
 	var result []string
+	if err := s.withDBRead(func(db *sql.DB) (err error) {
+		result, err = matchingPageIDs(db, whereClause, whereArgs, hasQuery)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if !fuzzy {
+		return result, nil
+	}
+
+	expansion, err := s.fuzzyExpansion(q, scope, stems, whereClause, whereArgs, len(result))
+	if err != nil || expansion == nil {
+		return result, err
+	}
+	err = s.withDBRead(func(db *sql.DB) error {
+		fuzzyIDs, err := matchingPageIDs(db, expansion.where, expansion.args, true)
+		result = append(result, fuzzyIDs...)
+		return err
+	})
+	return result, err
+}
 
-	err := s.withDBRead(func(db *sql.DB) error {
-		whereClause, whereArgs := buildSearchWhereClause(query, ftsQuery, pageIDs)
-		searchQuery := fmt.Sprintf(`
+func matchingPageIDs(db *sql.DB, whereClause string, whereArgs []interface{}, hasQuery bool) ([]string, error) {
+	searchQuery := fmt.Sprintf(`
 		SELECT pageID, %s AS bm25_score
 		FROM pages
 		WHERE %s
 		ORDER BY %s;
-	`, searchRankExpr(query != ""), whereClause, searchOrderByExpr(query != ""))
+	`, searchRankExpr(hasQuery), whereClause, searchOrderByExpr(hasQuery))
 
-		rows, err := db.Query(searchQuery, whereArgs...)
-		if err != nil {
-			return err
+	rows, err := db.Query(searchQuery, whereArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Default().Error("could not close rows", "error", err)
 		}
-		defer func() {
-			if err := rows.Close(); err != nil {
-				slog.Default().Error("could not close rows", "error", err)
-			}
-		}()
+	}()
 
-		for rows.Next() {
-			var pageID string
-			var bm25Score float64
-			if err := rows.Scan(&pageID, &bm25Score); err != nil {
-				return err
-			}
-			result = append(result, pageID)
+	var result []string
+	for rows.Next() {
+		var pageID string
+		var bm25Score float64
+		if err := rows.Scan(&pageID, &bm25Score); err != nil {
+			return nil, err
 		}
-
-		return rows.Err()
-	})
-
-	return result, err
+		result = append(result, pageID)
+	}
+	return result, rows.Err()
 }
 
-func buildSearchWhereClause(query string, ftsQuery string, pageIDs []string) (string, []interface{}) {
-	clauses := make([]string, 0, 2)
-	args := make([]interface{}, 0, 1+len(pageIDs))
+func buildSearchWhereClause(q *Query, scope SearchScope, stems stemFunc) (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
 
-	if query != "" {
+	if match := q.ftsMatch(stems); match != "" {
 		clauses = append(clauses, "pages MATCH ?")
-		args = append(args, ftsQuery)
+		args = append(args, match)
+	}
+	if exclude := q.ftsExclude(stems); exclude != "" {
+		clauses = append(clauses, "pageID NOT IN (SELECT pageID FROM pages WHERE pages MATCH ?)")
+		args = append(args, exclude)
 	}
 
-	if len(pageIDs) > 0 {
-		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(pageIDs)), ",")
-		clauses = append(clauses, fmt.Sprintf("pageID IN (%s)", placeholders))
-		for _, pageID := range pageIDs {
+	for _, f := range q.FiltersOf(FilterPath) {
+		clause := `(path = ? OR path LIKE ? ESCAPE '\')`
+		if f.Negated {
+			clause = "NOT " + clause
+		}
+		clauses = append(clauses, clause)
+		args = append(args, f.Value, escapeLikePrefix(f.Value)+"/%")
+	}
+
+	if scope.PageIDs != nil {
+		clauses = append(clauses, fmt.Sprintf("pageID IN (%s)", placeholders(len(scope.PageIDs))))
+		for _, pageID := range scope.PageIDs {
+			args = append(args, pageID)
+		}
+	}
+	if len(scope.ExcludedPageIDs) > 0 {
+		clauses = append(clauses, fmt.Sprintf("pageID NOT IN (%s)", placeholders(len(scope.ExcludedPageIDs))))
+		for _, pageID := range scope.ExcludedPageIDs {
 			args = append(args, pageID)
 		}
 	}
 
+	if len(clauses) == 0 {
+		return "1", args
+	}
 	return strings.Join(clauses, " AND "), args
 }
 
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// escapeLikePrefix escapes LIKE special characters so that '%', '_', and '\'
+// are treated as literals and not SQL wildcards.
+func escapeLikePrefix(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `%`, `\%`)
+	s = strings.ReplaceAll(s, `_`, `\_`)
+	return s
+}
+
 func searchTitleExpr(hasQuery bool) string {
 	if hasQuery {
 		return "highlight(pages, 4, char(2), char(3))"
@@ -609,8 +988,12 @@ func searchRankExpr(hasQuery bool) string {
 				0.0,  -- pageID
 				0.0,  -- kind
 				20.0, -- title
-				5.0,   -- headings
-				1.0    -- content
+				5.0,  -- headings
+				1.0,  -- content
+				0.0,  -- lang
+				10.0, -- title_stems
+				2.5,  -- headings_stems
+				0.5   -- content_stems
 			)`
 	}
 	return "0.0"
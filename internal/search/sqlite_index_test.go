@@ -2,9 +2,11 @@ package search
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
@@ -399,7 +401,7 @@ func TestSQLiteIndex_Search_RanksTitleMatchHigherThanContent(t *testing.T) {
 		t.Fatalf("failed to index contentMatch page: %v", err)
 	}
 
-	// "search" is converted by buildFuzzyQuery to "search*", matching both
+	// words also match as a prefix, so "search" matches both
 	result, err := index.Search("search", nil, 0, 10)
 	if err != nil {
 		t.Fatalf("search failed: %v", err)
@@ -908,3 +910,146 @@ func TestExtractHeadings_HeadingWithCodeSpan(t *testing.T) {
 		t.Errorf("expected heading words preserved, got %q", got)
 	}
 }
+
+func TestSQLiteIndex_KeepsPagesAcrossReopenUntilSchemaChanges(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	index, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	if !index.Recreated() {
+		t.Fatalf("expected a new index to report being recreated")
+	}
+	if err := index.IndexPage("/kept", "kept.md", "kept", "Kept", tree.NodeKindPage, "persistent content"); err != nil {
+		t.Fatalf("IndexPage failed: %v", err)
+	}
+	if err := index.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	reopened, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to reopen SQLiteIndex: %v", err)
+	}
+	if reopened.Recreated() {
+		t.Fatalf("expected the reopened index to be kept")
+	}
+	ids, err := reopened.SearchPageIDs("persistent", nil)
+	if err != nil {
+		t.Fatalf("SearchPageIDs failed: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != "kept" {
+		t.Fatalf("expected the page to survive the reopen, got %v", ids)
+	}
+
+	// An outdated schema version recreates the index.
+	if err := reopened.withDB(func(db *sql.DB) error {
+		_, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", searchSchemaVersion-1))
+		return err
+	}); err != nil {
+		t.Fatalf("set user_version: %v", err)
+	}
+	if err := reopened.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	outdated, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to reopen SQLiteIndex: %v", err)
+	}
+	defer test_utils.WrapCloseWithErrorCheck(outdated.Close, t)
+	if !outdated.Recreated() {
+		t.Fatalf("expected an outdated index to be recreated")
+	}
+	ids, err = outdated.SearchPageIDs("persistent", nil)
+	if err != nil {
+		t.Fatalf("SearchPageIDs failed: %v", err)
+	}
+	if len(ids) != 0 {
+		t.Fatalf("expected the recreated index to be empty, got %v", ids)
+	}
+}
+
+func TestSQLiteIndex_SearchQuery_AppliesFieldsPathsAndNegation(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	index, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer test_utils.WrapCloseWithErrorCheck(index.Close, t)
+
+	pages := []IndexPageInput{
+		{Path: "docs/deploy", PageID: "guide", Title: "Deploy Guide", Kind: tree.NodeKindSection, Raw: "Use a rolling update first."},
+		{Path: "docs/deploy/advanced", PageID: "advanced", Title: "Advanced", Kind: tree.NodeKindPage, Raw: "How to deploy canaries."},
+		{Path: "docs/deployment", PageID: "deployment", Title: "Deployment", Kind: tree.NodeKindPage, Raw: "Update the rolling schedule."},
+		{Path: "ops/runbook", PageID: "runbook", Title: "Runbook", Kind: tree.NodeKindPage, Raw: "Deploy the draft (NEAR AND OR)."},
+	}
+	if failures, err := index.IndexPages(pages); err != nil || len(failures) > 0 {
+		t.Fatalf("IndexPages failed: %v %v", err, failures)
+	}
+
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{`title:deploy`, []string{"deployment", "guide"}},
+		{`"rolling update"`, []string{"guide"}},
+		{`deploy -draft`, []string{"advanced", "deployment", "guide"}},
+		{`deploy -"the draft"`, []string{"advanced", "deployment", "guide"}},
+		{`canaries OR schedule`, []string{"advanced", "deployment"}},
+		{`path:docs/deploy`, []string{"advanced", "guide"}},
+		{`path:docs -path:docs/deploy/advanced`, []string{"deployment", "guide"}},
+		{`-deploy`, []string{}},
+		{`NEAR( AND ) OR*`, []string{"runbook"}},
+		{`"draft (near"`, []string{"runbook"}},
+	}
+	for _, tc := range cases {
+		q, err := ParseQuery(tc.query)
+		if err != nil {
+			t.Fatalf("ParseQuery(%q) failed: %v", tc.query, err)
+		}
+		ids, err := index.SearchQueryPageIDs(q, SearchScope{})
+		if err != nil {
+			t.Fatalf("SearchQueryPageIDs(%q) failed: %v", tc.query, err)
+		}
+		if ids == nil {
+			ids = []string{}
+		}
+		slices.Sort(ids)
+		if !slices.Equal(ids, tc.want) {
+			t.Errorf("%q matched %v, want %v", tc.query, ids, tc.want)
+		}
+	}
+
+	q, err := ParseQuery(`deploy`)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+	result, err := index.SearchQuery(q, SearchScope{ExcludedPageIDs: []string{"guide", "deployment"}}, 0, 10)
+	if err != nil {
+		t.Fatalf("SearchQuery failed: %v", err)
+	}
+	if result.Count != 2 || len(result.Items) != 2 {
+		t.Fatalf("expected the excluded pages to be left out, got %+v", result.Items)
+	}
+	for _, item := range result.Items {
+		if item.PageID == "guide" || item.PageID == "deployment" {
+			t.Fatalf("excluded page %s was returned", item.PageID)
+		}
+	}
+}
+
+func TestSQLiteIndex_Search_RejectsInvalidQuery(t *testing.T) {
+	index, err := NewSQLiteIndex(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer test_utils.WrapCloseWithErrorCheck(index.Close, t)
+
+	_, err = index.Search(`"unterminated`, nil, 0, 10)
+	var syntaxErr *QuerySyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Pos != 0 {
+		t.Fatalf("expected a syntax error at position 0, got %v", err)
+	}
+}
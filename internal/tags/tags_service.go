@@ -57,6 +57,10 @@ func (s *TagsService) GetTagsForPages(pageIDs []string) (map[string][]string, er
 	return s.store.GetTagsForPages(pageIDs)
 }
 
+func (s *TagsService) GetSharedTags(pageID string) ([]SharedTag, error) {
+	return s.store.GetSharedTags(pageID)
+}
+
 func (s *TagsService) GetExcerptsForPages(pageIDs []string) (map[string]string, error) {
 	return s.store.GetExcerptsForPages(pageIDs)
 }
@@ -469,6 +469,44 @@ func (s *TagsStore) GetTagsForPages(pageIDs []string) (map[string][]string, erro
 	return result, rows.Err()
 }
 
+// SharedTag is a tag a page has in common with another page.
+type SharedTag struct {
+	PageID string
+	Tag    string
+	// Pages is the number of pages with the tag.
+	Pages int
+}
+
+// GetSharedTags returns the tags pageID shares with other pages, one entry
+// per other page and tag.
+func (s *TagsStore) GetSharedTags(pageID string) ([]SharedTag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows, err := s.db.Query(`
+		SELECT other.page_id, other.tag,
+			(SELECT COUNT(*) FROM page_tags counted WHERE counted.tag = other.tag)
+		FROM page_tags self
+		JOIN page_tags other ON other.tag = self.tag AND other.page_id <> self.page_id
+		WHERE self.page_id = ?
+		ORDER BY other.page_id, other.tag
+	`, pageID)
+	if err != nil {
+		return nil, err
+	}
+	defer shared.LogClose(rows.Close, logCloseRowsFailed)
+
+	var result []SharedTag
+	for rows.Next() {
+		var st SharedTag
+		if err := rows.Scan(&st.PageID, &st.Tag, &st.Pages); err != nil {
+			return nil, err
+		}
+		result = append(result, st)
+	}
+	return result, rows.Err()
+}
+
 // escapeLikePrefix escapes LIKE special characters in a prefix filter so that
 // '%', '_', and '\' are treated as literals and not as SQL wildcards.
 func escapeLikePrefix(s string) string {
@@ -5,11 +5,13 @@ import (
 	"errors"
 	"log/slog"
 	"mime/multipart"
+	"path"
 
 	coreassets "github.com/perber/wiki/internal/core/assets"
 	"github.com/perber/wiki/internal/core/revision"
 	sharederrors "github.com/perber/wiki/internal/core/shared/errors"
 	"github.com/perber/wiki/internal/core/tree"
+	"github.com/perber/wiki/internal/wiki/pagesave"
 )
 
 // ─── UploadAssetUseCase ──────────────────────────────────────────────────────
@@ -35,11 +37,12 @@ type UploadAssetUseCase struct {
 	tree     *tree.TreeService
 	asset    *coreassets.AssetService
 	revision *revision.Service
+	search   *pagesave.SearchIndexSideEffect
 	log      *slog.Logger
 }
 
-func NewUploadAssetUseCase(t *tree.TreeService, a *coreassets.AssetService, r *revision.Service, log *slog.Logger) *UploadAssetUseCase {
-	return &UploadAssetUseCase{tree: t, asset: a, revision: r, log: log}
+func NewUploadAssetUseCase(t *tree.TreeService, a *coreassets.AssetService, r *revision.Service, s *pagesave.SearchIndexSideEffect, log *slog.Logger) *UploadAssetUseCase {
+	return &UploadAssetUseCase{tree: t, asset: a, revision: r, search: s, log: log}
 }
 
 func (uc *UploadAssetUseCase) Execute(_ context.Context, in UploadAssetInput) (*UploadAssetOutput, error) {
@@ -55,6 +58,7 @@ func (uc *UploadAssetUseCase) Execute(_ context.Context, in UploadAssetInput) (*
 		return nil, err
 	}
 	recordAssetRevision(uc.revision, in.PageID, in.UserID, uc.log)
+	uc.search.IndexAttachment(page, path.Base(url))
 	return &UploadAssetOutput{URL: url}, nil
 }
 
@@ -109,11 +113,12 @@ type RenameAssetUseCase struct {
 	tree     *tree.TreeService
 	asset    *coreassets.AssetService
 	revision *revision.Service
+	search   *pagesave.SearchIndexSideEffect
 	log      *slog.Logger
 }
 
-func NewRenameAssetUseCase(t *tree.TreeService, a *coreassets.AssetService, r *revision.Service, log *slog.Logger) *RenameAssetUseCase {
-	return &RenameAssetUseCase{tree: t, asset: a, revision: r, log: log}
+func NewRenameAssetUseCase(t *tree.TreeService, a *coreassets.AssetService, r *revision.Service, s *pagesave.SearchIndexSideEffect, log *slog.Logger) *RenameAssetUseCase {
+	return &RenameAssetUseCase{tree: t, asset: a, revision: r, search: s, log: log}
 }
 
 func (uc *RenameAssetUseCase) Execute(_ context.Context, in RenameAssetInput) (*RenameAssetOutput, error) {
@@ -129,6 +134,8 @@ func (uc *RenameAssetUseCase) Execute(_ context.Context, in RenameAssetInput) (*
 		return nil, err
 	}
 	recordAssetRevision(uc.revision, in.PageID, in.UserID, uc.log)
+	uc.search.RemoveAttachment(page.ID, in.OldFilename)
+	uc.search.IndexAttachment(page, in.NewFilename)
 	return &RenameAssetOutput{URL: newPath}, nil
 }
 
@@ -144,11 +151,12 @@ type DeleteAssetUseCase struct {
 	tree     *tree.TreeService
 	asset    *coreassets.AssetService
 	revision *revision.Service
+	search   *pagesave.SearchIndexSideEffect
 	log      *slog.Logger
 }
 
-func NewDeleteAssetUseCase(t *tree.TreeService, a *coreassets.AssetService, r *revision.Service, log *slog.Logger) *DeleteAssetUseCase {
-	return &DeleteAssetUseCase{tree: t, asset: a, revision: r, log: log}
+func NewDeleteAssetUseCase(t *tree.TreeService, a *coreassets.AssetService, r *revision.Service, s *pagesave.SearchIndexSideEffect, log *slog.Logger) *DeleteAssetUseCase {
+	return &DeleteAssetUseCase{tree: t, asset: a, revision: r, search: s, log: log}
 }
 
 func (uc *DeleteAssetUseCase) Execute(_ context.Context, in DeleteAssetInput) error {
@@ -163,6 +171,7 @@ func (uc *DeleteAssetUseCase) Execute(_ context.Context, in DeleteAssetInput) er
 		return err
 	}
 	recordAssetRevision(uc.revision, in.PageID, in.UserID, uc.log)
+	uc.search.RemoveAttachment(page.ID, in.Filename)
 	return nil
 }
 
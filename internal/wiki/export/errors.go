@@ -0,0 +1,59 @@
+package export
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	sharederrors "github.com/perber/wiki/internal/core/shared/errors"
+)
+
+const (
+	ErrCodeExportSourceNotFound = "export_source_not_found"
+	ErrCodeExportInvalidFormat  = "export_invalid_format"
+	ErrCodeExportInternalError  = "export_internal_error"
+)
+
+// ExportErrorResponse is the structured JSON error body returned by export endpoints.
+type ExportErrorResponse struct {
+	Error ExportErrorDetail `json:"error"`
+}
+
+// ExportErrorDetail carries the localization-ready error data.
+type ExportErrorDetail struct {
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+	Template string   `json:"template"`
+	Args     []string `json:"args,omitempty"`
+}
+
+func respondWithExportStatusError(c *gin.Context, status int, code, message, template string, args ...string) {
+	c.JSON(status, ExportErrorResponse{
+		Error: ExportErrorDetail{
+			Code:     code,
+			Message:  message,
+			Template: template,
+			Args:     append([]string(nil), args...),
+		},
+	})
+}
+
+// respondWithExportError maps errors to JSON responses for export endpoints.
+func respondWithExportError(c *gin.Context, err error) {
+	if loc, ok := sharederrors.AsLocalizedError(err); ok {
+		respondWithExportStatusError(c, exportErrorStatus(loc.Code), loc.Code, loc.Message, loc.Template, loc.Args...)
+		return
+	}
+
+	respondWithExportStatusError(c, http.StatusInternalServerError, ErrCodeExportInternalError, "Export failed", "export failed")
+}
+
+func exportErrorStatus(code string) int {
+	switch code {
+	case ErrCodeExportSourceNotFound:
+		return http.StatusNotFound
+	case ErrCodeExportInvalidFormat:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
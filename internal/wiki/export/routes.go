@@ -0,0 +1,138 @@
+package export
+
+import (
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/perber/wiki/internal/core/auth"
+	httpinternal "github.com/perber/wiki/internal/http"
+	authmw "github.com/perber/wiki/internal/http/middleware/auth"
+	"github.com/perber/wiki/internal/http/middleware/security"
+)
+
+// Routes is the RouteRegistrar for the export endpoints.
+type Routes struct {
+	exportSite     *ExportSiteUseCase
+	exportMarkdown *ExportMarkdownUseCase
+	exportBook     *ExportBookUseCase
+	authService    *coreauth.AuthService
+	log            *slog.Logger
+}
+
+// RoutesConfig holds the dependencies required to build a Routes instance.
+type RoutesConfig struct {
+	ExportSite     *ExportSiteUseCase
+	ExportMarkdown *ExportMarkdownUseCase
+	ExportBook     *ExportBookUseCase
+	AuthService    *coreauth.AuthService
+	Log            *slog.Logger
+}
+
+// NewRoutes constructs the export RouteRegistrar.
+func NewRoutes(cfg RoutesConfig) *Routes {
+	log := cfg.Log
+	if log == nil {
+		log = slog.Default().With("component", "ExportRoutes")
+	}
+	return &Routes{
+		exportSite:     cfg.ExportSite,
+		exportMarkdown: cfg.ExportMarkdown,
+		exportBook:     cfg.ExportBook,
+		authService:    cfg.AuthService,
+		log:            log,
+	}
+}
+
+// RegisterRoutes implements RouteRegistrar.
+func (r *Routes) RegisterRoutes(ctx httpinternal.RouterContext) {
+	opts := ctx.Opts
+
+	authGroup := ctx.Base.Group("/api")
+	authGroup.Use(
+		authmw.InjectPublicEditor(opts.AuthDisabled),
+		authmw.RequireAuth(r.authService, ctx.AuthCookies, opts.AuthDisabled),
+		security.CSRFMiddleware(ctx.CSRFCookie),
+	)
+
+	authGroup.GET("/export/markdown", authmw.RequireEditorOrAdmin(), r.handleExportMarkdown)
+	authGroup.GET("/export/book", authmw.RequireEditorOrAdmin(), r.handleExportBook)
+
+	adminGroup := authGroup.Group("/admin")
+	adminGroup.Use(authmw.RequireAdmin(opts.AuthDisabled))
+
+	adminGroup.GET("/export/site", r.handleExportSite)
+}
+
+// ─── Handlers ───────────────────────────────────────────────────────────────
+
+// handleExportSite renders the subtree at ?path= (whole wiki when empty) as
+// a static HTML site and streams it as a ZIP attachment.
+func (r *Routes) handleExportSite(c *gin.Context) {
+	out, err := r.exportSite.Execute(c.Request.Context(), ExportSiteInput{Path: c.Query("path")})
+	if err != nil {
+		r.log.Error("static site export failed", "path", c.Query("path"), "error", err)
+		respondWithExportError(c, err)
+		return
+	}
+	r.serveArchive(c, out.File, out.Filename, "application/zip")
+}
+
+// handleExportMarkdown streams the subtree at ?path= as a ZIP of Markdown
+// files that can be imported again. ?wikilinks=true writes Obsidian
+// wikilinks, ?ids=true keeps the page IDs in the frontmatter.
+func (r *Routes) handleExportMarkdown(c *gin.Context) {
+	out, err := r.exportMarkdown.Execute(c.Request.Context(), ExportMarkdownInput{
+		Path:       c.Query("path"),
+		WikiLinks:  c.DefaultQuery("wikilinks", "false") == "true",
+		IncludeIDs: c.DefaultQuery("ids", "false") == "true",
+	})
+	if err != nil {
+		r.log.Error("markdown export failed", "path", c.Query("path"), "error", err)
+		respondWithExportError(c, err)
+		return
+	}
+	r.serveArchive(c, out.File, out.Filename, "application/zip")
+}
+
+// handleExportBook streams the subtree at ?path= as one book: a standalone
+// HTML document (?format=html, default) or an EPUB 3 package
+// (?format=epub). ?title= overrides the book title.
+func (r *Routes) handleExportBook(c *gin.Context) {
+	out, err := r.exportBook.Execute(c.Request.Context(), ExportBookInput{
+		Path:   c.Query("path"),
+		Format: c.DefaultQuery("format", BookFormatHTML),
+		Title:  c.Query("title"),
+	})
+	if err != nil {
+		r.log.Error("book export failed", "path", c.Query("path"), "format", c.Query("format"), "error", err)
+		respondWithExportError(c, err)
+		return
+	}
+	r.serveArchive(c, out.File, out.Filename, out.ContentType)
+}
+
+// serveArchive streams a finished export file and removes it afterwards.
+func (r *Routes) serveArchive(c *gin.Context, f *os.File, filename, contentType string) {
+	defer func() {
+		if err := f.Close(); err != nil {
+			r.log.Warn("could not close export file", "error", err)
+		}
+		if err := os.Remove(f.Name()); err != nil {
+			r.log.Warn("could not remove export file", "path", f.Name(), "error", err)
+		}
+	}()
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	c.Header("Content-Disposition", disposition)
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+	c.Writer.Header().Set("Content-Type", contentType)
+	http.ServeContent(c.Writer, c.Request, filename, time.Time{}, f)
+}
@@ -0,0 +1,262 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	sharederrors "github.com/perber/wiki/internal/core/shared/errors"
+	"github.com/perber/wiki/internal/core/tree"
+	coreexport "github.com/perber/wiki/internal/export"
+)
+
+// collectSubtree wraps coreexport.CollectSubtree with localized errors.
+func collectSubtree(treeService *tree.TreeService, routePath string) (*coreexport.Subtree, error) {
+	src, err := coreexport.CollectSubtree(treeService, routePath)
+	if err != nil {
+		if errors.Is(err, coreexport.ErrExportSourceNotFound) {
+			return nil, sharederrors.NewLocalizedError(
+				ErrCodeExportSourceNotFound, "Page not found",
+				"page %s not found", err, routePath,
+			)
+		}
+		return nil, err
+	}
+	return src, nil
+}
+
+// exportFilename derives a download name from the exported path, e.g.
+// "ops-handbook-site.zip" for /ops/handbook or "wiki-site.zip" for the root.
+func exportFilename(routePath, suffix string) string {
+	name := strings.ReplaceAll(strings.Trim(routePath, "/"), "/", "-")
+	if name == "" {
+		name = "wiki"
+	}
+	return name + suffix
+}
+
+// createExportTempFile creates the temporary archive an export is written
+// to before it is streamed to the client, so that a failing export can
+// still be reported as a JSON error instead of a truncated download.
+func createExportTempFile() (*os.File, error) {
+	f, err := os.CreateTemp("", "leafwiki-export-*.zip")
+	if err != nil {
+		return nil, fmt.Errorf("create export file: %w", err)
+	}
+	return f, nil
+}
+
+// ─── ExportSiteUseCase ───────────────────────────────────────────────────────
+
+type ExportSiteInput struct {
+	// Path is the route path of the subtree to export; empty exports the whole wiki.
+	Path string
+}
+
+type ExportSiteOutput struct {
+	// File holds the finished ZIP archive, positioned at the start. The
+	// caller must close and remove it.
+	File     *os.File
+	Filename string
+	Result   *coreexport.SiteResult
+}
+
+type ExportSiteUseCase struct {
+	tree      *tree.TreeService
+	branding  coreexport.BrandingSource
+	assetsDir string
+}
+
+func NewExportSiteUseCase(treeService *tree.TreeService, branding coreexport.BrandingSource, assetsDir string) *ExportSiteUseCase {
+	return &ExportSiteUseCase{tree: treeService, branding: branding, assetsDir: assetsDir}
+}
+
+func (uc *ExportSiteUseCase) Execute(_ context.Context, in ExportSiteInput) (out *ExportSiteOutput, err error) {
+	src, err := collectSubtree(uc.tree, in.Path)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := coreexport.SiteOptionsFromBranding(uc.branding, uc.assetsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := createExportTempFile()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	zw := coreexport.NewZipWriter(f)
+	result, err := coreexport.ExportSite(src, zw, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = zw.Close(); err != nil {
+		return nil, err
+	}
+	if _, err = f.Seek(0, 0); err != nil {
+		return nil, err
+	}
+
+	return &ExportSiteOutput{File: f, Filename: exportFilename(in.Path, "-site.zip"), Result: result}, nil
+}
+
+// ─── ExportMarkdownUseCase ───────────────────────────────────────────────────
+
+type ExportMarkdownInput struct {
+	// Path is the route path of the subtree to export; empty exports the whole wiki.
+	Path string
+	// WikiLinks writes links as Obsidian wikilinks instead of relative paths.
+	WikiLinks bool
+	// IncludeIDs keeps the LeafWiki page IDs in the frontmatter.
+	IncludeIDs bool
+}
+
+type ExportMarkdownOutput struct {
+	// File holds the finished ZIP archive, positioned at the start. The
+	// caller must close and remove it.
+	File     *os.File
+	Filename string
+	Result   *coreexport.MarkdownResult
+}
+
+type ExportMarkdownUseCase struct {
+	tree      *tree.TreeService
+	assetsDir string
+}
+
+func NewExportMarkdownUseCase(treeService *tree.TreeService, assetsDir string) *ExportMarkdownUseCase {
+	return &ExportMarkdownUseCase{tree: treeService, assetsDir: assetsDir}
+}
+
+func (uc *ExportMarkdownUseCase) Execute(_ context.Context, in ExportMarkdownInput) (out *ExportMarkdownOutput, err error) {
+	src, err := collectSubtree(uc.tree, in.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := createExportTempFile()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	zw := coreexport.NewZipWriter(f)
+	result, err := coreexport.ExportMarkdown(src, zw, coreexport.MarkdownOptions{
+		AssetsDir:  uc.assetsDir,
+		WikiLinks:  in.WikiLinks,
+		IncludeIDs: in.IncludeIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = zw.Close(); err != nil {
+		return nil, err
+	}
+	if _, err = f.Seek(0, 0); err != nil {
+		return nil, err
+	}
+
+	return &ExportMarkdownOutput{File: f, Filename: exportFilename(in.Path, "-markdown.zip"), Result: result}, nil
+}
+
+// ─── ExportBookUseCase ───────────────────────────────────────────────────────
+
+const (
+	BookFormatHTML = "html"
+	BookFormatEPUB = "epub"
+)
+
+type ExportBookInput struct {
+	// Path is the route path of the subtree to export; empty exports the whole wiki.
+	Path string
+	// Format is BookFormatHTML (default) or BookFormatEPUB.
+	Format string
+	// Title overrides the book title (default: title of the exported page).
+	Title string
+}
+
+type ExportBookOutput struct {
+	// File holds the finished book, positioned at the start. The caller
+	// must close and remove it.
+	File        *os.File
+	Filename    string
+	ContentType string
+	Result      *coreexport.BookResult
+}
+
+type ExportBookUseCase struct {
+	tree      *tree.TreeService
+	assetsDir string
+}
+
+func NewExportBookUseCase(treeService *tree.TreeService, assetsDir string) *ExportBookUseCase {
+	return &ExportBookUseCase{tree: treeService, assetsDir: assetsDir}
+}
+
+func (uc *ExportBookUseCase) Execute(_ context.Context, in ExportBookInput) (out *ExportBookOutput, err error) {
+	format := strings.ToLower(strings.TrimSpace(in.Format))
+	if format == "" {
+		format = BookFormatHTML
+	}
+	if format != BookFormatHTML && format != BookFormatEPUB {
+		return nil, sharederrors.NewLocalizedError(
+			ErrCodeExportInvalidFormat, "Unsupported book format",
+			"unsupported book format %s", nil, in.Format,
+		)
+	}
+
+	src, err := collectSubtree(uc.tree, in.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := createExportTempFile()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	opts := coreexport.BookOptions{Title: in.Title, AssetsDir: uc.assetsDir}
+	out = &ExportBookOutput{File: f}
+	if format == BookFormatEPUB {
+		zw := coreexport.NewZipWriter(f)
+		if out.Result, err = coreexport.ExportBookEPUB(src, zw, opts); err != nil {
+			return nil, err
+		}
+		if err = zw.Close(); err != nil {
+			return nil, err
+		}
+		out.Filename = exportFilename(in.Path, "-book.epub")
+		out.ContentType = "application/epub+zip"
+	} else {
+		if out.Result, err = coreexport.ExportBookHTML(src, f, opts); err != nil {
+			return nil, err
+		}
+		out.Filename = exportFilename(in.Path, "-book.html")
+		out.ContentType = "text/html; charset=utf-8"
+	}
+	if _, err = f.Seek(0, 0); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
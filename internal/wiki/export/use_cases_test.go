@@ -0,0 +1,145 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/perber/wiki/internal/branding"
+	sharederrors "github.com/perber/wiki/internal/core/shared/errors"
+	"github.com/perber/wiki/internal/core/tree"
+)
+
+func setupExportTree(t *testing.T) (*tree.TreeService, *branding.BrandingService, string) {
+	t.Helper()
+	storageDir := t.TempDir()
+	ts := tree.NewTreeService(storageDir)
+	if err := ts.LoadTree(); err != nil {
+		t.Fatalf("LoadTree failed: %v", err)
+	}
+	page := tree.NodeKindPage
+	if _, err := ts.CreateNode("system", nil, "Handbook", "handbook", &page); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+	brandingService, err := branding.NewBrandingService(storageDir)
+	if err != nil {
+		t.Fatalf("NewBrandingService failed: %v", err)
+	}
+	return ts, brandingService, filepath.Join(storageDir, "assets")
+}
+
+func TestExportSiteUseCase_Execute_UnknownPathIsLocalized(t *testing.T) {
+	ts, brandingService, assetsDir := setupExportTree(t)
+	uc := NewExportSiteUseCase(ts, brandingService, assetsDir)
+
+	_, err := uc.Execute(context.Background(), ExportSiteInput{Path: "missing"})
+	loc, ok := sharederrors.AsLocalizedError(err)
+	if !ok || loc.Code != ErrCodeExportSourceNotFound {
+		t.Fatalf("expected %s, got %v", ErrCodeExportSourceNotFound, err)
+	}
+	if exportErrorStatus(loc.Code) != 404 {
+		t.Fatalf("expected 404 status for %s", loc.Code)
+	}
+}
+
+func TestExportSiteUseCase_Execute_ReturnsZip(t *testing.T) {
+	ts, brandingService, assetsDir := setupExportTree(t)
+	uc := NewExportSiteUseCase(ts, brandingService, assetsDir)
+
+	out, err := uc.Execute(context.Background(), ExportSiteInput{Path: "/handbook"})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	defer func() {
+		_ = out.File.Close()
+		_ = os.Remove(out.File.Name())
+	}()
+
+	if out.Filename != "handbook-site.zip" {
+		t.Fatalf("Filename = %q", out.Filename)
+	}
+	stat, err := out.File.Stat()
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	zr, err := zip.NewReader(out.File, stat.Size())
+	if err != nil {
+		t.Fatalf("zip.NewReader failed: %v", err)
+	}
+	names := map[string]bool{}
+	for _, f := range zr.File {
+		names[f.Name] = true
+	}
+	for _, want := range []string{"index.html", "_static/site.css", "_static/search.js", "_static/search-index.js"} {
+		if !names[want] {
+			t.Fatalf("archive is missing %s (got %v)", want, names)
+		}
+	}
+}
+
+func TestExportMarkdownUseCase_Execute_ReturnsZip(t *testing.T) {
+	ts, _, assetsDir := setupExportTree(t)
+	uc := NewExportMarkdownUseCase(ts, assetsDir)
+
+	out, err := uc.Execute(context.Background(), ExportMarkdownInput{Path: "handbook", IncludeIDs: true})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	defer func() {
+		_ = out.File.Close()
+		_ = os.Remove(out.File.Name())
+	}()
+
+	if out.Filename != "handbook-markdown.zip" || out.Result.Pages != 1 {
+		t.Fatalf("unexpected output: %q %+v", out.Filename, out.Result)
+	}
+	stat, err := out.File.Stat()
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	zr, err := zip.NewReader(out.File, stat.Size())
+	if err != nil {
+		t.Fatalf("zip.NewReader failed: %v", err)
+	}
+	if len(zr.File) != 1 || zr.File[0].Name != "handbook.md" {
+		t.Fatalf("unexpected archive entries: %d, first %q", len(zr.File), zr.File[0].Name)
+	}
+}
+
+func TestExportBookUseCase_Execute_WritesFormats(t *testing.T) {
+	ts, _, assetsDir := setupExportTree(t)
+	uc := NewExportBookUseCase(ts, assetsDir)
+
+	for _, tc := range []struct {
+		format, filename, contentType string
+	}{
+		{"", "handbook-book.html", "text/html; charset=utf-8"},
+		{"epub", "handbook-book.epub", "application/epub+zip"},
+	} {
+		out, err := uc.Execute(context.Background(), ExportBookInput{Path: "handbook", Format: tc.format})
+		if err != nil {
+			t.Fatalf("Execute(%q) failed: %v", tc.format, err)
+		}
+		_ = out.File.Close()
+		_ = os.Remove(out.File.Name())
+		if out.Filename != tc.filename || out.ContentType != tc.contentType {
+			t.Fatalf("format %q: unexpected output %q %q", tc.format, out.Filename, out.ContentType)
+		}
+	}
+}
+
+func TestExportBookUseCase_Execute_RejectsUnknownFormat(t *testing.T) {
+	ts, _, assetsDir := setupExportTree(t)
+	uc := NewExportBookUseCase(ts, assetsDir)
+
+	_, err := uc.Execute(context.Background(), ExportBookInput{Path: "handbook", Format: "pdf"})
+	loc, ok := sharederrors.AsLocalizedError(err)
+	if !ok || loc.Code != ErrCodeExportInvalidFormat {
+		t.Fatalf("expected %s, got %v", ErrCodeExportInvalidFormat, err)
+	}
+	if exportErrorStatus(loc.Code) != 400 {
+		t.Fatalf("expected 400 status for %s", loc.Code)
+	}
+}
@@ -6,15 +6,20 @@ import (
 	"mime/multipart"
 
 	"github.com/perber/wiki/internal/core/assets"
+	"github.com/perber/wiki/internal/core/auth"
 	"github.com/perber/wiki/internal/core/revision"
 	"github.com/perber/wiki/internal/core/tree"
+	"github.com/perber/wiki/internal/favorites"
+	"github.com/perber/wiki/internal/importer"
 	"github.com/perber/wiki/internal/links"
 	"github.com/perber/wiki/internal/properties"
+	"github.com/perber/wiki/internal/related"
 	"github.com/perber/wiki/internal/search"
 	"github.com/perber/wiki/internal/tags"
 	wikiassets "github.com/perber/wiki/internal/wiki/assets"
 	wikipages "github.com/perber/wiki/internal/wiki/pages"
 	"github.com/perber/wiki/internal/wiki/pagesave"
+	wikisearch "github.com/perber/wiki/internal/wiki/search"
 )
 
 // WikiImportAdapter implements the importer.ImporterWiki interface using
@@ -25,9 +30,14 @@ type WikiImportAdapter struct {
 	revision    *revision.Service
 	links       *links.LinkService
 	asset       *assets.AssetService
+	favorites   *favorites.FavoritesStore
 	tags        *tags.TagsService
 	props       *properties.PropertiesService
+	related     *related.RelatedService
+	notifier    *wikisearch.SavedSearchNotifier
 	searchIndex *search.SQLiteIndex
+	attachments search.AttachmentOptions
+	users       func() *auth.UserService
 	log         *slog.Logger
 }
 
@@ -40,9 +50,14 @@ func NewWikiImportAdapter(w *Wiki) *WikiImportAdapter {
 		revision:    w.revision,
 		links:       w.links,
 		asset:       w.asset,
+		favorites:   w.favorites,
 		tags:        w.tags,
 		props:       w.props,
+		related:     w.related,
+		notifier:    w.newSavedSearchNotifier(),
 		searchIndex: w.searchIndex,
+		attachments: w.searchAttachments,
+		users:       w.UserService,
 		log:         w.log,
 	}
 }
@@ -55,6 +70,10 @@ func (a *WikiImportAdapter) LookupPagePath(path string) (*tree.PathLookup, error
 	return a.tree.LookupPagePath(path)
 }
 
+func (a *WikiImportAdapter) GetPage(id string) (*tree.Page, error) {
+	return a.tree.GetPage(id)
+}
+
 func (a *WikiImportAdapter) FindByPath(route string) (*tree.Page, error) {
 	return a.tree.FindPageByRoutePath(route)
 }
@@ -67,13 +86,21 @@ func (a *WikiImportAdapter) ListAssets(pageID string) ([]string, error) {
 	return a.asset.ListAssetsForPage(page)
 }
 
+func (a *WikiImportAdapter) searchEffect() *pagesave.SearchIndexSideEffect {
+	e := pagesave.NewSearchIndexSideEffect(a.searchIndex, a.tree, a.log, nil)
+	e.EnableAttachments(a.asset, a.attachments)
+	return e
+}
+
 func (a *WikiImportAdapter) orchestrator() *pagesave.PageSaveOrchestrator {
 	return pagesave.NewPageSaveOrchestrator(nil,
-		pagesave.NewSearchIndexSideEffect(a.searchIndex, a.tree, a.log, nil),
+		a.searchEffect(),
 		pagesave.NewLinkIndexSideEffect(a.links, a.log, nil),
 		pagesave.NewTagsSideEffect(a.tags, a.log, nil),
 		pagesave.NewPropertiesSideEffect(a.props, a.log, nil),
 		pagesave.NewRevisionSideEffect(a.revision, a.log, nil),
+		pagesave.NewRelatedSideEffect(a.related),
+		a.notifier,
 	)
 }
 
@@ -103,8 +130,30 @@ func (a *WikiImportAdapter) UpdatePage(userID, id, title, slug string, content *
 	return out.Page, nil
 }
 
+func (a *WikiImportAdapter) DeletePage(userID, id string) error {
+	current, err := a.tree.GetPage(id)
+	if err != nil {
+		return err
+	}
+	return wikipages.NewDeletePageUseCase(a.tree, a.revision, a.asset, a.favorites, a.orchestrator(), a.log, nil).Execute(
+		context.Background(),
+		wikipages.DeletePageInput{UserID: userID, ID: id, Version: current.Version()},
+	)
+}
+
+func (a *WikiImportAdapter) MovePage(userID, id, parentID string) error {
+	current, err := a.tree.GetPage(id)
+	if err != nil {
+		return err
+	}
+	return wikipages.NewMovePageUseCase(a.tree, a.orchestrator(), a.log, nil).Execute(
+		context.Background(),
+		wikipages.MovePageInput{UserID: userID, ID: id, Version: current.Version(), ParentID: parentID},
+	)
+}
+
 func (a *WikiImportAdapter) UploadAsset(userID, pageID string, file multipart.File, filename string, maxBytes int64) (string, error) {
-	out, err := wikiassets.NewUploadAssetUseCase(a.tree, a.asset, a.revision, a.log).Execute(
+	out, err := wikiassets.NewUploadAssetUseCase(a.tree, a.asset, a.revision, a.searchEffect(), a.log).Execute(
 		context.Background(),
 		wikiassets.UploadAssetInput{UserID: userID, PageID: pageID, File: file, Filename: filename, MaxBytes: maxBytes},
 	)
@@ -113,3 +162,31 @@ func (a *WikiImportAdapter) UploadAsset(userID, pageID string, file multipart.Fi
 	}
 	return out.URL, nil
 }
+
+// ImportRevisions records the history of an imported page. Authors keep
+// their name from the history without a user; with mapAuthors, authors with
+// a user of the same name are linked to that user.
+func (a *WikiImportAdapter) ImportRevisions(pageID string, revisions []importer.ImportedRevision, mapAuthors bool) error {
+	if a.revision == nil {
+		return importer.ErrRevisionsDisabled
+	}
+	authorIDs := map[string]string{}
+	imported := make([]revision.ImportedRevision, 0, len(revisions))
+	for _, rev := range revisions {
+		authorID, ok := authorIDs[rev.Author]
+		if !ok && mapAuthors && rev.Author != "" && a.users != nil {
+			if user, err := a.users().GetUserByUsername(rev.Author); err == nil {
+				authorID = user.ID
+			}
+			authorIDs[rev.Author] = authorID
+		}
+		imported = append(imported, revision.ImportedRevision{
+			AuthorID:   authorID,
+			AuthorName: rev.Author,
+			CreatedAt:  rev.Timestamp,
+			Content:    rev.Content,
+			Summary:    rev.Summary,
+		})
+	}
+	return a.revision.RecordImportedRevisions(pageID, imported)
+}
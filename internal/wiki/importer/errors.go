@@ -8,13 +8,18 @@ import (
 )
 
 const (
-	ErrCodeImporterNoPlan           = "importer_no_plan"
-	ErrCodeImporterExecutionRunning = "importer_execution_running"
-	ErrCodeImporterStateUnavailable = "importer_state_unavailable"
-	ErrCodeImporterInternalError    = "importer_internal_error"
-	ErrCodeImporterUploadTooLarge   = "importer_upload_too_large"
-	ErrCodeImporterMissingFile      = "importer_missing_file"
-	ErrCodeImporterFileOpenFailed   = "importer_file_open_failed"
+	ErrCodeImporterNoPlan                 = "importer_no_plan"
+	ErrCodeImporterExecutionRunning       = "importer_execution_running"
+	ErrCodeImporterStateUnavailable       = "importer_state_unavailable"
+	ErrCodeImporterInternalError          = "importer_internal_error"
+	ErrCodeImporterUploadTooLarge         = "importer_upload_too_large"
+	ErrCodeImporterMissingFile            = "importer_missing_file"
+	ErrCodeImporterFileOpenFailed         = "importer_file_open_failed"
+	ErrCodeImporterPlanStale              = "importer_plan_stale"
+	ErrCodeImporterInvalidPlanEdit        = "importer_invalid_plan_edit"
+	ErrCodeImporterPlanNotEditable        = "importer_plan_not_editable"
+	ErrCodeImporterInvalidOptions         = "importer_invalid_options"
+	ErrCodeImporterAuthorMappingForbidden = "importer_author_mapping_forbidden"
 
 	ErrCodeImporterZipEntryTooLarge     = "importer_zip_entry_too_large"
 	ErrCodeImporterZipExtractedTooLarge = "importer_zip_extracted_too_large"
@@ -59,14 +64,16 @@ func importerErrorStatus(code string) int {
 	switch code {
 	case ErrCodeImporterNoPlan:
 		return http.StatusNotFound
-	case ErrCodeImporterExecutionRunning:
+	case ErrCodeImporterExecutionRunning, ErrCodeImporterPlanStale, ErrCodeImporterPlanNotEditable:
 		return http.StatusConflict
 	case ErrCodeImporterStateUnavailable:
 		return http.StatusInternalServerError
 	case ErrCodeImporterUploadTooLarge, ErrCodeImporterZipEntryTooLarge, ErrCodeImporterZipExtractedTooLarge, ErrCodeImporterZipRatioTooHigh:
 		return http.StatusRequestEntityTooLarge
-	case ErrCodeImporterMissingFile, ErrCodeImporterFileOpenFailed:
+	case ErrCodeImporterMissingFile, ErrCodeImporterFileOpenFailed, ErrCodeImporterInvalidPlanEdit, ErrCodeImporterInvalidOptions:
 		return http.StatusBadRequest
+	case ErrCodeImporterAuthorMappingForbidden:
+		return http.StatusForbidden
 	default:
 		return http.StatusInternalServerError
 	}
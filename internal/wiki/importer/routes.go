@@ -22,6 +22,7 @@ const (
 type Routes struct {
 	createPlan  *CreateImportPlanUseCase
 	getPlan     *GetImportPlanUseCase
+	editPlan    *EditImportPlanUseCase
 	execute     *ExecuteImportUseCase
 	clearPlan   *ClearImportPlanUseCase
 	authService *coreauth.AuthService
@@ -33,6 +34,7 @@ type Routes struct {
 type RoutesConfig struct {
 	CreatePlan  *CreateImportPlanUseCase
 	GetPlan     *GetImportPlanUseCase
+	EditPlan    *EditImportPlanUseCase
 	Execute     *ExecuteImportUseCase
 	ClearPlan   *ClearImportPlanUseCase
 	AuthService *coreauth.AuthService
@@ -45,6 +47,7 @@ func NewRoutes(cfg RoutesConfig) *Routes {
 	return &Routes{
 		createPlan:  cfg.CreatePlan,
 		getPlan:     cfg.GetPlan,
+		editPlan:    cfg.EditPlan,
 		execute:     cfg.Execute,
 		clearPlan:   cfg.ClearPlan,
 		authService: cfg.AuthService,
@@ -71,6 +74,7 @@ func (r *Routes) RegisterRoutes(ctx httpinternal.RouterContext) {
 
 	authGroup.POST(importPlanRoutePath, authmw.RequireEditorOrAdmin(), r.handleCreatePlan)
 	authGroup.GET(importPlanRoutePath, authmw.RequireEditorOrAdmin(), r.handleGetPlan)
+	authGroup.PATCH(importPlanRoutePath, authmw.RequireEditorOrAdmin(), r.handleEditPlan)
 	authGroup.POST("/import/execute", authmw.RequireEditorOrAdmin(), r.handleExecute)
 	authGroup.DELETE(importPlanRoutePath, authmw.RequireEditorOrAdmin(), r.handleClearPlan)
 }
@@ -106,8 +110,11 @@ func (r *Routes) handleCreatePlan(c *gin.Context) {
 	}()
 
 	targetBasePath := c.PostForm("targetBasePath")
+	importHistory := c.PostForm("importHistory") == "true"
 	out, err := r.createPlan.Execute(c.Request.Context(), CreateImportPlanInput{
-		File: file, TargetBasePath: targetBasePath,
+		File: file, TargetBasePath: targetBasePath, ImportHistory: importHistory,
+		MapHistoryAuthors: c.PostForm("mapHistoryAuthors") == "true", RequesterIsAdmin: user.HasRole(coreauth.RoleAdmin),
+		Source: c.PostForm("source"), RemovedPages: coreimporter.RemovedPages(c.PostForm("removedPages")),
 	})
 	if err != nil {
 		logRejectedZipExtraction(r.log, err)
@@ -142,6 +149,55 @@ func (r *Routes) handleGetPlan(c *gin.Context) {
 	c.JSON(http.StatusOK, out.Plan)
 }
 
+type editPlanItemRequest struct {
+	SourcePath string                         `json:"source_path" binding:"required"`
+	Action     *coreimporter.PlanAction       `json:"action"`
+	TargetPath *string                        `json:"target_path"`
+	Title      *string                        `json:"title"`
+	Slug       *string                        `json:"slug"`
+	Strategy   *coreimporter.ConflictStrategy `json:"strategy"`
+}
+
+type editPlanRequest struct {
+	TreeHash         string                         `json:"tree_hash"`
+	ConflictStrategy *coreimporter.ConflictStrategy `json:"conflict_strategy"`
+	Items            []editPlanItemRequest          `json:"items"`
+	Exclude          []string                       `json:"exclude"`
+	Include          []string                       `json:"include"`
+}
+
+func (r *Routes) handleEditPlan(c *gin.Context) {
+	var req editPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithImporterStatusError(c, http.StatusBadRequest, ErrCodeImporterInvalidPlanEdit, "Invalid import plan edit", "invalid import plan edit")
+		return
+	}
+
+	edit := coreimporter.PlanEdit{
+		TreeHash:         req.TreeHash,
+		ConflictStrategy: req.ConflictStrategy,
+		Exclude:          req.Exclude,
+		Include:          req.Include,
+	}
+	for _, item := range req.Items {
+		edit.Items = append(edit.Items, coreimporter.PlanItemEdit{
+			SourcePath: item.SourcePath,
+			Action:     item.Action,
+			TargetPath: item.TargetPath,
+			Title:      item.Title,
+			Slug:       item.Slug,
+			Strategy:   item.Strategy,
+		})
+	}
+
+	out, err := r.editPlan.Execute(c.Request.Context(), edit)
+	if err != nil {
+		respondWithImporterError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out.Plan)
+}
+
 func (r *Routes) handleExecute(c *gin.Context) {
 	user := authmw.MustGetUser(c)
 	if user == nil {
@@ -15,6 +15,13 @@ import (
 type CreateImportPlanInput struct {
 	File           io.Reader
 	TargetBasePath string
+	ImportHistory  bool
+	// MapHistoryAuthors links history authors to the users of the same
+	// name; only admins may ask for it.
+	MapHistoryAuthors bool
+	RequesterIsAdmin  bool
+	Source            string
+	RemovedPages      coreimporter.RemovedPages
 }
 
 type CreateImportPlanOutput struct {
@@ -30,13 +37,28 @@ func NewCreateImportPlanUseCase(svc *coreimporter.ImporterService) *CreateImport
 }
 
 func (uc *CreateImportPlanUseCase) Execute(_ context.Context, in CreateImportPlanInput) (*CreateImportPlanOutput, error) {
-	if _, err := uc.svc.CreateImportPlanFromZipUpload(in.File, in.TargetBasePath); err != nil {
+	if in.MapHistoryAuthors && !in.RequesterIsAdmin {
+		return nil, sharederrors.NewLocalizedError(
+			ErrCodeImporterAuthorMappingForbidden, "Only admins can link history authors to users",
+			"only admins can link history authors to users", nil,
+		)
+	}
+	opts := coreimporter.ImportOptions{
+		ImportHistory:     in.ImportHistory,
+		MapHistoryAuthors: in.MapHistoryAuthors,
+		Source:            in.Source,
+		RemovedPages:      in.RemovedPages,
+	}
+	if _, err := uc.svc.CreateImportPlanFromZipUpload(in.File, in.TargetBasePath, opts); err != nil {
 		// Deliberately static, user-facing messages: err's wrapped chain
 		// (e.g. "extract zip to temp: extract zip: write file: file too
 		// large: 200 bytes (max 100)") is internal implementation detail —
 		// function names and call-path context, not anything a user should
 		// see. logRejectedZipExtraction (routes.go) logs it server-side
 		// instead.
+		if errors.Is(err, coreimporter.ErrInvalidImportOptions) {
+			return nil, sharederrors.NewLocalizedError(ErrCodeImporterInvalidOptions, err.Error(), "invalid import options: %s", err, err.Error())
+		}
 		if errors.Is(err, coreshared.ErrFileTooLarge) {
 			return nil, sharederrors.NewLocalizedError(
 				ErrCodeImporterZipEntryTooLarge, "A file inside the uploaded archive is too large",
@@ -89,6 +111,43 @@ func (uc *GetImportPlanUseCase) Execute(_ context.Context) (*GetImportPlanOutput
 	return &GetImportPlanOutput{Plan: plan}, nil
 }
 
+// ─── EditImportPlanUseCase ───────────────────────────────────────────────────
+
+type EditImportPlanOutput struct {
+	Plan *coreimporter.CurrentPlanState
+}
+
+type EditImportPlanUseCase struct {
+	svc *coreimporter.ImporterService
+}
+
+func NewEditImportPlanUseCase(svc *coreimporter.ImporterService) *EditImportPlanUseCase {
+	return &EditImportPlanUseCase{svc: svc}
+}
+
+func (uc *EditImportPlanUseCase) Execute(_ context.Context, edit coreimporter.PlanEdit) (*EditImportPlanOutput, error) {
+	plan, err := uc.svc.EditCurrentPlan(edit)
+	if err != nil {
+		if errors.Is(err, coreimporter.ErrNoPlan) {
+			return nil, sharederrors.NewLocalizedError(ErrCodeImporterNoPlan, "No import plan available", "no import plan available", err)
+		}
+		if errors.Is(err, coreimporter.ErrPlanNotEditable) {
+			return nil, sharederrors.NewLocalizedError(ErrCodeImporterPlanNotEditable, "Import plan can no longer be edited", "import plan can no longer be edited", err)
+		}
+		if errors.Is(err, coreimporter.ErrPlanStale) {
+			return nil, sharederrors.NewLocalizedError(ErrCodeImporterPlanStale, "The wiki changed since the import plan was created", "the wiki changed since the import plan was created", err)
+		}
+		if errors.Is(err, coreimporter.ErrInvalidPlanEdit) {
+			return nil, sharederrors.NewLocalizedError(ErrCodeImporterInvalidPlanEdit, err.Error(), "invalid import plan edit: %s", err, err.Error())
+		}
+		if errors.Is(err, coreimporter.ErrImportStateUnavailable) {
+			return nil, sharederrors.NewLocalizedError(ErrCodeImporterStateUnavailable, "Import state is unavailable", "import state is unavailable", err)
+		}
+		return nil, err
+	}
+	return &EditImportPlanOutput{Plan: plan}, nil
+}
+
 // ─── ExecuteImportUseCase ────────────────────────────────────────────────────
 
 type ExecuteImportInput struct {
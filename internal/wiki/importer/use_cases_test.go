@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"net/http"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -130,3 +131,31 @@ func assertMessageDoesNotLeakInternalDetail(t *testing.T, localized *sharederror
 		}
 	}
 }
+
+func TestCreateImportPlanUseCase_Execute_OnlyAdminsMapHistoryAuthors(t *testing.T) {
+	uc := setupCreateImportPlanUseCase(t, coreshared.ExtractionLimits{})
+	upload := buildZipUpload(t, map[string]string{"page.md": "# Page"})
+
+	_, err := uc.Execute(context.Background(), CreateImportPlanInput{File: upload, ImportHistory: true, MapHistoryAuthors: true})
+
+	localized, ok := sharederrors.AsLocalizedError(err)
+	if !ok || localized.Code != ErrCodeImporterAuthorMappingForbidden {
+		t.Fatalf("expected %s, got %v", ErrCodeImporterAuthorMappingForbidden, err)
+	}
+	if got := importerErrorStatus(localized.Code); got != http.StatusForbidden {
+		t.Fatalf("expected status %d, got %d", http.StatusForbidden, got)
+	}
+}
+
+func TestEditImportPlanUseCase_Execute_MapsNoPlanError(t *testing.T) {
+	importerDir := filepath.Join(t.TempDir(), ".importer")
+	planner := coreimporter.NewPlanner(nil, tree.NewSlugService(), "")
+	store := coreimporter.NewPlanStore(filepath.Join(importerDir, "current-plan.json"))
+	svc := coreimporter.NewImporterService(planner, store, filepath.Join(importerDir, "workspaces"), 0)
+
+	_, err := NewEditImportPlanUseCase(svc).Execute(context.Background(), coreimporter.PlanEdit{})
+	localized, ok := sharederrors.AsLocalizedError(err)
+	if !ok || localized.Code != ErrCodeImporterNoPlan {
+		t.Fatalf("expected %s, got %v", ErrCodeImporterNoPlan, err)
+	}
+}
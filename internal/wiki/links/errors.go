@@ -8,9 +8,10 @@ import (
 )
 
 const (
-	ErrCodeLinkPageNotFound  = "link_page_not_found"
-	ErrCodeLinkUnavailable   = "link_service_unavailable"
-	ErrCodeLinkInternalError = "link_internal_error"
+	ErrCodeLinkPageNotFound   = "link_page_not_found"
+	ErrCodeLinkUnavailable    = "link_service_unavailable"
+	ErrCodeLinkInternalError  = "link_internal_error"
+	ErrCodeLinkInvalidRequest = "link_invalid_request"
 )
 
 // LinkErrorResponse is the structured JSON error body returned by link endpoints.
@@ -49,6 +50,8 @@ func linkErrorStatus(code string) int {
 	switch code {
 	case ErrCodeLinkPageNotFound:
 		return http.StatusNotFound
+	case ErrCodeLinkInvalidRequest:
+		return http.StatusBadRequest
 	case ErrCodeLinkUnavailable:
 		return http.StatusServiceUnavailable
 	default:
@@ -33,6 +33,24 @@ func TestRespondWithLinkError_PageNotFound(t *testing.T) {
 	}
 }
 
+func TestRespondWithLinkError_InvalidRequest(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+
+	respondWithLinkError(c, invalidGraphRequest("Invalid depth value", "invalid depth value"))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	if got, want := rec.Body.String(), `{"error":{"code":"link_invalid_request","message":"Invalid depth value","template":"invalid depth value"}}`; got != want {
+		t.Fatalf("body = %s, want %s", got, want)
+	}
+}
+
 func TestRespondWithLinkError_ServiceUnavailable(t *testing.T) {
 	t.Parallel()
 
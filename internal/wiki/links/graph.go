@@ -0,0 +1,157 @@
+package links
+
+import (
+	"context"
+	"errors"
+
+	sharederrors "github.com/perber/wiki/internal/core/shared/errors"
+	"github.com/perber/wiki/internal/core/tree"
+	corelinks "github.com/perber/wiki/internal/links"
+	coreprop "github.com/perber/wiki/internal/properties"
+	coretags "github.com/perber/wiki/internal/tags"
+)
+
+const (
+	// DefaultGraphDepth is the number of hops around a page the graph spans
+	// unless asked for another number, at most MaxGraphDepth.
+	DefaultGraphDepth = 1
+	MaxGraphDepth     = 5
+)
+
+// ─── GetLinkGraphUseCase ─────────────────────────────────────────────────────
+
+// GetLinkGraphInput selects the pages of the graph: the whole wiki, the
+// subtree under RootID, or the pages at most Depth links away from PageID.
+// RootID and PageID cannot be combined.
+type GetLinkGraphInput struct {
+	RootID            string
+	PageID            string
+	Depth             int
+	IncludeTags       bool
+	IncludeProperties bool
+	IncludeBroken     bool
+}
+
+type GetLinkGraphOutput struct {
+	Graph *corelinks.Graph
+}
+
+// GetLinkGraphUseCase returns the pages of the wiki, or of a part of it, as
+// the nodes of a graph whose edges are the links between them.
+type GetLinkGraphUseCase struct {
+	links *corelinks.LinkService
+	tree  *tree.TreeService
+	tags  *coretags.TagsService
+	props *coreprop.PropertiesService
+}
+
+func NewGetLinkGraphUseCase(l *corelinks.LinkService, t *tree.TreeService, tags *coretags.TagsService, props *coreprop.PropertiesService) *GetLinkGraphUseCase {
+	return &GetLinkGraphUseCase{links: l, tree: t, tags: tags, props: props}
+}
+
+func (uc *GetLinkGraphUseCase) Execute(_ context.Context, in GetLinkGraphInput) (*GetLinkGraphOutput, error) {
+	if uc.links == nil {
+		return nil, ErrLinkServiceUnavailable
+	}
+	if in.RootID != "" && in.PageID != "" {
+		return nil, invalidGraphRequest("Either root or page can be set, not both", "either root or page can be set, not both")
+	}
+	if in.PageID != "" && (in.Depth < 1 || in.Depth > MaxGraphDepth) {
+		return nil, invalidGraphRequest("Invalid depth value", "invalid depth value")
+	}
+
+	links, err := uc.links.GetAllLinks()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	if in.PageID != "" {
+		if _, err := uc.tree.FindPageByID(in.PageID); err != nil {
+			return nil, graphPageError(err)
+		}
+		ids = corelinks.Neighbourhood(links, in.PageID, in.Depth)
+	} else {
+		root := in.RootID
+		if root == "" {
+			root = "root"
+		}
+		if ids, err = uc.tree.SubtreeIDs(root); err != nil {
+			return nil, graphPageError(err)
+		}
+	}
+
+	nodes := make([]corelinks.GraphNode, 0, len(ids))
+	for _, id := range ids {
+		// The links may still name a page that was just deleted.
+		node, err := uc.tree.FindPageByID(id)
+		if err != nil || node == nil {
+			continue
+		}
+		nodes = append(nodes, corelinks.GraphNode{
+			ID:    node.ID,
+			Title: node.Title,
+			Path:  node.CalculatePath(),
+			Kind:  string(node.Kind),
+		})
+	}
+	if err := uc.addLabels(nodes, in); err != nil {
+		return nil, err
+	}
+	return &GetLinkGraphOutput{Graph: corelinks.BuildGraph(nodes, links, in.IncludeBroken)}, nil
+}
+
+// addLabels adds the tags and properties of the pages to their nodes, as
+// far as asked for.
+func (uc *GetLinkGraphUseCase) addLabels(nodes []corelinks.GraphNode, in GetLinkGraphInput) error {
+	if len(nodes) == 0 || (!in.IncludeTags && !in.IncludeProperties) {
+		return nil
+	}
+	ids := make([]string, len(nodes))
+	for i, node := range nodes {
+		ids[i] = node.ID
+	}
+
+	if in.IncludeTags && uc.tags != nil {
+		tags, err := uc.tags.GetTagsForPages(ids)
+		if err != nil {
+			return err
+		}
+		for i := range nodes {
+			nodes[i].Tags = tags[nodes[i].ID]
+		}
+	}
+	if in.IncludeProperties && uc.props != nil {
+		props, err := uc.props.GetPropertiesForPages(ids)
+		if err != nil {
+			return err
+		}
+		for i := range nodes {
+			entries := props[nodes[i].ID]
+			if len(entries) == 0 {
+				continue
+			}
+			nodes[i].Properties = make(map[string]string, len(entries))
+			for key, entry := range entries {
+				nodes[i].Properties[key] = entry.Value
+			}
+		}
+	}
+	return nil
+}
+
+func invalidGraphRequest(message, template string) error {
+	return sharederrors.NewLocalizedError(ErrCodeLinkInvalidRequest, message, template, nil)
+}
+
+func graphPageError(err error) error {
+	if errors.Is(err, tree.ErrPageNotFound) {
+		return sharederrors.NewLocalizedError(
+			ErrCodeLinkPageNotFound,
+			"Page not found",
+			"page not found",
+			err,
+		)
+	}
+	return err
+}
@@ -1,7 +1,9 @@
 package links
 
 import (
+	"bytes"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	coreauth "github.com/perber/wiki/internal/core/auth"
@@ -13,12 +15,14 @@ import (
 // Routes is the RouteRegistrar for the links domain.
 type Routes struct {
 	getLinkStatus *GetLinkStatusUseCase
+	getLinkGraph  *GetLinkGraphUseCase
 	authService   *coreauth.AuthService
 }
 
 // RoutesConfig holds the dependencies required to build a Routes instance.
 type RoutesConfig struct {
 	GetLinkStatus *GetLinkStatusUseCase
+	GetLinkGraph  *GetLinkGraphUseCase
 	AuthService   *coreauth.AuthService
 }
 
@@ -26,6 +30,7 @@ type RoutesConfig struct {
 func NewRoutes(cfg RoutesConfig) *Routes {
 	return &Routes{
 		getLinkStatus: cfg.GetLinkStatus,
+		getLinkGraph:  cfg.GetLinkGraph,
 		authService:   cfg.AuthService,
 	}
 }
@@ -37,6 +42,7 @@ func (r *Routes) RegisterRoutes(ctx httpinternal.RouterContext) {
 	if opts.PublicAccess {
 		pub := ctx.Base.Group("/api")
 		pub.GET("/pages/:id/links", r.handleGetLinkStatus)
+		pub.GET("/links/graph", r.handleGetLinkGraph)
 	}
 
 	authGroup := ctx.Base.Group("/api")
@@ -48,6 +54,7 @@ func (r *Routes) RegisterRoutes(ctx httpinternal.RouterContext) {
 
 	if !opts.PublicAccess {
 		authGroup.GET("/pages/:id/links", r.handleGetLinkStatus)
+		authGroup.GET("/links/graph", r.handleGetLinkGraph)
 	}
 }
 
@@ -62,3 +69,51 @@ func (r *Routes) handleGetLinkStatus(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, out.Status)
 }
+
+func (r *Routes) handleGetLinkGraph(c *gin.Context) {
+	in := GetLinkGraphInput{RootID: c.Query("root"), PageID: c.Query("page")}
+	depth, err := strconv.Atoi(c.DefaultQuery("depth", strconv.Itoa(DefaultGraphDepth)))
+	if err != nil {
+		respondWithLinkStatusError(c, http.StatusBadRequest, ErrCodeLinkInvalidRequest, "Invalid depth value", "invalid depth value")
+		return
+	}
+	in.Depth = depth
+	flags := []struct {
+		key  string
+		into *bool
+	}{{"tags", &in.IncludeTags}, {"properties", &in.IncludeProperties}, {"broken", &in.IncludeBroken}}
+	for _, flag := range flags {
+		if *flag.into, err = strconv.ParseBool(c.DefaultQuery(flag.key, "false")); err != nil {
+			respondWithLinkStatusError(c, http.StatusBadRequest, ErrCodeLinkInvalidRequest, "Invalid "+flag.key+" value", "invalid "+flag.key+" value")
+			return
+		}
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "graphml" && format != "dot" {
+		respondWithLinkStatusError(c, http.StatusBadRequest, ErrCodeLinkInvalidRequest, "Invalid format, use json, graphml or dot", "invalid format, use json, graphml or dot")
+		return
+	}
+
+	out, err := r.getLinkGraph.Execute(c.Request.Context(), in)
+	if err != nil {
+		respondWithLinkError(c, err)
+		return
+	}
+	if format == "json" {
+		c.JSON(http.StatusOK, out.Graph)
+		return
+	}
+	var buf bytes.Buffer
+	contentType := "application/graphml+xml; charset=utf-8"
+	if format == "dot" {
+		contentType = "text/vnd.graphviz; charset=utf-8"
+		err = out.Graph.WriteDOT(&buf)
+	} else {
+		err = out.Graph.WriteGraphML(&buf)
+	}
+	if err != nil {
+		respondWithLinkError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
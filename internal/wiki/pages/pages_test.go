@@ -2727,9 +2727,9 @@ func TestMovePageUseCase_ReindexesRelativeLinks(t *testing.T) {
 func TestAssetUseCases_RecordAssetRevisionForUser(t *testing.T) {
 	deps := newTestDeps(t)
 	createUC := pages.NewCreatePageUseCase(deps.tree, deps.slug, deps.orchestrator(), slog.Default(), nil)
-	uploadUC := wikiassets.NewUploadAssetUseCase(deps.tree, deps.assets, deps.revision, slog.Default())
-	renameUC := wikiassets.NewRenameAssetUseCase(deps.tree, deps.assets, deps.revision, slog.Default())
-	deleteUC := wikiassets.NewDeleteAssetUseCase(deps.tree, deps.assets, deps.revision, slog.Default())
+	uploadUC := wikiassets.NewUploadAssetUseCase(deps.tree, deps.assets, deps.revision, nil, slog.Default())
+	renameUC := wikiassets.NewRenameAssetUseCase(deps.tree, deps.assets, deps.revision, nil, slog.Default())
+	deleteUC := wikiassets.NewDeleteAssetUseCase(deps.tree, deps.assets, deps.revision, nil, slog.Default())
 	listUC := wikiassets.NewListAssetsUseCase(deps.tree, deps.assets)
 
 	writeAsset := func(t *testing.T, pageID, name string, data []byte) {
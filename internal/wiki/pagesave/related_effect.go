@@ -0,0 +1,26 @@
+package pagesave
+
+import (
+	"github.com/perber/wiki/internal/related"
+)
+
+// RelatedSideEffect drops the cached related pages after every page
+// mutation, since any change to a page may change what others relate to.
+type RelatedSideEffect struct {
+	svc *related.RelatedService
+}
+
+func NewRelatedSideEffect(svc *related.RelatedService) *RelatedSideEffect {
+	return &RelatedSideEffect{svc: svc}
+}
+
+func (e *RelatedSideEffect) Name() string {
+	return "related"
+}
+
+func (e *RelatedSideEffect) Apply(PageSaveEvent) {
+	if e.svc == nil {
+		return
+	}
+	e.svc.Invalidate()
+}
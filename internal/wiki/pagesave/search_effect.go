@@ -3,8 +3,10 @@ package pagesave
 import (
 	"context"
 	"log/slog"
+	"path"
 	"strings"
 
+	"github.com/perber/wiki/internal/core/assets"
 	"github.com/perber/wiki/internal/core/tree"
 	httpmetrics "github.com/perber/wiki/internal/http/metrics"
 	"github.com/perber/wiki/internal/search"
@@ -16,6 +18,9 @@ type SearchIndexSideEffect struct {
 	tree    *tree.TreeService // only used by IndexAllPages for the initial walk
 	log     *slog.Logger
 	metrics *httpmetrics.HTTPMetrics
+	// assets is set when attachments are indexed, see EnableAttachments.
+	assets            *assets.AssetService
+	attachmentOptions search.AttachmentOptions
 }
 
 func NewSearchIndexSideEffect(index *search.SQLiteIndex, treeService *tree.TreeService, log *slog.Logger, metrics *httpmetrics.HTTPMetrics) *SearchIndexSideEffect {
@@ -25,6 +30,16 @@ func NewSearchIndexSideEffect(index *search.SQLiteIndex, treeService *tree.TreeS
 	return &SearchIndexSideEffect{index: index, tree: treeService, log: log, metrics: metrics}
 }
 
+// EnableAttachments makes the side effect index the text of the attachments
+// of created and restored pages and of all pages in IndexAllPages.
+func (e *SearchIndexSideEffect) EnableAttachments(a *assets.AssetService, opts search.AttachmentOptions) {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = search.DefaultAttachmentMaxBytes
+	}
+	e.assets = a
+	e.attachmentOptions = opts
+}
+
 func (e *SearchIndexSideEffect) Name() string {
 	return "search"
 }
@@ -39,6 +54,10 @@ func (e *SearchIndexSideEffect) Apply(event PageSaveEvent) {
 		if event.After != nil {
 			e.indexPage(event.After, event.Operation)
 		}
+		// Copies and restored pages come with their attachments.
+		if event.After != nil && event.Operation != PageOperationUpdate {
+			e.IndexPageAttachments(event.After.PageNode)
+		}
 
 	case PageOperationMove:
 		inputs := make([]search.IndexPageInput, 0, len(event.AffectedPages))
@@ -119,9 +138,72 @@ func (e *SearchIndexSideEffect) IndexAllPagesContext(ctx context.Context) error
 		}
 		e.writeToIndex(page, page.RawContent, "")
 	}
+	if e.assets == nil {
+		return nil
+	}
+	for i, page := range pages {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if errs[i] == nil {
+			e.IndexPageAttachments(page.PageNode)
+		}
+	}
 	return nil
 }
 
+// reindexBatchSize is the number of pages ReindexAllPagesContext writes per
+// transaction.
+const reindexBatchSize = 100
+
+// ReindexAllPagesContext indexes every page again without clearing the index
+// first, so that searches keep working meanwhile, and then marks the index
+// as analyzed with its current analyzer. progress, when not nil, is called
+// with the number of pages done after every batch.
+func (e *SearchIndexSideEffect) ReindexAllPagesContext(ctx context.Context, progress func(done, total int)) error {
+	if e.index == nil {
+		return nil
+	}
+
+	var ids []string
+	if err := e.tree.WalkNodes(func(id string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	pages, errs := e.tree.GetPages(ids)
+	for start := 0; start < len(pages); start += reindexBatchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := min(start+reindexBatchSize, len(pages))
+		inputs := make([]search.IndexPageInput, 0, end-start)
+		for i := start; i < end; i++ {
+			if errs[i] != nil {
+				e.log.Warn("skipping page during search reindex", "pageID", ids[i], "error", errs[i])
+				continue
+			}
+			inputs = append(inputs, buildIndexInput(pages[i]))
+		}
+		failures, err := e.index.IndexPages(inputs)
+		if err != nil {
+			return err
+		}
+		for _, f := range failures {
+			e.log.Warn("failed to update search index for page", "pageID", f.PageID, "error", f.Err)
+		}
+		if progress != nil {
+			progress(end, len(pages))
+		}
+	}
+	return e.index.MarkAnalyzed()
+}
+
 func (e *SearchIndexSideEffect) indexPage(page *tree.Page, operation PageOperationType) {
 	if page == nil {
 		return
@@ -142,3 +224,60 @@ func (e *SearchIndexSideEffect) writeToIndex(page *tree.Page, content string, op
 		}
 	}
 }
+
+// IndexAttachment indexes the text of an attachment of page, or removes it
+// from the index when it has none. It does nothing unless attachments are
+// enabled.
+func (e *SearchIndexSideEffect) IndexAttachment(page *tree.PageNode, filename string) {
+	if e == nil || e.index == nil || e.assets == nil || page == nil {
+		return
+	}
+	text, _ := e.attachmentText(page, filename)
+	if err := e.index.IndexAttachment(page.ID, filename, text); err != nil {
+		e.log.Warn("failed to update search index for attachment", "pageID", page.ID, "filename", filename, "error", err)
+	}
+}
+
+// RemoveAttachment removes an attachment of a page from the index.
+func (e *SearchIndexSideEffect) RemoveAttachment(pageID, filename string) {
+	if e == nil || e.index == nil || e.assets == nil {
+		return
+	}
+	if err := e.index.RemoveAttachment(pageID, filename); err != nil {
+		e.log.Warn("failed to remove attachment from search index", "pageID", pageID, "filename", filename, "error", err)
+	}
+}
+
+// IndexPageAttachments indexes the text of all attachments of page again.
+func (e *SearchIndexSideEffect) IndexPageAttachments(page *tree.PageNode) {
+	if e == nil || e.index == nil || e.assets == nil || page == nil {
+		return
+	}
+	files, err := e.assets.ListAssetsForPage(page)
+	if err != nil {
+		e.log.Warn("failed to list attachments for search index", "pageID", page.ID, "error", err)
+		return
+	}
+	texts := make(map[string]string, len(files))
+	for _, file := range files {
+		filename := path.Base(file)
+		if text, ok := e.attachmentText(page, filename); ok {
+			texts[filename] = text
+		}
+	}
+	if err := e.index.ReplaceAttachments(page.ID, texts, true); err != nil {
+		e.log.Warn("failed to update search index for attachments", "pageID", page.ID, "error", err)
+	}
+}
+
+func (e *SearchIndexSideEffect) attachmentText(page *tree.PageNode, filename string) (string, bool) {
+	if !e.attachmentOptions.Indexes(filename) {
+		return "", false
+	}
+	data, truncated, err := e.assets.ReadAssetForPage(page, filename, e.attachmentOptions.MaxBytes)
+	if err != nil {
+		e.log.Warn("failed to read attachment for search index", "pageID", page.ID, "filename", filename, "error", err)
+		return "", false
+	}
+	return e.attachmentOptions.AttachmentText(filename, data, truncated)
+}
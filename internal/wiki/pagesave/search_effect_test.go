@@ -1,10 +1,13 @@
 package pagesave
 
 import (
+	"context"
 	"testing"
 
+	"github.com/perber/wiki/internal/core/assets"
 	"github.com/perber/wiki/internal/core/tree"
 	"github.com/perber/wiki/internal/search"
+	"github.com/perber/wiki/internal/test_utils"
 )
 
 // setupSearchTest creates a temp-dir-backed tree, SQLiteIndex and SearchIndexSideEffect.
@@ -113,6 +116,44 @@ func TestSearchIndexSideEffect_IndexAllPages_EmptyTree(t *testing.T) {
 	}
 }
 
+// ─── ReindexAllPages ─────────────────────────────────────────────────────────
+
+func TestSearchIndexSideEffect_ReindexAllPages_AppliesNewAnalyzer(t *testing.T) {
+	treeSvc, index, effect := setupSearchTest(t)
+	page := createPageWithContent(t, treeSvc, "Zertifikate", "zertifikate", "Alle Zertifikate erneuern.")
+	if err := effect.IndexAllPages(); err != nil {
+		t.Fatalf("IndexAllPages: %v", err)
+	}
+
+	if err := index.SetAnalyzer(search.Analyzer{Language: search.LanguageGerman}); err != nil {
+		t.Fatalf("SetAnalyzer: %v", err)
+	}
+	if !index.AnalyzerOutdated() {
+		t.Fatal("expected the index to be outdated after the analyzer changed")
+	}
+
+	var progress [][2]int
+	if err := effect.ReindexAllPagesContext(context.Background(), func(done, total int) {
+		progress = append(progress, [2]int{done, total})
+	}); err != nil {
+		t.Fatalf("ReindexAllPagesContext: %v", err)
+	}
+	if index.AnalyzerOutdated() {
+		t.Error("expected the index to be up to date after the reindex")
+	}
+	if len(progress) == 0 || progress[len(progress)-1][0] != progress[len(progress)-1][1] {
+		t.Errorf("expected progress up to the total, got %v", progress)
+	}
+
+	result, err := index.Search("Zertifikat erneuert", nil, 0, 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if result.Count != 1 || result.Items[0].PageID != page.ID {
+		t.Fatalf("expected the page to be found by its stems, got %+v", result.Items)
+	}
+}
+
 // ─── Apply ───────────────────────────────────────────────────────────────────
 
 func TestSearchIndexSideEffect_Apply_Create_IndexesPage(t *testing.T) {
@@ -338,3 +379,54 @@ func TestSearchIndexSideEffect_Apply_Move_PageStillSearchableAtNewPath(t *testin
 		t.Errorf("expected path %q after move, got %q", wantPath, result.Items[0].Path)
 	}
 }
+
+// ─── Attachments ──────────────────────────────────────────────────────────────
+
+func TestSearchIndexSideEffect_Attachments(t *testing.T) {
+	treeSvc, index, effect := setupSearchTest(t)
+	assetSvc := assets.NewAssetService(t.TempDir(), tree.NewSlugService())
+	effect.EnableAttachments(assetSvc, search.AttachmentOptions{})
+
+	page := createPageWithContent(t, treeSvc, "Exports", "exports", "Monthly exports.")
+	for name, content := range map[string]string{"report.csv": "region,revenue\nnorthwind,42", "logo.png": "northwind"} {
+		file, filename, err := test_utils.CreateMultipartFile(name, []byte(content))
+		if err != nil {
+			t.Fatalf("CreateMultipartFile: %v", err)
+		}
+		if _, err := assetSvc.SaveAssetForPage(page.PageNode, file, filename, 1024); err != nil {
+			t.Fatalf("SaveAssetForPage: %v", err)
+		}
+		if err := file.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	}
+
+	if err := effect.IndexAllPages(); err != nil {
+		t.Fatalf("IndexAllPages: %v", err)
+	}
+	result, err := index.Search("northwind", nil, 0, 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(result.Attachments) != 1 || result.Attachments[0].Filename != "report.csv" || result.Attachments[0].PageID != page.ID {
+		t.Fatalf("expected the CSV attachment only, got %+v", result.Attachments)
+	}
+
+	effect.RemoveAttachment(page.ID, "report.csv")
+	result, err = index.Search("northwind", nil, 0, 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(result.Attachments) != 0 {
+		t.Fatalf("expected the removed attachment to be gone, got %+v", result.Attachments)
+	}
+
+	effect.IndexAttachment(page.PageNode, "report.csv")
+	result, err = index.Search("revenue", nil, 0, 10)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(result.Attachments) != 1 {
+		t.Fatalf("expected the attachment to be indexed again, got %+v", result.Attachments)
+	}
+}
@@ -0,0 +1,59 @@
+package related
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	sharederrors "github.com/perber/wiki/internal/core/shared/errors"
+)
+
+const (
+	ErrCodeRelatedPageNotFound  = "related_page_not_found"
+	ErrCodeRelatedInvalidLimit  = "related_invalid_limit"
+	ErrCodeRelatedInternalError = "related_internal_error"
+)
+
+// RelatedErrorResponse is the structured JSON error body returned by the
+// related pages endpoint.
+type RelatedErrorResponse struct {
+	Error RelatedErrorDetail `json:"error"`
+}
+
+// RelatedErrorDetail carries the localization-ready error data.
+type RelatedErrorDetail struct {
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Template string `json:"template"`
+}
+
+func respondWithRelatedStatusError(c *gin.Context, status int, code, message, template string) {
+	c.JSON(status, RelatedErrorResponse{
+		Error: RelatedErrorDetail{
+			Code:     code,
+			Message:  message,
+			Template: template,
+		},
+	})
+}
+
+// respondWithRelatedError maps errors to JSON responses for the related
+// pages endpoint.
+func respondWithRelatedError(c *gin.Context, err error) {
+	if loc, ok := sharederrors.AsLocalizedError(err); ok {
+		respondWithRelatedStatusError(c, relatedErrorStatus(loc.Code), loc.Code, loc.Message, loc.Template)
+		return
+	}
+
+	respondWithRelatedStatusError(c, http.StatusInternalServerError, ErrCodeRelatedInternalError, "Failed to load related pages", "failed to load related pages")
+}
+
+func relatedErrorStatus(code string) int {
+	switch code {
+	case ErrCodeRelatedPageNotFound:
+		return http.StatusNotFound
+	case ErrCodeRelatedInvalidLimit:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,69 @@
+package related
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	coreauth "github.com/perber/wiki/internal/core/auth"
+	httpinternal "github.com/perber/wiki/internal/http"
+	authmw "github.com/perber/wiki/internal/http/middleware/auth"
+	"github.com/perber/wiki/internal/http/middleware/security"
+)
+
+// Routes is the RouteRegistrar for the related pages domain.
+type Routes struct {
+	getRelatedPages *GetRelatedPagesUseCase
+	authService     *coreauth.AuthService
+}
+
+// RoutesConfig holds the dependencies required to build a Routes instance.
+type RoutesConfig struct {
+	GetRelatedPages *GetRelatedPagesUseCase
+	AuthService     *coreauth.AuthService
+}
+
+// NewRoutes constructs the related pages RouteRegistrar.
+func NewRoutes(cfg RoutesConfig) *Routes {
+	return &Routes{
+		getRelatedPages: cfg.GetRelatedPages,
+		authService:     cfg.AuthService,
+	}
+}
+
+// RegisterRoutes implements RouteRegistrar.
+func (r *Routes) RegisterRoutes(ctx httpinternal.RouterContext) {
+	opts := ctx.Opts
+
+	if opts.PublicAccess {
+		pub := ctx.Base.Group("/api")
+		pub.GET("/pages/:id/related", r.handleGetRelatedPages)
+	}
+
+	authGroup := ctx.Base.Group("/api")
+	authGroup.Use(
+		authmw.InjectPublicEditor(opts.AuthDisabled),
+		authmw.RequireAuth(r.authService, ctx.AuthCookies, opts.AuthDisabled),
+		security.CSRFMiddleware(ctx.CSRFCookie),
+	)
+
+	if !opts.PublicAccess {
+		authGroup.GET("/pages/:id/related", r.handleGetRelatedPages)
+	}
+}
+
+// ─── Handlers ───────────────────────────────────────────────────────────────
+
+func (r *Routes) handleGetRelatedPages(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(DefaultLimit)))
+	if err != nil {
+		respondWithRelatedStatusError(c, http.StatusBadRequest, ErrCodeRelatedInvalidLimit, "Invalid limit value", "invalid limit value")
+		return
+	}
+	out, err := r.getRelatedPages.Execute(c.Request.Context(), GetRelatedPagesInput{PageID: c.Param("id"), Limit: limit})
+	if err != nil {
+		respondWithRelatedError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out.Result)
+}
@@ -0,0 +1,96 @@
+package related
+
+import (
+	"context"
+	"errors"
+
+	sharederrors "github.com/perber/wiki/internal/core/shared/errors"
+	"github.com/perber/wiki/internal/core/tree"
+	corerelated "github.com/perber/wiki/internal/related"
+)
+
+// DefaultLimit is the number of related pages returned unless asked for
+// another number.
+const DefaultLimit = 10
+
+// ─── GetRelatedPagesUseCase ──────────────────────────────────────────────────
+
+type GetRelatedPagesInput struct {
+	PageID string
+	// Limit is between 1 and related.MaxRelated.
+	Limit int
+}
+
+// RelatedPagesResult lists the pages related to a page, the most related
+// first.
+type RelatedPagesResult struct {
+	Items []RelatedPageItem `json:"items"`
+}
+
+// RelatedPageItem is a related page with its current title and path.
+type RelatedPageItem struct {
+	PageID  string               `json:"page_id"`
+	Title   string               `json:"title"`
+	Path    string               `json:"path"`
+	Score   float64              `json:"score"`
+	Reasons []corerelated.Reason `json:"reasons"`
+}
+
+type GetRelatedPagesOutput struct {
+	Result *RelatedPagesResult
+}
+
+type GetRelatedPagesUseCase struct {
+	related *corerelated.RelatedService
+	tree    *tree.TreeService
+}
+
+func NewGetRelatedPagesUseCase(r *corerelated.RelatedService, t *tree.TreeService) *GetRelatedPagesUseCase {
+	return &GetRelatedPagesUseCase{related: r, tree: t}
+}
+
+func (uc *GetRelatedPagesUseCase) Execute(_ context.Context, in GetRelatedPagesInput) (*GetRelatedPagesOutput, error) {
+	if in.Limit < 1 || in.Limit > corerelated.MaxRelated {
+		return nil, sharederrors.NewLocalizedError(
+			ErrCodeRelatedInvalidLimit,
+			"Invalid limit value",
+			"invalid limit value",
+			nil,
+		)
+	}
+	if _, err := uc.tree.FindPageByID(in.PageID); err != nil {
+		if errors.Is(err, tree.ErrPageNotFound) {
+			return nil, sharederrors.NewLocalizedError(
+				ErrCodeRelatedPageNotFound,
+				"Page not found",
+				"page not found",
+				err,
+			)
+		}
+		return nil, err
+	}
+
+	pages, err := uc.related.RelatedPages(in.PageID)
+	if err != nil {
+		return nil, err
+	}
+	result := &RelatedPagesResult{Items: []RelatedPageItem{}}
+	for _, page := range pages {
+		if len(result.Items) == in.Limit {
+			break
+		}
+		// The indexes may still list a page that was just deleted.
+		node, err := uc.tree.FindPageByID(page.PageID)
+		if err != nil || node == nil {
+			continue
+		}
+		result.Items = append(result.Items, RelatedPageItem{
+			PageID:  page.PageID,
+			Title:   node.Title,
+			Path:    node.CalculatePath(),
+			Score:   page.Score,
+			Reasons: page.Reasons,
+		})
+	}
+	return &GetRelatedPagesOutput{Result: result}, nil
+}
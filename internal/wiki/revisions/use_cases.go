@@ -23,6 +23,7 @@ type RevisionResponse struct {
 	Type              string              `json:"type"`
 	AuthorID          string              `json:"authorId"`
 	Author            *coreauth.UserLabel `json:"author,omitempty"`
+	AuthorName        string              `json:"authorName,omitempty"`
 	CreatedAt         string              `json:"createdAt"`
 	Title             string              `json:"title"`
 	Slug              string              `json:"slug"`
@@ -90,6 +91,7 @@ func toRevisionResponse(rev *revision.Revision, userResolver *coreauth.UserResol
 		Type:              string(rev.Type),
 		AuthorID:          rev.AuthorID,
 		Author:            author,
+		AuthorName:        rev.AuthorName,
 		CreatedAt:         formatTime(rev.CreatedAt),
 		Title:             rev.Title,
 		Slug:              rev.Slug,
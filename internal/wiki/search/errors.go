@@ -13,6 +13,12 @@ const (
 	ErrCodeSearchMissingQuery  = "search_missing_query"
 	ErrCodeSearchInvalidOffset = "search_invalid_offset"
 	ErrCodeSearchInvalidLimit  = "search_invalid_limit"
+	ErrCodeSearchInvalidQuery  = "search_invalid_query"
+	ErrCodeSearchInvalidTime   = "search_invalid_time"
+
+	ErrCodeSavedSearchNotFound  = "saved_search_not_found"
+	ErrCodeSavedSearchForbidden = "saved_search_forbidden"
+	ErrCodeSavedSearchInvalid   = "saved_search_invalid_request"
 )
 
 // SearchErrorResponse is the structured JSON error body returned by search endpoints.
@@ -53,8 +59,13 @@ func searchErrorStatus(code string) int {
 	switch code {
 	case ErrCodeSearchUnavailable:
 		return http.StatusServiceUnavailable
-	case ErrCodeSearchMissingQuery, ErrCodeSearchInvalidOffset, ErrCodeSearchInvalidLimit:
+	case ErrCodeSearchMissingQuery, ErrCodeSearchInvalidOffset, ErrCodeSearchInvalidLimit, ErrCodeSearchInvalidQuery, ErrCodeSearchInvalidTime,
+		ErrCodeSavedSearchInvalid:
 		return http.StatusBadRequest
+	case ErrCodeSavedSearchNotFound:
+		return http.StatusNotFound
+	case ErrCodeSavedSearchForbidden:
+		return http.StatusForbidden
 	default:
 		return http.StatusInternalServerError
 	}
@@ -0,0 +1,148 @@
+package search
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/perber/wiki/internal/core/auth"
+	"github.com/perber/wiki/internal/core/revision"
+	"github.com/perber/wiki/internal/core/tree"
+	coreprop "github.com/perber/wiki/internal/properties"
+	coresearch "github.com/perber/wiki/internal/search"
+	coretags "github.com/perber/wiki/internal/tags"
+)
+
+// ─── HistoryIndexer ──────────────────────────────────────────────────────────
+
+// HistoryIndexer keeps the history index in line with the revisions the
+// revision service stores and prunes. Index errors are only logged so that
+// they never fail a save.
+type HistoryIndexer struct {
+	index *coresearch.HistoryIndex
+	log   *slog.Logger
+}
+
+func NewHistoryIndexer(index *coresearch.HistoryIndex, log *slog.Logger) *HistoryIndexer {
+	return &HistoryIndexer{index: index, log: log}
+}
+
+// RevisionSaved implements revision.Observer.
+func (x *HistoryIndexer) RevisionSaved(rev *revision.Revision, content string) {
+	if err := x.index.IndexRevision(historyRevision(rev, content)); err != nil {
+		x.log.Warn("failed to index revision for history search", "pageID", rev.PageID, "revisionID", rev.ID, "error", err)
+	}
+}
+
+// RevisionsRemoved implements revision.Observer.
+func (x *HistoryIndexer) RevisionsRemoved(pageID string, revisionIDs []string) {
+	if err := x.index.RemoveRevisions(pageID, revisionIDs); err != nil {
+		x.log.Warn("failed to remove revisions from history search", "pageID", pageID, "error", err)
+	}
+}
+
+// IndexAll adds every revision stored by svc to the index.
+func (x *HistoryIndexer) IndexAll(ctx context.Context, svc *revision.Service) error {
+	return svc.WalkRevisions(func(rev *revision.Revision, content string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		x.RevisionSaved(rev, content)
+		return nil
+	})
+}
+
+func historyRevision(rev *revision.Revision, content string) coresearch.HistoryRevision {
+	return coresearch.HistoryRevision{
+		PageID:      rev.PageID,
+		RevisionID:  rev.ID,
+		ContentHash: rev.ContentHash,
+		Title:       rev.Title,
+		Path:        rev.Path,
+		AuthorID:    rev.AuthorID,
+		AuthorName:  rev.AuthorName,
+		CreatedAt:   rev.CreatedAt,
+		Content:     content,
+	}
+}
+
+// ─── SearchHistoryUseCase ────────────────────────────────────────────────────
+
+type SearchHistoryInput struct {
+	Query string
+	// From and To limit the search to the versions current at some time
+	// between them; either may be zero. Without both, the current versions
+	// are searched.
+	From, To   time.Time
+	AnyVersion bool
+	Offset     int
+	Limit      int
+}
+
+type SearchHistoryOutput struct {
+	Result *coresearch.HistorySearchResult
+}
+
+type SearchHistoryUseCase struct {
+	index *coresearch.HistoryIndex
+	pages *SearchUseCase
+}
+
+func NewSearchHistoryUseCase(idx *coresearch.HistoryIndex, tags *coretags.TagsService, props *coreprop.PropertiesService, tree *tree.TreeService, users *auth.UserResolver) *SearchHistoryUseCase {
+	return &SearchHistoryUseCase{index: idx, pages: NewSearchUseCase(nil, tags, props, tree, users)}
+}
+
+func (uc *SearchHistoryUseCase) Execute(_ context.Context, in SearchHistoryInput) (*SearchHistoryOutput, error) {
+	if uc.index == nil {
+		return nil, ErrSearchUnavailable
+	}
+
+	query, err := uc.pages.parseQuery(in.Query)
+	if err != nil {
+		return nil, invalidQueryError(err)
+	}
+
+	// Tags and properties are those of the pages today; authors and dates
+	// are matched against each revision by the index.
+	scope, err := uc.pages.resolveLabelScope(query, nil)
+	if err != nil {
+		return nil, err
+	}
+	authorIDs, err := uc.resolveAuthors(query)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := uc.index.Search(query, scope, coresearch.HistorySearchOptions{
+		From:       in.From,
+		To:         in.To,
+		AnyVersion: in.AnyVersion,
+		AuthorIDs:  authorIDs,
+	}, in.Offset, in.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return &SearchHistoryOutput{Result: result}, nil
+}
+
+// resolveAuthors maps the values of the author: filters of query to the
+// IDs of the revision authors they name.
+func (uc *SearchHistoryUseCase) resolveAuthors(query *coresearch.Query) (map[string][]string, error) {
+	filters := query.FiltersOf(coresearch.FilterAuthor)
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	ids, err := uc.index.AuthorIDs()
+	if err != nil {
+		return nil, err
+	}
+	authorIDs := map[string][]string{}
+	for _, f := range filters {
+		for _, id := range ids {
+			if uc.pages.isUser(id, f.Value) {
+				authorIDs[f.Value] = append(authorIDs[f.Value], id)
+			}
+		}
+	}
+	return authorIDs, nil
+}
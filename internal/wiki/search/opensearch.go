@@ -0,0 +1,140 @@
+package search
+
+import (
+	"context"
+	"encoding/xml"
+	"unicode/utf8"
+
+	"github.com/perber/wiki/internal/branding"
+	sharederrors "github.com/perber/wiki/internal/core/shared/errors"
+	"github.com/perber/wiki/internal/core/tree"
+	"github.com/perber/wiki/internal/http/dto"
+)
+
+const (
+	// DefaultSuggestionLimit is the number of title suggestions returned
+	// unless asked for another number, at most MaxSuggestionLimit.
+	DefaultSuggestionLimit = 10
+	MaxSuggestionLimit     = 20
+
+	// OpenSearchDescriptionType and OpenSearchSuggestionsType are the media
+	// types of the description document and of the suggestions.
+	OpenSearchDescriptionType = "application/opensearchdescription+xml"
+	OpenSearchSuggestionsType = "application/x-suggestions+json"
+
+	openSearchNamespace = "http://a9.com/-/spec/opensearch/1.1/"
+	// The OpenSearch 1.1 specification limits the short name to 16
+	// characters.
+	maxOpenSearchShortName = 16
+	defaultSiteName        = "LeafWiki"
+)
+
+// ─── SuggestTitlesUseCase ────────────────────────────────────────────────────
+
+type SuggestTitlesInput struct {
+	Query string
+	Limit int
+}
+
+// SuggestTitlesOutput holds the completions of a query, with the path of the
+// page each one stands for.
+type SuggestTitlesOutput struct {
+	Query  string
+	Titles []string
+	Paths  []string
+}
+
+// SuggestTitlesUseCase completes a query with page titles. It reads the
+// title index of the tree only, so it stays fast enough to run on every
+// keystroke in the browser's search bar.
+type SuggestTitlesUseCase struct {
+	tree *tree.TreeService
+}
+
+func NewSuggestTitlesUseCase(t *tree.TreeService) *SuggestTitlesUseCase {
+	return &SuggestTitlesUseCase{tree: t}
+}
+
+func (uc *SuggestTitlesUseCase) Execute(_ context.Context, in SuggestTitlesInput) (*SuggestTitlesOutput, error) {
+	if in.Limit < 1 || in.Limit > MaxSuggestionLimit {
+		return nil, sharederrors.NewLocalizedError(ErrCodeSearchInvalidLimit, "Invalid limit value", "invalid limit value", nil)
+	}
+
+	out := &SuggestTitlesOutput{Query: in.Query, Titles: []string{}, Paths: []string{}}
+	for _, node := range uc.tree.SuggestPagesByTitle(in.Query, in.Limit) {
+		out.Titles = append(out.Titles, node.Title)
+		out.Paths = append(out.Paths, dto.BuildPathFromNode(node))
+	}
+	return out, nil
+}
+
+// ─── GetOpenSearchDescriptionUseCase ─────────────────────────────────────────
+
+// OpenSearchDescription is the OpenSearch 1.1 description document that lets
+// browsers add the wiki as a search engine.
+type OpenSearchDescription struct {
+	XMLName       xml.Name        `xml:"OpenSearchDescription"`
+	Namespace     string          `xml:"xmlns,attr"`
+	ShortName     string          `xml:"ShortName"`
+	Description   string          `xml:"Description"`
+	InputEncoding string          `xml:"InputEncoding"`
+	Image         string          `xml:"Image"`
+	URLs          []OpenSearchURL `xml:"Url"`
+}
+
+type OpenSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Rel      string `xml:"rel,attr,omitempty"`
+	Method   string `xml:"method,attr"`
+	Template string `xml:"template,attr"`
+}
+
+// GetOpenSearchDescriptionInput carries the absolute URL the wiki is served
+// at, including the base path and without a trailing slash.
+type GetOpenSearchDescriptionInput struct {
+	BaseURL string
+}
+
+type GetOpenSearchDescriptionOutput struct {
+	Description *OpenSearchDescription
+}
+
+type GetOpenSearchDescriptionUseCase struct {
+	branding *branding.BrandingService
+}
+
+func NewGetOpenSearchDescriptionUseCase(b *branding.BrandingService) *GetOpenSearchDescriptionUseCase {
+	return &GetOpenSearchDescriptionUseCase{branding: b}
+}
+
+func (uc *GetOpenSearchDescriptionUseCase) Execute(_ context.Context, in GetOpenSearchDescriptionInput) (*GetOpenSearchDescriptionOutput, error) {
+	siteName := defaultSiteName
+	if uc.branding != nil {
+		cfg, err := uc.branding.GetBranding()
+		if err != nil {
+			return nil, err
+		}
+		if cfg != nil && cfg.SiteName != "" {
+			siteName = cfg.SiteName
+		}
+	}
+
+	shortName := siteName
+	if utf8.RuneCountInString(shortName) > maxOpenSearchShortName {
+		shortName = string([]rune(shortName)[:maxOpenSearchShortName])
+	}
+
+	return &GetOpenSearchDescriptionOutput{Description: &OpenSearchDescription{
+		Namespace:     openSearchNamespace,
+		ShortName:     shortName,
+		Description:   "Search " + siteName,
+		InputEncoding: "UTF-8",
+		Image:         in.BaseURL + "/favicon.ico",
+		URLs: []OpenSearchURL{
+			// The frontend opens the search panel for a ?q= on any page.
+			{Type: "text/html", Method: "get", Template: in.BaseURL + "/?q={searchTerms}"},
+			{Type: OpenSearchSuggestionsType, Method: "get", Template: in.BaseURL + "/api/search/suggest?q={searchTerms}"},
+			{Type: OpenSearchDescriptionType, Rel: "self", Method: "get", Template: in.BaseURL + "/opensearch.xml"},
+		},
+	}}, nil
+}
@@ -1,37 +1,76 @@
 package search
 
 import (
+	"encoding/xml"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	coreauth "github.com/perber/wiki/internal/core/auth"
 	httpinternal "github.com/perber/wiki/internal/http"
 	authmw "github.com/perber/wiki/internal/http/middleware/auth"
 	"github.com/perber/wiki/internal/http/middleware/security"
+	"github.com/perber/wiki/internal/http/middleware/utils"
 )
 
 // Routes is the RouteRegistrar for the search domain.
 type Routes struct {
-	search            *SearchUseCase
-	getIndexingStatus *GetIndexingStatusUseCase
-	authService       *coreauth.AuthService
+	search                *SearchUseCase
+	searchHistory         *SearchHistoryUseCase
+	getIndexingStatus     *GetIndexingStatusUseCase
+	suggestTitles         *SuggestTitlesUseCase
+	openSearch            *GetOpenSearchDescriptionUseCase
+	createSavedSearch     *CreateSavedSearchUseCase
+	updateSavedSearch     *UpdateSavedSearchUseCase
+	deleteSavedSearch     *DeleteSavedSearchUseCase
+	getSavedSearch        *GetSavedSearchUseCase
+	listSavedSearches     *ListSavedSearchesUseCase
+	setSubscription       *SetSavedSearchSubscriptionUseCase
+	listNotifications     *ListNotificationsUseCase
+	markNotificationsRead *MarkNotificationsReadUseCase
+	authService           *coreauth.AuthService
 }
 
 // RoutesConfig holds the dependencies required to build a Routes instance.
 type RoutesConfig struct {
-	Search            *SearchUseCase
+	Search *SearchUseCase
+	// SearchHistory is nil when history search is disabled.
+	SearchHistory     *SearchHistoryUseCase
 	GetIndexingStatus *GetIndexingStatusUseCase
-	AuthService       *coreauth.AuthService
+	// Browser integration: title suggestions and the OpenSearch description.
+	SuggestTitles            *SuggestTitlesUseCase
+	GetOpenSearchDescription *GetOpenSearchDescriptionUseCase
+	// Saved searches, their subscriptions and notifications.
+	CreateSavedSearch          *CreateSavedSearchUseCase
+	UpdateSavedSearch          *UpdateSavedSearchUseCase
+	DeleteSavedSearch          *DeleteSavedSearchUseCase
+	GetSavedSearch             *GetSavedSearchUseCase
+	ListSavedSearches          *ListSavedSearchesUseCase
+	SetSavedSearchSubscription *SetSavedSearchSubscriptionUseCase
+	ListNotifications          *ListNotificationsUseCase
+	MarkNotificationsRead      *MarkNotificationsReadUseCase
+	AuthService                *coreauth.AuthService
 }
 
 // NewRoutes constructs the search RouteRegistrar.
 func NewRoutes(cfg RoutesConfig) *Routes {
 	return &Routes{
-		search:            cfg.Search,
-		getIndexingStatus: cfg.GetIndexingStatus,
-		authService:       cfg.AuthService,
+		search:                cfg.Search,
+		searchHistory:         cfg.SearchHistory,
+		getIndexingStatus:     cfg.GetIndexingStatus,
+		suggestTitles:         cfg.SuggestTitles,
+		openSearch:            cfg.GetOpenSearchDescription,
+		createSavedSearch:     cfg.CreateSavedSearch,
+		updateSavedSearch:     cfg.UpdateSavedSearch,
+		deleteSavedSearch:     cfg.DeleteSavedSearch,
+		getSavedSearch:        cfg.GetSavedSearch,
+		listSavedSearches:     cfg.ListSavedSearches,
+		setSubscription:       cfg.SetSavedSearchSubscription,
+		listNotifications:     cfg.ListNotifications,
+		markNotificationsRead: cfg.MarkNotificationsRead,
+		authService:           cfg.AuthService,
 	}
 }
 
@@ -39,9 +78,14 @@ func NewRoutes(cfg RoutesConfig) *Routes {
 func (r *Routes) RegisterRoutes(ctx httpinternal.RouterContext) {
 	opts := ctx.Opts
 
+	// Browsers fetch the description without credentials; it names the
+	// site, as the public branding does, and nothing else.
+	ctx.Base.GET("/opensearch.xml", r.handleOpenSearchDescription(opts.BasePath))
+
 	if opts.PublicAccess {
 		pub := ctx.Base.Group("/api")
 		pub.GET("/search/status", r.handleGetIndexingStatus)
+		pub.GET("/search/suggest", r.handleSuggest(opts.BasePath))
 		pub.GET("/search", r.handleSearch)
 	}
 
@@ -54,8 +98,24 @@ func (r *Routes) RegisterRoutes(ctx httpinternal.RouterContext) {
 
 	if !opts.PublicAccess {
 		authGroup.GET("/search/status", r.handleGetIndexingStatus)
+		authGroup.GET("/search/suggest", r.handleSuggest(opts.BasePath))
 		authGroup.GET("/search", r.handleSearch)
 	}
+	// Like the revisions themselves, their history is never public.
+	if r.searchHistory != nil {
+		authGroup.GET("/search/history", r.handleSearchHistory)
+	}
+
+	// Saved searches belong to a user, so they are never public.
+	authGroup.GET("/saved-searches", r.handleListSavedSearches)
+	authGroup.POST("/saved-searches", r.handleCreateSavedSearch)
+	authGroup.GET("/saved-searches/:id", r.handleGetSavedSearch)
+	authGroup.PUT("/saved-searches/:id", r.handleUpdateSavedSearch)
+	authGroup.DELETE("/saved-searches/:id", r.handleDeleteSavedSearch)
+	authGroup.PUT("/saved-searches/:id/subscription", r.handleSetSubscription(true))
+	authGroup.DELETE("/saved-searches/:id/subscription", r.handleSetSubscription(false))
+	authGroup.GET("/notifications", r.handleListNotifications)
+	authGroup.POST("/notifications/read", r.handleMarkNotificationsRead)
 }
 
 // ─── Handlers ───────────────────────────────────────────────────────────────
@@ -90,6 +150,292 @@ func (r *Routes) handleSearch(c *gin.Context) {
 	c.JSON(http.StatusOK, out.Result)
 }
 
+func (r *Routes) handleSearchHistory(c *gin.Context) {
+	query := c.Query("q")
+	if query == "" {
+		respondWithSearchStatusError(c, http.StatusBadRequest, ErrCodeSearchMissingQuery, "Query parameter 'q' is required", "query parameter q is required")
+		return
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		respondWithSearchStatusError(c, http.StatusBadRequest, ErrCodeSearchInvalidOffset, "Invalid offset value", "invalid offset value")
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		respondWithSearchStatusError(c, http.StatusBadRequest, ErrCodeSearchInvalidLimit, "Invalid limit value", "invalid limit value")
+		return
+	}
+	from, ok := queryTime(c, "from", false)
+	if !ok {
+		return
+	}
+	to, ok := queryTime(c, "to", true)
+	if !ok {
+		return
+	}
+	anyVersion, err := strconv.ParseBool(c.DefaultQuery("any_version", "false"))
+	if err != nil {
+		respondWithSearchStatusError(c, http.StatusBadRequest, ErrCodeSearchInvalidQuery, "Invalid any_version value", "invalid any_version value")
+		return
+	}
+
+	out, err := r.searchHistory.Execute(c.Request.Context(), SearchHistoryInput{
+		Query:      query,
+		From:       from,
+		To:         to,
+		AnyVersion: anyVersion,
+		Offset:     offset,
+		Limit:      limit,
+	})
+	if err != nil {
+		respondWithSearchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out.Result)
+}
+
+// queryTime parses the RFC 3339 time or YYYY-MM-DD date in the query
+// parameter key; a date stands for its start, or its end when endOfDay is
+// set. It responds with an error and returns false when the value is
+// invalid.
+func queryTime(c *gin.Context, key string, endOfDay bool) (time.Time, bool) {
+	raw := strings.TrimSpace(c.Query(key))
+	if raw == "" {
+		return time.Time{}, true
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse(time.DateOnly, raw); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		return t, true
+	}
+	respondWithSearchStatusError(c, http.StatusBadRequest, ErrCodeSearchInvalidTime,
+		"Invalid time in parameter '"+key+"'", "invalid time in parameter %s", key)
+	return time.Time{}, false
+}
+
+type savedSearchRequest struct {
+	Name   string   `json:"name"`
+	Query  string   `json:"query"`
+	Tags   []string `json:"tags"`
+	Shared bool     `json:"shared"`
+	// Subscribe is only read on create.
+	Subscribe bool `json:"subscribe"`
+}
+
+func (req savedSearchRequest) fields() SavedSearchFields {
+	return SavedSearchFields{Name: req.Name, Query: req.Query, Tags: req.Tags, Shared: req.Shared}
+}
+
+func bindSavedSearchRequest(c *gin.Context) (savedSearchRequest, bool) {
+	var req savedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondWithSearchStatusError(c, http.StatusBadRequest, ErrCodeSavedSearchInvalid, "Invalid request", "invalid request")
+		return req, false
+	}
+	return req, true
+}
+
+func (r *Routes) handleListSavedSearches(c *gin.Context) {
+	user := authmw.MustGetUser(c)
+	if user == nil {
+		return
+	}
+	out, err := r.listSavedSearches.Execute(c.Request.Context(), ListSavedSearchesInput{UserID: user.ID})
+	if err != nil {
+		respondWithSearchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out.Searches)
+}
+
+func (r *Routes) handleCreateSavedSearch(c *gin.Context) {
+	user := authmw.MustGetUser(c)
+	if user == nil {
+		return
+	}
+	req, ok := bindSavedSearchRequest(c)
+	if !ok {
+		return
+	}
+	out, err := r.createSavedSearch.Execute(c.Request.Context(), CreateSavedSearchInput{
+		UserID:            user.ID,
+		SavedSearchFields: req.fields(),
+		Subscribe:         req.Subscribe,
+	})
+	if err != nil {
+		respondWithSearchError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, out.Search)
+}
+
+func (r *Routes) handleGetSavedSearch(c *gin.Context) {
+	user := authmw.MustGetUser(c)
+	if user == nil {
+		return
+	}
+	out, err := r.getSavedSearch.Execute(c.Request.Context(), GetSavedSearchInput{UserID: user.ID, ID: c.Param("id")})
+	if err != nil {
+		respondWithSearchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out.Search)
+}
+
+func (r *Routes) handleUpdateSavedSearch(c *gin.Context) {
+	user := authmw.MustGetUser(c)
+	if user == nil {
+		return
+	}
+	req, ok := bindSavedSearchRequest(c)
+	if !ok {
+		return
+	}
+	out, err := r.updateSavedSearch.Execute(c.Request.Context(), UpdateSavedSearchInput{
+		UserID:            user.ID,
+		ID:                c.Param("id"),
+		SavedSearchFields: req.fields(),
+	})
+	if err != nil {
+		respondWithSearchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out.Search)
+}
+
+func (r *Routes) handleDeleteSavedSearch(c *gin.Context) {
+	user := authmw.MustGetUser(c)
+	if user == nil {
+		return
+	}
+	if err := r.deleteSavedSearch.Execute(c.Request.Context(), DeleteSavedSearchInput{UserID: user.ID, ID: c.Param("id")}); err != nil {
+		respondWithSearchError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (r *Routes) handleSetSubscription(subscribed bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := authmw.MustGetUser(c)
+		if user == nil {
+			return
+		}
+		out, err := r.setSubscription.Execute(c.Request.Context(), SetSavedSearchSubscriptionInput{
+			UserID:     user.ID,
+			ID:         c.Param("id"),
+			Subscribed: subscribed,
+		})
+		if err != nil {
+			respondWithSearchError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, out.Search)
+	}
+}
+
+func (r *Routes) handleListNotifications(c *gin.Context) {
+	user := authmw.MustGetUser(c)
+	if user == nil {
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(DefaultNotificationLimit)))
+	if err != nil {
+		respondWithSearchStatusError(c, http.StatusBadRequest, ErrCodeSearchInvalidLimit, "Invalid limit value", "invalid limit value")
+		return
+	}
+	unread, err := strconv.ParseBool(c.DefaultQuery("unread", "false"))
+	if err != nil {
+		respondWithSearchStatusError(c, http.StatusBadRequest, ErrCodeSavedSearchInvalid, "Invalid unread value", "invalid unread value")
+		return
+	}
+	out, err := r.listNotifications.Execute(c.Request.Context(), ListNotificationsInput{UserID: user.ID, UnreadOnly: unread, Limit: limit})
+	if err != nil {
+		respondWithSearchError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, out.Notifications)
+}
+
+func (r *Routes) handleMarkNotificationsRead(c *gin.Context) {
+	user := authmw.MustGetUser(c)
+	if user == nil {
+		return
+	}
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondWithSearchStatusError(c, http.StatusBadRequest, ErrCodeSavedSearchInvalid, "Invalid request", "invalid request")
+			return
+		}
+	}
+	if err := r.markNotificationsRead.Execute(c.Request.Context(), MarkNotificationsReadInput{UserID: user.ID, IDs: req.IDs}); err != nil {
+		respondWithSearchError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// handleSuggest answers in the OpenSearch suggestions format:
+// [query, [titles], [descriptions], [urls]].
+func (r *Routes) handleSuggest(basePath string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(DefaultSuggestionLimit)))
+		if err != nil {
+			respondWithSearchStatusError(c, http.StatusBadRequest, ErrCodeSearchInvalidLimit, "Invalid limit value", "invalid limit value")
+			return
+		}
+		out, err := r.suggestTitles.Execute(c.Request.Context(), SuggestTitlesInput{Query: c.Query("q"), Limit: limit})
+		if err != nil {
+			respondWithSearchError(c, err)
+			return
+		}
+
+		baseURL := requestBaseURL(c, basePath)
+		urls := make([]string, len(out.Paths))
+		for i, path := range out.Paths {
+			urls[i] = baseURL + "/" + path
+		}
+		c.Header("Content-Type", OpenSearchSuggestionsType+"; charset=utf-8")
+		c.JSON(http.StatusOK, []any{out.Query, out.Titles, out.Paths, urls})
+	}
+}
+
+func (r *Routes) handleOpenSearchDescription(basePath string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		out, err := r.openSearch.Execute(c.Request.Context(), GetOpenSearchDescriptionInput{BaseURL: requestBaseURL(c, basePath)})
+		if err != nil {
+			respondWithSearchError(c, err)
+			return
+		}
+		doc, err := xml.MarshalIndent(out.Description, "", "  ")
+		if err != nil {
+			respondWithSearchError(c, err)
+			return
+		}
+		c.Data(http.StatusOK, OpenSearchDescriptionType+"; charset=utf-8", append([]byte(xml.Header), doc...))
+	}
+}
+
+// requestBaseURL is the absolute URL the request reached the wiki at,
+// including the base path. Behind a proxy it relies on the Host and
+// X-Forwarded-Proto headers passed on.
+func requestBaseURL(c *gin.Context, basePath string) string {
+	scheme := "http"
+	if secure, _ := utils.RequireSecure(c, true); secure {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + basePath
+}
+
 func (r *Routes) handleGetIndexingStatus(c *gin.Context) {
 	out := r.getIndexingStatus.Execute(c.Request.Context())
 	c.JSON(http.StatusOK, out.Status)
@@ -0,0 +1,565 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/perber/wiki/internal/core/auth"
+	sharederrors "github.com/perber/wiki/internal/core/shared/errors"
+	"github.com/perber/wiki/internal/favorites"
+	"github.com/perber/wiki/internal/http/dto"
+	httpmetrics "github.com/perber/wiki/internal/http/metrics"
+	"github.com/perber/wiki/internal/wiki/pagesave"
+)
+
+const (
+	maxSavedSearchNameLength = 100
+	// DefaultNotificationLimit is the number of notifications listed unless
+	// asked for another number, at most MaxNotificationLimit.
+	DefaultNotificationLimit = 50
+	MaxNotificationLimit     = 200
+)
+
+var (
+	ErrSavedSearchNotFound = sharederrors.NewLocalizedError(
+		ErrCodeSavedSearchNotFound,
+		"Saved search not found",
+		"saved search not found",
+		nil,
+	)
+	ErrSavedSearchForbidden = sharederrors.NewLocalizedError(
+		ErrCodeSavedSearchForbidden,
+		"Only the owner may change a saved search",
+		"only the owner may change a saved search",
+		nil,
+	)
+)
+
+// SavedSearchResponse is a saved search as seen by one user.
+type SavedSearchResponse struct {
+	ID    string   `json:"id"`
+	Name  string   `json:"name"`
+	Query string   `json:"query"`
+	Tags  []string `json:"tags"`
+	// Shared searches are listed for every user, who may subscribe to them.
+	Shared     bool            `json:"shared"`
+	OwnerID    string          `json:"owner_id"`
+	Owner      *auth.UserLabel `json:"owner,omitempty"`
+	Subscribed bool            `json:"subscribed"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// SearchNotificationResponse tells that a page started to match a saved
+// search.
+type SearchNotificationResponse struct {
+	ID         string    `json:"id"`
+	SearchID   string    `json:"search_id"`
+	SearchName string    `json:"search_name"`
+	PageID     string    `json:"page_id"`
+	Title      string    `json:"title"`
+	Path       string    `json:"path"`
+	CreatedAt  time.Time `json:"created_at"`
+	Read       bool      `json:"read"`
+}
+
+// SavedSearchFields are the fields of a saved search its owner sets.
+type SavedSearchFields struct {
+	Name   string
+	Query  string
+	Tags   []string
+	Shared bool
+}
+
+// savedSearches holds what the saved search use cases share. The pages
+// search use case decides which pages a saved search matches.
+type savedSearches struct {
+	store *favorites.FavoritesStore
+	pages *SearchUseCase
+}
+
+// validate checks and normalizes the fields of a saved search.
+func (s *savedSearches) validate(in *SavedSearchFields) error {
+	in.Name = strings.TrimSpace(in.Name)
+	in.Query = strings.TrimSpace(in.Query)
+	in.Tags = normalizeTags(in.Tags)
+	if in.Name == "" || utf8.RuneCountInString(in.Name) > maxSavedSearchNameLength {
+		return sharederrors.NewLocalizedError(
+			ErrCodeSavedSearchInvalid,
+			"A saved search needs a name of at most 100 characters",
+			"a saved search needs a name of at most %s characters",
+			nil,
+			"100",
+		)
+	}
+	if in.Query == "" && len(in.Tags) == 0 {
+		return sharederrors.NewLocalizedError(
+			ErrCodeSearchMissingQuery,
+			"A saved search needs a query or tags",
+			"a saved search needs a query or tags",
+			nil,
+		)
+	}
+	if _, err := s.pages.parseQuery(in.Query); err != nil {
+		return invalidQueryError(err)
+	}
+	return nil
+}
+
+// visible returns the saved search id if userID may see it.
+func (s *savedSearches) visible(userID, id string) (*favorites.SavedSearch, error) {
+	search, err := s.store.GetSavedSearch(id)
+	if errors.Is(err, favorites.ErrSavedSearchNotFound) || (err == nil && search.UserID != userID && !search.Shared) {
+		return nil, ErrSavedSearchNotFound
+	}
+	return search, err
+}
+
+// owned returns the saved search id if userID owns it.
+func (s *savedSearches) owned(userID, id string) (*favorites.SavedSearch, error) {
+	search, err := s.visible(userID, id)
+	if err == nil && search.UserID != userID {
+		return nil, ErrSavedSearchForbidden
+	}
+	return search, err
+}
+
+// recordMatches records the pages that match search now, so that only
+// pages matching later count as new.
+func (s *savedSearches) recordMatches(search *favorites.SavedSearch) error {
+	pageIDs, err := s.pages.matchingPageIDs(search, nil)
+	if err != nil {
+		return err
+	}
+	return s.store.ReplaceMatches(search.ID, pageIDs)
+}
+
+func (s *savedSearches) response(search *favorites.SavedSearch, subscribed bool) SavedSearchResponse {
+	resp := SavedSearchResponse{
+		ID:         search.ID,
+		Name:       search.Name,
+		Query:      search.Query,
+		Tags:       search.Tags,
+		Shared:     search.Shared,
+		OwnerID:    search.UserID,
+		Subscribed: subscribed,
+		CreatedAt:  search.CreatedAt,
+		UpdatedAt:  search.UpdatedAt,
+	}
+	if resp.Tags == nil {
+		resp.Tags = []string{}
+	}
+	if s.pages.users != nil {
+		if label, err := s.pages.users.ResolveUserLabel(search.UserID); err == nil {
+			resp.Owner = label
+		}
+	}
+	return resp
+}
+
+func (s *savedSearches) isSubscribed(userID, searchID string) (bool, error) {
+	ids, err := s.store.SubscribedSearchIDs(userID)
+	return ids[searchID], err
+}
+
+// matchingPageIDs returns the pages among pageIDs (all pages when nil) that
+// search matches. Spelling corrections do not count, so that a page never
+// matches by accident.
+func (uc *SearchUseCase) matchingPageIDs(search *favorites.SavedSearch, pageIDs []string) ([]string, error) {
+	if uc.index == nil {
+		return nil, ErrSearchUnavailable
+	}
+	query, err := uc.parseQuery(search.Query)
+	if err != nil {
+		return nil, invalidQueryError(err)
+	}
+	scope, err := uc.resolveScope(query, search.Tags)
+	if err != nil {
+		return nil, err
+	}
+	if pageIDs != nil {
+		if scope.PageIDs == nil {
+			scope.PageIDs = pageIDs
+		} else {
+			scope.PageIDs = intersectPageIDs(scope.PageIDs, pageIDs)
+		}
+	}
+	return uc.index.ExactQueryPageIDs(query, scope)
+}
+
+// ─── CreateSavedSearchUseCase ────────────────────────────────────────────────
+
+type CreateSavedSearchInput struct {
+	UserID string
+	SavedSearchFields
+	Subscribe bool
+}
+
+type SavedSearchOutput struct {
+	Search SavedSearchResponse
+}
+
+type CreateSavedSearchUseCase struct {
+	searches *savedSearches
+}
+
+func NewCreateSavedSearchUseCase(store *favorites.FavoritesStore, pages *SearchUseCase) *CreateSavedSearchUseCase {
+	return &CreateSavedSearchUseCase{searches: &savedSearches{store: store, pages: pages}}
+}
+
+func (uc *CreateSavedSearchUseCase) Execute(_ context.Context, in CreateSavedSearchInput) (*SavedSearchOutput, error) {
+	if err := uc.searches.validate(&in.SavedSearchFields); err != nil {
+		return nil, err
+	}
+	search := &favorites.SavedSearch{
+		UserID: in.UserID,
+		Name:   in.Name,
+		Query:  in.Query,
+		Tags:   in.Tags,
+		Shared: in.Shared,
+	}
+	if err := uc.searches.store.CreateSavedSearch(search); err != nil {
+		return nil, err
+	}
+	if in.Subscribe {
+		if err := uc.searches.recordMatches(search); err != nil {
+			return nil, err
+		}
+		if err := uc.searches.store.Subscribe(search.ID, in.UserID); err != nil {
+			return nil, err
+		}
+	}
+	return &SavedSearchOutput{Search: uc.searches.response(search, in.Subscribe)}, nil
+}
+
+// ─── UpdateSavedSearchUseCase ────────────────────────────────────────────────
+
+type UpdateSavedSearchInput struct {
+	UserID string
+	ID     string
+	SavedSearchFields
+}
+
+type UpdateSavedSearchUseCase struct {
+	searches *savedSearches
+}
+
+func NewUpdateSavedSearchUseCase(store *favorites.FavoritesStore, pages *SearchUseCase) *UpdateSavedSearchUseCase {
+	return &UpdateSavedSearchUseCase{searches: &savedSearches{store: store, pages: pages}}
+}
+
+func (uc *UpdateSavedSearchUseCase) Execute(_ context.Context, in UpdateSavedSearchInput) (*SavedSearchOutput, error) {
+	search, err := uc.searches.owned(in.UserID, in.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.searches.validate(&in.SavedSearchFields); err != nil {
+		return nil, err
+	}
+	changed := search.Query != in.Query || strings.Join(search.Tags, ",") != strings.Join(in.Tags, ",")
+	search.Name, search.Query, search.Tags, search.Shared = in.Name, in.Query, in.Tags, in.Shared
+	if err := uc.searches.store.UpdateSavedSearch(search); err != nil {
+		if errors.Is(err, favorites.ErrSavedSearchNotFound) {
+			return nil, ErrSavedSearchNotFound
+		}
+		return nil, err
+	}
+
+	subscribers, err := uc.searches.store.Subscribers(search.ID)
+	if err != nil {
+		return nil, err
+	}
+	// The pages the old search matched say nothing about the new one.
+	if changed && len(subscribers) > 0 {
+		if err := uc.searches.recordMatches(search); err != nil {
+			return nil, err
+		}
+	}
+	subscribed := slices.Contains(subscribers, in.UserID)
+	return &SavedSearchOutput{Search: uc.searches.response(search, subscribed)}, nil
+}
+
+// ─── DeleteSavedSearchUseCase ────────────────────────────────────────────────
+
+type DeleteSavedSearchInput struct {
+	UserID string
+	ID     string
+}
+
+type DeleteSavedSearchUseCase struct {
+	searches *savedSearches
+}
+
+func NewDeleteSavedSearchUseCase(store *favorites.FavoritesStore) *DeleteSavedSearchUseCase {
+	return &DeleteSavedSearchUseCase{searches: &savedSearches{store: store}}
+}
+
+func (uc *DeleteSavedSearchUseCase) Execute(_ context.Context, in DeleteSavedSearchInput) error {
+	if _, err := uc.searches.owned(in.UserID, in.ID); err != nil {
+		return err
+	}
+	return uc.searches.store.DeleteSavedSearch(in.ID)
+}
+
+// ─── GetSavedSearchUseCase ───────────────────────────────────────────────────
+
+type GetSavedSearchInput struct {
+	UserID string
+	ID     string
+}
+
+type GetSavedSearchUseCase struct {
+	searches *savedSearches
+}
+
+func NewGetSavedSearchUseCase(store *favorites.FavoritesStore, pages *SearchUseCase) *GetSavedSearchUseCase {
+	return &GetSavedSearchUseCase{searches: &savedSearches{store: store, pages: pages}}
+}
+
+func (uc *GetSavedSearchUseCase) Execute(_ context.Context, in GetSavedSearchInput) (*SavedSearchOutput, error) {
+	search, err := uc.searches.visible(in.UserID, in.ID)
+	if err != nil {
+		return nil, err
+	}
+	subscribed, err := uc.searches.isSubscribed(in.UserID, search.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &SavedSearchOutput{Search: uc.searches.response(search, subscribed)}, nil
+}
+
+// ─── ListSavedSearchesUseCase ────────────────────────────────────────────────
+
+type ListSavedSearchesInput struct {
+	UserID string
+}
+
+type ListSavedSearchesOutput struct {
+	Searches []SavedSearchResponse
+}
+
+type ListSavedSearchesUseCase struct {
+	searches *savedSearches
+}
+
+func NewListSavedSearchesUseCase(store *favorites.FavoritesStore, pages *SearchUseCase) *ListSavedSearchesUseCase {
+	return &ListSavedSearchesUseCase{searches: &savedSearches{store: store, pages: pages}}
+}
+
+// Execute lists the user's saved searches and those shared by others.
+func (uc *ListSavedSearchesUseCase) Execute(_ context.Context, in ListSavedSearchesInput) (*ListSavedSearchesOutput, error) {
+	searches, err := uc.searches.store.ListSavedSearches(in.UserID)
+	if err != nil {
+		return nil, err
+	}
+	subscribed, err := uc.searches.store.SubscribedSearchIDs(in.UserID)
+	if err != nil {
+		return nil, err
+	}
+	out := &ListSavedSearchesOutput{Searches: make([]SavedSearchResponse, 0, len(searches))}
+	for _, search := range searches {
+		out.Searches = append(out.Searches, uc.searches.response(search, subscribed[search.ID]))
+	}
+	return out, nil
+}
+
+// ─── SetSavedSearchSubscriptionUseCase ───────────────────────────────────────
+
+type SetSavedSearchSubscriptionInput struct {
+	UserID     string
+	ID         string
+	Subscribed bool
+}
+
+// SetSavedSearchSubscriptionUseCase subscribes a user to one of their own
+// or a shared saved search, or unsubscribes them.
+type SetSavedSearchSubscriptionUseCase struct {
+	searches *savedSearches
+}
+
+func NewSetSavedSearchSubscriptionUseCase(store *favorites.FavoritesStore, pages *SearchUseCase) *SetSavedSearchSubscriptionUseCase {
+	return &SetSavedSearchSubscriptionUseCase{searches: &savedSearches{store: store, pages: pages}}
+}
+
+func (uc *SetSavedSearchSubscriptionUseCase) Execute(_ context.Context, in SetSavedSearchSubscriptionInput) (*SavedSearchOutput, error) {
+	search, err := uc.searches.visible(in.UserID, in.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !in.Subscribed {
+		if err := uc.searches.store.Unsubscribe(search.ID, in.UserID); err != nil {
+			return nil, err
+		}
+		return &SavedSearchOutput{Search: uc.searches.response(search, false)}, nil
+	}
+
+	subscribed, err := uc.searches.isSubscribed(in.UserID, search.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !subscribed {
+		if err := uc.searches.recordMatches(search); err != nil {
+			return nil, err
+		}
+		if err := uc.searches.store.Subscribe(search.ID, in.UserID); err != nil {
+			return nil, err
+		}
+	}
+	return &SavedSearchOutput{Search: uc.searches.response(search, true)}, nil
+}
+
+// ─── ListNotificationsUseCase ────────────────────────────────────────────────
+
+type ListNotificationsInput struct {
+	UserID     string
+	UnreadOnly bool
+	Limit      int
+}
+
+type ListNotificationsOutput struct {
+	Notifications []SearchNotificationResponse
+}
+
+type ListNotificationsUseCase struct {
+	store *favorites.FavoritesStore
+	pages *SearchUseCase
+}
+
+func NewListNotificationsUseCase(store *favorites.FavoritesStore, pages *SearchUseCase) *ListNotificationsUseCase {
+	return &ListNotificationsUseCase{store: store, pages: pages}
+}
+
+// Execute lists the user's notifications, newest first, leaving out those
+// about pages that no longer exist.
+func (uc *ListNotificationsUseCase) Execute(_ context.Context, in ListNotificationsInput) (*ListNotificationsOutput, error) {
+	if in.Limit < 1 || in.Limit > MaxNotificationLimit {
+		return nil, sharederrors.NewLocalizedError(ErrCodeSearchInvalidLimit, "Invalid limit value", "invalid limit value", nil)
+	}
+	notifications, err := uc.store.ListNotifications(in.UserID, in.UnreadOnly, in.Limit)
+	if err != nil {
+		return nil, err
+	}
+	out := &ListNotificationsOutput{Notifications: make([]SearchNotificationResponse, 0, len(notifications))}
+	for _, n := range notifications {
+		if uc.pages.tree == nil {
+			break
+		}
+		node, err := uc.pages.tree.FindPageByID(n.PageID)
+		if err != nil || node == nil {
+			continue
+		}
+		out.Notifications = append(out.Notifications, SearchNotificationResponse{
+			ID:         n.ID,
+			SearchID:   n.SearchID,
+			SearchName: n.SearchName,
+			PageID:     n.PageID,
+			Title:      node.Title,
+			Path:       dto.BuildPathFromNode(node),
+			CreatedAt:  n.CreatedAt,
+			Read:       n.ReadAt != nil,
+		})
+	}
+	return out, nil
+}
+
+// ─── MarkNotificationsReadUseCase ────────────────────────────────────────────
+
+type MarkNotificationsReadInput struct {
+	UserID string
+	// IDs are the notifications to mark; all when empty.
+	IDs []string
+}
+
+type MarkNotificationsReadUseCase struct {
+	store *favorites.FavoritesStore
+}
+
+func NewMarkNotificationsReadUseCase(store *favorites.FavoritesStore) *MarkNotificationsReadUseCase {
+	return &MarkNotificationsReadUseCase{store: store}
+}
+
+func (uc *MarkNotificationsReadUseCase) Execute(_ context.Context, in MarkNotificationsReadInput) error {
+	return uc.store.MarkNotificationsRead(in.UserID, in.IDs)
+}
+
+// ─── SavedSearchNotifier ─────────────────────────────────────────────────────
+
+// SavedSearchNotifier is a page save side effect that notifies the
+// subscribers of a saved search when a page starts to match it. It must run
+// after the search, tag and property indexes are updated. The user who made
+// the change is not notified about it.
+type SavedSearchNotifier struct {
+	searches *savedSearches
+	log      *slog.Logger
+	metrics  *httpmetrics.HTTPMetrics
+}
+
+func NewSavedSearchNotifier(store *favorites.FavoritesStore, pages *SearchUseCase, log *slog.Logger, metrics *httpmetrics.HTTPMetrics) *SavedSearchNotifier {
+	if log == nil {
+		log = slog.Default()
+	}
+	return &SavedSearchNotifier{searches: &savedSearches{store: store, pages: pages}, log: log, metrics: metrics}
+}
+
+func (n *SavedSearchNotifier) Name() string {
+	return "saved_searches"
+}
+
+func (n *SavedSearchNotifier) Apply(event pagesave.PageSaveEvent) {
+	// Deleted pages are dropped with the page's favorites.
+	if n.searches.store == nil || event.Operation == pagesave.PageOperationDelete {
+		return
+	}
+	pageIDs := make([]string, 0, len(event.AffectedPages)+1)
+	for _, page := range event.AffectedPages {
+		pageIDs = append(pageIDs, page.ID)
+	}
+	if len(pageIDs) == 0 && event.After != nil {
+		pageIDs = append(pageIDs, event.After.ID)
+	}
+	if len(pageIDs) == 0 {
+		return
+	}
+
+	subscribed, err := n.searches.store.ListSubscribedSearches()
+	if err != nil {
+		n.fail(event, "failed to list subscribed saved searches", err)
+		return
+	}
+	for _, s := range subscribed {
+		matching, err := n.searches.pages.matchingPageIDs(s.Search, pageIDs)
+		if err != nil {
+			n.fail(event, "failed to match pages against saved search", err, "searchID", s.Search.ID)
+			continue
+		}
+		added, err := n.searches.store.UpdateMatches(s.Search.ID, pageIDs, matching)
+		if err != nil {
+			n.fail(event, "failed to update saved search matches", err, "searchID", s.Search.ID)
+			continue
+		}
+		recipients := make([]string, 0, len(s.Subscribers))
+		for _, userID := range s.Subscribers {
+			if userID != event.UserID {
+				recipients = append(recipients, userID)
+			}
+		}
+		if len(recipients) == 0 {
+			continue
+		}
+		for _, pageID := range added {
+			if err := n.searches.store.AddNotifications(s.Search.ID, pageID, recipients); err != nil {
+				n.fail(event, "failed to add saved search notifications", err, "searchID", s.Search.ID, "pageID", pageID)
+			}
+		}
+	}
+}
+
+func (n *SavedSearchNotifier) fail(event pagesave.PageSaveEvent, msg string, err error, args ...any) {
+	n.log.Warn(msg, append(args, "error", err)...)
+	n.metrics.IncPageSaveSideEffectFailure(string(event.Operation), n.Name())
+}
@@ -2,13 +2,17 @@ package search
 
 import (
 	"context"
+	"errors"
 	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/perber/wiki/internal/core/auth"
 	sharederrors "github.com/perber/wiki/internal/core/shared/errors"
 	"github.com/perber/wiki/internal/core/shared/htmlutil"
 	"github.com/perber/wiki/internal/core/tree"
 	"github.com/perber/wiki/internal/http/dto"
+	coreprop "github.com/perber/wiki/internal/properties"
 	coresearch "github.com/perber/wiki/internal/search"
 	coretags "github.com/perber/wiki/internal/tags"
 )
@@ -36,11 +40,30 @@ type SearchOutput struct {
 type SearchUseCase struct {
 	index *coresearch.SQLiteIndex
 	tags  *coretags.TagsService
+	props *coreprop.PropertiesService
 	tree  *tree.TreeService
+	users *auth.UserResolver
 }
 
-func NewSearchUseCase(idx *coresearch.SQLiteIndex, tags *coretags.TagsService, tree *tree.TreeService) *SearchUseCase {
-	return &SearchUseCase{index: idx, tags: tags, tree: tree}
+func NewSearchUseCase(idx *coresearch.SQLiteIndex, tags *coretags.TagsService, props *coreprop.PropertiesService, tree *tree.TreeService, users *auth.UserResolver) *SearchUseCase {
+	return &SearchUseCase{index: idx, tags: tags, props: props, tree: tree, users: users}
+}
+
+// parseQuery parses a search query. A name: prefix filters by property only
+// when some page has that property; otherwise it is searched as text.
+func (uc *SearchUseCase) parseQuery(input string) (*coresearch.Query, error) {
+	return coresearch.ParseQueryWithOptions(input, coresearch.QueryOptions{IsPropertyKey: uc.isPropertyKey})
+}
+
+// isPropertyKey reports whether key names a property. When the properties
+// cannot be read, key is kept as a filter rather than silently widening the
+// search.
+func (uc *SearchUseCase) isPropertyKey(key string) bool {
+	if uc.props == nil {
+		return false
+	}
+	known, err := uc.props.HasPropertyKey(key)
+	return err != nil || known
 }
 
 func (uc *SearchUseCase) Execute(_ context.Context, in SearchInput) (*SearchOutput, error) {
@@ -48,28 +71,26 @@ func (uc *SearchUseCase) Execute(_ context.Context, in SearchInput) (*SearchOutp
 		return nil, ErrSearchUnavailable
 	}
 
-	var pageIDs []string
-	if len(in.Tags) > 0 {
-		pageIDs = []string{}
-		if uc.tags != nil {
-			var err error
-			pageIDs, err = uc.tags.GetPageIDsByTags(normalizeTags(in.Tags))
-			if err != nil {
-				return nil, err
-			}
-		}
+	query, err := uc.parseQuery(in.Query)
+	if err != nil {
+		return nil, invalidQueryError(err)
+	}
+
+	scope, err := uc.resolveScope(query, in.Tags)
+	if err != nil {
+		return nil, err
 	}
 
-	if strings.TrimSpace(in.Query) == "" && len(pageIDs) > 0 {
-		return uc.searchByTags(pageIDs, in.Offset, in.Limit)
+	if query.IsEmpty() && len(scope.PageIDs) > 0 {
+		return uc.searchByTags(scope.PageIDs, in.Offset, in.Limit)
 	}
 
-	result, err := uc.index.Search(in.Query, pageIDs, in.Offset, in.Limit)
+	result, err := uc.index.SearchQuery(query, scope, in.Offset, in.Limit)
 	if err != nil {
 		return nil, err
 	}
 
-	fullMatchPageIDs, err := uc.index.SearchPageIDs(in.Query, pageIDs)
+	fullMatchPageIDs, err := uc.index.SearchQueryPageIDs(query, scope)
 	if err != nil {
 		return nil, err
 	}
@@ -79,6 +100,164 @@ func (uc *SearchUseCase) Execute(_ context.Context, in SearchInput) (*SearchOutp
 	return &SearchOutput{Result: result}, nil
 }
 
+func invalidQueryError(err error) error {
+	var syntaxErr *coresearch.QuerySyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return err
+	}
+	return sharederrors.NewLocalizedError(
+		ErrCodeSearchInvalidQuery,
+		syntaxErr.Error(),
+		"invalid search query at position %s: %s",
+		err,
+		strconv.Itoa(syntaxErr.Pos), syntaxErr.Message,
+	)
+}
+
+// resolveScope turns the filters the search index cannot apply itself —
+// tags, properties, authors and update times — into the pages the search
+// is limited to or must leave out.
+func (uc *SearchUseCase) resolveScope(query *coresearch.Query, tags []string) (coresearch.SearchScope, error) {
+	scope, err := uc.resolveLabelScope(query, tags)
+	if err != nil {
+		return scope, err
+	}
+	pageFilters := append(query.FiltersOf(coresearch.FilterAuthor), query.FiltersOf(coresearch.FilterUpdated)...)
+	if len(pageFilters) > 0 {
+		scope.PageIDs = uc.filterPages(scope.PageIDs, pageFilters)
+	}
+	return scope, nil
+}
+
+// resolveLabelScope turns the tag and property filters into the pages the
+// search is limited to or must leave out.
+func (uc *SearchUseCase) resolveLabelScope(query *coresearch.Query, tags []string) (coresearch.SearchScope, error) {
+	var scope coresearch.SearchScope
+	restrict := func(pageIDs []string) {
+		if scope.PageIDs == nil {
+			scope.PageIDs = append([]string{}, pageIDs...)
+			return
+		}
+		scope.PageIDs = intersectPageIDs(scope.PageIDs, pageIDs)
+	}
+
+	required := append([]string{}, tags...)
+	var excluded []string
+	for _, f := range query.FiltersOf(coresearch.FilterTag) {
+		if f.Negated {
+			excluded = append(excluded, f.Value)
+		} else {
+			required = append(required, f.Value)
+		}
+	}
+	if required = normalizeTags(required); len(required) > 0 {
+		var pageIDs []string
+		if uc.tags != nil {
+			var err error
+			if pageIDs, err = uc.tags.GetPageIDsByTags(required); err != nil {
+				return scope, err
+			}
+		}
+		restrict(pageIDs)
+	}
+	for _, tag := range normalizeTags(excluded) {
+		if uc.tags == nil {
+			break
+		}
+		pageIDs, err := uc.tags.GetPageIDsByTags([]string{tag})
+		if err != nil {
+			return scope, err
+		}
+		scope.ExcludedPageIDs = append(scope.ExcludedPageIDs, pageIDs...)
+	}
+
+	for _, f := range query.FiltersOf(coresearch.FilterProperty) {
+		var pageIDs []string
+		if uc.props != nil {
+			var err error
+			if pageIDs, err = uc.props.GetPageIDsByProperty(f.Key, f.Value); err != nil {
+				return scope, err
+			}
+		}
+		if f.Negated {
+			scope.ExcludedPageIDs = append(scope.ExcludedPageIDs, pageIDs...)
+		} else {
+			restrict(pageIDs)
+		}
+	}
+	return scope, nil
+}
+
+// filterPages returns the pages among pageIDs (all pages when nil) whose
+// metadata matches every filter.
+func (uc *SearchUseCase) filterPages(pageIDs []string, filters []coresearch.QueryFilter) []string {
+	matched := []string{}
+	if uc.tree == nil {
+		return matched
+	}
+	if pageIDs == nil {
+		_ = uc.tree.WalkNodes(func(id string) error {
+			pageIDs = append(pageIDs, id)
+			return nil
+		})
+	}
+	for _, id := range pageIDs {
+		node, err := uc.tree.FindPageByID(id)
+		if err != nil || node == nil {
+			continue
+		}
+		if uc.matchesMetadata(node.Metadata, filters) {
+			matched = append(matched, id)
+		}
+	}
+	return matched
+}
+
+func (uc *SearchUseCase) matchesMetadata(meta tree.PageMetadata, filters []coresearch.QueryFilter) bool {
+	for _, f := range filters {
+		var ok bool
+		switch f.Field {
+		case coresearch.FilterAuthor:
+			ok = uc.isUser(meta.CreatorID, f.Value) || uc.isUser(meta.LastAuthorID, f.Value)
+		case coresearch.FilterUpdated:
+			ok = !meta.UpdatedAt.IsZero() && f.MatchesDay(meta.UpdatedAt)
+		}
+		if ok == f.Negated {
+			return false
+		}
+	}
+	return true
+}
+
+// isUser reports whether userID belongs to the user given by name or ID.
+func (uc *SearchUseCase) isUser(userID, user string) bool {
+	if userID == "" {
+		return false
+	}
+	if userID == user {
+		return true
+	}
+	if uc.users == nil {
+		return false
+	}
+	label, err := uc.users.ResolveUserLabel(userID)
+	return err == nil && label != nil && strings.EqualFold(label.Username, user)
+}
+
+func intersectPageIDs(a, b []string) []string {
+	inB := make(map[string]struct{}, len(b))
+	for _, id := range b {
+		inB[id] = struct{}{}
+	}
+	result := []string{}
+	for _, id := range a {
+		if _, ok := inB[id]; ok {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
 func (uc *SearchUseCase) searchByTags(pageIDs []string, offset, limit int) (*SearchOutput, error) {
 	if uc.tags == nil || uc.tree == nil {
 		return &SearchOutput{
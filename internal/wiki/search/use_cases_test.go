@@ -2,9 +2,13 @@ package search
 
 import (
 	"context"
+	"net/http"
+	"slices"
 	"testing"
 
+	sharederrors "github.com/perber/wiki/internal/core/shared/errors"
 	"github.com/perber/wiki/internal/core/tree"
+	coreprop "github.com/perber/wiki/internal/properties"
 	coresearch "github.com/perber/wiki/internal/search"
 	coretags "github.com/perber/wiki/internal/tags"
 	"github.com/perber/wiki/internal/test_utils"
@@ -33,7 +37,7 @@ func setupSearchUseCaseForTags(t *testing.T) (*SearchUseCase, *coretags.TagsServ
 	t.Cleanup(func() { test_utils.WrapCloseWithErrorCheck(tagsStore.Close, t) })
 
 	tagsSvc := coretags.NewTagsService(tagsStore)
-	return NewSearchUseCase(index, tagsSvc, treeSvc), tagsSvc, treeSvc
+	return NewSearchUseCase(index, tagsSvc, nil, treeSvc, nil), tagsSvc, treeSvc
 }
 
 func pageKind() *tree.NodeKind {
@@ -92,3 +96,153 @@ func TestSearchUseCase_Execute_TagOnlySearchEscapesTitle(t *testing.T) {
 		t.Fatalf("escaped title = %q", got)
 	}
 }
+
+func setupSearchUseCaseForQueries(t *testing.T) (*SearchUseCase, *tree.TreeService, func(title, slug, author, content string) string) {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	treeSvc := tree.NewTreeService(dir)
+	if err := treeSvc.LoadTree(); err != nil {
+		t.Fatalf("LoadTree: %v", err)
+	}
+
+	index, err := coresearch.NewSQLiteIndex(dir)
+	if err != nil {
+		t.Fatalf("NewSQLiteIndex: %v", err)
+	}
+	t.Cleanup(func() { test_utils.WrapCloseWithErrorCheck(index.Close, t) })
+
+	tagsStore, err := coretags.NewTagsStore(dir)
+	if err != nil {
+		t.Fatalf("NewTagsStore: %v", err)
+	}
+	t.Cleanup(func() { test_utils.WrapCloseWithErrorCheck(tagsStore.Close, t) })
+	tagsSvc := coretags.NewTagsService(tagsStore)
+
+	propsStore, err := coreprop.NewPropertiesStore(dir)
+	if err != nil {
+		t.Fatalf("NewPropertiesStore: %v", err)
+	}
+	t.Cleanup(func() { test_utils.WrapCloseWithErrorCheck(propsStore.Close, t) })
+	propsSvc := coreprop.NewPropertiesService(propsStore)
+
+	create := func(title, slug, author, content string) string {
+		t.Helper()
+		idPtr, err := treeSvc.CreateNode(author, nil, title, slug, pageKind())
+		if err != nil {
+			t.Fatalf("CreateNode %q: %v", slug, err)
+		}
+		if err := treeSvc.UpdateNode(author, *idPtr, title, slug, &content, tree.VersionUnchecked, nil, nil, true); err != nil {
+			t.Fatalf("UpdateNode %q: %v", slug, err)
+		}
+		page, err := treeSvc.GetPage(*idPtr)
+		if err != nil {
+			t.Fatalf("GetPage: %v", err)
+		}
+		if err := index.IndexPage(slug, slug+".md", page.ID, page.Title, page.Kind, page.RawContent); err != nil {
+			t.Fatalf("IndexPage: %v", err)
+		}
+		if err := tagsSvc.IndexPageContent(page.ID, page.RawContent); err != nil {
+			t.Fatalf("IndexPageContent tags: %v", err)
+		}
+		if err := propsSvc.IndexPageContent(page.ID, page.RawContent); err != nil {
+			t.Fatalf("IndexPageContent properties: %v", err)
+		}
+		return page.ID
+	}
+
+	return NewSearchUseCase(index, tagsSvc, propsSvc, treeSvc, nil), treeSvc, create
+}
+
+func TestSearchUseCase_Execute_AppliesQueryFilters(t *testing.T) {
+	uc, _, create := setupSearchUseCaseForQueries(t)
+
+	draft := create("Draft Runbook", "draft-runbook", "alice", "---\ntags:\n  - ops\nstatus: draft\n---\nRestart the service.")
+	published := create("Published Runbook", "published-runbook", "bob", "---\ntags:\n  - ops\n  - legacy\nstatus: published\n---\nRestart the service.")
+	notes := create("Notes", "notes", "alice", "---\nstatus: draft\n---\nRestart nothing.")
+
+	cases := []struct {
+		query string
+		tags  []string
+		want  []string
+	}{
+		{"restart tag:ops", nil, []string{draft, published}},
+		{"restart -tag:legacy", nil, []string{draft, notes}},
+		{"restart", []string{"ops"}, []string{draft, published}},
+		{"restart status:draft", nil, []string{draft, notes}},
+		{"restart -status:draft", nil, []string{published}},
+		{"tag:ops status:draft", nil, []string{draft}},
+		{"author:alice", nil, []string{draft, notes}},
+		{"restart -author:alice", nil, []string{published}},
+		{"updated:>2000-01-01 -tag:ops", nil, []string{notes}},
+		{"restart updated:<2000-01-01", nil, []string{}},
+		{"restart unknown:value", nil, []string{}},
+		{"restart: service", nil, []string{draft, published}},
+	}
+	for _, tc := range cases {
+		out, err := uc.Execute(context.Background(), SearchInput{Query: tc.query, Tags: tc.tags, Limit: 10})
+		if err != nil {
+			t.Fatalf("Execute(%q): %v", tc.query, err)
+		}
+		got := []string{}
+		for _, item := range out.Result.Items {
+			got = append(got, item.PageID)
+		}
+		slices.Sort(got)
+		want := slices.Sorted(slices.Values(tc.want))
+		if !slices.Equal(got, want) || out.Result.Count != len(want) {
+			t.Errorf("Execute(%q, tags=%v) = %v (count %d), want %v", tc.query, tc.tags, got, out.Result.Count, want)
+		}
+	}
+}
+
+func TestSearchUseCase_Execute_PropertyFilterNeedsAPageWithTheKey(t *testing.T) {
+	uc, _, create := setupSearchUseCaseForQueries(t)
+
+	search := func(query string) []string {
+		t.Helper()
+		out, err := uc.Execute(context.Background(), SearchInput{Query: query, Limit: 10})
+		if err != nil {
+			t.Fatalf("Execute(%q): %v", query, err)
+		}
+		got := []string{}
+		for _, item := range out.Result.Items {
+			got = append(got, item.PageID)
+		}
+		slices.Sort(got)
+		return got
+	}
+
+	checklist := create("Release Checklist", "release-checklist", "alice", "Keep the status: draft until the review.")
+
+	// No page has a status property yet, so the query is searched as text.
+	if got, want := search("status:draft"), []string{checklist}; !slices.Equal(got, want) {
+		t.Fatalf("before the first status property: %v, want %v", got, want)
+	}
+
+	runbook := create("Draft Runbook", "draft-runbook", "bob", "---\nstatus: draft\n---\nRestart the service.")
+
+	if got, want := search("status:draft"), []string{runbook}; !slices.Equal(got, want) {
+		t.Fatalf("after the first status property: %v, want %v", got, want)
+	}
+}
+
+func TestSearchUseCase_Execute_InvalidQueryReturnsPosition(t *testing.T) {
+	uc, _, _ := setupSearchUseCaseForQueries(t)
+
+	_, err := uc.Execute(context.Background(), SearchInput{Query: `restart tag:`, Limit: 10})
+	loc, ok := sharederrors.AsLocalizedError(err)
+	if !ok {
+		t.Fatalf("expected a localized error, got %v", err)
+	}
+	if loc.Code != ErrCodeSearchInvalidQuery {
+		t.Fatalf("code = %q, want %q", loc.Code, ErrCodeSearchInvalidQuery)
+	}
+	if want := []string{"12", "missing value after tag:"}; !slices.Equal(loc.Args, want) {
+		t.Fatalf("args = %v, want %v", loc.Args, want)
+	}
+	if got := searchErrorStatus(loc.Code); got != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", got, http.StatusBadRequest)
+	}
+}
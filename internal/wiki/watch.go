@@ -0,0 +1,157 @@
+package wiki
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/perber/wiki/internal/core/tree"
+	"github.com/perber/wiki/internal/fswatch"
+	"github.com/perber/wiki/internal/wiki/pagesave"
+)
+
+// externalEditSummary is the revision summary of content changed on disk.
+const externalEditSummary = "changed on disk"
+
+// startWatcher picks up edits made outside LeafWiki below root/ and assets/
+// until the wiki is closed.
+func (w *Wiki) startWatcher(options *WikiOptions) error {
+	rootDir := filepath.Join(w.storageDir, "root")
+	assetsDir := w.asset.GetAssetsDir()
+	for _, dir := range []string{rootDir, assetsDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create watched directory %s: %w", dir, err)
+		}
+	}
+	if err := w.tree.EnableChangeTracking(); err != nil {
+		return fmt.Errorf("failed to enable change tracking: %w", err)
+	}
+
+	w.reloadWG.Add(1)
+	go func() {
+		defer w.reloadWG.Done()
+		err := fswatch.Run(w.shutdownCtx, fswatch.Options{
+			Dirs:         []string{rootDir, assetsDir},
+			PollInterval: options.WatchPollInterval,
+			ForcePolling: options.WatchPolling,
+			Logger:       w.log,
+		}, w.applyFSChanges)
+		if err != nil {
+			w.log.Error("filesystem watcher stopped", "error", err)
+		}
+	}()
+	return nil
+}
+
+// applyFSChanges brings the tree and the indexes in line with the changed
+// paths. It holds reloadMu, so it never runs alongside a full resync.
+func (w *Wiki) applyFSChanges(paths []string) {
+	rootDir := filepath.Join(w.storageDir, "root")
+	assetsDir := w.asset.GetAssetsDir()
+
+	var pagePaths []string
+	assetPages := map[string]struct{}{}
+	for _, p := range paths {
+		if rel, ok := relativeTo(rootDir, p); ok {
+			pagePaths = append(pagePaths, rel)
+		} else if rel, ok := relativeTo(assetsDir, p); ok && rel != "" {
+			assetPages[strings.SplitN(rel, "/", 2)[0]] = struct{}{}
+		}
+	}
+
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	if len(pagePaths) > 0 {
+		changes, err := w.tree.SyncFromFS(w.shutdownCtx, pagePaths)
+		if err != nil {
+			w.log.Error("failed to apply filesystem changes", "paths", len(pagePaths), "error", err)
+			w.indexesInSync.Store(false)
+		}
+		w.applyTreeChanges(w.newPageOrchestrator(), changes)
+	}
+	if len(assetPages) > 0 {
+		w.log.Info("assets changed on disk", "pages", len(assetPages))
+		search := w.newSearchEffect()
+		for id := range assetPages {
+			page, err := w.tree.FindPageByID(id)
+			if err != nil {
+				continue
+			}
+			search.IndexPageAttachments(page)
+		}
+	}
+}
+
+// applyTreeChanges runs the page-save side effects of o for every node that
+// changed on disk, as if the change had been made through the API.
+func (w *Wiki) applyTreeChanges(o *pagesave.PageSaveOrchestrator, changes []tree.FSChange) {
+	if len(changes) == 0 {
+		return
+	}
+	for _, c := range changes {
+		if c.Removed {
+			before := &tree.Page{PageNode: c.Node}
+			o.Run(pagesave.PageSaveEvent{
+				Operation:     pagesave.PageOperationDelete,
+				UserID:        SYSTEM_USER_ID,
+				Before:        before,
+				OldPath:       c.OldPath,
+				AffectedPages: []*tree.Page{before},
+			})
+			continue
+		}
+
+		page, err := w.tree.GetPage(c.ID)
+		if err != nil {
+			w.log.Warn("failed to load page changed on disk", "pageID", c.ID, "error", err)
+			continue
+		}
+		if c.Added {
+			o.Run(pagesave.PageSaveEvent{
+				Operation:     pagesave.PageOperationCreate,
+				UserID:        SYSTEM_USER_ID,
+				After:         page,
+				AffectedPages: []*tree.Page{page},
+				Summary:       externalEditSummary,
+			})
+			continue
+		}
+		if c.Moved {
+			o.Run(pagesave.PageSaveEvent{
+				Operation:     pagesave.PageOperationMove,
+				UserID:        SYSTEM_USER_ID,
+				After:         page,
+				OldPath:       c.OldPath,
+				AffectedPages: []*tree.Page{page},
+			})
+		}
+		if c.ContentChanged {
+			o.Run(pagesave.PageSaveEvent{
+				Operation:      pagesave.PageOperationUpdate,
+				UserID:         SYSTEM_USER_ID,
+				After:          page,
+				ContentChanged: true,
+				TitleChanged:   page.Title != c.OldTitle,
+				OldTitle:       c.OldTitle,
+				AffectedPages:  []*tree.Page{page},
+				Summary:        externalEditSummary,
+			})
+		}
+	}
+	w.log.Info("applied filesystem changes", "nodes", len(changes))
+}
+
+// relativeTo returns p relative to dir in slash form ("" for dir itself),
+// or false when p is not inside dir.
+func relativeTo(dir, p string) (string, bool) {
+	rel, err := filepath.Rel(dir, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	if rel == "." {
+		return "", true
+	}
+	return filepath.ToSlash(rel), true
+}
@@ -0,0 +1,108 @@
+package wiki
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/perber/wiki/internal/test_utils"
+)
+
+func searchPageIDs(t *testing.T, w *Wiki, query string) []string {
+	t.Helper()
+	result, err := w.searchIndex.Search(query, nil, 0, 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	var ids []string
+	for _, item := range result.Items {
+		ids = append(ids, item.PageID)
+	}
+	return ids
+}
+
+func TestWiki_ApplyFSChanges_UpdatesIndexesForExternalEdits(t *testing.T) {
+	w := createWikiTestInstance(t)
+	defer test_utils.WrapCloseWithErrorCheck(w.Close, t)
+	page := createPageForTest(t, w, "system", nil, "Notes", "notes", pageNodeKind())
+	if err := w.tree.EnableChangeTracking(); err != nil {
+		t.Fatalf("EnableChangeTracking failed: %v", err)
+	}
+	oldVersion := page.Version()
+
+	rootDir := filepath.Join(w.storageDir, "root")
+	notesPath := filepath.Join(rootDir, "notes.md")
+	content := "---\nleafwiki_id: " + page.ID + "\nleafwiki_title: Notes\ntags: [external]\n---\n\nThe word zanzibar was typed in an editor.\n"
+	if err := os.WriteFile(notesPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write notes: %v", err)
+	}
+	addedPath := filepath.Join(rootDir, "added.md")
+	if err := os.WriteFile(addedPath, []byte("# Added\n\nA quokka appeared.\n"), 0o644); err != nil {
+		t.Fatalf("write added: %v", err)
+	}
+
+	w.applyFSChanges([]string{notesPath, addedPath})
+
+	if ids := searchPageIDs(t, w, "zanzibar"); !slices.Contains(ids, page.ID) {
+		t.Fatalf("expected the edited page to be found, got %v", ids)
+	}
+	if ids, err := w.tags.GetPageIDsByTags([]string{"external"}); err != nil || !slices.Contains(ids, page.ID) {
+		t.Fatalf("expected the edited page to be tagged, got %v (%v)", ids, err)
+	}
+	updated, err := w.tree.GetPage(page.ID)
+	if err != nil {
+		t.Fatalf("GetPage failed: %v", err)
+	}
+	if updated.Version() == oldVersion {
+		t.Fatalf("expected a new version after the external edit")
+	}
+	if ids := searchPageIDs(t, w, "quokka"); len(ids) != 1 {
+		t.Fatalf("expected the added page to be found, got %v", ids)
+	}
+
+	if err := os.Remove(notesPath); err != nil {
+		t.Fatalf("remove notes: %v", err)
+	}
+	w.applyFSChanges([]string{notesPath})
+
+	if ids := searchPageIDs(t, w, "zanzibar"); slices.Contains(ids, page.ID) {
+		t.Fatalf("expected the removed page to leave the index, got %v", ids)
+	}
+	if _, err := w.tree.GetPage(page.ID); err == nil {
+		t.Fatalf("expected the removed page to be gone from the tree")
+	}
+}
+
+func TestWiki_WatchFilesystem_PicksUpNewPages(t *testing.T) {
+	w, err := NewWiki(&WikiOptions{
+		StorageDir:          t.TempDir(),
+		AdminPassword:       "adminpassword",
+		JWTSecret:           "secretkey",
+		AccessTokenTimeout:  15 * time.Minute,
+		RefreshTokenTimeout: 7 * 24 * time.Hour,
+		WatchFilesystem:     true,
+		WatchPolling:        true,
+		WatchPollInterval:   20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create wiki instance: %v", err)
+	}
+	defer test_utils.WrapCloseWithErrorCheck(w.Close, t)
+
+	// Give the watcher time to take its first snapshot.
+	time.Sleep(200 * time.Millisecond)
+	p := filepath.Join(w.storageDir, "root", "watched.md")
+	if err := os.WriteFile(p, []byte("# Watched\n\nA wombat was here.\n"), 0o644); err != nil {
+		t.Fatalf("write watched: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(searchPageIDs(t, w, "wombat")) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for the watcher to index the new page")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/perber/wiki/internal/branding"
@@ -21,6 +23,7 @@ import (
 	coreimporter "github.com/perber/wiki/internal/importer"
 	"github.com/perber/wiki/internal/links"
 	"github.com/perber/wiki/internal/properties"
+	"github.com/perber/wiki/internal/related"
 	"github.com/perber/wiki/internal/search"
 	"github.com/perber/wiki/internal/tags"
 	wikiapikeys "github.com/perber/wiki/internal/wiki/apikeys"
@@ -28,12 +31,14 @@ import (
 	wikiauth "github.com/perber/wiki/internal/wiki/auth"
 	wikibackup "github.com/perber/wiki/internal/wiki/backup"
 	wikibranding "github.com/perber/wiki/internal/wiki/branding"
+	wikiexport "github.com/perber/wiki/internal/wiki/export"
 	wikihealth "github.com/perber/wiki/internal/wiki/health"
 	wikiimporter "github.com/perber/wiki/internal/wiki/importer"
 	wikilinks "github.com/perber/wiki/internal/wiki/links"
 	wikipages "github.com/perber/wiki/internal/wiki/pages"
 	"github.com/perber/wiki/internal/wiki/pagesave"
 	wikiproperties "github.com/perber/wiki/internal/wiki/properties"
+	wikirelated "github.com/perber/wiki/internal/wiki/related"
 	wikirestore "github.com/perber/wiki/internal/wiki/restore"
 	wikiresync "github.com/perber/wiki/internal/wiki/resync"
 	wikirevisions "github.com/perber/wiki/internal/wiki/revisions"
@@ -57,6 +62,10 @@ type Wiki struct {
 	searchIndex       *search.SQLiteIndex
 	status            *search.IndexingStatus
 	storageDir        string
+	// searchAttachments selects the attachments indexed for search.
+	searchAttachments search.AttachmentOptions
+	// historyIndex is nil unless history search is enabled.
+	historyIndex *search.HistoryIndex
 
 	// Domain route registrars (populated by NewWiki).
 	pagesRoutes      *wikipages.Routes
@@ -67,14 +76,17 @@ type Wiki struct {
 	linksRoutes      *wikilinks.Routes
 	tagsRoutes       *wikitags.Routes
 	propertiesRoutes *wikiproperties.Routes
+	relatedRoutes    *wikirelated.Routes
 	brandingRoutes   *wikibranding.Routes
 	apiKeysRoutes    *wikiapikeys.Routes
 	importerRoutes   *wikiimporter.Routes
+	exportRoutes     *wikiexport.Routes
 	healthRoutes     *wikihealth.Routes
 	revision         *revision.Service
 	links            *links.LinkService
 	tags             *tags.TagsService
 	props            *properties.PropertiesService
+	related          *related.RelatedService
 	favorites        *favorites.FavoritesStore
 	backupRoutes     *wikibackup.Routes
 	snapshotRoutes   *wikisnapshot.Routes
@@ -88,6 +100,15 @@ type Wiki struct {
 	shutdownCancel   context.CancelFunc
 	log              *slog.Logger
 	metrics          *httpmetrics.HTTPMetrics
+
+	// startupChanges lists what changed on disk since the last clean
+	// shutdown; incrementalStartup is false when every index is rebuilt.
+	startupChanges     []tree.FSChange
+	incrementalStartup bool
+	// indexesInSync reports that the indexes match the tree, so that Close
+	// may write the manifest for the next incremental startup.
+	indexesInSync        atomic.Bool
+	linksBootstrapFailed bool
 }
 
 const SYSTEM_USER_ID = "system"
@@ -108,7 +129,21 @@ type WikiOptions struct {
 	RevisionCoalesceWindow  time.Duration // Window for coalescing rapid successive saves; 0 = disabled
 	TOTPEncryptionKey       string        // Key used to encrypt per-user TOTP secrets at rest; empty disables TOTP self-service
 	SMTP                    email.Config  // SMTP config for password-reset/invite email; SMTP.Enabled()==false disables the feature entirely
+	WatchFilesystem         bool          // Whether edits made outside LeafWiki below root/ and assets/ are applied automatically
+	WatchPolling            bool          // Detect those edits by scanning instead of native file notifications
+	WatchPollInterval       time.Duration // Scan interval when polling; 0 = default
+	RebuildIndex            bool          // Rebuild the tree and all indexes from scratch instead of only what changed since the last clean shutdown
 	Metrics                 *httpmetrics.HTTPMetrics
+	// SearchAnalyzer sets the stemming language and stopwords of the search
+	// index; when it changes, all pages are reindexed at startup.
+	SearchAnalyzer search.Analyzer
+	// SearchAttachments sets the size limit of indexed attachments and
+	// whether PDFs are indexed; the zero value indexes text attachments up
+	// to search.DefaultAttachmentMaxBytes.
+	SearchAttachments search.AttachmentOptions
+	// SearchHistory keeps a search index of all stored revisions; it has
+	// no effect unless EnableRevision is set.
+	SearchHistory bool
 }
 
 func NewWiki(options *WikiOptions) (*Wiki, error) {
@@ -142,8 +177,10 @@ func NewWiki(options *WikiOptions) (*Wiki, error) {
 	if err := w.initFavoritesService(); err != nil {
 		return nil, err
 	}
-	w.bootstrapTagsAndProperties()
-	if err := w.initSearch(); err != nil {
+	if !w.incrementalStartup {
+		w.bootstrapTagsAndProperties()
+	}
+	if err := w.initSearch(options); err != nil {
 		return nil, err
 	}
 	if err := w.initBranding(); err != nil {
@@ -159,7 +196,17 @@ func NewWiki(options *WikiOptions) (*Wiki, error) {
 				MaxRevisions:   options.MaxRevisionHistory,
 				CoalesceWindow: options.RevisionCoalesceWindow,
 			})
+		if err := w.initSearchHistory(options); err != nil {
+			return nil, err
+		}
 		w.ensureBaselineRevisions()
+	} else {
+		search.RemoveHistoryIndex(w.storageDir)
+	}
+	if options.WatchFilesystem {
+		if err := w.startWatcher(options); err != nil {
+			return nil, err
+		}
 	}
 	w.buildRoutes(options)
 	return w, nil
@@ -285,8 +332,17 @@ func (w *Wiki) initCoreServices(options *WikiOptions) error {
 
 	w.tree = tree.NewTreeService(w.storageDir)
 	w.tree.SetIgnoreCache(w.ignoreCache)
-	if err := w.tree.LoadTree(); err != nil {
-		return err
+	if options.RebuildIndex || !w.indexDatabasesExist() {
+		if err := w.tree.LoadTree(); err != nil {
+			return err
+		}
+	} else {
+		changes, full, err := w.tree.LoadTreeFromManifest()
+		if err != nil {
+			return err
+		}
+		w.startupChanges = changes
+		w.incrementalStartup = !full
 	}
 	w.slug = tree.NewSlugService()
 	w.asset = assets.NewAssetService(w.storageDir, w.slug)
@@ -301,8 +357,12 @@ func (w *Wiki) initLinkService() error {
 		return fmt.Errorf("failed to init links store: %w", err)
 	}
 	w.links = links.NewLinkService(w.storageDir, w.tree, linksStore)
+	if w.incrementalStartup {
+		return nil
+	}
 	if err := w.links.IndexAllPages(); err != nil {
 		w.log.Warn("failed to index links on startup", "error", err)
+		w.linksBootstrapFailed = true
 	}
 	return nil
 }
@@ -368,14 +428,23 @@ func (w *Wiki) bootstrapTagsAndProperties() {
 	}
 }
 
-func (w *Wiki) initSearch() error {
+func (w *Wiki) initSearch(options *WikiOptions) error {
 	var err error
 	w.searchIndex, err = search.NewSQLiteIndex(w.storageDir)
 	if err != nil {
 		return fmt.Errorf("failed to init search index: %w", err)
 	}
+	if err := w.searchIndex.SetAnalyzer(options.SearchAnalyzer); err != nil {
+		return fmt.Errorf("failed to set search analyzer: %w", err)
+	}
+	w.searchAttachments = options.SearchAttachments
+	w.related = related.NewRelatedService(w.tags, w.props, w.links, w.searchIndex)
 	w.status = search.NewIndexingStatus()
-	searchEffect := pagesave.NewSearchIndexSideEffect(w.searchIndex, w.tree, w.log, w.metrics)
+	if w.incrementalStartup {
+		w.startIncrementalIndexing()
+		return nil
+	}
+	searchEffect := w.newSearchEffect()
 	w.log.Info("search indexing started")
 	w.reloadWG.Add(1)
 	go func() {
@@ -388,11 +457,119 @@ func (w *Wiki) initSearch() error {
 		} else {
 			w.log.Info("search indexing completed")
 			w.status.Success()
+			w.indexesInSync.Store(!w.linksBootstrapFailed)
+			w.related.Invalidate()
 		}
 	}()
 	return nil
 }
 
+// initSearchHistory opens the history index and registers it with the
+// revision service. A new or rebuilt index is filled with all stored
+// revisions in the background. When history search is disabled, the index
+// is deleted so that it is filled from scratch once enabled again.
+func (w *Wiki) initSearchHistory(options *WikiOptions) error {
+	if !options.SearchHistory {
+		search.RemoveHistoryIndex(w.storageDir)
+		return nil
+	}
+	var err error
+	w.historyIndex, err = search.NewHistoryIndex(w.storageDir)
+	if err != nil {
+		return fmt.Errorf("failed to init search history index: %w", err)
+	}
+	indexer := wikisearch.NewHistoryIndexer(w.historyIndex, w.log)
+	w.revision.SetObserver(indexer)
+	if !w.historyIndex.Recreated() && !options.RebuildIndex {
+		return nil
+	}
+	if err := w.historyIndex.Clear(); err != nil {
+		return fmt.Errorf("failed to clear search history index: %w", err)
+	}
+	w.log.Info("search history indexing started")
+	w.reloadWG.Add(1)
+	go func() {
+		defer w.reloadWG.Done()
+		if err := indexer.IndexAll(w.shutdownCtx, w.revision); err != nil {
+			w.log.Warn("search history bootstrap failed", "error", err)
+			return
+		}
+		w.log.Info("search history indexing completed")
+	}()
+	return nil
+}
+
+// indexDatabaseFiles are the index databases kept in sync with the tree. An
+// incremental startup relies on all of them surviving from the last run.
+var indexDatabaseFiles = []string{"search.db", "links.db", "tags.db", "properties.db"}
+
+func (w *Wiki) indexDatabasesExist() bool {
+	for _, name := range indexDatabaseFiles {
+		if _, err := os.Stat(filepath.Join(w.storageDir, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// startIncrementalIndexing brings the indexes in line with the pages that
+// changed on disk since the last clean shutdown. The search status only
+// reports indexing while this runs. A search index that had to be recreated
+// is filled from scratch; one analyzed with other settings is reindexed in
+// place, reporting its progress through the search status.
+func (w *Wiki) startIncrementalIndexing() {
+	changes := w.startupChanges
+	w.startupChanges = nil
+	fullSearch := w.searchIndex.Recreated()
+	reanalyze := !fullSearch && w.searchIndex.AnalyzerOutdated()
+	w.log.Info("incremental indexing started", "changes", len(changes), "fullSearch", fullSearch, "reanalyze", reanalyze)
+	w.reloadWG.Add(1)
+	go func() {
+		defer w.reloadWG.Done()
+		w.status.Start()
+		defer w.status.Finish()
+
+		searchEffect := w.newSearchEffect()
+		// Revisions of pages edited while LeafWiki was stopped are recorded
+		// by ensureBaselineRevisions, not here.
+		effects := []pagesave.PageSideEffect{
+			pagesave.NewLinkIndexSideEffect(w.links, w.log, w.metrics),
+			pagesave.NewTagsSideEffect(w.tags, w.log, w.metrics),
+			pagesave.NewPropertiesSideEffect(w.props, w.log, w.metrics),
+		}
+		if !fullSearch {
+			effects = append(effects, searchEffect)
+		}
+		effects = append(effects, pagesave.NewRelatedSideEffect(w.related))
+		// A rebuilt search index only matches the changes afterwards.
+		if !fullSearch {
+			effects = append(effects, w.newSavedSearchNotifier())
+		}
+		w.applyTreeChanges(pagesave.NewPageSaveOrchestrator(w.metrics, effects...), changes)
+		switch {
+		case fullSearch:
+			if err := searchEffect.IndexAllPagesContext(w.shutdownCtx); err != nil {
+				w.log.Warn("search bootstrap failed", "error", err)
+				w.status.Fail()
+				return
+			}
+			w.status.Success()
+		case reanalyze:
+			w.log.Info("search analyzer changed, reindexing all pages")
+			if err := searchEffect.ReindexAllPagesContext(w.shutdownCtx, w.status.Progress); err != nil {
+				w.log.Warn("search reindex failed", "error", err)
+				w.status.Fail()
+				return
+			}
+		default:
+			w.status.Success()
+		}
+		w.log.Info("incremental indexing completed")
+		w.indexesInSync.Store(true)
+		w.related.Invalidate()
+	}()
+}
+
 func (w *Wiki) initBranding() error {
 	var err error
 	w.branding, err = branding.NewBrandingService(w.storageDir)
@@ -411,9 +588,14 @@ func (w *Wiki) buildRoutes(options *WikiOptions) {
 	w.linksRoutes = w.buildLinksRoutes()
 	w.tagsRoutes = w.buildTagsRoutes()
 	w.propertiesRoutes = w.buildPropertiesRoutes()
+	w.relatedRoutes = wikirelated.NewRoutes(wikirelated.RoutesConfig{
+		GetRelatedPages: wikirelated.NewGetRelatedPagesUseCase(w.related, w.tree),
+		AuthService:     w.auth,
+	})
 	w.brandingRoutes = w.buildBrandingRoutes()
 	w.apiKeysRoutes = w.buildAPIKeysRoutes()
 	w.importerRoutes = w.buildImporterRoutes(options)
+	w.exportRoutes = w.buildExportRoutes()
 	w.healthRoutes = wikihealth.NewRoutes(wikihealth.RoutesConfig{
 		Index:      w.searchIndex,
 		Status:     w.status,
@@ -428,17 +610,36 @@ func (w *Wiki) buildRoutes(options *WikiOptions) {
 
 // ─── Domain route builder helpers ────────────────────────────────────────────
 
+// newSearchEffect returns the search side effect, indexing attachments too.
+func (w *Wiki) newSearchEffect() *pagesave.SearchIndexSideEffect {
+	e := pagesave.NewSearchIndexSideEffect(w.searchIndex, w.tree, w.log, w.metrics)
+	e.EnableAttachments(w.asset, w.searchAttachments)
+	return e
+}
+
 func (w *Wiki) newPageOrchestrator() *pagesave.PageSaveOrchestrator {
 	return pagesave.NewPageSaveOrchestrator(
 		w.metrics,
-		pagesave.NewSearchIndexSideEffect(w.searchIndex, w.tree, w.log, w.metrics),
+		w.newSearchEffect(),
 		pagesave.NewLinkIndexSideEffect(w.links, w.log, w.metrics),
 		pagesave.NewRevisionSideEffect(w.revision, w.log, w.metrics),
 		pagesave.NewTagsSideEffect(w.tags, w.log, w.metrics),
 		pagesave.NewPropertiesSideEffect(w.props, w.log, w.metrics),
+		pagesave.NewRelatedSideEffect(w.related),
+		w.newSavedSearchNotifier(),
 	)
 }
 
+func (w *Wiki) newSearchUseCase() *wikisearch.SearchUseCase {
+	return wikisearch.NewSearchUseCase(w.searchIndex, w.tags, w.props, w.tree, w.userResolver)
+}
+
+// newSavedSearchNotifier returns the side effect that notifies the
+// subscribers of saved searches; it runs after the indexes are updated.
+func (w *Wiki) newSavedSearchNotifier() *wikisearch.SavedSearchNotifier {
+	return wikisearch.NewSavedSearchNotifier(w.favorites, w.newSearchUseCase(), w.log, w.metrics)
+}
+
 func (w *Wiki) buildPagesRoutes() *wikipages.Routes {
 	o := w.newPageOrchestrator()
 	return wikipages.NewRoutes(wikipages.RoutesConfig{
@@ -496,10 +697,10 @@ func (w *Wiki) buildAuthRoutes() *wikiauth.Routes {
 
 func (w *Wiki) buildAssetsRoutes() *wikiassets.Routes {
 	return wikiassets.NewRoutes(wikiassets.RoutesConfig{
-		Upload:      wikiassets.NewUploadAssetUseCase(w.tree, w.asset, w.revision, w.log),
+		Upload:      wikiassets.NewUploadAssetUseCase(w.tree, w.asset, w.revision, w.newSearchEffect(), w.log),
 		List:        wikiassets.NewListAssetsUseCase(w.tree, w.asset),
-		Rename:      wikiassets.NewRenameAssetUseCase(w.tree, w.asset, w.revision, w.log),
-		Delete:      wikiassets.NewDeleteAssetUseCase(w.tree, w.asset, w.revision, w.log),
+		Rename:      wikiassets.NewRenameAssetUseCase(w.tree, w.asset, w.revision, w.newSearchEffect(), w.log),
+		Delete:      wikiassets.NewDeleteAssetUseCase(w.tree, w.asset, w.revision, w.newSearchEffect(), w.log),
 		AuthService: w.auth,
 		AssetsDir:   w.asset.GetAssetsDir(),
 		Log:         w.log,
@@ -521,16 +722,36 @@ func (w *Wiki) buildRevisionsRoutes() *wikirevisions.Routes {
 }
 
 func (w *Wiki) buildSearchRoutes() *wikisearch.Routes {
+	pages := w.newSearchUseCase()
 	return wikisearch.NewRoutes(wikisearch.RoutesConfig{
-		Search:            wikisearch.NewSearchUseCase(w.searchIndex, w.tags, w.tree),
-		GetIndexingStatus: wikisearch.NewGetIndexingStatusUseCase(w.status),
-		AuthService:       w.auth,
+		Search:                     pages,
+		SearchHistory:              w.buildSearchHistory(),
+		GetIndexingStatus:          wikisearch.NewGetIndexingStatusUseCase(w.status),
+		SuggestTitles:              wikisearch.NewSuggestTitlesUseCase(w.tree),
+		GetOpenSearchDescription:   wikisearch.NewGetOpenSearchDescriptionUseCase(w.branding),
+		CreateSavedSearch:          wikisearch.NewCreateSavedSearchUseCase(w.favorites, pages),
+		UpdateSavedSearch:          wikisearch.NewUpdateSavedSearchUseCase(w.favorites, pages),
+		DeleteSavedSearch:          wikisearch.NewDeleteSavedSearchUseCase(w.favorites),
+		GetSavedSearch:             wikisearch.NewGetSavedSearchUseCase(w.favorites, pages),
+		ListSavedSearches:          wikisearch.NewListSavedSearchesUseCase(w.favorites, pages),
+		SetSavedSearchSubscription: wikisearch.NewSetSavedSearchSubscriptionUseCase(w.favorites, pages),
+		ListNotifications:          wikisearch.NewListNotificationsUseCase(w.favorites, pages),
+		MarkNotificationsRead:      wikisearch.NewMarkNotificationsReadUseCase(w.favorites),
+		AuthService:                w.auth,
 	})
 }
 
+func (w *Wiki) buildSearchHistory() *wikisearch.SearchHistoryUseCase {
+	if w.historyIndex == nil {
+		return nil
+	}
+	return wikisearch.NewSearchHistoryUseCase(w.historyIndex, w.tags, w.props, w.tree, w.userResolver)
+}
+
 func (w *Wiki) buildLinksRoutes() *wikilinks.Routes {
 	return wikilinks.NewRoutes(wikilinks.RoutesConfig{
 		GetLinkStatus: wikilinks.NewGetLinkStatusUseCase(w.links, w.tree),
+		GetLinkGraph:  wikilinks.NewGetLinkGraphUseCase(w.links, w.tree, w.tags, w.props),
 		AuthService:   w.auth,
 	})
 }
@@ -579,12 +800,14 @@ func (w *Wiki) buildImporterRoutes(options *WikiOptions) *wikiimporter.Routes {
 	adapter := NewWikiImportAdapter(w)
 	planner := coreimporter.NewPlanner(adapter, w.slug, options.StorageDir)
 	planner.SetIgnoreCache(w.ignoreCache)
+	planner.SetSourceMappings(coreimporter.NewSourceMappingStore(filepath.Join(importerDir, "sources")))
 
 	store := coreimporter.NewPlanStore(filepath.Join(importerDir, "current-plan.json"))
 	svc := coreimporter.NewImporterService(planner, store, filepath.Join(importerDir, "workspaces"), options.MaxAssetUploadSizeBytes)
 	return wikiimporter.NewRoutes(wikiimporter.RoutesConfig{
 		CreatePlan:  wikiimporter.NewCreateImportPlanUseCase(svc),
 		GetPlan:     wikiimporter.NewGetImportPlanUseCase(svc),
+		EditPlan:    wikiimporter.NewEditImportPlanUseCase(svc),
 		Execute:     wikiimporter.NewExecuteImportUseCase(svc),
 		ClearPlan:   wikiimporter.NewClearImportPlanUseCase(svc),
 		AuthService: w.auth,
@@ -593,6 +816,16 @@ func (w *Wiki) buildImporterRoutes(options *WikiOptions) *wikiimporter.Routes {
 	})
 }
 
+func (w *Wiki) buildExportRoutes() *wikiexport.Routes {
+	return wikiexport.NewRoutes(wikiexport.RoutesConfig{
+		ExportSite:     wikiexport.NewExportSiteUseCase(w.tree, w.branding, w.asset.GetAssetsDir()),
+		ExportMarkdown: wikiexport.NewExportMarkdownUseCase(w.tree, w.asset.GetAssetsDir()),
+		ExportBook:     wikiexport.NewExportBookUseCase(w.tree, w.asset.GetAssetsDir()),
+		AuthService:    w.auth,
+		Log:            w.log,
+	})
+}
+
 // ─── Registrars / FrontendConfig ─────────────────────────────────────────────
 
 // Registrars returns all domain route registrars in registration order.
@@ -606,9 +839,11 @@ func (w *Wiki) Registrars() []httpinternal.RouteRegistrar {
 		w.linksRoutes,
 		w.tagsRoutes,
 		w.propertiesRoutes,
+		w.relatedRoutes,
 		w.brandingRoutes,
 		w.apiKeysRoutes,
 		w.importerRoutes,
+		w.exportRoutes,
 		w.healthRoutes,
 		w.resyncRoutes,
 	}
@@ -759,6 +994,7 @@ func (w *Wiki) ReloadFromFSContext(ctx context.Context) error {
 	defer w.reloadMu.Unlock()
 
 	w.log.Info("filesystem reload started")
+	w.indexesInSync.Store(false)
 
 	if err := w.tree.ReconstructTreeFromFSContext(ctx); err != nil {
 		return fmt.Errorf("tree reconstruction failed: %w", err)
@@ -780,7 +1016,7 @@ func (w *Wiki) ReloadFromFSContext(ctx context.Context) error {
 
 	w.status.Start()
 	defer w.status.Finish()
-	searchEffect := pagesave.NewSearchIndexSideEffect(w.searchIndex, w.tree, w.log, w.metrics)
+	searchEffect := w.newSearchEffect()
 	if err := searchEffect.IndexAllPagesContext(ctx); err != nil {
 		w.log.Warn("search re-index failed during reload", "error", err)
 		w.status.Fail()
@@ -789,6 +1025,8 @@ func (w *Wiki) ReloadFromFSContext(ctx context.Context) error {
 
 	w.status.Success()
 	w.log.Info("filesystem reload completed")
+	w.indexesInSync.Store(true)
+	w.related.Invalidate()
 	return nil
 }
 
@@ -826,6 +1064,7 @@ func (w *Wiki) reloadWithProgress(ctx context.Context) {
 	defer w.reloadMu.Unlock()
 
 	w.log.Info("filesystem reload started (async)")
+	w.indexesInSync.Store(false)
 
 	job.SetPhase(wikiresync.PhaseTree)
 	if err := w.tree.ReconstructTreeFromFSContext(ctx); err != nil {
@@ -861,7 +1100,7 @@ func (w *Wiki) reloadWithProgress(ctx context.Context) {
 
 	job.SetPhase(wikiresync.PhaseSearch)
 	w.status.Start()
-	searchEffect := pagesave.NewSearchIndexSideEffect(w.searchIndex, w.tree, w.log, w.metrics)
+	searchEffect := w.newSearchEffect()
 	if err := searchEffect.IndexAllPagesContext(ctx); err != nil {
 		w.log.Warn("search re-index failed during reload", "error", err)
 		w.status.Fail()
@@ -874,6 +1113,8 @@ func (w *Wiki) reloadWithProgress(ctx context.Context) {
 	w.status.Finish()
 
 	w.log.Info("filesystem reload completed (async)")
+	w.indexesInSync.Store(true)
+	w.related.Invalidate()
 	finishErr = nil
 	job.Finish(nil)
 }
@@ -929,6 +1170,11 @@ func (w *Wiki) EmailTokenService() *auth.EmailTokenService {
 func (w *Wiki) Close() error {
 	w.shutdownCancel() // signal in-flight reloads to abort
 	w.reloadWG.Wait()  // drain goroutines before closing stores
+	if w.indexesInSync.Load() {
+		if err := w.tree.SaveManifest(); err != nil {
+			w.log.Warn("failed to save manifest, the next start rebuilds all indexes", "error", err)
+		}
+	}
 	w.status.Finish()
 	if w.auth != nil {
 		// When auth is enabled, AuthService owns both the session store and user store.
@@ -962,5 +1208,11 @@ func (w *Wiki) Close() error {
 		}
 	}
 
+	if w.historyIndex != nil {
+		if err := w.historyIndex.Close(); err != nil {
+			w.log.Error("error closing search history index", "error", err)
+		}
+	}
+
 	return w.searchIndex.Close()
 }
@@ -6,14 +6,17 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/perber/wiki/internal/core/tree"
 	httpmetrics "github.com/perber/wiki/internal/http/metrics"
+	"github.com/perber/wiki/internal/search"
 	"github.com/perber/wiki/internal/test_utils"
 	wikipages "github.com/perber/wiki/internal/wiki/pages"
+	wikisearch "github.com/perber/wiki/internal/wiki/search"
 )
 
 func createWikiTestInstance(t *testing.T) *Wiki {
@@ -503,3 +506,292 @@ func TestWiki_ResyncRespectsLeafwikiignore(t *testing.T) {
 		t.Fatal("expected ignored page 'secret' to not exist")
 	}
 }
+
+func newWikiForStorage(t *testing.T, storageDir string, rebuildIndex bool) *Wiki {
+	t.Helper()
+	w, err := NewWiki(&WikiOptions{
+		StorageDir:          storageDir,
+		AdminPassword:       "adminpassword",
+		JWTSecret:           "secretkey",
+		AccessTokenTimeout:  15 * time.Minute,
+		RefreshTokenTimeout: 7 * 24 * time.Hour,
+		RebuildIndex:        rebuildIndex,
+	})
+	if err != nil {
+		t.Fatalf("NewWiki: %v", err)
+	}
+	return w
+}
+
+func waitForIndexing(t *testing.T, w *Wiki) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !w.status.IsReady() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for indexing, status %+v", w.status.Snapshot())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWiki_Restart_IndexesOnlyChangesSinceCleanShutdown(t *testing.T) {
+	storageDir := t.TempDir()
+	w := newWikiForStorage(t, storageDir, false)
+	waitForIndexing(t, w)
+	kept := createPageForTest(t, w, "system", nil, "Kept", "kept", pageNodeKind())
+	edited := createPageForTest(t, w, "system", nil, "Edited", "edited", pageNodeKind())
+	removed := createPageForTest(t, w, "system", nil, "Removed", "removed", pageNodeKind())
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	rootDir := filepath.Join(storageDir, "root")
+	raw, err := os.ReadFile(filepath.Join(rootDir, "edited.md"))
+	if err != nil {
+		t.Fatalf("read edited: %v", err)
+	}
+	test_utils.WriteFile(t, storageDir, "root/edited.md", string(raw)+"\nA narwhal swims by.\n")
+	test_utils.WriteFile(t, storageDir, "root/added.md", "# Added\n\nA pangolin appears.\n")
+	if err := os.Remove(filepath.Join(rootDir, "removed.md")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	w = newWikiForStorage(t, storageDir, false)
+	defer test_utils.WrapCloseWithErrorCheck(w.Close, t)
+	if !w.incrementalStartup {
+		t.Fatalf("expected an incremental startup after a clean shutdown")
+	}
+	waitForIndexing(t, w)
+
+	if ids := searchPageIDs(t, w, "narwhal"); !slices.Contains(ids, edited.ID) {
+		t.Fatalf("expected the edited page to be reindexed, got %v", ids)
+	}
+	if ids := searchPageIDs(t, w, "pangolin"); len(ids) != 1 {
+		t.Fatalf("expected the added page to be indexed, got %v", ids)
+	}
+	if ids := searchPageIDs(t, w, "Removed"); slices.Contains(ids, removed.ID) {
+		t.Fatalf("expected the removed page to leave the index, got %v", ids)
+	}
+	if ids := searchPageIDs(t, w, "Kept"); !slices.Contains(ids, kept.ID) {
+		t.Fatalf("expected the unchanged page to stay indexed, got %v", ids)
+	}
+}
+
+func TestWiki_Restart_RebuildsFullyWithoutCleanShutdown(t *testing.T) {
+	storageDir := t.TempDir()
+	w := newWikiForStorage(t, storageDir, false)
+	waitForIndexing(t, w)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	w = newWikiForStorage(t, storageDir, true)
+	if w.incrementalStartup {
+		t.Fatalf("expected --rebuild-index to rebuild everything")
+	}
+	waitForIndexing(t, w)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// A wiki that is not shut down cleanly leaves no manifest behind.
+	w = newWikiForStorage(t, storageDir, false)
+	if !w.incrementalStartup {
+		t.Fatalf("expected an incremental startup after a clean shutdown")
+	}
+	waitForIndexing(t, w)
+	w.shutdownCancel()
+	w.reloadWG.Wait()
+	w2 := newWikiForStorage(t, storageDir, false)
+	defer test_utils.WrapCloseWithErrorCheck(w2.Close, t)
+	if w2.incrementalStartup {
+		t.Fatalf("expected a full rebuild after an unclean shutdown")
+	}
+	defer test_utils.WrapCloseWithErrorCheck(w.Close, t)
+}
+
+func TestWiki_Restart_ReindexesWhenSearchAnalyzerChanges(t *testing.T) {
+	storageDir := t.TempDir()
+	w := newWikiForStorage(t, storageDir, false)
+	waitForIndexing(t, w)
+	page := createPageForTest(t, w, "system", nil, "Zertifikats", "zertifikats", pageNodeKind())
+	// Without stemming the page is only a fuzzy hit.
+	if result, err := w.searchIndex.Search("Zertifikate", nil, 0, 10); err != nil || len(result.Items) != 1 || !result.Items[0].Fuzzy {
+		t.Fatalf("expected a fuzzy hit without stemming, got %+v, %v", result, err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	w, err := NewWiki(&WikiOptions{
+		StorageDir:          storageDir,
+		AdminPassword:       "adminpassword",
+		JWTSecret:           "secretkey",
+		AccessTokenTimeout:  15 * time.Minute,
+		RefreshTokenTimeout: 7 * 24 * time.Hour,
+		SearchAnalyzer:      search.Analyzer{Language: search.LanguageGerman},
+	})
+	if err != nil {
+		t.Fatalf("NewWiki: %v", err)
+	}
+	defer test_utils.WrapCloseWithErrorCheck(w.Close, t)
+	if !w.incrementalStartup {
+		t.Fatalf("expected an incremental startup after a clean shutdown")
+	}
+	waitForIndexing(t, w)
+
+	status := w.status.Snapshot()
+	if status.Total == 0 || status.Indexed != status.Total {
+		t.Fatalf("expected the reindex to report its progress, got %+v", status)
+	}
+	if w.searchIndex.AnalyzerOutdated() {
+		t.Fatal("expected the search index to be analyzed with the new settings")
+	}
+	result, err := w.searchIndex.Search("Zertifikate", nil, 0, 10)
+	if err != nil || len(result.Items) != 1 || result.Items[0].PageID != page.ID || result.Items[0].Fuzzy {
+		t.Fatalf("expected an exact hit by the stem, got %+v, %v", result, err)
+	}
+}
+
+func TestWiki_SearchHistory_BackfillsAndFollowsRevisions(t *testing.T) {
+	storageDir := t.TempDir()
+	options := &WikiOptions{
+		StorageDir:          storageDir,
+		AdminPassword:       "adminpassword",
+		JWTSecret:           "secretkey",
+		AccessTokenTimeout:  15 * time.Minute,
+		RefreshTokenTimeout: 7 * 24 * time.Hour,
+		EnableRevision:      true,
+	}
+	w, err := NewWiki(options)
+	if err != nil {
+		t.Fatalf("NewWiki: %v", err)
+	}
+	page := createPageForTest(t, w, "system", nil, "Deployment", "deployment", pageNodeKind())
+	for _, content := range []string{"Deploy with Jenkins.", "Deploy with ArgoCD."} {
+		updatePageForTest(t, w, "system", page.ID, "Deployment", "deployment", &content, pageNodeKind())
+	}
+	if w.buildSearchHistory() != nil {
+		t.Fatal("expected no history search unless enabled")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	options.SearchHistory = true
+	w, err = NewWiki(options)
+	if err != nil {
+		t.Fatalf("NewWiki: %v", err)
+	}
+	defer test_utils.WrapCloseWithErrorCheck(w.Close, t)
+	w.reloadWG.Wait()
+
+	history := w.buildSearchHistory()
+	find := func(query string, anyVersion bool) []string {
+		t.Helper()
+		out, err := history.Execute(context.Background(), wikisearch.SearchHistoryInput{Query: query, AnyVersion: anyVersion, Limit: 10})
+		if err != nil {
+			t.Fatalf("SearchHistory(%q): %v", query, err)
+		}
+		var pageIDs []string
+		for _, item := range out.Result.Items {
+			pageIDs = append(pageIDs, item.PageID)
+		}
+		return pageIDs
+	}
+	if ids := find("jenkins", false); len(ids) != 0 {
+		t.Fatalf("expected the old text to be absent from the current version, got %v", ids)
+	}
+	if ids := find("jenkins", true); !slices.Equal(ids, []string{page.ID}) {
+		t.Fatalf("expected the stored revisions to be backfilled, got %v", ids)
+	}
+
+	content := "Deploy with Flux."
+	updatePageForTest(t, w, "system", page.ID, "Deployment", "deployment", &content, pageNodeKind())
+	if ids := find("flux", false); !slices.Equal(ids, []string{page.ID}) {
+		t.Fatalf("expected a new revision to be indexed, got %v", ids)
+	}
+
+	deletePageForTest(t, w, "system", page.ID, false)
+	if ids := find("deploy", true); len(ids) != 0 {
+		t.Fatalf("expected the revisions of a deleted page to leave the index, got %v", ids)
+	}
+}
+
+func TestWiki_RelatedPages_InvalidatedOnSave(t *testing.T) {
+	w := createWikiTestInstance(t)
+	defer test_utils.WrapCloseWithErrorCheck(w.Close, t)
+	waitForIndexing(t, w)
+
+	tagged := "---\ntags: [ops]\n---\n"
+	a := createPageForTest(t, w, "system", nil, "Alpha", "alpha", pageNodeKind())
+	updatePageForTest(t, w, "system", a.ID, "Alpha", "alpha", &tagged, pageNodeKind())
+	b := createPageForTest(t, w, "system", nil, "Beta", "beta", pageNodeKind())
+	updatePageForTest(t, w, "system", b.ID, "Beta", "beta", &tagged, pageNodeKind())
+
+	related, err := w.related.RelatedPages(a.ID)
+	if err != nil || len(related) != 1 || related[0].PageID != b.ID {
+		t.Fatalf("RelatedPages = %+v, %v", related, err)
+	}
+
+	untagged := "Nothing in common"
+	updatePageForTest(t, w, "system", b.ID, "Beta", "beta", &untagged, pageNodeKind())
+	related, err = w.related.RelatedPages(a.ID)
+	if err != nil || len(related) != 0 {
+		t.Fatalf("expected the save to invalidate the related pages, got %+v, %v", related, err)
+	}
+}
+
+func TestWiki_SavedSearchSubscription_NotifiesAboutNewMatches(t *testing.T) {
+	w := createWikiTestInstance(t)
+	defer test_utils.WrapCloseWithErrorCheck(w.Close, t)
+	waitForIndexing(t, w)
+	ctx := context.Background()
+
+	text := func(text string) *string { return &text }
+	old := createPageForTest(t, w, "system", nil, "Database", "database", pageNodeKind())
+	updatePageForTest(t, w, "system", old.ID, "Database", "database", text("Database outage"), pageNodeKind())
+
+	pages := w.newSearchUseCase()
+	created, err := wikisearch.NewCreateSavedSearchUseCase(w.favorites, pages).Execute(ctx, wikisearch.CreateSavedSearchInput{
+		UserID:            "alice",
+		SavedSearchFields: wikisearch.SavedSearchFields{Name: " Outages ", Query: "outage"},
+		Subscribe:         true,
+	})
+	if err != nil {
+		t.Fatalf("CreateSavedSearch: %v", err)
+	}
+	if !created.Search.Subscribed || created.Search.Name != "Outages" {
+		t.Fatalf("unexpected saved search: %+v", created.Search)
+	}
+
+	// Pages that matched already, or that do not match, are not news.
+	updatePageForTest(t, w, "system", old.ID, "Database", "database", text("Database outage, again"), pageNodeKind())
+	calm := createPageForTest(t, w, "system", nil, "Weather", "weather", pageNodeKind())
+	updatePageForTest(t, w, "system", calm.ID, "Weather", "weather", text("All quiet"), pageNodeKind())
+	// Nor is what alice changed herself.
+	own := createPageForTest(t, w, "alice", nil, "Cache", "cache", pageNodeKind())
+	updatePageForTest(t, w, "alice", own.ID, "Cache", "cache", text("Cache outage"), pageNodeKind())
+
+	network := createPageForTest(t, w, "system", nil, "Network", "network", pageNodeKind())
+	updatePageForTest(t, w, "system", network.ID, "Network", "network", text("Network outage"), pageNodeKind())
+
+	list := wikisearch.NewListNotificationsUseCase(w.favorites, pages)
+	out, err := list.Execute(ctx, wikisearch.ListNotificationsInput{UserID: "alice", UnreadOnly: true, Limit: 10})
+	if err != nil {
+		t.Fatalf("ListNotifications: %v", err)
+	}
+	if len(out.Notifications) != 1 {
+		t.Fatalf("expected one notification, got %+v", out.Notifications)
+	}
+	if n := out.Notifications[0]; n.PageID != network.ID || n.Title != "Network" || n.SearchName != "Outages" || n.Read {
+		t.Fatalf("unexpected notification: %+v", n)
+	}
+
+	if err := wikisearch.NewMarkNotificationsReadUseCase(w.favorites).Execute(ctx, wikisearch.MarkNotificationsReadInput{UserID: "alice"}); err != nil {
+		t.Fatalf("MarkNotificationsRead: %v", err)
+	}
+	if out, err = list.Execute(ctx, wikisearch.ListNotificationsInput{UserID: "alice", UnreadOnly: true, Limit: 10}); err != nil || len(out.Notifications) != 0 {
+		t.Fatalf("expected no unread notifications, got %+v, %v", out, err)
+	}
+}
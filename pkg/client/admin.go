@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// The methods in this file require an admin. API key management only works
+// in a session, as keys cannot manage keys.
+
+func (c *Client) Users(ctx context.Context) ([]*User, error) {
+	var users []*User
+	if err := c.doJSON(ctx, http.MethodGet, "/api/users", nil, nil, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// CreateUser is the input of Client.CreateUser and Client.UpdateUser. An
+// empty Password keeps the password on update.
+type CreateUser struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password,omitempty"`
+	Role     string `json:"role"`
+}
+
+func (c *Client) CreateUser(ctx context.Context, in CreateUser) (*User, error) {
+	var user User
+	if err := c.doJSON(ctx, http.MethodPost, "/api/users", nil, in, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (c *Client) UpdateUser(ctx context.Context, id string, in CreateUser) (*User, error) {
+	var user User
+	if err := c.doJSON(ctx, http.MethodPut, "/api/users/"+url.PathEscape(id), nil, in, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (c *Client) DeleteUser(ctx context.Context, id string) error {
+	return c.doJSON(ctx, http.MethodDelete, "/api/users/"+url.PathEscape(id), nil, nil, nil)
+}
+
+func (c *Client) APIKeys(ctx context.Context) ([]*APIKey, error) {
+	var keys []*APIKey
+	if err := c.doJSON(ctx, http.MethodGet, "/api/api-keys", nil, nil, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// CreateAPIKey is the input of Client.CreateAPIKey. Role narrows the role of
+// the user (default: viewer); a nil ExpiresAt never expires.
+type CreateAPIKey struct {
+	Name      string
+	UserID    string
+	Role      string
+	ExpiresAt *time.Time
+}
+
+// CreateAPIKey creates an API key and returns it with its secret, which the
+// server does not show again.
+func (c *Client) CreateAPIKey(ctx context.Context, in CreateAPIKey) (*APIKey, string, error) {
+	body := map[string]string{"name": in.Name, "userId": in.UserID, "role": in.Role}
+	if in.ExpiresAt != nil {
+		body["expiresAt"] = in.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	var out struct {
+		Key    *APIKey `json:"key"`
+		Secret string  `json:"secret"`
+	}
+	if err := c.doJSON(ctx, http.MethodPost, "/api/api-keys", nil, body, &out); err != nil {
+		return nil, "", err
+	}
+	return out.Key, out.Secret, nil
+}
+
+func (c *Client) RevokeAPIKey(ctx context.Context, id string) error {
+	return c.doJSON(ctx, http.MethodDelete, "/api/api-keys/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// Snapshots returns the full backup snapshots, newest first. Requires
+// --snapshot on the server.
+func (c *Client) Snapshots(ctx context.Context) ([]Snapshot, error) {
+	var out struct {
+		Snapshots []Snapshot `json:"snapshots"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/api/admin/snapshot", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Snapshots, nil
+}
+
+// TriggerSnapshot starts a snapshot in the background.
+func (c *Client) TriggerSnapshot(ctx context.Context) error {
+	return c.doJSON(ctx, http.MethodPost, "/api/admin/snapshot", nil, nil, nil)
+}
+
+// TriggerResync starts rebuilding the wiki from its files in the background,
+// e.g. after they were edited outside of LeafWiki.
+func (c *Client) TriggerResync(ctx context.Context) error {
+	return c.doJSON(ctx, http.MethodPost, "/api/admin/resync", nil, nil, nil)
+}
+
+func (c *Client) ResyncStatus(ctx context.Context) (*ResyncStatus, error) {
+	var status ResyncStatus
+	if err := c.doJSON(ctx, http.MethodGet, "/api/admin/resync/status", nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Assets returns the public paths of the assets of a page, such as
+// "/assets/<page-id>/diagram.png".
+func (c *Client) Assets(ctx context.Context, pageID string) ([]string, error) {
+	var out struct {
+		Files []string `json:"files"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, pagePath(pageID)+"/assets", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Files, nil
+}
+
+// UploadAsset uploads an asset to a page and returns its public path. The
+// server normalizes the file name and numbers it if the page has an asset
+// of that name already.
+func (c *Client) UploadAsset(ctx context.Context, pageID, filename string, content io.Reader) (string, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, content); err != nil {
+		return "", err
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	var out struct {
+		File string `json:"file"`
+	}
+	if err := c.send(ctx, http.MethodPost, pagePath(pageID)+"/assets", nil, body.Bytes(), form.FormDataContentType(), &out, true); err != nil {
+		return "", err
+	}
+	return out.File, nil
+}
+
+// RenameAsset renames an asset of a page and returns its new public path.
+func (c *Client) RenameAsset(ctx context.Context, pageID, oldName, newName string) (string, error) {
+	body := map[string]string{"old_filename": oldName, "new_filename": newName}
+	var out struct {
+		URL string `json:"url"`
+	}
+	if err := c.doJSON(ctx, http.MethodPut, pagePath(pageID)+"/assets/rename", nil, body, &out); err != nil {
+		return "", err
+	}
+	return out.URL, nil
+}
+
+func (c *Client) DeleteAsset(ctx context.Context, pageID, name string) error {
+	return c.doJSON(ctx, http.MethodDelete, pagePath(pageID)+"/assets/"+url.PathEscape(name), nil, nil, nil)
+}
+
+// DownloadAsset writes the asset at a public path, as returned by Assets, to w.
+func (c *Client) DownloadAsset(ctx context.Context, publicPath string, w io.Writer) error {
+	return c.send(ctx, http.MethodGet, path.Clean("/"+strings.TrimPrefix(publicPath, c.baseURL.Path)), nil, nil, "", w, true)
+}
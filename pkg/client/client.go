@@ -0,0 +1,207 @@
+// Package client is a Go client for the REST API of a LeafWiki server.
+//
+// A client authenticates either with an API key (Options.APIKey, requires
+// --enable-api-key-management on the server) or with a user session started
+// by Login. Sessions handle the CSRF token and refresh expired access tokens
+// on their own. Writes to pages take the version the caller last read, and
+// fail with an error matching ErrConflict if the page changed since.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultTimeout = 60 * time.Second
+
+// csrfCookieNames are the names of the CSRF cookie over HTTPS and plain HTTP.
+var csrfCookieNames = []string{"__Host-leafwiki_csrf", "leafwiki_csrf"}
+
+// Options configure a Client.
+type Options struct {
+	// APIKey authenticates every request with the key. Leave it empty to
+	// call Login instead, or to read a wiki with public access.
+	APIKey string
+	// HTTPClient sends the requests (default: a client with a 60s timeout).
+	// Its cookie jar is replaced for session authentication.
+	HTTPClient *http.Client
+}
+
+// Client talks to one LeafWiki server. It is safe for concurrent use.
+type Client struct {
+	baseURL *url.URL
+	apiKey  string
+	http    *http.Client
+
+	mu       sync.Mutex
+	loggedIn bool
+}
+
+// New creates a client for the server at baseURL, including its base path
+// (e.g. https://example.com/wiki).
+func New(baseURL string, opts Options) (*Client, error) {
+	parsed, err := url.Parse(strings.TrimRight(strings.TrimSpace(baseURL), "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: expected http(s)://host[/base-path]", baseURL)
+	}
+
+	httpClient := &http.Client{Timeout: defaultTimeout}
+	if opts.HTTPClient != nil {
+		copied := *opts.HTTPClient
+		httpClient = &copied
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	httpClient.Jar = jar
+
+	return &Client{baseURL: parsed, apiKey: strings.TrimSpace(opts.APIKey), http: httpClient}, nil
+}
+
+// Login starts a session for the user with the given username or email. It
+// returns ErrTOTPRequired for accounts with a second factor, which this
+// client does not support; use an API key for those.
+func (c *Client) Login(ctx context.Context, identifier, password string) (*User, error) {
+	var out struct {
+		RequiresTOTP bool  `json:"requiresTotp"`
+		User         *User `json:"user"`
+	}
+	body := map[string]string{"identifier": identifier, "password": password}
+	if err := c.doJSON(ctx, http.MethodPost, "/api/auth/login", nil, body, &out); err != nil {
+		return nil, err
+	}
+	if out.RequiresTOTP {
+		return nil, ErrTOTPRequired
+	}
+	c.mu.Lock()
+	c.loggedIn = true
+	c.mu.Unlock()
+	return out.User, nil
+}
+
+// Logout ends the session started by Login.
+func (c *Client) Logout(ctx context.Context) error {
+	err := c.doJSON(ctx, http.MethodPost, "/api/auth/logout", nil, nil, nil)
+	c.mu.Lock()
+	c.loggedIn = false
+	c.mu.Unlock()
+	return err
+}
+
+// Me returns the user the client acts as, or nil for anonymous access.
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	var user *User
+	if err := c.doJSON(ctx, http.MethodGet, "/api/auth/me", nil, nil, &user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (c *Client) refreshSession(ctx context.Context) error {
+	return c.send(ctx, http.MethodPost, "/api/auth/refresh-token", nil, nil, "", nil, false)
+}
+
+func (c *Client) doJSON(ctx context.Context, method, apiPath string, query url.Values, body any, out any) error {
+	var raw []byte
+	contentType := ""
+	if body != nil {
+		var err error
+		if raw, err = json.Marshal(body); err != nil {
+			return err
+		}
+		contentType = "application/json"
+	}
+	return c.send(ctx, method, apiPath, query, raw, contentType, out, true)
+}
+
+// send sends a request and decodes a JSON response into out, or copies it
+// into out if it is an io.Writer. In a session, an expired access token is
+// refreshed once and the request retried.
+func (c *Client) send(ctx context.Context, method, apiPath string, query url.Values, body []byte, contentType string, out any, retry bool) error {
+	resp, err := c.roundTrip(ctx, method, apiPath, query, body, contentType)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode == http.StatusUnauthorized && retry && c.inSession() {
+		_ = resp.Body.Close()
+		if err := c.refreshSession(ctx); err != nil {
+			return err
+		}
+		return c.send(ctx, method, apiPath, query, body, contentType, out, false)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return decodeError(method, apiPath, resp)
+	}
+
+	switch out := out.(type) {
+	case nil:
+		return nil
+	case io.Writer:
+		_, err := io.Copy(out, resp.Body)
+		return err
+	default:
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s %s: decode response: %w", method, apiPath, err)
+		}
+		return nil
+	}
+}
+
+func (c *Client) roundTrip(ctx context.Context, method, apiPath string, query url.Values, body []byte, contentType string) (*http.Response, error) {
+	target := *c.baseURL
+	target.Path = c.baseURL.Path + apiPath
+	target.RawQuery = query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	} else if token := c.csrfToken(); token != "" && method != http.MethodGet && method != http.MethodHead {
+		req.Header.Set("X-CSRF-Token", token)
+	}
+	return c.http.Do(req)
+}
+
+func (c *Client) inSession() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.loggedIn && c.apiKey == ""
+}
+
+func (c *Client) csrfToken() string {
+	for _, cookie := range c.http.Jar.Cookies(c.baseURL) {
+		for _, name := range csrfCookieNames {
+			if cookie.Name == name {
+				return cookie.Value
+			}
+		}
+	}
+	return ""
+}
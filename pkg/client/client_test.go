@@ -0,0 +1,463 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	coreassets "github.com/perber/wiki/internal/core/assets"
+	httpinternal "github.com/perber/wiki/internal/http"
+	"github.com/perber/wiki/internal/test_utils"
+	"github.com/perber/wiki/internal/wiki"
+	"github.com/perber/wiki/pkg/client"
+)
+
+func newTestServer(t *testing.T) string {
+	t.Helper()
+	w, err := wiki.NewWiki(&wiki.WikiOptions{
+		StorageDir:             t.TempDir(),
+		AdminPassword:          "adminpassword",
+		JWTSecret:              "secretkey",
+		AccessTokenTimeout:     15 * time.Minute,
+		RefreshTokenTimeout:    7 * 24 * time.Hour,
+		EnableRevision:         true,
+		EnableAPIKeyManagement: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create wiki instance: %v", err)
+	}
+	t.Cleanup(func() { test_utils.WrapCloseWithErrorCheck(w.Close, t) })
+	router := httpinternal.NewRouter(w.Registrars(), w.FrontendConfig(), httpinternal.RouterOptions{
+		AllowInsecure:           true,
+		AccessTokenTimeout:      15 * time.Minute,
+		RefreshTokenTimeout:     7 * 24 * time.Hour,
+		MaxAssetUploadSizeBytes: coreassets.DefaultMaxUploadSizeBytes,
+		EnableRevision:          true,
+		APIKeyService:           w.APIKeyService(),
+		EnableAPIKeyManagement:  true,
+	})
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func newAdminClient(t *testing.T, baseURL string) *client.Client {
+	t.Helper()
+	c, err := client.New(baseURL, client.Options{})
+	if err != nil {
+		t.Fatalf("New err: %v", err)
+	}
+	if _, err := c.Login(context.Background(), "admin", "adminpassword"); err != nil {
+		t.Fatalf("Login err: %v", err)
+	}
+	return c
+}
+
+func createPage(t *testing.T, c *client.Client, parentID, title, slug string) *client.Page {
+	t.Helper()
+	page, err := c.CreatePage(context.Background(), client.CreatePage{ParentID: parentID, Title: title, Slug: slug, Kind: client.KindPage})
+	if err != nil {
+		t.Fatalf("CreatePage %s err: %v", slug, err)
+	}
+	return page
+}
+
+func writeContent(t *testing.T, c *client.Client, page *client.Page, content string, tags []string, props map[string]string) *client.Page {
+	t.Helper()
+	updated, err := c.UpdatePage(context.Background(), page.ID, client.UpdatePage{
+		Version:    page.Version,
+		Title:      page.Title,
+		Slug:       page.Slug,
+		Content:    &content,
+		Tags:       tags,
+		Properties: props,
+	})
+	if err != nil {
+		t.Fatalf("UpdatePage %s err: %v", page.Slug, err)
+	}
+	return updated
+}
+
+func TestNew_RejectsInvalidBaseURL(t *testing.T) {
+	for _, raw := range []string{"", "example.com", "ftp://example.com", "http://"} {
+		if _, err := client.New(raw, client.Options{}); err == nil {
+			t.Errorf("expected error for base URL %q", raw)
+		}
+	}
+}
+
+func TestClient_SessionLoginAndLogout(t *testing.T) {
+	ctx := context.Background()
+	baseURL := newTestServer(t)
+	c, err := client.New(baseURL, client.Options{})
+	if err != nil {
+		t.Fatalf("New err: %v", err)
+	}
+
+	if _, err := c.Login(ctx, "admin", "wrong"); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized for wrong password, got %v", err)
+	}
+	if _, err := c.Tree(ctx); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized before login, got %v", err)
+	}
+
+	user, err := c.Login(ctx, "admin", "adminpassword")
+	if err != nil {
+		t.Fatalf("Login err: %v", err)
+	}
+	if user == nil || user.Username != "admin" || user.Role != "admin" {
+		t.Fatalf("unexpected user: %+v", user)
+	}
+	me, err := c.Me(ctx)
+	if err != nil || me == nil || me.ID != user.ID {
+		t.Fatalf("Me = %+v, %v; want %s", me, err, user.ID)
+	}
+
+	// A write proves that the CSRF token is sent along with the session.
+	createPage(t, c, "", "Session", "session")
+
+	if err := c.Logout(ctx); err != nil {
+		t.Fatalf("Logout err: %v", err)
+	}
+	if _, err := c.CreatePage(ctx, client.CreatePage{Title: "After", Slug: "after"}); err == nil {
+		t.Fatalf("expected error after logout")
+	}
+}
+
+func TestClient_PageLifecycle(t *testing.T) {
+	ctx := context.Background()
+	c := newAdminClient(t, newTestServer(t))
+
+	section, err := c.EnsurePath(ctx, "guides", "Guides", client.KindSection)
+	if err != nil {
+		t.Fatalf("EnsurePath err: %v", err)
+	}
+	page := createPage(t, c, section.ID, "Install", "install")
+	page = writeContent(t, c, page, "# Install\n\nRun the binary.", []string{"Setup"}, map[string]string{"owner": "ops"})
+
+	got, err := c.PageByPath(ctx, "guides/install")
+	if err != nil {
+		t.Fatalf("PageByPath err: %v", err)
+	}
+	if got.ID != page.ID || !strings.Contains(got.Content, "Run the binary.") {
+		t.Fatalf("unexpected page: %+v", got)
+	}
+	if len(got.Tags) != 1 || got.Tags[0] != "setup" || got.Properties["owner"] != "ops" {
+		t.Fatalf("unexpected tags/properties: %v %v", got.Tags, got.Properties)
+	}
+
+	lookup, err := c.LookupPath(ctx, "guides/install")
+	if err != nil || lookup.PageID() != page.ID {
+		t.Fatalf("LookupPath = %+v, %v", lookup, err)
+	}
+	missing, err := c.LookupPath(ctx, "guides/missing")
+	if err != nil || missing.PageID() != "" {
+		t.Fatalf("LookupPath missing = %+v, %v", missing, err)
+	}
+
+	tree, err := c.Tree(ctx)
+	if err != nil {
+		t.Fatalf("Tree err: %v", err)
+	}
+	var guides *client.Node
+	for _, child := range tree.Children {
+		if child.ID == section.ID {
+			guides = child
+		}
+	}
+	if guides == nil || len(guides.Children) != 1 || guides.Children[0].ID != page.ID {
+		t.Fatalf("section %s with page %s not found in tree: %+v", section.ID, page.ID, tree)
+	}
+
+	// A nil Content keeps the body.
+	renamed, err := c.UpdatePage(ctx, page.ID, client.UpdatePage{Version: page.Version, Title: "Installation", Slug: "installation"})
+	if err != nil {
+		t.Fatalf("UpdatePage err: %v", err)
+	}
+	if renamed.Path != "guides/installation" || !strings.Contains(renamed.Content, "Run the binary.") {
+		t.Fatalf("unexpected renamed page: path=%q content=%q", renamed.Path, renamed.Content)
+	}
+
+	if err := c.MovePage(ctx, renamed.ID, renamed.Version, ""); err != nil {
+		t.Fatalf("MovePage err: %v", err)
+	}
+	moved, err := c.Page(ctx, renamed.ID)
+	if err != nil || moved.Path != "installation" {
+		t.Fatalf("Page after move = %+v, %v", moved, err)
+	}
+
+	if err := c.DeletePage(ctx, moved.ID, moved.Version, false); err != nil {
+		t.Fatalf("DeletePage err: %v", err)
+	}
+	if _, err := c.Page(ctx, moved.ID); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestClient_TypedErrors(t *testing.T) {
+	ctx := context.Background()
+	c := newAdminClient(t, newTestServer(t))
+	page := createPage(t, c, "", "Conflict", "conflict")
+	writeContent(t, c, page, "first", nil, nil)
+
+	content := "second"
+	_, err := c.UpdatePage(ctx, page.ID, client.UpdatePage{Version: page.Version, Title: page.Title, Slug: page.Slug, Content: &content})
+	if !errors.Is(err, client.ErrConflict) || !client.HasCode(err, client.CodePageVersionConflict) {
+		t.Fatalf("expected version conflict, got %v", err)
+	}
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 409 || apiErr.Message == "" {
+		t.Fatalf("expected *client.Error with message, got %#v", err)
+	}
+
+	_, err = c.CreatePage(ctx, client.CreatePage{Title: "Reserved", Slug: "api"})
+	if !errors.Is(err, client.ErrValidation) {
+		t.Fatalf("expected ErrValidation, got %v", err)
+	}
+	if !errors.As(err, &apiErr) || len(apiErr.Fields) == 0 || apiErr.Fields[0].Field != "slug" {
+		t.Fatalf("expected a slug field error, got %#v", err)
+	}
+
+	if _, err := c.Page(ctx, "does-not-exist"); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestClient_SearchTagsPropertiesAndLinks(t *testing.T) {
+	ctx := context.Background()
+	c := newAdminClient(t, newTestServer(t))
+	target := createPage(t, c, "", "Runbook", "runbook")
+	writeContent(t, c, target, "Restart the zeppelin service.", []string{"ops", "oncall"}, map[string]string{"team": "platform"})
+	source := createPage(t, c, "", "Overview", "overview")
+	writeContent(t, c, source, "See the [runbook](/runbook).", []string{"ops"}, nil)
+
+	var result *client.SearchResult
+	var err error
+	for i := 0; i < 50; i++ {
+		if result, err = c.Search(ctx, "zeppelin", client.SearchOptions{}); err == nil && result.Count > 0 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil || result.Count != 1 || result.Items[0].PageID != target.ID {
+		t.Fatalf("Search = %+v, %v", result, err)
+	}
+
+	tags, err := c.Tags(ctx, "", 0)
+	if err != nil {
+		t.Fatalf("Tags err: %v", err)
+	}
+	counts := map[string]int{}
+	for _, tag := range tags {
+		counts[tag.Tag] = tag.Count
+	}
+	if counts["ops"] != 2 || counts["oncall"] != 1 {
+		t.Fatalf("unexpected tag counts: %v", counts)
+	}
+	tagged, err := c.PagesByTags(ctx, "ops", "oncall")
+	if err != nil || len(tagged) != 1 || tagged[0].ID != target.ID {
+		t.Fatalf("PagesByTags = %+v, %v", tagged, err)
+	}
+
+	keys, err := c.PropertyKeys(ctx, "", 0)
+	if err != nil || len(keys) != 1 || keys[0].Key != "team" {
+		t.Fatalf("PropertyKeys = %+v, %v", keys, err)
+	}
+	byProperty, err := c.PagesByProperty(ctx, "team", "platform")
+	if err != nil || len(byProperty) != 1 || byProperty[0].ID != target.ID {
+		t.Fatalf("PagesByProperty = %+v, %v", byProperty, err)
+	}
+
+	links, err := c.LinkStatus(ctx, target.ID)
+	if err != nil {
+		t.Fatalf("LinkStatus err: %v", err)
+	}
+	if links.Counts.Backlinks != 1 || links.Backlinks[0].FromPageID != source.ID {
+		t.Fatalf("unexpected link status: %+v", links)
+	}
+
+	graph, err := c.LinkGraph(ctx, client.LinkGraphOptions{PageID: target.ID, IncludeTags: true, IncludeProperties: true})
+	if err != nil || len(graph.Nodes) != 2 || len(graph.Edges) != 1 {
+		t.Fatalf("LinkGraph = %+v, %v", graph, err)
+	}
+	if node := graph.Nodes[0]; node.ID != target.ID || len(node.Tags) != 2 || node.Properties["team"] != "platform" {
+		t.Fatalf("unexpected graph node: %+v", node)
+	}
+	if edge := graph.Edges[0]; edge.Source != source.ID || edge.Target != target.ID {
+		t.Fatalf("unexpected graph edge: %+v", edge)
+	}
+
+	related, err := c.RelatedPages(ctx, target.ID, 5)
+	if err != nil || len(related) == 0 || related[0].PageID != source.ID || related[0].Path != "/overview" {
+		t.Fatalf("RelatedPages = %+v, %v", related, err)
+	}
+}
+
+func TestClient_SavedSearchesAndNotifications(t *testing.T) {
+	ctx := context.Background()
+	baseURL := newTestServer(t)
+	admin := newAdminClient(t, baseURL)
+	if _, err := admin.CreateUser(ctx, client.CreateUser{Username: "oncall", Email: "oncall@example.com", Password: "password123", Role: "editor"}); err != nil {
+		t.Fatalf("CreateUser err: %v", err)
+	}
+	oncall, err := client.New(baseURL, client.Options{})
+	if err != nil {
+		t.Fatalf("New err: %v", err)
+	}
+	if _, err := oncall.Login(ctx, "oncall", "password123"); err != nil {
+		t.Fatalf("Login err: %v", err)
+	}
+
+	search, err := oncall.CreateSavedSearch(ctx, client.SavedSearchInput{Name: "Zeppelins", Query: "zeppelin", Shared: true, Subscribe: true})
+	if err != nil || !search.Subscribed || search.Owner == nil || search.Owner.Username != "oncall" {
+		t.Fatalf("CreateSavedSearch = %+v, %v", search, err)
+	}
+	listed, err := admin.SavedSearches(ctx)
+	if err != nil || len(listed) != 1 || listed[0].ID != search.ID || listed[0].Subscribed {
+		t.Fatalf("SavedSearches = %+v, %v", listed, err)
+	}
+	if _, err := admin.UpdateSavedSearch(ctx, search.ID, client.SavedSearchInput{Name: "Mine"}); !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("expected ErrForbidden, got %v", err)
+	}
+
+	page := createPage(t, admin, "", "Airships", "airships")
+	writeContent(t, admin, page, "The zeppelin has landed.", nil, nil)
+
+	notifications, err := oncall.Notifications(ctx, true, 0)
+	if err != nil || len(notifications) != 1 {
+		t.Fatalf("Notifications = %+v, %v", notifications, err)
+	}
+	if n := notifications[0]; n.PageID != page.ID || n.Path != "airships" || n.SearchName != "Zeppelins" {
+		t.Fatalf("unexpected notification: %+v", n)
+	}
+	if err := oncall.MarkNotificationsRead(ctx, notifications[0].ID); err != nil {
+		t.Fatalf("MarkNotificationsRead err: %v", err)
+	}
+	if unread, err := oncall.Notifications(ctx, true, 0); err != nil || len(unread) != 0 {
+		t.Fatalf("expected no unread notifications, got %+v, %v", unread, err)
+	}
+
+	if search, err = oncall.Unsubscribe(ctx, search.ID); err != nil || search.Subscribed {
+		t.Fatalf("Unsubscribe = %+v, %v", search, err)
+	}
+	if err := oncall.DeleteSavedSearch(ctx, search.ID); err != nil {
+		t.Fatalf("DeleteSavedSearch err: %v", err)
+	}
+	if listed, err = admin.SavedSearches(ctx); err != nil || len(listed) != 0 {
+		t.Fatalf("expected the search to be deleted, got %+v, %v", listed, err)
+	}
+}
+
+func TestClient_AssetsAndRevisions(t *testing.T) {
+	ctx := context.Background()
+	c := newAdminClient(t, newTestServer(t))
+	page := createPage(t, c, "", "Diagrams", "diagrams")
+	page = writeContent(t, c, page, "v1", nil, nil)
+
+	publicPath, err := c.UploadAsset(ctx, page.ID, "notes.txt", strings.NewReader("hello asset"))
+	if err != nil {
+		t.Fatalf("UploadAsset err: %v", err)
+	}
+	files, err := c.Assets(ctx, page.ID)
+	if err != nil || len(files) != 1 || files[0] != publicPath {
+		t.Fatalf("Assets = %v, %v; want [%s]", files, err, publicPath)
+	}
+	var downloaded bytes.Buffer
+	if err := c.DownloadAsset(ctx, publicPath, &downloaded); err != nil {
+		t.Fatalf("DownloadAsset err: %v", err)
+	}
+	if downloaded.String() != "hello asset" {
+		t.Fatalf("unexpected asset content %q", downloaded.String())
+	}
+	if err := c.DeleteAsset(ctx, page.ID, "notes.txt"); err != nil {
+		t.Fatalf("DeleteAsset err: %v", err)
+	}
+
+	writeContent(t, c, page, "v2", nil, nil)
+	list, err := c.Revisions(ctx, page.ID, "", 0)
+	if err != nil || len(list.Revisions) < 2 {
+		t.Fatalf("Revisions = %+v, %v", list, err)
+	}
+	latest, err := c.LatestRevision(ctx, page.ID)
+	if err != nil || latest.ID != list.Revisions[0].ID {
+		t.Fatalf("LatestRevision = %+v, %v", latest, err)
+	}
+
+	var v1 *client.Revision
+	for _, rev := range list.Revisions {
+		snapshot, err := c.Revision(ctx, page.ID, rev.ID)
+		if err != nil {
+			t.Fatalf("Revision err: %v", err)
+		}
+		if strings.TrimSpace(snapshot.Content) == "v1" {
+			v1 = rev
+		}
+	}
+	if v1 == nil {
+		t.Fatalf("no revision with content v1 in %+v", list.Revisions)
+	}
+	comparison, err := c.CompareRevisions(ctx, page.ID, v1.ID, latest.ID)
+	if err != nil || !comparison.ContentChanged {
+		t.Fatalf("CompareRevisions = %+v, %v", comparison, err)
+	}
+	restored, err := c.RestoreRevision(ctx, page.ID, v1.ID)
+	if err != nil || strings.TrimSpace(restored.Content) != "v1" {
+		t.Fatalf("RestoreRevision = %+v, %v", restored, err)
+	}
+}
+
+func TestClient_APIKeyAuthAndAdmin(t *testing.T) {
+	ctx := context.Background()
+	baseURL := newTestServer(t)
+	admin := newAdminClient(t, baseURL)
+
+	editor, err := admin.CreateUser(ctx, client.CreateUser{Username: "bot", Email: "bot@example.com", Password: "password123", Role: "editor"})
+	if err != nil {
+		t.Fatalf("CreateUser err: %v", err)
+	}
+	users, err := admin.Users(ctx)
+	if err != nil || len(users) != 2 {
+		t.Fatalf("Users = %+v, %v", users, err)
+	}
+
+	key, secret, err := admin.CreateAPIKey(ctx, client.CreateAPIKey{Name: "ci", UserID: editor.ID, Role: "editor"})
+	if err != nil || key == nil || secret == "" {
+		t.Fatalf("CreateAPIKey = %+v, %q, %v", key, secret, err)
+	}
+
+	bot, err := client.New(baseURL, client.Options{APIKey: secret})
+	if err != nil {
+		t.Fatalf("New err: %v", err)
+	}
+	me, err := bot.Me(ctx)
+	if err != nil || me == nil || me.ID != editor.ID {
+		t.Fatalf("Me = %+v, %v", me, err)
+	}
+	// Editors write without a session or CSRF token, but cannot manage the wiki.
+	createPage(t, bot, "", "From CI", "from-ci")
+	if _, err := bot.Users(ctx); !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("expected ErrForbidden for users, got %v", err)
+	}
+	if _, err := bot.APIKeys(ctx); !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("expected ErrForbidden for API keys, got %v", err)
+	}
+
+	if err := admin.RevokeAPIKey(ctx, key.ID); err != nil {
+		t.Fatalf("RevokeAPIKey err: %v", err)
+	}
+	if _, err := bot.Tree(ctx); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized after revoke, got %v", err)
+	}
+
+	if err := admin.DeleteUser(ctx, editor.ID); err != nil {
+		t.Fatalf("DeleteUser err: %v", err)
+	}
+	status, err := admin.ResyncStatus(ctx)
+	if err != nil || status.Running {
+		t.Fatalf("ResyncStatus = %+v, %v", status, err)
+	}
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Error codes of the server that callers commonly handle. Error.Code holds the
+// code of any error; these are only the ones with a meaning beyond the status.
+const (
+	CodeValidation          = "validation_error"
+	CodePageNotFound        = "page_not_found"
+	CodePageVersionConflict = "page_version_conflict"
+	CodePageSlugConflict    = "page_slug_conflict"
+	CodePageHasChildren     = "page_has_children"
+)
+
+// Sentinel errors to check an *Error against with errors.Is.
+var (
+	ErrUnauthorized = errors.New("unauthorized")                   // 401: not logged in, or the API key is invalid
+	ErrForbidden    = errors.New("forbidden")                      // 403: the role does not allow the request
+	ErrNotFound     = errors.New("not found")                      // 404
+	ErrConflict     = errors.New("conflict")                       // 409, e.g. an outdated page version
+	ErrValidation   = errors.New("validation failed")              // the request was rejected with field errors
+	ErrTOTPRequired = errors.New("login requires a second factor") // returned by Login for accounts with TOTP
+)
+
+// FieldError is a rejected field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error response of the server. Code, Template and Args are set
+// for localized errors; Fields for validation errors.
+type Error struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Code       string
+	Message    string
+	Template   string
+	Args       []string
+	Fields     []FieldError
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if len(e.Fields) > 0 {
+		fields := make([]string, 0, len(e.Fields))
+		for _, field := range e.Fields {
+			fields = append(fields, field.Field+": "+field.Message)
+		}
+		msg += " (" + strings.Join(fields, "; ") + ")"
+	}
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.Path, e.StatusCode, msg)
+}
+
+// Is matches the sentinel errors of this package by status code and by
+// error code.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrValidation:
+		return e.Code == CodeValidation
+	}
+	return false
+}
+
+// HasCode reports whether err is an *Error with the given code.
+func HasCode(err error, code string) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// decodeError reads the error shapes of the API: {"error": "message"},
+// {"error": {"code": ..., "message": ..., "template": ..., "args": [...]}}
+// and {"error": "validation_error", "fields": [...]}.
+func decodeError(method, path string, resp *http.Response) error {
+	apiErr := &Error{Method: method, Path: path, StatusCode: resp.StatusCode}
+	var payload struct {
+		Error  json.RawMessage `json:"error"`
+		Fields []FieldError    `json:"fields"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if json.Unmarshal(raw, &payload) != nil || len(payload.Error) == 0 {
+		return apiErr
+	}
+
+	var detail struct {
+		Code     string   `json:"code"`
+		Message  string   `json:"message"`
+		Template string   `json:"template"`
+		Args     []string `json:"args"`
+	}
+	if json.Unmarshal(payload.Error, &apiErr.Message) != nil && json.Unmarshal(payload.Error, &detail) == nil {
+		apiErr.Code, apiErr.Message, apiErr.Template, apiErr.Args = detail.Code, detail.Message, detail.Template, detail.Args
+	}
+	if apiErr.Message == CodeValidation {
+		apiErr.Code, apiErr.Message, apiErr.Fields = CodeValidation, "Validation failed", payload.Fields
+	}
+	return apiErr
+}
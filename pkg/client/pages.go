@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Tree returns the whole page tree, starting at the root node.
+func (c *Client) Tree(ctx context.Context) (*Node, error) {
+	var node Node
+	if err := c.doJSON(ctx, http.MethodGet, "/api/tree", nil, nil, &node); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+func (c *Client) Page(ctx context.Context, id string) (*Page, error) {
+	var page Page
+	if err := c.doJSON(ctx, http.MethodGet, pagePath(id), nil, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// PageByPath returns the page at a page path such as "docs/intro".
+func (c *Client) PageByPath(ctx context.Context, routePath string) (*Page, error) {
+	var page Page
+	if err := c.doJSON(ctx, http.MethodGet, "/api/pages/by-path", url.Values{"path": {routePath}}, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// LookupPath reports which segments of a page path exist, without failing
+// for missing pages.
+func (c *Client) LookupPath(ctx context.Context, routePath string) (*PathLookup, error) {
+	var lookup PathLookup
+	if err := c.doJSON(ctx, http.MethodGet, "/api/pages/lookup", url.Values{"path": {routePath}}, nil, &lookup); err != nil {
+		return nil, err
+	}
+	return &lookup, nil
+}
+
+// CreatePage is the input of Client.CreatePage. An empty ParentID creates
+// the page at the root.
+type CreatePage struct {
+	ParentID string `json:"parentId,omitempty"`
+	Title    string `json:"title"`
+	Slug     string `json:"slug"`
+	Kind     Kind   `json:"kind,omitempty"`
+}
+
+func (c *Client) CreatePage(ctx context.Context, in CreatePage) (*Page, error) {
+	var page Page
+	if err := c.doJSON(ctx, http.MethodPost, "/api/pages", nil, in, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// EnsurePath returns the page at routePath, creating it with the given title
+// and kind, and its missing parents as sections.
+func (c *Client) EnsurePath(ctx context.Context, routePath, title string, kind Kind) (*Page, error) {
+	body := map[string]string{"path": routePath, "title": title}
+	if kind != "" {
+		body["kind"] = string(kind)
+	}
+	var page Page
+	if err := c.doJSON(ctx, http.MethodPost, "/api/pages/ensure", nil, body, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// UpdatePage is the input of Client.UpdatePage. Version is the version of
+// the page the change is based on. A nil Content keeps the content; nil Tags
+// and Properties keep those of the page, empty ones remove them.
+type UpdatePage struct {
+	Version    string            `json:"version"`
+	Title      string            `json:"title"`
+	Slug       string            `json:"slug"`
+	Content    *string           `json:"content,omitempty"`
+	Tags       []string          `json:"tags"`
+	Properties map[string]string `json:"properties"`
+}
+
+func (c *Client) UpdatePage(ctx context.Context, id string, in UpdatePage) (*Page, error) {
+	var page Page
+	if err := c.doJSON(ctx, http.MethodPut, pagePath(id), nil, in, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// DeletePage deletes a page at the given version. Sections with children
+// are only deleted when recursive is set.
+func (c *Client) DeletePage(ctx context.Context, id, version string, recursive bool) error {
+	query := url.Values{"version": {version}}
+	if recursive {
+		query.Set("recursive", "true")
+	}
+	return c.doJSON(ctx, http.MethodDelete, pagePath(id), query, nil, nil)
+}
+
+// MovePage moves a page below parentID ("" or "root" for the root).
+func (c *Client) MovePage(ctx context.Context, id, version, parentID string) error {
+	body := map[string]string{"version": version, "parentId": parentID}
+	return c.doJSON(ctx, http.MethodPut, pagePath(id)+"/move", nil, body, nil)
+}
+
+// SortPages sets the order of the children of a section.
+func (c *Client) SortPages(ctx context.Context, parentID string, orderedIDs []string) error {
+	body := map[string][]string{"orderedIds": orderedIDs}
+	return c.doJSON(ctx, http.MethodPut, pagePath(parentID)+"/sort", nil, body, nil)
+}
+
+func pagePath(id string) string {
+	return "/api/pages/" + url.PathEscape(id)
+}
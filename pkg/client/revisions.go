@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Revisions returns a page of the history of a page, newest first. Pass the
+// NextCursor of the previous result as cursor to continue; limit <= 0 uses
+// the server default. Requires --enable-revision on the server.
+func (c *Client) Revisions(ctx context.Context, pageID, cursor string, limit int) (*RevisionList, error) {
+	query := url.Values{}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var list RevisionList
+	if err := c.doJSON(ctx, http.MethodGet, pagePath(pageID)+"/revisions", query, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (c *Client) LatestRevision(ctx context.Context, pageID string) (*Revision, error) {
+	var rev Revision
+	if err := c.doJSON(ctx, http.MethodGet, pagePath(pageID)+"/revisions/latest", nil, nil, &rev); err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// Revision returns a revision with the content and assets it recorded.
+func (c *Client) Revision(ctx context.Context, pageID, revisionID string) (*RevisionSnapshot, error) {
+	var snapshot RevisionSnapshot
+	if err := c.doJSON(ctx, http.MethodGet, revisionPath(pageID, revisionID), nil, nil, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+func (c *Client) CompareRevisions(ctx context.Context, pageID, baseRevisionID, targetRevisionID string) (*RevisionComparison, error) {
+	query := url.Values{"base": {baseRevisionID}, "target": {targetRevisionID}}
+	var comparison RevisionComparison
+	if err := c.doJSON(ctx, http.MethodGet, pagePath(pageID)+"/revisions/compare", query, nil, &comparison); err != nil {
+		return nil, err
+	}
+	return &comparison, nil
+}
+
+// RestoreRevision restores a page to a revision and returns the page.
+func (c *Client) RestoreRevision(ctx context.Context, pageID, revisionID string) (*Page, error) {
+	var page Page
+	if err := c.doJSON(ctx, http.MethodPost, revisionPath(pageID, revisionID)+"/restore", nil, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+func revisionPath(pageID, revisionID string) string {
+	return pagePath(pageID) + "/revisions/" + url.PathEscape(revisionID)
+}
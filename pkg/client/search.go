@@ -0,0 +1,269 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SearchOptions narrow a search. Zero values use the server defaults.
+type SearchOptions struct {
+	Tags   []string // only pages with all of these tags
+	Offset int
+	Limit  int
+}
+
+// Search runs a full-text search. query uses the search syntax described in
+// the README (phrases, OR, -term, title:, path:, tag:, author:, updated:,
+// property:value) and may be empty if opts.Tags is set.
+func (c *Client) Search(ctx context.Context, query string, opts SearchOptions) (*SearchResult, error) {
+	params := url.Values{}
+	if query != "" {
+		params.Set("q", query)
+	}
+	if len(opts.Tags) > 0 {
+		params.Set("tags", strings.Join(opts.Tags, ","))
+	}
+	if opts.Offset > 0 {
+		params.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	if opts.Limit > 0 {
+		params.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	var result SearchResult
+	if err := c.doJSON(ctx, http.MethodGet, "/api/search", params, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// HistorySearchOptions select the versions a history search looks at. Zero
+// values use the server defaults: the current version of each page.
+type HistorySearchOptions struct {
+	From       time.Time // only versions current at or after From
+	To         time.Time // only versions current at or before To
+	AnyVersion bool      // every stored version, ignoring From and To
+	Offset     int
+	Limit      int
+}
+
+// SearchHistory runs a full-text search over the stored revisions. It needs
+// a server with revisions and history search enabled.
+func (c *Client) SearchHistory(ctx context.Context, query string, opts HistorySearchOptions) (*HistorySearchResult, error) {
+	params := url.Values{"q": {query}}
+	if !opts.From.IsZero() {
+		params.Set("from", opts.From.Format(time.RFC3339Nano))
+	}
+	if !opts.To.IsZero() {
+		params.Set("to", opts.To.Format(time.RFC3339Nano))
+	}
+	if opts.AnyVersion {
+		params.Set("any_version", "true")
+	}
+	if opts.Offset > 0 {
+		params.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	if opts.Limit > 0 {
+		params.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	var result HistorySearchResult
+	if err := c.doJSON(ctx, http.MethodGet, "/api/search/history", params, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Tags returns the tags in use with their page counts. filter matches a
+// prefix of the tag; limit <= 0 uses the server default.
+func (c *Client) Tags(ctx context.Context, filter string, limit int) ([]TagCount, error) {
+	var tags []TagCount
+	if err := c.doJSON(ctx, http.MethodGet, "/api/tags", listQuery(filter, limit), nil, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// PagesByTags returns the pages that have all of the given tags.
+func (c *Client) PagesByTags(ctx context.Context, tags ...string) ([]*TaggedPage, error) {
+	var pages []*TaggedPage
+	query := url.Values{"tags": {strings.Join(tags, ",")}}
+	if err := c.doJSON(ctx, http.MethodGet, "/api/tags/pages", query, nil, &pages); err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+// PropertyKeys returns the property keys in use with their page counts.
+func (c *Client) PropertyKeys(ctx context.Context, filter string, limit int) ([]PropertyKeyCount, error) {
+	var keys []PropertyKeyCount
+	if err := c.doJSON(ctx, http.MethodGet, "/api/properties", listQuery(filter, limit), nil, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// PagesByProperty returns the pages whose property key has the given value.
+func (c *Client) PagesByProperty(ctx context.Context, key, value string) ([]*PropertyPage, error) {
+	query := url.Values{"key": {key}, "value": {value}}
+	var pages []*PropertyPage
+	if err := c.doJSON(ctx, http.MethodGet, "/api/properties/pages", query, nil, &pages); err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+// LinkStatus returns the links from and to a page.
+func (c *Client) LinkStatus(ctx context.Context, pageID string) (*LinkStatus, error) {
+	var status LinkStatus
+	if err := c.doJSON(ctx, http.MethodGet, pagePath(pageID)+"/links", nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// LinkGraphOptions select the pages of a link graph. With neither RootID
+// nor PageID set, the graph spans the whole wiki.
+type LinkGraphOptions struct {
+	RootID            string // only the subtree under this page
+	PageID            string // only the pages up to Depth links away from this one
+	Depth             int    // 0 uses the server default
+	IncludeTags       bool
+	IncludeProperties bool
+	IncludeBroken     bool // add broken links and nodes of kind missing
+}
+
+// LinkGraph returns pages as nodes and the links between them as edges.
+func (c *Client) LinkGraph(ctx context.Context, opts LinkGraphOptions) (*LinkGraph, error) {
+	params := url.Values{}
+	if opts.RootID != "" {
+		params.Set("root", opts.RootID)
+	}
+	if opts.PageID != "" {
+		params.Set("page", opts.PageID)
+	}
+	if opts.Depth > 0 {
+		params.Set("depth", strconv.Itoa(opts.Depth))
+	}
+	if opts.IncludeTags {
+		params.Set("tags", "true")
+	}
+	if opts.IncludeProperties {
+		params.Set("properties", "true")
+	}
+	if opts.IncludeBroken {
+		params.Set("broken", "true")
+	}
+	var graph LinkGraph
+	if err := c.doJSON(ctx, http.MethodGet, "/api/links/graph", params, nil, &graph); err != nil {
+		return nil, err
+	}
+	return &graph, nil
+}
+
+// RelatedPages returns the pages related to a page, the most related first.
+// A limit of 0 uses the server default.
+func (c *Client) RelatedPages(ctx context.Context, pageID string, limit int) ([]RelatedPage, error) {
+	var out struct {
+		Items []RelatedPage `json:"items"`
+	}
+	if err := c.doJSON(ctx, http.MethodGet, pagePath(pageID)+"/related", listQuery("", limit), nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
+
+// SavedSearchInput is the input of Client.CreateSavedSearch and
+// Client.UpdateSavedSearch. Subscribe is only read on create.
+type SavedSearchInput struct {
+	Name      string   `json:"name"`
+	Query     string   `json:"query"`
+	Tags      []string `json:"tags,omitempty"`
+	Shared    bool     `json:"shared"`
+	Subscribe bool     `json:"subscribe,omitempty"`
+}
+
+// SavedSearches returns the own and the shared saved searches by name.
+func (c *Client) SavedSearches(ctx context.Context) ([]*SavedSearch, error) {
+	var searches []*SavedSearch
+	if err := c.doJSON(ctx, http.MethodGet, "/api/saved-searches", nil, nil, &searches); err != nil {
+		return nil, err
+	}
+	return searches, nil
+}
+
+func (c *Client) CreateSavedSearch(ctx context.Context, in SavedSearchInput) (*SavedSearch, error) {
+	var search SavedSearch
+	if err := c.doJSON(ctx, http.MethodPost, "/api/saved-searches", nil, in, &search); err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+// UpdateSavedSearch replaces the fields of an own saved search.
+func (c *Client) UpdateSavedSearch(ctx context.Context, id string, in SavedSearchInput) (*SavedSearch, error) {
+	var search SavedSearch
+	if err := c.doJSON(ctx, http.MethodPut, savedSearchPath(id), nil, in, &search); err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+func (c *Client) DeleteSavedSearch(ctx context.Context, id string) error {
+	return c.doJSON(ctx, http.MethodDelete, savedSearchPath(id), nil, nil, nil)
+}
+
+// Subscribe notifies the user when a page starts to match the saved search.
+func (c *Client) Subscribe(ctx context.Context, searchID string) (*SavedSearch, error) {
+	return c.setSubscription(ctx, http.MethodPut, searchID)
+}
+
+func (c *Client) Unsubscribe(ctx context.Context, searchID string) (*SavedSearch, error) {
+	return c.setSubscription(ctx, http.MethodDelete, searchID)
+}
+
+func (c *Client) setSubscription(ctx context.Context, method, searchID string) (*SavedSearch, error) {
+	var search SavedSearch
+	if err := c.doJSON(ctx, method, savedSearchPath(searchID)+"/subscription", nil, nil, &search); err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+// Notifications returns the user's notifications about saved searches,
+// newest first. A limit of 0 uses the server default.
+func (c *Client) Notifications(ctx context.Context, unreadOnly bool, limit int) ([]SearchNotification, error) {
+	query := listQuery("", limit)
+	if unreadOnly {
+		query.Set("unread", "true")
+	}
+	var notifications []SearchNotification
+	if err := c.doJSON(ctx, http.MethodGet, "/api/notifications", query, nil, &notifications); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// MarkNotificationsRead marks the given notifications, or all of them
+// without ids, as read.
+func (c *Client) MarkNotificationsRead(ctx context.Context, ids ...string) error {
+	body := map[string][]string{"ids": ids}
+	return c.doJSON(ctx, http.MethodPost, "/api/notifications/read", nil, body, nil)
+}
+
+func savedSearchPath(id string) string {
+	return "/api/saved-searches/" + url.PathEscape(id)
+}
+
+func listQuery(filter string, limit int) url.Values {
+	query := url.Values{}
+	if filter != "" {
+		query.Set("q", filter)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	return query
+}
@@ -0,0 +1,393 @@
+package client
+
+import "time"
+
+// Kind is the kind of a node in the page tree.
+type Kind string
+
+const (
+	KindPage    Kind = "page"
+	KindSection Kind = "section"
+)
+
+// UserLabel identifies a user in page and revision metadata.
+type UserLabel struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+// NodeMetadata holds the authorship of a node.
+type NodeMetadata struct {
+	CreatedAt    string     `json:"createdAt"`
+	UpdatedAt    string     `json:"updatedAt"`
+	CreatorID    string     `json:"creatorId"`
+	LastAuthorID string     `json:"lastAuthorId"`
+	Creator      *UserLabel `json:"creator,omitempty"`
+	LastAuthor   *UserLabel `json:"lastAuthor,omitempty"`
+}
+
+// Node is a node of the page tree.
+type Node struct {
+	ID       string       `json:"id"`
+	Title    string       `json:"title"`
+	Slug     string       `json:"slug"`
+	Path     string       `json:"path"`
+	Version  string       `json:"version"`
+	Position int          `json:"position"`
+	Kind     Kind         `json:"kind"`
+	Children []*Node      `json:"children"`
+	Metadata NodeMetadata `json:"metadata"`
+	Pinned   bool         `json:"pinned,omitempty"`
+}
+
+// Page is a page with its content. Content is the Markdown body without
+// frontmatter; Tags and Properties come from the frontmatter.
+type Page struct {
+	*Node
+	Content    string            `json:"content"`
+	Path       string            `json:"path"`
+	Tags       []string          `json:"tags"`
+	Properties map[string]string `json:"properties"`
+}
+
+// PathSegment is one segment of a PathLookup.
+type PathSegment struct {
+	Slug   string  `json:"slug"`
+	Exists bool    `json:"exists"`
+	Kind   *Kind   `json:"kind,omitempty"`
+	Title  *string `json:"title,omitempty"`
+	ID     *string `json:"id,omitempty"`
+}
+
+// PathLookup tells which segments of a page path exist.
+type PathLookup struct {
+	Path      string        `json:"path"`
+	Segments  []PathSegment `json:"segments"`
+	Exists    bool          `json:"exists"`
+	CanCreate bool          `json:"canCreate"`
+}
+
+// PageID returns the ID of the page at the looked up path, or "" if it does
+// not exist.
+func (l *PathLookup) PageID() string {
+	if l == nil || !l.Exists || len(l.Segments) == 0 {
+		return ""
+	}
+	if id := l.Segments[len(l.Segments)-1].ID; id != nil {
+		return *id
+	}
+	return ""
+}
+
+// SearchResult is a page of search hits.
+type SearchResult struct {
+	Limit     int              `json:"limit"`
+	Offset    int              `json:"offset"`
+	Count     int              `json:"count"`
+	Items     []SearchHit      `json:"items"`
+	TagFacets []SearchTagFacet `json:"tag_facets"`
+	// Suggestion is the query with misspelled words corrected.
+	Suggestion string `json:"suggestion,omitempty"`
+	// Attachments are the best matching attachments, listed with the first
+	// page of results only.
+	Attachments     []SearchAttachmentHit `json:"attachments,omitempty"`
+	AttachmentCount int                   `json:"attachment_count,omitempty"`
+}
+
+type SearchHit struct {
+	PageID  string   `json:"page_id"`
+	Title   string   `json:"title"`
+	Path    string   `json:"path"`
+	Kind    string   `json:"kind"`
+	Rank    float64  `json:"rank"`
+	Excerpt string   `json:"excerpt"`
+	Tags    []string `json:"tags,omitempty"`
+	// Fuzzy hits only match a spelling correction of the query.
+	Fuzzy bool `json:"fuzzy,omitempty"`
+	// Headings are the best matching sections of the page.
+	Headings []SearchHeadingHit `json:"headings,omitempty"`
+}
+
+// SearchHeadingHit is a section of a page that matches a search; link to
+// it with the page's path and #Anchor.
+type SearchHeadingHit struct {
+	Heading string `json:"heading"`
+	Anchor  string `json:"anchor"`
+	Excerpt string `json:"excerpt"`
+}
+
+// SearchAttachmentHit is an attachment whose text matches a search; it is
+// served at /assets/<PageID>/<Filename>.
+type SearchAttachmentHit struct {
+	PageID    string  `json:"page_id"`
+	PageTitle string  `json:"page_title"`
+	PagePath  string  `json:"page_path"`
+	Filename  string  `json:"filename"`
+	Excerpt   string  `json:"excerpt"`
+	Rank      float64 `json:"rank"`
+}
+
+// HistorySearchResult is a page of history search hits.
+type HistorySearchResult struct {
+	Limit  int                `json:"limit"`
+	Offset int                `json:"offset"`
+	Count  int                `json:"count"`
+	Items  []HistorySearchHit `json:"items"`
+}
+
+// HistorySearchHit is a version of a page whose text matches a history
+// search. Title and Path are those of the page at the time of the revision.
+type HistorySearchHit struct {
+	PageID     string    `json:"page_id"`
+	RevisionID string    `json:"revision_id"`
+	Title      string    `json:"title"`
+	Path       string    `json:"path"`
+	AuthorID   string    `json:"author_id,omitempty"`
+	AuthorName string    `json:"author_name,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	Excerpt    string    `json:"excerpt"`
+	Rank       float64   `json:"rank"`
+}
+
+type SearchTagFacet struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// TaggedPage is a page returned by PagesByTags.
+type TaggedPage struct {
+	ID           string     `json:"id"`
+	Title        string     `json:"title"`
+	Path         string     `json:"path"`
+	Excerpt      string     `json:"excerpt,omitempty"`
+	Tags         []string   `json:"tags"`
+	CreatedAt    string     `json:"createdAt,omitempty"`
+	UpdatedAt    string     `json:"updatedAt,omitempty"`
+	CreatorID    string     `json:"creatorId,omitempty"`
+	LastAuthorID string     `json:"lastAuthorId,omitempty"`
+	LastAuthor   *UserLabel `json:"lastAuthor,omitempty"`
+}
+
+type PropertyKeyCount struct {
+	Key   string `json:"key"`
+	Count int    `json:"count"`
+}
+
+type PropertyValue struct {
+	Value string `json:"value"`
+	Type  string `json:"type"`
+}
+
+// PropertyPage is a page returned by PagesByProperty.
+type PropertyPage struct {
+	ID           string                   `json:"id"`
+	Title        string                   `json:"title"`
+	Path         string                   `json:"path"`
+	Properties   map[string]PropertyValue `json:"properties"`
+	CreatedAt    string                   `json:"createdAt,omitempty"`
+	UpdatedAt    string                   `json:"updatedAt,omitempty"`
+	CreatorID    string                   `json:"creatorId,omitempty"`
+	LastAuthorID string                   `json:"lastAuthorId,omitempty"`
+	LastAuthor   *UserLabel               `json:"lastAuthor,omitempty"`
+}
+
+// Revision is an entry of the history of a page.
+type Revision struct {
+	ID                string     `json:"id"`
+	PageID            string     `json:"pageId"`
+	ParentID          string     `json:"parentId,omitempty"`
+	Type              string     `json:"type"`
+	AuthorID          string     `json:"authorId"`
+	Author            *UserLabel `json:"author,omitempty"`
+	AuthorName        string     `json:"authorName,omitempty"`
+	CreatedAt         string     `json:"createdAt"`
+	Title             string     `json:"title"`
+	Slug              string     `json:"slug"`
+	Kind              string     `json:"kind"`
+	Path              string     `json:"path"`
+	ContentHash       string     `json:"contentHash"`
+	AssetManifestHash string     `json:"assetManifestHash"`
+	PageCreatedAt     string     `json:"pageCreatedAt,omitempty"`
+	PageUpdatedAt     string     `json:"pageUpdatedAt,omitempty"`
+	CreatorID         string     `json:"creatorId,omitempty"`
+	LastAuthorID      string     `json:"lastAuthorId,omitempty"`
+	Summary           string     `json:"summary,omitempty"`
+}
+
+// RevisionList is a page of revisions, newest first. NextCursor is empty on
+// the last page.
+type RevisionList struct {
+	Revisions  []*Revision `json:"revisions"`
+	NextCursor string      `json:"nextCursor"`
+}
+
+type RevisionAsset struct {
+	Name      string `json:"name"`
+	SHA256    string `json:"sha256"`
+	SizeBytes int64  `json:"sizeBytes"`
+	MIMEType  string `json:"mimeType,omitempty"`
+}
+
+// RevisionSnapshot is a revision with the content and assets it recorded.
+type RevisionSnapshot struct {
+	Revision *Revision       `json:"revision"`
+	Content  string          `json:"content"`
+	Assets   []RevisionAsset `json:"assets"`
+}
+
+type RevisionAssetChange struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+type RevisionComparison struct {
+	Base           *RevisionSnapshot     `json:"base"`
+	Target         *RevisionSnapshot     `json:"target"`
+	ContentChanged bool                  `json:"contentChanged"`
+	AssetChanges   []RevisionAssetChange `json:"assetChanges"`
+}
+
+// LinkStatus lists the links from and to a page.
+type LinkStatus struct {
+	Backlinks       []Backlink     `json:"backlinks"`
+	BrokenIncoming  []Backlink     `json:"broken_incoming"`
+	Outgoings       []OutgoingLink `json:"outgoings"`
+	BrokenOutgoings []OutgoingLink `json:"broken_outgoings"`
+	Counts          LinkCounts     `json:"counts"`
+}
+
+type Backlink struct {
+	FromPageID string `json:"from_page_id"`
+	FromTitle  string `json:"from_title"`
+	FromPath   string `json:"from_path"`
+	Broken     bool   `json:"broken"`
+	ToPageID   string `json:"to_page_id"`
+}
+
+type OutgoingLink struct {
+	ToPageID    string `json:"to_page_id"`
+	ToPageTitle string `json:"to_page_title"`
+	ToPath      string `json:"to_path"`
+	Broken      bool   `json:"broken"`
+	FromPageID  string `json:"from_page_id"`
+}
+
+type LinkCounts struct {
+	Backlinks       int `json:"backlinks"`
+	BrokenIncoming  int `json:"broken_incoming"`
+	Outgoings       int `json:"outgoings"`
+	BrokenOutgoings int `json:"broken_outgoings"`
+}
+
+// LinkGraph holds pages and the links between them.
+type LinkGraph struct {
+	Nodes []LinkGraphNode `json:"nodes"`
+	Edges []LinkGraphEdge `json:"edges"`
+}
+
+// LinkGraphNode is a page of a link graph. Kind is the kind of the page, or
+// missing for the path a broken link leads to.
+type LinkGraphNode struct {
+	ID         string            `json:"id"`
+	Title      string            `json:"title"`
+	Path       string            `json:"path"`
+	Kind       string            `json:"kind"`
+	Tags       []string          `json:"tags,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type LinkGraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Broken bool   `json:"broken"`
+}
+
+// RelatedPage is a page related to another, with the reasons why.
+type RelatedPage struct {
+	PageID  string          `json:"page_id"`
+	Title   string          `json:"title"`
+	Path    string          `json:"path"`
+	Score   float64         `json:"score"`
+	Reasons []RelatedReason `json:"reasons"`
+}
+
+// RelatedReason explains part of the score of a related page. Kind is one
+// of tag, property, link, co_citation, coupling and text.
+type RelatedReason struct {
+	Kind   string   `json:"kind"`
+	Score  float64  `json:"score"`
+	Values []string `json:"values,omitempty"`
+	Count  int      `json:"count,omitempty"`
+}
+
+// SavedSearch is a named search as seen by the requesting user. Shared
+// searches are listed for every user, who may subscribe to them.
+type SavedSearch struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Query      string     `json:"query"`
+	Tags       []string   `json:"tags"`
+	Shared     bool       `json:"shared"`
+	OwnerID    string     `json:"owner_id"`
+	Owner      *UserLabel `json:"owner,omitempty"`
+	Subscribed bool       `json:"subscribed"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// SearchNotification tells that a page started to match a subscribed
+// saved search.
+type SearchNotification struct {
+	ID         string    `json:"id"`
+	SearchID   string    `json:"search_id"`
+	SearchName string    `json:"search_name"`
+	PageID     string    `json:"page_id"`
+	Title      string    `json:"title"`
+	Path       string    `json:"path"`
+	CreatedAt  time.Time `json:"created_at"`
+	Read       bool      `json:"read"`
+}
+
+// User is an account of the wiki.
+type User struct {
+	ID              string `json:"id"`
+	Username        string `json:"username"`
+	Email           string `json:"email"`
+	Role            string `json:"role"`
+	TOTPEnabled     bool   `json:"totpEnabled"`
+	MustSetPassword bool   `json:"mustSetPassword"`
+}
+
+// APIKey describes an API key. The secret is only returned once, by
+// CreateAPIKey.
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	UserID     string     `json:"userId"`
+	Prefix     string     `json:"prefix"`
+	Role       string     `json:"role"`
+	CreatedBy  string     `json:"createdBy"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+type Snapshot struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	SizeBytes int64     `json:"sizeBytes"`
+}
+
+// ResyncStatus is the state of the last resync of the wiki with its files.
+type ResyncStatus struct {
+	Running bool   `json:"running"`
+	Phase   string `json:"phase,omitempty"`
+	Done    bool   `json:"done"`
+	Error   string `json:"error,omitempty"`
+}
@@ -122,7 +122,12 @@ function revisionTitle(revision: Revision) {
 }
 
 function revisionMeta(revision: Revision) {
-  return revision.author?.username || revision.authorId || t('common.unknown')
+  return (
+    revision.author?.username ||
+    revision.authorName ||
+    revision.authorId ||
+    t('common.unknown')
+  )
 }
 
 function getPathLeaf(path: string) {
@@ -175,7 +180,12 @@ function assetChangeLabel(status: RevisionAssetChange['status']) {
 }
 
 function displayAuthor(revision: Revision) {
-  return revision.author?.username || revision.authorId || t('common.unknown')
+  return (
+    revision.author?.username ||
+    revision.authorName ||
+    revision.authorId ||
+    t('common.unknown')
+  )
 }
 
 function formatTimestamp(value?: string) {
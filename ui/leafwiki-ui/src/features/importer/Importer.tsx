@@ -1,6 +1,22 @@
 import { Button } from '@/components/ui/button'
+import { Checkbox } from '@/components/ui/checkbox'
+import { Input } from '@/components/ui/input'
+import {
+  Select,
+  SelectContent,
+  SelectItem,
+  SelectTrigger,
+  SelectValue,
+} from '@/components/ui/select'
+import type {
+  CreateImportPlanOptions,
+  ImportChange,
+  ImportPlanItem,
+  ImportResult,
+} from '@/lib/api/import'
 import i18next from '@/lib/i18n'
 import { useImportStore } from '@/stores/import'
+import { useSessionStore } from '@/stores/session'
 import { FileUp, Loader2, PlayIcon, UploadIcon, XIcon } from 'lucide-react'
 import { useCallback, useEffect, useRef, useState } from 'react'
 import { Trans } from 'react-i18next'
@@ -12,7 +28,10 @@ type ResultFilter = 'all' | 'created' | 'skipped' | 'failed'
 const t = (key: string, opts?: Record<string, unknown>) =>
   i18next.t(key, { ...opts, ns: 'importer' })
 
-function getPlanActionLabel(action: 'create' | 'update' | 'skip'): string {
+type RemovedPages = NonNullable<CreateImportPlanOptions['removedPages']>
+type ResultAction = ImportResult['items'][number]['action']
+
+function getPlanActionLabel(action: ImportPlanItem['action']): string {
   switch (action) {
     case 'create':
       return t('planActions.create')
@@ -20,10 +39,14 @@ function getPlanActionLabel(action: 'create' | 'update' | 'skip'): string {
       return t('planActions.update')
     case 'skip':
       return t('planActions.skip')
+    case 'delete':
+      return t('planActions.delete')
+    case 'trash':
+      return t('planActions.trash')
   }
 }
 
-function getPlanActionClass(action: 'create' | 'update' | 'skip'): string {
+function getPlanActionClass(action: ImportPlanItem['action']): string {
   switch (action) {
     case 'create':
       return 'settings__pill settings__pill-success'
@@ -31,11 +54,29 @@ function getPlanActionClass(action: 'create' | 'update' | 'skip'): string {
       return 'settings__pill settings__pill-warning'
     case 'skip':
       return 'settings__pill settings__pill-warning'
+    case 'delete':
+    case 'trash':
+      return 'settings__pill settings__pill-error'
+  }
+}
+
+function getChangeLabel(change: ImportChange): string {
+  switch (change) {
+    case 'new':
+      return t('changes.new')
+    case 'unchanged':
+      return t('changes.unchanged')
+    case 'modified':
+      return t('changes.modified')
+    case 'moved':
+      return t('changes.moved')
+    case 'removed':
+      return t('changes.removed')
   }
 }
 
 function getResultActionLabel(
-  action: 'created' | 'updated' | 'skipped' | 'conflicted',
+  action: ResultAction,
   hasError: boolean,
 ): string {
   if (hasError) {
@@ -51,11 +92,15 @@ function getResultActionLabel(
       return t('resultActions.skipped')
     case 'conflicted':
       return t('resultActions.conflicted')
+    case 'deleted':
+      return t('resultActions.deleted')
+    case 'trashed':
+      return t('resultActions.trashed')
   }
 }
 
 function getResultActionClass(
-  action: 'created' | 'updated' | 'skipped' | 'conflicted',
+  action: ResultAction,
   hasError: boolean,
 ): string {
   if (hasError || action === 'conflicted') {
@@ -68,6 +113,8 @@ function getResultActionClass(
     case 'updated':
       return 'settings__pill settings__pill-warning'
     case 'skipped':
+    case 'deleted':
+    case 'trashed':
       return 'settings__pill settings__pill-warning'
   }
 }
@@ -77,9 +124,14 @@ export default function Importer() {
   const navigate = useNavigate()
   const zipRef = useRef<HTMLInputElement>(null)
   const [zipFileName, setZipFileName] = useState('')
+  const [importHistory, setImportHistory] = useState(false)
+  const [mapHistoryAuthors, setMapHistoryAuthors] = useState(false)
+  const [importSource, setImportSource] = useState('')
+  const [removedPages, setRemovedPages] = useState<RemovedPages>('keep')
   const [resultFilter, setResultFilter] = useState<ResultFilter>('all')
   const [resultSearch, setResultSearch] = useState('')
 
+  const isAdmin = useSessionStore((s) => s.user?.role === 'admin')
   const createImportPlan = useImportStore((store) => store.createImportPlan)
   const executeImportPlan = useImportStore((store) => store.executeImportPlan)
   const cancelImportPlan = useImportStore((store) => store.cancelImportPlan)
@@ -105,8 +157,20 @@ export default function Importer() {
     if (!zipFile) {
       return
     }
-    void createImportPlan(zipFile)
-  }, [createImportPlan])
+    void createImportPlan(zipFile, {
+      importHistory,
+      mapHistoryAuthors: isAdmin && importHistory && mapHistoryAuthors,
+      source: importSource.trim(),
+      removedPages,
+    })
+  }, [
+    createImportPlan,
+    importHistory,
+    isAdmin,
+    mapHistoryAuthors,
+    importSource,
+    removedPages,
+  ])
 
   const closeImporter = useCallback(async () => {
     const cleared = importPlan ? await cancelImportPlan() : true
@@ -400,6 +464,74 @@ export default function Importer() {
                 {t('package.supportedInputHint')}
               </div>
             </div>
+            <div className="settings__field">
+              <label className="flex items-center gap-2 text-sm">
+                <Checkbox
+                  data-testid="importer-import-history-checkbox"
+                  checked={importHistory}
+                  onCheckedChange={(val) => setImportHistory(!!val)}
+                />
+                {t('package.importHistory')}
+              </label>
+              <div className="settings__hint">
+                {t('package.importHistoryHint')}
+              </div>
+            </div>
+            {isAdmin && importHistory && (
+              <div className="settings__field">
+                <label className="flex items-center gap-2 text-sm">
+                  <Checkbox
+                    data-testid="importer-map-history-authors-checkbox"
+                    checked={mapHistoryAuthors}
+                    onCheckedChange={(val) => setMapHistoryAuthors(!!val)}
+                  />
+                  {t('package.mapHistoryAuthors')}
+                </label>
+                <div className="settings__hint">
+                  {t('package.mapHistoryAuthorsHint')}
+                </div>
+              </div>
+            )}
+            <div className="settings__field">
+              <label className="text-sm" htmlFor="importer-source">
+                {t('package.source')}
+              </label>
+              <Input
+                id="importer-source"
+                data-testid="importer-source-input"
+                value={importSource}
+                placeholder={t('package.sourcePlaceholder')}
+                onChange={(e) => setImportSource(e.target.value)}
+              />
+              <div className="settings__hint">{t('package.sourceHint')}</div>
+            </div>
+            {importSource.trim() !== '' && (
+              <div className="settings__field">
+                <label className="text-sm">{t('package.removedPages')}</label>
+                <Select
+                  value={removedPages}
+                  onValueChange={(val) => setRemovedPages(val as RemovedPages)}
+                >
+                  <SelectTrigger data-testid="importer-removed-pages-select">
+                    <SelectValue />
+                  </SelectTrigger>
+                  <SelectContent>
+                    <SelectItem value="keep">
+                      {t('package.removedPagesKeep')}
+                    </SelectItem>
+                    <SelectItem value="trash">
+                      {t('package.removedPagesTrash')}
+                    </SelectItem>
+                    <SelectItem value="delete">
+                      {t('package.removedPagesDelete')}
+                    </SelectItem>
+                  </SelectContent>
+                </Select>
+                <div className="settings__hint">
+                  {t('package.removedPagesHint')}
+                </div>
+              </div>
+            )}
             <Button
               variant="default"
               className="settings__save-button"
@@ -637,6 +769,11 @@ export default function Importer() {
                               <span className={getPlanActionClass(item.action)}>
                                 {getPlanActionLabel(item.action)}
                               </span>
+                              {item.change && (
+                                <div className="importer__muted-copy">
+                                  {getChangeLabel(item.change)}
+                                </div>
+                              )}
                             </td>
                             <td className="settings__table-cell importer__details-cell">
                               {item.notes && item.notes.length > 0 ? (
@@ -827,6 +964,14 @@ export default function Importer() {
                       </th>
                       <td>{importResult.skipped_count}</td>
                     </tr>
+                    {!!importResult.removed_count && (
+                      <tr>
+                        <th className="settings__table-header-cell">
+                          {t('result.removedCount')}
+                        </th>
+                        <td>{importResult.removed_count}</td>
+                      </tr>
+                    )}
                   </tbody>
                 </table>
               </div>
@@ -54,6 +54,16 @@ export default function Sidebar() {
     }
   }, [items, setSidebarMode, sidebarMode])
 
+  // Opened with ?q=, e.g. from the browser's search bar: show the results.
+  useEffect(() => {
+    if (!new URLSearchParams(window.location.search).has('q')) return
+    if (!items.some((item) => item.id === SIDEBAR_SEARCH_PANEL_ID)) return
+    setSidebarVisible(true)
+    setSidebarMode(SIDEBAR_SEARCH_PANEL_ID)
+    // Only on the first render; later changes of the query come from the panel.
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [])
+
   // add hotkeys for each tab
   const registerHotkey = useHotKeysStore((s) => s.registerHotkey)
   const unregisterHotkey = useHotKeysStore((s) => s.unregisterHotkey)
@@ -17,6 +17,7 @@ import {
 } from '@/lib/api/pages'
 import type { Page, PageNode } from '@/lib/api/pages'
 import { asApiLocalizedError, mapApiError } from '@/lib/api/errors'
+import { bookExportUrl, markdownExportUrl } from '@/lib/api/export'
 import {
   DIALOG_ADD_PAGE,
   DIALOG_COPY_PAGE,
@@ -36,7 +37,9 @@ import { useSessionStore } from '@/stores/session'
 import { useViewerStore } from '@/features/viewer/viewer'
 import { useTreeStore } from '@/stores/tree'
 import {
+  BookOpen,
   Copy,
+  Download,
   FilePlus,
   FolderPlus,
   List,
@@ -367,6 +370,36 @@ export default function TreeNodeActionsMenu({
                 {t('treeActions.menuConvertToPage')}
               </DropdownMenuItem>
             )}
+            <DropdownMenuItem
+              className="cursor-pointer"
+              data-testid="tree-view-action-button-export-markdown"
+              onClick={() => {
+                window.location.href = markdownExportUrl(node.path)
+              }}
+            >
+              <Download size={18} className="tree-node__action-icon" />{' '}
+              {t('treeActions.menuExportMarkdown')}
+            </DropdownMenuItem>
+            <DropdownMenuItem
+              className="cursor-pointer"
+              data-testid="tree-view-action-button-export-book-html"
+              onClick={() => {
+                window.location.href = bookExportUrl(node.path, 'html')
+              }}
+            >
+              <BookOpen size={18} className="tree-node__action-icon" />{' '}
+              {t('treeActions.menuExportBookHtml')}
+            </DropdownMenuItem>
+            <DropdownMenuItem
+              className="cursor-pointer"
+              data-testid="tree-view-action-button-export-book-epub"
+              onClick={() => {
+                window.location.href = bookExportUrl(node.path, 'epub')
+              }}
+            >
+              <BookOpen size={18} className="tree-node__action-icon" />{' '}
+              {t('treeActions.menuExportBookEpub')}
+            </DropdownMenuItem>
             <DropdownMenuSeparator />
           </>
         )}
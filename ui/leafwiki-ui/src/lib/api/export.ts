@@ -0,0 +1,25 @@
+import { API_BASE_URL } from '../config'
+
+export type MarkdownExportOptions = {
+  wikiLinks?: boolean
+  includeIds?: boolean
+}
+
+// Browser-native download, like snapshotDownloadUrl: cookies carry auth and
+// GET is exempt from CSRF.
+export function markdownExportUrl(
+  path: string,
+  options: MarkdownExportOptions = {},
+): string {
+  const params = new URLSearchParams({ path })
+  if (options.wikiLinks) params.set('wikilinks', 'true')
+  if (options.includeIds) params.set('ids', 'true')
+  return `${API_BASE_URL}/api/export/markdown?${params.toString()}`
+}
+
+export type BookExportFormat = 'html' | 'epub'
+
+export function bookExportUrl(path: string, format: BookExportFormat): string {
+  const params = new URLSearchParams({ path, format })
+  return `${API_BASE_URL}/api/export/book?${params.toString()}`
+}
@@ -6,6 +6,9 @@ export type ImportPlan = {
   tree_hash: string
   items: ImportPlanItem[]
   errors: string[]
+  conflict_strategy: ImportConflictStrategy
+  excluded_paths?: string[]
+  source?: string
   execution_status: ImportExecutionStatus
   cancel_requested: boolean
   execution_result?: ImportResult
@@ -20,6 +23,12 @@ export type ImportPlan = {
 export type ImportExecutionStatus =
   'planned' | 'running' | 'completed' | 'failed' | 'canceled'
 
+export type ImportConflictStrategy =
+  'skip' | 'overwrite' | 'merge_frontmatter' | 'keep_both'
+
+export type ImportChange =
+  'new' | 'unchanged' | 'modified' | 'moved' | 'removed'
+
 export type ImportPlanItem = {
   source_path: string
   target_path: string
@@ -28,28 +37,69 @@ export type ImportPlanItem = {
   kind: 'page' | 'section'
   exists: boolean
   existing_id: string | null
-  action: 'create' | 'update' | 'skip'
+  action: 'create' | 'update' | 'skip' | 'delete' | 'trash'
   conflicts: string[] | null
   notes: string[] | null
+  strategy?: ImportConflictStrategy
+  excluded?: boolean
+  conflict_path?: string
+  // Set when the plan was compared with an earlier import of the same source.
+  change?: ImportChange
+  content_hash?: string
 }
 
 export type ImportResult = {
   imported_count: number
   updated_count: number
   skipped_count: number
+  removed_count?: number
   items: {
     source_path: string
     target_path: string
-    action: 'created' | 'updated' | 'skipped' | 'conflicted'
+    action:
+      | 'created'
+      | 'updated'
+      | 'skipped'
+      | 'conflicted'
+      | 'deleted'
+      | 'trashed'
+    page_id?: string
     error?: string
   }[]
   tree_hash: string
   tree_hash_before: string
 }
 
-export async function createImportPlanFromZip(file: File): Promise<ImportPlan> {
+export type CreateImportPlanOptions = {
+  // Import the page history of exports that have one (MediaWiki dumps) as revisions.
+  importHistory?: boolean
+  // Link history authors to the users of the same name; admins only.
+  mapHistoryAuthors?: boolean
+  // Name of the import source. Re-importing the same source only updates
+  // changed pages.
+  source?: string
+  // What happens to pages whose source file disappeared.
+  removedPages?: 'keep' | 'delete' | 'trash'
+}
+
+export async function createImportPlanFromZip(
+  file: File,
+  options: CreateImportPlanOptions = {},
+): Promise<ImportPlan> {
   const formData = new FormData()
   formData.append('file', file)
+  if (options.importHistory) {
+    formData.append('importHistory', 'true')
+    if (options.mapHistoryAuthors) {
+      formData.append('mapHistoryAuthors', 'true')
+    }
+  }
+  if (options.source) {
+    formData.append('source', options.source)
+    if (options.removedPages) {
+      formData.append('removedPages', options.removedPages)
+    }
+  }
 
   return (await fetchWithAuth('/api/import/plan', {
     method: 'POST',
@@ -64,6 +114,30 @@ export async function getImportPlan(): Promise<ImportPlan> {
   })) as ImportPlan
 }
 
+export type ImportPlanEdit = {
+  tree_hash: string
+  conflict_strategy?: ImportConflictStrategy
+  items?: {
+    source_path: string
+    action?: ImportPlanItem['action']
+    target_path?: string
+    title?: string
+    slug?: string
+    strategy?: ImportConflictStrategy | ''
+  }[]
+  exclude?: string[]
+  include?: string[]
+}
+
+export async function updateImportPlan(
+  edit: ImportPlanEdit,
+): Promise<ImportPlan> {
+  return (await fetchWithAuth('/api/import/plan', {
+    method: 'PATCH',
+    body: JSON.stringify(edit),
+  })) as ImportPlan
+}
+
 export async function executeImportPlan(): Promise<ImportPlan> {
   return (await fetchWithAuth('/api/import/execute', {
     method: 'POST',
@@ -12,6 +12,7 @@ export type Revision = {
   type: string
   authorId: string
   author?: RevisionUserLabel
+  authorName?: string
   createdAt: string
   title: string
   slug: string
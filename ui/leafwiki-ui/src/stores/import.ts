@@ -17,7 +17,10 @@ type ImportStore = {
   loadingImportPlan: boolean
   importPlan: importAPI.ImportPlan | null
   importResult: importAPI.ImportResult | null
-  createImportPlan: (sourcePath: File) => Promise<boolean>
+  createImportPlan: (
+    sourcePath: File,
+    options?: importAPI.CreateImportPlanOptions,
+  ) => Promise<boolean>
   loadImportPlan: () => Promise<void>
   executeImportPlan: () => Promise<void>
   cancelImportPlan: () => Promise<boolean>
@@ -61,10 +64,13 @@ export const useImportStore = create<ImportStore>((set, get) => ({
   cancelingImportPlan: false,
   loadingImportPlan: false,
   importResult: null,
-  createImportPlan: async (sourcePath: File) => {
+  createImportPlan: async (sourcePath, options) => {
     set({ creatingImportPlan: true })
     try {
-      const importPlan = await importAPI.createImportPlanFromZip(sourcePath)
+      const importPlan = await importAPI.createImportPlanFromZip(
+        sourcePath,
+        options,
+      )
       toast.success(t('toast.planCreatedSuccess'))
       set({ importPlan, importResult: null })
       return true